@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/kerlexov/mcp-logging-go-sdk/pkg/logger"
+)
+
+// reconnectDelay is how long the agent waits before reconnecting to the events stream
+const reconnectDelay = 5 * time.Second
+
+// collector tails the logs of the running containers and of containers started later
+type collector struct {
+	docker       *dockerClient
+	logger       logger.Logger
+	serviceLabel string   // Label holding the service name
+	selector     string   // Label key or key=value a container must have, empty tails all
+	labels       []string // Label keys added to the entries, empty adds all
+
+	mu      sync.Mutex
+	tailing map[string]bool // Containers whose logs are followed
+	wg      sync.WaitGroup
+}
+
+// run tails the running containers, with their logs written after since, and the containers
+// started later until ctx is done
+func (c *collector) run(ctx context.Context, since time.Time) error {
+	ids, err := c.docker.runningContainers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		c.tail(ctx, id, since)
+	}
+
+	for ctx.Err() == nil {
+		err := c.docker.watchStarts(ctx, func(id string) {
+			// Containers started later are shipped from their first line
+			c.tail(ctx, id, time.Time{})
+		})
+		if err != nil {
+			log.Printf("Watching container starts failed, retrying in %s: %v", reconnectDelay, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}
+
+	c.wg.Wait()
+	return nil
+}
+
+// tail follows the logs of a container in the background unless they are already followed or
+// the container does not match the selector
+func (c *collector) tail(ctx context.Context, id string, since time.Time) {
+	c.mu.Lock()
+	if c.tailing[id] {
+		c.mu.Unlock()
+		return
+	}
+	c.tailing[id] = true
+	c.mu.Unlock()
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() {
+			c.mu.Lock()
+			delete(c.tailing, id)
+			c.mu.Unlock()
+		}()
+
+		if err := c.follow(ctx, id, since); err != nil && ctx.Err() == nil {
+			log.Printf("Stopped tailing container %.12s: %v", id, err)
+		}
+	}()
+}
+
+// follow ships the log lines of a container until it stops
+func (c *collector) follow(ctx context.Context, id string, since time.Time) error {
+	info, err := c.docker.inspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !c.selected(info) {
+		return nil
+	}
+
+	containerLogger := c.logger.WithServiceName(c.serviceName(info)).WithFields(c.fields(info)...)
+
+	stream, err := c.docker.followLogs(ctx, id, since)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	return readLines(stream, info.Config.Tty, func(stream, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		logLine(containerLogger, detectLevel(text), text, logger.String("stream", stream))
+	})
+}
+
+// selected checks if a container carries the selector label
+func (c *collector) selected(info *container) bool {
+	if c.selector == "" {
+		return true
+	}
+	key, value, hasValue := strings.Cut(c.selector, "=")
+	actual, ok := info.Config.Labels[key]
+	return ok && (!hasValue || actual == value)
+}
+
+// serviceName returns the service label of a container, or its name without it
+func (c *collector) serviceName(info *container) string {
+	if service := info.Config.Labels[c.serviceLabel]; service != "" {
+		return service
+	}
+	return info.Name
+}
+
+// fields returns the container fields added to each of its entries
+func (c *collector) fields(info *container) []logger.Field {
+	fields := []logger.Field{
+		logger.String("container_id", info.ID[:min(12, len(info.ID))]),
+		logger.String("container_name", info.Name),
+		logger.String("image", info.Config.Image),
+	}
+
+	if len(c.labels) == 0 {
+		for key, value := range info.Config.Labels {
+			fields = append(fields, logger.String("label."+key, value))
+		}
+		return fields
+	}
+	for _, key := range c.labels {
+		if value, ok := info.Config.Labels[key]; ok {
+			fields = append(fields, logger.String("label."+key, value))
+		}
+	}
+	return fields
+}
+
+// Level keywords looked for as words at the start of a line, most severe first
+var levelKeywords = []struct {
+	keywords []string
+	level    logger.LogLevel
+}{
+	{[]string{"FATAL", "PANIC", "CRIT", "CRITICAL"}, logger.LogLevelFatal},
+	{[]string{"ERROR", "ERR"}, logger.LogLevelError},
+	{[]string{"WARN", "WARNING"}, logger.LogLevelWarn},
+	{[]string{"DEBUG", "TRACE"}, logger.LogLevelDebug},
+}
+
+// detectLevel guesses the level of a line from a level keyword among the words of its first 80
+// bytes, such as [ERROR], level=warn or "level":"debug", INFO without one. Keywords only match
+// whole words, so that CRITERIA or stacktrace do not set the level.
+func detectLevel(text string) logger.LogLevel {
+	words := strings.FieldsFunc(strings.ToUpper(text[:min(len(text), 80)]), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, candidate := range levelKeywords {
+		for _, word := range words {
+			if slices.Contains(candidate.keywords, word) {
+				return candidate.level
+			}
+		}
+	}
+	return logger.LogLevelInfo
+}
+
+// logLine logs a line at the given level
+func logLine(l logger.Logger, level logger.LogLevel, text string, fields ...logger.Field) {
+	switch level {
+	case logger.LogLevelFatal:
+		l.Fatal(text, fields...)
+	case logger.LogLevelError:
+		l.Error(text, fields...)
+	case logger.LogLevelWarn:
+		l.Warn(text, fields...)
+	case logger.LogLevelDebug:
+		l.Debug(text, fields...)
+	default:
+		l.Info(text, fields...)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-go-sdk/pkg/logger"
+)
+
+func TestDetectLevel(t *testing.T) {
+	tests := []struct {
+		text string
+		want logger.LogLevel
+	}{
+		{"2024-01-01T00:00:00Z ERROR failed to connect", logger.LogLevelError},
+		{"[ERR] failed to connect", logger.LogLevelError},
+		{`time=2024-01-01 level=warn msg="slow query"`, logger.LogLevelWarn},
+		{`{"level":"debug","msg":"cache miss"}`, logger.LogLevelDebug},
+		{"panic: runtime error: index out of range", logger.LogLevelFatal},
+		{"CRITICAL disk full", logger.LogLevelFatal},
+		{"WARNING: deprecated flag", logger.LogLevelWarn},
+		{"TRACE entering handler", logger.LogLevelDebug},
+		{"ERROR then WARN", logger.LogLevelError},
+		{"listening on :8080", logger.LogLevelInfo},
+		{"", logger.LogLevelInfo},
+
+		// Keywords inside words do not set the level
+		{"matched CRITERIA for order 42", logger.LogLevelInfo},
+		{"printing stacktrace of goroutine 7", logger.LogLevelInfo},
+		{"0 errors, 0 warnings", logger.LogLevelInfo},
+		{"INTERRUPTED by signal", logger.LogLevelInfo},
+
+		// Only the first 80 bytes are looked at
+		{"request handled in 12ms by worker 3 of pool default with status 200 and no retries ERROR", logger.LogLevelInfo},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if level := detectLevel(tt.text); level != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, level)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dockerClient talks to the Docker Engine API over its unix socket or TCP
+type dockerClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+// container is the part of a container's inspect response the agent uses
+type container struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image    string            `json:"Image"`
+		Hostname string            `json:"Hostname"`
+		Labels   map[string]string `json:"Labels"`
+		Tty      bool              `json:"Tty"`
+	} `json:"Config"`
+}
+
+// containerEvent is a container lifecycle event of the events stream
+type containerEvent struct {
+	Action string `json:"Action"`
+	Actor  struct {
+		ID string `json:"ID"`
+	} `json:"Actor"`
+}
+
+// newDockerClient creates a client for a host such as unix:///var/run/docker.sock or tcp://127.0.0.1:2375
+func newDockerClient(host string) (*dockerClient, error) {
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	switch parsed.Scheme {
+	case "unix":
+		socket := parsed.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &dockerClient{client: &http.Client{Transport: transport}, baseURL: "http://docker"}, nil
+	case "tcp", "http":
+		return &dockerClient{client: &http.Client{}, baseURL: "http://" + parsed.Host}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", parsed.Scheme)
+	}
+}
+
+// get performs a GET request and fails on error statuses. The caller closes the body.
+func (d *dockerClient) get(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	target := d.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("docker API %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+// runningContainers returns the IDs of the running containers
+func (d *dockerClient) runningContainers(ctx context.Context) ([]string, error) {
+	body, err := d.get(ctx, "/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var summaries []struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(body).Decode(&summaries); err != nil {
+		return nil, fmt.Errorf("failed to decode container list: %w", err)
+	}
+
+	ids := make([]string, len(summaries))
+	for i, summary := range summaries {
+		ids[i] = summary.ID
+	}
+	return ids, nil
+}
+
+// inspect returns the configuration of a container
+func (d *dockerClient) inspect(ctx context.Context, id string) (*container, error) {
+	body, err := d.get(ctx, "/containers/"+id+"/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var c container
+	if err := json.NewDecoder(body).Decode(&c); err != nil {
+		return nil, fmt.Errorf("failed to decode container %s: %w", id, err)
+	}
+	c.Name = strings.TrimPrefix(c.Name, "/")
+	return &c, nil
+}
+
+// followLogs streams the stdout and stderr of a container written after since, until the
+// container stops or ctx is done
+func (d *dockerClient) followLogs(ctx context.Context, id string, since time.Time) (io.ReadCloser, error) {
+	query := url.Values{
+		"follow": {"1"},
+		"stdout": {"1"},
+		"stderr": {"1"},
+	}
+	if !since.IsZero() {
+		query.Set("since", strconv.FormatInt(since.Unix(), 10))
+	}
+	return d.get(ctx, "/containers/"+id+"/logs", query)
+}
+
+// watchStarts calls started with the ID of each container that starts until the events
+// stream ends or ctx is done
+func (d *dockerClient) watchStarts(ctx context.Context, started func(id string)) error {
+	filters, _ := json.Marshal(map[string][]string{"type": {"container"}, "event": {"start"}})
+	body, err := d.get(ctx, "/events", url.Values{"filters": {string(filters)}})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var event containerEvent
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("events stream ended: %w", err)
+		}
+		if event.Action == "start" && event.Actor.ID != "" {
+			started(event.Actor.ID)
+		}
+	}
+}
+
+// Stream types of the multiplexed log stream
+const (
+	streamStdout = "stdout"
+	streamStderr = "stderr"
+)
+
+// maxLineLength is the longest line read. Longer lines of multiplexed streams are split, TTY
+// streams fail on them.
+const maxLineLength = 1024 * 1024
+
+// readLines calls line with each line of a log stream and the stream it was written to.
+// Containers without a TTY multiplex stdout and stderr in frames with an 8-byte header of the
+// stream type and the big-endian payload size, TTY output is a raw stdout stream.
+func readLines(r io.Reader, tty bool, line func(stream, text string)) error {
+	if tty {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+		for scanner.Scan() {
+			line(streamStdout, strings.TrimRight(scanner.Text(), "\r"))
+		}
+		return scanner.Err()
+	}
+
+	// A frame may end in the middle of a line, which continues in the next frame of its stream
+	pending := map[string]string{}
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			for _, stream := range []string{streamStdout, streamStderr} {
+				if rest := pending[stream]; rest != "" {
+					line(stream, strings.TrimRight(rest, "\r"))
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		stream := streamStdout
+		if header[0] == 2 {
+			stream = streamStderr
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+
+		text := pending[stream] + string(payload)
+	lines:
+		for {
+			end := strings.IndexByte(text, '\n')
+			switch {
+			case end >= 0 && end <= maxLineLength:
+				line(stream, strings.TrimRight(text[:end], "\r"))
+				text = text[end+1:]
+			case len(text) > maxLineLength:
+				line(stream, text[:maxLineLength])
+				text = text[maxLineLength:]
+			default:
+				break lines
+			}
+		}
+		pending[stream] = text
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// frame returns a frame of the multiplexed log stream
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+type streamLine struct {
+	stream, text string
+}
+
+func TestReadLines(t *testing.T) {
+	long := strings.Repeat("x", maxLineLength+10)
+
+	tests := []struct {
+		name   string
+		stream []byte
+		tty    bool
+		want   []streamLine
+	}{
+		{
+			name:   "frames",
+			stream: bytes.Join([][]byte{frame(1, "first\nsecond\r\n"), frame(2, "failed\n")}, nil),
+			want:   []streamLine{{streamStdout, "first"}, {streamStdout, "second"}, {streamStderr, "failed"}},
+		},
+		{
+			name:   "line across frames",
+			stream: bytes.Join([][]byte{frame(1, "hel"), frame(2, "oops\n"), frame(1, "lo\n")}, nil),
+			want:   []streamLine{{streamStderr, "oops"}, {streamStdout, "hello"}},
+		},
+		{
+			name:   "unterminated lines",
+			stream: bytes.Join([][]byte{frame(2, "err"), frame(1, "out")}, nil),
+			want:   []streamLine{{streamStdout, "out"}, {streamStderr, "err"}},
+		},
+		{
+			name:   "empty frame",
+			stream: bytes.Join([][]byte{frame(1, ""), frame(1, "ok\n")}, nil),
+			want:   []streamLine{{streamStdout, "ok"}},
+		},
+		{
+			name:   "line longer than the limit",
+			stream: bytes.Join([][]byte{frame(1, long[:10]), frame(1, long[10:]+"\n")}, nil),
+			want:   []streamLine{{streamStdout, long[:maxLineLength]}, {streamStdout, long[maxLineLength:]}},
+		},
+		{
+			name:   "tty",
+			stream: []byte("first\r\nsecond\nlast"),
+			tty:    true,
+			want:   []streamLine{{streamStdout, "first"}, {streamStdout, "second"}, {streamStdout, "last"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Reading a byte at a time splits the headers and payloads across reads
+			for _, r := range []io.Reader{bytes.NewReader(tt.stream), iotest.OneByteReader(bytes.NewReader(tt.stream))} {
+				var lines []streamLine
+				err := readLines(r, tt.tty, func(stream, text string) {
+					lines = append(lines, streamLine{stream, text})
+				})
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if len(lines) != len(tt.want) {
+					t.Fatalf("Expected %d lines, got %d", len(tt.want), len(lines))
+				}
+				for i, line := range lines {
+					if line != tt.want[i] {
+						t.Errorf("Expected line %d to be %.40q on %s, got %.40q on %s", i, tt.want[i].text, tt.want[i].stream, line.text, line.stream)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReadLines_Truncated(t *testing.T) {
+	tests := map[string][]byte{
+		"header":  append(frame(1, "done\n"), 1, 0, 0),
+		"payload": frame(1, "cut off\n")[:10],
+	}
+
+	for name, stream := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := readLines(bytes.NewReader(stream), false, func(string, string) {})
+			if !errors.Is(err, io.ErrUnexpectedEOF) {
+				t.Errorf("Expected io.ErrUnexpectedEOF, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+// Command agent tails the stdout and stderr of Docker containers through the Docker API and
+// ships each line, enriched with the container's name, image and labels, to the ingestion API.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-go-sdk/pkg/logger"
+)
+
+func main() {
+	var (
+		dockerHost   = flag.String("docker-host", envOrDefault("DOCKER_HOST", "unix:///var/run/docker.sock"), "Docker API address (env DOCKER_HOST)")
+		serverURL    = flag.String("url", envOrDefault("MCP_LOGGING_URL", "http://localhost:9080"), "Ingestion server URL (env MCP_LOGGING_URL)")
+		apiKey       = flag.String("api-key", os.Getenv("MCP_LOGGING_API_KEY"), "API key sent in the X-API-Key header (env MCP_LOGGING_API_KEY)")
+		agentID      = flag.String("agent-id", "", "Agent ID of the entries, defaults to the host name")
+		serviceLabel = flag.String("service-label", "com.docker.compose.service", "Container label holding the service name, the container name is used without it")
+		selector     = flag.String("selector", "", "Only tail containers with this label, as key or key=value")
+		labels       = flag.String("labels", "", "Comma separated label keys added to the entries, all labels when empty")
+		fromStart    = flag.Bool("from-start", false, "Ship the logs written before the agent started as well")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: agent [options]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetPrefix("docker-agent: ")
+
+	if *agentID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			log.Fatalf("Failed to determine the host name, set -agent-id: %v", err)
+		}
+		*agentID = hostname
+	}
+
+	docker, err := newDockerClient(*dockerHost)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	config := logger.DefaultConfig()
+	config.ServerURL = strings.TrimRight(*serverURL, "/")
+	config.ServiceName = "docker-agent"
+	config.AgentID = *agentID
+	if *apiKey != "" {
+		transport, err := logger.NewTransport(config.Transport)
+		if err != nil {
+			log.Fatalf("Failed to create transport: %v", err)
+		}
+		config.Transport.RoundTripper = &apiKeyTransport{key: *apiKey, next: transport}
+	}
+
+	shipper, err := logger.New(config)
+	if err != nil {
+		log.Fatalf("Failed to create logger: %v", err)
+	}
+	defer shipper.Close()
+
+	c := &collector{
+		docker:       docker,
+		logger:       shipper,
+		serviceLabel: *serviceLabel,
+		selector:     *selector,
+		labels:       splitList(*labels),
+		tailing:      make(map[string]bool),
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	since := time.Now()
+	if *fromStart {
+		since = time.Time{}
+	}
+	if err := c.run(ctx, since); err != nil {
+		log.Printf("Agent stopped: %v", err)
+	}
+}
+
+// apiKeyTransport adds the API key header to the requests of the SDK
+type apiKeyTransport struct {
+	key  string
+	next http.RoundTripper
+}
+
+// RoundTrip sends the request with the X-API-Key header
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-API-Key", t.key)
+	return t.next.RoundTrip(req)
+}
+
+// envOrDefault returns the value of an environment variable, or fallback when it is unset
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
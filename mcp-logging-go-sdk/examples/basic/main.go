@@ -20,29 +20,29 @@ func main() {
 	defer mcpLogger.Close()
 
 	mcpLogger.Info("Application started",
-		logger.Field{Key: "version", Value: "1.0.0"},
-		logger.Field{Key: "environment", Value: "development"},
+		logger.String("version", "1.0.0"),
+		logger.String("environment", "development"),
 	)
 
 	mcpLogger.Debug("Debug message with metadata",
-		logger.Field{Key: "request_id", Value: "req-123"},
-		logger.Field{Key: "user_id", Value: "user-456"},
+		logger.String("request_id", "req-123"),
+		logger.String("user_id", "user-456"),
 	)
 
 	mcpLogger.Warn("Warning message")
 
 	mcpLogger.Error("Error occurred",
-		logger.Field{Key: "error_code", Value: "E001"},
-		logger.Field{Key: "stack_trace", Value: "stacktrace here..."},
+		logger.String("error_code", "E001"),
+		logger.String("stack_trace", "stacktrace here..."),
 	)
 
 	contextLogger := mcpLogger.WithFields(
-		logger.Field{Key: "module", Value: "auth"},
-		logger.Field{Key: "operation", Value: "login"},
+		logger.String("module", "auth"),
+		logger.String("operation", "login"),
 	)
 
 	contextLogger.Info("User login attempt",
-		logger.Field{Key: "username", Value: "john_doe"},
+		logger.String("username", "john_doe"),
 	)
 
 	time.Sleep(6 * time.Second)
@@ -336,3 +336,78 @@ func TestLogrusFormatter(t *testing.T) {
 		t.Errorf("Expected 1 log entry, got %d", len(mockLog.entries))
 	}
 }
+
+type kitLevel string
+
+func (l kitLevel) String() string {
+	return string(l)
+}
+
+func TestGoKitLogger(t *testing.T) {
+	mockLog := newMockLogger()
+	kitLogger := NewGoKitLogger(mockLog)
+
+	if err := kitLogger.Log("level", kitLevel("error"), "msg", "Request failed", "status", 500); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	kitLogger.Log("method", "GET", "took", 2*time.Second)
+	kitLogger.Log("msg", "odd", "dangling")
+	kitLogger.WithKeys("message", "severity").Log("severity", "warn", "message", "Custom keys")
+
+	if len(mockLog.entries) != 4 {
+		t.Fatalf("Expected 4 log entries, got %d", len(mockLog.entries))
+	}
+
+	entry := mockLog.entries[0]
+	if entry.level != logger.LogLevelError || entry.message != "Request failed" {
+		t.Errorf("Expected ERROR 'Request failed', got %s '%s'", entry.level, entry.message)
+	}
+	if len(entry.fields) != 1 || entry.fields[0].Key != "status" || entry.fields[0].Value != 500 {
+		t.Errorf("Expected the status field, got %v", entry.fields)
+	}
+
+	entry = mockLog.entries[1]
+	if entry.level != logger.LogLevelInfo || entry.message != "method=GET took=2s" {
+		t.Errorf("Expected INFO 'method=GET took=2s', got %s '%s'", entry.level, entry.message)
+	}
+
+	entry = mockLog.entries[2]
+	if len(entry.fields) != 1 || entry.fields[0].Value != "(MISSING)" {
+		t.Errorf("Expected the dangling key to get a missing value, got %v", entry.fields)
+	}
+
+	entry = mockLog.entries[3]
+	if entry.level != logger.LogLevelWarn || entry.message != "Custom keys" {
+		t.Errorf("Expected WARN 'Custom keys', got %s '%s'", entry.level, entry.message)
+	}
+}
+
+func TestApexLogWriter(t *testing.T) {
+	mockLog := newMockLogger()
+	writer := NewApexLogWriter(mockLog)
+
+	line := `{"fields":{"user":"tj","file":"sloth.png"},"level":"error","timestamp":"2024-01-01T00:00:00Z","message":"upload failed"}` + "\n"
+	n, err := writer.Write([]byte(line[:40]))
+	if err != nil || n != 40 {
+		t.Errorf("Expected 40 bytes written without error, got %d, %v", n, err)
+	}
+	if len(mockLog.entries) != 0 {
+		t.Fatalf("Expected incomplete lines to be kept, got %d entries", len(mockLog.entries))
+	}
+	writer.Write([]byte(line[40:] + `{"fields":{},"level":"debug","message":"cache miss"}` + "\nplain text\n"))
+
+	if len(mockLog.entries) != 3 {
+		t.Fatalf("Expected 3 log entries, got %d", len(mockLog.entries))
+	}
+
+	entry := mockLog.entries[0]
+	if entry.level != logger.LogLevelError || entry.message != "upload failed" || len(entry.fields) != 2 {
+		t.Errorf("Expected ERROR 'upload failed' with 2 fields, got %s '%s' %v", entry.level, entry.message, entry.fields)
+	}
+	if entry := mockLog.entries[1]; entry.level != logger.LogLevelDebug || entry.message != "cache miss" {
+		t.Errorf("Expected DEBUG 'cache miss', got %s '%s'", entry.level, entry.message)
+	}
+	if entry := mockLog.entries[2]; entry.level != logger.LogLevelInfo || entry.message != "plain text" {
+		t.Errorf("Expected INFO 'plain text', got %s '%s'", entry.level, entry.message)
+	}
+}
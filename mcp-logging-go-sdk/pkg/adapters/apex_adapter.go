@@ -0,0 +1,94 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/kerlexov/mcp-logging-go-sdk/pkg/logger"
+)
+
+// ApexLogWriter forwards the output of apex/log's JSON handler, without depending on apex/log:
+//
+//	log.SetHandler(json.New(adapters.NewApexLogWriter(mcpLogger)))
+//
+// Each line is an entry with the level, message and fields of the apex/log entry. Lines that
+// are not apex/log entries are logged as info messages.
+type ApexLogWriter struct {
+	mcpLogger logger.Logger
+	mu        sync.Mutex
+	pending   []byte
+}
+
+// apexEntry is an entry as encoded by apex/log's JSON handler
+type apexEntry struct {
+	Fields  map[string]interface{} `json:"fields"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+}
+
+func NewApexLogWriter(mcpLogger logger.Logger) *ApexLogWriter {
+	return &ApexLogWriter{
+		mcpLogger: mcpLogger,
+	}
+}
+
+// Write logs every complete line of p, keeping an incomplete last line for the next write
+func (w *ApexLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		i := bytes.IndexByte(w.pending, '\n')
+		if i < 0 {
+			break
+		}
+		w.logLine(w.pending[:i])
+		w.pending = w.pending[i+1:]
+	}
+	if len(w.pending) == 0 {
+		w.pending = nil
+	}
+
+	return len(p), nil
+}
+
+func (w *ApexLogWriter) logLine(line []byte) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+
+	var entry apexEntry
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Level == "" {
+		w.mcpLogger.Info(string(line))
+		return
+	}
+
+	fields := make([]logger.Field, 0, len(entry.Fields))
+	for key, value := range entry.Fields {
+		fields = append(fields, logger.Any(key, value))
+	}
+
+	logAtLevel(w.mcpLogger, apexLevel(entry.Level), entry.Message, fields)
+}
+
+// apexLevel maps the level names of apex/log to log levels
+func apexLevel(level string) logger.LogLevel {
+	switch level {
+	case "debug":
+		return logger.LogLevelDebug
+	case "warn":
+		return logger.LogLevelWarn
+	case "error":
+		return logger.LogLevelError
+	case "fatal":
+		return logger.LogLevelFatal
+	default:
+		return logger.LogLevelInfo
+	}
+}
+
+var _ io.Writer = (*ApexLogWriter)(nil)
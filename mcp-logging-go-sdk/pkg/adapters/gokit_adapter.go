@@ -0,0 +1,104 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-go-sdk/pkg/logger"
+)
+
+// GoKitLogger implements go-kit's log.Logger interface, so it can be passed wherever a go-kit
+// logger is expected, including log.With and level.NewFilter, without depending on go-kit.
+// The "msg" and "level" keys become the message and level of the entry, other pairs fields.
+type GoKitLogger struct {
+	mcpLogger  logger.Logger
+	messageKey string
+	levelKey   string
+}
+
+func NewGoKitLogger(mcpLogger logger.Logger) *GoKitLogger {
+	return &GoKitLogger{
+		mcpLogger:  mcpLogger,
+		messageKey: "msg",
+		levelKey:   "level",
+	}
+}
+
+// WithKeys returns a copy of the logger reading the message and level from other keys
+func (g *GoKitLogger) WithKeys(messageKey, levelKey string) *GoKitLogger {
+	return &GoKitLogger{
+		mcpLogger:  g.mcpLogger,
+		messageKey: messageKey,
+		levelKey:   levelKey,
+	}
+}
+
+// Log forwards a go-kit record. Entries without a message use the remaining pairs in logfmt
+// style as message, since go-kit services often log key-value pairs only.
+func (g *GoKitLogger) Log(keyvals ...interface{}) error {
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals, "(MISSING)")
+	}
+
+	level := logger.LogLevelInfo
+	message := ""
+	hasMessage := false
+	fields := make([]logger.Field, 0, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		value := keyvals[i+1]
+		switch key {
+		case g.messageKey:
+			message = fmt.Sprint(value)
+			hasMessage = true
+		case g.levelKey:
+			level = goKitLevel(value)
+		default:
+			fields = append(fields, logger.Any(key, value))
+		}
+	}
+
+	if !hasMessage {
+		pairs := make([]string, len(fields))
+		for i, field := range fields {
+			pairs[i] = fmt.Sprintf("%s=%v", field.Key, field.Value)
+		}
+		message = strings.Join(pairs, " ")
+	}
+
+	logAtLevel(g.mcpLogger, level, message, fields)
+	return nil
+}
+
+// goKitLevel maps the values of go-kit's level package, which format as "debug", "info",
+// "warn" and "error", to log levels
+func goKitLevel(value interface{}) logger.LogLevel {
+	switch strings.ToLower(fmt.Sprint(value)) {
+	case "debug", "trace":
+		return logger.LogLevelDebug
+	case "warn", "warning":
+		return logger.LogLevelWarn
+	case "error":
+		return logger.LogLevelError
+	case "fatal", "panic", "crit", "critical":
+		return logger.LogLevelFatal
+	default:
+		return logger.LogLevelInfo
+	}
+}
+
+// logAtLevel logs a message with the method of the given level
+func logAtLevel(mcpLogger logger.Logger, level logger.LogLevel, message string, fields []logger.Field) {
+	switch level {
+	case logger.LogLevelDebug:
+		mcpLogger.Debug(message, fields...)
+	case logger.LogLevelWarn:
+		mcpLogger.Warn(message, fields...)
+	case logger.LogLevelError:
+		mcpLogger.Error(message, fields...)
+	case logger.LogLevelFatal:
+		mcpLogger.Fatal(message, fields...)
+	default:
+		mcpLogger.Info(message, fields...)
+	}
+}
@@ -40,10 +40,7 @@ func (hook *LogrusHook) Fire(entry *logrus.Entry) error {
 
 	fields := make([]logger.Field, 0, len(entry.Data))
 	for key, value := range entry.Data {
-		fields = append(fields, logger.Field{
-			Key:   key,
-			Value: value,
-		})
+		fields = append(fields, logger.Any(key, value))
 	}
 
 	switch level {
@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Annotation is an operator note attached to a stored log entry, such as a ticket reference or
+// a false positive verdict. The server returns annotations with the entry without changing it.
+type Annotation struct {
+	Text      string    `json:"text"`
+	Author    string    `json:"author,omitempty"` // Defaults to the name of the server's API key
+	CreatedAt time.Time `json:"created_at"`       // Set by the server
+}
+
+// Annotate appends an annotation to the entry with the given ID and returns all annotations of
+// the entry. Unlike Send it is not retried, since repeating it would add the annotation twice.
+// An unknown entry is reported with ErrorCodeLogNotFound.
+func (h *HTTPSender) Annotate(ctx context.Context, logID string, annotation Annotation) ([]Annotation, error) {
+	data, err := json.Marshal(struct {
+		Text   string `json:"text"`
+		Author string `json:"author,omitempty"`
+	}{Text: annotation.Text, Author: annotation.Author})
+	if err != nil {
+		return nil, ErrServerError("failed to marshal annotation", err)
+	}
+
+	annotateURL := h.baseURL + "/v1/logs/" + url.PathEscape(logID) + "/annotations"
+	req, err := http.NewRequestWithContext(ctx, "POST", annotateURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrNetworkError("failed to create request", err)
+	}
+	for key, value := range h.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, ErrNetworkError("failed to send request", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, &Error{
+			Type:    ErrTypeServerError,
+			Message: fmt.Sprintf("annotating log entry failed with status %d", resp.StatusCode),
+			Err:     responseError(resp, body),
+		}
+	}
+
+	var response struct {
+		Annotations []Annotation `json:"annotations"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, ErrServerError("failed to parse annotations", err)
+	}
+
+	return response.Annotations, nil
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPSender_Annotate(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/logs/log-1/annotations" {
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"title":"Log entry does not exist","status":404,"code":"LOG_NOT_FOUND"}`))
+			return
+		}
+
+		var request map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request["text"] != "known issue JIRA-123" || request["author"] != "oncall" {
+			t.Errorf("Unexpected request %v", request)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"log-1","annotations":[{"text":"known issue JIRA-123","author":"oncall","created_at":"2024-01-01T00:00:00Z"}]}`))
+	}))
+	defer server.Close()
+
+	sender := NewHTTPSender(server.URL, 5*time.Second)
+	annotations, err := sender.Annotate(context.Background(), "log-1", Annotation{Text: "known issue JIRA-123", Author: "oncall"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(annotations) != 1 || annotations[0].Author != "oncall" || annotations[0].CreatedAt.IsZero() {
+		t.Errorf("Unexpected annotations %+v", annotations)
+	}
+
+	_, err = sender.Annotate(context.Background(), "missing", Annotation{Text: "x"})
+	if code := ErrorCodeOf(err); code != ErrorCodeLogNotFound {
+		t.Errorf("Expected %s, got %q (%v)", ErrorCodeLogNotFound, code, err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected annotations not to be retried, got %d requests", requests)
+	}
+}
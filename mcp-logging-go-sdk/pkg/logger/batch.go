@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"encoding/json"
+	"strconv"
+	"unicode/utf8"
+)
+
+// DefaultMaxBatchBytes keeps requests well below the server's 10MB request size limit, leaving
+// room for estimation errors
+const DefaultMaxBatchBytes = 8 << 20
+
+// Estimated JSON size of an entry's field names, punctuation and timestamp, and of the
+// envelope of a request
+const (
+	entryOverhead   = 256
+	requestOverhead = 16
+)
+
+// truncationMarker ends stack traces cut to fit an entry into a batch
+const truncationMarker = "\n...(truncated)"
+
+// estimateSize returns the approximate size of an entry serialized as JSON. Strings are measured
+// with their escapes and metadata values other than strings, booleans and numbers are marshaled.
+func estimateSize(entry *LogEntry) int {
+	size := entryOverhead +
+		jsonStringSize(entry.ID) +
+		jsonStringSize(entry.Message) +
+		jsonStringSize(entry.ServiceName) +
+		jsonStringSize(entry.AgentID) +
+		jsonStringSize(entry.Platform) +
+		jsonStringSize(entry.RequestID) +
+		jsonStringSize(entry.StackTrace)
+
+	if entry.SourceLocation != nil {
+		size += jsonStringSize(entry.SourceLocation.File) + jsonStringSize(entry.SourceLocation.Function) + 64
+	}
+	if entry.DeviceInfo != nil {
+		info := entry.DeviceInfo
+		size += jsonStringSize(info.Platform) + jsonStringSize(info.Version) + jsonStringSize(info.Model) + jsonStringSize(info.AppVersion) + 64
+	}
+	for key, value := range entry.Metadata {
+		size += jsonStringSize(key) + jsonValueSize(value) + 2
+	}
+
+	return size
+}
+
+// jsonStringSize returns the size of s encoded as a JSON string by encoding/json
+func jsonStringSize(s string) int {
+	size := 2 // Quotes
+	for i := 0; i < len(s); {
+		encoded, width := encodedRuneSize(s[i:])
+		size += encoded
+		i += width
+	}
+	return size
+}
+
+// encodedRuneSize returns the encoded size of the first rune of s in a JSON string, and its width in s
+func encodedRuneSize(s string) (int, int) {
+	if c := s[0]; c < utf8.RuneSelf {
+		switch {
+		case c == '"' || c == '\\' || c == '\n' || c == '\r' || c == '\t':
+			return 2, 1
+		case c < 0x20 || c == '<' || c == '>' || c == '&':
+			return 6, 1 // \u00XX
+		default:
+			return 1, 1
+		}
+	}
+
+	r, width := utf8.DecodeRuneInString(s)
+	switch {
+	case r == utf8.RuneError && width == 1:
+		return 3, 1 // Replaced by U+FFFD
+	case r == '\u2028' || r == '\u2029':
+		return 6, width
+	default:
+		return width, width
+	}
+}
+
+// jsonValueSize returns the size of a metadata value encoded as JSON
+func jsonValueSize(value interface{}) int {
+	switch v := value.(type) {
+	case nil:
+		return 4
+	case string:
+		return jsonStringSize(v)
+	case bool:
+		return 5
+	case int:
+		return len(strconv.Itoa(v))
+	case int64:
+		return len(strconv.FormatInt(v, 10))
+	case float64:
+		return len(strconv.FormatFloat(v, 'g', -1, 64))
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return 0 // Fails the request anyway
+		}
+		return len(data)
+	}
+}
+
+// fitEntry truncates the stack trace of an entry that alone exceeds maxBytes, so that it can
+// be sent at all. Entries that are still too large are left for the server to reject.
+func fitEntry(entry *LogEntry, maxBytes int) {
+	excess := estimateSize(entry) - maxBytes
+	if excess <= 0 || entry.StackTrace == "" {
+		return
+	}
+
+	budget := jsonStringSize(entry.StackTrace) - excess - jsonStringSize(truncationMarker)
+	keep, size := 0, 0
+	for keep < len(entry.StackTrace) {
+		encoded, width := encodedRuneSize(entry.StackTrace[keep:])
+		if size+encoded > budget {
+			break
+		}
+		size += encoded
+		keep += width
+	}
+	entry.StackTrace = entry.StackTrace[:keep] + truncationMarker
+}
+
+// splitBatches splits entries into consecutive batches whose estimated request size stays
+// within maxBytes. An entry larger than maxBytes is sent in a batch of its own.
+func splitBatches(entries []LogEntry, maxBytes int) [][]LogEntry {
+	var batches [][]LogEntry
+	start, size := 0, requestOverhead
+	for i := range entries {
+		entrySize := estimateSize(&entries[i]) + 1 // Separating comma
+		if i > start && size+entrySize > maxBytes {
+			batches = append(batches, entries[start:i])
+			start, size = i, requestOverhead
+		}
+		size += entrySize
+	}
+	if start < len(entries) {
+		batches = append(batches, entries[start:])
+	}
+	return batches
+}
@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingSender struct {
+	batches [][]LogEntry
+}
+
+func (s *recordingSender) Send(ctx context.Context, entries []LogEntry) error {
+	s.batches = append(s.batches, entries)
+	return nil
+}
+
+func (s *recordingSender) Close() error {
+	return nil
+}
+
+func requestSize(t *testing.T, entries []LogEntry) int {
+	data, err := json.Marshal(struct {
+		Logs []LogEntry `json:"logs"`
+	}{Logs: entries})
+	if err != nil {
+		t.Fatalf("Failed to marshal entries: %v", err)
+	}
+	return len(data)
+}
+
+func TestJSONStringSize(t *testing.T) {
+	for _, s := range []string{"", "plain", "quote \" and \\ backslash", "tabs\tand\nnewlines\r", "<html> & \x01", "ünïcödé ✓", "line\u2028separator", "invalid \xff utf-8"} {
+		data, _ := json.Marshal(s)
+		if got := jsonStringSize(s); got != len(data) {
+			t.Errorf("Expected size %d for %q, got %d", len(data), s, got)
+		}
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	entry := LogEntry{
+		ID:             generateID(),
+		Timestamp:      time.Now().UTC(),
+		Level:          LogLevelError,
+		Message:        "Request failed",
+		ServiceName:    "checkout",
+		AgentID:        "agent-1",
+		Platform:       "go",
+		Metadata:       map[string]interface{}{"status": 500, "path": "/orders", "retry": true, "ids": []string{"a", "b"}},
+		StackTrace:     strings.Repeat("main.handler()\n\t/app/main.go:42\n", 100),
+		SourceLocation: &SourceLocation{File: "main.go", Line: 42, Function: "main.handler"},
+	}
+
+	data, _ := json.Marshal(entry)
+	estimate := estimateSize(&entry)
+	if estimate < len(data) || estimate > len(data)+512 {
+		t.Errorf("Expected an estimate slightly above the real size %d, got %d", len(data), estimate)
+	}
+}
+
+func TestSplitBatches(t *testing.T) {
+	entries := make([]LogEntry, 50)
+	for i := range entries {
+		entries[i] = LogEntry{
+			ID:         generateID(),
+			Timestamp:  time.Now().UTC(),
+			Level:      LogLevelError,
+			Message:    "Crash",
+			Metadata:   map[string]interface{}{},
+			StackTrace: strings.Repeat("x", 1000*(i%5+1)),
+		}
+	}
+
+	const maxBytes = 10000
+	batches := splitBatches(entries, maxBytes)
+
+	count := 0
+	for _, batch := range batches {
+		if size := requestSize(t, batch); size > maxBytes {
+			t.Errorf("Expected batches within %d bytes, got %d", maxBytes, size)
+		}
+		count += len(batch)
+	}
+	if count != len(entries) || batches[0][0].ID != entries[0].ID {
+		t.Errorf("Expected all entries in order, got %d", count)
+	}
+	if len(batches) < 2 {
+		t.Errorf("Expected several batches, got %d", len(batches))
+	}
+
+	if got := splitBatches(entries[:3], 1<<20); len(got) != 1 {
+		t.Errorf("Expected small entries to be sent at once, got %d batches", len(got))
+	}
+}
+
+func TestFitEntry(t *testing.T) {
+	entry := LogEntry{
+		ID:         generateID(),
+		Message:    "Crash",
+		Metadata:   map[string]interface{}{},
+		StackTrace: strings.Repeat("frame\n\t<file>:1\n", 10000),
+	}
+
+	const maxBytes = 4096
+	fitEntry(&entry, maxBytes)
+
+	if !strings.HasSuffix(entry.StackTrace, truncationMarker) {
+		t.Error("Expected the stack trace to be marked as truncated")
+	}
+	if size := requestSize(t, []LogEntry{entry}); size > maxBytes {
+		t.Errorf("Expected the entry to fit into %d bytes, got %d", maxBytes, size)
+	}
+
+	small := LogEntry{StackTrace: "frame"}
+	fitEntry(&small, maxBytes)
+	if small.StackTrace != "frame" {
+		t.Errorf("Expected small entries to be unchanged, got %q", small.StackTrace)
+	}
+}
+
+func TestFlushSplitsBatches(t *testing.T) {
+	config := DefaultConfig()
+	config.ServiceName = "test-service"
+	config.AgentID = "test-agent"
+	config.FlushInterval = time.Hour
+	config.EnableHealthCheck = false
+	config.MaxBatchBytes = 4096
+
+	l, err := New(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	logger := l.(*mcpLogger)
+	sender := &recordingSender{}
+	logger.sender = sender
+	defer logger.Close()
+
+	for i := 0; i < 20; i++ {
+		logger.Error("Crash", String("stack", strings.Repeat("frame ", 100)))
+	}
+	logger.flush()
+
+	count := 0
+	for _, batch := range sender.batches {
+		if size := requestSize(t, batch); size > config.MaxBatchBytes {
+			t.Errorf("Expected requests within %d bytes, got %d", config.MaxBatchBytes, size)
+		}
+		count += len(batch)
+	}
+	if count != 20 || len(sender.batches) < 2 {
+		t.Errorf("Expected 20 entries in several requests, got %d in %d", count, len(sender.batches))
+	}
+}
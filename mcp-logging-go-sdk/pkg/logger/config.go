@@ -2,20 +2,28 @@ package logger
 
 import (
 	"errors"
+	"log"
+	"os"
 	"time"
 )
 
 type Config struct {
-	ServerURL           string        `json:"server_url" yaml:"server_url"`
-	ServiceName         string        `json:"service_name" yaml:"service_name"`
-	AgentID             string        `json:"agent_id" yaml:"agent_id"`
-	BufferSize          int           `json:"buffer_size" yaml:"buffer_size"`
-	FlushInterval       time.Duration `json:"flush_interval" yaml:"flush_interval"`
-	RetryConfig         RetryConfig   `json:"retry_config" yaml:"retry_config"`
-	HTTPTimeout         time.Duration `json:"http_timeout" yaml:"http_timeout"`
-	EnableHealthCheck   bool          `json:"enable_health_check" yaml:"enable_health_check"`
-	HealthCheckInterval time.Duration `json:"health_check_interval" yaml:"health_check_interval"`
-	MaxRetries          int           `json:"max_retries" yaml:"max_retries"`
+	ServerURL           string          `json:"server_url" yaml:"server_url"`
+	ServiceName         string          `json:"service_name" yaml:"service_name"`
+	AgentID             string          `json:"agent_id" yaml:"agent_id"`
+	BufferSize          int             `json:"buffer_size" yaml:"buffer_size"`
+	FlushInterval       time.Duration   `json:"flush_interval" yaml:"flush_interval"`
+	RetryConfig         RetryConfig     `json:"retry_config" yaml:"retry_config"`
+	HTTPTimeout         time.Duration   `json:"http_timeout" yaml:"http_timeout"`
+	EnableHealthCheck   bool            `json:"enable_health_check" yaml:"enable_health_check"`
+	HealthCheckInterval time.Duration   `json:"health_check_interval" yaml:"health_check_interval"`
+	MaxRetries          int             `json:"max_retries" yaml:"max_retries"`
+	CaptureStackTrace   bool            `json:"capture_stack_trace" yaml:"capture_stack_trace"` // Add the caller's stack trace to Error and Fatal entries
+	MaxBatchBytes       int             `json:"max_batch_bytes" yaml:"max_batch_bytes"`         // Estimated size limit of a request, flushes are split into several requests above it
+	Transport           TransportConfig `json:"transport" yaml:"transport"`                     // TLS, proxy and dial options of the connections to the server
+	Console             ConsoleConfig   `json:"console" yaml:"console"`                         // Human-readable output next to shipping entries
+	Limits              EntryLimits     `json:"limits" yaml:"limits"`                           // Size limits applied to entries before they are buffered
+	SelfLog             *log.Logger     `json:"-" yaml:"-"`                                     // Receives warnings about the SDK itself, defaults to stderr
 }
 
 type RetryConfig struct {
@@ -35,6 +43,13 @@ func DefaultConfig() Config {
 		EnableHealthCheck:   true,
 		HealthCheckInterval: 30 * time.Second,
 		MaxRetries:          3,
+		MaxBatchBytes:       DefaultMaxBatchBytes,
+		Limits: EntryLimits{
+			MaxMessageLength:    DefaultMaxMessageLength,
+			MaxStackTraceLength: DefaultMaxStackTraceLength,
+			MaxMetadataKeys:     DefaultMaxMetadataKeys,
+			MaxFieldBytes:       DefaultMaxFieldBytes,
+		},
 		RetryConfig: RetryConfig{
 			InitialInterval:     1 * time.Second,
 			MaxInterval:         30 * time.Second,
@@ -55,12 +70,25 @@ func (c *Config) Validate() error {
 	if c.AgentID == "" {
 		return errors.New("agent_id is required")
 	}
+	if err := c.Transport.Validate(); err != nil {
+		return err
+	}
+	if err := c.Console.Validate(); err != nil {
+		return err
+	}
 	if c.BufferSize <= 0 {
 		c.BufferSize = 1000
 	}
 	if c.FlushInterval <= 0 {
 		c.FlushInterval = 5 * time.Second
 	}
+	if c.MaxBatchBytes <= 0 {
+		c.MaxBatchBytes = DefaultMaxBatchBytes
+	}
+	c.Limits.applyDefaults()
+	if c.SelfLog == nil {
+		c.SelfLog = log.New(os.Stderr, "mcp-logging: ", log.LstdFlags)
+	}
 	if c.HTTPTimeout <= 0 {
 		c.HTTPTimeout = 10 * time.Second
 	}
@@ -47,6 +47,11 @@ func TestConfigValidation(t *testing.T) {
 			config:      Config{ServerURL: "http://localhost:8080", ServiceName: "test"},
 			expectError: true,
 		},
+		{
+			name:        "Unknown console level",
+			config:      Config{ServerURL: "http://localhost:8080", ServiceName: "test", AgentID: "agent1", Console: ConsoleConfig{Enabled: true, MinLevel: "VERBOSE"}},
+			expectError: true,
+		},
 	}
 
 	for _, test := range tests {
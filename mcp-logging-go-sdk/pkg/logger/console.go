@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ConsoleConfig configures human-readable output of every entry next to shipping it to the
+// server, to keep a local console while developing
+type ConsoleConfig struct {
+	Enabled  bool      `json:"enabled" yaml:"enabled"`
+	MinLevel LogLevel  `json:"min_level" yaml:"min_level"` // Entries below this level are only shipped, empty writes all
+	NoColor  bool      `json:"no_color" yaml:"no_color"`   // Colors are also off when the writer is not a terminal or NO_COLOR is set
+	Writer   io.Writer `json:"-" yaml:"-"`                 // Defaults to os.Stdout
+}
+
+// Validate checks the minimum level
+func (c *ConsoleConfig) Validate() error {
+	if c.MinLevel != "" && levelRank(c.MinLevel) < 0 {
+		return errors.New("console.min_level must be one of DEBUG, INFO, WARN, ERROR or FATAL")
+	}
+	return nil
+}
+
+const (
+	colorReset   = "\x1b[0m"
+	colorGray    = "\x1b[90m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorBoldRed = "\x1b[1;31m"
+)
+
+// levelColors are the colors of the level names in console output
+var levelColors = map[LogLevel]string{
+	LogLevelDebug: colorBlue,
+	LogLevelInfo:  colorGreen,
+	LogLevelWarn:  colorYellow,
+	LogLevelError: colorRed,
+	LogLevelFatal: colorBoldRed,
+}
+
+// levelRank orders the levels by severity, -1 for unknown levels
+func levelRank(level LogLevel) int {
+	switch level {
+	case LogLevelDebug:
+		return 0
+	case LogLevelInfo:
+		return 1
+	case LogLevelWarn:
+		return 2
+	case LogLevelError:
+		return 3
+	case LogLevelFatal:
+		return 4
+	default:
+		return -1
+	}
+}
+
+// consoleWriter writes entries as single lines like
+// "15:04:05.000 INFO  User logged in  user_id=123 (main.go:42)", followed by the stack trace
+type consoleWriter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	color    bool
+	minLevel int
+}
+
+// newConsoleWriter returns a writer for the console configuration, nil if it is disabled
+func newConsoleWriter(config ConsoleConfig) *consoleWriter {
+	if !config.Enabled {
+		return nil
+	}
+
+	out := config.Writer
+	if out == nil {
+		out = os.Stdout
+	}
+
+	minLevel := 0
+	if config.MinLevel != "" {
+		minLevel = levelRank(config.MinLevel)
+	}
+
+	return &consoleWriter{
+		out:      out,
+		color:    !config.NoColor && os.Getenv("NO_COLOR") == "" && isTerminal(out),
+		minLevel: minLevel,
+	}
+}
+
+// isTerminal reports whether w is a character device such as a terminal
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *consoleWriter) write(entry *LogEntry) {
+	if levelRank(entry.Level) < c.minLevel {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(c.paint(colorGray, entry.Timestamp.Local().Format("15:04:05.000")))
+	buf.WriteByte(' ')
+	buf.WriteString(c.paint(levelColors[entry.Level], fmt.Sprintf("%-5s", entry.Level)))
+	buf.WriteByte(' ')
+	buf.WriteString(entry.Message)
+
+	keys := make([]string, 0, len(entry.Metadata))
+	for key := range entry.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteByte(' ')
+		buf.WriteString(c.paint(colorGray, key+"="))
+		buf.WriteString(formatConsoleValue(entry.Metadata[key]))
+	}
+
+	if loc := entry.SourceLocation; loc != nil {
+		buf.WriteByte(' ')
+		buf.WriteString(c.paint(colorGray, fmt.Sprintf("(%s:%d)", loc.File, loc.Line)))
+	}
+	buf.WriteByte('\n')
+
+	if entry.StackTrace != "" {
+		for _, line := range strings.Split(strings.TrimRight(entry.StackTrace, "\n"), "\n") {
+			buf.WriteString("    ")
+			buf.WriteString(c.paint(colorGray, line))
+			buf.WriteByte('\n')
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.out.Write(buf.Bytes())
+}
+
+func (c *consoleWriter) paint(color, s string) string {
+	if !c.color || color == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// formatConsoleValue formats a field value, quoting strings that contain spaces or quotes
+func formatConsoleValue(value interface{}) string {
+	s := fmt.Sprint(value)
+	if value == nil {
+		s = "null"
+	}
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleWriter(t *testing.T) {
+	var out bytes.Buffer
+	console := newConsoleWriter(ConsoleConfig{Enabled: true, Writer: &out})
+	if console.color {
+		t.Error("Expected no colors for a writer that is not a terminal")
+	}
+
+	console.write(&LogEntry{
+		Timestamp:      time.Date(2024, 1, 1, 12, 30, 45, 0, time.Local),
+		Level:          LogLevelError,
+		Message:        "Payment failed",
+		Metadata:       map[string]interface{}{"order_id": 42, "reason": "card declined", "error": nil},
+		SourceLocation: &SourceLocation{File: "main.go", Line: 17, Function: "main.main"},
+		StackTrace:     "main.main()\n\t/app/main.go:17\n",
+	})
+
+	expected := "12:30:45.000 ERROR Payment failed  error=null order_id=42 reason=\"card declined\" (main.go:17)\n" +
+		"    main.main()\n" +
+		"    \t/app/main.go:17\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestConsoleWriter_Colors(t *testing.T) {
+	var out bytes.Buffer
+	console := newConsoleWriter(ConsoleConfig{Enabled: true, Writer: &out})
+	console.color = true
+
+	console.write(&LogEntry{Timestamp: time.Now(), Level: LogLevelWarn, Message: "Slow query"})
+
+	if !strings.Contains(out.String(), colorYellow+"WARN "+colorReset) {
+		t.Errorf("Expected a yellow level, got %q", out.String())
+	}
+}
+
+func TestConsoleWriter_MinLevel(t *testing.T) {
+	var out bytes.Buffer
+	console := newConsoleWriter(ConsoleConfig{Enabled: true, Writer: &out, MinLevel: LogLevelWarn})
+
+	console.write(&LogEntry{Timestamp: time.Now(), Level: LogLevelInfo, Message: "hidden"})
+	console.write(&LogEntry{Timestamp: time.Now(), Level: LogLevelError, Message: "shown"})
+
+	if strings.Contains(out.String(), "hidden") || !strings.Contains(out.String(), "shown") {
+		t.Errorf("Expected only entries from WARN up, got %q", out.String())
+	}
+
+	if newConsoleWriter(ConsoleConfig{Writer: &out}) != nil {
+		t.Error("Expected no writer when the console is disabled")
+	}
+}
+
+func TestLogger_ConsoleOutput(t *testing.T) {
+	var out bytes.Buffer
+	config := DefaultConfig()
+	config.ServiceName = "test-service"
+	config.AgentID = "test-agent"
+	config.FlushInterval = time.Hour
+	config.EnableHealthCheck = false
+	config.Console = ConsoleConfig{Enabled: true, Writer: &out}
+
+	l, err := New(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer l.Close()
+
+	l.Info("Server started", Int("port", 8080))
+	l.Error("Request failed", Err(errors.New("timeout")))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 console lines, got %q", out.String())
+	}
+	if !strings.Contains(lines[0], "INFO  Server started  port=8080 (console_test.go:") {
+		t.Errorf("Unexpected info line %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "ERROR Request failed  error=timeout") {
+		t.Errorf("Unexpected error line %q", lines[1])
+	}
+	if entries := l.(*mcpLogger).buffer.Size(); entries != 2 {
+		t.Errorf("Expected the entries to be buffered for the server too, got %d", entries)
+	}
+}
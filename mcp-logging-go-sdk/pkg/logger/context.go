@@ -0,0 +1,25 @@
+package logger
+
+import "context"
+
+// MaxRequestIDLength is the longest request ID the server accepts in the request_id field
+const MaxRequestIDLength = 128
+
+// requestIDKey stores the request ID in contexts
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying a request ID. Entries logged with the context
+// methods of a logger, such as InfoContext, are tagged with it, so all entries of a request
+// can be queried across services by their request_id.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID set with WithRequestID, empty if none is
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrorKey is the metadata key of fields created by Err
+const ErrorKey = "error"
+
+// String returns a field with a string value
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int returns a field with an integer value
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Float returns a field with a floating point value
+func Float(key string, value float64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool returns a field with a boolean value
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Err returns a field holding the message of err under ErrorKey, or nil if err is nil.
+// Errors stored as raw values are usually serialized as an empty object.
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: ErrorKey, Value: nil}
+	}
+	return Field{Key: ErrorKey, Value: err.Error()}
+}
+
+// Time returns a field with the time formatted as RFC 3339 in UTC
+func Time(key string, value time.Time) Field {
+	return Field{Key: key, Value: value.UTC().Format(time.RFC3339Nano)}
+}
+
+// Duration returns a field with the duration formatted like "1.5s"
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value.String()}
+}
+
+// Any returns a field with an arbitrary value. Values of the types above are converted like
+// their constructors do, other values are serialized as JSON.
+func Any(key string, value interface{}) Field {
+	switch v := value.(type) {
+	case time.Time:
+		return Time(key, v)
+	case time.Duration:
+		return Duration(key, v)
+	case error:
+		return Field{Key: key, Value: v.Error()}
+	case fmt.Stringer:
+		return Field{Key: key, Value: v.String()}
+	default:
+		return Field{Key: key, Value: value}
+	}
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFieldConstructors(t *testing.T) {
+	timestamp := time.Date(2024, 3, 1, 12, 30, 0, 500, time.FixedZone("CET", 3600))
+
+	tests := []struct {
+		name     string
+		field    Field
+		expected Field
+	}{
+		{"string", String("user_id", "123"), Field{Key: "user_id", Value: "123"}},
+		{"int", Int("attempt", 3), Field{Key: "attempt", Value: 3}},
+		{"float", Float("ratio", 0.5), Field{Key: "ratio", Value: 0.5}},
+		{"bool", Bool("cached", true), Field{Key: "cached", Value: true}},
+		{"error", Err(errors.New("connection refused")), Field{Key: ErrorKey, Value: "connection refused"}},
+		{"nil error", Err(nil), Field{Key: ErrorKey, Value: nil}},
+		{"time", Time("started_at", timestamp), Field{Key: "started_at", Value: "2024-03-01T11:30:00.0000005Z"}},
+		{"duration", Duration("elapsed", 1500*time.Millisecond), Field{Key: "elapsed", Value: "1.5s"}},
+		{"any error", Any("cause", errors.New("timeout")), Field{Key: "cause", Value: "timeout"}},
+		{"any time", Any("at", timestamp), Field{Key: "at", Value: "2024-03-01T11:30:00.0000005Z"}},
+		{"any duration", Any("took", time.Second), Field{Key: "took", Value: "1s"}},
+		{"any stringer", Any("ip", net.IPv4(10, 0, 0, 1)), Field{Key: "ip", Value: "10.0.0.1"}},
+		{"any map", Any("tags", map[string]int{"a": 1}), Field{Key: "tags", Value: map[string]int{"a": 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := json.Marshal(tt.field.Value)
+			expected, _ := json.Marshal(tt.expected.Value)
+			if tt.field.Key != tt.expected.Key || string(got) != string(expected) {
+				t.Errorf("Expected %s=%s, got %s=%s", tt.expected.Key, expected, tt.field.Key, got)
+			}
+		})
+	}
+}
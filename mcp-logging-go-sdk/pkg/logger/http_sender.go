@@ -12,6 +12,7 @@ import (
 
 type HTTPSender struct {
 	client         *http.Client
+	baseURL        string
 	serverURL      string
 	headers        map[string]string
 	retryer        *retryer
@@ -19,6 +20,12 @@ type HTTPSender struct {
 }
 
 func NewHTTPSender(serverURL string, timeout time.Duration) *HTTPSender {
+	return NewHTTPSenderWithTransport(serverURL, timeout, nil)
+}
+
+// NewHTTPSenderWithTransport returns a sender using transport for its connections, nil uses
+// http.DefaultTransport
+func NewHTTPSenderWithTransport(serverURL string, timeout time.Duration, transport http.RoundTripper) *HTTPSender {
 	retryConfig := RetryConfig{
 		InitialInterval:     1 * time.Second,
 		MaxInterval:         30 * time.Second,
@@ -28,8 +35,10 @@ func NewHTTPSender(serverURL string, timeout time.Duration) *HTTPSender {
 	}
 	return &HTTPSender{
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: transport,
 		},
+		baseURL:   serverURL,
 		serverURL: serverURL + "/api/logs",
 		headers: map[string]string{
 			"Content-Type": "application/json",
@@ -77,7 +86,7 @@ func (h *HTTPSender) Send(ctx context.Context, entries []LogEntry) error {
 				body, _ := io.ReadAll(resp.Body)
 				return ErrServerError(
 					fmt.Sprintf("server returned status %d", resp.StatusCode),
-					fmt.Errorf("response body: %s", string(body)),
+					responseError(resp, body),
 				)
 			}
 
@@ -86,7 +95,7 @@ func (h *HTTPSender) Send(ctx context.Context, entries []LogEntry) error {
 				return &Error{
 					Type:    ErrTypeServerError,
 					Message: fmt.Sprintf("client error: status %d", resp.StatusCode),
-					Err:     fmt.Errorf("response body: %s", string(body)),
+					Err:     responseError(resp, body),
 				}
 			}
 
@@ -95,6 +104,14 @@ func (h *HTTPSender) Send(ctx context.Context, entries []LogEntry) error {
 	})
 }
 
+// responseError describes an error response, as a *Problem if the server reported one
+func responseError(resp *http.Response, body []byte) error {
+	if problem := parseProblem(resp, body); problem != nil {
+		return problem
+	}
+	return fmt.Errorf("response body: %s", string(body))
+}
+
 func (h *HTTPSender) HealthCheck(ctx context.Context) error {
 	healthURL := h.serverURL + "/health"
 
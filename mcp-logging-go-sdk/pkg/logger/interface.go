@@ -25,6 +25,16 @@ type Logger interface {
 	Close() error
 }
 
+// StackLogger is a Logger recording the stack traces of errors, such as the loggers returned by
+// New. Use the ErrorWithStack function to log errors with any Logger.
+type StackLogger interface {
+	Logger
+
+	// ErrorWithStack logs err at the error level with the stack where it was wrapped by
+	// WithStack or Wrap, or else where it is logged
+	ErrorWithStack(msg string, err error, fields ...Field)
+}
+
 type Sender interface {
 	Send(ctx context.Context, entries []LogEntry) error
 	Close() error
@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default entry limits, matching the server's default validation rules except for the field
+// size, which the server only bounds through the request size
+const (
+	DefaultMaxMessageLength    = 10000
+	DefaultMaxStackTraceLength = 50000
+	DefaultMaxMetadataKeys     = 50
+	DefaultMaxFieldBytes       = 16 << 10
+)
+
+// TruncatedFieldsKey is the metadata key listing the fields removed from an entry to fit the limits
+const TruncatedFieldsKey = "_truncated_fields"
+
+// messageTruncationMarker ends messages cut to the maximum length
+const messageTruncationMarker = " ...(truncated)"
+
+// EntryLimits bounds entries before they are buffered, so that an oversized entry is shipped
+// truncated instead of the server rejecting its whole batch with VALIDATION_ERROR. Zero values
+// use the defaults; raise them when the server's validation rules are relaxed.
+type EntryLimits struct {
+	MaxMessageLength    int `json:"max_message_length" yaml:"max_message_length"`         // Characters, longer messages are cut with a marker
+	MaxStackTraceLength int `json:"max_stack_trace_length" yaml:"max_stack_trace_length"` // Bytes, longer stack traces are cut with a marker
+	MaxMetadataKeys     int `json:"max_metadata_keys" yaml:"max_metadata_keys"`           // Fields beyond it are dropped, in key order
+	MaxFieldBytes       int `json:"max_field_bytes" yaml:"max_field_bytes"`               // Estimated JSON size of a field value, larger values are dropped
+}
+
+func (l *EntryLimits) applyDefaults() {
+	if l.MaxMessageLength <= 0 {
+		l.MaxMessageLength = DefaultMaxMessageLength
+	}
+	if l.MaxStackTraceLength <= 0 {
+		l.MaxStackTraceLength = DefaultMaxStackTraceLength
+	}
+	if l.MaxMetadataKeys <= 0 {
+		l.MaxMetadataKeys = DefaultMaxMetadataKeys
+	}
+	if l.MaxFieldBytes <= 0 {
+		l.MaxFieldBytes = DefaultMaxFieldBytes
+	}
+}
+
+// enforceLimits truncates the message and stack trace of an entry exceeding the limits and
+// moves oversized or excess metadata fields to TruncatedFieldsKey. It returns a description of
+// each change, empty if the entry was within the limits.
+func enforceLimits(entry *LogEntry, limits EntryLimits) []string {
+	var changes []string
+
+	if length := utf8.RuneCountInString(entry.Message); length > limits.MaxMessageLength {
+		entry.Message = truncateRunes(entry.Message, limits.MaxMessageLength-utf8.RuneCountInString(messageTruncationMarker)) + messageTruncationMarker
+		changes = append(changes, fmt.Sprintf("message of %d characters", length))
+	}
+
+	if length := len(entry.StackTrace); length > limits.MaxStackTraceLength {
+		entry.StackTrace = truncateBytes(entry.StackTrace, limits.MaxStackTraceLength-len(truncationMarker)) + truncationMarker
+		changes = append(changes, fmt.Sprintf("stack trace of %d bytes", length))
+	}
+
+	var truncated []string
+	for key, value := range entry.Metadata {
+		if jsonValueSize(value) > limits.MaxFieldBytes {
+			truncated = append(truncated, key)
+			delete(entry.Metadata, key)
+		}
+	}
+
+	maxKeys := limits.MaxMetadataKeys
+	if len(truncated) > 0 || len(entry.Metadata) > maxKeys {
+		maxKeys-- // Room for TruncatedFieldsKey
+	}
+	if excess := len(entry.Metadata) - maxKeys; excess > 0 {
+		keys := make([]string, 0, len(entry.Metadata))
+		for key := range entry.Metadata {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys[len(keys)-excess:] {
+			truncated = append(truncated, key)
+			delete(entry.Metadata, key)
+		}
+	}
+
+	if len(truncated) > 0 {
+		sort.Strings(truncated)
+		entry.Metadata[TruncatedFieldsKey] = truncated
+		changes = append(changes, fmt.Sprintf("fields %s", strings.Join(truncated, ", ")))
+	}
+
+	return changes
+}
+
+// truncateRunes returns the first n characters of s
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// truncateBytes returns the longest prefix of s of at most n bytes that does not split a character
+func truncateBytes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
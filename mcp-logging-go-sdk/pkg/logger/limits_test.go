@@ -0,0 +1,124 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func testLimits() EntryLimits {
+	var limits EntryLimits
+	limits.applyDefaults()
+	return limits
+}
+
+func TestEnforceLimits_WithinLimits(t *testing.T) {
+	entry := LogEntry{
+		Message:    "ok",
+		StackTrace: "main.main()",
+		Metadata:   map[string]interface{}{"user_id": 42},
+	}
+
+	if changes := enforceLimits(&entry, testLimits()); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+	if _, ok := entry.Metadata[TruncatedFieldsKey]; ok {
+		t.Error("Expected no truncated fields")
+	}
+}
+
+func TestEnforceLimits_MessageAndStackTrace(t *testing.T) {
+	entry := LogEntry{
+		Message:    strings.Repeat("ü", DefaultMaxMessageLength+100),
+		StackTrace: strings.Repeat("€", DefaultMaxStackTraceLength),
+		Metadata:   map[string]interface{}{},
+	}
+
+	changes := enforceLimits(&entry, testLimits())
+	if len(changes) != 2 {
+		t.Errorf("Expected 2 changes, got %v", changes)
+	}
+
+	if length := utf8.RuneCountInString(entry.Message); length != DefaultMaxMessageLength {
+		t.Errorf("Expected a message of %d characters, got %d", DefaultMaxMessageLength, length)
+	}
+	if !strings.HasSuffix(entry.Message, messageTruncationMarker) {
+		t.Error("Expected the message to be marked as truncated")
+	}
+
+	if len(entry.StackTrace) > DefaultMaxStackTraceLength || !utf8.ValidString(entry.StackTrace) {
+		t.Errorf("Expected a valid stack trace within %d bytes, got %d", DefaultMaxStackTraceLength, len(entry.StackTrace))
+	}
+	if !strings.HasSuffix(entry.StackTrace, truncationMarker) {
+		t.Error("Expected the stack trace to be marked as truncated")
+	}
+}
+
+func TestEnforceLimits_Metadata(t *testing.T) {
+	limits := testLimits()
+	limits.MaxMetadataKeys = 5
+	limits.MaxFieldBytes = 100
+
+	entry := LogEntry{Message: "ok", Metadata: map[string]interface{}{
+		"a": 1, "b": 2, "c": 3, "d": 4, "e": 5,
+		"payload": strings.Repeat("x", 200),
+	}}
+
+	changes := enforceLimits(&entry, limits)
+	if len(changes) != 1 {
+		t.Errorf("Expected 1 change, got %v", changes)
+	}
+	if len(entry.Metadata) != 5 {
+		t.Errorf("Expected 5 keys, got %v", entry.Metadata)
+	}
+	if truncated := entry.Metadata[TruncatedFieldsKey]; !reflect.DeepEqual(truncated, []string{"e", "payload"}) {
+		t.Errorf("Expected [e payload] to be truncated, got %v", truncated)
+	}
+}
+
+func TestLogger_TruncatesOversizedEntries(t *testing.T) {
+	var selfLog bytes.Buffer
+	config := DefaultConfig()
+	config.ServiceName = "test-service"
+	config.AgentID = "test-agent"
+	config.FlushInterval = time.Hour
+	config.EnableHealthCheck = false
+	config.Limits.MaxFieldBytes = 1000
+	config.SelfLog = log.New(&selfLog, "", 0)
+
+	l, err := New(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	logger := l.(*mcpLogger)
+	sender := &recordingSender{}
+	logger.sender = sender
+	defer logger.Close()
+
+	fields := []Field{String("body", strings.Repeat("x", 2000))}
+	for i := 0; i < 60; i++ {
+		fields = append(fields, Int(fmt.Sprintf("key_%02d", i), i))
+	}
+	logger.Info(strings.Repeat("m", 20000), fields...)
+	logger.flush()
+
+	if len(sender.batches) != 1 || len(sender.batches[0]) != 1 {
+		t.Fatalf("Expected 1 entry to be sent, got %v", sender.batches)
+	}
+	entry := sender.batches[0][0]
+	if utf8.RuneCountInString(entry.Message) > DefaultMaxMessageLength || len(entry.Metadata) > DefaultMaxMetadataKeys {
+		t.Errorf("Expected the entry within the limits, got %d characters and %d keys", len(entry.Message), len(entry.Metadata))
+	}
+	truncated, _ := entry.Metadata[TruncatedFieldsKey].([]string)
+	if len(truncated) != 12 || truncated[0] != "body" {
+		t.Errorf("Expected body and 11 excess keys to be truncated, got %v", truncated)
+	}
+	if !strings.Contains(selfLog.String(), "Truncated INFO entry") {
+		t.Errorf("Expected a self-log warning, got %q", selfLog.String())
+	}
+}
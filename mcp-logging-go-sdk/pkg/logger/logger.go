@@ -2,7 +2,6 @@ package logger
 
 import (
 	"context"
-	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +11,7 @@ type mcpLogger struct {
 	config        Config
 	sender        Sender
 	buffer        *memoryBuffer
+	console       *consoleWriter
 	defaultFields map[string]interface{}
 	mu            sync.RWMutex
 	closed        bool
@@ -24,13 +24,19 @@ func New(config Config) (Logger, error) {
 		return nil, err
 	}
 
-	sender := NewHTTPSender(config.ServerURL, config.HTTPTimeout)
+	transport, err := NewTransport(config.Transport)
+	if err != nil {
+		return nil, &Error{Type: ErrTypeInvalidConfig, Message: "invalid transport configuration", Err: err}
+	}
+
+	sender := NewHTTPSenderWithTransport(config.ServerURL, config.HTTPTimeout, transport)
 	buffer := newMemoryBuffer(config.BufferSize)
 
 	logger := &mcpLogger{
 		config:        config,
 		sender:        sender,
 		buffer:        buffer,
+		console:       newConsoleWriter(config.Console),
 		defaultFields: make(map[string]interface{}),
 		stopCh:        make(chan struct{}),
 	}
@@ -45,43 +51,58 @@ func New(config Config) (Logger, error) {
 }
 
 func (l *mcpLogger) Debug(msg string, fields ...Field) {
-	l.log(LogLevelDebug, msg, fields...)
+	l.logContext(context.Background(), LogLevelDebug, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) Info(msg string, fields ...Field) {
-	l.log(LogLevelInfo, msg, fields...)
+	l.logContext(context.Background(), LogLevelInfo, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) Warn(msg string, fields ...Field) {
-	l.log(LogLevelWarn, msg, fields...)
+	l.logContext(context.Background(), LogLevelWarn, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) Error(msg string, fields ...Field) {
-	l.log(LogLevelError, msg, fields...)
+	l.logContext(context.Background(), LogLevelError, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) Fatal(msg string, fields ...Field) {
-	l.log(LogLevelFatal, msg, fields...)
+	l.logContext(context.Background(), LogLevelFatal, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) DebugContext(ctx context.Context, msg string, fields ...Field) {
-	l.logContext(ctx, LogLevelDebug, msg, fields...)
+	l.logContext(ctx, LogLevelDebug, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) InfoContext(ctx context.Context, msg string, fields ...Field) {
-	l.logContext(ctx, LogLevelInfo, msg, fields...)
+	l.logContext(ctx, LogLevelInfo, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) WarnContext(ctx context.Context, msg string, fields ...Field) {
-	l.logContext(ctx, LogLevelWarn, msg, fields...)
+	l.logContext(ctx, LogLevelWarn, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) ErrorContext(ctx context.Context, msg string, fields ...Field) {
-	l.logContext(ctx, LogLevelError, msg, fields...)
+	l.logContext(ctx, LogLevelError, msg, callers(), nil, fields)
 }
 
 func (l *mcpLogger) FatalContext(ctx context.Context, msg string, fields ...Field) {
-	l.logContext(ctx, LogLevelFatal, msg, fields...)
+	l.logContext(ctx, LogLevelFatal, msg, callers(), nil, fields)
+}
+
+// ErrorWithStack logs err at the error level with a stack trace: the stack recorded by
+// WithStack or Wrap if err carries one, else the caller's
+func (l *mcpLogger) ErrorWithStack(msg string, err error, fields ...Field) {
+	l.errorWithStack(callers(), msg, err, fields)
+}
+
+// errorWithStack logs err with the stack it carries, or else the caller's
+func (l *mcpLogger) errorWithStack(caller stack, msg string, err error, fields []Field) {
+	trace := errorStack(err)
+	if trace == nil {
+		trace = caller
+	}
+	l.logContext(context.Background(), LogLevelError, msg, caller, trace, append(fields, Err(err)))
 }
 
 func (l *mcpLogger) WithFields(fields ...Field) Logger {
@@ -143,11 +164,9 @@ func (l *mcpLogger) Close() error {
 	return nil
 }
 
-func (l *mcpLogger) log(level LogLevel, msg string, fields ...Field) {
-	l.logContext(context.Background(), level, msg, fields...)
-}
-
-func (l *mcpLogger) logContext(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+// logContext buffers an entry logged at the caller's location. trace is the stack trace of the
+// entry, Error and Fatal entries get the caller's when CaptureStackTrace is set.
+func (l *mcpLogger) logContext(ctx context.Context, level LogLevel, msg string, caller, trace stack, fields []Field) {
 	l.mu.RLock()
 	if l.closed {
 		l.mu.RUnlock()
@@ -188,34 +207,32 @@ func (l *mcpLogger) logContext(ctx context.Context, level LogLevel, msg string,
 		AgentID:        agentID,
 		Platform:       "go",
 		Metadata:       metadata,
-		SourceLocation: l.getSourceLocation(),
+		SourceLocation: caller.location(),
 	}
 
-	if err := l.buffer.Add(entry); err != nil {
-		return
+	// Request IDs too long for the field are kept as metadata rather than failing the batch
+	if requestID := RequestIDFromContext(ctx); len(requestID) > MaxRequestIDLength {
+		entry.Metadata["request_id"] = requestID
+	} else if requestID != "" {
+		entry.RequestID = requestID
 	}
-}
 
-func (l *mcpLogger) getSourceLocation() *SourceLocation {
-	pc, file, line, ok := runtime.Caller(4)
-	if !ok {
-		return nil
+	if trace == nil && l.config.CaptureStackTrace && (level == LogLevelError || level == LogLevelFatal) {
+		trace = caller
 	}
-
-	fn := runtime.FuncForPC(pc)
-	if fn == nil {
-		return nil
+	if trace != nil {
+		entry.StackTrace = trace.String()
 	}
-
-	parts := strings.Split(file, "/")
-	if len(parts) > 0 {
-		file = parts[len(parts)-1]
+	if l.console != nil {
+		l.console.write(&entry)
+	}
+	if changes := enforceLimits(&entry, l.config.Limits); len(changes) > 0 {
+		l.config.SelfLog.Printf("Truncated %s entry %q exceeding the entry limits: %s", entry.Level, entry.ID, strings.Join(changes, "; "))
 	}
+	fitEntry(&entry, l.config.MaxBatchBytes)
 
-	return &SourceLocation{
-		File:     file,
-		Line:     line,
-		Function: fn.Name(),
+	if err := l.buffer.Add(entry); err != nil {
+		return
 	}
 }
 
@@ -265,12 +282,19 @@ func (l *mcpLogger) flush() {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), l.config.HTTPTimeout)
-	defer cancel()
-
-	if err := l.sender.Send(ctx, entries); err != nil {
-		for _, entry := range entries {
-			l.buffer.Add(entry)
+	// Requests are kept below MaxBatchBytes. On a failure, the unsent batches go back to the buffer.
+	batches := splitBatches(entries, l.config.MaxBatchBytes)
+	for i, batch := range batches {
+		ctx, cancel := context.WithTimeout(context.Background(), l.config.HTTPTimeout)
+		err := l.sender.Send(ctx, batch)
+		cancel()
+		if err != nil {
+			for _, unsent := range batches[i:] {
+				for _, entry := range unsent {
+					l.buffer.Add(entry)
+				}
+			}
+			return
 		}
 	}
 }
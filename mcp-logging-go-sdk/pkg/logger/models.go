@@ -22,6 +22,7 @@ type LogEntry struct {
 	ServiceName    string                 `json:"service_name"`
 	AgentID        string                 `json:"agent_id"`
 	Platform       string                 `json:"platform"`
+	RequestID      string                 `json:"request_id,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata"`
 	DeviceInfo     *DeviceInfo            `json:"device_info,omitempty"`
 	StackTrace     string                 `json:"stack_trace,omitempty"`
@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// ErrorCode identifies the kind of an error reported by the server. It mirrors the server's
+// error catalog, so applications can branch on it instead of on error messages.
+type ErrorCode string
+
+const (
+	ErrorCodeInvalidJSON          ErrorCode = "INVALID_JSON"
+	ErrorCodeInvalidRequest       ErrorCode = "INVALID_REQUEST"
+	ErrorCodeValidationError      ErrorCode = "VALIDATION_ERROR"
+	ErrorCodeInvalidQuery         ErrorCode = "INVALID_QUERY"
+	ErrorCodeInvalidSequence      ErrorCode = "INVALID_SEQUENCE"
+	ErrorCodeInvalidSymbolFile    ErrorCode = "INVALID_SYMBOL_FILE"
+	ErrorCodeInvalidConfiguration ErrorCode = "INVALID_CONFIGURATION"
+	ErrorCodeEmptyBatch           ErrorCode = "EMPTY_BATCH"
+	ErrorCodeBatchTooLarge        ErrorCode = "BATCH_TOO_LARGE"
+	ErrorCodeRequestTooLarge      ErrorCode = "REQUEST_TOO_LARGE"
+	ErrorCodeRequestTimeout       ErrorCode = "REQUEST_TIMEOUT"
+
+	ErrorCodeMissingAPIKey           ErrorCode = "MISSING_API_KEY"
+	ErrorCodeInvalidAPIKey           ErrorCode = "INVALID_API_KEY"
+	ErrorCodeMissingSignature        ErrorCode = "MISSING_SIGNATURE"
+	ErrorCodeInvalidSignature        ErrorCode = "INVALID_SIGNATURE"
+	ErrorCodeSignatureExpired        ErrorCode = "SIGNATURE_EXPIRED"
+	ErrorCodeReplayedRequest         ErrorCode = "REPLAYED_REQUEST"
+	ErrorCodeAuthenticationRequired  ErrorCode = "AUTHENTICATION_REQUIRED"
+	ErrorCodeInvalidAuthContext      ErrorCode = "INVALID_AUTH_CONTEXT"
+	ErrorCodeInsufficientPermissions ErrorCode = "INSUFFICIENT_PERMISSIONS"
+	ErrorCodeRateLimitExceeded       ErrorCode = "RATE_LIMIT_EXCEEDED"
+
+	ErrorCodeLogNotFound        ErrorCode = "LOG_NOT_FOUND"
+	ErrorCodeServiceNotFound    ErrorCode = "SERVICE_NOT_FOUND"
+	ErrorCodeAPIKeyNotFound     ErrorCode = "API_KEY_NOT_FOUND"
+	ErrorCodeBatchNotFound      ErrorCode = "BATCH_NOT_FOUND"
+	ErrorCodeLegalHoldNotFound  ErrorCode = "LEGAL_HOLD_NOT_FOUND"
+	ErrorCodeSymbolFileNotFound ErrorCode = "SYMBOL_FILE_NOT_FOUND"
+	ErrorCodeBlockedKeyNotFound ErrorCode = "BLOCKED_KEY_NOT_FOUND"
+	ErrorCodeIncidentNotFound   ErrorCode = "INCIDENT_NOT_FOUND"
+	ErrorCodeToolNotFound       ErrorCode = "TOOL_NOT_FOUND"
+	ErrorCodeNotSupported       ErrorCode = "NOT_SUPPORTED"
+	ErrorCodeReplicationLoop    ErrorCode = "REPLICATION_LOOP"
+
+	ErrorCodeStorageError        ErrorCode = "STORAGE_ERROR"
+	ErrorCodeBufferError         ErrorCode = "BUFFER_ERROR"
+	ErrorCodeFlushError          ErrorCode = "FLUSH_ERROR"
+	ErrorCodeDataProtectionError ErrorCode = "DATA_PROTECTION_ERROR"
+	ErrorCodeProcessorError      ErrorCode = "PROCESSOR_ERROR"
+	ErrorCodeConfigSaveError     ErrorCode = "CONFIG_SAVE_ERROR"
+	ErrorCodeRecoveryStatsError  ErrorCode = "RECOVERY_STATS_ERROR"
+	ErrorCodeInternalError       ErrorCode = "INTERNAL_SERVER_ERROR"
+	ErrorCodeReadOnly            ErrorCode = "READ_ONLY"
+	ErrorCodeMaintenance         ErrorCode = "MAINTENANCE"
+	ErrorCodeServerBusy          ErrorCode = "SERVER_BUSY"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error response of the server
+type Problem struct {
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Status    int             `json:"status"`
+	Detail    string          `json:"detail,omitempty"`
+	Instance  string          `json:"instance,omitempty"`
+	Code      ErrorCode       `json:"code"`
+	RequestID string          `json:"request_id,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	message := fmt.Sprintf("%s (status %d): %s", p.Code, p.Status, p.Title)
+	if p.Detail != "" {
+		message += " - " + p.Detail
+	}
+	if p.RequestID != "" {
+		message += " [request " + p.RequestID + "]"
+	}
+	return message
+}
+
+// ErrorCodeOf returns the server error code of err, or an empty code if the server did not
+// report one
+func ErrorCodeOf(err error) ErrorCode {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem.Code
+	}
+	return ""
+}
+
+// parseProblem decodes a problem details response body, returning nil if it is not one
+func parseProblem(resp *http.Response, body []byte) *Problem {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != problemContentType {
+		return nil
+	}
+
+	var problem Problem
+	if err := json.Unmarshal(body, &problem); err != nil || problem.Code == "" {
+		return nil
+	}
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+	return &problem
+}
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestParseProblem(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Content-Type": []string{"application/problem+json; charset=utf-8"}},
+	}
+	body := []byte(`{"type":"urn:mcp-logging:error:RATE_LIMIT_EXCEEDED","title":"Rate limit exceeded","status":429,"code":"RATE_LIMIT_EXCEEDED","request_id":"req-1","details":{"retry_after":30}}`)
+
+	problem := parseProblem(resp, body)
+	if problem == nil {
+		t.Fatal("Expected a problem")
+	}
+	if problem.Code != ErrorCodeRateLimitExceeded || problem.RequestID != "req-1" || string(problem.Details) != `{"retry_after":30}` {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+
+	// The code is found through the SDK's error wrapping
+	err := fmt.Errorf("send failed: %w", &Error{Type: ErrTypeServerError, Message: "client error", Err: responseError(resp, body)})
+	if code := ErrorCodeOf(err); code != ErrorCodeRateLimitExceeded {
+		t.Errorf("Expected %s, got %q", ErrorCodeRateLimitExceeded, code)
+	}
+
+	// Other bodies are kept as text
+	resp.Header.Set("Content-Type", "application/json")
+	if parseProblem(resp, body) != nil {
+		t.Error("Expected bodies of other content types to be ignored")
+	}
+	if err := responseError(resp, body); ErrorCodeOf(err) != "" || errors.As(err, new(*Problem)) {
+		t.Errorf("Expected a plain error, got %v", err)
+	}
+}
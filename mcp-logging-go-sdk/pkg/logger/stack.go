@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// maxStackDepth is the number of frames recorded for stack traces
+const maxStackDepth = 32
+
+// stack is a captured call stack
+type stack []uintptr
+
+// callers returns the stack of the caller of the function calling callers
+func callers() stack {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	return stack(pcs[:n])
+}
+
+// String formats the stack like a goroutine trace in a panic: the function of each frame,
+// followed by its file and line on an indented line
+func (s stack) String() string {
+	var builder strings.Builder
+	frames := runtime.CallersFrames(s)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			fmt.Fprintf(&builder, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return builder.String()
+}
+
+// location returns the source location of the innermost frame
+func (s stack) location() *SourceLocation {
+	if len(s) == 0 {
+		return nil
+	}
+
+	frame, _ := runtime.CallersFrames(s[:1]).Next()
+	if frame.Function == "" {
+		return nil
+	}
+
+	file := frame.File
+	if i := strings.LastIndex(file, "/"); i >= 0 {
+		file = file[i+1:]
+	}
+
+	return &SourceLocation{
+		File:     file,
+		Line:     frame.Line,
+		Function: frame.Function,
+	}
+}
+
+// stackError is an error annotated with the stack where it was wrapped
+type stackError struct {
+	err   error
+	stack stack
+}
+
+func (e *stackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *stackError) Unwrap() error {
+	return e.err
+}
+
+// StackTrace returns the stack where the error was wrapped
+func (e *stackError) StackTrace() string {
+	return e.stack.String()
+}
+
+// WithStack annotates err with the caller's stack, which ErrorWithStack reports instead of the
+// stack where the error is logged. Errors already carrying a stack are returned unchanged, as
+// is nil.
+func WithStack(err error) error {
+	if err == nil {
+		return nil
+	}
+	var annotated *stackError
+	if errors.As(err, &annotated) {
+		return err
+	}
+	return &stackError{err: err, stack: callers()}
+}
+
+// Wrap annotates err with a message and the caller's stack, like fmt.Errorf("%s: %w", msg, err)
+// followed by WithStack. Wrapping nil returns nil.
+func Wrap(err error, msg string) error {
+	if err == nil {
+		return nil
+	}
+	wrapped := fmt.Errorf("%s: %w", msg, err)
+	var annotated *stackError
+	if errors.As(err, &annotated) {
+		return wrapped
+	}
+	return &stackError{err: wrapped, stack: callers()}
+}
+
+// errorStack returns the stack recorded by WithStack or Wrap in err's chain, nil if none
+func errorStack(err error) stack {
+	var annotated *stackError
+	if errors.As(err, &annotated) {
+		return annotated.stack
+	}
+	return nil
+}
+
+// ErrorWithStack logs err at the error level with l: with its stack trace if l is a
+// StackLogger, or else with only the error message under ErrorKey
+func ErrorWithStack(l Logger, msg string, err error, fields ...Field) {
+	switch l := l.(type) {
+	case *mcpLogger:
+		l.errorWithStack(callers(), msg, err, fields)
+	case StackLogger:
+		l.ErrorWithStack(msg, err, fields...)
+	default:
+		l.Error(msg, append(fields, Err(err))...)
+	}
+}
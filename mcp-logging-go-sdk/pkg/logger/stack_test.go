@@ -0,0 +1,158 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestLogger returns a logger that keeps entries in its buffer until the test flushes them
+func newTestLogger(t *testing.T, captureStackTrace bool) *mcpLogger {
+	config := DefaultConfig()
+	config.ServiceName = "test-service"
+	config.AgentID = "test-agent"
+	config.FlushInterval = time.Hour
+	config.EnableHealthCheck = false
+	config.CaptureStackTrace = captureStackTrace
+
+	l, err := New(config)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l.(*mcpLogger)
+}
+
+func flushEntries(t *testing.T, l *mcpLogger) []LogEntry {
+	entries, err := l.buffer.Flush()
+	if err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	return entries
+}
+
+func TestSourceLocation(t *testing.T) {
+	l := newTestLogger(t, false)
+
+	l.Info("plain")
+	l.InfoContext(context.Background(), "with context")
+
+	for _, entry := range flushEntries(t, l) {
+		if entry.SourceLocation == nil || entry.SourceLocation.File != "stack_test.go" || entry.SourceLocation.Function != "github.com/kerlexov/mcp-logging-go-sdk/pkg/logger.TestSourceLocation" {
+			t.Errorf("Expected %q to be located at the caller, got %+v", entry.Message, entry.SourceLocation)
+		}
+		if entry.StackTrace != "" {
+			t.Errorf("Expected no stack trace by default, got %q", entry.StackTrace)
+		}
+	}
+}
+
+func TestCaptureStackTrace(t *testing.T) {
+	l := newTestLogger(t, true)
+
+	l.Info("info")
+	l.Error("error")
+	l.FatalContext(context.Background(), "fatal")
+
+	entries := flushEntries(t, l)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].StackTrace != "" {
+		t.Error("Expected info entries not to capture a stack trace")
+	}
+	for _, entry := range entries[1:] {
+		if !strings.HasPrefix(entry.StackTrace, "github.com/kerlexov/mcp-logging-go-sdk/pkg/logger.TestCaptureStackTrace\n\t") {
+			t.Errorf("Expected the stack trace of %q to start at the caller, got %q", entry.Message, entry.StackTrace)
+		}
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	l := newTestLogger(t, false)
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	l.InfoContext(ctx, "tagged")
+	l.InfoContext(context.Background(), "untagged")
+	l.InfoContext(WithRequestID(ctx, strings.Repeat("x", MaxRequestIDLength+1)), "too long")
+
+	entries := flushEntries(t, l)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	if entries[0].RequestID != "req-123" {
+		t.Errorf("Expected the request ID of the context, got %q", entries[0].RequestID)
+	}
+	if entries[1].RequestID != "" {
+		t.Errorf("Expected no request ID without one in the context, got %q", entries[1].RequestID)
+	}
+	if entries[2].RequestID != "" || entries[2].Metadata["request_id"] == nil {
+		t.Errorf("Expected an oversized request ID to be kept as metadata, got %+v", entries[2])
+	}
+}
+
+func wrapFailure() error {
+	return Wrap(errors.New("connection refused"), "failed to query")
+}
+
+func TestErrorWithStack(t *testing.T) {
+	l := newTestLogger(t, false)
+
+	err := wrapFailure()
+	l.ErrorWithStack("Wrapped error", err)
+	ErrorWithStack(l, "Plain error", errors.New("timeout"))
+	// Loggers that are not a StackLogger log the error without a stack
+	ErrorWithStack(struct{ Logger }{l}, "Other logger", err)
+
+	entries := flushEntries(t, l)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	wrapped := entries[0]
+	if wrapped.Level != LogLevelError || wrapped.Metadata[ErrorKey] != "failed to query: connection refused" {
+		t.Errorf("Expected the error message in the metadata, got %v", wrapped.Metadata)
+	}
+	if !strings.HasPrefix(wrapped.StackTrace, "github.com/kerlexov/mcp-logging-go-sdk/pkg/logger.wrapFailure\n") {
+		t.Errorf("Expected the stack where the error was wrapped, got %q", wrapped.StackTrace)
+	}
+	if wrapped.SourceLocation.Function != "github.com/kerlexov/mcp-logging-go-sdk/pkg/logger.TestErrorWithStack" {
+		t.Errorf("Expected the source location where the error was logged, got %+v", wrapped.SourceLocation)
+	}
+
+	if !strings.HasPrefix(entries[1].StackTrace, "github.com/kerlexov/mcp-logging-go-sdk/pkg/logger.TestErrorWithStack\n") {
+		t.Errorf("Expected the caller's stack for errors without one, got %q", entries[1].StackTrace)
+	}
+	if entries[1].SourceLocation.Function != "github.com/kerlexov/mcp-logging-go-sdk/pkg/logger.TestErrorWithStack" {
+		t.Errorf("Expected the source location where the function was called, got %+v", entries[1].SourceLocation)
+	}
+
+	if other := entries[2]; other.StackTrace != "" || other.Metadata[ErrorKey] != "failed to query: connection refused" {
+		t.Errorf("Expected the error message without a stack, got %q and %v", other.StackTrace, other.Metadata)
+	}
+}
+
+func TestWithStack(t *testing.T) {
+	if WithStack(nil) != nil || Wrap(nil, "context") != nil {
+		t.Error("Expected nil errors to stay nil")
+	}
+
+	base := errors.New("base")
+	err := WithStack(base)
+	if !errors.Is(err, base) || err.Error() != "base" {
+		t.Errorf("Expected the error to wrap its cause, got %v", err)
+	}
+	if WithStack(err) != err {
+		t.Error("Expected errors with a stack to be returned unchanged")
+	}
+
+	wrapped := Wrap(err, "context")
+	if wrapped.Error() != "context: base" || !errors.Is(wrapped, base) {
+		t.Errorf("Expected the message to be prefixed, got %v", wrapped)
+	}
+	if errorStack(wrapped)[0] != errorStack(err)[0] {
+		t.Error("Expected wrapping to keep the innermost stack")
+	}
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig configures the connections to the server, e.g. for private CAs, mutual TLS or
+// corporate proxies. The zero value uses the system roots and the proxy environment variables.
+type TransportConfig struct {
+	CAFile              string        `json:"ca_file" yaml:"ca_file"`                             // PEM bundle of CAs trusted in addition to the system roots
+	CertFile            string        `json:"cert_file" yaml:"cert_file"`                         // Client certificate for mutual TLS, requires KeyFile
+	KeyFile             string        `json:"key_file" yaml:"key_file"`                           // Key of the client certificate
+	ServerName          string        `json:"server_name" yaml:"server_name"`                     // Name verified against the server certificate, defaults to the URL host
+	InsecureSkipVerify  bool          `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`   // Disables server certificate verification, for testing only
+	ProxyURL            string        `json:"proxy_url" yaml:"proxy_url"`                         // http, https or socks5 proxy, empty uses HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	DialTimeout         time.Duration `json:"dial_timeout" yaml:"dial_timeout"`                   // Connection timeout, defaults to 10s
+	TLSHandshakeTimeout time.Duration `json:"tls_handshake_timeout" yaml:"tls_handshake_timeout"` // TLS handshake timeout, defaults to 10s
+
+	// RoundTripper replaces the transport built from the options above when set
+	RoundTripper http.RoundTripper `json:"-" yaml:"-"`
+}
+
+// Validate checks the options that can be checked without reading files
+func (c *TransportConfig) Validate() error {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return errors.New("transport.cert_file and transport.key_file must be set together")
+	}
+	if c.ProxyURL != "" {
+		if _, err := parseProxyURL(c.ProxyURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewTransport builds the HTTP transport of the sender, loading the CA bundle and client
+// certificate files
+func NewTransport(config TransportConfig) (http.RoundTripper, error) {
+	if config.RoundTripper != nil {
+		return config.RoundTripper, nil
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	dialTimeout := config.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = 10 * time.Second
+	}
+	handshakeTimeout := config.TLSHandshakeTimeout
+	if handshakeTimeout <= 0 {
+		handshakeTimeout = 10 * time.Second
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         config.ServerName,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if config.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, _ := parseProxyURL(config.ProxyURL)
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = handshakeTimeout
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// parseProxyURL parses a proxy URL and checks its scheme
+func parseProxyURL(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transport.proxy_url: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+		return proxyURL, nil
+	default:
+		return nil, fmt.Errorf("transport.proxy_url must be an http, https or socks5 URL, got %q", rawURL)
+	}
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCAFile(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+	return path
+}
+
+func TestNewTransport_CAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	entries := []LogEntry{{ID: generateID(), Timestamp: time.Now().UTC(), Level: LogLevelInfo, Message: "hello"}}
+
+	untrusted := NewHTTPSender(server.URL, 5*time.Second)
+	if err := untrusted.Send(context.Background(), entries); err == nil {
+		t.Error("Expected an error for a server with an unknown CA")
+	}
+
+	transport, err := NewTransport(TransportConfig{CAFile: writeCAFile(t, server)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sender := NewHTTPSenderWithTransport(server.URL, 5*time.Second, transport)
+	if err := sender.Send(context.Background(), entries); err != nil {
+		t.Errorf("Expected the CA file to be trusted, got %v", err)
+	}
+}
+
+func TestNewTransport_ProxyURL(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	transport, err := NewTransport(TransportConfig{ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sender := NewHTTPSenderWithTransport("http://logs.example.invalid", 5*time.Second, transport)
+	entries := []LogEntry{{ID: generateID(), Timestamp: time.Now().UTC(), Level: LogLevelInfo, Message: "hello"}}
+	if err := sender.Send(context.Background(), entries); err != nil {
+		t.Fatalf("Expected the request to go through the proxy, got %v", err)
+	}
+	if requested != "http://logs.example.invalid/api/logs" {
+		t.Errorf("Expected the proxy to receive the log request, got %q", requested)
+	}
+}
+
+func TestNewTransport_Errors(t *testing.T) {
+	dir := t.TempDir()
+	invalidPEM := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		config TransportConfig
+	}{
+		{name: "missing CA file", config: TransportConfig{CAFile: filepath.Join(dir, "missing.pem")}},
+		{name: "CA file without certificates", config: TransportConfig{CAFile: invalidPEM}},
+		{name: "certificate without key", config: TransportConfig{CertFile: invalidPEM}},
+		{name: "invalid client certificate", config: TransportConfig{CertFile: invalidPEM, KeyFile: invalidPEM}},
+		{name: "unsupported proxy scheme", config: TransportConfig{ProxyURL: "ftp://proxy:21"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewTransport(tt.config); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestNew_InvalidTransport(t *testing.T) {
+	config := DefaultConfig()
+	config.ServiceName = "test-service"
+	config.AgentID = "test-agent"
+	config.EnableHealthCheck = false
+	config.Transport.CAFile = filepath.Join(t.TempDir(), "missing.pem")
+
+	if _, err := New(config); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+}
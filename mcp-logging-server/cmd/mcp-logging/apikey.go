@@ -11,21 +11,29 @@ import (
 	"github.com/kerlexov/mcp-logging-server/pkg/auth"
 )
 
-func main() {
+// keysFlag registers the -keys flag shared by the subcommands that read the API key configuration
+func keysFlag(flags *flag.FlagSet) *string {
+	return flags.String("keys", envOrDefault("API_KEYS_CONFIG_PATH", "./config/api-keys.yaml"), "Path to API keys configuration file (env API_KEYS_CONFIG_PATH)")
+}
+
+// runAPIKey manages the API keys in the API key configuration file
+func runAPIKey(args []string) {
+	flags := newFlagSet("apikey", "")
 	var (
-		configPath  = flag.String("config", "./config/api-keys.yaml", "Path to API keys configuration file")
-		action      = flag.String("action", "", "Action to perform: create, list, revoke, rotate")
-		name        = flag.String("name", "", "Name for the API key")
-		permissions = flag.String("permissions", "ingest_logs", "Comma-separated list of permissions")
-		rateLimit   = flag.Int("rate-limit", 1000, "Rate limit for the API key (requests per minute)")
-		expiresIn   = flag.String("expires-in", "", "Expiration duration (e.g., '30d', '1y', '6m')")
-		apiKey      = flag.String("key", "", "API key to operate on (for revoke/rotate)")
+		configPath  = keysFlag(flags)
+		action      = flags.String("action", "", "Action to perform: create, list, revoke, rotate, set-rate-limit")
+		name        = flags.String("name", "", "Name for the API key")
+		permissions = flags.String("permissions", "ingest_logs", "Comma-separated list of permissions")
+		rateLimit   = flags.Int("rate-limit", 1000, "Rate limit for the API key (requests per minute, 0 for the server's global limit)")
+		expiresIn   = flags.String("expires-in", "", "Expiration duration (e.g., '30d', '1y', '6m')")
+		apiKey      = flags.String("key", "", "API key to operate on (for revoke/rotate/set-rate-limit)")
+		keyID       = flags.String("id", "", "ID of the API key to operate on, as shown by list (for set-rate-limit)")
+		signed      = flags.Bool("signed", false, "Require requests with the key to be signed, printing the signing secret (for create/rotate)")
 	)
-	flag.Parse()
+	flags.Parse(args)
 
 	if *action == "" {
-		fmt.Println("Usage: apikey -action=<create|list|revoke|rotate> [options]")
-		flag.PrintDefaults()
+		flags.Usage()
 		os.Exit(1)
 	}
 
@@ -63,6 +71,13 @@ func main() {
 		}
 
 		fmt.Printf("Created API key: %s\n", key)
+		if *signed {
+			secret, err := manager.EnableSigning(key)
+			if err != nil {
+				log.Fatalf("Failed to create signing secret: %v", err)
+			}
+			fmt.Printf("Signing secret: %s\n", secret)
+		}
 		fmt.Printf("Name: %s\n", *name)
 		fmt.Printf("Permissions: %v\n", perms)
 		fmt.Printf("Rate Limit: %d requests/minute\n", *rateLimit)
@@ -77,6 +92,9 @@ func main() {
 
 		fmt.Printf("\nConfiguration saved to: %s\n", *configPath)
 		fmt.Println("\n⚠️  IMPORTANT: Store this API key securely. It cannot be retrieved again.")
+		if *signed {
+			fmt.Println("⚠️  IMPORTANT: The signing secret is stored in the key file, keep it readable only by the server.")
+		}
 
 	case "list":
 		keys := manager.ListAPIKeys()
@@ -85,8 +103,8 @@ func main() {
 			return
 		}
 
-		fmt.Printf("%-20s %-15s %-30s %-20s %-10s\n", "Name", "Permissions", "Created", "Expires", "Active")
-		fmt.Println(strings.Repeat("-", 95))
+		fmt.Printf("%-16s %-20s %-15s %-12s %-30s %-20s %-10s %-10s\n", "ID", "Name", "Permissions", "Rate Limit", "Created", "Expires", "Active", "Signed")
+		fmt.Println(strings.Repeat("-", 136))
 
 		for _, keyInfo := range keys {
 			permsStr := strings.Join(permissionsToStrings(keyInfo.Permissions), ",")
@@ -104,12 +122,25 @@ func main() {
 				activeStr = "No"
 			}
 
-			fmt.Printf("%-20s %-15s %-30s %-20s %-10s\n",
+			signedStr := "No"
+			if keyInfo.SigningSecret != "" {
+				signedStr = "Yes"
+			}
+
+			rateLimitStr := "Global"
+			if keyInfo.RateLimit > 0 {
+				rateLimitStr = fmt.Sprintf("%d/min", keyInfo.RateLimit)
+			}
+
+			fmt.Printf("%-16s %-20s %-15s %-12s %-30s %-20s %-10s %-10s\n",
+				keyInfo.ID,
 				keyInfo.Name,
 				permsStr,
+				rateLimitStr,
 				keyInfo.CreatedAt.Format("2006-01-02 15:04:05"),
 				expiresStr,
 				activeStr,
+				signedStr,
 			)
 		}
 
@@ -153,11 +184,42 @@ func main() {
 		fmt.Printf("Old API key revoked\n")
 		fmt.Printf("New API key: %s\n", newKey)
 
+		// Keep requiring signatures, with a new secret
+		if *signed || keyInfo.SigningSecret != "" {
+			secret, err := manager.EnableSigning(newKey)
+			if err != nil {
+				log.Fatalf("Failed to create signing secret: %v", err)
+			}
+			fmt.Printf("New signing secret: %s\n", secret)
+		}
+
 		// Save configuration
 		if err := auth.SaveAPIKeyConfig(*configPath, config); err != nil {
 			log.Fatalf("Failed to save config: %v", err)
 		}
 
+	case "set-rate-limit":
+		id := *keyID
+		if *apiKey != "" {
+			id = manager.KeyID(*apiKey)
+		}
+		if id == "" {
+			log.Fatal("API key or ID is required for setting the rate limit")
+		}
+
+		keyInfo, err := manager.SetRateLimit(id, *rateLimit)
+		if err != nil {
+			log.Fatalf("Failed to set rate limit: %v", err)
+		}
+
+		// Save configuration
+		if err := auth.SaveAPIKeyConfig(*configPath, config); err != nil {
+			log.Fatalf("Failed to save config: %v", err)
+		}
+
+		fmt.Printf("Rate limit of %s set to %d requests/minute\n", keyInfo.Name, keyInfo.RateLimit)
+		fmt.Println("Restart the server to apply it, or use PUT /admin/api-keys/:id/rate-limit to change it without a restart.")
+
 	default:
 		log.Fatalf("Unknown action: %s", *action)
 	}
@@ -178,6 +240,14 @@ func parsePermissions(permsStr string) []auth.Permission {
 			perms = append(perms, auth.PermissionAdmin)
 		case "metrics":
 			perms = append(perms, auth.PermissionMetrics)
+		case "dataprotection_read":
+			perms = append(perms, auth.PermissionDataProtectionRead)
+		case "dataprotection_write":
+			perms = append(perms, auth.PermissionDataProtectionWrite)
+		case "replicate_logs":
+			perms = append(perms, auth.PermissionReplicateLogs)
+		case "route_logs":
+			perms = append(perms, auth.PermissionRouteLogs)
 		default:
 			log.Fatalf("Unknown permission: %s", part)
 		}
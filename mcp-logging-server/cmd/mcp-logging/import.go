@@ -0,0 +1,520 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/validation"
+)
+
+// combinedLogPattern matches the combined access log format written by nginx and Apache
+var combinedLogPattern = regexp.MustCompile(`^(?P<remote_addr>\S+) \S+ (?P<remote_user>\S+) \[(?P<timestamp>[^\]]+)\] "(?P<request>[^"]*)" (?P<status>\d{3}) (?P<body_bytes>\S+)(?: "(?P<referer>[^"]*)" "(?P<user_agent>[^"]*)")?`)
+
+// combinedTimeLayout is the timestamp layout of the combined access log format
+const combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Field names recognized in JSON, logfmt and pattern lines, first match wins
+var (
+	timestampKeys = []string{"timestamp", "time", "ts", "@timestamp", "date"}
+	levelKeys     = []string{"level", "lvl", "severity", "log_level"}
+	messageKeys   = []string{"message", "msg", "log"}
+	serviceKeys   = []string{"service_name", "service", "app"}
+	agentKeys     = []string{"agent_id", "agent", "host", "hostname"}
+)
+
+// importDefaults fills the fields a line does not set
+type importDefaults struct {
+	service    string
+	agent      string
+	platform   models.Platform
+	level      models.LogLevel
+	timeLayout string
+}
+
+// lineParser extracts the fields of one log line
+type lineParser func(line string) (map[string]interface{}, error)
+
+// importState records how far each file was imported so an interrupted import resumes
+type importState struct {
+	path  string
+	Files map[string]int64 `json:"files"` // Byte offset after the last imported line, by absolute file path
+}
+
+// loadImportState reads the state file, a missing file starts from the beginning
+func loadImportState(path string) (*importState, error) {
+	state := &importState{path: path, Files: make(map[string]int64)}
+	if path == "" {
+		return state, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]int64)
+	}
+	return state, nil
+}
+
+// save writes the state file atomically
+func (s *importState) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// runImport converts existing log files to log entries and loads them to a running server or directly to storage
+func runImport(args []string) {
+	flags := newFlagSet("import", " file ...")
+	var (
+		format     = flags.String("format", "json", "Line format: json (JSON lines), logfmt, combined (nginx/Apache access logs) or pattern")
+		pattern    = flags.String("pattern", "", "Regular expression with named groups (timestamp, level, message, service, agent, others become metadata) for the pattern format")
+		timeLayout = flags.String("time-layout", "", "Go time layout of timestamps, RFC3339 and common layouts are tried when empty")
+		service    = flags.String("service", "", "Service name of lines that do not set one")
+		agent      = flags.String("agent", "import", "Agent ID of lines that do not set one")
+		platform   = flags.String("platform", "go", "Platform of the imported entries")
+		level      = flags.String("level", "INFO", "Level of lines that do not set one")
+		target     = flags.String("target", "http", "Import target: http (running server) or storage (direct write)")
+		serverURL  = flags.String("url", envOrDefault("MCP_LOGGING_URL", "http://localhost:9080"), "Ingestion server URL for the http target (env MCP_LOGGING_URL)")
+		apiKey     = flags.String("api-key", os.Getenv("MCP_LOGGING_API_KEY"), "API key for the http target (env MCP_LOGGING_API_KEY)")
+		statePath  = flags.String("state", "", "File recording the progress of each input file, an interrupted import resumes from it")
+		dryRun     = flags.Bool("dry-run", false, "Only parse the files and report what would be imported")
+		rate       = flags.Float64("rate", 0, "Maximum entries per second, 0 for unlimited")
+		batchSize  = flags.Int("batch-size", 500, "Entries per batch (max 1000)")
+	)
+	configPath, db := storageFlags(flags)
+	flags.Parse(args)
+
+	files := flags.Args()
+	if len(files) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *batchSize < 1 || *batchSize > maxBatchSize {
+		log.Fatalf("Batch size must be between 1 and %d", maxBatchSize)
+	}
+
+	parse, err := newLineParser(*format, *pattern)
+	if err != nil {
+		log.Fatalf("Invalid format: %v", err)
+	}
+
+	defaults := importDefaults{
+		service:    *service,
+		agent:      *agent,
+		platform:   models.Platform(*platform),
+		level:      models.LogLevel(strings.ToUpper(*level)),
+		timeLayout: *timeLayout,
+	}
+	if defaults.level.Severity() == 0 {
+		log.Fatalf("Unknown level %q", *level)
+	}
+
+	state, err := loadImportState(*statePath)
+	if err != nil {
+		log.Fatalf("Failed to load state: %v", err)
+	}
+
+	var importTarget sink
+	if !*dryRun {
+		switch *target {
+		case "http":
+			importTarget = &httpSink{
+				url:    strings.TrimRight(*serverURL, "/"),
+				apiKey: *apiKey,
+				client: &http.Client{Timeout: 30 * time.Second},
+			}
+		case "storage":
+			store, err := openStorage(*configPath, *db)
+			if err != nil {
+				log.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+			importTarget = &storageSink{storage: store}
+		default:
+			log.Fatalf("Unknown target: %s", *target)
+		}
+	}
+
+	importer := &fileImporter{
+		parse:     parse,
+		defaults:  defaults,
+		levels:    mustLevelNormalizer(),
+		target:    importTarget,
+		limiter:   newRateLimiter(*rate),
+		state:     state,
+		batchSize: *batchSize,
+	}
+
+	ctx := context.Background()
+	var totalImported, totalSkipped, failedFiles int
+	for _, file := range files {
+		imported, skipped, err := importer.importFile(ctx, file)
+		totalImported += imported
+		totalSkipped += skipped
+		if err != nil {
+			fmt.Printf("%s: imported %d entries before failing: %v\n", file, imported, err)
+			failedFiles++
+			continue
+		}
+		fmt.Printf("%s: imported %d entries, skipped %d lines\n", file, imported, skipped)
+	}
+
+	verb := "Imported"
+	if *dryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("\n%s %d entries from %d files, skipped %d lines (%d files failed)\n", verb, totalImported, len(files), totalSkipped, failedFiles)
+
+	if failedFiles > 0 {
+		os.Exit(1)
+	}
+}
+
+// mustLevelNormalizer creates a normalizer of the built-in level aliases
+func mustLevelNormalizer() *validation.LevelNormalizer {
+	normalizer, err := validation.NewLevelNormalizer(nil)
+	if err != nil {
+		log.Fatalf("Failed to create level normalizer: %v", err)
+	}
+	return normalizer
+}
+
+// fileImporter reads log files line by line and sends the converted entries in batches
+type fileImporter struct {
+	parse     lineParser
+	defaults  importDefaults
+	levels    *validation.LevelNormalizer
+	target    sink // Nil for a dry run
+	limiter   *rateLimiter
+	state     *importState
+	batchSize int
+}
+
+// importFile imports the lines of a file after its recorded offset and returns how many
+// entries were imported and how many lines were skipped because they could not be converted
+func (im *fileImporter) importFile(ctx context.Context, path string) (int, int, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	offset := im.state.Files[absPath]
+	if offset > 0 {
+		if offset > info.Size() {
+			return 0, 0, fmt.Errorf("recorded offset %d is past the end of the file, was it truncated?", offset)
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+		fmt.Printf("%s: resuming at byte %d\n", path, offset)
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	batch := make([]models.LogEntry, 0, im.batchSize)
+	imported, skipped := 0, 0
+	lastProgress := time.Now()
+
+	// flush sends the batch and records the offset after its last line
+	flush := func() error {
+		if len(batch) > 0 && im.target != nil {
+			im.limiter.Wait(len(batch))
+			if err := im.target.Send(ctx, batch); err != nil {
+				return err
+			}
+		}
+		imported += len(batch)
+		batch = batch[:0]
+
+		if im.target != nil {
+			im.state.Files[absPath] = offset
+			if err := im.state.save(); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+		}
+
+		if time.Since(lastProgress) >= time.Second {
+			lastProgress = time.Now()
+			fmt.Printf("%s: %d entries imported (%.0f%%)\n", path, imported, 100*float64(offset)/float64(max(info.Size(), 1)))
+		}
+		return nil
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return imported, skipped, readErr
+		}
+		offset += int64(len(line))
+
+		if line = strings.TrimSpace(line); line != "" {
+			entry, err := im.convert(line)
+			if err != nil {
+				skipped++
+			} else {
+				batch = append(batch, entry)
+			}
+		}
+
+		if len(batch) >= im.batchSize || readErr == io.EOF {
+			if err := flush(); err != nil {
+				return imported, skipped, err
+			}
+		}
+		if readErr == io.EOF {
+			return imported, skipped, nil
+		}
+	}
+}
+
+// convert parses a line and builds a valid log entry from its fields
+func (im *fileImporter) convert(line string) (models.LogEntry, error) {
+	fields, err := im.parse(line)
+	if err != nil {
+		return models.LogEntry{}, err
+	}
+
+	entry := models.LogEntry{
+		ID:          uuid.New().String(),
+		ServiceName: im.defaults.service,
+		AgentID:     im.defaults.agent,
+		Platform:    im.defaults.platform,
+		Level:       im.defaults.level,
+	}
+
+	if value, ok := takeField(fields, timestampKeys); ok {
+		if entry.Timestamp, err = parseImportTime(value, im.defaults.timeLayout); err != nil {
+			return models.LogEntry{}, err
+		}
+	} else {
+		return models.LogEntry{}, errors.New("missing timestamp")
+	}
+	if value, ok := takeField(fields, levelKeys); ok {
+		entry.Level = im.levels.Normalize(models.LogLevel(fmt.Sprint(value)))
+	}
+	if value, ok := takeField(fields, messageKeys); ok {
+		entry.Message = fmt.Sprint(value)
+	}
+	if value, ok := takeField(fields, serviceKeys); ok {
+		entry.ServiceName = fmt.Sprint(value)
+	}
+	if value, ok := takeField(fields, agentKeys); ok {
+		entry.AgentID = fmt.Sprint(value)
+	}
+	if len(fields) > 0 {
+		entry.Metadata = fields
+	}
+
+	if err := entry.Validate(); err != nil {
+		return models.LogEntry{}, err
+	}
+	return entry, nil
+}
+
+// takeField removes and returns the first non-empty field of the given names
+func takeField(fields map[string]interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		value, ok := fields[key]
+		if !ok {
+			continue
+		}
+		delete(fields, key)
+		if value != nil && value != "" {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// parseImportTime parses a timestamp with the given layout, or RFC3339, common layouts and
+// Unix seconds or milliseconds when the layout is empty
+func parseImportTime(value interface{}, layout string) (time.Time, error) {
+	text := strings.TrimSpace(fmt.Sprint(value))
+	if number, ok := value.(float64); ok {
+		text = strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	if layout != "" {
+		return time.Parse(layout, text)
+	}
+
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		if number > 1e12 {
+			return time.UnixMilli(int64(number)), nil
+		}
+		seconds := int64(number)
+		return time.Unix(seconds, int64((number-float64(seconds))*1e9)), nil
+	}
+
+	for _, candidate := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", combinedTimeLayout, time.RFC1123Z} {
+		if t, err := time.Parse(candidate, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized timestamp %q", text)
+}
+
+// newLineParser returns the parser of a line format
+func newLineParser(format, pattern string) (lineParser, error) {
+	switch format {
+	case "json":
+		return parseJSONLine, nil
+	case "logfmt":
+		return parseLogfmtLine, nil
+	case "combined":
+		return parseCombinedLine, nil
+	case "pattern":
+		if pattern == "" {
+			return nil, errors.New("the pattern format requires -pattern")
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return func(line string) (map[string]interface{}, error) {
+			return matchFields(re, line)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// parseJSONLine parses a JSON object
+func parseJSONLine(line string) (map[string]interface{}, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// parseLogfmtLine parses space separated key=value pairs, values may be double quoted
+func parseLogfmtLine(line string) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	for i := 0; i < len(line); {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		keyStart := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		key := line[keyStart:i]
+		if key == "" {
+			return nil, fmt.Errorf("empty key at column %d", keyStart+1)
+		}
+		if i >= len(line) || line[i] != '=' {
+			fields[key] = "true"
+			continue
+		}
+		i++
+
+		if i < len(line) && line[i] == '"' {
+			end := i + 1
+			for end < len(line) && (line[end] != '"' || line[end-1] == '\\') {
+				end++
+			}
+			if end >= len(line) {
+				return nil, fmt.Errorf("unterminated quote in value of %s", key)
+			}
+			value, err := strconv.Unquote(line[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid quoted value of %s: %w", key, err)
+			}
+			fields[key] = value
+			i = end + 1
+			continue
+		}
+
+		valueStart := i
+		for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+			i++
+		}
+		fields[key] = line[valueStart:i]
+	}
+
+	if len(fields) == 0 {
+		return nil, errors.New("no key=value pairs")
+	}
+	return fields, nil
+}
+
+// parseCombinedLine parses an access log line, the level follows the response status
+func parseCombinedLine(line string) (map[string]interface{}, error) {
+	fields, err := matchFields(combinedLogPattern, line)
+	if err != nil {
+		return nil, err
+	}
+
+	status, _ := strconv.Atoi(fmt.Sprint(fields["status"]))
+	switch {
+	case status >= 500:
+		fields["level"] = "ERROR"
+	case status >= 400:
+		fields["level"] = "WARN"
+	default:
+		fields["level"] = "INFO"
+	}
+	fields["message"] = fmt.Sprintf("%s %d", fields["request"], status)
+	return fields, nil
+}
+
+// matchFields returns the named groups of a regular expression match, empty groups are left out
+func matchFields(re *regexp.Regexp, line string) (map[string]interface{}, error) {
+	match := re.FindStringSubmatch(line)
+	if match == nil {
+		return nil, errors.New("line does not match the pattern")
+	}
+
+	fields := make(map[string]interface{})
+	for i, name := range re.SubexpNames() {
+		if name != "" && match[i] != "" {
+			fields[name] = match[i]
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/config"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// command is a subcommand of the mcp-logging binary
+type command struct {
+	name        string
+	description string
+	run         func(args []string)
+}
+
+var commands = []command{
+	{name: "serve", description: "Run the ingestion and MCP servers", run: runServe},
+	{name: "apikey", description: "Create, list, revoke and rotate API keys", run: runAPIKey},
+	{name: "query", description: "Query stored logs", run: runQuery},
+	{name: "migrate", description: "Apply database migrations", run: runMigrate},
+	{name: "replay", description: "Replay recovery and dead-letter files", run: runReplay},
+	{name: "import", description: "Import existing log files", run: runImport},
+	{name: "restore", description: "Restore the database from the WAL archive", run: runRestore},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(os.Args[2:])
+			return
+		}
+	}
+
+	switch name {
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", name)
+		usage()
+		os.Exit(2)
+	}
+}
+
+// usage prints the available subcommands
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: mcp-logging <command> [options]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun 'mcp-logging <command> -h' for the options of a command.")
+}
+
+// newFlagSet creates the flag set of a subcommand with a usage line
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: mcp-logging %s [options]%s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// envOrDefault returns the value of an environment variable, or fallback when it is unset
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// configFlag registers the -config flag shared by the subcommands that read the server configuration
+func configFlag(flags *flag.FlagSet) *string {
+	return flags.String("config", os.Getenv("MCP_LOGGING_CONFIG"), "Path to the configuration file (env MCP_LOGGING_CONFIG, searched in the usual locations when empty)")
+}
+
+// storageFlags registers the -config and -db flags of the subcommands that open the database
+func storageFlags(flags *flag.FlagSet) (configPath, db *string) {
+	configPath = configFlag(flags)
+	db = flags.String("db", "", "SQLite connection string, overrides the configured storage.connection_string")
+	return configPath, db
+}
+
+// openStorage opens the configured database without a search index, db overrides the connection string
+func openStorage(configPath, db string) (*storage.SQLiteStorage, error) {
+	cfg, err := config.LoadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if db != "" {
+		cfg.Storage.ConnectionString = db
+	}
+	return storage.NewSQLiteStorage(cfg.Storage.ConnectionString)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// runMigrate applies pending database migrations and reports the schema version
+func runMigrate(args []string) {
+	flags := newFlagSet("migrate", "")
+	configPath, db := storageFlags(flags)
+	flags.Parse(args)
+
+	// Opening the storage applies any pending migrations
+	store, err := openStorage(*configPath, *db)
+	if err != nil {
+		log.Fatalf("Failed to migrate storage: %v", err)
+	}
+	defer store.Close()
+
+	version, err := store.SchemaVersion(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to read schema version: %v", err)
+	}
+
+	fmt.Printf("Database schema is at version %d\n", version)
+}
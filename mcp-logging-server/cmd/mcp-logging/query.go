@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// runQuery prints stored log entries matching the given filter, newest first
+func runQuery(args []string) {
+	flags := newFlagSet("query", "")
+	var (
+		service  = flags.String("service", "", "Only show entries of this service")
+		agent    = flags.String("agent", "", "Only show entries of this agent")
+		level    = flags.String("level", "", "Only show entries with this level (DEBUG, INFO, WARN, ERROR, FATAL)")
+		platform = flags.String("platform", "", "Only show entries from this platform")
+		contains = flags.String("contains", "", "Only show entries whose message contains this text")
+		since    = flags.String("since", "", "Only show entries at or after this time (RFC3339, a local time, now, today, yesterday, or a duration ago such as 1h or 7d)")
+		until    = flags.String("until", "", "Only show entries at or before this time, in the same formats as -since")
+		timeZone = flags.String("time-zone", "", "IANA time zone of local times such as 2024-05-01 10:00, UTC when empty")
+		limit    = flags.Int("limit", 50, "Maximum entries to show (max 1000)")
+		offset   = flags.Int("offset", 0, "Entries to skip")
+		asJSON   = flags.Bool("json", false, "Print one JSON object per entry")
+	)
+	configPath, db := storageFlags(flags)
+	flags.Parse(args)
+
+	if *limit < 1 || *limit > 1000 {
+		log.Fatal("Limit must be between 1 and 1000")
+	}
+
+	filter := models.LogFilter{
+		ServiceName:     *service,
+		AgentID:         *agent,
+		Platform:        models.Platform(*platform),
+		MessageContains: *contains,
+		Limit:           *limit,
+		Offset:          *offset,
+	}
+
+	if *level != "" {
+		filter.Level = models.LogLevel(strings.ToUpper(*level))
+		if filter.Level.Severity() == 0 {
+			log.Fatalf("Unknown level %q", *level)
+		}
+	}
+
+	loc, err := models.LoadTimeZone(*timeZone)
+	if err != nil {
+		log.Fatal(err)
+	}
+	now := time.Now()
+	if filter.StartTime, err = parseQueryTime(*since, now, loc); err != nil {
+		log.Fatalf("Invalid since time: %v", err)
+	}
+	if filter.EndTime, err = parseQueryTime(*until, now, loc); err != nil {
+		log.Fatalf("Invalid until time: %v", err)
+	}
+
+	store, err := openStorage(*configPath, *db)
+	if err != nil {
+		log.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	result, err := store.Query(context.Background(), filter)
+	if err != nil {
+		log.Fatalf("Failed to query logs: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, entry := range result.Logs {
+			if err := encoder.Encode(entry); err != nil {
+				log.Fatalf("Failed to encode log entry: %v", err)
+			}
+		}
+		return
+	}
+
+	for _, entry := range result.Logs {
+		fmt.Printf("%s %-5s %s [%s] %s\n", entry.Timestamp.Format(time.RFC3339), entry.Level, entry.ServiceName, entry.AgentID, entry.Message)
+	}
+	fmt.Fprintf(os.Stderr, "Showing %d of %d entries\n", len(result.Logs), result.TotalCount)
+}
+
+// parseQueryTime parses a duration counted back from now, such as 1h or 7d, or any time
+// models.ParseTimeExpression accepts
+func parseQueryTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if value != "" && value[0] >= '0' && value[0] <= '9' {
+		if t, err := models.ParseTimeExpression("-"+value, now, loc); err == nil {
+			return t, nil
+		}
+	}
+	return models.ParseTimeExpression(value, now, loc)
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/recovery"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// maxBatchSize is the largest batch the ingestion server accepts
+const maxBatchSize = 1000
+
+// filter selects which log entries are replayed
+type filter struct {
+	serviceName string
+	minLevel    models.LogLevel
+	since       time.Time
+	until       time.Time
+}
+
+// matches checks if a log entry passes the filter
+func (f filter) matches(entry models.LogEntry) bool {
+	if f.serviceName != "" && entry.ServiceName != f.serviceName {
+		return false
+	}
+	if f.minLevel != "" && entry.Level.Severity() < f.minLevel.Severity() {
+		return false
+	}
+	if !f.since.IsZero() && entry.Timestamp.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && entry.Timestamp.After(f.until) {
+		return false
+	}
+	return true
+}
+
+// sink receives replayed batches
+type sink interface {
+	Send(ctx context.Context, batch []models.LogEntry) error
+}
+
+// httpSink re-submits batches to a running ingestion server
+type httpSink struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// Send posts a batch to the batch ingestion endpoint
+func (s *httpSink) Send(ctx context.Context, batch []models.LogEntry) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url+"/v1/logs/batch", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("X-API-Key", s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("server responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	return nil
+}
+
+// storageSink writes batches directly to storage
+type storageSink struct {
+	storage storage.LogStorage
+}
+
+// Send stores a batch
+func (s *storageSink) Send(ctx context.Context, batch []models.LogEntry) error {
+	return s.storage.Store(ctx, batch)
+}
+
+// runReplay replays recovery and dead-letter files to a running server or directly to storage
+func runReplay(args []string) {
+	flags := newFlagSet("replay", " [file ...]")
+	var (
+		dir       = flags.String("dir", envOrDefault("MCP_LOGGING_RECOVERY_DIR", "./recovery"), "Directory with recovery/dead-letter files, used when no files are given (env MCP_LOGGING_RECOVERY_DIR)")
+		target    = flags.String("target", "http", "Replay target: http (running server) or storage (direct write)")
+		serverURL = flags.String("url", envOrDefault("MCP_LOGGING_URL", "http://localhost:9080"), "Ingestion server URL for the http target (env MCP_LOGGING_URL)")
+		apiKey    = flags.String("api-key", os.Getenv("MCP_LOGGING_API_KEY"), "API key for the http target (env MCP_LOGGING_API_KEY)")
+		dryRun    = flags.Bool("dry-run", false, "Only report what would be replayed")
+		service   = flags.String("service", "", "Only replay entries of this service")
+		minLevel  = flags.String("min-level", "", "Only replay entries at or above this level (DEBUG, INFO, WARN, ERROR, FATAL)")
+		since     = flags.String("since", "", "Only replay entries at or after this time (RFC3339)")
+		until     = flags.String("until", "", "Only replay entries at or before this time (RFC3339)")
+		rate      = flags.Float64("rate", 0, "Maximum entries per second, 0 for unlimited")
+		batchSize = flags.Int("batch-size", 100, "Entries per batch (max 1000)")
+		remove    = flags.Bool("delete", false, "Delete files after all their entries were replayed")
+	)
+	configPath, db := storageFlags(flags)
+	flags.Parse(args)
+
+	if *batchSize < 1 || *batchSize > maxBatchSize {
+		log.Fatalf("Batch size must be between 1 and %d", maxBatchSize)
+	}
+
+	f, err := parseFilter(*service, *minLevel, *since, *until)
+	if err != nil {
+		log.Fatalf("Invalid filter: %v", err)
+	}
+
+	files := flags.Args()
+	if len(files) == 0 {
+		files, err = findReplayFiles(*dir)
+		if err != nil {
+			log.Fatalf("Failed to list replay files: %v", err)
+		}
+	}
+	if len(files) == 0 {
+		fmt.Println("No files to replay")
+		return
+	}
+
+	var replayTarget sink
+	if !*dryRun {
+		switch *target {
+		case "http":
+			replayTarget = &httpSink{
+				url:    strings.TrimRight(*serverURL, "/"),
+				apiKey: *apiKey,
+				client: &http.Client{Timeout: 30 * time.Second},
+			}
+		case "storage":
+			store, err := openStorage(*configPath, *db)
+			if err != nil {
+				log.Fatalf("Failed to open storage: %v", err)
+			}
+			defer store.Close()
+			replayTarget = &storageSink{storage: store}
+		default:
+			log.Fatalf("Unknown target: %s", *target)
+		}
+	}
+
+	ctx := context.Background()
+	limiter := newRateLimiter(*rate)
+	var totalRead, totalReplayed, failedFiles int
+
+	for _, file := range files {
+		entries, err := recovery.LoadLogsFile(file)
+		if err != nil {
+			fmt.Printf("%s: failed to load: %v\n", file, err)
+			failedFiles++
+			continue
+		}
+
+		selected := make([]models.LogEntry, 0, len(entries))
+		for _, entry := range entries {
+			if f.matches(entry) {
+				if entry.ID == "" {
+					entry.ID = uuid.New().String()
+				}
+				selected = append(selected, entry)
+			}
+		}
+		totalRead += len(entries)
+
+		if *dryRun {
+			fmt.Printf("%s: would replay %d of %d entries\n", file, len(selected), len(entries))
+			totalReplayed += len(selected)
+			continue
+		}
+
+		replayed, err := replayEntries(ctx, replayTarget, limiter, selected, *batchSize)
+		totalReplayed += replayed
+		if err != nil {
+			fmt.Printf("%s: replayed %d of %d entries before failing: %v\n", file, replayed, len(selected), err)
+			failedFiles++
+			continue
+		}
+
+		fmt.Printf("%s: replayed %d of %d entries\n", file, replayed, len(entries))
+
+		if *remove {
+			if err := os.Remove(file); err != nil {
+				fmt.Printf("%s: failed to delete: %v\n", file, err)
+			}
+		}
+	}
+
+	verb := "Replayed"
+	if *dryRun {
+		verb = "Would replay"
+	}
+	fmt.Printf("\n%s %d of %d entries from %d files (%d failed)\n", verb, totalReplayed, totalRead, len(files), failedFiles)
+
+	if failedFiles > 0 {
+		os.Exit(1)
+	}
+}
+
+// replayEntries sends entries to the target in rate limited batches and returns how many were sent
+func replayEntries(ctx context.Context, target sink, limiter *rateLimiter, entries []models.LogEntry, batchSize int) (int, error) {
+	replayed := 0
+	for start := 0; start < len(entries); start += batchSize {
+		end := start + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		batch := entries[start:end]
+
+		limiter.Wait(len(batch))
+		if err := target.Send(ctx, batch); err != nil {
+			return replayed, err
+		}
+		replayed += len(batch)
+	}
+	return replayed, nil
+}
+
+// parseFilter builds a filter from the command line flags
+func parseFilter(service, minLevel, since, until string) (filter, error) {
+	f := filter{serviceName: service}
+
+	if minLevel != "" {
+		f.minLevel = models.LogLevel(strings.ToUpper(minLevel))
+		if f.minLevel.Severity() == 0 {
+			return f, fmt.Errorf("unknown level %q", minLevel)
+		}
+	}
+
+	if since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return f, fmt.Errorf("invalid since time: %w", err)
+		}
+		f.since = t
+	}
+
+	if until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return f, fmt.Errorf("invalid until time: %w", err)
+		}
+		f.until = t
+	}
+
+	return f, nil
+}
+
+// findReplayFiles lists the JSON and NDJSON files in a directory
+func findReplayFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch filepath.Ext(entry.Name()) {
+		case ".json", ".jsonl", ".ndjson":
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// rateLimiter paces replay to a maximum number of entries per second
+type rateLimiter struct {
+	rate float64
+	next time.Time
+}
+
+// newRateLimiter creates a rate limiter, a non-positive rate disables limiting
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{rate: rate}
+}
+
+// Wait blocks until count more entries may be sent
+func (r *rateLimiter) Wait(count int) {
+	if r.rate <= 0 {
+		return
+	}
+
+	now := time.Now()
+	if r.next.After(now) {
+		time.Sleep(r.next.Sub(now))
+		now = r.next
+	}
+	r.next = now.Add(time.Duration(float64(count) / r.rate * float64(time.Second)))
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/config"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/server"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// runRestore rebuilds the SQLite database from the WAL archive, as of a point in time
+func runRestore(args []string) {
+	flags := newFlagSet("restore", "")
+	var (
+		output   = flags.String("output", "", "File to restore the database into, which must not exist")
+		at       = flags.String("time", "", "Restore the database as of this time (RFC3339, a local time, or a duration ago such as 1h), the newest archived state when empty")
+		timeZone = flags.String("time-zone", "", "IANA time zone of local times such as 2024-05-01 10:00, UTC when empty")
+		archive  = flags.String("archive", "", "Archive URL, overrides the configured storage.archive.url")
+		list     = flags.Bool("list", false, "List the generations of the archive instead of restoring")
+	)
+	configPath := configFlag(flags)
+	flags.Parse(args)
+
+	cfg, err := config.LoadFile(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *archive != "" {
+		cfg.Storage.Archive.URL = *archive
+	}
+	if cfg.Storage.Archive.URL == "" {
+		log.Fatal("No WAL archive configured, set storage.archive.url or -archive")
+	}
+
+	store, err := server.OpenArchiveStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to open archive: %v", err)
+	}
+	ctx := context.Background()
+
+	if *list {
+		generations, err := storage.ListArchiveGenerations(ctx, store)
+		if err != nil {
+			log.Fatalf("Failed to list archive: %v", err)
+		}
+		for _, generation := range generations {
+			covered := generation.SnapshotAt
+			if !generation.LastSegmentAt.IsZero() {
+				covered = generation.LastSegmentAt
+			}
+			fmt.Printf("%s  %s to %s  %d WAL segments\n", generation.Name,
+				generation.SnapshotAt.Format(time.RFC3339), covered.Format(time.RFC3339), generation.Segments)
+		}
+		return
+	}
+
+	if *output == "" {
+		log.Fatal("-output is required")
+	}
+	loc, err := models.LoadTimeZone(*timeZone)
+	if err != nil {
+		log.Fatal(err)
+	}
+	until, err := parseQueryTime(*at, time.Now(), loc)
+	if err != nil {
+		log.Fatalf("Invalid time: %v", err)
+	}
+
+	result, err := storage.RestoreArchive(ctx, store, *output, until)
+	if err != nil {
+		log.Fatalf("Failed to restore: %v", err)
+	}
+	fmt.Printf("Restored generation %s with %d WAL segments to %s, as of %s\n",
+		result.Generation, result.Segments, *output, result.RestoredTo.Format(time.RFC3339))
+	fmt.Println("Stop the server and replace its database file with the restored file, removing the -wal and -shm files of the old database.")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/config"
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+	"github.com/kerlexov/mcp-logging-server/pkg/procs"
+	"github.com/kerlexov/mcp-logging-server/pkg/ratelimit"
+	"github.com/kerlexov/mcp-logging-server/pkg/security"
+	"github.com/kerlexov/mcp-logging-server/pkg/server"
+	tlsconfig "github.com/kerlexov/mcp-logging-server/pkg/tls"
+)
+
+// runServe runs the ingestion and MCP servers until interrupted
+func runServe(args []string) {
+	flags := newFlagSet("serve", "")
+	configPath := configFlag(flags)
+	keysPath := keysFlag(flags)
+	devMode := flags.Bool("dev", false, "Run locally without API keys, TLS or rate limiting, keeping logs in memory")
+	flags.Parse(args)
+
+	// Load configuration
+	var cfg *config.Config
+	var err error
+	if *devMode {
+		cfg = config.DevConfig()
+	} else {
+		cfg, err = config.LoadFile(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load configuration: %v", err)
+		}
+	}
+
+	// Size the runtime to the container's CPU limit before anything sizes itself to GOMAXPROCS
+	maxProcs, source := procs.Set(cfg.Server.Concurrency.MaxProcs)
+	log.Printf("Using GOMAXPROCS %d from the %s", maxProcs, source)
+
+	// Load authentication configuration, dev mode accepts every request
+	var authConfig *auth.APIKeyConfig
+	var authPath string
+	if !*devMode {
+		authPath = *keysPath
+		authConfig, err = auth.LoadAPIKeyConfig(*keysPath)
+		if err != nil {
+			log.Fatalf("Failed to load API key configuration: %v", err)
+		}
+
+		// Merge with environment configuration
+		envAuthConfig := auth.LoadAPIKeyConfigFromEnv()
+		authConfig = auth.MergeConfigs(authConfig, envAuthConfig)
+	}
+
+	// Load rate limiting configuration
+	rateLimitConfig := ratelimit.DefaultRateLimitConfig()
+	if *devMode || os.Getenv("RATE_LIMIT_ENABLED") == "false" {
+		rateLimitConfig.Enabled = false
+	}
+	if requestsPerMinute := os.Getenv("RATE_LIMIT_REQUESTS_PER_MINUTE"); requestsPerMinute != "" {
+		if rpm, err := strconv.Atoi(requestsPerMinute); err == nil {
+			rateLimitConfig.RequestsPerMinute = rpm
+		}
+	}
+	if burstSize := os.Getenv("RATE_LIMIT_BURST"); burstSize != "" {
+		if burst, err := strconv.Atoi(burstSize); err == nil {
+			rateLimitConfig.BurstSize = burst
+		}
+	}
+	if algorithm := os.Getenv("RATE_LIMIT_ALGORITHM"); algorithm != "" {
+		rateLimitConfig.Algorithm, err = ratelimit.ParseAlgorithm(algorithm)
+		if err != nil {
+			log.Fatalf("Invalid rate limiting configuration: %v", err)
+		}
+	}
+
+	// Load TLS configuration
+	tlsConfig := tlsconfig.DefaultTLSConfig()
+	if !*devMode {
+		tlsConfig = tlsconfig.LoadTLSConfigFromEnv()
+		if err := tlsConfig.ValidateConfig(); err != nil {
+			log.Fatalf("Invalid TLS configuration: %v", err)
+		}
+	}
+
+	// Load security configuration
+	securityConfig := security.DefaultSecurityConfig()
+	if !*devMode && os.Getenv("HTTPS_REDIRECT") == "true" {
+		securityConfig.HTTPSRedirect = true
+	}
+
+	// Load data protection configuration
+	dataProtectionConfig := dataprotection.DefaultDataProtectionConfig()
+	if os.Getenv("MASK_SENSITIVE_FIELDS") == "false" {
+		dataProtectionConfig.Enabled = false
+	}
+	if os.Getenv("DATA_PROTECTION_REPORT_ONLY") == "true" {
+		dataProtectionConfig.ReportOnly = true
+	}
+	if sensitiveFields := os.Getenv("SENSITIVE_FIELDS"); sensitiveFields != "" {
+		// Fields are masked unless an action is given, e.g. device_info.model:hash
+		specs := strings.Split(sensitiveFields, ",")
+		dataProtectionConfig.MaskFields = nil
+		dataProtectionConfig.FieldRules = make([]dataprotection.FieldRule, len(specs))
+		for i, spec := range specs {
+			rule, err := dataprotection.ParseFieldRule(spec)
+			if err != nil {
+				log.Fatalf("Invalid SENSITIVE_FIELDS: %v", err)
+			}
+			dataProtectionConfig.FieldRules[i] = rule
+		}
+	}
+
+	logServer := server.NewWithOptions(cfg, server.Options{
+		Auth:           authConfig,
+		AuthPath:       authPath,
+		RateLimit:      rateLimitConfig,
+		TLS:            tlsConfig,
+		Security:       securityConfig,
+		DataProtection: dataProtectionConfig,
+		RecoveryDir:    os.Getenv("MCP_LOGGING_RECOVERY_DIR"),
+	})
+
+	// Run until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if *devMode {
+		printDevBanner(cfg)
+	}
+
+	if err := logServer.Run(ctx); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+	log.Println("Servers stopped")
+}
+
+// printDevBanner prints the endpoints of a dev mode server and how to send a first log
+func printDevBanner(cfg *config.Config) {
+	ingestionURL := fmt.Sprintf("http://localhost:%d", cfg.Server.IngestionPort)
+
+	fmt.Printf(`
+MCP Logging Server - dev mode
+  API keys, TLS and rate limiting are disabled, logs are kept in memory and lost on exit.
+
+  Ingestion API: %s
+  MCP server:    http://localhost:%d
+
+Send a log:
+  curl -X POST %s/v1/logs \
+    -H "Content-Type: application/json" \
+    -d '{"level": "INFO", "message": "Hello from dev mode", "service_name": "my-service", "agent_id": "my-agent", "platform": "go"}'
+
+Point an SDK at %s with any API key.
+
+`, ingestionURL, cfg.Server.MCPPort, ingestionURL, ingestionURL)
+}
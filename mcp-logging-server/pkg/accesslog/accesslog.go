@@ -0,0 +1,189 @@
+// Package accesslog writes a structured line for every ingestion request to a sink of its own,
+// separate from the server's application log, so that abuse can be investigated from who sent
+// what, how much of their rate limit was left and how the request ended.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Outcomes of requests
+const (
+	OutcomeOK           = "ok"           // Answered with a 1xx, 2xx or 3xx status
+	OutcomeUnauthorized = "unauthorized" // Missing, invalid or insufficient API key
+	OutcomeRateLimited  = "rate_limited" // Rejected by the rate limits
+	OutcomeRejected     = "rejected"     // Any other client error, such as an invalid entry
+	OutcomeUnavailable  = "unavailable"  // Refused while the server is overloaded, read-only or in maintenance
+	OutcomeError        = "error"        // Failed on the server
+)
+
+// Sinks that are streams instead of files
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// Record is the access log line of a request
+type Record struct {
+	Time               time.Time `json:"time"`
+	RequestID          string    `json:"request_id,omitempty"`
+	Method             string    `json:"method"`
+	Path               string    `json:"path"` // Route such as /v1/logs/batch, the URL path for unknown routes
+	Status             int       `json:"status"`
+	Outcome            string    `json:"outcome"`              // One of the Outcome constants
+	ErrorCode          string    `json:"error_code,omitempty"` // Code of the problem details of failed requests
+	DurationMs         float64   `json:"duration_ms"`
+	ClientIP           string    `json:"client_ip"`
+	UserAgent          string    `json:"user_agent,omitempty"`
+	KeyID              string    `json:"key_id,omitempty"` // API key of authenticated requests, never the key itself
+	KeyName            string    `json:"key_name,omitempty"`
+	RateLimitRemaining *int      `json:"rate_limit_remaining,omitempty"` // Requests left to the key, or to the client IP without a key
+	BytesIn            int64     `json:"bytes_in"`                       // Body bytes read, compressed as sent
+	BytesOut           int64     `json:"bytes_out"`                      // Body bytes written
+	TLSVersion         string    `json:"tls_version,omitempty"`          // Empty for plain HTTP
+	TLSCipher          string    `json:"tls_cipher,omitempty"`
+}
+
+// Outcome classifies a response status
+func Outcome(status int) string {
+	switch {
+	case status < 400:
+		return OutcomeOK
+	case status == 401 || status == 403:
+		return OutcomeUnauthorized
+	case status == 429:
+		return OutcomeRateLimited
+	case status == 503:
+		return OutcomeUnavailable
+	case status < 500:
+		return OutcomeRejected
+	}
+	return OutcomeError
+}
+
+// Config configures the sink of the access log
+type Config struct {
+	Path       string // File the records are appended to, or Stdout or Stderr
+	MaxSize    int64  // Size in bytes at which the file is rotated, 0 never rotates it
+	MaxBackups int    // Rotated files kept as <path>.1 (newest) to <path>.N, at least 1
+}
+
+// Logger writes records as JSON lines. It is safe for concurrent use.
+type Logger struct {
+	config Config
+
+	mutex sync.Mutex
+	out   io.Writer
+	file  *os.File // Nil for streams
+	size  int64
+}
+
+// New opens the sink of the access log
+func New(config Config) (*Logger, error) {
+	l := &Logger{config: config}
+	switch config.Path {
+	case "":
+		return nil, fmt.Errorf("access log has no path")
+	case Stdout:
+		l.out = os.Stdout
+	case Stderr:
+		l.out = os.Stderr
+	default:
+		if l.config.MaxBackups < 1 {
+			l.config.MaxBackups = 1
+		}
+		if err := l.open(); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// open opens the file of the log, creating it and its directory as needed
+func (l *Logger) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.config.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create access log directory: %w", err)
+	}
+	file, err := os.OpenFile(l.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open access log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open access log: %w", err)
+	}
+	l.file, l.out, l.size = file, file, info.Size()
+	return nil
+}
+
+// Log writes a record. Write errors are reported to the application log, requests are not
+// failed for them.
+func (l *Logger) Log(record Record) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("Failed to encode access log record: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file != nil && l.config.MaxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.config.MaxSize {
+		if err := l.rotate(); err != nil {
+			log.Printf("Failed to rotate access log: %v", err)
+		}
+	}
+	if l.out == nil {
+		return
+	}
+	n, err := l.out.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		log.Printf("Failed to write access log record: %v", err)
+	}
+}
+
+// rotate renames the file to <path>.1, shifting older backups and removing the oldest, and
+// opens a new file. The file is opened again when renaming fails, so that records keep being
+// written. The caller must hold the mutex.
+func (l *Logger) rotate() error {
+	l.file.Close()
+	l.file, l.out = nil, nil
+
+	var err error
+	for i := l.config.MaxBackups; i > 1 && err == nil; i-- {
+		older := fmt.Sprintf("%s.%d", l.config.Path, i-1)
+		if _, statErr := os.Stat(older); statErr == nil {
+			err = os.Rename(older, fmt.Sprintf("%s.%d", l.config.Path, i))
+		}
+	}
+	if err == nil {
+		err = os.Rename(l.config.Path, l.config.Path+".1")
+	}
+	if openErr := l.open(); openErr != nil {
+		return openErr
+	}
+	return err
+}
+
+// Close closes the file of the log
+func (l *Logger) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file, l.out = nil, nil
+	return err
+}
@@ -0,0 +1,102 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger_Log(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "access.log")
+	logger, err := New(Config{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+
+	remaining := 41
+	logger.Log(Record{Time: time.Now(), Method: "POST", Path: "/v1/logs", Status: 201, Outcome: OutcomeOK, KeyID: "key-1", RateLimitRemaining: &remaining, BytesIn: 312})
+	logger.Log(Record{Time: time.Now(), Method: "POST", Path: "/v1/logs", Status: 429, Outcome: OutcomeRateLimited, ErrorCode: "RATE_LIMIT_EXCEEDED"})
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close access log: %v", err)
+	}
+
+	records := readRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].KeyID != "key-1" || *records[0].RateLimitRemaining != 41 || records[0].BytesIn != 312 {
+		t.Errorf("Unexpected first record %+v", records[0])
+	}
+	if records[1].Outcome != OutcomeRateLimited || records[1].RateLimitRemaining != nil {
+		t.Errorf("Unexpected second record %+v", records[1])
+	}
+}
+
+func TestLogger_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	logger, err := New(Config{Path: path, MaxSize: 300, MaxBackups: 2})
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+	defer logger.Close()
+
+	// Each record is over 100 bytes, so that every few records rotate the file
+	for i := 0; i < 20; i++ {
+		logger.Log(Record{Time: time.Now(), Method: "POST", Path: "/v1/logs/batch", Status: 201, Outcome: OutcomeOK, ClientIP: "203.0.113.7"})
+	}
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", name, err)
+		}
+		if info.Size() > 300 {
+			t.Errorf("Expected %s to be rotated at 300 bytes, got %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept, got %v", err)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := map[int]string{
+		200: OutcomeOK,
+		201: OutcomeOK,
+		400: OutcomeRejected,
+		401: OutcomeUnauthorized,
+		403: OutcomeUnauthorized,
+		413: OutcomeRejected,
+		429: OutcomeRateLimited,
+		500: OutcomeError,
+		503: OutcomeUnavailable,
+	}
+	for status, want := range tests {
+		if got := Outcome(status); got != want {
+			t.Errorf("Outcome(%d): expected %s, got %s", status, want, got)
+		}
+	}
+}
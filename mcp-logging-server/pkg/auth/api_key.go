@@ -4,7 +4,9 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -16,28 +18,54 @@ const (
 	PermissionQueryLogs  Permission = "query_logs"
 	PermissionAdmin      Permission = "admin"
 	PermissionMetrics    Permission = "metrics"
+
+	// Data protection admin endpoints: viewing the configuration and stats, and changing the
+	// configuration. Write implies read.
+	PermissionDataProtectionRead  Permission = "dataprotection_read"
+	PermissionDataProtectionWrite Permission = "dataprotection_write"
+
+	// Storing batches replicated by a peer server, which skip validation and data protection
+	PermissionReplicateLogs Permission = "replicate_logs"
+
+	// Buffering entries that another node of a sharded cluster received, which skip validation
+	// and data protection
+	PermissionRouteLogs Permission = "route_logs"
 )
 
+// keyIDLength is the number of characters of a key's hash that identify it in stats and the admin API
+const keyIDLength = 16
+
+// ErrAPIKeyNotFound is returned for operations on API keys that do not exist
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
 // APIKeyInfo contains information about an API key
 type APIKeyInfo struct {
+	ID          string       `yaml:"-" json:"id"` // Prefix of the key's hash, set by ValidateAPIKey and ListAPIKeys
 	Name        string       `yaml:"name" json:"name"`
 	Permissions []Permission `yaml:"permissions" json:"permissions"`
-	RateLimit   int          `yaml:"rate_limit" json:"rate_limit"`
+	RateLimit   int          `yaml:"rate_limit" json:"rate_limit"` // Requests per minute, 0 uses the global limit
 	ExpiresAt   *time.Time   `yaml:"expires_at,omitempty" json:"expires_at,omitempty"`
 	CreatedAt   time.Time    `yaml:"created_at" json:"created_at"`
 	LastUsed    *time.Time   `yaml:"last_used,omitempty" json:"last_used,omitempty"`
 	IsActive    bool         `yaml:"is_active" json:"is_active"`
+
+	// SigningSecret requires requests with this key to be signed with it, see SignRequest
+	SigningSecret string `yaml:"signing_secret,omitempty" json:"-"`
 }
 
 // APIKeyConfig represents the configuration for API key authentication
 type APIKeyConfig struct {
-	RequireAuth bool                  `yaml:"require_auth" json:"require_auth"`
-	APIKeys     map[string]APIKeyInfo `yaml:"api_keys" json:"api_keys"`
+	RequireAuth     bool                  `yaml:"require_auth" json:"require_auth"`
+	APIKeys         map[string]APIKeyInfo `yaml:"api_keys" json:"api_keys"`
+	SignatureWindow time.Duration         `yaml:"signature_window,omitempty" json:"signature_window,omitempty"` // How far signed request timestamps may be off, defaults to DefaultSignatureWindow
 }
 
 // APIKeyManager manages API keys and their validation
 type APIKeyManager struct {
-	config *APIKeyConfig
+	mutex       sync.RWMutex // Guards config, which SetConfig may replace while requests are served
+	config      *APIKeyConfig
+	configPath  string // File that Save writes the configuration to, empty if it is not persisted
+	replayGuard *ReplayGuard
 }
 
 // NewAPIKeyManager creates a new API key manager
@@ -49,7 +77,8 @@ func NewAPIKeyManager(config *APIKeyConfig) *APIKeyManager {
 		}
 	}
 	return &APIKeyManager{
-		config: config,
+		config:      config,
+		replayGuard: NewReplayGuard(),
 	}
 }
 
@@ -74,6 +103,9 @@ func (m *APIKeyManager) HashAPIKey(apiKey string) string {
 
 // ValidateAPIKey validates an API key and returns its information
 func (m *APIKeyManager) ValidateAPIKey(apiKey string) (*APIKeyInfo, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
 	if !m.config.RequireAuth {
 		// If auth is not required, return a default key info with all permissions
 		return &APIKeyInfo{
@@ -102,6 +134,7 @@ func (m *APIKeyManager) ValidateAPIKey(apiKey string) (*APIKeyInfo, bool) {
 		return nil, false
 	}
 	
+	keyInfo.ID = keyID(hashedKey)
 	return &keyInfo, true
 }
 
@@ -116,6 +149,9 @@ func (m *APIKeyManager) HasPermission(keyInfo *APIKeyInfo, permission Permission
 		if p == PermissionAdmin || p == permission {
 			return true
 		}
+		if p == PermissionDataProtectionWrite && permission == PermissionDataProtectionRead {
+			return true
+		}
 	}
 	
 	return false
@@ -123,6 +159,9 @@ func (m *APIKeyManager) HasPermission(keyInfo *APIKeyInfo, permission Permission
 
 // UpdateLastUsed updates the last used timestamp for an API key
 func (m *APIKeyManager) UpdateLastUsed(apiKey string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	if !m.config.RequireAuth {
 		return
 	}
@@ -144,6 +183,9 @@ func (m *APIKeyManager) CreateAPIKey(name string, permissions []Permission, rate
 	
 	hashedKey := m.HashAPIKey(apiKey)
 	
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	keyInfo := APIKeyInfo{
 		Name:        name,
 		Permissions: permissions,
@@ -158,8 +200,34 @@ func (m *APIKeyManager) CreateAPIKey(name string, permissions []Permission, rate
 	return apiKey, nil
 }
 
+// EnableSigning generates a signing secret for an API key, from then on requiring its requests
+// to be signed, and returns the secret
+func (m *APIKeyManager) EnableSigning(apiKey string) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	hashedKey := m.HashAPIKey(apiKey)
+	keyInfo, exists := m.config.APIKeys[hashedKey]
+	if !exists {
+		return "", fmt.Errorf("API key not found")
+	}
+	
+	secret, err := GenerateSigningSecret()
+	if err != nil {
+		return "", err
+	}
+	
+	keyInfo.SigningSecret = secret
+	m.config.APIKeys[hashedKey] = keyInfo
+	
+	return secret, nil
+}
+
 // RevokeAPIKey revokes an API key by setting it as inactive
 func (m *APIKeyManager) RevokeAPIKey(apiKey string) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
 	hashedKey := m.HashAPIKey(apiKey)
 	if keyInfo, exists := m.config.APIKeys[hashedKey]; exists {
 		keyInfo.IsActive = false
@@ -171,19 +239,85 @@ func (m *APIKeyManager) RevokeAPIKey(apiKey string) bool {
 
 // ListAPIKeys returns a list of all API keys (without the actual key values)
 func (m *APIKeyManager) ListAPIKeys() []APIKeyInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
 	keys := make([]APIKeyInfo, 0, len(m.config.APIKeys))
-	for _, keyInfo := range m.config.APIKeys {
+	for hashedKey, keyInfo := range m.config.APIKeys {
+		keyInfo.ID = keyID(hashedKey)
 		keys = append(keys, keyInfo)
 	}
 	return keys
 }
 
+// SetRateLimit changes the requests per minute allowed for the API key with the given ID, 0
+// for the global limit. The new limit applies from the key's next request.
+func (m *APIKeyManager) SetRateLimit(id string, rateLimit int) (*APIKeyInfo, error) {
+	if rateLimit < 0 {
+		return nil, fmt.Errorf("rate limit must not be negative")
+	}
+	
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	
+	for hashedKey, keyInfo := range m.config.APIKeys {
+		if id == "" || keyID(hashedKey) != id {
+			continue
+		}
+		keyInfo.RateLimit = rateLimit
+		m.config.APIKeys[hashedKey] = keyInfo
+		keyInfo.ID = id
+		return &keyInfo, nil
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// KeyID returns the ID of an API key as shown by ListAPIKeys
+func (m *APIKeyManager) KeyID(apiKey string) string {
+	return keyID(m.HashAPIKey(apiKey))
+}
+
+// keyID returns the ID of the key with the given hash
+func keyID(hashedKey string) string {
+	if len(hashedKey) < keyIDLength {
+		return hashedKey
+	}
+	return hashedKey[:keyIDLength]
+}
+
+// SetConfigPath makes Save write the configuration to the given file
+func (m *APIKeyManager) SetConfigPath(path string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.configPath = path
+}
+
+// Save writes the configuration to the file set with SetConfigPath, if any, so that changes
+// made while the server runs survive a restart
+func (m *APIKeyManager) Save() error {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	
+	if m.configPath == "" {
+		return nil
+	}
+	return SaveAPIKeyConfig(m.configPath, m.config)
+}
+
 // GetConfig returns the current API key configuration
 func (m *APIKeyManager) GetConfig() *APIKeyConfig {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
 	return m.config
 }
 
-// SetConfig updates the API key configuration
+// SetConfig updates the API key configuration, e.g. when it was reloaded from a secrets manager
 func (m *APIKeyManager) SetConfig(config *APIKeyConfig) {
+	if config.APIKeys == nil {
+		config.APIKeys = make(map[string]APIKeyInfo)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.config = config
 }
\ No newline at end of file
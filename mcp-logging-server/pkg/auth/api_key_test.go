@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -136,6 +138,18 @@ func TestAPIKeyManager_HasPermission(t *testing.T) {
 	if !manager.HasPermission(adminKeyInfo, PermissionIngestLogs) {
 		t.Error("Admin permission should grant all permissions")
 	}
+	
+	// Changing the data protection configuration includes viewing it
+	writeKeyInfo := &APIKeyInfo{
+		Permissions: []Permission{PermissionDataProtectionWrite},
+	}
+	
+	if !manager.HasPermission(writeKeyInfo, PermissionDataProtectionRead) {
+		t.Error("Data protection write permission should grant read")
+	}
+	if manager.HasPermission(&APIKeyInfo{Permissions: []Permission{PermissionDataProtectionRead}}, PermissionDataProtectionWrite) {
+		t.Error("Data protection read permission should not grant write")
+	}
 }
 
 func TestAPIKeyManager_NoAuthRequired(t *testing.T) {
@@ -184,4 +198,53 @@ func TestAPIKeyManager_HashAPIKey(t *testing.T) {
 	if len(hash1) != 64 { // SHA-256 produces 64 character hex string
 		t.Errorf("Expected hash length 64, got %d", len(hash1))
 	}
-}
\ No newline at end of file
+}
+
+func TestAPIKeyManager_SetRateLimit(t *testing.T) {
+	config := &APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]APIKeyInfo)}
+	manager := NewAPIKeyManager(config)
+	configPath := filepath.Join(t.TempDir(), "api-keys.yaml")
+	manager.SetConfigPath(configPath)
+
+	apiKey, err := manager.CreateAPIKey("edge", []Permission{PermissionIngestLogs}, 100, nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	keys := manager.ListAPIKeys()
+	if len(keys) != 1 || keys[0].ID != manager.KeyID(apiKey) {
+		t.Fatalf("Expected the listed key to have ID %s, got %+v", manager.KeyID(apiKey), keys)
+	}
+
+	updated, err := manager.SetRateLimit(keys[0].ID, 250)
+	if err != nil {
+		t.Fatalf("Failed to set rate limit: %v", err)
+	}
+	if updated.RateLimit != 250 || updated.Name != "edge" {
+		t.Errorf("Unexpected updated key %+v", updated)
+	}
+
+	keyInfo, valid := manager.ValidateAPIKey(apiKey)
+	if !valid || keyInfo.RateLimit != 250 || keyInfo.ID != keys[0].ID {
+		t.Errorf("Expected the validated key to have the new rate limit, got %+v", keyInfo)
+	}
+
+	if _, err := manager.SetRateLimit("unknown", 10); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("Expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if _, err := manager.SetRateLimit(keys[0].ID, -1); err == nil {
+		t.Error("Expected error for a negative rate limit")
+	}
+
+	// The new limit survives a restart
+	if err := manager.Save(); err != nil {
+		t.Fatalf("Failed to save config: %v", err)
+	}
+	saved, err := LoadAPIKeyConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load saved config: %v", err)
+	}
+	if saved.APIKeys[manager.HashAPIKey(apiKey)].RateLimit != 250 {
+		t.Errorf("Expected the saved rate limit to be 250, got %+v", saved.APIKeys)
+	}
+}
@@ -39,7 +39,12 @@ func LoadAPIKeyConfig(configPath string) (*APIKeyConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 	
-	// Parse YAML
+	return ParseAPIKeyConfig(data)
+}
+
+// ParseAPIKeyConfig parses an API key configuration in the YAML file format, e.g. one stored
+// in a secrets manager
+func ParseAPIKeyConfig(data []byte) (*APIKeyConfig, error) {
 	var config APIKeyConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
@@ -95,8 +100,12 @@ func MergeConfigs(base, override *APIKeyConfig) *APIKeyConfig {
 	}
 	
 	merged := &APIKeyConfig{
-		RequireAuth: override.RequireAuth || base.RequireAuth,
-		APIKeys:     make(map[string]APIKeyInfo),
+		RequireAuth:     override.RequireAuth || base.RequireAuth,
+		APIKeys:         make(map[string]APIKeyInfo),
+		SignatureWindow: base.SignatureWindow,
+	}
+	if override.SignatureWindow > 0 {
+		merged.SignatureWindow = override.SignatureWindow
 	}
 	
 	// Copy base keys
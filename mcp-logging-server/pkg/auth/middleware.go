@@ -1,10 +1,12 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
 )
 
 // AuthMiddleware creates a Gin middleware for API key authentication
@@ -25,22 +27,20 @@ func AuthMiddleware(keyManager *APIKeyManager) gin.HandlerFunc {
 		// Extract API key from header
 		apiKey := extractAPIKey(c)
 		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "API key required",
-				"code":  "MISSING_API_KEY",
-			})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeMissingAPIKey, "API key required", "")
 			return
 		}
 		
 		// Validate API key
 		keyInfo, valid := keyManager.ValidateAPIKey(apiKey)
 		if !valid {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or expired API key",
-				"code":  "INVALID_API_KEY",
-			})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidAPIKey, "Invalid or expired API key", "")
+			return
+		}
+		
+		// Verify the request signature of keys with a signing secret
+		if err := keyManager.VerifyRequest(keyInfo, c.Request); err != nil {
+			problem.Abort(c, http.StatusUnauthorized, signatureErrorCode(err), "Request signature rejected", err.Error())
 			return
 		}
 		
@@ -67,29 +67,19 @@ func RequirePermission(keyManager *APIKeyManager, permission Permission) gin.Han
 		// Get key info from context (set by AuthMiddleware)
 		keyInfoInterface, exists := c.Get("api_key_info")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authentication required",
-				"code":  "AUTHENTICATION_REQUIRED",
-			})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeAuthenticationRequired, "Authentication required", "")
 			return
 		}
 		
 		keyInfo, ok := keyInfoInterface.(*APIKeyInfo)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Invalid authentication context",
-				"code":  "INVALID_AUTH_CONTEXT",
-			})
-			c.Abort()
+			problem.Abort(c, http.StatusInternalServerError, problem.CodeInvalidAuthContext, "Invalid authentication context", "")
 			return
 		}
 		
 		// Check permission
 		if !keyManager.HasPermission(keyInfo, permission) {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Insufficient permissions",
-				"code":  "INSUFFICIENT_PERMISSIONS",
+			problem.RespondDetails(c, http.StatusForbidden, problem.CodeInsufficientPermissions, "Insufficient permissions", gin.H{
 				"required_permission": permission,
 			})
 			c.Abort()
@@ -125,10 +115,26 @@ func extractAPIKey(c *gin.Context) string {
 	return ""
 }
 
+// signatureErrorCode returns the error code for a failed signature verification
+func signatureErrorCode(err error) problem.Code {
+	switch {
+	case errors.Is(err, ErrMissingSignature):
+		return problem.CodeMissingSignature
+	case errors.Is(err, ErrSignatureExpired):
+		return problem.CodeSignatureExpired
+	case errors.Is(err, ErrReplayedRequest):
+		return problem.CodeReplayedRequest
+	default:
+		return problem.CodeInvalidSignature
+	}
+}
+
 // isPublicEndpoint checks if an endpoint should be publicly accessible
 func isPublicEndpoint(path string) bool {
 	publicEndpoints := []string{
 		"/health",
+		"/openapi.json",
+		"/v1/capabilities",
 		"/ping",
 		"/version",
 	}
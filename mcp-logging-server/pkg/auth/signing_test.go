@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSigningTestManager(t *testing.T) (*APIKeyManager, string, string) {
+	manager := NewAPIKeyManager(&APIKeyConfig{
+		RequireAuth: true,
+		APIKeys:     make(map[string]APIKeyInfo),
+	})
+
+	apiKey, err := manager.CreateAPIKey("edge-agent", []Permission{PermissionIngestLogs}, 1000, nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	secret, err := manager.EnableSigning(apiKey)
+	if err != nil {
+		t.Fatalf("Failed to enable signing: %v", err)
+	}
+	return manager, apiKey, secret
+}
+
+func newSignedRequest(t *testing.T, apiKey, secret, body string) *http.Request {
+	req, _ := http.NewRequest("POST", "/v1/logs?source=edge", bytes.NewBufferString(body))
+	req.Header.Set("X-API-Key", apiKey)
+	if err := SignRequest(req, secret); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+	return req
+}
+
+func TestAPIKeyManager_VerifyRequest(t *testing.T) {
+	manager, apiKey, secret := newSigningTestManager(t)
+	keyInfo, _ := manager.ValidateAPIKey(apiKey)
+	if keyInfo.SigningSecret != secret {
+		t.Fatal("Expected the signing secret to be stored with the key")
+	}
+
+	req := newSignedRequest(t, apiKey, secret, `{"message":"signed"}`)
+	if err := manager.VerifyRequest(keyInfo, req); err != nil {
+		t.Fatalf("Expected a valid signature, got %v", err)
+	}
+
+	// The body can still be read by the handler
+	var body map[string]string
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil || body["message"] != "signed" {
+		t.Errorf("Expected the body to be restored, got %v: %v", body, err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *http.Request)
+		want   error
+	}{
+		{name: "missing signature", modify: func(req *http.Request) { req.Header.Del(SignatureHeader) }, want: ErrMissingSignature},
+		{name: "tampered body", modify: func(req *http.Request) { req.Body = http.NoBody }, want: ErrInvalidSignature},
+		{name: "other path", modify: func(req *http.Request) { req.URL.Path = "/v1/logs/batch" }, want: ErrInvalidSignature},
+		{name: "wrong secret", modify: func(req *http.Request) {
+			timestamp, _ := strconv.ParseInt(req.Header.Get(SignatureTimestampHeader), 10, 64)
+			req.Header.Set(SignatureHeader, Sign("other-secret", timestamp, req.Method, req.URL.RequestURI(), []byte(`{"message":"fresh"}`)))
+		}, want: ErrInvalidSignature},
+		{name: "expired timestamp", modify: func(req *http.Request) {
+			timestamp := time.Now().Add(-10 * time.Minute).Unix()
+			req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(timestamp, 10))
+			req.Header.Set(SignatureHeader, Sign(secret, timestamp, req.Method, req.URL.RequestURI(), []byte(`{"message":"fresh"}`)))
+		}, want: ErrSignatureExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newSignedRequest(t, apiKey, secret, `{"message":"fresh"}`)
+			tt.modify(req)
+			if err := manager.VerifyRequest(keyInfo, req); err != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Keys without a signing secret do not need signatures
+	if err := manager.VerifyRequest(&APIKeyInfo{Name: "unsigned"}, httptest.NewRequest("GET", "/v1/search", nil)); err != nil {
+		t.Errorf("Expected unsigned keys to pass, got %v", err)
+	}
+}
+
+func TestAPIKeyManager_SignatureWindow(t *testing.T) {
+	manager, apiKey, secret := newSigningTestManager(t)
+	manager.GetConfig().SignatureWindow = time.Hour
+	keyInfo, _ := manager.ValidateAPIKey(apiKey)
+
+	req, _ := http.NewRequest("POST", "/v1/logs", bytes.NewBufferString("{}"))
+	timestamp := time.Now().Add(-30 * time.Minute).Unix()
+	req.Header.Set(SignatureTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, "POST", "/v1/logs", []byte("{}")))
+
+	if err := manager.VerifyRequest(keyInfo, req); err != nil {
+		t.Errorf("Expected a timestamp inside the configured window to pass, got %v", err)
+	}
+}
+
+func TestAuthMiddleware_SignedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	manager, apiKey, secret := newSigningTestManager(t)
+
+	router := gin.New()
+	router.Use(AuthMiddleware(manager))
+	router.POST("/v1/logs", func(c *gin.Context) {
+		var body map[string]string
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var response struct {
+			Code string `json:"code"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Code
+	}
+
+	signed, _ := http.NewRequest("POST", "/v1/logs", bytes.NewBufferString(`{"message":"signed"}`))
+	signed.Header.Set("X-API-Key", apiKey)
+	if err := SignRequest(signed, secret); err != nil {
+		t.Fatalf("Failed to sign request: %v", err)
+	}
+	replayed, _ := http.NewRequest("POST", "/v1/logs", bytes.NewBufferString(`{"message":"signed"}`))
+	replayed.Header = signed.Header.Clone()
+
+	if w := serve(signed); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// The same signed request is rejected when it is sent again
+	if w := serve(replayed); w.Code != http.StatusUnauthorized || errorCode(w) != "REPLAYED_REQUEST" {
+		t.Errorf("Expected a replayed request to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+
+	unsigned, _ := http.NewRequest("POST", "/v1/logs", bytes.NewBufferString(`{"message":"unsigned"}`))
+	unsigned.Header.Set("X-API-Key", apiKey)
+	if w := serve(unsigned); w.Code != http.StatusUnauthorized || errorCode(w) != "MISSING_SIGNATURE" {
+		t.Errorf("Expected an unsigned request to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestReplayGuard_Use(t *testing.T) {
+	guard := NewReplayGuard()
+
+	if !guard.Use("signature", time.Now().Add(time.Minute)) {
+		t.Error("Expected the first use to be accepted")
+	}
+	if guard.Use("signature", time.Now().Add(time.Minute)) {
+		t.Error("Expected the second use to be rejected")
+	}
+
+	// Expired signatures are forgotten
+	if !guard.Use("expired", time.Now().Add(-time.Second)) || !guard.Use("expired", time.Now().Add(time.Minute)) {
+		t.Error("Expected an expired signature to be accepted again")
+	}
+}
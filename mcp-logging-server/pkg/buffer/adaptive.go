@@ -0,0 +1,144 @@
+package buffer
+
+import (
+	"sync"
+	"time"
+)
+
+// AdaptiveConfig contains bounds for adaptive batch sizing and flush tuning
+type AdaptiveConfig struct {
+	MinBatchSize     int           // Smallest batch size the tuner shrinks to
+	MaxBatchSize     int           // Largest batch size the tuner grows to
+	MinFlushInterval time.Duration // Shortest flush interval, used at low volume
+	MaxFlushInterval time.Duration // Longest flush interval, used while storage is struggling
+	TargetLatency    time.Duration // Storage write latency above which batches shrink
+}
+
+// AdaptiveTuner adjusts batch size and flush interval from observed storage
+// latency and errors using additive increase / multiplicative decrease.
+// Fast successful writes grow the batch size by a fixed step and shorten the
+// flush interval, slow or failed writes halve the batch size and double the
+// flush interval.
+type AdaptiveTuner struct {
+	mutex         sync.Mutex
+	config        AdaptiveConfig
+	batchSize     int
+	flushInterval time.Duration
+	lastLatency   time.Duration
+	errorCount    int64
+}
+
+// AdaptiveStats contains the current adaptive tuning state
+type AdaptiveStats struct {
+	BatchSize       int   `json:"batch_size"`
+	FlushIntervalMs int64 `json:"flush_interval_ms"`
+	LastLatencyMs   int64 `json:"last_latency_ms"`
+	ErrorCount      int64 `json:"error_count"`
+}
+
+// NewAdaptiveTuner creates a tuner starting at the minimum batch size and flush interval
+func NewAdaptiveTuner(config AdaptiveConfig) *AdaptiveTuner {
+	if config.MinBatchSize < 1 {
+		config.MinBatchSize = 1
+	}
+	if config.MaxBatchSize < config.MinBatchSize {
+		config.MaxBatchSize = config.MinBatchSize
+	}
+	if config.MinFlushInterval <= 0 {
+		config.MinFlushInterval = 100 * time.Millisecond
+	}
+	if config.MaxFlushInterval < config.MinFlushInterval {
+		config.MaxFlushInterval = config.MinFlushInterval
+	}
+	if config.TargetLatency <= 0 {
+		config.TargetLatency = 200 * time.Millisecond
+	}
+
+	return &AdaptiveTuner{
+		config:        config,
+		batchSize:     config.MinBatchSize,
+		flushInterval: config.MinFlushInterval,
+	}
+}
+
+// BatchSize returns the current batch size
+func (t *AdaptiveTuner) BatchSize() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.batchSize
+}
+
+// FlushInterval returns the current flush interval
+func (t *AdaptiveTuner) FlushInterval() time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.flushInterval
+}
+
+// Observe records the outcome of a storage write and adjusts the tuning
+func (t *AdaptiveTuner) Observe(latency time.Duration, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.lastLatency = latency
+
+	if err != nil || latency > t.config.TargetLatency {
+		if err != nil {
+			t.errorCount++
+		}
+
+		// Multiplicative decrease
+		t.batchSize = t.batchSize / 2
+		if t.batchSize < t.config.MinBatchSize {
+			t.batchSize = t.config.MinBatchSize
+		}
+
+		t.flushInterval = t.flushInterval * 2
+		if t.flushInterval > t.config.MaxFlushInterval {
+			t.flushInterval = t.config.MaxFlushInterval
+		}
+		return
+	}
+
+	// Additive increase
+	t.batchSize += t.batchStep()
+	if t.batchSize > t.config.MaxBatchSize {
+		t.batchSize = t.config.MaxBatchSize
+	}
+
+	t.flushInterval -= t.intervalStep()
+	if t.flushInterval < t.config.MinFlushInterval {
+		t.flushInterval = t.config.MinFlushInterval
+	}
+}
+
+// GetStats returns the current tuning state
+func (t *AdaptiveTuner) GetStats() AdaptiveStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return AdaptiveStats{
+		BatchSize:       t.batchSize,
+		FlushIntervalMs: t.flushInterval.Milliseconds(),
+		LastLatencyMs:   t.lastLatency.Milliseconds(),
+		ErrorCount:      t.errorCount,
+	}
+}
+
+// batchStep returns the additive batch size increase, a tenth of the range
+func (t *AdaptiveTuner) batchStep() int {
+	step := (t.config.MaxBatchSize - t.config.MinBatchSize) / 10
+	if step < 1 {
+		step = 1
+	}
+	return step
+}
+
+// intervalStep returns the additive flush interval decrease, a tenth of the range
+func (t *AdaptiveTuner) intervalStep() time.Duration {
+	step := (t.config.MaxFlushInterval - t.config.MinFlushInterval) / 10
+	if step < time.Millisecond {
+		step = time.Millisecond
+	}
+	return step
+}
@@ -0,0 +1,65 @@
+package buffer
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTuner_Observe(t *testing.T) {
+	tuner := NewAdaptiveTuner(AdaptiveConfig{
+		MinBatchSize:     10,
+		MaxBatchSize:     110,
+		MinFlushInterval: 100 * time.Millisecond,
+		MaxFlushInterval: 1100 * time.Millisecond,
+		TargetLatency:    50 * time.Millisecond,
+	})
+
+	if tuner.BatchSize() != 10 || tuner.FlushInterval() != 100*time.Millisecond {
+		t.Fatalf("Expected tuner to start at the minimums, got %d and %v", tuner.BatchSize(), tuner.FlushInterval())
+	}
+
+	// Fast writes grow the batch size additively up to the maximum
+	for i := 0; i < 3; i++ {
+		tuner.Observe(time.Millisecond, nil)
+	}
+	if tuner.BatchSize() != 40 {
+		t.Errorf("Expected batch size 40, got %d", tuner.BatchSize())
+	}
+	for i := 0; i < 20; i++ {
+		tuner.Observe(time.Millisecond, nil)
+	}
+	if tuner.BatchSize() != 110 {
+		t.Errorf("Expected batch size capped at 110, got %d", tuner.BatchSize())
+	}
+
+	// Slow writes halve the batch size and back off the flush interval
+	tuner.Observe(100*time.Millisecond, nil)
+	if tuner.BatchSize() != 55 {
+		t.Errorf("Expected batch size 55, got %d", tuner.BatchSize())
+	}
+	if tuner.FlushInterval() != 200*time.Millisecond {
+		t.Errorf("Expected flush interval 200ms, got %v", tuner.FlushInterval())
+	}
+
+	// Errors decrease down to the minimum and are counted
+	for i := 0; i < 10; i++ {
+		tuner.Observe(time.Millisecond, errors.New("storage error"))
+	}
+	stats := tuner.GetStats()
+	if stats.BatchSize != 10 {
+		t.Errorf("Expected batch size floored at 10, got %d", stats.BatchSize)
+	}
+	if stats.FlushIntervalMs != 1100 {
+		t.Errorf("Expected flush interval capped at 1100ms, got %dms", stats.FlushIntervalMs)
+	}
+	if stats.ErrorCount != 10 {
+		t.Errorf("Expected 10 errors, got %d", stats.ErrorCount)
+	}
+
+	// Recovery shortens the flush interval step by step
+	tuner.Observe(time.Millisecond, nil)
+	if tuner.FlushInterval() != 1000*time.Millisecond {
+		t.Errorf("Expected flush interval 1000ms, got %v", tuner.FlushInterval())
+	}
+}
@@ -0,0 +1,227 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// DefaultReconcileInterval is how often entries are copied back from the fallback storage
+// when no interval is configured
+const DefaultReconcileInterval = 30 * time.Second
+
+// CircuitBreaker guards writes to the primary storage. Execute returns an error without
+// calling fn while the circuit is open.
+type CircuitBreaker interface {
+	Execute(fn func() error) error
+}
+
+// FailoverConfig configures a fallback storage that receives the batches the primary storage
+// cannot take. Entries are copied back to the primary storage once it recovers.
+type FailoverConfig struct {
+	// Fallback receives batches while the primary storage fails. It must implement
+	// storage.LogDeleter so that entries copied back can be removed from it.
+	Fallback storage.LogStorage
+
+	// Breaker guards the primary storage, batches go straight to the fallback while it is
+	// open. Nil only fails over when a write to the primary storage fails.
+	Breaker CircuitBreaker
+
+	ReconcileInterval time.Duration // How often entries are copied back, defaults to DefaultReconcileInterval
+}
+
+// failover writes batches to the fallback storage and copies them back to the primary
+type failover struct {
+	fallback storage.LogStorage
+	deleter  storage.LogDeleter
+	breaker  CircuitBreaker
+	interval time.Duration
+
+	mutex sync.Mutex
+	stats FailoverStats
+}
+
+// FailoverStats reports the activity of the fallback storage
+type FailoverStats struct {
+	FallbackWrites     int64     `json:"fallback_writes"`               // Entries written to the fallback storage
+	Reconciled         int64     `json:"reconciled"`                    // Entries copied back to the primary storage
+	LastFallbackWrite  time.Time `json:"last_fallback_write,omitempty"` // Zero if the fallback was never used
+	LastReconcileError string    `json:"last_reconcile_error,omitempty"`
+}
+
+// newFailover checks the failover configuration
+func newFailover(config FailoverConfig) (*failover, error) {
+	if config.Fallback == nil {
+		return nil, errors.New("fallback storage is required")
+	}
+	deleter, ok := config.Fallback.(storage.LogDeleter)
+	if !ok {
+		return nil, errors.New("fallback storage does not support deleting entries")
+	}
+
+	interval := config.ReconcileInterval
+	if interval <= 0 {
+		interval = DefaultReconcileInterval
+	}
+
+	return &failover{
+		fallback: config.Fallback,
+		deleter:  deleter,
+		breaker:  config.Breaker,
+		interval: interval,
+	}, nil
+}
+
+// getStats returns a copy of the failover statistics
+func (f *failover) getStats() FailoverStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.stats
+}
+
+// store writes a batch to the primary storage, or to the fallback storage if the primary
+// fails or its circuit breaker is open
+func (mb *MessageBuffer) store(ctx context.Context, batch []models.LogEntry) error {
+	if mb.failover == nil {
+		return mb.storage.Store(ctx, batch)
+	}
+
+	err := mb.storePrimary(ctx, batch)
+	if err == nil {
+		return nil
+	}
+
+	if fallbackErr := mb.failover.fallback.Store(ctx, batch); fallbackErr != nil {
+		return fmt.Errorf("primary storage: %v, fallback storage: %w", err, fallbackErr)
+	}
+
+	mb.failover.mutex.Lock()
+	mb.failover.stats.FallbackWrites += int64(len(batch))
+	mb.failover.stats.LastFallbackWrite = time.Now()
+	mb.failover.mutex.Unlock()
+	return nil
+}
+
+// storePrimary writes to the primary storage through its circuit breaker, if there is one
+func (mb *MessageBuffer) storePrimary(ctx context.Context, batch []models.LogEntry) error {
+	if mb.failover.breaker == nil {
+		return mb.storage.Store(ctx, batch)
+	}
+	return mb.failover.breaker.Execute(func() error {
+		return mb.storage.Store(ctx, batch)
+	})
+}
+
+// reconcileRoutine periodically copies entries from the fallback storage back to the primary
+func (mb *MessageBuffer) reconcileRoutine(ctx context.Context) {
+	defer mb.wg.Done()
+
+	ticker := time.NewTicker(mb.failover.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-mb.stopCh:
+			return
+		case <-ticker.C:
+			_, err := mb.Reconcile(ctx)
+
+			mb.failover.mutex.Lock()
+			mb.failover.stats.LastReconcileError = ""
+			if err != nil {
+				mb.failover.stats.LastReconcileError = err.Error()
+			}
+			mb.failover.mutex.Unlock()
+		}
+	}
+}
+
+// Reconcile copies the entries in the fallback storage back to the primary storage in
+// batches and removes them from the fallback. It stops at the first failure and leaves the
+// remaining entries for the next run. Without a fallback storage it does nothing.
+func (mb *MessageBuffer) Reconcile(ctx context.Context) (int, error) {
+	if mb.failover == nil {
+		return 0, nil
+	}
+
+	reconciled := 0
+	for {
+		batch, err := mb.fallbackBatch(ctx)
+		if err != nil {
+			return reconciled, fmt.Errorf("failed to read fallback storage: %w", err)
+		}
+		if len(batch) == 0 {
+			return reconciled, nil
+		}
+
+		if err := mb.storePrimary(ctx, mb.missingFromPrimary(ctx, batch)); err != nil {
+			return reconciled, fmt.Errorf("failed to copy entries to primary storage: %w", err)
+		}
+
+		ids := make([]string, len(batch))
+		for i, entry := range batch {
+			ids[i] = entry.ID
+		}
+		deleted, err := mb.failover.deleter.DeleteByIDs(ctx, ids)
+		if err != nil {
+			return reconciled, fmt.Errorf("failed to remove entries from fallback storage: %w", err)
+		}
+		if deleted == 0 {
+			// Nothing would ever be removed, stop rather than copying the same batch forever
+			return reconciled, errors.New("fallback storage did not remove reconciled entries")
+		}
+
+		reconciled += deleted
+		mb.failover.mutex.Lock()
+		mb.failover.stats.Reconciled += int64(deleted)
+		mb.failover.mutex.Unlock()
+	}
+}
+
+// fallbackBatch reads up to a batch of entries from the fallback storage
+func (mb *MessageBuffer) fallbackBatch(ctx context.Context) ([]models.LogEntry, error) {
+	iterator, err := mb.failover.fallback.QueryStream(ctx, models.LogFilter{Limit: mb.batchSize()})
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	var batch []models.LogEntry
+	for iterator.Next() {
+		batch = append(batch, iterator.Entry())
+	}
+	return batch, iterator.Err()
+}
+
+// missingFromPrimary drops the entries the primary storage already holds, which were copied
+// by a run that then failed to remove them from the fallback. If the primary cannot be asked,
+// all entries are returned and the write decides.
+func (mb *MessageBuffer) missingFromPrimary(ctx context.Context, batch []models.LogEntry) []models.LogEntry {
+	ids := make([]string, len(batch))
+	for i, entry := range batch {
+		ids[i] = entry.ID
+	}
+	existing, err := mb.storage.GetByIDs(ctx, ids)
+	if err != nil || len(existing) == 0 {
+		return batch
+	}
+
+	stored := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		stored[entry.ID] = true
+	}
+	missing := make([]models.LogEntry, 0, len(batch))
+	for _, entry := range batch {
+		if !stored[entry.ID] {
+			missing = append(missing, entry)
+		}
+	}
+	return missing
+}
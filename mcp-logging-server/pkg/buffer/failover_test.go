@@ -0,0 +1,146 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// openBreaker is a circuit breaker that never lets writes through
+type openBreaker struct{}
+
+func (openBreaker) Execute(fn func() error) error {
+	return errors.New("circuit breaker is open")
+}
+
+func newFailoverBuffer(t *testing.T, primary storage.LogStorage, breaker CircuitBreaker) (*MessageBuffer, *storage.MemoryStorage) {
+	t.Helper()
+
+	fallback := storage.NewMemoryStorage()
+	buffer := NewMessageBufferWithOptions(primary, Config{
+		Size:         10,
+		MaxBatchSize: 5,
+		FlushTimeout: time.Second,
+	}, Options{
+		Failover: &FailoverConfig{Fallback: fallback, Breaker: breaker},
+	})
+	if buffer.failover == nil {
+		t.Fatal("Expected failover to be configured")
+	}
+	return buffer, fallback
+}
+
+func countEntries(t *testing.T, store storage.LogStorage) int {
+	t.Helper()
+
+	result, err := store.Query(context.Background(), models.LogFilter{Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to query storage: %v", err)
+	}
+	return len(result.Logs)
+}
+
+func TestMessageBuffer_FailoverOnStorageError(t *testing.T) {
+	primary := &MockStorage{storeError: errors.New("storage error")}
+	buffer, fallback := newFailoverBuffer(t, primary, nil)
+
+	entries := []models.LogEntry{
+		createTestLogEntry(uuid.New().String()),
+		createTestLogEntry(uuid.New().String()),
+	}
+	if err := buffer.Add(entries); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Expected flush to fail over, got %v", err)
+	}
+
+	if count := countEntries(t, fallback); count != 2 {
+		t.Errorf("Expected 2 entries in the fallback storage, got %d", count)
+	}
+
+	stats := buffer.GetStats()
+	if stats.Size != 0 {
+		t.Errorf("Expected buffer to be empty after failover, got size %d", stats.Size)
+	}
+	if stats.Failover == nil || stats.Failover.FallbackWrites != 2 {
+		t.Errorf("Expected 2 fallback writes in stats, got %+v", stats.Failover)
+	}
+}
+
+func TestMessageBuffer_FailoverOpenBreaker(t *testing.T) {
+	primary := &MockStorage{}
+	buffer, fallback := newFailoverBuffer(t, primary, openBreaker{})
+
+	if err := buffer.Add([]models.LogEntry{createTestLogEntry(uuid.New().String())}); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Expected flush to fail over, got %v", err)
+	}
+
+	if called := primary.GetStoreCalled(); called != 0 {
+		t.Errorf("Expected the open breaker to skip the primary storage, got %d writes", called)
+	}
+	if count := countEntries(t, fallback); count != 1 {
+		t.Errorf("Expected 1 entry in the fallback storage, got %d", count)
+	}
+
+	// Reconciling fails while the breaker stays open and keeps the entries
+	if _, err := buffer.Reconcile(context.Background()); err == nil {
+		t.Error("Expected reconcile to fail while the breaker is open")
+	}
+	if count := countEntries(t, fallback); count != 1 {
+		t.Errorf("Expected the fallback storage to keep 1 entry, got %d", count)
+	}
+}
+
+func TestMessageBuffer_Reconcile(t *testing.T) {
+	primary := &MockStorage{storeError: errors.New("storage error")}
+	buffer, fallback := newFailoverBuffer(t, primary, nil)
+
+	for i := 0; i < 7; i++ {
+		if err := buffer.Add([]models.LogEntry{createTestLogEntry(uuid.New().String())}); err != nil {
+			t.Fatalf("Failed to add entries: %v", err)
+		}
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Expected flush to fail over, got %v", err)
+	}
+
+	// The primary storage recovers
+	primary.mutex.Lock()
+	primary.storeError = nil
+	primary.mutex.Unlock()
+
+	reconciled, err := buffer.Reconcile(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to reconcile: %v", err)
+	}
+	if reconciled != 7 {
+		t.Errorf("Expected 7 reconciled entries, got %d", reconciled)
+	}
+	if stored := len(primary.GetStoredLogs()); stored != 7 {
+		t.Errorf("Expected 7 entries in the primary storage, got %d", stored)
+	}
+	if count := countEntries(t, fallback); count != 0 {
+		t.Errorf("Expected the fallback storage to be empty, got %d entries", count)
+	}
+	if stats := buffer.GetStats(); stats.Failover.Reconciled != 7 {
+		t.Errorf("Expected 7 reconciled entries in stats, got %d", stats.Failover.Reconciled)
+	}
+}
+
+func TestNewFailover_RequiresDeleter(t *testing.T) {
+	if _, err := newFailover(FailoverConfig{Fallback: &MockStorage{}}); err == nil {
+		t.Error("Expected a fallback storage without DeleteByIDs to be rejected")
+	}
+	if _, err := newFailover(FailoverConfig{}); err == nil {
+		t.Error("Expected a missing fallback storage to be rejected")
+	}
+}
@@ -3,10 +3,12 @@ package buffer
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"sync"
 	"time"
 
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/pool"
 	"github.com/kerlexov/mcp-logging-server/pkg/storage"
 )
 
@@ -16,6 +18,8 @@ type MessageBuffer struct {
 	buffer          []models.LogEntry
 	mutex           sync.RWMutex
 	size            int
+	maxBytes        int64 // Budget for the estimated memory of buffered entries, 0 bounds only their number
+	bytes           int64 // Estimated memory of the buffered entries
 	maxBatchSize    int
 	flushTimeout    time.Duration
 	stopCh          chan struct{}
@@ -23,8 +27,26 @@ type MessageBuffer struct {
 	wg              sync.WaitGroup
 	recoveryManager RecoveryManager
 	metrics         MetricsReporter
+	serviceQuota    int            // Entries a service may hold once the buffer is full, 0 disables fair-share
+	serviceCounts   map[string]int // Buffered entries per service
+	evictionPolicy  EvictionPolicy
+	tuner           *AdaptiveTuner // Adjusts batch size and flush interval, nil uses the fixed values
+	failover        *failover      // Writes to a fallback storage while the primary fails, nil disables failover
+	workers         int            // Concurrent Store calls per flush
+	ordered         bool           // Keeps all entries of a service on one worker
+	committed       CommitListener // Notified of every stored batch, nil notifies nothing
 }
 
+// EvictionPolicy selects which entries are dropped when the buffer is full
+type EvictionPolicy string
+
+const (
+	// EvictionOldest drops the oldest entries first
+	EvictionOldest EvictionPolicy = "oldest"
+	// EvictionLevel drops the least severe entries first, oldest first within a level
+	EvictionLevel EvictionPolicy = "level"
+)
+
 // RecoveryManager interface for saving pending logs
 type RecoveryManager interface {
 	SavePendingLogs(logs []models.LogEntry) error
@@ -35,19 +57,62 @@ type MetricsReporter interface {
 	IncrementBufferFlushes()
 	IncrementBufferFlushErrors()
 	IncrementBufferOverflows()
+	IncrementServiceDrops(serviceName string)
+	IncrementBufferRetries()
+	ObserveBufferFlush(duration time.Duration, entries int)
+}
+
+// CommitListener is notified of every batch once it has been stored, such as to replicate it.
+// Committed is called from the flush, so it must not block and must not modify or retain the
+// batch, whose slice is reused once Committed returns.
+type CommitListener interface {
+	Committed(batch []models.LogEntry)
+}
+
+// CommitListeners notifies several listeners of every stored batch, in order
+type CommitListeners []CommitListener
+
+// Committed notifies each listener of the batch
+func (l CommitListeners) Committed(batch []models.LogEntry) {
+	for _, listener := range l {
+		listener.Committed(batch)
+	}
 }
 
 // Config contains configuration for the message buffer
 type Config struct {
 	Size         int           // Maximum buffer size
+	MaxBytes     int64         // Maximum estimated memory of the buffered entries, 0 bounds only their number
 	MaxBatchSize int           // Maximum batch size for storage writes
 	FlushTimeout time.Duration // Timeout for automatic flushing
+
+	// MaxServiceShare is the fraction of the buffer a single service may hold
+	// once the buffer is full (0 < share < 1). Zero disables fair-share admission.
+	MaxServiceShare float64
+
+	// EvictionPolicy selects which entries are dropped when the buffer is full.
+	// Defaults to EvictionOldest.
+	EvictionPolicy EvictionPolicy
+
+	// Adaptive enables adaptive batch sizing and flush tuning. MaxBatchSize and
+	// FlushTimeout are then used as the upper bounds. Nil keeps them fixed.
+	Adaptive *AdaptiveConfig
+
+	// FlushWorkers is how many batches are stored concurrently during a flush, bounded by
+	// the storage's MaxConcurrentWrites. Zero or one stores batches sequentially.
+	FlushWorkers int
+
+	// OrderedPerService stores all entries of a service through the same worker, in the
+	// order they were added, when FlushWorkers is above one
+	OrderedPerService bool
 }
 
 // Options contains optional dependencies for the message buffer
 type Options struct {
 	RecoveryManager RecoveryManager
 	MetricsReporter MetricsReporter
+	Failover        *FailoverConfig // Nil keeps retrying the primary storage only
+	CommitListener  CommitListener  // Notified of every stored batch, nil notifies nothing
 }
 
 // NewMessageBuffer creates a new message buffer
@@ -57,23 +122,81 @@ func NewMessageBuffer(storage storage.LogStorage, config Config) *MessageBuffer
 
 // NewMessageBufferWithOptions creates a new message buffer with optional dependencies
 func NewMessageBufferWithOptions(storage storage.LogStorage, config Config, options Options) *MessageBuffer {
+	serviceQuota := 0
+	if config.MaxServiceShare > 0 && config.MaxServiceShare < 1 {
+		serviceQuota = int(float64(config.Size) * config.MaxServiceShare)
+		if serviceQuota < 1 {
+			serviceQuota = 1
+		}
+	}
+
+	evictionPolicy := config.EvictionPolicy
+	if evictionPolicy == "" {
+		evictionPolicy = EvictionOldest
+	}
+
+	var tuner *AdaptiveTuner
+	if config.Adaptive != nil {
+		adaptiveConfig := *config.Adaptive
+		adaptiveConfig.MaxBatchSize = config.MaxBatchSize
+		adaptiveConfig.MaxFlushInterval = config.FlushTimeout
+		tuner = NewAdaptiveTuner(adaptiveConfig)
+	}
+
+	var fallback *failover
+	if options.Failover != nil {
+		var err error
+		if fallback, err = newFailover(*options.Failover); err != nil {
+			// Log error but continue without failover
+			fmt.Printf("Failed to initialize storage failover: %v\n", err)
+		}
+	}
+
 	return &MessageBuffer{
 		storage:         storage,
 		buffer:          make([]models.LogEntry, 0, config.Size),
 		size:            config.Size,
+		maxBytes:        config.MaxBytes,
 		maxBatchSize:    config.MaxBatchSize,
 		flushTimeout:    config.FlushTimeout,
 		stopCh:          make(chan struct{}),
 		flushCh:         make(chan struct{}, 1),
 		recoveryManager: options.RecoveryManager,
 		metrics:         options.MetricsReporter,
+		serviceQuota:    serviceQuota,
+		serviceCounts:   make(map[string]int),
+		evictionPolicy:  evictionPolicy,
+		tuner:           tuner,
+		failover:        fallback,
+		workers:         flushWorkers(storage, config.FlushWorkers),
+		ordered:         config.OrderedPerService,
+		committed:       options.CommitListener,
+	}
+}
+
+// flushWorkers bounds the configured flush workers by the writes the storage allows at once
+func flushWorkers(target storage.LogStorage, configured int) int {
+	workers := configured
+	if writer, ok := target.(storage.ConcurrentWriter); ok {
+		if limit := writer.MaxConcurrentWrites(); limit > 0 && workers > limit {
+			workers = limit
+		}
+	}
+	if workers < 1 {
+		workers = 1
 	}
+	return workers
 }
 
 // Start starts the buffer's background flush routine
 func (mb *MessageBuffer) Start(ctx context.Context) {
 	mb.wg.Add(1)
 	go mb.flushRoutine(ctx)
+
+	if mb.failover != nil {
+		mb.wg.Add(1)
+		go mb.reconcileRoutine(ctx)
+	}
 }
 
 // Stop stops the buffer and flushes any remaining entries
@@ -104,23 +227,34 @@ func (mb *MessageBuffer) Add(entries []models.LogEntry) error {
 	defer mb.mutex.Unlock()
 
 	for _, entry := range entries {
-		// Check if buffer is full
-		if len(mb.buffer) >= mb.size {
-			// Implement rotation strategy - remove oldest entries
-			removeCount := len(mb.buffer) - mb.size + 1
-			mb.buffer = mb.buffer[removeCount:]
+		size := estimateSize(&entry)
 
-			// Report buffer overflow
+		// An entry larger than the whole budget could only be admitted by emptying the buffer
+		if mb.maxBytes > 0 && size > mb.maxBytes {
 			if mb.metrics != nil {
 				mb.metrics.IncrementBufferOverflows()
 			}
+			mb.recordServiceDrop(entry.ServiceName)
+			continue
+		}
+
+		// Check if buffer is full
+		if mb.full(size) && mb.metrics != nil {
+			// Report buffer overflow
+			mb.metrics.IncrementBufferOverflows()
+		}
+		if !mb.makeRoom(entry, size) {
+			mb.recordServiceDrop(entry.ServiceName)
+			continue
 		}
 
 		mb.buffer = append(mb.buffer, entry)
+		mb.serviceCounts[entry.ServiceName]++
+		mb.bytes += size
 	}
 
 	// Trigger flush if buffer is getting full or batch size is reached
-	if len(mb.buffer) >= mb.maxBatchSize {
+	if len(mb.buffer) >= mb.batchSize() || (mb.maxBytes > 0 && mb.bytes >= mb.maxBytes/2) {
 		select {
 		case mb.flushCh <- struct{}{}:
 		default:
@@ -131,6 +265,42 @@ func (mb *MessageBuffer) Add(entries []models.LogEntry) error {
 	return nil
 }
 
+// full reports whether an entry of the given size only fits once others are evicted: the
+// buffer holds its maximum number of entries or the entry would exceed the memory budget.
+// Must be called with the mutex held.
+func (mb *MessageBuffer) full(size int64) bool {
+	return len(mb.buffer) >= mb.size || (mb.maxBytes > 0 && mb.bytes+size > mb.maxBytes)
+}
+
+// makeRoom evicts entries until an entry of the given size fits, and reports false if the
+// entry has to be dropped instead. A service already holding its fair share cannot push out
+// other services' entries, at most (with the level policy) its own less severe ones.
+// Must be called with the mutex held.
+func (mb *MessageBuffer) makeRoom(entry models.LogEntry, size int64) bool {
+	for mb.full(size) {
+		overQuota := mb.serviceQuota > 0 && mb.serviceCounts[entry.ServiceName] >= mb.serviceQuota
+		if overQuota && mb.evictionPolicy != EvictionLevel {
+			return false
+		}
+
+		candidates := ""
+		if overQuota {
+			candidates = entry.ServiceName
+		} else if mb.serviceQuota > 0 {
+			candidates = mb.largestService()
+		}
+		victim := mb.selectVictim(candidates)
+
+		// Never push out a more severe entry to make room for a less severe one
+		if mb.evictionPolicy == EvictionLevel && entry.Level.Severity() < mb.buffer[victim].Level.Severity() {
+			return false
+		}
+
+		mb.evict(victim)
+	}
+	return true
+}
+
 // Flush manually flushes the buffer
 func (mb *MessageBuffer) Flush() error {
 	return mb.flush(context.Background())
@@ -141,25 +311,126 @@ func (mb *MessageBuffer) GetStats() BufferStats {
 	mb.mutex.RLock()
 	defer mb.mutex.RUnlock()
 
-	return BufferStats{
-		Size:     len(mb.buffer),
-		Capacity: mb.size,
-		MaxBatch: mb.maxBatchSize,
+	serviceCounts := make(map[string]int, len(mb.serviceCounts))
+	for service, count := range mb.serviceCounts {
+		serviceCounts[service] = count
+	}
+
+	stats := BufferStats{
+		Size:           len(mb.buffer),
+		Capacity:       mb.size,
+		Bytes:          mb.bytes,
+		MaxBytes:       mb.maxBytes,
+		MaxBatch:       mb.maxBatchSize,
+		ServiceQuota:   mb.serviceQuota,
+		ServiceCounts:  serviceCounts,
+		EvictionPolicy: mb.evictionPolicy,
+		FlushWorkers:   mb.workers,
 	}
+
+	if mb.tuner != nil {
+		adaptiveStats := mb.tuner.GetStats()
+		stats.Adaptive = &adaptiveStats
+	}
+	if mb.failover != nil {
+		failoverStats := mb.failover.getStats()
+		stats.Failover = &failoverStats
+	}
+
+	return stats
 }
 
 // BufferStats contains buffer statistics
 type BufferStats struct {
-	Size     int `json:"size"`
-	Capacity int `json:"capacity"`
-	MaxBatch int `json:"max_batch"`
+	Size           int            `json:"size"`
+	Capacity       int            `json:"capacity"`
+	Bytes          int64          `json:"bytes"`               // Estimated memory of the buffered entries
+	MaxBytes       int64          `json:"max_bytes,omitempty"` // Memory budget, 0 when only the number of entries is bounded
+	MaxBatch       int            `json:"max_batch"`
+	ServiceQuota   int            `json:"service_quota,omitempty"`
+	ServiceCounts  map[string]int `json:"service_counts,omitempty"`
+	EvictionPolicy EvictionPolicy `json:"eviction_policy"`
+	FlushWorkers   int            `json:"flush_workers"`
+	Adaptive       *AdaptiveStats `json:"adaptive,omitempty"`
+	Failover       *FailoverStats `json:"failover,omitempty"`
+}
+
+// batchSize returns the current batch size for storage writes
+func (mb *MessageBuffer) batchSize() int {
+	if mb.tuner != nil {
+		return mb.tuner.BatchSize()
+	}
+	return mb.maxBatchSize
+}
+
+// flushInterval returns the current interval between periodic flushes
+func (mb *MessageBuffer) flushInterval() time.Duration {
+	if mb.tuner != nil {
+		return mb.tuner.FlushInterval()
+	}
+	return mb.flushTimeout
+}
+
+// largestService returns the service holding the most buffered entries. Must be called with the mutex held.
+func (mb *MessageBuffer) largestService() string {
+	largest := ""
+	for service, count := range mb.serviceCounts {
+		if count > mb.serviceCounts[largest] {
+			largest = service
+		}
+	}
+	return largest
+}
+
+// selectVictim returns the index of the entry to drop when the buffer is full.
+// Only entries of the given service are candidates unless it is empty. The level
+// policy picks the least severe candidate, otherwise the oldest candidate is
+// picked. Must be called with the mutex held on a non-empty buffer.
+func (mb *MessageBuffer) selectVictim(service string) int {
+	victim := -1
+	for i, entry := range mb.buffer {
+		if service != "" && entry.ServiceName != service {
+			continue
+		}
+		if mb.evictionPolicy != EvictionLevel {
+			return i
+		}
+		if victim < 0 || entry.Level.Severity() < mb.buffer[victim].Level.Severity() {
+			victim = i
+		}
+	}
+
+	if victim < 0 {
+		return 0
+	}
+	return victim
+}
+
+// evict removes the entry at index and reports it as dropped. Must be called with the mutex held.
+func (mb *MessageBuffer) evict(index int) {
+	evicted := mb.buffer[index].ServiceName
+	mb.bytes -= estimateSize(&mb.buffer[index])
+	mb.buffer = append(mb.buffer[:index], mb.buffer[index+1:]...)
+	mb.serviceCounts[evicted]--
+	if mb.serviceCounts[evicted] <= 0 {
+		delete(mb.serviceCounts, evicted)
+	}
+	mb.recordServiceDrop(evicted)
+}
+
+// recordServiceDrop reports a dropped entry for a service
+func (mb *MessageBuffer) recordServiceDrop(serviceName string) {
+	if mb.metrics != nil {
+		mb.metrics.IncrementServiceDrops(serviceName)
+	}
 }
 
 // flushRoutine runs the background flush routine
 func (mb *MessageBuffer) flushRoutine(ctx context.Context) {
 	defer mb.wg.Done()
 
-	ticker := time.NewTicker(mb.flushTimeout)
+	interval := mb.flushInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -191,6 +462,12 @@ func (mb *MessageBuffer) flushRoutine(ctx context.Context) {
 				}
 			}
 		}
+
+		// Pick up flush interval changes from adaptive tuning
+		if next := mb.flushInterval(); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
 	}
 }
 
@@ -204,34 +481,123 @@ func (mb *MessageBuffer) flush(ctx context.Context) error {
 	}
 
 	// Create batches to avoid overwhelming storage
-	var batches [][]models.LogEntry
-	for i := 0; i < len(mb.buffer); i += mb.maxBatchSize {
-		end := i + mb.maxBatchSize
-		if end > len(mb.buffer) {
-			end = len(mb.buffer)
+	batchSize := mb.batchSize()
+	batches := make([][][]models.LogEntry, mb.workers) // Batches per worker
+	if mb.workers > 1 && mb.ordered {
+		for worker, entries := range mb.partitionByService() {
+			batches[worker] = splitBatches(entries, batchSize)
+		}
+	} else {
+		for i, batch := range splitBatches(mb.buffer, batchSize) {
+			batches[i%mb.workers] = append(batches[i%mb.workers], batch)
 		}
-
-		batch := make([]models.LogEntry, end-i)
-		copy(batch, mb.buffer[i:end])
-		batches = append(batches, batch)
 	}
 
 	// Clear buffer after copying
+	clear(mb.buffer) // Let go of the entries' strings and metadata while the backing array is reused
 	mb.buffer = mb.buffer[:0]
+	mb.serviceCounts = make(map[string]int)
+	mb.bytes = 0
 	mb.mutex.Unlock()
 
-	// Store batches
+	if mb.workers == 1 {
+		return mb.storeBatches(ctx, batches[0])
+	}
+
+	// Each worker stores its batches in order and stops at its first failure
+	errs := make([]error, len(batches))
+	var wg sync.WaitGroup
+	for worker, workerBatches := range batches {
+		if len(workerBatches) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(worker int, workerBatches [][]models.LogEntry) {
+			defer wg.Done()
+			errs[worker] = mb.storeBatches(ctx, workerBatches)
+		}(worker, workerBatches)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partitionByService splits the buffered entries into one slice per worker, keeping the
+// entries of a service together and in order. Must be called with the mutex held.
+func (mb *MessageBuffer) partitionByService() [][]models.LogEntry {
+	partitions := make([][]models.LogEntry, mb.workers)
+	for _, entry := range mb.buffer {
+		hash := fnv.New32a()
+		hash.Write([]byte(entry.ServiceName))
+		worker := int(hash.Sum32() % uint32(mb.workers))
+		partitions[worker] = append(partitions[worker], entry)
+	}
+	return partitions
+}
+
+// splitBatches copies entries into pooled batches of at most batchSize entries, which
+// storeBatches returns to the pool once stored
+func splitBatches(entries []models.LogEntry, batchSize int) [][]models.LogEntry {
+	var batches [][]models.LogEntry
+	for i := 0; i < len(entries); i += batchSize {
+		end := i + batchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		batch := append(pool.GetEntries(end-i), entries[i:end]...)
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// storeBatches stores batches in order, returning the first failed batch to the buffer.
+// Storage copies what it keeps, so each batch goes back to the pool once handled.
+func (mb *MessageBuffer) storeBatches(ctx context.Context, batches [][]models.LogEntry) error {
 	for _, batch := range batches {
-		if err := mb.storage.Store(ctx, batch); err != nil {
+		start := time.Now()
+		err := mb.store(ctx, batch)
+		duration := time.Since(start)
+		if mb.tuner != nil {
+			mb.tuner.Observe(duration, err)
+		}
+		if mb.metrics != nil {
+			mb.metrics.ObserveBufferFlush(duration, len(batch))
+		}
+
+		if err != nil {
 			// On error, try to add entries back to buffer
 			mb.mutex.Lock()
 			// Only add back if there's space to avoid infinite loops
-			if len(mb.buffer)+len(batch) <= mb.size {
+			batchBytes := estimateBatchSize(batch)
+			if len(mb.buffer)+len(batch) <= mb.size && (mb.maxBytes == 0 || mb.bytes+batchBytes <= mb.maxBytes) {
 				mb.buffer = append(mb.buffer, batch...)
+				for _, entry := range batch {
+					mb.serviceCounts[entry.ServiceName]++
+				}
+				mb.bytes += batchBytes
+				if mb.metrics != nil {
+					mb.metrics.IncrementBufferRetries()
+				}
+			} else {
+				for _, entry := range batch {
+					mb.recordServiceDrop(entry.ServiceName)
+				}
 			}
 			mb.mutex.Unlock()
+			pool.PutEntries(batch)
 			return err
 		}
+
+		if mb.committed != nil {
+			mb.committed.Committed(batch)
+		}
+		pool.PutEntries(batch)
 	}
 
 	return nil
@@ -3,11 +3,14 @@ package buffer
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
 )
 
 // MockStorage implements storage.LogStorage for testing
@@ -41,12 +44,16 @@ func (m *MockStorage) Query(ctx context.Context, filter models.LogFilter) (*mode
 	return nil, nil
 }
 
+func (m *MockStorage) QueryStream(ctx context.Context, filter models.LogFilter) (storage.LogIterator, error) {
+	return storage.NewSliceIterator(nil), nil
+}
+
 func (m *MockStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
 	return nil, nil
 }
 
-func (m *MockStorage) GetServices(ctx context.Context) ([]models.ServiceInfo, error) {
-	return nil, nil
+func (m *MockStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	return &models.ServiceResult{}, nil
 }
 
 func (m *MockStorage) HealthCheck(ctx context.Context) models.HealthStatus {
@@ -137,6 +144,213 @@ func TestMessageBuffer_BufferOverflow(t *testing.T) {
 	}
 }
 
+// MockMetricsReporter records buffer metrics for testing
+type MockMetricsReporter struct {
+	overflows    int
+	serviceDrops map[string]int
+	retries      int
+	flushed      []int
+}
+
+func (m *MockMetricsReporter) IncrementBufferFlushes()        {}
+func (m *MockMetricsReporter) IncrementBufferFlushErrors()    {}
+func (m *MockMetricsReporter) IncrementBufferOverflows()      { m.overflows++ }
+func (m *MockMetricsReporter) IncrementServiceDrops(s string) { m.serviceDrops[s]++ }
+func (m *MockMetricsReporter) IncrementBufferRetries()        { m.retries++ }
+func (m *MockMetricsReporter) ObserveBufferFlush(d time.Duration, entries int) {
+	m.flushed = append(m.flushed, entries)
+}
+
+func createServiceLogEntry(serviceName string) models.LogEntry {
+	entry := createTestLogEntry(uuid.New().String())
+	entry.ServiceName = serviceName
+	return entry
+}
+
+func TestMessageBuffer_FairShare(t *testing.T) {
+	mockStorage := &MockStorage{}
+	metrics := &MockMetricsReporter{serviceDrops: make(map[string]int)}
+	config := Config{
+		Size:            10,
+		MaxBatchSize:    100,
+		FlushTimeout:    time.Second,
+		MaxServiceShare: 0.5,
+	}
+
+	buffer := NewMessageBufferWithOptions(mockStorage, config, Options{MetricsReporter: metrics})
+
+	// A single service may use the whole buffer while there is room
+	var noisy []models.LogEntry
+	for i := 0; i < 10; i++ {
+		noisy = append(noisy, createServiceLogEntry("noisy-service"))
+	}
+	if err := buffer.Add(noisy); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+
+	// Once full, other services push out the noisy service's entries
+	if err := buffer.Add([]models.LogEntry{createServiceLogEntry("quiet-service"), createServiceLogEntry("quiet-service")}); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+
+	stats := buffer.GetStats()
+	if stats.ServiceCounts["quiet-service"] != 2 || stats.ServiceCounts["noisy-service"] != 8 {
+		t.Errorf("Unexpected service counts: %v", stats.ServiceCounts)
+	}
+
+	// The noisy service is over its share, so its new entries are dropped
+	if err := buffer.Add([]models.LogEntry{createServiceLogEntry("noisy-service")}); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+
+	stats = buffer.GetStats()
+	if stats.Size != 10 || stats.ServiceCounts["noisy-service"] != 8 {
+		t.Errorf("Expected noisy service entry to be dropped, got %v", stats.ServiceCounts)
+	}
+	if metrics.serviceDrops["noisy-service"] != 3 || metrics.serviceDrops["quiet-service"] != 0 {
+		t.Errorf("Unexpected service drops: %v", metrics.serviceDrops)
+	}
+	if metrics.overflows != 3 {
+		t.Errorf("Expected 3 overflows, got %d", metrics.overflows)
+	}
+
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if stats := buffer.GetStats(); len(stats.ServiceCounts) != 0 {
+		t.Errorf("Expected service counts to be reset after flush, got %v", stats.ServiceCounts)
+	}
+}
+
+func TestMessageBuffer_MaxBytes(t *testing.T) {
+	mockStorage := &MockStorage{}
+	metrics := &MockMetricsReporter{serviceDrops: make(map[string]int)}
+
+	large := func() models.LogEntry {
+		entry := createTestLogEntry(uuid.New().String())
+		entry.Message = strings.Repeat("x", 50*1024)
+		return entry
+	}
+	sample := large()
+	size := estimateSize(&sample)
+	config := Config{
+		Size:         100,
+		MaxBytes:     3*size + size/2,
+		MaxBatchSize: 100,
+		FlushTimeout: time.Second,
+	}
+	buffer := NewMessageBufferWithOptions(mockStorage, config, Options{MetricsReporter: metrics})
+
+	// Only 3 of the large entries fit the budget although the buffer takes 100 entries
+	for i := 0; i < 5; i++ {
+		if err := buffer.Add([]models.LogEntry{large()}); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	stats := buffer.GetStats()
+	if stats.Size != 3 || stats.Bytes != 3*size || stats.MaxBytes != config.MaxBytes {
+		t.Errorf("Expected 3 entries of %d bytes within the budget, got %d entries of %d bytes", size, stats.Size, stats.Bytes)
+	}
+	if metrics.overflows != 2 {
+		t.Errorf("Expected 2 overflows, got %d", metrics.overflows)
+	}
+
+	// Reaching half the budget schedules a flush
+	if len(buffer.flushCh) != 1 {
+		t.Error("Expected a flush to be scheduled")
+	}
+
+	// An entry larger than the whole budget is dropped without evicting anything
+	huge := createTestLogEntry(uuid.New().String())
+	huge.Message = strings.Repeat("x", 200*1024)
+	if err := buffer.Add([]models.LogEntry{huge}); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if stats := buffer.GetStats(); stats.Size != 3 || metrics.serviceDrops["test-service"] != 3 {
+		t.Errorf("Expected the huge entry to be dropped, got %d entries and %v drops", stats.Size, metrics.serviceDrops)
+	}
+
+	// A failed batch is only returned to the buffer within the budget
+	mockStorage.storeError = errors.New("storage error")
+	if err := buffer.Flush(); err == nil {
+		t.Fatal("Expected flush to return error")
+	}
+	if stats := buffer.GetStats(); stats.Size != 3 || stats.Bytes != 3*size {
+		t.Errorf("Expected the failed batch back in the buffer, got %d entries of %d bytes", stats.Size, stats.Bytes)
+	}
+
+	mockStorage.storeError = nil
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if stats := buffer.GetStats(); stats.Size != 0 || stats.Bytes != 0 {
+		t.Errorf("Expected an empty buffer after flush, got %d entries of %d bytes", stats.Size, stats.Bytes)
+	}
+	if stored := mockStorage.GetStoredLogs(); len(stored) != 3 || len(stored[0].Message) != 50*1024 {
+		t.Errorf("Expected the 3 large entries to be stored intact, got %d", len(stored))
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	entry := createTestLogEntry(uuid.New().String())
+	base := estimateSize(&entry)
+
+	entry.Metadata = map[string]interface{}{
+		"request": map[string]interface{}{"body": strings.Repeat("x", 1000)},
+		"items":   []interface{}{"a", 1.0, true},
+	}
+	entry.StackTrace = strings.Repeat("x", 500)
+	entry.Tags = []string{"checkout"}
+	entry.Crash = &models.CrashInfo{Threads: []models.ThreadDump{{Frames: []models.StackFrame{{Function: "main"}}}}}
+	if size := estimateSize(&entry); size < base+1500 {
+		t.Errorf("Expected the metadata, stack trace and crash to add at least 1500 bytes to %d, got %d", base, size)
+	}
+}
+
+func TestMessageBuffer_LevelEviction(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Size:           3,
+		MaxBatchSize:   100,
+		FlushTimeout:   time.Second,
+		EvictionPolicy: EvictionLevel,
+	}
+
+	buffer := NewMessageBuffer(mockStorage, config)
+
+	levels := []models.LogLevel{
+		models.LogLevelError,
+		models.LogLevelDebug,
+		models.LogLevelInfo,
+		models.LogLevelWarn,  // evicts the DEBUG entry
+		models.LogLevelDebug, // dropped, everything buffered is more severe
+		models.LogLevelFatal, // evicts the INFO entry
+	}
+
+	for _, level := range levels {
+		entry := createTestLogEntry(uuid.New().String())
+		entry.Level = level
+		if err := buffer.Add([]models.LogEntry{entry}); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	stored := mockStorage.GetStoredLogs()
+	expected := []models.LogLevel{models.LogLevelError, models.LogLevelWarn, models.LogLevelFatal}
+	if len(stored) != len(expected) {
+		t.Fatalf("Expected %d stored logs, got %d", len(expected), len(stored))
+	}
+	for i, level := range expected {
+		if stored[i].Level != level {
+			t.Errorf("Expected level %s at position %d, got %s", level, i, stored[i].Level)
+		}
+	}
+}
+
 func TestMessageBuffer_AutoFlush(t *testing.T) {
 	mockStorage := &MockStorage{}
 	config := Config{
@@ -325,6 +539,59 @@ func TestMessageBuffer_ErrorHandling(t *testing.T) {
 	}
 }
 
+func TestMessageBuffer_FlushMetrics(t *testing.T) {
+	mockStorage := &MockStorage{}
+	metrics := &MockMetricsReporter{serviceDrops: make(map[string]int)}
+	config := Config{
+		Size:         4,
+		MaxBatchSize: 3,
+		FlushTimeout: time.Second,
+	}
+
+	buffer := NewMessageBufferWithOptions(mockStorage, config, Options{MetricsReporter: metrics})
+
+	for i := 0; i < 4; i++ {
+		if err := buffer.Add([]models.LogEntry{createTestLogEntry(uuid.New().String())}); err != nil {
+			t.Fatalf("Failed to add entry: %v", err)
+		}
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if len(metrics.flushed) != 2 || metrics.flushed[0] != 3 || metrics.flushed[1] != 1 {
+		t.Errorf("Expected writes of 3 and 1 entries, got %v", metrics.flushed)
+	}
+
+	// A failed write is kept for a retry while there is space
+	mockStorage.mutex.Lock()
+	mockStorage.storeError = errors.New("storage error")
+	mockStorage.mutex.Unlock()
+	buffer.Add([]models.LogEntry{createTestLogEntry(uuid.New().String())})
+	if err := buffer.Flush(); err == nil {
+		t.Fatal("Expected flush to return error")
+	}
+	if metrics.retries != 1 || len(metrics.serviceDrops) != 0 {
+		t.Errorf("Expected 1 retry and no drops, got %d retries and drops %v", metrics.retries, metrics.serviceDrops)
+	}
+
+	// A failed write is dropped if entries that arrived meanwhile left no space for it
+	mockStorage.mutex.Lock()
+	mockStorage.storeDelay = 100 * time.Millisecond
+	mockStorage.mutex.Unlock()
+	flushed := make(chan error)
+	go func() { flushed <- buffer.Flush() }()
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		buffer.Add([]models.LogEntry{createServiceLogEntry("late-service")})
+	}
+	if err := <-flushed; err == nil {
+		t.Fatal("Expected flush to return error")
+	}
+	if metrics.retries != 1 || metrics.serviceDrops["test-service"] != 1 {
+		t.Errorf("Expected the failed entry to be dropped, got %d retries and drops %v", metrics.retries, metrics.serviceDrops)
+	}
+}
+
 func TestMessageBuffer_ConcurrentAccess(t *testing.T) {
 	mockStorage := &MockStorage{}
 	config := Config{
@@ -374,3 +641,107 @@ func TestMessageBuffer_ConcurrentAccess(t *testing.T) {
 		t.Errorf("Expected %d stored logs, got %d", expectedTotal, len(storedLogs))
 	}
 }
+
+// singleWriterStorage is a MockStorage that allows one Store call at a time
+type singleWriterStorage struct {
+	MockStorage
+}
+
+func (s *singleWriterStorage) MaxConcurrentWrites() int {
+	return 1
+}
+
+func TestMessageBuffer_FlushWorkers(t *testing.T) {
+	mockStorage := &MockStorage{}
+	config := Config{
+		Size:              100,
+		MaxBatchSize:      2,
+		FlushTimeout:      time.Second,
+		FlushWorkers:      4,
+		OrderedPerService: true,
+	}
+
+	buffer := NewMessageBuffer(mockStorage, config)
+	if workers := buffer.GetStats().FlushWorkers; workers != 4 {
+		t.Fatalf("Expected 4 flush workers, got %d", workers)
+	}
+
+	var entries []models.LogEntry
+	for i := 0; i < 30; i++ {
+		entry := createServiceLogEntry([]string{"service-a", "service-b", "service-c"}[i%3])
+		entry.Message = string(rune('A' + i))
+		entries = append(entries, entry)
+	}
+	if err := buffer.Add(entries); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	storedLogs := mockStorage.GetStoredLogs()
+	if len(storedLogs) != len(entries) {
+		t.Fatalf("Expected %d stored logs, got %d", len(entries), len(storedLogs))
+	}
+
+	// Entries of each service are stored in the order they were added
+	last := make(map[string]string)
+	for _, entry := range storedLogs {
+		if entry.Message < last[entry.ServiceName] {
+			t.Errorf("Entry %s of %s stored after %s", entry.Message, entry.ServiceName, last[entry.ServiceName])
+		}
+		last[entry.ServiceName] = entry.Message
+	}
+
+	// The storage's write limit caps the workers
+	capped := NewMessageBuffer(&singleWriterStorage{}, config)
+	if workers := capped.GetStats().FlushWorkers; workers != 1 {
+		t.Errorf("Expected flush workers capped to 1, got %d", workers)
+	}
+}
+
+// commitRecorder records the batches a buffer reports as stored
+type commitRecorder struct {
+	mutex   sync.Mutex
+	batches [][]models.LogEntry
+}
+
+func (r *commitRecorder) Committed(batch []models.LogEntry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.batches = append(r.batches, append([]models.LogEntry(nil), batch...)) // The batch is reused after Committed
+}
+
+func TestMessageBuffer_CommitListener(t *testing.T) {
+	mockStorage := &MockStorage{}
+	recorder := &commitRecorder{}
+	buffer := NewMessageBufferWithOptions(mockStorage, Config{Size: 10, MaxBatchSize: 2, FlushTimeout: time.Second}, Options{CommitListener: recorder})
+
+	entries := make([]models.LogEntry, 3)
+	for i := range entries {
+		entries[i] = createTestLogEntry(uuid.New().String())
+	}
+	if err := buffer.Add(entries); err != nil {
+		t.Fatalf("Failed to add entries: %v", err)
+	}
+	if err := buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if len(recorder.batches) != 2 || len(recorder.batches[0]) != 2 || len(recorder.batches[1]) != 1 {
+		t.Fatalf("Expected the 3 entries to be committed in 2 batches, got %v", recorder.batches)
+	}
+
+	// Batches that were not stored are not reported
+	mockStorage.mutex.Lock()
+	mockStorage.storeError = errors.New("storage error")
+	mockStorage.mutex.Unlock()
+	if err := buffer.Add([]models.LogEntry{createTestLogEntry(uuid.New().String())}); err != nil {
+		t.Fatalf("Failed to add entry: %v", err)
+	}
+	if err := buffer.Flush(); err == nil {
+		t.Fatal("Expected flush to return error")
+	}
+	if len(recorder.batches) != 2 {
+		t.Errorf("Expected a failed batch not to be committed, got %d batches", len(recorder.batches))
+	}
+}
@@ -0,0 +1,105 @@
+package buffer
+
+import (
+	"unsafe"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// Sizes of the values an entry is made of, as held in memory
+const (
+	logEntrySize     = int64(unsafe.Sizeof(models.LogEntry{}))
+	deviceInfoSize   = int64(unsafe.Sizeof(models.DeviceInfo{}))
+	locationSize     = int64(unsafe.Sizeof(models.SourceLocation{}))
+	crashSize        = int64(unsafe.Sizeof(models.CrashInfo{}))
+	threadSize       = int64(unsafe.Sizeof(models.ThreadDump{}))
+	frameSize        = int64(unsafe.Sizeof(models.StackFrame{}))
+	breadcrumbSize   = int64(unsafe.Sizeof(models.Breadcrumb{}))
+	annotationSize   = int64(unsafe.Sizeof(models.LogAnnotation{}))
+	stringSize       = int64(unsafe.Sizeof(""))
+	interfaceSize    = int64(unsafe.Sizeof(interface{}(nil)))
+	mapEntryOverhead = 2 * interfaceSize // Approximate cost of a map slot besides its key and value
+)
+
+// estimateSize approximates the memory a buffered entry holds: the entry itself, its strings
+// and everything its metadata, device info, source location, tags and crash report point to.
+// It is an estimate rather than an exact count, but grows with the entry like its memory does.
+func estimateSize(entry *models.LogEntry) int64 {
+	size := logEntrySize +
+		int64(len(entry.ID)+len(entry.Level)+len(entry.Message)+len(entry.ServiceName)+
+			len(entry.AgentID)+len(entry.Platform)+len(entry.RequestID)+len(entry.TemplateID)+len(entry.StackTrace)) +
+		metadataSize(entry.Metadata)
+
+	if entry.DeviceInfo != nil {
+		size += deviceInfoSize + int64(len(entry.DeviceInfo.Platform)+len(entry.DeviceInfo.Version)+
+			len(entry.DeviceInfo.Model)+len(entry.DeviceInfo.AppVersion))
+	}
+	if entry.SourceLocation != nil {
+		size += locationSize + int64(len(entry.SourceLocation.File)+len(entry.SourceLocation.Function))
+	}
+	for _, tag := range entry.Tags {
+		size += stringSize + int64(len(tag))
+	}
+	if entry.Crash != nil {
+		size += crashReportSize(entry.Crash)
+	}
+	for _, annotation := range entry.Annotations {
+		size += annotationSize + int64(len(annotation.Author)+len(annotation.Text))
+	}
+	return size
+}
+
+// crashReportSize approximates the memory a crash report holds
+func crashReportSize(crash *models.CrashInfo) int64 {
+	size := crashSize + int64(len(crash.Signal)+len(crash.ExceptionType)+len(crash.Reason)+len(crash.Signature))
+	for _, thread := range crash.Threads {
+		size += threadSize + int64(len(thread.ID)+len(thread.Name))
+		for _, frame := range thread.Frames {
+			size += frameSize + int64(len(frame.Function)+len(frame.Module)+len(frame.File)+len(frame.Address))
+		}
+	}
+	for _, breadcrumb := range crash.Breadcrumbs {
+		size += breadcrumbSize + int64(len(breadcrumb.Category)+len(breadcrumb.Message))
+		for key, value := range breadcrumb.Data {
+			size += mapEntryOverhead + 2*stringSize + int64(len(key)+len(value))
+		}
+	}
+	return size
+}
+
+// metadataSize approximates the memory of decoded JSON metadata
+func metadataSize(metadata map[string]interface{}) int64 {
+	var size int64
+	for key, value := range metadata {
+		size += mapEntryOverhead + stringSize + int64(len(key)) + valueSize(value)
+	}
+	return size
+}
+
+// valueSize approximates the memory of a value decoded from JSON
+func valueSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case string:
+		return interfaceSize + stringSize + int64(len(v))
+	case map[string]interface{}:
+		return interfaceSize + metadataSize(v)
+	case []interface{}:
+		size := interfaceSize
+		for _, item := range v {
+			size += valueSize(item)
+		}
+		return size
+	default:
+		// Numbers, booleans and null
+		return interfaceSize + 8
+	}
+}
+
+// estimateBatchSize returns the estimated memory of a batch of entries
+func estimateBatchSize(batch []models.LogEntry) int64 {
+	var size int64
+	for i := range batch {
+		size += estimateSize(&batch[i])
+	}
+	return size
+}
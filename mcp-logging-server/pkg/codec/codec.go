@@ -0,0 +1,118 @@
+// Package codec encodes and decodes the log entries of the ingestion and flush hot path, where
+// profiles of busy servers are dominated by JSON. Entries are encoded by code written for their
+// types, like the code easyjson generates, appending to pooled buffers instead of walking the
+// entries with reflection. Requests are decoded with jsoniter, which caches a decoder per type.
+// Both produce and accept the same JSON as encoding/json. Other JSON, such as configuration and
+// API responses, keeps using encoding/json.
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// api decodes like encoding/json: json.Unmarshaler implementations are used and numbers in
+// interface values become float64
+var api = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// ErrNoBody is returned when decoding a request without a body
+var ErrNoBody = errors.New("invalid request")
+
+// maxPooledBuffer is the capacity above which a buffer is left to the garbage collector, so a
+// few huge entries do not keep their memory pinned in the pool
+const maxPooledBuffer = 1 << 20
+
+var buffers = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 0, 4096)
+		return &buf
+	},
+}
+
+// getBuffer returns an empty pooled buffer
+func getBuffer() *[]byte {
+	buf := buffers.Get().(*[]byte)
+	*buf = (*buf)[:0]
+	return buf
+}
+
+// putBuffer returns a buffer to the pool
+func putBuffer(buf *[]byte) {
+	if cap(*buf) <= maxPooledBuffer {
+		buffers.Put(buf)
+	}
+}
+
+// Decode decodes the next JSON value of r into v, as a json.Decoder does. A nil reader, such as
+// the body of a request without one, returns ErrNoBody.
+func Decode(r io.Reader, v interface{}) error {
+	if r == nil {
+		return ErrNoBody
+	}
+	return api.NewDecoder(r).Decode(v)
+}
+
+// MarshalEntries returns the JSON encoding of a batch of entries, as json.Marshal does
+func MarshalEntries(entries []models.LogEntry) ([]byte, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	data, err := AppendEntries(*buf, entries)
+	*buf = data
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// EntrySize returns the length of the JSON encoding of an entry without keeping the encoding
+func EntrySize(entry *models.LogEntry) (int64, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	data, err := AppendEntry(*buf, entry)
+	*buf = data
+	return int64(len(data)), err
+}
+
+// MarshalString returns the JSON encoding of v as a string, as json.Marshal does. The values
+// entries hold are encoded into a pooled buffer, so the string is the only allocation; other
+// values are encoded with encoding/json.
+func MarshalString(v interface{}) (string, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	var data []byte
+	var err error
+	switch v := v.(type) {
+	case map[string]interface{}:
+		data, err = appendObject(*buf, v)
+	case []string:
+		data = appendStrings(*buf, v)
+	case *models.SourceLocation:
+		data = appendSourceLocation(*buf, v)
+	case *models.DeviceInfo:
+		data = appendDeviceInfo(*buf, v)
+	default:
+		return marshalString(v)
+	}
+	*buf = data
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// marshalString encodes v with encoding/json
+func marshalString(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
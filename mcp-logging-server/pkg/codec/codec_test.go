@@ -0,0 +1,231 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// testBatch returns a batch of entries with the fields clients commonly set
+func testBatch(n int) []models.LogEntry {
+	batch := make([]models.LogEntry, n)
+	for i := range batch {
+		batch[i] = models.LogEntry{
+			ID:          fmt.Sprintf("550e8400-e29b-41d4-a716-%012d", i),
+			Timestamp:   time.Date(2026, 10, 16, 12, 0, i%60, 1234, time.UTC),
+			Level:       models.LogLevelError,
+			Message:     "Payment <declined> for order & " + strings.Repeat("x", 200),
+			ServiceName: "checkout",
+			AgentID:     "checkout-1",
+			Platform:    models.PlatformGo,
+			Metadata: map[string]interface{}{
+				"order_id": "A-1001",
+				"amount":   12.5,
+				"items":    []interface{}{"book", 2.0, true, nil},
+				"customer": map[string]interface{}{"id": "c-42", "tier": "gold"},
+			},
+			SourceLocation: &models.SourceLocation{File: "payment.go", Line: 42, Function: "charge"},
+			Tags:           []string{"payments", "eu"},
+		}
+	}
+	return batch
+}
+
+func TestMarshalEntriesMatchesEncodingJSON(t *testing.T) {
+	batch := testBatch(3)
+	batch[1].DeviceInfo = &models.DeviceInfo{Platform: "ios", Version: "17.1", Model: "iPhone15,2"}
+	batch[1].ClockSkewed = true
+	batch[1].ReceivedAt = time.Date(2026, 10, 16, 12, 0, 1, 0, time.FixedZone("CEST", 2*60*60))
+	batch[1].StackTrace = "panic: boom\n\tmain.go:12"
+	batch[1].RequestID = "req-7f3a"
+	batch[1].TemplateID = "9d4c0e5fa1b2c3d4"
+	batch[2].Metadata = nil
+	batch[2].Tags = nil
+	batch[2].SourceLocation = nil
+	batch[2].Crash = &models.CrashInfo{Signal: "SIGSEGV", Threads: []models.ThreadDump{{Crashed: true, Frames: []models.StackFrame{{Address: "0x1024a3f0c"}}}}}
+	batch[2].Annotations = []models.LogAnnotation{{Text: "known issue", CreatedAt: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}}
+
+	for _, entries := range [][]models.LogEntry{batch, {}, nil} {
+		want, err := json.Marshal(entries)
+		if err != nil {
+			t.Fatalf("Failed to marshal with encoding/json: %v", err)
+		}
+		got, err := MarshalEntries(entries)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %v", err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Expected the encoding/json encoding\n%s\ngot\n%s", want, got)
+		}
+	}
+
+	size, err := EntrySize(&batch[1])
+	if want, _ := json.Marshal(&batch[1]); err != nil || size != int64(len(want)) {
+		t.Errorf("Expected size %d, got %d (%v)", len(want), size, err)
+	}
+}
+
+func TestMarshalStringMatchesEncodingJSON(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{
+			"html":     "<b>&</b>",
+			"escapes":  "quote \" backslash \\ tab \t bell \a form \f nul \x00",
+			"unicode":  "ünïcödé 日本 \u2028 \u2029 invalid \xff",
+			"numbers":  []interface{}{0.0, -1.5, 1e21, 1e-7, 123456789.0, 3.0},
+			"nested":   map[string]interface{}{"b": nil, "a": true},
+			"empty":    map[string]interface{}{},
+			"nil_list": []interface{}(nil),
+			"other":    42,
+		},
+		map[string]interface{}(nil),
+		[]string{"a", "<b>"},
+		[]string(nil),
+		&models.SourceLocation{File: "main.go", Line: 12, Function: "main"},
+		&models.DeviceInfo{Platform: "android", Model: "Pixel"},
+		&models.CrashInfo{Signal: "SIGABRT"},
+	}
+	for _, value := range values {
+		want, _ := json.Marshal(value)
+		got, err := MarshalString(value)
+		if err != nil {
+			t.Fatalf("Failed to marshal %v: %v", value, err)
+		}
+		if got != string(want) {
+			t.Errorf("Expected %s, got %s", want, got)
+		}
+	}
+
+	if _, err := MarshalString(map[string]interface{}{"nan": math.NaN()}); err == nil {
+		t.Error("Expected an error for a value JSON cannot encode")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	batch := testBatch(2)
+	data, _ := json.Marshal(batch)
+
+	var decoded []models.LogEntry
+	if err := Decode(bytes.NewReader(data), &decoded); err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	var want []models.LogEntry
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("Failed to decode with encoding/json: %v", err)
+	}
+	again, _ := json.Marshal(decoded)
+	if !bytes.Equal(again, data) || !decoded[1].Timestamp.Equal(want[1].Timestamp) {
+		t.Errorf("Expected the entries to survive a round trip, got %s", again)
+	}
+
+	// Numeric levels are accepted by LogLevel's UnmarshalJSON
+	var entry models.LogEntry
+	if err := Decode(strings.NewReader(`{"level":40}`), &entry); err != nil || entry.Level != "40" {
+		t.Errorf("Expected the numeric level to be decoded, got %q (%v)", entry.Level, err)
+	}
+
+	if err := Decode(strings.NewReader(`{"message":`), &entry); err == nil {
+		t.Error("Expected an error for truncated JSON")
+	}
+	if err := Decode(nil, &entry); !errors.Is(err, ErrNoBody) {
+		t.Errorf("Expected ErrNoBody without a body, got %v", err)
+	}
+}
+
+func TestEncodingAllocations(t *testing.T) {
+	batch := testBatch(10)
+	metadata := batch[0].Metadata
+
+	tests := []struct {
+		name    string
+		std     func()
+		encoded func()
+	}{
+		{"batch", func() { _, _ = json.Marshal(batch) }, func() { _, _ = MarshalEntries(batch) }},
+		{"size", func() { _, _ = json.Marshal(&batch[0]) }, func() { _, _ = EntrySize(&batch[0]) }},
+		{"metadata", func() {
+			data, _ := json.Marshal(metadata)
+			_ = string(data)
+		}, func() { _, _ = MarshalString(metadata) }},
+	}
+	for _, tt := range tests {
+		std := testing.AllocsPerRun(100, tt.std)
+		encoded := testing.AllocsPerRun(100, tt.encoded)
+		if encoded >= std {
+			t.Errorf("Expected encoding the %s to allocate less than encoding/json (%.0f), got %.0f", tt.name, std, encoded)
+		}
+	}
+}
+
+func BenchmarkMarshalBatch(b *testing.B) {
+	batch := testBatch(100)
+
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("codec", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := MarshalEntries(batch); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkDecodeBatch(b *testing.B) {
+	data, _ := json.Marshal(testBatch(100))
+
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var entries []models.LogEntry
+			if err := json.NewDecoder(bytes.NewReader(data)).Decode(&entries); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("codec", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			var entries []models.LogEntry
+			if err := Decode(bytes.NewReader(data), &entries); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSize(b *testing.B) {
+	entry := testBatch(1)[0]
+
+	b.Run("encoding/json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := json.Marshal(&entry); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("codec", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := EntrySize(&entry); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
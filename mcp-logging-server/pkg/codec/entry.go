@@ -0,0 +1,317 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// AppendEntries appends the JSON encoding of a batch of entries to dst
+func AppendEntries(dst []byte, entries []models.LogEntry) ([]byte, error) {
+	if entries == nil {
+		return append(dst, "null"...), nil
+	}
+	dst = append(dst, '[')
+	for i := range entries {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		var err error
+		if dst, err = AppendEntry(dst, &entries[i]); err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, ']'), nil
+}
+
+// AppendEntry appends the JSON encoding of an entry to dst, with the fields in the order and
+// with the omitempty rules of the LogEntry struct tags. Crash reports and annotations are rare
+// and encoded with encoding/json.
+func AppendEntry(dst []byte, entry *models.LogEntry) ([]byte, error) {
+	var err error
+	dst = append(dst, `{"id":`...)
+	dst = appendString(dst, entry.ID)
+	dst = append(dst, `,"timestamp":`...)
+	if dst, err = appendTime(dst, entry.Timestamp); err != nil {
+		return dst, err
+	}
+	dst = append(dst, `,"level":`...)
+	dst = appendString(dst, string(entry.Level))
+	dst = append(dst, `,"message":`...)
+	dst = appendString(dst, entry.Message)
+	dst = append(dst, `,"service_name":`...)
+	dst = appendString(dst, entry.ServiceName)
+	dst = append(dst, `,"agent_id":`...)
+	dst = appendString(dst, entry.AgentID)
+	dst = append(dst, `,"platform":`...)
+	dst = appendString(dst, string(entry.Platform))
+	if entry.RequestID != "" {
+		dst = append(dst, `,"request_id":`...)
+		dst = appendString(dst, entry.RequestID)
+	}
+	if entry.TemplateID != "" {
+		dst = append(dst, `,"template_id":`...)
+		dst = appendString(dst, entry.TemplateID)
+	}
+	if len(entry.Metadata) > 0 {
+		dst = append(dst, `,"metadata":`...)
+		if dst, err = appendObject(dst, entry.Metadata); err != nil {
+			return dst, err
+		}
+	}
+	if entry.DeviceInfo != nil {
+		dst = append(dst, `,"device_info":`...)
+		dst = appendDeviceInfo(dst, entry.DeviceInfo)
+	}
+	if entry.StackTrace != "" {
+		dst = append(dst, `,"stack_trace":`...)
+		dst = appendString(dst, entry.StackTrace)
+	}
+	if entry.SourceLocation != nil {
+		dst = append(dst, `,"source_location":`...)
+		dst = appendSourceLocation(dst, entry.SourceLocation)
+	}
+	if len(entry.Tags) > 0 {
+		dst = append(dst, `,"tags":`...)
+		dst = appendStrings(dst, entry.Tags)
+	}
+	// omitempty does not omit structs, so a zero received_at is encoded
+	dst = append(dst, `,"received_at":`...)
+	if dst, err = appendTime(dst, entry.ReceivedAt); err != nil {
+		return dst, err
+	}
+	if entry.ClockSkewed {
+		dst = append(dst, `,"clock_skewed":true`...)
+	}
+	if entry.Crash != nil {
+		dst = append(dst, `,"crash":`...)
+		if dst, err = appendMarshaled(dst, entry.Crash); err != nil {
+			return dst, err
+		}
+	}
+	if len(entry.Annotations) > 0 {
+		dst = append(dst, `,"annotations":`...)
+		if dst, err = appendMarshaled(dst, entry.Annotations); err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// appendDeviceInfo appends the JSON encoding of device info to dst
+func appendDeviceInfo(dst []byte, info *models.DeviceInfo) []byte {
+	if info == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, `{"platform":`...)
+	dst = appendString(dst, info.Platform)
+	dst = append(dst, `,"version":`...)
+	dst = appendString(dst, info.Version)
+	dst = append(dst, `,"model":`...)
+	dst = appendString(dst, info.Model)
+	dst = append(dst, `,"app_version":`...)
+	dst = appendString(dst, info.AppVersion)
+	return append(dst, '}')
+}
+
+// appendSourceLocation appends the JSON encoding of a source location to dst
+func appendSourceLocation(dst []byte, location *models.SourceLocation) []byte {
+	if location == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, `{"file":`...)
+	dst = appendString(dst, location.File)
+	dst = append(dst, `,"line":`...)
+	dst = strconv.AppendInt(dst, int64(location.Line), 10)
+	dst = append(dst, `,"function":`...)
+	dst = appendString(dst, location.Function)
+	return append(dst, '}')
+}
+
+// appendStrings appends the JSON encoding of a string slice to dst
+func appendStrings(dst []byte, values []string) []byte {
+	if values == nil {
+		return append(dst, "null"...)
+	}
+	dst = append(dst, '[')
+	for i, value := range values {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendString(dst, value)
+	}
+	return append(dst, ']')
+}
+
+// appendTime appends a time as time.Time's MarshalJSON does
+func appendTime(dst []byte, t time.Time) ([]byte, error) {
+	if year := t.Year(); year < 0 || year > 9999 {
+		return dst, fmt.Errorf("json: error calling MarshalJSON for type time.Time: year %d outside of range [0,9999]", year)
+	}
+	dst = append(dst, '"')
+	dst = t.AppendFormat(dst, time.RFC3339Nano)
+	return append(dst, '"'), nil
+}
+
+// appendObject appends decoded JSON metadata to dst with its keys sorted, as encoding/json does
+func appendObject(dst []byte, object map[string]interface{}) ([]byte, error) {
+	if object == nil {
+		return append(dst, "null"...), nil
+	}
+
+	var stack [16]string
+	keys := stack[:0]
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dst = append(dst, '{')
+	for i, key := range keys {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendString(dst, key)
+		dst = append(dst, ':')
+		var err error
+		if dst, err = appendValue(dst, object[key]); err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, '}'), nil
+}
+
+// appendValue appends a value of decoded JSON metadata to dst. Values of other types, which
+// only metadata set in code can hold, are encoded with encoding/json.
+func appendValue(dst []byte, value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return append(dst, "null"...), nil
+	case string:
+		return appendString(dst, v), nil
+	case bool:
+		return strconv.AppendBool(dst, v), nil
+	case float64:
+		return appendFloat(dst, v)
+	case map[string]interface{}:
+		return appendObject(dst, v)
+	case []interface{}:
+		if v == nil {
+			return append(dst, "null"...), nil
+		}
+		dst = append(dst, '[')
+		for i, item := range v {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			if dst, err = appendValue(dst, item); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, ']'), nil
+	default:
+		return appendMarshaled(dst, v)
+	}
+}
+
+// appendFloat appends a float64 as encoding/json does, in exponent notation only for very small
+// and very large values
+func appendFloat(dst []byte, f float64) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return dst, &json.UnsupportedValueError{Str: strconv.FormatFloat(f, 'g', -1, 64)}
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst, nil
+}
+
+// appendMarshaled appends the encoding/json encoding of v to dst
+func appendMarshaled(dst []byte, v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, data...), nil
+}
+
+const hex = "0123456789abcdef"
+
+// invalidUTF8 replaces invalid UTF-8 in strings as encoding/json does, which depends on the Go
+// version: older versions escape U+FFFD, newer ones write it as is
+var invalidUTF8 = func() string {
+	data, _ := json.Marshal("\xff")
+	return string(data[1 : len(data)-1])
+}()
+
+// appendString appends a JSON string to dst, escaping like encoding/json: quotes, backslashes,
+// control characters, the HTML characters <, > and &, U+2028 and U+2029, and replacing invalid
+// UTF-8
+func appendString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && b != '<' && b != '>' && b != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, invalidUTF8...)
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
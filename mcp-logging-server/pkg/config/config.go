@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-playground/validator/v10"
@@ -12,43 +14,451 @@ import (
 
 // ServerConfig contains server-specific configuration
 type ServerConfig struct {
-	IngestionPort int `yaml:"ingestion_port" validate:"required,min=1024,max=65535"`
-	MCPPort       int `yaml:"mcp_port" validate:"required,min=1024,max=65535"`
+	Host          string `yaml:"host"` // Address the ingestion and MCP servers listen on, empty for every interface
+	IngestionPort int    `yaml:"ingestion_port" validate:"required,min=1024,max=65535"`
+	MCPPort       int    `yaml:"mcp_port" validate:"required,min=1024,max=65535"`
+
+	Preflight   PreflightConfig   `yaml:"preflight"`
+	Concurrency ConcurrencyConfig `yaml:"concurrency"`
+	AccessLog   AccessLogConfig   `yaml:"access_log"`
+}
+
+// AccessLogConfig configures the access log, a JSON line per ingestion request written apart
+// from the application log
+type AccessLogConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	Path       string `yaml:"path" validate:"required_if=Enabled true"` // File the records are appended to, or stdout or stderr
+	MaxSizeMB  int    `yaml:"max_size_mb" validate:"min=0"`             // Size at which the file is rotated, 0 never rotates it
+	MaxBackups int    `yaml:"max_backups" validate:"min=0"`             // Rotated files kept as <path>.1 to <path>.N, 1 when 0
+}
+
+// ConcurrencyConfig sizes the server's concurrency. Zero values follow the CPUs the process may
+// use, which in a container is its CPU limit rather than the CPUs of the node.
+type ConcurrencyConfig struct {
+	MaxProcs     int `yaml:"max_procs" validate:"min=0"`     // GOMAXPROCS, 0 uses the GOMAXPROCS environment variable or the container CPU limit
+	MaxRequests  int `yaml:"max_requests" validate:"min=0"`  // Ingestion requests processed at once, more wait for a slot; 0 allows 16 per CPU
+	AsyncBatches int `yaml:"async_batches" validate:"min=0"` // Async batches stored at once, more are rejected with 503; 0 allows 4 per CPU
+	IndexWorkers int `yaml:"index_workers" validate:"min=0"` // Goroutines analyzing entries for the search index, 0 uses one per CPU
+}
+
+// PreflightConfig configures the self-test of storage, search, recovery directory and TLS run at startup
+type PreflightConfig struct {
+	Mode    string        `yaml:"mode" validate:"omitempty,oneof=strict degraded off"` // strict refuses to start when a check fails, degraded starts and reports it in /health
+	Timeout time.Duration `yaml:"timeout" validate:"min=0"`                            // Bounds each storage and search check
 }
 
 // StorageConfig contains storage-specific configuration
 type StorageConfig struct {
-	Type             string `yaml:"type" validate:"required,oneof=sqlite postgres clickhouse"`
-	ConnectionString string `yaml:"connection_string" validate:"required"`
+	Type             string `yaml:"type" validate:"required,oneof=sqlite postgres clickhouse memory"`
+	ConnectionString string `yaml:"connection_string" validate:"required_unless=Type memory"`
 	MaxConnections   int    `yaml:"max_connections" validate:"min=1,max=1000"`
+
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" validate:"min=0"` // Log queries slower than this with their filter, 0 disables
+	IntegrityHashing   bool          `yaml:"integrity_hashing"`                     // Store a content hash with every entry for POST /admin/verify
+	IntegrityKey       string        `yaml:"integrity_key"`                         // HMAC key for the content hashes, plain SHA-256 when empty
+	ImmutableWindow    time.Duration `yaml:"immutable_window" validate:"min=0"`     // Entries received less than this ago cannot be deleted, 0 disables
+
+	// SQLite storage only
+	ReadConnectionString string        `yaml:"read_connection_string" validate:"excluded_if=Type memory"` // Read-only replica serving queries, empty queries the primary
+	MaxReplicaLag        time.Duration `yaml:"max_replica_lag" validate:"min=0"`                          // Health is degraded when the replica lags more, 0 disables
+
+	Compression ColumnCompressionConfig `yaml:"compression"`
+	Encryption  EncryptionConfig        `yaml:"encryption"`
+	Archive     WALArchiveConfig        `yaml:"archive"`
+
+	// Memory storage only
+	MaxEntries       int           `yaml:"max_entries" validate:"min=0"`       // Entries kept before the oldest are evicted, 0 uses the default
+	SnapshotPath     string        `yaml:"snapshot_path"`                      // File restored at startup and saved on shutdown, empty disables
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" validate:"min=0"` // How often to save a snapshot while running, 0 only saves on shutdown
+
+	Fallback FallbackStorageConfig `yaml:"fallback"`
+}
+
+// EncryptionConfig configures the encryption of log contents at rest. The free-text columns of
+// new rows are encrypted with AES-256-GCM, and the search index holds keyed hashes of terms.
+type EncryptionConfig struct {
+	Key     string   `yaml:"key"`      // 32-byte key as 64 hexadecimal digits or in base64, empty disables encryption
+	OldKeys []string `yaml:"old_keys"` // Keys of entries written before a key rotation, only used to decrypt
+}
+
+// WALArchiveConfig configures continuous archiving of the SQLite database for point-in-time
+// recovery. Each generation of the archive starts with a snapshot of the database, followed by
+// the write-ahead log as transactions commit. mcp-logging restore rebuilds the database as of
+// any time the archive covers.
+type WALArchiveConfig struct {
+	URL              string        `yaml:"url"`                                // s3://bucket/prefix or a local directory, empty disables archiving
+	Interval         time.Duration `yaml:"interval" validate:"min=0"`          // How often committed transactions are shipped, bounds the data a restore can lose
+	SnapshotInterval time.Duration `yaml:"snapshot_interval" validate:"min=0"` // How often a new generation starts with a snapshot
+	Retention        time.Duration `yaml:"retention" validate:"min=0"`         // Generations superseded longer ago than this are deleted, 0 keeps all
+
+	S3 S3ArchiveConfig `yaml:"s3"`
+}
+
+// S3ArchiveConfig configures access to the S3 bucket of a WAL archive. Credentials default to
+// those of the AWS secrets provider.
+type S3ArchiveConfig struct {
+	Region          string `yaml:"region"`
+	Endpoint        string `yaml:"endpoint"` // Overrides the regional endpoint, e.g. for MinIO or R2
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+}
+
+// FallbackStorageConfig configures a secondary storage that receives buffer flushes while the
+// primary storage is failing. Entries are copied back once the primary recovers.
+type FallbackStorageConfig struct {
+	Type              string        `yaml:"type" validate:"omitempty,oneof=sqlite memory"` // Empty disables failover
+	ConnectionString  string        `yaml:"connection_string" validate:"required_if=Type sqlite"`
+	ReconcileInterval time.Duration `yaml:"reconcile_interval" validate:"min=0"` // How often entries are copied back to the primary storage
+
+	Compression ColumnCompressionConfig `yaml:"compression"` // SQLite fallback only
+}
+
+// ColumnCompressionConfig selects the SQLite columns stored compressed. Compressed values are
+// decompressed transparently on read, so compression can be enabled or disabled at any time.
+type ColumnCompressionConfig struct {
+	Columns []string `yaml:"columns" validate:"dive,oneof=message stack_trace metadata"` // Empty disables compression
+	MinSize int      `yaml:"min_size" validate:"min=0"`                                  // Values smaller than this many bytes are stored uncompressed
 }
 
 // RetentionConfig contains log retention policies
 type RetentionConfig struct {
 	DefaultDays int                `yaml:"default_days" validate:"min=1,max=3650"`
 	ByLevel     map[string]int     `yaml:"by_level"`
+	Compaction  CompactionConfig   `yaml:"compaction"`
+}
+
+// CompactionConfig configures the replacement of old low-severity entries with hourly summaries
+type CompactionConfig struct {
+	After    time.Duration `yaml:"after" validate:"min=0"`                                   // Entries older than this are compacted, 0 disables
+	Levels   []string      `yaml:"levels" validate:"dive,oneof=DEBUG INFO WARN ERROR FATAL"` // Levels to compact
+	Interval time.Duration `yaml:"interval" validate:"min=0"`                                // How often compaction runs
 }
 
 // IndexingConfig contains search indexing configuration
 type IndexingConfig struct {
-	Enabled        bool `yaml:"enabled"`
-	FullTextSearch bool `yaml:"full_text_search"`
+	Enabled        bool          `yaml:"enabled"`
+	FullTextSearch bool          `yaml:"full_text_search"`
+	IndexPath      string        `yaml:"index_path"`                      // Directory for the search index shards, empty disables full-text search
+	ShardDuration  time.Duration `yaml:"shard_duration" validate:"min=0"` // Time span covered by each search index shard
 }
 
 // BufferConfig contains message buffering configuration
 type BufferConfig struct {
-	Size         int           `yaml:"size" validate:"min=100,max=1000000"`
-	FlushTimeout time.Duration `yaml:"flush_timeout" validate:"min=1s,max=60s"`
-	MaxBatchSize int           `yaml:"max_batch_size" validate:"min=1,max=10000"`
+	Size            int           `yaml:"size" validate:"min=100,max=1000000"`
+	MaxBytes        int64         `yaml:"max_bytes" validate:"min=0"` // Budget for the estimated memory of buffered entries, 0 bounds only their number
+	FlushTimeout    time.Duration `yaml:"flush_timeout" validate:"min=1s,max=60s"`
+	MaxBatchSize    int           `yaml:"max_batch_size" validate:"min=1,max=10000"`
+	MaxServiceShare float64       `yaml:"max_service_share" validate:"min=0,max=1"` // Fraction of a full buffer one service may hold, 0 disables
+	EvictionPolicy  string        `yaml:"eviction_policy" validate:"omitempty,oneof=oldest level"`
+
+	// Adaptive tuning, max_batch_size and flush_timeout become the upper bounds
+	Adaptive         bool          `yaml:"adaptive"`
+	MinBatchSize     int           `yaml:"min_batch_size" validate:"min=0,max=10000"`
+	MinFlushInterval time.Duration `yaml:"min_flush_interval" validate:"min=0,max=60s"`
+	TargetLatency    time.Duration `yaml:"target_latency" validate:"min=0"`
+
+	FlushWorkers      int  `yaml:"flush_workers" validate:"min=0,max=64"` // Batches stored concurrently per flush, capped by the storage; 0 uses one per CPU
+	OrderedPerService bool `yaml:"ordered_per_service"`                   // Keeps the entries of a service on one flush worker, in order
+}
+
+// IngestionConfig contains log ingestion configuration
+type IngestionConfig struct {
+	MaxClockSkew    time.Duration     `yaml:"max_clock_skew" validate:"min=0"`
+	ClockSkewAction string            `yaml:"clock_skew_action" validate:"omitempty,oneof=clamp flag"`
+	LevelAliases    map[string]string `yaml:"level_aliases"`
+	Platforms       []string          `yaml:"platforms"`
+	Validation      ValidationConfig  `yaml:"validation"`
+	Shipper         ShipperConfig     `yaml:"shipper"`
+	Routing         RoutingConfig     `yaml:"routing"`
+	Sampling        SamplingConfig    `yaml:"sampling"`
+	Processors      []ProcessorConfig `yaml:"processors" validate:"dive"`
+}
+
+// ProcessorConfig contains an external processor, a command enriching or filtering the valid
+// entries of each batch before data protection. Processors run in order.
+type ProcessorConfig struct {
+	Name    string        `yaml:"name" validate:"required"`
+	Command string        `yaml:"command" validate:"required"`
+	Args    []string      `yaml:"args"`
+	Env     []string      `yaml:"env"`                                           // Variables added to the server's environment, as KEY=value
+	Workers int           `yaml:"workers" validate:"min=0"`                      // Instances of the command processing batches concurrently, 0 uses one per CPU
+	Timeout time.Duration `yaml:"timeout" validate:"min=0"`                      // Deadline per batch, 5s when 0
+	OnError string        `yaml:"on_error" validate:"omitempty,oneof=fail skip"` // fail rejects the batch, skip stores it unprocessed
+}
+
+// SamplingConfig contains the sampling policies of noisy services, applied after validation and
+// data protection, before the routing rules. The first policy matching the service of an entry
+// applies to it.
+type SamplingConfig struct {
+	Policies []SamplingPolicyConfig `yaml:"policies" validate:"dive"`
+}
+
+// SamplingPolicyConfig sets the fraction of the entries of a service kept at each level
+type SamplingPolicyConfig struct {
+	Service string             `yaml:"service" validate:"required"`                // Service name or pattern such as payments-*
+	Rates   map[string]float64 `yaml:"rates" validate:"required,dive,min=0,max=1"` // Fraction kept per level, levels not listed keep all
+}
+
+// RoutingConfig contains the rules applied to ingested entries in order, after validation and
+// data protection, and the sinks route rules send entries to
+type RoutingConfig struct {
+	Rules []RoutingRuleConfig `yaml:"rules" validate:"dive"`
+	Sinks []RoutingSinkConfig `yaml:"sinks" validate:"dive"`
+}
+
+// RoutingRuleConfig contains a routing rule. Tag, set and relevel rules change matching entries
+// and continue with the next rule, the first matching drop or route rule ends the evaluation.
+type RoutingRuleConfig struct {
+	Name   string             `yaml:"name" validate:"required"`
+	Match  RoutingMatchConfig `yaml:"match"`
+	Action string             `yaml:"action" validate:"oneof=tag set relevel drop route"`
+	Tags   []string           `yaml:"tags"`                                     // Added by tag rules
+	Set    map[string]string  `yaml:"set"`                                      // Metadata keys set by set rules to the values of expressions
+	Level  string             `yaml:"level"`                                    // Set by relevel rules
+	Sink   string             `yaml:"sink" validate:"required_if=Action route"` // Name of the sink of route rules
+}
+
+// RoutingMatchConfig selects the entries a routing rule applies to, empty conditions match all
+type RoutingMatchConfig struct {
+	Services   []string          `yaml:"services"`   // Service names or patterns such as payments-*, any of them
+	Levels     []string          `yaml:"levels"`     // Any of the levels
+	Metadata   map[string]string `yaml:"metadata"`   // Regular expressions the metadata values must all match
+	Expression string            `yaml:"expression"` // Condition such as metadata.latency_ms > 500 && service_name == "api"
+}
+
+// RoutingSinkConfig contains a server that route rules send entries to instead of storing them,
+// such as the server of another tenant. Entries are delivered like replicated batches.
+type RoutingSinkConfig struct {
+	Name          string `yaml:"name" validate:"required"`    // Referred to by the sink of route rules
+	URL           string `yaml:"url" validate:"required,url"` // Base URL of the sink's ingestion API
+	APIKey        string `yaml:"api_key"`                     // Key of the sink with the replicate_logs permission
+	SigningSecret string `yaml:"signing_secret"`              // Signs requests when the sink requires it for the key
+	CAFile        string `yaml:"ca_file"`                     // PEM CA bundle for the sink, system roots when empty
+}
+
+// ShipperConfig maps the record keys of Fluent Bit and Vector posts to /v1/logs/shipper. Each
+// list is tried in order, nested keys use dots, and empty lists keep the defaults.
+type ShipperConfig struct {
+	TimestampKeys   []string `yaml:"timestamp_keys"`
+	LevelKeys       []string `yaml:"level_keys"`
+	MessageKeys     []string `yaml:"message_keys"`
+	ServiceKeys     []string `yaml:"service_keys"`
+	AgentKeys       []string `yaml:"agent_keys"`
+	DefaultService  string   `yaml:"default_service"`  // Service of records without a service key
+	DefaultPlatform string   `yaml:"default_platform"` // Platform of the entries, defaults to go
+}
+
+// ValidationConfig contains the rules applied to ingested log entries, zero limits keep the defaults
+type ValidationConfig struct {
+	MaxMessageLength    int               `yaml:"max_message_length" validate:"min=0"`
+	RequiredFields      []string          `yaml:"required_fields"`                  // Optional fields entries must set, such as device_info or metadata.user_id
+	MaxFutureSkew       time.Duration     `yaml:"max_future_skew" validate:"min=0"` // How far timestamps may be ahead of the server clock
+	MaxAge              time.Duration     `yaml:"max_age" validate:"min=0"`         // How far timestamps may be behind the server clock
+	MaxMetadataKeys     int               `yaml:"max_metadata_keys" validate:"min=0"`
+	MaxStackTraceLength int               `yaml:"max_stack_trace_length" validate:"min=0"`
+	FieldRules          []FieldRuleConfig `yaml:"field_rules" validate:"dive"`
+	DisabledRules       []string          `yaml:"disabled_rules"` // Names of rules and field rules that are not applied
+}
+
+// FieldRuleConfig contains a custom validator of a log entry field
+type FieldRuleConfig struct {
+	Name      string   `yaml:"name" validate:"required"`
+	Field     string   `yaml:"field" validate:"required"` // Such as agent_id or metadata.order_id
+	Pattern   string   `yaml:"pattern"`                   // Regular expression the value must match
+	MaxLength int      `yaml:"max_length" validate:"min=0"`
+	OneOf     []string `yaml:"one_of"`
+}
+
+// MCPConfig contains MCP tool call configuration
+type MCPConfig struct {
+	QueryTimeout        time.Duration            `yaml:"query_timeout" validate:"min=0"`         // Default deadline for tool calls, 0 disables
+	ToolTimeouts        map[string]time.Duration `yaml:"tool_timeouts"`                          // Per-tool deadlines overriding query_timeout
+	SlowQueryThreshold  time.Duration            `yaml:"slow_query_threshold" validate:"min=0"`  // Log tool calls slower than this, 0 disables
+	WriteTimeout        time.Duration            `yaml:"write_timeout" validate:"min=0"`         // Disconnect clients not reading a response for this long, 0 disables
+	MaxPendingResponses int                      `yaml:"max_pending_responses" validate:"min=0"` // Disconnect clients leaving more responses unread
+	DisabledTools       []string                 `yaml:"disabled_tools"`                         // Tools neither listed nor served until enabled at /admin/tools
+	Masking             MaskingConfig            `yaml:"masking"`
+	ServiceCatalog      ServiceCatalogConfig     `yaml:"service_catalog"`
+	ResultCache         ResultCacheConfig        `yaml:"result_cache"`
+}
+
+// ResultCacheConfig configures the cache of query_logs, get_error_rate and diff_time_windows results
+type ResultCacheConfig struct {
+	TTL        time.Duration `yaml:"ttl" validate:"min=0"`         // How long results are kept, 0 disables the cache
+	MaxEntries int           `yaml:"max_entries" validate:"min=0"` // Results kept at most
+}
+
+// ServiceCatalogConfig configures how list_services treats agents that stopped logging
+type ServiceCatalogConfig struct {
+	StaleAfter time.Duration `yaml:"stale_after" validate:"min=0"` // Agents not seen for this long are flagged stale, 0 disables
+	HideAfter  time.Duration `yaml:"hide_after" validate:"min=0"`  // Agents not seen for this long are hidden unless include_hidden is set, 0 disables
+}
+
+// MaskingConfig configures how MCP tools mask the fields listed in mask_fields
+type MaskingConfig struct {
+	RevealChars       int    `yaml:"reveal_chars" validate:"min=0"`        // Characters kept at each end of masked values
+	Token             string `yaml:"token" validate:"required"`            // Replaces the hidden characters
+	RepeatToken       bool   `yaml:"repeat_token"`                         // Repeat the token for each hidden character instead of once
+	FullMaskThreshold int    `yaml:"full_mask_threshold" validate:"min=0"` // Values this long or shorter are hidden entirely
+}
+
+// RelayConfig contains relay mode configuration, where received logs are forwarded in
+// batches to a central server instead of being stored locally
+type RelayConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	URL           string        `yaml:"url" validate:"required_if=Enabled true,omitempty,url"` // Base URL of the central ingestion API
+	APIKey        string        `yaml:"api_key"`                                               // Sent as X-API-Key to the central server
+	SigningSecret string        `yaml:"signing_secret"`                                        // Signs forwarded requests when the central server requires it for the key
+	CAFile        string        `yaml:"ca_file"`                                               // PEM CA bundle for the central server, system roots when empty
+	Timeout       time.Duration `yaml:"timeout" validate:"min=0"`                              // Per-request timeout, 0 uses the default
+	MaxRetries    int           `yaml:"max_retries" validate:"min=0"`                          // Attempts after the first before a batch is returned to the buffer
+	RetryBackoff  time.Duration `yaml:"retry_backoff" validate:"min=0"`                        // Initial delay between attempts, doubled after each one
+	MaxBatchSize  int           `yaml:"max_batch_size" validate:"min=0,max=1000"`              // Entries per forwarded request, 0 uses the central maximum
+}
+
+// ReplicationConfig contains the asynchronous replication of stored batches to peer servers in
+// other regions. Peers store replicated batches without replicating them again.
+type ReplicationConfig struct {
+	ServerID     string                  `yaml:"server_id" validate:"required_with=Peers"` // Sent to peers as the origin of batches, unique among the servers
+	Peers        []ReplicationPeerConfig `yaml:"peers" validate:"dive"`                    // Empty disables replication
+	Timeout      time.Duration           `yaml:"timeout" validate:"min=0"`                 // Per-request timeout, 0 uses the default
+	QueueSize    int                     `yaml:"queue_size" validate:"min=0"`              // Batches held for each peer, 0 uses the default
+	RetryBackoff time.Duration           `yaml:"retry_backoff" validate:"min=0"`           // Initial delay between attempts, doubled after each one
+}
+
+// ReplicationPeerConfig contains a peer server that stored batches are replicated to
+type ReplicationPeerConfig struct {
+	Name          string `yaml:"name"`                        // Identifies the peer in the stats, the host of the URL when empty
+	URL           string `yaml:"url" validate:"required,url"` // Base URL of the peer's ingestion API
+	APIKey        string `yaml:"api_key"`                     // Key of the peer with the replicate_logs permission
+	SigningSecret string `yaml:"signing_secret"`              // Signs replicated requests when the peer requires it for the key
+	CAFile        string `yaml:"ca_file"`                     // PEM CA bundle for the peer, system roots when empty
+}
+
+// ShardingConfig contains the writer nodes of a sharded cluster. Each service is owned by one
+// node, chosen by consistent hashing of the service name, and entries received by any node are
+// sent to the owner of their service. All nodes must be configured with the same nodes.
+type ShardingConfig struct {
+	NodeID       string            `yaml:"node_id" validate:"required_with=Nodes"` // ID of this node, one of the nodes
+	Nodes        []ShardNodeConfig `yaml:"nodes" validate:"dive"`                  // Empty disables sharding
+	VirtualNodes int               `yaml:"virtual_nodes" validate:"min=0"`         // Points per node on the hash ring, 0 uses the default
+	Timeout      time.Duration     `yaml:"timeout" validate:"min=0"`               // Timeout of requests to other nodes, 0 uses the default
+}
+
+// ShardNodeConfig contains a writer node of a sharded cluster
+type ShardNodeConfig struct {
+	ID            string `yaml:"id" validate:"required"`      // Must not change while the node holds logs
+	URL           string `yaml:"url" validate:"required,url"` // Base URL of the node's ingestion API
+	APIKey        string `yaml:"api_key"`                     // Key of the node with the route_logs permission
+	SigningSecret string `yaml:"signing_secret"`              // Signs routed requests when the node requires it for the key
+	CAFile        string `yaml:"ca_file"`                     // PEM CA bundle for the node, system roots when empty
+}
+
+// SIEMConfig contains the SIEM that data protection and admin audit events are forwarded to,
+// as CEF over syslog or as JSON over HTTPS
+type SIEMConfig struct {
+	Protocol     string        `yaml:"protocol" validate:"omitempty,oneof=syslog https"`        // Empty disables forwarding
+	Address      string        `yaml:"address" validate:"required_if=Protocol syslog"`          // host:port of the syslog receiver
+	Network      string        `yaml:"network" validate:"omitempty,oneof=tcp udp tls"`          // Syslog transport, tcp when empty
+	URL          string        `yaml:"url" validate:"required_if=Protocol https,omitempty,url"` // HTTPS collector endpoint
+	Token        string        `yaml:"token"`                                                   // Bearer token of the HTTPS collector
+	CAFile       string        `yaml:"ca_file"`                                                 // PEM CA bundle for the receiver, system roots when empty
+	Timeout      time.Duration `yaml:"timeout" validate:"min=0"`                                // Per-delivery timeout, 0 uses the default
+	QueueSize    int           `yaml:"queue_size" validate:"min=0"`                             // Events held for delivery, 0 uses the default
+	MaxRetries   int           `yaml:"max_retries" validate:"min=0"`                            // Attempts after the first before events are dropped
+	RetryBackoff time.Duration `yaml:"retry_backoff" validate:"min=0"`                          // Initial delay between attempts, doubled after each one
+}
+
+// KubernetesConfig contains the watch of Kubernetes Events ingested as log entries, with the
+// namespace and name of the involved object as service
+type KubernetesConfig struct {
+	Events    bool   `yaml:"events"`     // Watch and ingest events, a relay forwards them to the central server
+	APIServer string `yaml:"api_server"` // API server URL, the in-cluster address when empty
+	TokenFile string `yaml:"token_file"` // Bearer token file, the pod's service account token when empty
+	CAFile    string `yaml:"ca_file"`    // PEM CA bundle of the API server, the service account CA when empty
+	Namespace string `yaml:"namespace"`  // Namespace to watch, empty watches all namespaces
+	Platform  string `yaml:"platform"`   // Platform of the entries, defaults to go
+}
+
+// JournaldConfig contains the systemd journal reader ingesting journal entries as log entries,
+// with the unit as service
+type JournaldConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	Command       string        `yaml:"command"`                              // journalctl binary, looked up in PATH when empty
+	Directory     string        `yaml:"directory"`                            // Journal directory, the system journal when empty
+	Units         []string      `yaml:"units"`                                // Units to follow, empty follows all
+	CursorFile    string        `yaml:"cursor_file"`                          // Where the position of the last ingested entry is saved, empty disables resume
+	BatchSize     int           `yaml:"batch_size" validate:"min=0,max=1000"` // Entries ingested together, 0 uses the default
+	FlushInterval time.Duration `yaml:"flush_interval" validate:"min=0"`      // How long entries wait for a batch to fill
+	Platform      string        `yaml:"platform"`                             // Platform of the entries, defaults to go
+}
+
+// QueryAuditConfig contains the audit trail of MCP tool calls and REST searches, listing who
+// queried which logs at GET /admin/query-audit
+type QueryAuditConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Retention time.Duration `yaml:"retention" validate:"min=0"` // Records older than this are deleted, 0 keeps them
+}
+
+// SecretsConfig contains the secrets manager that API keys, the TLS key pair and the hash salt
+// are loaded from instead of local files and environment variables. Secrets are referenced by
+// name, with #field selecting a value of a secret holding a JSON object.
+type SecretsConfig struct {
+	Provider        string        `yaml:"provider" validate:"omitempty,oneof=vault aws gcp"` // Empty disables secrets manager integration
+	RefreshInterval time.Duration `yaml:"refresh_interval" validate:"min=0"`                 // How often secrets are reloaded, 0 uses the default
+	APIKeys         string        `yaml:"api_keys"`                                          // API key configuration in the api-keys.yaml format, replaces the keys file
+	TLSCert         string        `yaml:"tls_cert" validate:"required_with=TLSKey"`          // PEM certificate chain, replaces the certificate file
+	TLSKey          string        `yaml:"tls_key" validate:"required_with=TLSCert"`          // PEM private key, replaces the key file
+	HashSalt        string        `yaml:"hash_salt"`                                         // Salt of fields hashed by data protection
+	EncryptionKey   string        `yaml:"encryption_key"`                                    // Storage encryption key, loaded once at startup
+
+	Vault VaultSecretsConfig `yaml:"vault"`
+	AWS   AWSSecretsConfig   `yaml:"aws"`
+	GCP   GCPSecretsConfig   `yaml:"gcp"`
+}
+
+// VaultSecretsConfig configures the HashiCorp Vault KV version 2 secrets provider
+type VaultSecretsConfig struct {
+	Address   string `yaml:"address"`
+	Token     string `yaml:"token"`
+	Namespace string `yaml:"namespace"` // Vault Enterprise namespace
+	Mount     string `yaml:"mount"`     // Mount path of the KV engine, defaults to secret
+}
+
+// AWSSecretsConfig configures the AWS Secrets Manager secrets provider
+type AWSSecretsConfig struct {
+	Region          string `yaml:"region"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	Endpoint        string `yaml:"endpoint"` // Overrides the regional endpoint, e.g. for VPC endpoints
+}
+
+// GCPSecretsConfig configures the Google Cloud Secret Manager secrets provider
+type GCPSecretsConfig struct {
+	Project     string `yaml:"project"`      // Project of secret IDs that are not full resource names
+	AccessToken string `yaml:"access_token"` // Fetched from the metadata server when empty
+	Endpoint    string `yaml:"endpoint"`
 }
 
 // Config represents the complete application configuration
 type Config struct {
-	Server    ServerConfig    `yaml:"server" validate:"required"`
-	Storage   StorageConfig   `yaml:"storage" validate:"required"`
-	Retention RetentionConfig `yaml:"retention" validate:"required"`
-	Indexing  IndexingConfig  `yaml:"indexing"`
-	Buffer    BufferConfig    `yaml:"buffer" validate:"required"`
+	Server      ServerConfig      `yaml:"server" validate:"required"`
+	Storage     StorageConfig     `yaml:"storage" validate:"required"`
+	Retention   RetentionConfig   `yaml:"retention" validate:"required"`
+	Indexing    IndexingConfig    `yaml:"indexing"`
+	Buffer      BufferConfig      `yaml:"buffer" validate:"required"`
+	Ingestion   IngestionConfig   `yaml:"ingestion"`
+	MCP         MCPConfig         `yaml:"mcp"`
+	Relay       RelayConfig       `yaml:"relay"`
+	Replication ReplicationConfig `yaml:"replication"`
+	Sharding    ShardingConfig    `yaml:"sharding"`
+	Secrets     SecretsConfig     `yaml:"secrets"`
+	SIEM        SIEMConfig        `yaml:"siem"`
+	Kubernetes  KubernetesConfig  `yaml:"kubernetes"`
+	Journald    JournaldConfig    `yaml:"journald"`
+	QueryAudit  QueryAuditConfig  `yaml:"query_audit"`
 }
 
 // Validate validates the configuration using struct tags
@@ -60,6 +470,35 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("ingestion_port and mcp_port cannot be the same")
 	}
 	
+	if c.Storage.Fallback.Type == c.Storage.Type && c.Storage.Fallback.Type == "sqlite" &&
+		c.Storage.Fallback.ConnectionString == c.Storage.ConnectionString {
+		return fmt.Errorf("fallback storage cannot use the primary storage database")
+	}
+	
+	if c.Storage.Type == "memory" && (c.Storage.Encryption.Key != "" || c.Secrets.EncryptionKey != "") {
+		return fmt.Errorf("storage encryption requires sqlite storage")
+	}
+	
+	if c.Storage.Type == "memory" && c.Storage.Archive.URL != "" {
+		return fmt.Errorf("WAL archiving requires sqlite storage")
+	}
+	
+	if c.Relay.Enabled && len(c.Replication.Peers) > 0 {
+		return fmt.Errorf("replication is not available in relay mode, configure it on the central server")
+	}
+	
+	if c.Relay.Enabled && len(c.Sharding.Nodes) > 0 {
+		return fmt.Errorf("sharding is not available in relay mode, configure it on the central servers")
+	}
+	
+	if c.Relay.Enabled && c.QueryAudit.Enabled {
+		return fmt.Errorf("query audit is not available in relay mode, configure it on the central server")
+	}
+	
+	if catalog := c.MCP.ServiceCatalog; catalog.StaleAfter > 0 && catalog.HideAfter > 0 && catalog.HideAfter < catalog.StaleAfter {
+		return fmt.Errorf("mcp service_catalog hide_after cannot be shorter than stale_after")
+	}
+	
 	return validate.Struct(c)
 }
 
@@ -69,11 +508,27 @@ func DefaultConfig() *Config {
 		Server: ServerConfig{
 			IngestionPort: 8080,
 			MCPPort:       8081,
+			Preflight: PreflightConfig{
+				Mode:    "strict",
+				Timeout: 10 * time.Second,
+			},
+			AccessLog: AccessLogConfig{
+				Path:       "./logs/access.log",
+				MaxSizeMB:  100,
+				MaxBackups: 5,
+			},
 		},
 		Storage: StorageConfig{
 			Type:             "sqlite",
 			ConnectionString: "./logs.db",
 			MaxConnections:   10,
+
+			SlowQueryThreshold: time.Second,
+			MaxReplicaLag:      30 * time.Second,
+
+			Fallback: FallbackStorageConfig{
+				ReconcileInterval: 30 * time.Second,
+			},
 		},
 		Retention: RetentionConfig{
 			DefaultDays: 30,
@@ -84,25 +539,131 @@ func DefaultConfig() *Config {
 				"ERROR": 365,
 				"FATAL": 365,
 			},
+			Compaction: CompactionConfig{
+				Levels:   []string{"DEBUG", "INFO"},
+				Interval: time.Hour,
+			},
 		},
 		Indexing: IndexingConfig{
 			Enabled:        true,
 			FullTextSearch: true,
+			ShardDuration:  24 * time.Hour,
 		},
 		Buffer: BufferConfig{
-			Size:         10000,
-			FlushTimeout: 5 * time.Second,
-			MaxBatchSize: 100,
+			Size:            10000,
+			MaxBytes:        256 << 20,
+			FlushTimeout:    5 * time.Second,
+			MaxBatchSize:    100,
+			MaxServiceShare: 0.5,
+			EvictionPolicy:  "level",
+
+			Adaptive:         true,
+			MinBatchSize:     10,
+			MinFlushInterval: 100 * time.Millisecond,
+			TargetLatency:    200 * time.Millisecond,
+
+			FlushWorkers:      0,
+			OrderedPerService: true,
+		},
+		Ingestion: IngestionConfig{
+			MaxClockSkew:    0,
+			ClockSkewAction: "flag",
+			Validation: ValidationConfig{
+				MaxMessageLength:    10000,
+				MaxFutureSkew:       5 * time.Minute,
+				MaxAge:              365 * 24 * time.Hour,
+				MaxMetadataKeys:     50,
+				MaxStackTraceLength: 50000,
+			},
+		},
+		MCP: MCPConfig{
+			QueryTimeout:        30 * time.Second,
+			SlowQueryThreshold:  2 * time.Second,
+			WriteTimeout:        10 * time.Second,
+			MaxPendingResponses: 32,
+			Masking: MaskingConfig{
+				RevealChars:       2,
+				Token:             "[MASKED]",
+				FullMaskThreshold: 4,
+			},
+			ServiceCatalog: ServiceCatalogConfig{
+				StaleAfter: 7 * 24 * time.Hour,
+				HideAfter:  30 * 24 * time.Hour,
+			},
+			ResultCache: ResultCacheConfig{
+				TTL:        0,
+				MaxEntries: 1000,
+			},
+		},
+		Relay: RelayConfig{
+			Timeout:      30 * time.Second,
+			MaxRetries:   5,
+			RetryBackoff: time.Second,
+			MaxBatchSize: 1000,
+		},
+		Replication: ReplicationConfig{
+			Timeout:      30 * time.Second,
+			QueueSize:    1000,
+			RetryBackoff: time.Second,
+		},
+		Sharding: ShardingConfig{
+			VirtualNodes: 128,
+			Timeout:      10 * time.Second,
+		},
+		SIEM: SIEMConfig{
+			Timeout:      10 * time.Second,
+			QueueSize:    10000,
+			MaxRetries:   5,
+			RetryBackoff: time.Second,
+		},
+		Journald: JournaldConfig{
+			CursorFile:    "./journald.cursor",
+			BatchSize:     100,
+			FlushInterval: time.Second,
+		},
+		QueryAudit: QueryAuditConfig{
+			Retention: 90 * 24 * time.Hour,
+		},
+		Secrets: SecretsConfig{
+			RefreshInterval: 5 * time.Minute,
+			Vault: VaultSecretsConfig{
+				Mount: "secret",
+			},
 		},
 	}
 }
 
+// DevHost is the address dev mode listens on. Dev mode runs without API keys, so its servers
+// must not be reachable from other machines.
+const DevHost = "127.0.0.1"
+
+// DevConfig returns the configuration for local development, which keeps logs in an
+// in-memory database, listens on the loopback interface only and ignores config files and
+// environment overrides
+func DevConfig() *Config {
+	config := DefaultConfig()
+	config.Server.Host = DevHost
+	config.Storage.ConnectionString = ":memory:"
+	config.Storage.MaxConnections = 1
+	config.Indexing.IndexPath = ""
+	return config
+}
+
 // Load loads configuration from file or environment variables
 func Load() (*Config, error) {
+	return LoadFile("")
+}
+
+// LoadFile loads configuration from the given file, falling back to MCP_LOGGING_CONFIG and the
+// common locations when path is empty, and applies environment overrides
+func LoadFile(path string) (*Config, error) {
 	config := DefaultConfig()
 	
 	// Try to load from config file
-	configPath := os.Getenv("MCP_LOGGING_CONFIG")
+	configPath := path
+	if configPath == "" {
+		configPath = os.Getenv("MCP_LOGGING_CONFIG")
+	}
 	if configPath == "" {
 		// Look for config file in common locations
 		possiblePaths := []string{
@@ -149,6 +710,10 @@ func loadFromFile(config *Config, path string) error {
 
 // loadFromEnv loads configuration from environment variables
 func loadFromEnv(config *Config) {
+	if host := os.Getenv("MCP_LOGGING_HOST"); host != "" {
+		config.Server.Host = host
+	}
+	
 	if port := os.Getenv("MCP_LOGGING_INGESTION_PORT"); port != "" {
 		if p, err := parsePort(port); err == nil {
 			config.Server.IngestionPort = p
@@ -161,6 +726,44 @@ func loadFromEnv(config *Config) {
 		}
 	}
 	
+	if maxProcs := os.Getenv("MCP_LOGGING_MAX_PROCS"); maxProcs != "" {
+		if n, err := strconv.Atoi(maxProcs); err == nil {
+			config.Server.Concurrency.MaxProcs = n
+		}
+	}
+	
+	if maxRequests := os.Getenv("MCP_LOGGING_MAX_REQUESTS"); maxRequests != "" {
+		if n, err := strconv.Atoi(maxRequests); err == nil {
+			config.Server.Concurrency.MaxRequests = n
+		}
+	}
+	
+	if asyncBatches := os.Getenv("MCP_LOGGING_ASYNC_BATCHES"); asyncBatches != "" {
+		if n, err := strconv.Atoi(asyncBatches); err == nil {
+			config.Server.Concurrency.AsyncBatches = n
+		}
+	}
+	
+	if bufferMaxBytes := os.Getenv("MCP_LOGGING_BUFFER_MAX_BYTES"); bufferMaxBytes != "" {
+		if n, err := strconv.ParseInt(bufferMaxBytes, 10, 64); err == nil {
+			config.Buffer.MaxBytes = n
+		}
+	}
+	
+	if preflightMode := os.Getenv("MCP_LOGGING_PREFLIGHT_MODE"); preflightMode != "" {
+		config.Server.Preflight.Mode = preflightMode
+	}
+	
+	if accessLog := os.Getenv("MCP_LOGGING_ACCESS_LOG"); accessLog != "" {
+		if enabled, err := strconv.ParseBool(accessLog); err == nil {
+			config.Server.AccessLog.Enabled = enabled
+		}
+	}
+	
+	if accessLogPath := os.Getenv("MCP_LOGGING_ACCESS_LOG_PATH"); accessLogPath != "" {
+		config.Server.AccessLog.Path = accessLogPath
+	}
+	
 	if connStr := os.Getenv("MCP_LOGGING_DB_CONNECTION"); connStr != "" {
 		config.Storage.ConnectionString = connStr
 	}
@@ -168,6 +771,267 @@ func loadFromEnv(config *Config) {
 	if dbType := os.Getenv("MCP_LOGGING_DB_TYPE"); dbType != "" {
 		config.Storage.Type = dbType
 	}
+	
+	if indexPath := os.Getenv("MCP_LOGGING_INDEX_PATH"); indexPath != "" {
+		config.Indexing.IndexPath = indexPath
+	}
+	
+	if slowQuery := os.Getenv("MCP_LOGGING_DB_SLOW_QUERY_THRESHOLD"); slowQuery != "" {
+		if d, err := time.ParseDuration(slowQuery); err == nil {
+			config.Storage.SlowQueryThreshold = d
+		}
+	}
+	
+	if integrityHashing := os.Getenv("MCP_LOGGING_DB_INTEGRITY_HASHING"); integrityHashing != "" {
+		if enabled, err := strconv.ParseBool(integrityHashing); err == nil {
+			config.Storage.IntegrityHashing = enabled
+		}
+	}
+	
+	if integrityKey := os.Getenv("MCP_LOGGING_DB_INTEGRITY_KEY"); integrityKey != "" {
+		config.Storage.IntegrityKey = integrityKey
+	}
+	
+	if encryptionKey := os.Getenv("MCP_LOGGING_DB_ENCRYPTION_KEY"); encryptionKey != "" {
+		config.Storage.Encryption.Key = encryptionKey
+	}
+	
+	if immutableWindow := os.Getenv("MCP_LOGGING_DB_IMMUTABLE_WINDOW"); immutableWindow != "" {
+		if d, err := time.ParseDuration(immutableWindow); err == nil {
+			config.Storage.ImmutableWindow = d
+		}
+	}
+	
+	if maxEntries := os.Getenv("MCP_LOGGING_DB_MAX_ENTRIES"); maxEntries != "" {
+		if n, err := strconv.Atoi(maxEntries); err == nil {
+			config.Storage.MaxEntries = n
+		}
+	}
+	
+	if archiveURL := os.Getenv("MCP_LOGGING_DB_ARCHIVE_URL"); archiveURL != "" {
+		config.Storage.Archive.URL = archiveURL
+	}
+	
+	if readConn := os.Getenv("MCP_LOGGING_DB_READ_CONNECTION"); readConn != "" {
+		config.Storage.ReadConnectionString = readConn
+	}
+	
+	if compressedColumns := os.Getenv("MCP_LOGGING_DB_COMPRESSED_COLUMNS"); compressedColumns != "" {
+		config.Storage.Compression.Columns = strings.Split(compressedColumns, ",")
+	}
+	
+	if fallbackType := os.Getenv("MCP_LOGGING_FALLBACK_DB_TYPE"); fallbackType != "" {
+		config.Storage.Fallback.Type = fallbackType
+	}
+	
+	if fallbackConn := os.Getenv("MCP_LOGGING_FALLBACK_DB_CONNECTION"); fallbackConn != "" {
+		config.Storage.Fallback.ConnectionString = fallbackConn
+	}
+	
+	if snapshotPath := os.Getenv("MCP_LOGGING_DB_SNAPSHOT_PATH"); snapshotPath != "" {
+		config.Storage.SnapshotPath = snapshotPath
+	}
+	
+	if snapshotInterval := os.Getenv("MCP_LOGGING_DB_SNAPSHOT_INTERVAL"); snapshotInterval != "" {
+		if d, err := time.ParseDuration(snapshotInterval); err == nil {
+			config.Storage.SnapshotInterval = d
+		}
+	}
+	
+	if maxSkew := os.Getenv("MCP_LOGGING_MAX_CLOCK_SKEW"); maxSkew != "" {
+		if d, err := time.ParseDuration(maxSkew); err == nil {
+			config.Ingestion.MaxClockSkew = d
+		}
+	}
+	
+	if skewAction := os.Getenv("MCP_LOGGING_CLOCK_SKEW_ACTION"); skewAction != "" {
+		config.Ingestion.ClockSkewAction = skewAction
+	}
+	
+	if platforms := os.Getenv("MCP_LOGGING_PLATFORMS"); platforms != "" {
+		config.Ingestion.Platforms = strings.Split(platforms, ",")
+	}
+	
+	if disabledRules := os.Getenv("MCP_LOGGING_DISABLED_VALIDATION_RULES"); disabledRules != "" {
+		config.Ingestion.Validation.DisabledRules = strings.Split(disabledRules, ",")
+	}
+	
+	if compactAfter := os.Getenv("MCP_LOGGING_COMPACTION_AFTER"); compactAfter != "" {
+		if d, err := time.ParseDuration(compactAfter); err == nil {
+			config.Retention.Compaction.After = d
+		}
+	}
+	
+	if queryTimeout := os.Getenv("MCP_LOGGING_MCP_QUERY_TIMEOUT"); queryTimeout != "" {
+		if d, err := time.ParseDuration(queryTimeout); err == nil {
+			config.MCP.QueryTimeout = d
+		}
+	}
+	
+	if slowQuery := os.Getenv("MCP_LOGGING_MCP_SLOW_QUERY_THRESHOLD"); slowQuery != "" {
+		if d, err := time.ParseDuration(slowQuery); err == nil {
+			config.MCP.SlowQueryThreshold = d
+		}
+	}
+	
+	if staleAfter := os.Getenv("MCP_LOGGING_MCP_SERVICE_STALE_AFTER"); staleAfter != "" {
+		if d, err := time.ParseDuration(staleAfter); err == nil {
+			config.MCP.ServiceCatalog.StaleAfter = d
+		}
+	}
+	
+	if hideAfter := os.Getenv("MCP_LOGGING_MCP_SERVICE_HIDE_AFTER"); hideAfter != "" {
+		if d, err := time.ParseDuration(hideAfter); err == nil {
+			config.MCP.ServiceCatalog.HideAfter = d
+		}
+	}
+	
+	if writeTimeout := os.Getenv("MCP_LOGGING_MCP_WRITE_TIMEOUT"); writeTimeout != "" {
+		if d, err := time.ParseDuration(writeTimeout); err == nil {
+			config.MCP.WriteTimeout = d
+		}
+	}
+	
+	if maxPending := os.Getenv("MCP_LOGGING_MCP_MAX_PENDING_RESPONSES"); maxPending != "" {
+		if n, err := strconv.Atoi(maxPending); err == nil {
+			config.MCP.MaxPendingResponses = n
+		}
+	}
+	
+	if disabledTools := os.Getenv("MCP_LOGGING_MCP_DISABLED_TOOLS"); disabledTools != "" {
+		config.MCP.DisabledTools = strings.Split(disabledTools, ",")
+	}
+	
+	if cacheTTL := os.Getenv("MCP_LOGGING_MCP_RESULT_CACHE_TTL"); cacheTTL != "" {
+		if d, err := time.ParseDuration(cacheTTL); err == nil {
+			config.MCP.ResultCache.TTL = d
+		}
+	}
+	
+	if relayURL := os.Getenv("MCP_LOGGING_RELAY_URL"); relayURL != "" {
+		config.Relay.Enabled = true
+		config.Relay.URL = relayURL
+	}
+	
+	if relayAPIKey := os.Getenv("MCP_LOGGING_RELAY_API_KEY"); relayAPIKey != "" {
+		config.Relay.APIKey = relayAPIKey
+	}
+	
+	if relayCAFile := os.Getenv("MCP_LOGGING_RELAY_CA_FILE"); relayCAFile != "" {
+		config.Relay.CAFile = relayCAFile
+	}
+	
+	if relaySigningSecret := os.Getenv("MCP_LOGGING_RELAY_SIGNING_SECRET"); relaySigningSecret != "" {
+		config.Relay.SigningSecret = relaySigningSecret
+	}
+	
+	if serverID := os.Getenv("MCP_LOGGING_REPLICATION_SERVER_ID"); serverID != "" {
+		config.Replication.ServerID = serverID
+	}
+	
+	if peers := os.Getenv("MCP_LOGGING_REPLICATION_PEERS"); peers != "" {
+		config.Replication.Peers = nil
+		for _, peerURL := range strings.Split(peers, ",") {
+			config.Replication.Peers = append(config.Replication.Peers, ReplicationPeerConfig{URL: strings.TrimSpace(peerURL)})
+		}
+	}
+	
+	if replicationAPIKey := os.Getenv("MCP_LOGGING_REPLICATION_API_KEY"); replicationAPIKey != "" {
+		for i := range config.Replication.Peers {
+			if config.Replication.Peers[i].APIKey == "" {
+				config.Replication.Peers[i].APIKey = replicationAPIKey
+			}
+		}
+	}
+	
+	if shardNodeID := os.Getenv("MCP_LOGGING_SHARD_NODE_ID"); shardNodeID != "" {
+		config.Sharding.NodeID = shardNodeID
+	}
+	
+	if siemProtocol := os.Getenv("MCP_LOGGING_SIEM_PROTOCOL"); siemProtocol != "" {
+		config.SIEM.Protocol = siemProtocol
+	}
+	
+	if siemAddress := os.Getenv("MCP_LOGGING_SIEM_ADDRESS"); siemAddress != "" {
+		config.SIEM.Address = siemAddress
+	}
+	
+	if siemNetwork := os.Getenv("MCP_LOGGING_SIEM_NETWORK"); siemNetwork != "" {
+		config.SIEM.Network = siemNetwork
+	}
+	
+	if siemURL := os.Getenv("MCP_LOGGING_SIEM_URL"); siemURL != "" {
+		config.SIEM.URL = siemURL
+	}
+	
+	if siemToken := os.Getenv("MCP_LOGGING_SIEM_TOKEN"); siemToken != "" {
+		config.SIEM.Token = siemToken
+	}
+	
+	if siemCAFile := os.Getenv("MCP_LOGGING_SIEM_CA_FILE"); siemCAFile != "" {
+		config.SIEM.CAFile = siemCAFile
+	}
+	
+	if kubernetesEvents := os.Getenv("MCP_LOGGING_KUBERNETES_EVENTS"); kubernetesEvents != "" {
+		if enabled, err := strconv.ParseBool(kubernetesEvents); err == nil {
+			config.Kubernetes.Events = enabled
+		}
+	}
+	
+	if kubernetesNamespace := os.Getenv("MCP_LOGGING_KUBERNETES_NAMESPACE"); kubernetesNamespace != "" {
+		config.Kubernetes.Namespace = kubernetesNamespace
+	}
+	
+	if journald := os.Getenv("MCP_LOGGING_JOURNALD"); journald != "" {
+		if enabled, err := strconv.ParseBool(journald); err == nil {
+			config.Journald.Enabled = enabled
+		}
+	}
+	
+	if journaldUnits := os.Getenv("MCP_LOGGING_JOURNALD_UNITS"); journaldUnits != "" {
+		config.Journald.Units = strings.Split(journaldUnits, ",")
+	}
+	
+	if queryAudit := os.Getenv("MCP_LOGGING_QUERY_AUDIT"); queryAudit != "" {
+		if enabled, err := strconv.ParseBool(queryAudit); err == nil {
+			config.QueryAudit.Enabled = enabled
+		}
+	}
+	
+	loadSecretsFromEnv(&config.Secrets)
+}
+
+// loadSecretsFromEnv overrides the secrets manager configuration with environment variables.
+// Provider settings use the variables of the respective vendor tools.
+func loadSecretsFromEnv(secrets *SecretsConfig) {
+	overrides := map[string]*string{
+		"MCP_LOGGING_SECRETS_PROVIDER":       &secrets.Provider,
+		"MCP_LOGGING_SECRETS_API_KEYS":       &secrets.APIKeys,
+		"MCP_LOGGING_SECRETS_TLS_CERT":       &secrets.TLSCert,
+		"MCP_LOGGING_SECRETS_TLS_KEY":        &secrets.TLSKey,
+		"MCP_LOGGING_SECRETS_HASH_SALT":      &secrets.HashSalt,
+		"MCP_LOGGING_SECRETS_ENCRYPTION_KEY": &secrets.EncryptionKey,
+		"VAULT_ADDR":                         &secrets.Vault.Address,
+		"VAULT_TOKEN":                        &secrets.Vault.Token,
+		"VAULT_NAMESPACE":                    &secrets.Vault.Namespace,
+		"AWS_REGION":                         &secrets.AWS.Region,
+		"AWS_ACCESS_KEY_ID":                  &secrets.AWS.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY":              &secrets.AWS.SecretAccessKey,
+		"AWS_SESSION_TOKEN":                  &secrets.AWS.SessionToken,
+		"GOOGLE_CLOUD_PROJECT":               &secrets.GCP.Project,
+		"GOOGLE_OAUTH_ACCESS_TOKEN":          &secrets.GCP.AccessToken,
+	}
+	for name, value := range overrides {
+		if env := os.Getenv(name); env != "" {
+			*value = env
+		}
+	}
+	
+	if refreshInterval := os.Getenv("MCP_LOGGING_SECRETS_REFRESH_INTERVAL"); refreshInterval != "" {
+		if d, err := time.ParseDuration(refreshInterval); err == nil {
+			secrets.RefreshInterval = d
+		}
+	}
 }
 
 // parsePort parses a port string to int with validation
@@ -16,17 +16,24 @@ type AuditAction struct {
 	Action        ActionType `json:"action"`
 	OriginalValue string     `json:"original_value,omitempty"`
 	NewValue      string     `json:"new_value,omitempty"`
+	WouldApply    ActionType `json:"would_apply,omitempty"` // Action a report-only processor did not apply
 }
 
 // AuditEntry represents a complete audit log entry
 type AuditEntry struct {
 	Timestamp        time.Time     `json:"timestamp"`
+	RequestID        string        `json:"request_id,omitempty"` // Ingestion request the entry arrived in
 	LogEntryID       string        `json:"log_entry_id"`
 	ServiceName      string        `json:"service_name"`
 	AgentID          string        `json:"agent_id"`
 	ActionsPerformed []AuditAction `json:"actions_performed"`
 }
 
+// AuditSink receives the audit entries of data protection actions as they happen
+type AuditSink interface {
+	RecordAuditEntry(entry AuditEntry)
+}
+
 // AuditLogger handles audit logging for data protection actions
 type AuditLogger struct {
 	logFile *os.File
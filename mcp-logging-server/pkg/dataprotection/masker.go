@@ -0,0 +1,54 @@
+package dataprotection
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Masker hides sensitive string values, keeping a few characters at both ends so that masked
+// values stay recognizable. It is shared by masking at ingestion and masking in MCP results.
+type Masker struct {
+	RevealChars       int    `yaml:"reveal_chars" json:"reveal_chars"`               // Characters kept at each end
+	Token             string `yaml:"token" json:"token"`                             // Replaces the hidden characters
+	RepeatToken       bool   `yaml:"repeat_token" json:"repeat_token"`               // Repeat the token for each hidden character instead of once
+	FullMaskThreshold int    `yaml:"full_mask_threshold" json:"full_mask_threshold"` // Values this long or shorter are hidden entirely
+}
+
+// CharMasker returns the masker of ingestion-time masking, replacing each hidden character
+// with maskChar and showing the first and last 2 characters of values longer than 4
+func CharMasker(maskChar string) Masker {
+	return Masker{RevealChars: 2, Token: maskChar, RepeatToken: true, FullMaskThreshold: 4}
+}
+
+// TokenMasker returns the masker of MCP result masking, replacing the hidden characters with a
+// single token and showing the first and last 2 characters of values longer than 4
+func TokenMasker(token string) Masker {
+	return Masker{RevealChars: 2, Token: token, FullMaskThreshold: 4}
+}
+
+// Mask hides the middle of a value, or all of it if it is too short to reveal any characters
+func (m Masker) Mask(value string) string {
+	length := utf8.RuneCountInString(value)
+	if length <= m.FullMaskThreshold || length <= 2*m.RevealChars {
+		return m.Redact(value)
+	}
+
+	// Reveal whole characters so that multi-byte characters are never split
+	runes := []rune(value)
+	prefix := string(runes[:m.RevealChars])
+	suffix := string(runes[length-m.RevealChars:])
+	return prefix + m.hide(length-2*m.RevealChars) + suffix
+}
+
+// Redact hides all of a value
+func (m Masker) Redact(value string) string {
+	return m.hide(utf8.RuneCountInString(value))
+}
+
+// hide returns the replacement of count hidden characters
+func (m Masker) hide(count int) string {
+	if m.RepeatToken {
+		return strings.Repeat(m.Token, count)
+	}
+	return m.Token
+}
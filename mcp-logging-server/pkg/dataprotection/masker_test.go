@@ -0,0 +1,52 @@
+package dataprotection
+
+import "testing"
+
+func TestMasker_Mask(t *testing.T) {
+	testCases := []struct {
+		name     string
+		masker   Masker
+		input    string
+		expected string
+	}{
+		{"char short", CharMasker("*"), "abcd", "****"},
+		{"char long", CharMasker("*"), "abcdef", "ab**ef"},
+		{"token short", TokenMasker("[MASKED]"), "abcd", "[MASKED]"},
+		{"token long", TokenMasker("[MASKED]"), "sensitive-data-123", "se[MASKED]23"},
+		{"token empty", TokenMasker("[MASKED]"), "", "[MASKED]"},
+		{"reveal more", Masker{RevealChars: 4, Token: "…", FullMaskThreshold: 8}, "1234567890", "1234…7890"},
+		{"reveal none", Masker{Token: "#", RepeatToken: true}, "secret", "######"},
+		{"threshold below reveal", Masker{RevealChars: 3, Token: "*", RepeatToken: true}, "abcdef", "******"},
+		{"multi-byte characters", CharMasker("*"), "żółwiątko", "żó*****ko"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if result := tc.masker.Mask(tc.input); result != tc.expected {
+				t.Errorf("Expected '%s' to be masked as '%s', got '%s'", tc.input, tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestDataProtectionProcessor_MaskingConfig(t *testing.T) {
+	processor, err := NewDataProtectionProcessor(&DataProtectionConfig{
+		Enabled:  true,
+		MaskChar: "*",
+		Masking:  &Masker{RevealChars: 1, Token: "[REDACTED]", FullMaskThreshold: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	if result := processor.maskValue("test", "password"); result != "p[REDACTED]d" {
+		t.Errorf("Expected masking setting to replace mask_char, got '%s'", result)
+	}
+
+	if err := processor.UpdateConfig(&DataProtectionConfig{Enabled: true, MaskChar: "#"}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if result := processor.maskValue("test", "password"); result != "pa####rd" {
+		t.Errorf("Expected mask_char masking after update, got '%s'", result)
+	}
+}
@@ -1,10 +1,12 @@
 package dataprotection
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
 )
 
 // DataProtectionMiddleware creates middleware for data protection
@@ -16,14 +18,15 @@ func DataProtectionMiddleware(processor *DataProtectionProcessor) gin.HandlerFun
 	}
 }
 
-// ProcessLogEntries processes a slice of log entries for data protection
-func ProcessLogEntries(processor *DataProtectionProcessor, entries []models.LogEntry) error {
+// ProcessLogEntries processes a slice of log entries for data protection, recording the ID of the
+// request carried by ctx in the audit entries
+func ProcessLogEntries(ctx context.Context, processor *DataProtectionProcessor, entries []models.LogEntry) error {
 	if processor == nil || !processor.GetConfig().Enabled {
 		return nil
 	}
 
 	for i := range entries {
-		if err := processor.ProcessLogEntry(&entries[i]); err != nil {
+		if err := processor.ProcessLogEntryContext(ctx, &entries[i]); err != nil {
 			return err
 		}
 	}
@@ -41,32 +44,23 @@ func GetProcessorFromContext(c *gin.Context) *DataProtectionProcessor {
 	return nil
 }
 
-// AdminDataProtectionMiddleware creates middleware for data protection admin endpoints
-func AdminDataProtectionMiddleware(processor *DataProtectionProcessor, statsCollector *AuditStatsCollector) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		switch c.Request.URL.Path {
-		case "/admin/data-protection/config":
-			if c.Request.Method == "GET" {
-				handleGetDataProtectionConfig(c, processor)
-				return
-			} else if c.Request.Method == "PUT" {
-				handleUpdateDataProtectionConfig(c, processor)
-				return
-			}
-		case "/admin/data-protection/stats":
-			if c.Request.Method == "GET" {
-				handleGetDataProtectionStats(c, statsCollector)
-				return
-			}
-		case "/admin/data-protection/test":
-			if c.Request.Method == "POST" {
-				handleTestDataProtection(c, processor)
-				return
-			}
-		}
-
-		c.Next()
-	}
+// RegisterAdminRoutes registers the data protection admin endpoints, relative to the
+// /admin/data-protection prefix. Viewing the configuration and stats and testing rules on sample
+// data are registered on read, changing the configuration on write, so that the two can require
+// different permissions.
+func RegisterAdminRoutes(read, write gin.IRoutes, processor *DataProtectionProcessor, statsCollector *AuditStatsCollector) {
+	read.GET("/config", func(c *gin.Context) {
+		handleGetDataProtectionConfig(c, processor)
+	})
+	read.GET("/stats", func(c *gin.Context) {
+		handleGetDataProtectionStats(c, statsCollector)
+	})
+	read.POST("/test", func(c *gin.Context) {
+		handleTestDataProtection(c, processor)
+	})
+	write.PUT("/config", func(c *gin.Context) {
+		handleUpdateDataProtectionConfig(c, processor)
+	})
 }
 
 // handleGetDataProtectionConfig returns current data protection configuration
@@ -81,18 +75,12 @@ func handleGetDataProtectionConfig(c *gin.Context, processor *DataProtectionProc
 func handleUpdateDataProtectionConfig(c *gin.Context, processor *DataProtectionProcessor) {
 	var newConfig DataProtectionConfig
 	if err := c.ShouldBindJSON(&newConfig); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid configuration",
-			"details": err.Error(),
-		})
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidConfiguration, "Invalid configuration", err.Error())
 		return
 	}
 
 	if err := processor.UpdateConfig(&newConfig); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Failed to update configuration",
-			"details": err.Error(),
-		})
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidConfiguration, "Failed to update configuration", err.Error())
 		return
 	}
 
@@ -105,9 +93,7 @@ func handleUpdateDataProtectionConfig(c *gin.Context, processor *DataProtectionP
 // handleGetDataProtectionStats returns data protection statistics
 func handleGetDataProtectionStats(c *gin.Context, statsCollector *AuditStatsCollector) {
 	if statsCollector == nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"error": "Statistics collection not enabled",
-		})
+		problem.Respond(c, http.StatusServiceUnavailable, problem.CodeNotSupported, "Statistics collection not enabled", "")
 		return
 	}
 
@@ -124,10 +110,7 @@ func handleTestDataProtection(c *gin.Context, processor *DataProtectionProcessor
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
 
@@ -137,10 +120,7 @@ func handleTestDataProtection(c *gin.Context, processor *DataProtectionProcessor
 
 	// Process the test entry
 	if err := processor.ProcessLogEntry(&testEntry); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to process log entry",
-			"details": err.Error(),
-		})
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeDataProtectionError, "Failed to process log entry", err.Error())
 		return
 	}
 
@@ -1,14 +1,17 @@
 package dataprotection
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
 )
 
 // ActionType represents the type of data protection action
@@ -18,8 +21,33 @@ const (
 	ActionMask ActionType = "mask"
 	ActionHash ActionType = "hash"
 	ActionDrop ActionType = "drop"
+
+	// ActionReport records matches in the audit log and statistics without modifying the entry
+	ActionReport ActionType = "report"
+)
+
+// Structured log entry fields that field rules can name besides metadata keys
+const (
+	FieldStackTrace       = "stack_trace"
+	FieldDeviceVersion    = "device_info.version"
+	FieldDeviceModel      = "device_info.model"
+	FieldDeviceAppVersion = "device_info.app_version"
+	FieldSourceFile       = "source_location.file"
+	FieldSourceFunction   = "source_location.function"
 )
 
+// contentPatterns are the sensitive values masked inside free text, i.e. messages and stack
+// traces without a field rule
+var contentPatterns = map[string]*regexp.Regexp{
+	"credit_card": regexp.MustCompile(`\b\d{4}[-\s]?\d{4}[-\s]?\d{4}[-\s]?\d{4}\b`),
+	"ssn":         regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	"email":       regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`),
+	"phone":       regexp.MustCompile(`\b\d{3}[-.]?\d{3}[-.]?\d{4}\b`),
+	"ip_address":  regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`),
+	// Credentials as they appear in panics and errors, e.g. "token=abc" or "Authorization: Bearer abc"
+	"credential": regexp.MustCompile(`(?i)\b(?:bearer\s+[A-Za-z0-9._~+/-]+=*|(?:token|api[_-]?key|password|passwd|secret)["']?\s*[=:]\s*["']?[^\s,;&"']+)`),
+}
+
 // FieldRule represents a rule for protecting a specific field
 type FieldRule struct {
 	Field   string     `yaml:"field" json:"field"`
@@ -27,6 +55,24 @@ type FieldRule struct {
 	Pattern string     `yaml:"pattern,omitempty" json:"pattern,omitempty"` // Regex pattern for partial matching
 }
 
+// ParseFieldRule parses a field rule given as "field" or "field:action", masking by default
+func ParseFieldRule(spec string) (FieldRule, error) {
+	field, action, hasAction := strings.Cut(strings.TrimSpace(spec), ":")
+	rule := FieldRule{Field: strings.TrimSpace(field), Action: ActionMask}
+	if rule.Field == "" {
+		return FieldRule{}, fmt.Errorf("field rule %q has no field", spec)
+	}
+	if hasAction {
+		rule.Action = ActionType(strings.ToLower(strings.TrimSpace(action)))
+		switch rule.Action {
+		case ActionMask, ActionHash, ActionDrop, ActionReport:
+		default:
+			return FieldRule{}, fmt.Errorf("field rule %q has unknown action %q, expected mask, hash, drop or report", spec, action)
+		}
+	}
+	return rule, nil
+}
+
 // DataProtectionConfig represents data protection configuration
 type DataProtectionConfig struct {
 	Enabled      bool        `yaml:"enabled" json:"enabled"`
@@ -35,7 +81,9 @@ type DataProtectionConfig struct {
 	HashFields   []string    `yaml:"hash_fields" json:"hash_fields"` // Deprecated: use FieldRules
 	DropFields   []string    `yaml:"drop_fields" json:"drop_fields"` // Deprecated: use FieldRules
 	MaskChar     string      `yaml:"mask_char" json:"mask_char"`
+	Masking      *Masker     `yaml:"masking,omitempty" json:"masking,omitempty"` // Replaces the mask_char masking when set
 	HashSalt     string      `yaml:"hash_salt" json:"hash_salt"`
+	ReportOnly   bool        `yaml:"report_only" json:"report_only"` // Record every action as a report without modifying entries
 	AuditEnabled bool        `yaml:"audit_enabled" json:"audit_enabled"`
 }
 
@@ -67,7 +115,10 @@ func DefaultDataProtectionConfig() *DataProtectionConfig {
 type DataProtectionProcessor struct {
 	config      *DataProtectionConfig
 	auditLogger *AuditLogger
+	sinks       []AuditSink
 	patterns    map[string]*regexp.Regexp
+	masker      Masker
+	saltMutex   sync.RWMutex // Guards config.HashSalt, which SetHashSalt may change while logs are processed
 }
 
 // NewDataProtectionProcessor creates a new data protection processor
@@ -79,6 +130,7 @@ func NewDataProtectionProcessor(config *DataProtectionConfig) (*DataProtectionPr
 	processor := &DataProtectionProcessor{
 		config:   config,
 		patterns: make(map[string]*regexp.Regexp),
+		masker:   configMasker(config),
 	}
 
 	// Compile regex patterns
@@ -100,43 +152,55 @@ func NewDataProtectionProcessor(config *DataProtectionConfig) (*DataProtectionPr
 	return processor, nil
 }
 
-// ProcessLogEntry processes a log entry according to data protection rules
+// ProcessLogEntry processes a log entry according to data protection rules. In report-only mode,
+// and for fields with the report action, the actions are recorded but the entry is left unchanged.
 func (p *DataProtectionProcessor) ProcessLogEntry(entry *models.LogEntry) error {
+	return p.ProcessLogEntryContext(context.Background(), entry)
+}
+
+// ProcessLogEntryContext processes a log entry like ProcessLogEntry, recording the ID of the
+// request carried by ctx in the audit entry
+func (p *DataProtectionProcessor) ProcessLogEntryContext(ctx context.Context, entry *models.LogEntry) error {
 	if !p.config.Enabled {
 		return nil
 	}
 
 	actionsPerformed := make([]AuditAction, 0)
+	auditing := p.auditLogger != nil || len(p.sinks) > 0
 
 	// Process metadata fields
-	if entry.Metadata != nil {
-		for field, value := range entry.Metadata {
-			action := p.getActionForField(field)
-			if action == "" {
-				continue
-			}
+	for field := range entry.Metadata {
+		var err error
+		if actionsPerformed, err = p.processMetadataField(entry, field, actionsPerformed); err != nil {
+			return err
+		}
+	}
 
-			originalValue := fmt.Sprintf("%v", value)
-			newValue, err := p.applyAction(field, originalValue, action)
-			if err != nil {
-				return fmt.Errorf("failed to apply action %s to field %s: %w", action, field, err)
-			}
+	// Process structured fields with a rule of their own
+	for _, field := range entryFields(entry) {
+		if *field.value == "" {
+			continue
+		}
+		action := p.getActionForField(field.name)
+		if action == "" {
+			continue
+		}
 
-			if action == ActionDrop {
-				delete(entry.Metadata, field)
-			} else {
-				entry.Metadata[field] = newValue
-			}
+		originalValue := *field.value
+		newValue, err := p.applyAction(field.name, originalValue, action)
+		if err != nil {
+			return fmt.Errorf("failed to apply action %s to field %s: %w", action, field.name, err)
+		}
+		processedValue := ""
+		if action != ActionDrop {
+			processedValue = fmt.Sprintf("%v", newValue)
+		}
+		if !p.reporting(action) {
+			*field.value = processedValue
+		}
 
-			// Record audit action
-			if p.auditLogger != nil {
-				actionsPerformed = append(actionsPerformed, AuditAction{
-					Field:         field,
-					Action:        action,
-					OriginalValue: originalValue,
-					NewValue:      fmt.Sprintf("%v", newValue),
-				})
-			}
+		if auditing {
+			actionsPerformed = append(actionsPerformed, p.auditAction(field.name, action, originalValue, processedValue))
 		}
 	}
 
@@ -144,26 +208,159 @@ func (p *DataProtectionProcessor) ProcessLogEntry(entry *models.LogEntry) error
 	if entry.Message != "" {
 		processedMessage, messageActions := p.processMessageContent(entry.Message)
 		if processedMessage != entry.Message {
-			entry.Message = processedMessage
+			if !p.config.ReportOnly {
+				entry.Message = processedMessage
+			}
 			actionsPerformed = append(actionsPerformed, messageActions...)
 		}
 	}
 
-	// Log audit information
-	if p.auditLogger != nil && len(actionsPerformed) > 0 {
-		auditEntry := AuditEntry{
-			Timestamp:        time.Now(),
-			LogEntryID:       entry.ID,
-			ServiceName:      entry.ServiceName,
-			AgentID:          entry.AgentID,
-			ActionsPerformed: actionsPerformed,
+	// Stack traces without a rule are scanned like messages, as panics often include credentials
+	if entry.StackTrace != "" && p.getActionForField(FieldStackTrace) == "" {
+		processedTrace, traceActions := p.processContent(FieldStackTrace, entry.StackTrace)
+		if processedTrace != entry.StackTrace {
+			if !p.config.ReportOnly {
+				entry.StackTrace = processedTrace
+			}
+			actionsPerformed = append(actionsPerformed, traceActions...)
 		}
-		p.auditLogger.LogAuditEntry(auditEntry)
 	}
 
+	p.audit(ctx, entry, actionsPerformed)
+	return nil
+}
+
+// ProcessMetadataContext processes the named metadata fields of a log entry like
+// ProcessLogEntryContext, such as fields set after the entry was processed
+func (p *DataProtectionProcessor) ProcessMetadataContext(ctx context.Context, entry *models.LogEntry, fields []string) error {
+	if !p.config.Enabled {
+		return nil
+	}
+
+	var actionsPerformed []AuditAction
+	for _, field := range fields {
+		var err error
+		if actionsPerformed, err = p.processMetadataField(entry, field, actionsPerformed); err != nil {
+			return err
+		}
+	}
+	p.audit(ctx, entry, actionsPerformed)
 	return nil
 }
 
+// processMetadataField applies the action of a metadata field, if the entry has the field and
+// a rule applies to it, and returns actions with the action appended
+func (p *DataProtectionProcessor) processMetadataField(entry *models.LogEntry, field string, actions []AuditAction) ([]AuditAction, error) {
+	value, ok := entry.Metadata[field]
+	if !ok {
+		return actions, nil
+	}
+	action := p.getActionForField(field)
+	if action == "" {
+		return actions, nil
+	}
+
+	originalValue := fmt.Sprintf("%v", value)
+	newValue, err := p.applyAction(field, originalValue, action)
+	if err != nil {
+		return actions, fmt.Errorf("failed to apply action %s to field %s: %w", action, field, err)
+	}
+
+	if !p.reporting(action) {
+		if action == ActionDrop {
+			delete(entry.Metadata, field)
+		} else {
+			entry.Metadata[field] = newValue
+		}
+	}
+
+	// Record audit action
+	if p.auditLogger != nil || len(p.sinks) > 0 {
+		actions = append(actions, p.auditAction(field, action, originalValue, fmt.Sprintf("%v", newValue)))
+	}
+	return actions, nil
+}
+
+// audit passes the actions performed on an entry to the audit logger and sinks
+func (p *DataProtectionProcessor) audit(ctx context.Context, entry *models.LogEntry, actions []AuditAction) {
+	if len(actions) == 0 || (p.auditLogger == nil && len(p.sinks) == 0) {
+		return
+	}
+	auditEntry := AuditEntry{
+		Timestamp:        time.Now(),
+		RequestID:        requestid.FromContext(ctx),
+		LogEntryID:       entry.ID,
+		ServiceName:      entry.ServiceName,
+		AgentID:          entry.AgentID,
+		ActionsPerformed: actions,
+	}
+	if p.auditLogger != nil {
+		p.auditLogger.LogAuditEntry(auditEntry)
+	}
+	for _, sink := range p.sinks {
+		sink.RecordAuditEntry(auditEntry)
+	}
+}
+
+// reporting reports whether an action is only recorded instead of applied
+func (p *DataProtectionProcessor) reporting(action ActionType) bool {
+	return action == ActionReport || p.config.ReportOnly
+}
+
+// auditAction records an action on a field, as a report of the action it would have applied
+// when the entry is left unchanged
+func (p *DataProtectionProcessor) auditAction(field string, action ActionType, originalValue, newValue string) AuditAction {
+	auditAction := AuditAction{
+		Field:         field,
+		Action:        action,
+		OriginalValue: originalValue,
+		NewValue:      newValue,
+	}
+	if action == ActionReport {
+		auditAction.NewValue = ""
+	} else if p.config.ReportOnly {
+		auditAction.Action = ActionReport
+		auditAction.WouldApply = action
+	}
+	return auditAction
+}
+
+// SetStatsCollector makes the processor record its actions in the statistics of a collector
+func (p *DataProtectionProcessor) SetStatsCollector(stats *AuditStatsCollector) {
+	p.AddAuditSink(stats)
+}
+
+// AddAuditSink makes the processor pass the audit entries of its actions to a sink, e.g. a SIEM
+// forwarder. Sinks must be added before entries are processed.
+func (p *DataProtectionProcessor) AddAuditSink(sink AuditSink) {
+	p.sinks = append(p.sinks, sink)
+}
+
+// entryField is a structured string field of a log entry
+type entryField struct {
+	name  string
+	value *string
+}
+
+// entryFields returns the structured fields of a log entry that field rules can apply to
+func entryFields(entry *models.LogEntry) []entryField {
+	fields := []entryField{{FieldStackTrace, &entry.StackTrace}}
+	if entry.DeviceInfo != nil {
+		fields = append(fields,
+			entryField{FieldDeviceVersion, &entry.DeviceInfo.Version},
+			entryField{FieldDeviceModel, &entry.DeviceInfo.Model},
+			entryField{FieldDeviceAppVersion, &entry.DeviceInfo.AppVersion},
+		)
+	}
+	if entry.SourceLocation != nil {
+		fields = append(fields,
+			entryField{FieldSourceFile, &entry.SourceLocation.File},
+			entryField{FieldSourceFunction, &entry.SourceLocation.Function},
+		)
+	}
+	return fields
+}
+
 // getActionForField determines the action to take for a specific field
 func (p *DataProtectionProcessor) getActionForField(field string) ActionType {
 	fieldLower := strings.ToLower(field)
@@ -219,7 +416,7 @@ func (p *DataProtectionProcessor) maskValue(field, value string) string {
 			groups := pattern.FindStringSubmatch(match)
 			if len(groups) > 1 {
 				// Mask the first capture group, keep the rest
-				masked := strings.Repeat(p.config.MaskChar, len(groups[1]))
+				masked := p.masker.Redact(groups[1])
 				result := strings.Replace(match, groups[1], masked, 1)
 				return result
 			}
@@ -230,53 +427,45 @@ func (p *DataProtectionProcessor) maskValue(field, value string) string {
 	return p.maskString(value)
 }
 
-// maskString masks a string with the configured mask character
+// maskString masks a string with the configured masker
 func (p *DataProtectionProcessor) maskString(value string) string {
-	if len(value) <= 4 {
-		return strings.Repeat(p.config.MaskChar, len(value))
-	}
-
-	// Show first and last 2 characters, mask the middle
-	prefix := value[:2]
-	suffix := value[len(value)-2:]
-	middle := strings.Repeat(p.config.MaskChar, len(value)-4)
+	return p.masker.Mask(value)
+}
 
-	return prefix + middle + suffix
+// configMasker returns the masker of a configuration, masking with mask_char unless set
+func configMasker(config *DataProtectionConfig) Masker {
+	if config.Masking != nil {
+		return *config.Masking
+	}
+	return CharMasker(config.MaskChar)
 }
 
 // hashValue creates a SHA-256 hash of the value with salt
 func (p *DataProtectionProcessor) hashValue(value string) string {
+	p.saltMutex.RLock()
 	saltedValue := value + p.config.HashSalt
+	p.saltMutex.RUnlock()
 	hash := sha256.Sum256([]byte(saltedValue))
 	return "sha256:" + hex.EncodeToString(hash[:])
 }
 
 // processMessageContent processes the message content for sensitive patterns
 func (p *DataProtectionProcessor) processMessageContent(message string) (string, []AuditAction) {
-	actions := make([]AuditAction, 0)
-	processedMessage := message
+	return p.processContent("message", message)
+}
 
-	// Common sensitive patterns
-	patterns := map[string]*regexp.Regexp{
-		"credit_card": regexp.MustCompile(`\b\d{4}[-\s]?\d{4}[-\s]?\d{4}[-\s]?\d{4}\b`),
-		"ssn":         regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
-		"email":       regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Z|a-z]{2,}\b`),
-		"phone":       regexp.MustCompile(`\b\d{3}[-.]?\d{3}[-.]?\d{4}\b`),
-		"ip_address":  regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`),
-	}
+// processContent masks the sensitive patterns in the free text of a field
+func (p *DataProtectionProcessor) processContent(field, content string) (string, []AuditAction) {
+	actions := make([]AuditAction, 0)
+	processedMessage := content
 
-	for patternName, pattern := range patterns {
+	for patternName, pattern := range contentPatterns {
 		matches := pattern.FindAllString(processedMessage, -1)
 		for _, match := range matches {
 			masked := p.maskString(match)
 			processedMessage = strings.Replace(processedMessage, match, masked, -1)
 
-			actions = append(actions, AuditAction{
-				Field:         "message:" + patternName,
-				Action:        ActionMask,
-				OriginalValue: match,
-				NewValue:      masked,
-			})
+			actions = append(actions, p.auditAction(field+":"+patternName, ActionMask, match, masked))
 		}
 	}
 
@@ -288,6 +477,13 @@ func (p *DataProtectionProcessor) GetConfig() *DataProtectionConfig {
 	return p.config
 }
 
+// SetHashSalt replaces the salt of hashed values, e.g. when it was reloaded from a secrets manager
+func (p *DataProtectionProcessor) SetHashSalt(salt string) {
+	p.saltMutex.Lock()
+	defer p.saltMutex.Unlock()
+	p.config.HashSalt = salt
+}
+
 // UpdateConfig updates the processor configuration
 func (p *DataProtectionProcessor) UpdateConfig(config *DataProtectionConfig) error {
 	// Recompile patterns
@@ -304,6 +500,7 @@ func (p *DataProtectionProcessor) UpdateConfig(config *DataProtectionConfig) err
 
 	p.config = config
 	p.patterns = patterns
+	p.masker = configMasker(config)
 
 	// Update audit logger
 	if config.AuditEnabled && p.auditLogger == nil {
@@ -1,10 +1,13 @@
 package dataprotection
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
 )
 
 func TestDataProtectionProcessor_ProcessLogEntry(t *testing.T) {
@@ -147,6 +150,27 @@ func TestDataProtectionProcessor_HashValue(t *testing.T) {
 	}
 }
 
+func TestDataProtectionProcessor_SetHashSalt(t *testing.T) {
+	processor, err := NewDataProtectionProcessor(&DataProtectionConfig{
+		Enabled:  true,
+		HashSalt: "old-salt",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	before := processor.hashValue("sensitive-data")
+	processor.SetHashSalt("new-salt")
+	after := processor.hashValue("sensitive-data")
+
+	if before == after {
+		t.Error("Hash should change with the salt")
+	}
+	if processor.GetConfig().HashSalt != "new-salt" {
+		t.Errorf("Expected salt new-salt, got %s", processor.GetConfig().HashSalt)
+	}
+}
+
 func TestDataProtectionProcessor_ProcessMessageContent(t *testing.T) {
 	config := &DataProtectionConfig{
 		Enabled:      true,
@@ -264,3 +288,247 @@ func TestDataProtectionProcessor_PatternMatching(t *testing.T) {
 		}
 	}
 }
+
+func TestDataProtectionProcessor_StructuredFields(t *testing.T) {
+	config := &DataProtectionConfig{
+		Enabled:  true,
+		MaskChar: "*",
+		HashSalt: "test-salt",
+		FieldRules: []FieldRule{
+			{Field: FieldDeviceModel, Action: ActionHash},
+			{Field: FieldDeviceAppVersion, Action: ActionDrop},
+			{Field: FieldSourceFile, Action: ActionMask, Pattern: `^/home/([^/]+)/`},
+		},
+		AuditEnabled: false,
+	}
+
+	processor, err := NewDataProtectionProcessor(config)
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	logEntry := &models.LogEntry{
+		ID:          "test-id",
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelError,
+		Message:     "Request failed",
+		ServiceName: "api-service",
+		AgentID:     "agent-001",
+		Platform:    models.PlatformGo,
+		DeviceInfo: &models.DeviceInfo{
+			Platform:   "ios",
+			Version:    "17.2",
+			Model:      "iPhone15,2",
+			AppVersion: "2.4.1",
+		},
+		SourceLocation: &models.SourceLocation{
+			File:     "/home/alice/api/handler.go",
+			Line:     42,
+			Function: "handleRequest",
+		},
+		StackTrace: "panic: request to https://api.example.com?token=abc123def failed\n\ngoroutine 1 [running]:\nmain.handleRequest()",
+	}
+
+	if err := processor.ProcessLogEntry(logEntry); err != nil {
+		t.Fatalf("Failed to process log entry: %v", err)
+	}
+
+	if !strings.HasPrefix(logEntry.DeviceInfo.Model, "sha256:") {
+		t.Errorf("Expected device model to be hashed, got '%s'", logEntry.DeviceInfo.Model)
+	}
+	if logEntry.DeviceInfo.AppVersion != "" {
+		t.Errorf("Expected app version to be dropped, got '%s'", logEntry.DeviceInfo.AppVersion)
+	}
+	if logEntry.DeviceInfo.Version != "17.2" {
+		t.Errorf("Expected device version without a rule to be kept, got '%s'", logEntry.DeviceInfo.Version)
+	}
+	if logEntry.SourceLocation.File != "/home/*****/api/handler.go" {
+		t.Errorf("Expected user directory to be masked, got '%s'", logEntry.SourceLocation.File)
+	}
+	if logEntry.SourceLocation.Function != "handleRequest" {
+		t.Errorf("Expected function without a rule to be kept, got '%s'", logEntry.SourceLocation.Function)
+	}
+
+	// Stack traces without a rule have the credentials in them masked
+	if strings.Contains(logEntry.StackTrace, "abc123def") {
+		t.Errorf("Expected token in stack trace to be masked, got '%s'", logEntry.StackTrace)
+	}
+	if !strings.Contains(logEntry.StackTrace, "goroutine 1 [running]") {
+		t.Errorf("Expected rest of stack trace to be kept, got '%s'", logEntry.StackTrace)
+	}
+}
+
+func TestDataProtectionProcessor_StackTraceRule(t *testing.T) {
+	processor, err := NewDataProtectionProcessor(&DataProtectionConfig{
+		Enabled:    true,
+		FieldRules: []FieldRule{{Field: FieldStackTrace, Action: ActionDrop}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+
+	logEntry := &models.LogEntry{Message: "Crash", StackTrace: "panic: token=abc123def"}
+	if err := processor.ProcessLogEntry(logEntry); err != nil {
+		t.Fatalf("Failed to process log entry: %v", err)
+	}
+	if logEntry.StackTrace != "" {
+		t.Errorf("Expected stack trace to be dropped, got '%s'", logEntry.StackTrace)
+	}
+}
+
+func TestParseFieldRule(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		expected FieldRule
+		wantErr  bool
+	}{
+		{"password", FieldRule{Field: "password", Action: ActionMask}, false},
+		{" device_info.model:hash ", FieldRule{Field: "device_info.model", Action: ActionHash}, false},
+		{"stack_trace:DROP", FieldRule{Field: "stack_trace", Action: ActionDrop}, false},
+		{"ssn:encrypt", FieldRule{}, true},
+		{":hash", FieldRule{}, true},
+	}
+
+	for _, tc := range testCases {
+		rule, err := ParseFieldRule(tc.spec)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseFieldRule(%q) error = %v, wantErr %v", tc.spec, err, tc.wantErr)
+			continue
+		}
+		if rule != tc.expected {
+			t.Errorf("ParseFieldRule(%q) = %+v, expected %+v", tc.spec, rule, tc.expected)
+		}
+	}
+}
+
+func TestDataProtectionProcessor_ReportOnly(t *testing.T) {
+	processor, err := NewDataProtectionProcessor(&DataProtectionConfig{
+		Enabled:    true,
+		MaskChar:   "*",
+		ReportOnly: true,
+		FieldRules: []FieldRule{
+			{Field: "password", Action: ActionMask},
+			{Field: "internal_id", Action: ActionDrop},
+			{Field: FieldDeviceModel, Action: ActionHash},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	stats := NewAuditStatsCollector()
+	processor.SetStatsCollector(stats)
+
+	logEntry := &models.LogEntry{
+		ID:          "test-id",
+		Message:     "Login from john.doe@example.com",
+		ServiceName: "auth-service",
+		DeviceInfo:  &models.DeviceInfo{Platform: "ios", Model: "iPhone15,2"},
+		Metadata: map[string]interface{}{
+			"password":    "secret123",
+			"internal_id": 12345,
+		},
+	}
+
+	if err := processor.ProcessLogEntry(logEntry); err != nil {
+		t.Fatalf("Failed to process log entry: %v", err)
+	}
+
+	// Nothing is modified
+	if logEntry.Metadata["password"] != "secret123" || logEntry.Metadata["internal_id"] != 12345 {
+		t.Errorf("Expected metadata to be unchanged, got %v", logEntry.Metadata)
+	}
+	if logEntry.DeviceInfo.Model != "iPhone15,2" {
+		t.Errorf("Expected device model to be unchanged, got '%s'", logEntry.DeviceInfo.Model)
+	}
+	if logEntry.Message != "Login from john.doe@example.com" {
+		t.Errorf("Expected message to be unchanged, got '%s'", logEntry.Message)
+	}
+
+	// But every match is counted as a report
+	result := stats.GetStats()
+	if result.TotalEntries != 1 {
+		t.Errorf("Expected 1 audited entry, got %d", result.TotalEntries)
+	}
+	if result.ActionCounts[ActionReport] != 4 {
+		t.Errorf("Expected 4 reported actions, got %v", result.ActionCounts)
+	}
+	for _, field := range []string{"password", "internal_id", FieldDeviceModel, "message:email"} {
+		if result.FieldCounts[field] != 1 {
+			t.Errorf("Expected field %s to be counted once, got %v", field, result.FieldCounts)
+		}
+	}
+}
+
+func TestDataProtectionProcessor_ReportAction(t *testing.T) {
+	processor, err := NewDataProtectionProcessor(&DataProtectionConfig{
+		Enabled:  true,
+		MaskChar: "*",
+		FieldRules: []FieldRule{
+			{Field: "password", Action: ActionMask},
+			{Field: "user_id", Action: ActionReport},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	stats := NewAuditStatsCollector()
+	processor.SetStatsCollector(stats)
+
+	logEntry := &models.LogEntry{
+		ID:      "test-id",
+		Message: "Login",
+		Metadata: map[string]interface{}{
+			"password": "secret123",
+			"user_id":  "user-42",
+		},
+	}
+	if err := processor.ProcessLogEntry(logEntry); err != nil {
+		t.Fatalf("Failed to process log entry: %v", err)
+	}
+
+	if logEntry.Metadata["password"] == "secret123" {
+		t.Error("Expected password to be masked")
+	}
+	if logEntry.Metadata["user_id"] != "user-42" {
+		t.Errorf("Expected reported field to be unchanged, got %v", logEntry.Metadata["user_id"])
+	}
+
+	result := stats.GetStats()
+	if result.ActionCounts[ActionMask] != 1 || result.ActionCounts[ActionReport] != 1 {
+		t.Errorf("Expected one mask and one report, got %v", result.ActionCounts)
+	}
+}
+
+// recordingSink keeps the audit entries it receives
+type recordingSink struct {
+	entries []AuditEntry
+}
+
+func (s *recordingSink) RecordAuditEntry(entry AuditEntry) {
+	s.entries = append(s.entries, entry)
+}
+
+func TestDataProtectionProcessor_AuditRequestID(t *testing.T) {
+	processor, err := NewDataProtectionProcessor(&DataProtectionConfig{
+		Enabled:    true,
+		MaskChar:   "*",
+		FieldRules: []FieldRule{{Field: "password", Action: ActionMask}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	sink := &recordingSink{}
+	processor.AddAuditSink(sink)
+
+	entries := []models.LogEntry{
+		{ID: "entry-1", Message: "Login", Metadata: map[string]interface{}{"password": "secret123"}},
+	}
+	ctx := requestid.NewContext(context.Background(), "request-1")
+	if err := ProcessLogEntries(ctx, processor, entries); err != nil {
+		t.Fatalf("Failed to process log entries: %v", err)
+	}
+
+	if len(sink.entries) != 1 || sink.entries[0].RequestID != "request-1" || sink.entries[0].LogEntryID != "entry-1" {
+		t.Errorf("Expected an audit entry for request-1, got %+v", sink.entries)
+	}
+}
@@ -0,0 +1,197 @@
+// Package expression evaluates the expressions of routing rules, such as
+// metadata.latency_ms > 500 && service_name == "api", against log entries. Expressions are
+// written in the language of github.com/expr-lang/expr, compiled once against the fields of an
+// entry and evaluated for each entry. They cannot call out of the server or change the entry,
+// and their size is limited.
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// MaxLength is the longest expression accepted, in bytes
+const MaxLength = 4096
+
+// maxNodes bounds the syntax tree of an expression
+const maxNodes = 1000
+
+// env is what expressions see of an entry. Fields an entry does not set are empty, and metadata
+// keys it does not have, like the keys of absent device_info and source_location, are nil.
+type env struct {
+	ID             string                 `expr:"id"`
+	Level          string                 `expr:"level"`
+	Severity       int                    `expr:"severity"` // 1 for DEBUG to 5 for FATAL
+	Message        string                 `expr:"message"`
+	ServiceName    string                 `expr:"service_name"`
+	AgentID        string                 `expr:"agent_id"`
+	Platform       string                 `expr:"platform"`
+	RequestID      string                 `expr:"request_id"`
+	TemplateID     string                 `expr:"template_id"`
+	StackTrace     string                 `expr:"stack_trace"`
+	ClockSkewed    bool                   `expr:"clock_skewed"`
+	Tags           []string               `expr:"tags"`
+	Metadata       map[string]interface{} `expr:"metadata"`
+	DeviceInfo     map[string]interface{} `expr:"device_info"`
+	SourceLocation map[string]interface{} `expr:"source_location"`
+}
+
+// newEnv returns the fields of an entry
+func newEnv(entry *models.LogEntry) env {
+	e := env{
+		ID:          entry.ID,
+		Level:       string(entry.Level),
+		Severity:    entry.Level.Severity(),
+		Message:     entry.Message,
+		ServiceName: entry.ServiceName,
+		AgentID:     entry.AgentID,
+		Platform:    string(entry.Platform),
+		RequestID:   entry.RequestID,
+		TemplateID:  entry.TemplateID,
+		StackTrace:  entry.StackTrace,
+		ClockSkewed: entry.ClockSkewed,
+		Tags:        entry.Tags,
+		Metadata:    entry.Metadata,
+	}
+	if entry.DeviceInfo != nil {
+		e.DeviceInfo = map[string]interface{}{
+			"platform":    entry.DeviceInfo.Platform,
+			"version":     entry.DeviceInfo.Version,
+			"model":       entry.DeviceInfo.Model,
+			"app_version": entry.DeviceInfo.AppVersion,
+		}
+	}
+	if entry.SourceLocation != nil {
+		e.SourceLocation = map[string]interface{}{
+			"file":     entry.SourceLocation.File,
+			"line":     entry.SourceLocation.Line,
+			"function": entry.SourceLocation.Function,
+		}
+	}
+	return e
+}
+
+// levelOrder rewrites the comparisons of the level field with <, <=, > and >= to compare
+// severities rather than names, so that level >= "WARN" matches warnings, errors and fatal
+// entries. The other side is a level name, converted by the severityOf function.
+type levelOrder struct{}
+
+// Visit implements ast.Visitor
+func (levelOrder) Visit(node *ast.Node) {
+	binary, ok := (*node).(*ast.BinaryNode)
+	if !ok {
+		return
+	}
+	switch binary.Operator {
+	case "<", "<=", ">", ">=":
+	default:
+		return
+	}
+	if !isLevel(binary.Left) && !isLevel(binary.Right) {
+		return
+	}
+	binary.Left, binary.Right = severityNode(binary.Left), severityNode(binary.Right)
+}
+
+// isLevel reports whether a node is the level field
+func isLevel(node ast.Node) bool {
+	identifier, ok := node.(*ast.IdentifierNode)
+	return ok && identifier.Value == "level"
+}
+
+// severityNode returns a node evaluating to the severity of a level node
+func severityNode(node ast.Node) ast.Node {
+	if isLevel(node) {
+		return &ast.IdentifierNode{Value: "severity"}
+	}
+	return &ast.CallNode{Callee: &ast.IdentifierNode{Value: "severityOf"}, Arguments: []ast.Node{node}}
+}
+
+// severityOf returns the severity of a level name compared with the level field
+func severityOf(params ...interface{}) (interface{}, error) {
+	name, _ := params[0].(string)
+	severity := models.LogLevel(name).Severity()
+	if severity == 0 {
+		return nil, fmt.Errorf("cannot compare a level with %q", params[0])
+	}
+	return severity, nil
+}
+
+// unbounded finds the ranges of an expression, such as 1..1000000, whose loops would take time
+// unrelated to the entry and the length of the expression
+type unbounded struct {
+	found bool
+}
+
+// Visit implements ast.Visitor
+func (u *unbounded) Visit(node *ast.Node) {
+	if binary, ok := (*node).(*ast.BinaryNode); ok && binary.Operator == ".." {
+		u.found = true
+	}
+}
+
+// options are the compile options of every expression. repeat is disabled for the same reason
+// as ranges.
+var options = []expr.Option{
+	expr.Env(env{}),
+	expr.MaxNodes(maxNodes),
+	expr.DisableBuiltin("repeat"),
+	expr.Function("severityOf", severityOf, new(func(string) int)),
+	expr.Patch(levelOrder{}),
+}
+
+// Expression is a compiled expression
+type Expression struct {
+	source  string
+	program *vm.Program
+}
+
+// Compile parses an expression and checks its fields, functions and types
+func Compile(source string) (*Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, errors.New("expression is empty")
+	}
+	if len(source) > MaxLength {
+		return nil, fmt.Errorf("expression is longer than %d bytes", MaxLength)
+	}
+
+	program, err := expr.Compile(source, options...)
+	if err != nil {
+		return nil, err
+	}
+	node := program.Node()
+	var ranges unbounded
+	if ast.Walk(&node, &ranges); ranges.found {
+		return nil, errors.New("ranges such as 1..10 are not supported")
+	}
+	return &Expression{source: source, program: program}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression for an entry
+func (e *Expression) Eval(entry *models.LogEntry) (interface{}, error) {
+	return expr.Run(e.program, newEnv(entry))
+}
+
+// Match evaluates a condition for an entry, it must evaluate to a bool
+func (e *Expression) Match(entry *models.LogEntry) (bool, error) {
+	value, err := expr.Run(e.program, newEnv(entry))
+	if err != nil {
+		return false, err
+	}
+	matched, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluated to %T, not a bool", value)
+	}
+	return matched, nil
+}
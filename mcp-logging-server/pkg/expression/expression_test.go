@@ -0,0 +1,171 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func testEntry() *models.LogEntry {
+	return &models.LogEntry{
+		ID:          "2f1c3a4e-8a6b-4c1d-9e2f-3a4b5c6d7e8f",
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelWarn,
+		Message:     "GET /api/orders took 812ms",
+		ServiceName: "api",
+		AgentID:     "agent-1",
+		Platform:    models.PlatformGo,
+		Tags:        []string{"http", "slow"},
+		Metadata: map[string]interface{}{
+			"latency_ms": 812,
+			"path":       "/api/orders",
+			"retry":      false,
+			"http":       map[string]interface{}{"status": float64(200)},
+			"user-id":    "u-42",
+		},
+	}
+}
+
+func TestExpression_Match(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`metadata.latency_ms > 500 && service_name == "api"`, true},
+		{`metadata.latency_ms > 1000 || service_name == "checkout"`, false},
+		{`level >= "WARN"`, true},
+		{`level > "WARN"`, false},
+		{`"ERROR" > level and level <= "FATAL"`, true},
+		{`level == "WARN" && severity == 3`, true},
+		{`level in ["ERROR", "FATAL"]`, false},
+		{`"slow" in tags && len(tags) == 2 && tags[0] == "http"`, true},
+		{`"path" in metadata && not ("trace_id" in metadata)`, true},
+		{`metadata.http.status >= 200 && metadata.http.status < 300`, true},
+		{`metadata["user-id"] == 'u-42'`, true},
+		{`metadata.trace_id != nil || metadata.missing?.nested == nil`, true},
+		{`metadata.path startsWith "/api" && message endsWith "ms" && lower(message) contains "get"`, true},
+		{`message matches "took [0-9]+ms$"`, true},
+		{`float("12.5") * 2 == 25 && string(metadata.latency_ms) == "812"`, true},
+		{`metadata.latency_ms / 1000 + 1 > 1.8 && -metadata.latency_ms % 100 == -12`, true},
+		{`"o" + "k" == "ok" && metadata.path contains "ord"`, true},
+		{`metadata.retry == false && !metadata.retry`, true},
+		{`request_id == "" && device_info?.model == nil`, true},
+		{`any(tags, # startsWith "sl")`, true},
+		{`service_name == "checkout" && metadata.missing > 1`, false}, // Short-circuits before the error
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := Compile(tt.expression)
+			if err != nil {
+				t.Fatalf("Failed to compile: %v", err)
+			}
+			matched, err := expression.Match(testEntry())
+			if err != nil {
+				t.Fatalf("Failed to evaluate: %v", err)
+			}
+			if matched != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, matched)
+			}
+		})
+	}
+}
+
+func TestExpression_EvalErrors(t *testing.T) {
+	tests := []string{
+		`metadata.missing > 500`,
+		`metadata.path > 5`,
+		`level >= "LOUD"`,
+		`metadata.latency_ms % 0 > 1`,
+		`metadata.missing.nested == nil`,
+		`message`,
+		`float(metadata.path) > 1`,
+	}
+
+	for _, source := range tests {
+		t.Run(source, func(t *testing.T) {
+			expression, err := Compile(source)
+			if err != nil {
+				t.Fatalf("Failed to compile: %v", err)
+			}
+			if _, err := expression.Match(testEntry()); err == nil {
+				t.Error("Expected an evaluation error")
+			}
+		})
+	}
+}
+
+func TestExpression_Eval(t *testing.T) {
+	expression, err := Compile(`metadata.latency_ms > 500 && level != "DEBUG"`)
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	if value, err := expression.Eval(testEntry()); err != nil || value != true {
+		t.Errorf("Expected true, got %v, %v", value, err)
+	}
+
+	expression, err = Compile(`upper(service_name) + "-" + level`)
+	if err != nil {
+		t.Fatalf("Failed to compile: %v", err)
+	}
+	if value, err := expression.Eval(testEntry()); err != nil || value != "API-WARN" {
+		t.Errorf("Expected API-WARN, got %v, %v", value, err)
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	tests := []string{
+		``,
+		`servicename == "api"`,
+		`service_name ==`,
+		`service_name == "api`,
+		`(service_name == "api"`,
+		`exec("rm")`,
+		`service_name && true`,
+		`message matches "["`,
+		`level >= 3`,
+		`all(1..1000000, # > 0)`,
+		`repeat(message, 1000000) != ""`,
+		`service_name == "api" extra`,
+		`metadata.`,
+		`1.2.3 > 1`,
+		`service_name # "api"`,
+		strings.Repeat("!", 2000) + "true",
+		strings.Repeat("1 + ", 1000) + "1 > 0",
+		`"` + strings.Repeat("a", MaxLength) + `" == message`,
+	}
+
+	for _, source := range tests {
+		name := source
+		if len(name) > 40 {
+			name = name[:40]
+		}
+		t.Run(name, func(t *testing.T) {
+			if _, err := Compile(source); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func FuzzCompile(f *testing.F) {
+	f.Add(`metadata.latency_ms > 500 && service_name == "api"`)
+	f.Add(`level >= "WARN" || "slow" in tags`)
+	f.Add(`metadata?.http?.status in [500, 503] and message matches "^GET"`)
+	f.Add(`upper(service_name) + "-" + level`)
+	f.Add(`len(message) % 7 == 0 ? message : metadata["user-id"]`)
+
+	// Expressions must compile or fail with an error, and compiled ones must evaluate with an error
+	// rather than panic
+	f.Fuzz(func(t *testing.T, source string) {
+		expression, err := Compile(source)
+		if err != nil {
+			return
+		}
+		expression.Eval(testEntry())
+		expression.Match(testEntry())
+		expression.Eval(&models.LogEntry{})
+	})
+}
@@ -0,0 +1,133 @@
+package ingestion
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/accesslog"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+)
+
+// rateLimitRemainingHeaders are the headers of the rate limits a request is counted against,
+// the key's limits first. Rejections by the key's limit use the API_KEY prefix.
+var rateLimitRemainingHeaders = []string{
+	"X-RateLimit-API-Key-Remaining",
+	"X-RateLimit-API_KEY-Remaining",
+	"X-RateLimit-IP-Remaining",
+}
+
+// countingBody counts the bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	read int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+// accessLogMiddleware counts the traffic of every request by API key and writes it to the access
+// log when one is configured
+func (s *Server) accessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		body := &countingBody{ReadCloser: c.Request.Body}
+		if c.Request.Body != nil {
+			c.Request.Body = body
+		}
+
+		c.Next()
+
+		keyID, keyName := "", ""
+		if keyInfo, ok := auth.GetAPIKeyInfo(c); ok {
+			keyID, keyName = keyInfo.ID, keyInfo.Name
+		}
+		bytesOut := int64(c.Writer.Size())
+		if bytesOut < 0 {
+			bytesOut = 0
+		}
+		tlsVersion, tlsCipher := metrics.PlainHTTP, ""
+		if state := c.Request.TLS; state != nil {
+			tlsVersion, tlsCipher = tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite)
+		}
+		status := c.Writer.Status()
+
+		metricsKey := keyID
+		if metricsKey == "" {
+			metricsKey = metrics.AnonymousKey
+		}
+		s.metrics.ObserveKeyRequest(metricsKey, tlsVersion, status >= http.StatusBadRequest, body.read, bytesOut)
+
+		if s.accessLog == nil {
+			return
+		}
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		record := accesslog.Record{
+			Time:       start.UTC(),
+			RequestID:  requestid.Get(c),
+			Method:     c.Request.Method,
+			Path:       path,
+			Status:     status,
+			Outcome:    accesslog.Outcome(status),
+			ErrorCode:  string(problem.CodeOf(c)),
+			DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			ClientIP:   c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			KeyID:      keyID,
+			KeyName:    keyName,
+			BytesIn:    body.read,
+			BytesOut:   bytesOut,
+			TLSCipher:  tlsCipher,
+		}
+		if c.Request.TLS != nil {
+			record.TLSVersion = tlsVersion
+		}
+		for _, header := range rateLimitRemainingHeaders {
+			if remaining, err := strconv.Atoi(c.Writer.Header().Get(header)); err == nil && remaining >= 0 {
+				record.RateLimitRemaining = &remaining
+				break
+			}
+		}
+		s.accessLog.Log(record)
+	}
+}
+
+// trackConnection counts the client connections of the ingestion server, as its
+// http.Server.ConnState
+func (s *Server) trackConnection(conn net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		s.metrics.ConnectionOpened()
+	case http.StateClosed, http.StateHijacked:
+		s.metrics.ConnectionClosed()
+	}
+}
+
+// handshakeErrorCounter is the error log of the ingestion server's http.Server. It counts the
+// TLS handshake failures the server reports there and passes every line on to the standard
+// logger.
+type handshakeErrorCounter struct {
+	metrics *metrics.Metrics
+}
+
+func (w *handshakeErrorCounter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte("TLS handshake error")) {
+		w.metrics.IncrementTLSHandshakeErrors()
+	}
+	return log.Writer().Write(p)
+}
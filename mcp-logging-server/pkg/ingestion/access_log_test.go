@@ -0,0 +1,97 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/accesslog"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
+	"github.com/kerlexov/mcp-logging-server/pkg/ratelimit"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_AccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	path := filepath.Join(t.TempDir(), "access.log")
+	accessLog, err := accesslog.New(accesslog.Config{Path: path})
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	edgeKey, _ := manager.CreateAPIKey("edge", []auth.Permission{auth.PermissionIngestLogs}, 0, nil)
+
+	server := NewServerWithOptions(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil, Options{AccessLog: accessLog})
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(requestid.Middleware(), server.accessLogMiddleware(), auth.AuthMiddleware(manager), ratelimit.RateLimitMiddleware(server.rateLimiter))
+	server.registerRoutes(router)
+
+	serve := func(apiKey, body string) {
+		req, _ := http.NewRequest("POST", "/v1/logs", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	entry := `{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "agent-1", "platform": "go"}`
+	serve(edgeKey, entry)
+	serve(edgeKey, `{"level": "INFO"}`)
+	serve("wrong-key", entry)
+	if err := accessLog.Close(); err != nil {
+		t.Fatalf("Failed to close access log: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open access log: %v", err)
+	}
+	defer file.Close()
+	var records []accesslog.Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record accesslog.Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid access log line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+
+	accepted := records[0]
+	if accepted.Status != http.StatusCreated || accepted.Outcome != accesslog.OutcomeOK || accepted.Path != "/v1/logs" || accepted.KeyName != "edge" || accepted.KeyID == "" || accepted.RequestID == "" {
+		t.Errorf("Unexpected record of the accepted request: %+v", accepted)
+	}
+	if accepted.BytesIn != int64(len(entry)) || accepted.BytesOut == 0 || accepted.RateLimitRemaining == nil || accepted.TLSVersion != "" {
+		t.Errorf("Unexpected traffic of the accepted request: %+v", accepted)
+	}
+	if invalid := records[1]; invalid.Outcome != accesslog.OutcomeRejected || invalid.ErrorCode == "" || invalid.KeyName != "edge" {
+		t.Errorf("Unexpected record of the invalid entry: %+v", invalid)
+	}
+	if unauthorized := records[2]; unauthorized.Outcome != accesslog.OutcomeUnauthorized || unauthorized.ErrorCode != "INVALID_API_KEY" || unauthorized.KeyID != "" {
+		t.Errorf("Unexpected record of the unauthorized request: %+v", unauthorized)
+	}
+
+	traffic := server.metrics.GetSnapshot().KeyTraffic
+	if key := traffic[accepted.KeyID]; key.Requests != 2 || key.Rejected != 1 || key.TLSVersions[metrics.PlainHTTP] != 2 {
+		t.Errorf("Unexpected traffic of the key: %+v", key)
+	}
+	if anonymous := traffic[metrics.AnonymousKey]; anonymous.Requests != 1 || anonymous.Rejected != 1 {
+		t.Errorf("Unexpected anonymous traffic: %+v", anonymous)
+	}
+}
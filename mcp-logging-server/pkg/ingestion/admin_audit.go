@@ -0,0 +1,53 @@
+package ingestion
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+	"github.com/kerlexov/mcp-logging-server/pkg/siem"
+)
+
+// adminAuditMiddleware forwards every admin API request to the SIEM once it has been handled,
+// with the API key that made it and whether it succeeded
+func (s *Server) adminAuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		status := c.Writer.Status()
+
+		event := siem.Event{
+			Time:     time.Now().UTC(),
+			Category: siem.CategoryAdmin,
+			Name:     c.Request.Method + " " + path,
+			Severity: 3,
+			Source:   c.ClientIP(),
+			Outcome:  "success",
+			Fields: map[string]string{
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+				"status":     strconv.Itoa(status),
+				"request_id": requestid.Get(c),
+			},
+		}
+		if keyInfo, ok := auth.GetAPIKeyInfo(c); ok {
+			event.Actor = keyInfo.ID
+		}
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			event.Severity = 5
+		}
+		if status >= http.StatusBadRequest {
+			event.Severity = 7
+			event.Outcome = "failure"
+		}
+
+		s.siem.Send(event)
+	}
+}
@@ -0,0 +1,60 @@
+package ingestion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/siem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_AdminAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	adminKey, _ := manager.CreateAPIKey("admin", []auth.Permission{auth.PermissionAdmin}, 0, nil)
+	edgeKey, _ := manager.CreateAPIKey("edge", []auth.Permission{auth.PermissionIngestLogs}, 0, nil)
+
+	forwarder, err := siem.NewForwarder(siem.Config{Protocol: siem.ProtocolHTTPS, URL: "https://siem.example.com"})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	server := NewServerWithOptions(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil, Options{SIEM: forwarder})
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	serve := func(method, url, apiKey string) int {
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := serve("GET", "/admin/usage", adminKey); code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := serve("POST", "/admin/flush", edgeKey); code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d", http.StatusForbidden, code)
+	}
+	if stats := forwarder.Stats(); stats.Queued != 2 {
+		t.Fatalf("Expected 2 queued events, got %+v", stats)
+	}
+
+	// Requests outside the admin API are not forwarded
+	if code := serve("GET", "/health", edgeKey); code == http.StatusForbidden {
+		t.Fatalf("Expected health check to be allowed")
+	}
+	if stats := forwarder.Stats(); stats.Queued != 2 {
+		t.Errorf("Expected 2 queued events, got %+v", stats)
+	}
+}
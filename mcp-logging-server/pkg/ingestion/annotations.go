@@ -0,0 +1,65 @@
+package ingestion
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// annotateLogRequest is the body of requests annotating a log entry
+type annotateLogRequest struct {
+	Text   string `json:"text" binding:"required"`
+	Author string `json:"author"` // Defaults to the name of the API key
+}
+
+// annotationsResponse lists the annotations of a log entry after adding one
+type annotationsResponse struct {
+	ID          string                 `json:"id"`
+	Annotations []models.LogAnnotation `json:"annotations"`
+}
+
+// handleAnnotateLog handles requests appending an operator annotation to a log entry
+func (s *Server) handleAnnotateLog(c *gin.Context) {
+	annotator, ok := s.storage.(storage.LogAnnotator)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support annotations", "")
+		return
+	}
+
+	var request annotateLogRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	annotation := models.LogAnnotation{Text: request.Text, Author: request.Author}
+	if annotation.Author == "" {
+		if keyInfo, ok := auth.GetAPIKeyInfo(c); ok {
+			annotation.Author = keyInfo.Name
+		}
+	}
+
+	validationResult := s.validator.ValidateAnnotation(&annotation)
+	if !validationResult.IsValid {
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Annotation validation failed", validationResult.Errors)
+		return
+	}
+
+	id := c.Param("id")
+	annotations, err := annotator.AnnotateLog(c.Request.Context(), id, annotation)
+	if errors.Is(err, storage.ErrLogNotFound) {
+		problem.Respond(c, http.StatusNotFound, problem.CodeLogNotFound, "Log entry does not exist", id)
+		return
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to annotate log entry", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotationsResponse{ID: id, Annotations: annotations})
+}
@@ -0,0 +1,71 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_AnnotateLog(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	entry := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now().UTC(),
+		Level:       models.LogLevelError,
+		Message:     "Payment declined",
+		ServiceName: "billing-service",
+		AgentID:     "test-agent",
+		Platform:    models.PlatformGo,
+	}
+	if err := memoryStorage.Store(context.Background(), []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+
+	router := newServiceRegistryTestRouter(t, memoryStorage)
+
+	serve := func(url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", url, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	url := "/v1/logs/" + entry.ID + "/annotations"
+	for _, body := range []string{`{"text": 5}`, `{"author": "oncall"}`, `{"text": "   "}`} {
+		if w := serve(url, body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d: %s", http.StatusBadRequest, body, w.Code, w.Body.String())
+		}
+	}
+
+	if w := serve("/v1/logs/"+uuid.New().String()+"/annotations", `{"text": "known issue"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	w := serve(url, `{"text": "known issue JIRA-123", "author": "oncall"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response annotationsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.ID != entry.ID || len(response.Annotations) != 1 || response.Annotations[0].Author != "oncall" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+
+	logs, _ := memoryStorage.GetByIDs(context.Background(), []string{entry.ID})
+	if len(logs) != 1 || len(logs[0].Annotations) != 1 || logs[0].Message != entry.Message {
+		t.Errorf("Expected the annotation to be stored next to the unchanged entry, got %+v", logs)
+	}
+}
@@ -0,0 +1,77 @@
+package ingestion
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/ratelimit"
+)
+
+// apiKeyResponse is an API key as listed by the admin API, with its requests since the last restart
+type apiKeyResponse struct {
+	auth.APIKeyInfo
+	Signed bool                   `json:"signed"`
+	Usage  *ratelimit.APIKeyUsage `json:"usage,omitempty"`
+}
+
+// handleListAPIKeys handles requests listing the API keys with their rate limits and usage
+func (s *Server) handleListAPIKeys(c *gin.Context) {
+	usage := s.rateLimiter.GetAPIKeyUsage()
+
+	keys := s.authManager.ListAPIKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Name < keys[j].Name
+	})
+
+	response := make([]apiKeyResponse, len(keys))
+	for i, keyInfo := range keys {
+		response[i] = apiKeyResponse{
+			APIKeyInfo: keyInfo,
+			Signed:     keyInfo.SigningSecret != "",
+		}
+		if keyUsage, ok := usage[keyInfo.ID]; ok {
+			response[i].Usage = &keyUsage
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys":    response,
+		"total_count": len(response),
+	})
+}
+
+// handleSetAPIKeyRateLimit handles requests changing the rate limit of an API key. The new limit
+// applies from the key's next request and is saved to the API key file.
+func (s *Server) handleSetAPIKeyRateLimit(c *gin.Context) {
+	var request struct {
+		RateLimit *int `json:"rate_limit" binding:"required,min=0"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	keyInfo, err := s.authManager.SetRateLimit(c.Param("id"), *request.RateLimit)
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		problem.Respond(c, http.StatusNotFound, problem.CodeAPIKeyNotFound, "API key not found", "")
+		return
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Invalid rate limit", err.Error())
+		return
+	}
+
+	if err := s.authManager.Save(); err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeConfigSaveError, "Rate limit changed but not saved, it is lost on restart", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKeyResponse{
+		APIKeyInfo: *keyInfo,
+		Signed:     keyInfo.SigningSecret != "",
+	})
+}
@@ -0,0 +1,111 @@
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/ratelimit"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_APIKeyRateLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	configPath := filepath.Join(t.TempDir(), "api-keys.yaml")
+	manager.SetConfigPath(configPath)
+	adminKey, _ := manager.CreateAPIKey("admin", []auth.Permission{auth.PermissionAdmin}, 0, nil)
+	edgeKey, _ := manager.CreateAPIKey("edge", []auth.Permission{auth.PermissionIngestLogs}, 1, nil)
+
+	rateLimitConfig := &ratelimit.RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60000000, // Keeps the per-IP limit out of the way
+		BurstSize:         1,
+		CleanupInterval:   time.Minute,
+		BlockDuration:     time.Minute,
+		MaxViolations:     100,
+	}
+	server := NewServer(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, rateLimitConfig, nil, nil, nil)
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	router.Use(ratelimit.RateLimitMiddleware(server.rateLimiter))
+	server.registerRoutes(router)
+
+	serve := func(method, url, apiKey string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	logBody := []byte(`{"level": "INFO", "message": "Rate limited", "service_name": "edge-service", "agent_id": "edge-1", "platform": "go"}`)
+
+	// The edge key's own limit of one request per minute applies, not the global one
+	if w := serve("POST", "/v1/logs", edgeKey, logBody); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if w := serve("POST", "/v1/logs", edgeKey, logBody); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusTooManyRequests, w.Code, w.Body.String())
+	}
+
+	w := serve("GET", "/admin/api-keys", adminKey, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var listed struct {
+		APIKeys []apiKeyResponse `json:"api_keys"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(listed.APIKeys) != 2 || listed.APIKeys[1].Name != "edge" {
+		t.Fatalf("Unexpected API keys %+v", listed.APIKeys)
+	}
+	edge := listed.APIKeys[1]
+	if edge.ID != manager.KeyID(edgeKey) || edge.Usage == nil || edge.Usage.Allowed != 1 || edge.Usage.Rejected != 1 {
+		t.Errorf("Expected the edge key's usage, got %+v", edge)
+	}
+
+	invalid := []struct {
+		id     string
+		body   string
+		status int
+	}{
+		{id: edge.ID, body: `{}`, status: http.StatusBadRequest},
+		{id: edge.ID, body: `{"rate_limit": -1}`, status: http.StatusBadRequest},
+		{id: "unknown", body: `{"rate_limit": 10}`, status: http.StatusNotFound},
+	}
+	for _, tt := range invalid {
+		if w := serve("PUT", "/admin/api-keys/"+tt.id+"/rate-limit", adminKey, []byte(tt.body)); w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tt.id, tt.body, tt.status, w.Code, w.Body.String())
+		}
+	}
+
+	// Raising the limit applies to the key's next request and is saved
+	if w := serve("PUT", "/admin/api-keys/"+edge.ID+"/rate-limit", adminKey, []byte(`{"rate_limit": 6000}`)); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := serve("POST", "/v1/logs", edgeKey, logBody); w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d after raising the limit, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	saved, err := auth.LoadAPIKeyConfig(configPath)
+	if err != nil {
+		t.Fatalf("Failed to load saved config: %v", err)
+	}
+	if saved.APIKeys[manager.HashAPIKey(edgeKey)].RateLimit != 6000 {
+		t.Errorf("Expected the new rate limit to be saved, got %+v", saved.APIKeys[manager.HashAPIKey(edgeKey)])
+	}
+}
@@ -0,0 +1,118 @@
+package ingestion
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BatchStatus represents the delivery state of an asynchronously ingested batch
+type BatchStatus string
+
+const (
+	BatchStatusPending BatchStatus = "pending"
+	BatchStatusStored  BatchStatus = "stored"
+	BatchStatusFailed  BatchStatus = "failed"
+)
+
+// BatchRecord contains the delivery state of an asynchronously ingested batch
+type BatchRecord struct {
+	Token       string      `json:"token"`
+	Status      BatchStatus `json:"status"`
+	EntryCount  int         `json:"entry_count"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// BatchTracker keeps track of asynchronously ingested batches so clients can
+// poll for end-to-end delivery confirmation
+type BatchTracker struct {
+	mutex   sync.RWMutex
+	batches map[string]*BatchRecord
+	ttl     time.Duration
+}
+
+// NewBatchTracker creates a new batch tracker that forgets completed batches after ttl
+func NewBatchTracker(ttl time.Duration) *BatchTracker {
+	return &BatchTracker{
+		batches: make(map[string]*BatchRecord),
+		ttl:     ttl,
+	}
+}
+
+// Register creates a new pending batch record and returns its token
+func (bt *BatchTracker) Register(entryCount int) string {
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	token := uuid.New().String()
+	bt.batches[token] = &BatchRecord{
+		Token:      token,
+		Status:     BatchStatusPending,
+		EntryCount: entryCount,
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	return token
+}
+
+// MarkStored marks a batch as durably stored
+func (bt *BatchTracker) MarkStored(token string) {
+	bt.complete(token, BatchStatusStored, nil)
+}
+
+// MarkFailed marks a batch as failed with the given error
+func (bt *BatchTracker) MarkFailed(token string, err error) {
+	bt.complete(token, BatchStatusFailed, err)
+}
+
+// Get returns a copy of the batch record for the given token
+func (bt *BatchTracker) Get(token string) (BatchRecord, bool) {
+	bt.mutex.RLock()
+	defer bt.mutex.RUnlock()
+
+	record, exists := bt.batches[token]
+	if !exists {
+		return BatchRecord{}, false
+	}
+
+	return *record, true
+}
+
+// Cleanup removes completed batch records older than the tracker's ttl
+func (bt *BatchTracker) Cleanup() int {
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	cutoff := time.Now().Add(-bt.ttl)
+	removed := 0
+
+	for token, record := range bt.batches {
+		if record.CompletedAt != nil && record.CompletedAt.Before(cutoff) {
+			delete(bt.batches, token)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// complete records the final state of a batch
+func (bt *BatchTracker) complete(token string, status BatchStatus, err error) {
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	record, exists := bt.batches[token]
+	if !exists {
+		return
+	}
+
+	now := time.Now().UTC()
+	record.Status = status
+	record.CompletedAt = &now
+	if err != nil {
+		record.Error = err.Error()
+	}
+}
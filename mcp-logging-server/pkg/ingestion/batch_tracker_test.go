@@ -0,0 +1,84 @@
+package ingestion
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBatchTracker_Lifecycle(t *testing.T) {
+	tracker := NewBatchTracker(time.Hour)
+
+	token := tracker.Register(3)
+	if token == "" {
+		t.Fatal("Expected non-empty token")
+	}
+
+	record, exists := tracker.Get(token)
+	if !exists {
+		t.Fatal("Expected batch to be tracked")
+	}
+	if record.Status != BatchStatusPending {
+		t.Errorf("Expected status %s, got %s", BatchStatusPending, record.Status)
+	}
+	if record.EntryCount != 3 {
+		t.Errorf("Expected entry count 3, got %d", record.EntryCount)
+	}
+
+	tracker.MarkStored(token)
+
+	record, _ = tracker.Get(token)
+	if record.Status != BatchStatusStored {
+		t.Errorf("Expected status %s, got %s", BatchStatusStored, record.Status)
+	}
+	if record.CompletedAt == nil {
+		t.Error("Expected completed_at to be set")
+	}
+}
+
+func TestBatchTracker_MarkFailed(t *testing.T) {
+	tracker := NewBatchTracker(time.Hour)
+
+	token := tracker.Register(1)
+	tracker.MarkFailed(token, errors.New("storage unavailable"))
+
+	record, _ := tracker.Get(token)
+	if record.Status != BatchStatusFailed {
+		t.Errorf("Expected status %s, got %s", BatchStatusFailed, record.Status)
+	}
+	if record.Error != "storage unavailable" {
+		t.Errorf("Expected error to be recorded, got %q", record.Error)
+	}
+}
+
+func TestBatchTracker_Cleanup(t *testing.T) {
+	tracker := NewBatchTracker(10 * time.Millisecond)
+
+	completed := tracker.Register(1)
+	pending := tracker.Register(1)
+	tracker.MarkStored(completed)
+
+	time.Sleep(20 * time.Millisecond)
+
+	if removed := tracker.Cleanup(); removed != 1 {
+		t.Errorf("Expected 1 removed batch, got %d", removed)
+	}
+
+	if _, exists := tracker.Get(completed); exists {
+		t.Error("Expected completed batch to be removed")
+	}
+	if _, exists := tracker.Get(pending); !exists {
+		t.Error("Expected pending batch to be kept")
+	}
+}
+
+func TestBatchTracker_UnknownToken(t *testing.T) {
+	tracker := NewBatchTracker(time.Hour)
+
+	if _, exists := tracker.Get("missing"); exists {
+		t.Error("Expected unknown token to be reported as missing")
+	}
+
+	// Completing an unknown token must be a no-op
+	tracker.MarkStored("missing")
+}
@@ -0,0 +1,103 @@
+package ingestion
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// CapabilitiesPath is the public path the capabilities of the server are served at
+const CapabilitiesPath = "/v1/capabilities"
+
+// MaxBatchSize is the largest batch the batch endpoints accept
+const MaxBatchSize = 1000
+
+// contentEncodings are the request body encodings the ingestion endpoints accept
+var contentEncodings = []string{"identity"}
+
+// capabilitiesResponse describes what this server supports, so that SDKs and clients can adapt
+// instead of probing endpoints that may not be enabled
+type capabilitiesResponse struct {
+	APIVersion       string       `json:"api_version"`
+	MaxBatchSize     int          `json:"max_batch_size"`    // Entries per request to the batch endpoints
+	ContentEncodings []string     `json:"content_encodings"` // Accepted Content-Encoding values of request bodies
+	Platforms        []string     `json:"platforms"`         // Accepted platforms of log entries
+	Features         featureFlags `json:"features"`
+
+	// Mode is normal, or read_only or maintenance while the server rejects ingestion
+	Mode maintenance.Mode `json:"mode"`
+}
+
+// featureFlags report the optional subsystems that are enabled
+type featureFlags struct {
+	Search          bool `json:"search"`           // Full-text search at /v1/search
+	Annotations     bool `json:"annotations"`      // Annotating entries at /v1/logs/{id}/annotations
+	Incidents       bool `json:"incidents"`        // Incidents at /v1/incidents
+	ServiceRegistry bool `json:"service_registry"` // Registering services at /v1/services
+	Sync            bool `json:"sync"`             // Resumable uploads at /v1/logs/sync
+	Symbolication   bool `json:"symbolication"`    // Symbolicating crash reports and stack traces
+	LegalHolds      bool `json:"legal_holds"`      // Protecting entries from deletion
+	Integrity       bool `json:"integrity"`        // Storing content hashes to verify entries against
+	Archival        bool `json:"archival"`         // Continuous archiving of the database to an object store
+	Replication     bool `json:"replication"`      // Replicating stored entries to peer servers
+	Sharding        bool `json:"sharding"`         // Routing services to the writer nodes owning them
+	Routing         bool `json:"routing"`          // Routing rules that may tag, drop, re-level or redirect entries
+}
+
+// searchToggle is implemented by storages whose full-text search can be disabled
+type searchToggle interface {
+	SearchEnabled() bool
+}
+
+// integrityToggle is implemented by storages whose content hashing can be disabled
+type integrityToggle interface {
+	IntegrityHashing() bool
+}
+
+// capabilities returns what this server supports. Features the storage provides are enabled
+// when it implements their interface, unless it reports them disabled.
+func (s *Server) capabilities() capabilitiesResponse {
+	_, search := s.storage.(storage.LogSearcher)
+	if toggle, ok := s.storage.(searchToggle); ok && search {
+		search = toggle.SearchEnabled()
+	}
+	_, integrity := s.storage.(storage.IntegrityVerifier)
+	if toggle, ok := s.storage.(integrityToggle); ok && integrity {
+		integrity = toggle.IntegrityHashing()
+	}
+	_, annotations := s.storage.(storage.LogAnnotator)
+	_, incidents := s.storage.(storage.IncidentStore)
+	_, registry := s.storage.(storage.ServiceRegistry)
+	_, sync := s.storage.(storage.SyncStateStore)
+	_, symbols := s.storage.(storage.SymbolFileStore)
+	_, legalHolds := s.storage.(storage.LegalHoldManager)
+
+	return capabilitiesResponse{
+		APIVersion:       apiInfo.Version,
+		MaxBatchSize:     MaxBatchSize,
+		ContentEncodings: contentEncodings,
+		Platforms:        s.validator.Platforms(),
+		Features: featureFlags{
+			Search:          search,
+			Annotations:     annotations,
+			Incidents:       incidents,
+			ServiceRegistry: registry,
+			Sync:            sync,
+			Symbolication:   symbols || len(s.symbolicators) > 0,
+			LegalHolds:      legalHolds,
+			Integrity:       integrity,
+			Archival:        s.archival,
+			Replication:     s.replicator != nil,
+			Sharding:        s.router != nil,
+			Routing:         len(s.routing.Rules()) > 0,
+		},
+		Mode: s.maintenance.State().Mode,
+	}
+}
+
+// handleCapabilities describes the enabled optional subsystems and limits of the server
+func (s *Server) handleCapabilities(c *gin.Context) {
+	c.JSON(http.StatusOK, s.capabilities())
+}
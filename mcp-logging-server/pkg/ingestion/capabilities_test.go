@@ -0,0 +1,78 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_Capabilities(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	store := storage.NewMemoryStorage()
+	server := NewServerWithOptions(8080, store, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil, Options{Platforms: []string{"swift", "go"}, Archival: true})
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	// Clients ask before they have a key
+	req, _ := http.NewRequest("GET", CapabilitiesPath, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var capabilities capabilitiesResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &capabilities); err != nil {
+		t.Fatalf("Failed to parse capabilities: %v", err)
+	}
+	if capabilities.MaxBatchSize != MaxBatchSize || len(capabilities.ContentEncodings) != 1 || capabilities.ContentEncodings[0] != "identity" {
+		t.Errorf("Unexpected limits: %+v", capabilities)
+	}
+	if len(capabilities.Platforms) != 2 || capabilities.Platforms[0] != "go" {
+		t.Errorf("Expected the configured platforms, got %v", capabilities.Platforms)
+	}
+
+	// The memory storage has no search index or content hashes, but keeps incidents and sync state
+	features := capabilities.Features
+	if features.Search || features.Integrity || features.Replication || features.Sharding || features.Routing {
+		t.Errorf("Expected search, integrity, replication, sharding and routing disabled, got %+v", features)
+	}
+	if !features.Incidents || !features.Sync || !features.Annotations || !features.Archival {
+		t.Errorf("Expected incidents, sync, annotations and archival enabled, got %+v", features)
+	}
+}
+
+func TestServer_CapabilitiesSQLite(t *testing.T) {
+	sqliteStorage, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "capabilities.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	server := NewServer(8080, sqliteStorage, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+	defer server.rateLimiter.Stop()
+
+	// The SQLite storage implements search and integrity checks, neither is configured
+	if features := server.capabilities().Features; features.Search || features.Integrity || !features.LegalHolds {
+		t.Errorf("Expected search and integrity disabled and legal holds enabled, got %+v", features)
+	}
+	sqliteStorage.SetIntegrityHashing(true, "")
+	if features := server.capabilities().Features; !features.Integrity {
+		t.Error("Expected integrity enabled with content hashing")
+	}
+}
@@ -0,0 +1,63 @@
+package ingestion
+
+import (
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// SkewAction represents what to do with entries whose timestamp is too far from the receive time
+type SkewAction string
+
+const (
+	// SkewActionClamp replaces the client timestamp with the server receive time
+	SkewActionClamp SkewAction = "clamp"
+	// SkewActionFlag keeps the client timestamp but marks the entry as clock skewed
+	SkewActionFlag SkewAction = "flag"
+)
+
+// ClockSkewConfig represents client clock skew correction configuration
+type ClockSkewConfig struct {
+	MaxSkew time.Duration `yaml:"max_skew" json:"max_skew"` // 0 disables skew correction
+	Action  SkewAction    `yaml:"action" json:"action"`
+}
+
+// DefaultClockSkewConfig returns default clock skew configuration
+func DefaultClockSkewConfig() *ClockSkewConfig {
+	return &ClockSkewConfig{
+		MaxSkew: 0,
+		Action:  SkewActionFlag,
+	}
+}
+
+// Apply records the receive time on the entry and corrects its timestamp if
+// it differs from the receive time by more than the configured maximum skew
+func (c *ClockSkewConfig) Apply(entry *models.LogEntry, receivedAt time.Time) {
+	entry.ReceivedAt = receivedAt
+	entry.ClockSkewed = false
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = receivedAt
+		return
+	}
+
+	if c == nil || c.MaxSkew <= 0 {
+		return
+	}
+
+	skew := entry.Timestamp.Sub(receivedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	if skew <= c.MaxSkew {
+		return
+	}
+
+	switch c.Action {
+	case SkewActionClamp:
+		entry.Timestamp = receivedAt
+	default:
+		entry.ClockSkewed = true
+	}
+}
@@ -0,0 +1,69 @@
+package ingestion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestClockSkewConfig_Apply(t *testing.T) {
+	receivedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		config            *ClockSkewConfig
+		timestamp         time.Time
+		expectedTimestamp time.Time
+		expectedSkewed    bool
+	}{
+		{
+			name:              "missing timestamp uses receive time",
+			config:            DefaultClockSkewConfig(),
+			timestamp:         time.Time{},
+			expectedTimestamp: receivedAt,
+		},
+		{
+			name:              "disabled keeps client timestamp",
+			config:            DefaultClockSkewConfig(),
+			timestamp:         receivedAt.Add(-48 * time.Hour),
+			expectedTimestamp: receivedAt.Add(-48 * time.Hour),
+		},
+		{
+			name:              "within max skew",
+			config:            &ClockSkewConfig{MaxSkew: 10 * time.Minute, Action: SkewActionClamp},
+			timestamp:         receivedAt.Add(-5 * time.Minute),
+			expectedTimestamp: receivedAt.Add(-5 * time.Minute),
+		},
+		{
+			name:              "clamp future timestamp",
+			config:            &ClockSkewConfig{MaxSkew: 10 * time.Minute, Action: SkewActionClamp},
+			timestamp:         receivedAt.Add(2 * time.Hour),
+			expectedTimestamp: receivedAt,
+		},
+		{
+			name:              "flag past timestamp",
+			config:            &ClockSkewConfig{MaxSkew: 10 * time.Minute, Action: SkewActionFlag},
+			timestamp:         receivedAt.Add(-2 * time.Hour),
+			expectedTimestamp: receivedAt.Add(-2 * time.Hour),
+			expectedSkewed:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := models.LogEntry{Timestamp: tt.timestamp}
+			tt.config.Apply(&entry, receivedAt)
+
+			if !entry.ReceivedAt.Equal(receivedAt) {
+				t.Errorf("Expected received_at %v, got %v", receivedAt, entry.ReceivedAt)
+			}
+			if !entry.Timestamp.Equal(tt.expectedTimestamp) {
+				t.Errorf("Expected timestamp %v, got %v", tt.expectedTimestamp, entry.Timestamp)
+			}
+			if entry.ClockSkewed != tt.expectedSkewed {
+				t.Errorf("Expected clock_skewed %v, got %v", tt.expectedSkewed, entry.ClockSkewed)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+)
+
+// symbolicationTimeout bounds the time all symbolicators together may spend on one crash report
+const symbolicationTimeout = 10 * time.Second
+
+// Symbolicator resolves the raw frames of a crash report, e.g. instruction addresses with dSYM
+// files or obfuscated names with ProGuard mappings, by filling in their function, file and line
+type Symbolicator interface {
+	Symbolicate(ctx context.Context, entry *models.LogEntry) error
+}
+
+// SymbolicatorFunc adapts a function to the Symbolicator interface
+type SymbolicatorFunc func(ctx context.Context, entry *models.LogEntry) error
+
+// Symbolicate calls f(ctx, entry)
+func (f SymbolicatorFunc) Symbolicate(ctx context.Context, entry *models.LogEntry) error {
+	return f(ctx, entry)
+}
+
+// symbolicate runs the symbolicators on a crash report. A failing symbolicator does not reject
+// the report, which is then stored with the frames as far as they were resolved.
+func (s *Server) symbolicate(ctx context.Context, entry *models.LogEntry) {
+	if len(s.symbolicators) == 0 || entry.Crash.Symbolicated {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, symbolicationTimeout)
+	defer cancel()
+
+	for _, symbolicator := range s.symbolicators {
+		if err := symbolicator.Symbolicate(ctx, entry); err != nil {
+			fmt.Printf("Failed to symbolicate crash report %s: %v\n", entry.ID, err)
+			return
+		}
+	}
+	entry.Crash.Symbolicated = true
+}
+
+// handleIngestCrash handles crash report ingestion. Reports default to the FATAL level and to a
+// message describing the crash, and are symbolicated before their signature is computed.
+func (s *Server) handleIngestCrash(c *gin.Context) {
+	s.metrics.IncrementRequestsTotal()
+
+	var entry models.LogEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		s.metrics.IncrementValidationErrors()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if entry.Crash == nil {
+		s.metrics.IncrementRequestsFailed()
+		s.metrics.IncrementValidationErrors()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Crash report validation failed", "crash is required")
+		return
+	}
+
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if entry.Level == "" {
+		entry.Level = models.LogLevelFatal
+	}
+	s.symbolicate(c.Request.Context(), &entry)
+	if entry.Message == "" {
+		entry.Message = entry.Crash.Title()
+		if entry.Crash.Reason != "" {
+			entry.Message += ": " + entry.Crash.Reason
+		}
+	}
+
+	entries, ok := s.prepareEntries(c, []models.LogEntry{entry})
+	if !ok {
+		return
+	}
+
+	if err := s.bufferEntries(c.Request.Context(), entries); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to buffer crash report", err.Error())
+		return
+	}
+
+	s.recordUsage(c, entries)
+	s.metrics.IncrementRequestsSuccessful()
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Crash report buffered successfully",
+		"id":        entries[0].ID,
+		"signature": entries[0].Crash.Signature,
+	})
+}
@@ -0,0 +1,114 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_IngestCrash(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	// Resolves the frames of the app binary from a symbol table
+	symbols := map[string]string{"0x1000": "CheckoutViewController.pay"}
+	symbolicator := SymbolicatorFunc(func(ctx context.Context, entry *models.LogEntry) error {
+		for _, thread := range entry.Crash.Threads {
+			for i, frame := range thread.Frames {
+				if function, ok := symbols[frame.Address]; ok {
+					thread.Frames[i].Function = function
+				}
+			}
+		}
+		return nil
+	})
+
+	server := NewServerWithOptions(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 100, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{
+		Symbolicators: []Symbolicator{symbolicator},
+	})
+	router := gin.New()
+	server.registerRoutes(router)
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/v1/crashes", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(`{"service_name": "ios-app", "agent_id": "device-1", "platform": "swift", "message": "No crash"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without crash, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w := serve(`{
+		"service_name": "ios-app",
+		"agent_id": "device-1",
+		"platform": "swift",
+		"crash": {
+			"signal": "SIGABRT",
+			"exception_type": "NSInvalidArgumentException",
+			"reason": "unrecognized selector",
+			"threads": [{"name": "main", "crashed": true, "frames": [{"address": "0x1000", "module": "Shop", "in_app": true}]}],
+			"breadcrumbs": [{"timestamp": "2024-01-15T10:29:58Z", "category": "ui", "message": "Tapped pay"}]
+		}
+	}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response struct {
+		ID        string `json:"id"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.Signature == "" {
+		t.Error("Expected a crash signature")
+	}
+
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	logs, _ := memoryStorage.GetByIDs(context.Background(), []string{response.ID})
+	if len(logs) != 1 {
+		t.Fatalf("Expected the crash report to be stored, got %d", len(logs))
+	}
+	entry := logs[0]
+	if entry.Level != models.LogLevelFatal || entry.Message != "NSInvalidArgumentException in Shop!CheckoutViewController.pay: unrecognized selector" {
+		t.Errorf("Unexpected defaults: %s %q", entry.Level, entry.Message)
+	}
+	if !entry.Crash.Symbolicated || entry.Crash.Threads[0].Frames[0].Function != "CheckoutViewController.pay" {
+		t.Errorf("Expected the crash to be symbolicated, got %+v", entry.Crash)
+	}
+	if entry.Crash.Signature != response.Signature || entry.Crash.Signature != entry.Crash.ComputeSignature() {
+		t.Error("Expected the signature to be computed from the symbolicated frames")
+	}
+}
+
+func TestServer_SymbolicateFailure(t *testing.T) {
+	server := NewServerWithOptions(8080, &MockStorage{}, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{
+		Symbolicators: []Symbolicator{SymbolicatorFunc(func(ctx context.Context, entry *models.LogEntry) error {
+			return errors.New("symbols not uploaded")
+		})},
+	})
+
+	entry := &models.LogEntry{Crash: &models.CrashInfo{Signal: "SIGSEGV"}}
+	server.symbolicate(context.Background(), entry)
+	if entry.Crash.Symbolicated {
+		t.Error("Expected a failed symbolication to leave the crash unsymbolicated")
+	}
+}
@@ -0,0 +1,122 @@
+package ingestion
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// incidentStore returns the storage as an incident store, responding with an error if unsupported
+func (s *Server) incidentStore(c *gin.Context) (storage.IncidentStore, bool) {
+	store, ok := s.storage.(storage.IncidentStore)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support incidents", "")
+		return nil, false
+	}
+	return store, true
+}
+
+// handleListIncidents handles requests listing all incidents, newest first
+func (s *Server) handleListIncidents(c *gin.Context) {
+	store, ok := s.incidentStore(c)
+	if !ok {
+		return
+	}
+
+	incidents, err := store.ListIncidents(c.Request.Context())
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to list incidents", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"incidents":   incidents,
+		"total_count": len(incidents),
+	})
+}
+
+// handleCreateIncident handles requests creating an incident with its initial evidence
+func (s *Server) handleCreateIncident(c *gin.Context) {
+	store, ok := s.incidentStore(c)
+	if !ok {
+		return
+	}
+
+	var incident models.Incident
+	if err := c.ShouldBindJSON(&incident); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	validationResult := s.validator.ValidateIncident(&incident)
+	if !validationResult.IsValid {
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Incident validation failed", validationResult.Errors)
+		return
+	}
+
+	created, err := store.CreateIncident(c.Request.Context(), incident)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to create incident", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// handleGetIncident handles requests for an incident with its attached log IDs
+func (s *Server) handleGetIncident(c *gin.Context) {
+	store, ok := s.incidentStore(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	incident, err := store.GetIncident(c.Request.Context(), id)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to get incident", err.Error())
+		return
+	}
+	if incident == nil {
+		problem.Respond(c, http.StatusNotFound, problem.CodeIncidentNotFound, "Incident does not exist", id)
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
+
+// handleAttachToIncident handles requests attaching log IDs or a filter to an incident
+func (s *Server) handleAttachToIncident(c *gin.Context) {
+	store, ok := s.incidentStore(c)
+	if !ok {
+		return
+	}
+
+	var attachment models.IncidentAttachment
+	if err := c.ShouldBindJSON(&attachment); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	validationResult := s.validator.ValidateIncidentAttachment(&attachment)
+	if !validationResult.IsValid {
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Attachment validation failed", validationResult.Errors)
+		return
+	}
+
+	id := c.Param("id")
+	incident, err := store.AttachToIncident(c.Request.Context(), id, attachment)
+	if errors.Is(err, storage.ErrIncidentNotFound) {
+		problem.Respond(c, http.StatusNotFound, problem.CodeIncidentNotFound, "Incident does not exist", id)
+		return
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to attach to incident", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, incident)
+}
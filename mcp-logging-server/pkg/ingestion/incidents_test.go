@@ -0,0 +1,78 @@
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_Incidents(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	router := newServiceRegistryTestRouter(t, memoryStorage)
+
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, body := range []string{`{"title": 5}`, `{"description": "no title"}`, `{"title": "Outage", "filter": {"level": "LOUD"}}`} {
+		if w := serve("POST", "/v1/incidents", body); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d: %s", http.StatusBadRequest, body, w.Code, w.Body.String())
+		}
+	}
+
+	w := serve("POST", "/v1/incidents", `{"title": "Checkout outage", "log_ids": ["log-1"], "filter": {"service_name": "checkout", "level": "ERROR"}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created models.Incident
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if created.ID == "" || created.Filter == nil || created.Filter.EndTime.IsZero() {
+		t.Errorf("Expected an incident with a filter snapshot, got %+v", created)
+	}
+
+	url := "/v1/incidents/" + created.ID
+	if w := serve("POST", url+"/logs", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an empty attachment, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	if w := serve("POST", "/v1/incidents/missing/logs", `{"log_ids": ["log-2"]}`); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+	if w := serve("POST", url+"/logs", `{"log_ids": ["log-2", "log-1"]}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w = serve("GET", url, "")
+	var incident models.Incident
+	if err := json.Unmarshal(w.Body.Bytes(), &incident); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected the incident, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(incident.LogIDs) != 2 || incident.Title != "Checkout outage" {
+		t.Errorf("Expected the incident with two log IDs, got %+v", incident)
+	}
+
+	if w := serve("GET", "/v1/incidents/missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+
+	w = serve("GET", "/v1/incidents", "")
+	var list struct {
+		Incidents  []models.Incident `json:"incidents"`
+		TotalCount int               `json:"total_count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.TotalCount != 1 {
+		t.Errorf("Expected one incident, got %d: %s", w.Code, w.Body.String())
+	}
+}
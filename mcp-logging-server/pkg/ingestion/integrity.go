@@ -0,0 +1,45 @@
+package ingestion
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// handleVerifyIntegrity re-hashes stored log entries and reports those that were altered or corrupted
+func (s *Server) handleVerifyIntegrity(c *gin.Context) {
+	verifier, ok := s.storage.(storage.IntegrityVerifier)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support integrity verification", "")
+		return
+	}
+
+	// The body is optional, an empty request verifies every entry
+	var options storage.IntegrityVerifyOptions
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&options); err != nil && !errors.Is(err, io.EOF) {
+			problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+			return
+		}
+	}
+
+	report, err := verifier.VerifyIntegrity(c.Request.Context(), options)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to verify log integrity", err.Error())
+		return
+	}
+
+	status := "ok"
+	if report.Mismatched > 0 {
+		status = "mismatch"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": status,
+		"report": report,
+	})
+}
@@ -0,0 +1,77 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_VerifyIntegrity(t *testing.T) {
+	sqliteStorage, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "integrity.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+	sqliteStorage.SetIntegrityHashing(true, "")
+
+	err = sqliteStorage.Store(context.Background(), []models.LogEntry{{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelInfo,
+		Message:     "Payment captured",
+		ServiceName: "payment-service",
+		AgentID:     "payment-agent",
+		Platform:    models.PlatformGo,
+	}})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	router := newServiceRegistryTestRouter(t, sqliteStorage)
+
+	tests := []struct {
+		name           string
+		body           []byte
+		expectedStatus int
+	}{
+		{name: "empty body verifies everything", expectedStatus: http.StatusOK},
+		{name: "time range", body: []byte(`{"start_time": "2020-01-01T00:00:00Z", "max_mismatches": 10}`), expectedStatus: http.StatusOK},
+		{name: "invalid json", body: []byte(`{"start_time": 5}`), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/admin/verify", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Status string                  `json:"status"`
+				Report storage.IntegrityReport `json:"report"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Status != "ok" || response.Report.Checked != 1 {
+				t.Errorf("Expected 1 verified entry, got %s %+v", response.Status, response.Report)
+			}
+		})
+	}
+}
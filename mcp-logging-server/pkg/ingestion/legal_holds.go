@@ -0,0 +1,92 @@
+package ingestion
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// legalHoldManager returns the storage as a legal hold manager, responding with an error if unsupported
+func (s *Server) legalHoldManager(c *gin.Context) (storage.LegalHoldManager, bool) {
+	manager, ok := s.storage.(storage.LegalHoldManager)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support legal holds", "")
+		return nil, false
+	}
+	return manager, true
+}
+
+// handleListLegalHolds handles requests listing all active legal holds
+func (s *Server) handleListLegalHolds(c *gin.Context) {
+	manager, ok := s.legalHoldManager(c)
+	if !ok {
+		return
+	}
+
+	holds, err := manager.ListLegalHolds(c.Request.Context())
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to list legal holds", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"legal_holds": holds,
+		"total_count": len(holds),
+	})
+}
+
+// handlePlaceLegalHold handles requests placing a legal hold on a service and time range
+func (s *Server) handlePlaceLegalHold(c *gin.Context) {
+	manager, ok := s.legalHoldManager(c)
+	if !ok {
+		return
+	}
+
+	var hold models.LegalHold
+	if err := c.ShouldBindJSON(&hold); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	validationResult := s.validator.ValidateLegalHold(&hold)
+	if !validationResult.IsValid {
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Legal hold validation failed", validationResult.Errors)
+		return
+	}
+
+	stored, err := manager.PlaceLegalHold(c.Request.Context(), hold)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to place legal hold", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, stored)
+}
+
+// handleReleaseLegalHold handles requests releasing a legal hold
+func (s *Server) handleReleaseLegalHold(c *gin.Context) {
+	manager, ok := s.legalHoldManager(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	released, err := manager.ReleaseLegalHold(c.Request.Context(), id)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to release legal hold", err.Error())
+		return
+	}
+
+	if !released {
+		problem.Respond(c, http.StatusNotFound, problem.CodeLegalHoldNotFound, "Legal hold does not exist", id)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Legal hold released",
+		"id":      id,
+	})
+}
@@ -0,0 +1,81 @@
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_LegalHolds(t *testing.T) {
+	sqliteStorage, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "holds.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	router := newServiceRegistryTestRouter(t, sqliteStorage)
+
+	serve := func(method, url string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{"reason": 5}`},
+		{name: "missing reason", body: `{"service_name": "orders-service"}`},
+		{name: "end before start", body: `{"reason": "Audit", "start_time": "2024-02-01T00:00:00Z", "end_time": "2024-01-01T00:00:00Z"}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve("POST", "/admin/legal-holds", []byte(tt.body)); w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := serve("POST", "/admin/legal-holds", []byte(`{"service_name": "orders-service", "start_time": "2024-01-01T00:00:00Z", "reason": "Litigation 2024-17"}`))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var hold models.LegalHold
+	if err := json.Unmarshal(w.Body.Bytes(), &hold); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if hold.ID == "" || hold.ServiceName != "orders-service" {
+		t.Errorf("Unexpected legal hold: %+v", hold)
+	}
+
+	w = serve("GET", "/admin/legal-holds", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var list struct {
+		LegalHolds []models.LegalHold `json:"legal_holds"`
+		TotalCount int                `json:"total_count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if list.TotalCount != 1 || list.LegalHolds[0].ID != hold.ID {
+		t.Errorf("Expected the placed hold to be listed, got %+v", list)
+	}
+
+	if w := serve("DELETE", "/admin/legal-holds/"+hold.ID, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := serve("DELETE", "/admin/legal-holds/"+hold.ID, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,75 @@
+package ingestion
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+)
+
+// modeRetryAfter is the Retry-After of requests rejected by the mode, in seconds. Modes are
+// switched by hand, so it only paces clients retrying in a loop.
+const modeRetryAfter = 30
+
+// modeMiddleware rejects the requests the current mode does not serve with 503: writes in
+// read-only mode and every request in maintenance mode. Reads are GET and HEAD requests.
+func (s *Server) modeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := s.maintenance.State()
+		read := c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead
+
+		switch {
+		case state.Mode == maintenance.ModeMaintenance:
+			c.Header("Retry-After", strconv.Itoa(modeRetryAfter))
+			problem.Abort(c, http.StatusServiceUnavailable, problem.CodeMaintenance, "Server is in maintenance mode", state.Reason)
+		case state.Mode == maintenance.ModeReadOnly && !read:
+			c.Header("Retry-After", strconv.Itoa(modeRetryAfter))
+			problem.Abort(c, http.StatusServiceUnavailable, problem.CodeReadOnly, "Server is in read-only mode", state.Reason)
+		default:
+			c.Next()
+		}
+	}
+}
+
+// handleGetMode handles requests for the current mode
+func (s *Server) handleGetMode(c *gin.Context) {
+	c.JSON(http.StatusOK, s.maintenance.State())
+}
+
+// handleSetMode handles requests switching the server to normal, read-only or maintenance mode.
+// Entries already accepted are still flushed to storage, POST /admin/flush before switching
+// to have none left.
+func (s *Server) handleSetMode(c *gin.Context) {
+	var request struct {
+		Mode   string `json:"mode" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	previous := s.maintenance.State()
+	state, err := s.maintenance.Set(maintenance.Mode(request.Mode), request.Reason)
+	if err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid mode", err.Error())
+		return
+	}
+	if state.Mode != previous.Mode {
+		log.Printf("Switched from %s to %s mode: %s", previous.Mode, state.Mode, modeReason(state))
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// modeReason returns the reason of a mode for log lines
+func modeReason(state maintenance.State) string {
+	if state.Reason == "" {
+		return "no reason given"
+	}
+	return fmt.Sprintf("%q", state.Reason)
+}
@@ -0,0 +1,103 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_Mode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	adminKey, _ := manager.CreateAPIKey("admin", []auth.Permission{auth.PermissionAdmin}, 0, nil)
+
+	server := NewServer(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil)
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	serve := func(method, url string, body interface{}) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, url, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", adminKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	expectRejected := func(w *httptest.ResponseRecorder, code problem.Code) {
+		t.Helper()
+		var details problem.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil || w.Code != http.StatusServiceUnavailable || details.Code != code {
+			t.Errorf("Expected status %d with %s, got %d: %s", http.StatusServiceUnavailable, code, w.Code, w.Body.String())
+		}
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("Expected a Retry-After header")
+		}
+	}
+	entry := models.LogEntry{ID: uuid.New().String(), Timestamp: time.Now(), Level: models.LogLevelInfo, Message: "order placed", ServiceName: "checkout", AgentID: "agent-1", Platform: models.PlatformGo}
+
+	w := serve("PUT", "/admin/mode", gin.H{"mode": "read_only", "reason": "storage migration"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Read-only mode rejects ingestion, including entries collected by the server, and serves reads
+	expectRejected(serve("POST", "/v1/logs", entry), problem.CodeReadOnly)
+	expectRejected(serve("POST", "/v1/incidents", gin.H{"title": "Checkout down"}), problem.CodeReadOnly)
+	if _, err := server.Ingest(context.Background(), []models.LogEntry{entry}); err == nil {
+		t.Error("Expected Ingest to fail in read-only mode")
+	}
+	if w := serve("GET", "/v1/incidents", nil); w.Code != http.StatusOK {
+		t.Errorf("Expected incidents to be listed in read-only mode, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// The mode is reported by health and capabilities
+	var health struct {
+		Mode maintenance.State `json:"mode"`
+	}
+	if err := json.Unmarshal(serve("GET", "/health", nil).Body.Bytes(), &health); err != nil || health.Mode.Mode != maintenance.ModeReadOnly || health.Mode.Reason != "storage migration" {
+		t.Errorf("Expected read-only mode in the health check, got %+v, %v", health.Mode, err)
+	}
+	var capabilities capabilitiesResponse
+	if err := json.Unmarshal(serve("GET", CapabilitiesPath, nil).Body.Bytes(), &capabilities); err != nil || capabilities.Mode != maintenance.ModeReadOnly {
+		t.Errorf("Expected read-only mode in the capabilities, got %q, %v", capabilities.Mode, err)
+	}
+
+	// Maintenance mode rejects reads too, admin endpoints are still served
+	serve("PUT", "/admin/mode", gin.H{"mode": "maintenance"})
+	expectRejected(serve("GET", "/v1/incidents", nil), problem.CodeMaintenance)
+	var state maintenance.State
+	if w := serve("GET", "/admin/mode", nil); json.Unmarshal(w.Body.Bytes(), &state) != nil || state.Mode != maintenance.ModeMaintenance || state.Reason != "" {
+		t.Errorf("Expected maintenance mode, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := serve("PUT", "/admin/mode", gin.H{"mode": "offline"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown mode, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	serve("PUT", "/admin/mode", gin.H{"mode": "normal"})
+	if w := serve("POST", "/v1/logs", entry); w.Code != http.StatusCreated {
+		t.Errorf("Expected ingestion to be served in normal mode, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,833 @@
+package ingestion
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/openapi"
+	"github.com/kerlexov/mcp-logging-server/pkg/recovery"
+	"github.com/kerlexov/mcp-logging-server/pkg/replication"
+	"github.com/kerlexov/mcp-logging-server/pkg/routing"
+	"github.com/kerlexov/mcp-logging-server/pkg/sharding"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
+)
+
+// OpenAPIPath is the public path the OpenAPI document of the server is served at
+const OpenAPIPath = "/openapi.json"
+
+// apiInfo describes the API in the OpenAPI document
+var apiInfo = openapi.Info{
+	Title:       "MCP Logging Server",
+	Description: "Log ingestion, search and administration API. Errors are RFC 7807 problem details.",
+	Version:     "1",
+}
+
+// Tags grouping the endpoints in the OpenAPI document
+const (
+	tagHealth         = "health"
+	tagMetrics        = "metrics"
+	tagIngestion      = "ingestion"
+	tagSearch         = "search"
+	tagServices       = "services"
+	tagIncidents      = "incidents"
+	tagAdmin          = "admin"
+	tagDataProtection = "data-protection"
+)
+
+// actionResponse is the response of endpoints that confirm an action
+type actionResponse struct {
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// apiRoutes returns the registry of the endpoints served by registerRoutes, with the types their
+// handlers bind and respond with. A route registered without an entry here fails the tests.
+func apiRoutes() []openapi.Route {
+	routes := []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/health",
+			OperationID: "getHealth",
+			Summary:     "Report the health of the server and its storage",
+			Tag:         tagHealth,
+			Errors:      []int{http.StatusServiceUnavailable},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        OpenAPIPath,
+			OperationID: "getOpenAPIDocument",
+			Summary:     "Get this OpenAPI document",
+			Tag:         tagHealth,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        CapabilitiesPath,
+			OperationID: "getCapabilities",
+			Summary:     "Describe the optional features and limits of the server",
+			Tag:         tagHealth,
+			Response:    capabilitiesResponse{},
+		},
+
+		// Metrics
+		{
+			Method:      http.MethodGet,
+			Path:        "/metrics",
+			OperationID: "getMetrics",
+			Summary:     "Get server metrics, in the Prometheus text format with format=prometheus",
+			Tag:         tagMetrics,
+			Permission:  auth.PermissionMetrics,
+			Query:       []openapi.Parameter{openapi.QueryParam("format", "", "prometheus for the Prometheus text format")},
+			Response: struct {
+				Metrics   metrics.MetricsSnapshot `json:"metrics"`
+				Timestamp time.Time               `json:"timestamp"`
+			}{},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/stats",
+			OperationID: "getBufferStats",
+			Summary:     "Get buffer statistics",
+			Tag:         tagMetrics,
+			Permission:  auth.PermissionMetrics,
+			Response: struct {
+				BufferStats      buffer.BufferStats `json:"buffer_stats"`
+				ConcurrencyStats ConcurrencyStats   `json:"concurrency_stats"`
+				Timestamp        time.Time          `json:"timestamp"`
+			}{},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/recovery/stats",
+			OperationID: "getRecoveryStats",
+			Summary:     "Get statistics of entries saved for recovery",
+			Tag:         tagMetrics,
+			Permission:  auth.PermissionMetrics,
+			Response: struct {
+				RecoveryStats recovery.RecoveryStats `json:"recovery_stats"`
+				Timestamp     time.Time              `json:"timestamp"`
+			}{},
+			Errors: []int{http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/circuit-breaker/stats",
+			OperationID: "getCircuitBreakerStats",
+			Summary:     "Get the storage circuit breaker state, 0 closed, 1 open and 2 half-open",
+			Tag:         tagMetrics,
+			Permission:  auth.PermissionMetrics,
+			Response: struct {
+				CircuitBreakerStats CircuitBreakerStats `json:"circuit_breaker_stats"`
+				Timestamp           time.Time           `json:"timestamp"`
+			}{},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/replication/stats",
+			OperationID: "getReplicationStats",
+			Summary:     "Get the replication lag and counters of each peer server",
+			Tag:         tagMetrics,
+			Permission:  auth.PermissionMetrics,
+			Response: struct {
+				ReplicationStats replication.Stats `json:"replication_stats"`
+				Timestamp        time.Time         `json:"timestamp"`
+			}{},
+			Errors: []int{http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/sharding/stats",
+			OperationID: "getShardingStats",
+			Summary:     "Get the entries routed to the other nodes of a sharded cluster, and the node owning a service",
+			Tag:         tagMetrics,
+			Permission:  auth.PermissionMetrics,
+			Query:       []openapi.Parameter{openapi.QueryParam("service", "", "Service whose owning node is reported")},
+			Response: struct {
+				ShardingStats sharding.Stats `json:"sharding_stats"`
+				Owner         string         `json:"owner,omitempty"`
+				Timestamp     time.Time      `json:"timestamp"`
+			}{},
+			Errors: []int{http.StatusNotImplemented},
+		},
+
+		// Ingestion
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/logs",
+			OperationID: "ingestLog",
+			Summary:     "Ingest a log entry",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Request:     models.LogEntry{},
+			Status:      http.StatusCreated,
+			Response: struct {
+				Message string `json:"message"`
+				ID      string `json:"id"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/logs/batch",
+			OperationID: "ingestLogBatch",
+			Summary:     "Ingest a batch of log entries",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Request:     []models.LogEntry{},
+			Status:      http.StatusCreated,
+			Response: struct {
+				Message       string `json:"message"`
+				BufferedCount int    `json:"buffered_count"`
+				TotalCount    int    `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/logs/batch/async",
+			OperationID: "ingestLogBatchAsync",
+			Summary:     "Accept a batch of log entries and return a token to poll until it is stored",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Request:     []models.LogEntry{},
+			Status:      http.StatusAccepted,
+			Response: struct {
+				Message    string `json:"message"`
+				Token      string `json:"token"`
+				TotalCount int    `json:"total_count"`
+				StatusURL  string `json:"status_url"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/batches/:token",
+			OperationID: "getBatchStatus",
+			Summary:     "Get the delivery status of an asynchronously ingested batch",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Response:    BatchRecord{},
+			Errors:      []int{http.StatusNotFound},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        sharding.RoutePath,
+			OperationID: "routeLogBatch",
+			Summary:     "Buffer entries another node of a sharded cluster received, identified by the X-Shard-Origin header",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionRouteLogs,
+			Request:     []models.LogEntry{},
+			Status:      http.StatusCreated,
+			Response: struct {
+				Message       string `json:"message"`
+				Origin        string `json:"origin"`
+				BufferedCount int    `json:"buffered_count"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        replication.BatchPath,
+			OperationID: "replicateLogBatch",
+			Summary:     "Store a batch replicated by a peer server, identified by the X-Replication-Origin header",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionReplicateLogs,
+			Request:     []models.LogEntry{},
+			Status:      http.StatusCreated,
+			Response: struct {
+				Message     string `json:"message"`
+				Origin      string `json:"origin"`
+				StoredCount int    `json:"stored_count"`
+				Duplicates  int    `json:"duplicates"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/logs/sync",
+			OperationID: "syncLogs",
+			Summary:     "Upload entries buffered offline, skipping those uploaded before",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Request:     models.SyncRequest{},
+			Response: struct {
+				AgentID       string `json:"agent_id"`
+				LastSequence  int64  `json:"last_sequence"`
+				AcceptedCount int    `json:"accepted_count"`
+				Duplicates    int    `json:"duplicates"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/logs/sync/:agent_id",
+			OperationID: "getSyncState",
+			Summary:     "Get the last sequence accepted from an agent",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Response:    models.SyncState{},
+			Errors:      []int{http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/crashes",
+			OperationID: "ingestCrash",
+			Summary:     "Ingest a crash report, symbolicated before it is stored",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Request:     models.LogEntry{},
+			Status:      http.StatusCreated,
+			Response: struct {
+				Message   string `json:"message"`
+				ID        string `json:"id"`
+				Signature string `json:"signature"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/logs/shipper",
+			OperationID: "ingestShipperRecords",
+			Summary:     "Ingest records posted by Fluent Bit's http output or Vector's http sink, as a JSON array or newline delimited JSON",
+			Tag:         tagIngestion,
+			Permission:  auth.PermissionIngestLogs,
+			Query: []openapi.Parameter{
+				openapi.QueryParam("service", "", "Service of all records, defaults to the mapped record key"),
+				openapi.QueryParam("agent", "", "Agent ID of all records, defaults to the mapped record key"),
+				openapi.QueryParam("platform", "", "Platform of all records"),
+			},
+			Request: []map[string]interface{}{},
+			Status:  http.StatusCreated,
+			Response: struct {
+				Message       string `json:"message"`
+				BufferedCount int    `json:"buffered_count"`
+				TotalCount    int    `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusInternalServerError},
+		},
+
+		// Search
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/search",
+			OperationID: "searchLogs",
+			Summary:     "Full-text search of log messages with highlighted fragments",
+			Tag:         tagSearch,
+			Permission:  auth.PermissionQueryLogs,
+			Query: []openapi.Parameter{
+				{Name: "q", In: "query", Description: "Search query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+				openapi.QueryParam("service_name", "", ""),
+				openapi.QueryParam("agent_id", "", ""),
+				openapi.QueryParam("request_id", "", ""),
+				openapi.QueryParam("template_id", "", "Message template ID, as returned for message groups"),
+				openapi.QueryParam("level", models.LogLevel(""), ""),
+				openapi.QueryParam("min_level", models.LogLevel(""), "Minimum level, e.g. WARN for warnings, errors and fatal errors"),
+				openapi.QueryParam("platform", models.Platform(""), ""),
+				openapi.QueryParam("tags_any", "", "Comma-separated tags, entries with any of them match"),
+				openapi.QueryParam("tags_all", "", "Comma-separated tags, entries with all of them match"),
+				openapi.QueryParam("start_time", "", "RFC3339, a local time in time_zone, now, today, yesterday, or relative to now such as -2h"),
+				openapi.QueryParam("end_time", "", "Same formats as start_time"),
+				openapi.QueryParam("time_zone", "", "IANA time zone of local times, defaults to UTC"),
+				openapi.QueryParam("time_field", models.TimeField(""), "timestamp or received_at"),
+				openapi.QueryParam("sort", models.SearchSort(""), "time or relevance"),
+				openapi.QueryParam("fuzziness", 0, "Edit distance of fuzzy matches"),
+				openapi.QueryParam("prefix", false, "Match terms by prefix"),
+				openapi.QueryParam("facets", "", "Comma-separated facets to count"),
+				openapi.QueryParam("limit", 0, "Defaults to 100"),
+				openapi.QueryParam("offset", 0, ""),
+			},
+			Response: struct {
+				Query      string                         `json:"query"`
+				Hits       []models.SearchHit             `json:"hits"`
+				TotalCount int                            `json:"total_count"`
+				HasMore    bool                           `json:"has_more"`
+				Limit      int                            `json:"limit"`
+				Offset     int                            `json:"offset"`
+				Facets     map[string][]models.FacetCount `json:"facets,omitempty"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/logs/:id/annotations",
+			OperationID: "annotateLog",
+			Summary:     "Append an operator annotation to a log entry without changing its content",
+			Tag:         tagSearch,
+			Permission:  auth.PermissionQueryLogs,
+			Request:     annotateLogRequest{},
+			Status:      http.StatusCreated,
+			Response:    annotationsResponse{},
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+
+		// Incidents
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/incidents",
+			OperationID: "listIncidents",
+			Summary:     "List the incidents without their log IDs, newest first",
+			Tag:         tagIncidents,
+			Permission:  auth.PermissionQueryLogs,
+			Response: struct {
+				Incidents  []models.Incident `json:"incidents"`
+				TotalCount int               `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/incidents",
+			OperationID: "createIncident",
+			Summary:     "Create an incident with attached log IDs or a filter snapshot",
+			Tag:         tagIncidents,
+			Permission:  auth.PermissionQueryLogs,
+			Request:     models.Incident{},
+			Status:      http.StatusCreated,
+			Response:    models.Incident{},
+			Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/incidents/:id",
+			OperationID: "getIncident",
+			Summary:     "Get an incident with its attached log IDs",
+			Tag:         tagIncidents,
+			Permission:  auth.PermissionQueryLogs,
+			Response:    models.Incident{},
+			Errors:      []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/v1/incidents/:id/logs",
+			OperationID: "attachToIncident",
+			Summary:     "Attach log IDs to an incident or replace its filter snapshot",
+			Tag:         tagIncidents,
+			Permission:  auth.PermissionQueryLogs,
+			Request:     models.IncidentAttachment{},
+			Response:    models.Incident{},
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		// Service registry
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/services",
+			OperationID: "listServices",
+			Summary:     "List the registered services",
+			Tag:         tagServices,
+			Permission:  auth.PermissionQueryLogs,
+			Response: struct {
+				Services   []models.ServiceRegistration `json:"services"`
+				TotalCount int                          `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/v1/services/:name",
+			OperationID: "getService",
+			Summary:     "Get the registration of a service",
+			Tag:         tagServices,
+			Permission:  auth.PermissionQueryLogs,
+			Response:    models.ServiceRegistration{},
+			Errors:      []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/v1/services/:name",
+			OperationID: "registerService",
+			Summary:     "Register a service or update its registration",
+			Tag:         tagServices,
+			Permission:  auth.PermissionIngestLogs,
+			Request:     models.ServiceRegistration{},
+			Response:    models.ServiceRegistration{},
+			Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/v1/services/:name",
+			OperationID: "deleteService",
+			Summary:     "Delete the registration of a service",
+			Tag:         tagServices,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				Message     string `json:"message"`
+				ServiceName string `json:"service_name"`
+			}{},
+			Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+
+		// Administration
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/circuit-breaker/reset",
+			OperationID: "resetCircuitBreaker",
+			Summary:     "Close the storage circuit breaker",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response:    actionResponse{},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/flush",
+			OperationID: "flushBuffer",
+			Summary:     "Write the buffered entries to storage",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response:    actionResponse{},
+			Errors:      []int{http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/query-plan",
+			OperationID: "explainQuery",
+			Summary:     "Get the execution plan of a log filter",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request:     models.LogFilter{},
+			Response: struct {
+				Filter models.LogFilter  `json:"filter"`
+				Plan   storage.QueryPlan `json:"plan"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/verify",
+			OperationID: "verifyIntegrity",
+			Summary:     "Re-hash stored entries and report those that were altered, the body is optional",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request:     storage.IntegrityVerifyOptions{},
+			Response: struct {
+				Status string                  `json:"status"`
+				Report storage.IntegrityReport `json:"report"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/routing",
+			OperationID: "getRouting",
+			Summary:     "List the routing rules and the entries they matched",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				Rules []routing.Rule `json:"rules"`
+				Stats routing.Stats  `json:"stats"`
+			}{},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/routing/preview",
+			OperationID: "previewRouting",
+			Summary:     "Show what the routing rules would do with a batch of entries, without storing them",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request:     []models.LogEntry{},
+			Response: struct {
+				Decisions []routing.Decision `json:"decisions"`
+			}{},
+			Errors: []int{http.StatusBadRequest},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/legal-holds",
+			OperationID: "listLegalHolds",
+			Summary:     "List the active legal holds",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				LegalHolds []models.LegalHold `json:"legal_holds"`
+				TotalCount int                `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/legal-holds",
+			OperationID: "placeLegalHold",
+			Summary:     "Place a legal hold on a service and time range",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request:     models.LegalHold{},
+			Status:      http.StatusCreated,
+			Response:    models.LegalHold{},
+			Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/admin/legal-holds/:id",
+			OperationID: "releaseLegalHold",
+			Summary:     "Release a legal hold",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				Message string `json:"message"`
+				ID      string `json:"id"`
+			}{},
+			Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/symbols/:service",
+			OperationID: "listSymbolFiles",
+			Summary:     "List the symbol files of a service",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Query:       []openapi.Parameter{openapi.QueryParam("version", "", "Only list the files of this version")},
+			Response: struct {
+				SymbolFiles []models.SymbolFile `json:"symbol_files"`
+				TotalCount  int                 `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/admin/symbols/:service/:version/:name",
+			OperationID: "uploadSymbolFile",
+			Summary:     "Upload a source map or ProGuard mapping file for a service version",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Query:       []openapi.Parameter{openapi.QueryParam("kind", models.SymbolFileKind(""), "sourcemap or proguard, inferred from the name by default")},
+			RawRequest:  "application/octet-stream",
+			Status:      http.StatusCreated,
+			Response:    models.SymbolFile{},
+			Errors:      []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/admin/symbols/:service/:version/:name",
+			OperationID: "deleteSymbolFile",
+			Summary:     "Delete a symbol file",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				Message string `json:"message"`
+				Name    string `json:"name"`
+			}{},
+			Errors: []int{http.StatusNotFound, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/api-keys",
+			OperationID: "listAPIKeys",
+			Summary:     "List the API keys with their rate limits and usage",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				APIKeys    []apiKeyResponse `json:"api_keys"`
+				TotalCount int              `json:"total_count"`
+			}{},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/admin/api-keys/:id/rate-limit",
+			OperationID: "setAPIKeyRateLimit",
+			Summary:     "Change the rate limit of an API key",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request: struct {
+				RateLimit *int `json:"rate_limit" binding:"required,min=0"`
+			}{},
+			Response: apiKeyResponse{},
+			Errors:   []int{http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/usage",
+			OperationID: "getUsage",
+			Summary:     "Get the entries and bytes ingested per API key and service",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Query: []openapi.Parameter{
+				openapi.QueryParam("start_day", "", "First day, YYYY-MM-DD"),
+				openapi.QueryParam("end_day", "", "Last day, YYYY-MM-DD"),
+				openapi.QueryParam("api_key_id", "", ""),
+				openapi.QueryParam("service", "", ""),
+				openapi.QueryParam("daily", false, "Report each day separately"),
+				openapi.QueryParam("limit", 0, ""),
+			},
+			Response: struct {
+				Usage        []usageResponse `json:"usage"`
+				TotalCount   int             `json:"total_count"`
+				TotalEntries int64           `json:"total_entries"`
+				TotalBytes   int64           `json:"total_bytes"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/query-audit",
+			OperationID: "getQueryAudit",
+			Summary:     "List the audited log queries of MCP clients and API keys, newest first",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Query: []openapi.Parameter{
+				openapi.QueryParam("actor", "", "API key ID of REST queries, client name of MCP queries"),
+				openapi.QueryParam("interface", "", "mcp or rest"),
+				openapi.QueryParam("operation", "", "MCP tool, or method and route of REST queries"),
+				openapi.QueryParam("start_time", "", "RFC3339 in UTC, now, today, yesterday, or relative to now such as -24h"),
+				openapi.QueryParam("end_time", "", "Same formats as start_time"),
+				openapi.QueryParam("limit", storage.DefaultQueryAuditLimit, ""),
+			},
+			Response: struct {
+				Records    []queryAuditResponse `json:"records"`
+				TotalCount int                  `json:"total_count"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/mode",
+			OperationID: "getMode",
+			Summary:     "Get whether the server is in normal, read-only or maintenance mode",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response:    maintenance.State{},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/admin/mode",
+			OperationID: "setMode",
+			Summary:     "Switch to normal, read-only (ingestion rejected with 503) or maintenance mode (ingestion and queries rejected)",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request: struct {
+				Mode   string `json:"mode" binding:"required"`
+				Reason string `json:"reason"`
+			}{},
+			Response: maintenance.State{},
+			Errors:   []int{http.StatusBadRequest},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/tools",
+			OperationID: "listTools",
+			Summary:     "List the MCP tools and whether they are enabled",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Response: struct {
+				Tools []toolswitch.State `json:"tools"`
+			}{},
+			Errors: []int{http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/admin/tools/:name",
+			OperationID: "setTool",
+			Summary:     "Enable or disable an MCP tool, notifying connected MCP clients that the tools changed",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Request:     setToolRequest{},
+			Response:    toolswitch.State{},
+			Errors:      []int{http.StatusBadRequest, http.StatusNotFound, http.StatusNotImplemented},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/services/purge",
+			OperationID: "purgeServices",
+			Summary:     "Remove the agents that stopped logging from the listed services, keeping their entries",
+			Tag:         tagAdmin,
+			Permission:  auth.PermissionAdmin,
+			Query: []openapi.Parameter{
+				{Name: "not_seen_for", In: "query", Description: "Purge agents that have not logged for this duration, e.g. 720h", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			},
+			Response: struct {
+				Message      string               `json:"message"`
+				NotSeenSince time.Time            `json:"not_seen_since"`
+				Purged       []models.ServiceInfo `json:"purged"`
+				PurgedCount  int                  `json:"purged_count"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError, http.StatusNotImplemented},
+		},
+
+		// Data protection
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/data-protection/config",
+			OperationID: "getDataProtectionConfig",
+			Summary:     "Get the data protection configuration",
+			Tag:         tagDataProtection,
+			Permission:  auth.PermissionDataProtectionRead,
+			Response: struct {
+				Config dataprotection.DataProtectionConfig `json:"config"`
+			}{},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/admin/data-protection/config",
+			OperationID: "updateDataProtectionConfig",
+			Summary:     "Replace the data protection configuration",
+			Tag:         tagDataProtection,
+			Permission:  auth.PermissionDataProtectionWrite,
+			Request:     dataprotection.DataProtectionConfig{},
+			Response: struct {
+				Message string                              `json:"message"`
+				Config  dataprotection.DataProtectionConfig `json:"config"`
+			}{},
+			Errors: []int{http.StatusBadRequest},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/admin/data-protection/stats",
+			OperationID: "getDataProtectionStats",
+			Summary:     "Get data protection statistics",
+			Tag:         tagDataProtection,
+			Permission:  auth.PermissionDataProtectionRead,
+			Response: struct {
+				Stats dataprotection.AuditStats `json:"stats"`
+			}{},
+			Errors: []int{http.StatusServiceUnavailable},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/admin/data-protection/test",
+			OperationID: "testDataProtection",
+			Summary:     "Apply the data protection rules to a sample entry without storing it",
+			Tag:         tagDataProtection,
+			Permission:  auth.PermissionDataProtectionRead,
+			Request: struct {
+				LogEntry models.LogEntry `json:"log_entry" binding:"required"`
+			}{},
+			Response: struct {
+				Original  models.LogEntry                     `json:"original"`
+				Processed models.LogEntry                     `json:"processed"`
+				Config    dataprotection.DataProtectionConfig `json:"config"`
+			}{},
+			Errors: []int{http.StatusBadRequest, http.StatusInternalServerError},
+		},
+	}
+	return withModeErrors(routes)
+}
+
+// withModeErrors adds the 503 of read-only and maintenance mode to the routes following the
+// mode, every route under /v1 but the capabilities
+func withModeErrors(routes []openapi.Route) []openapi.Route {
+	for i := range routes {
+		route := &routes[i]
+		if !strings.HasPrefix(route.Path, "/v1/") || route.Path == CapabilitiesPath {
+			continue
+		}
+		documented := false
+		for _, status := range route.Errors {
+			documented = documented || status == http.StatusServiceUnavailable
+		}
+		if !documented {
+			route.Errors = append(route.Errors, http.StatusServiceUnavailable)
+		}
+	}
+	return routes
+}
+
+// handleOpenAPI serves the OpenAPI document describing the API
+func (s *Server) handleOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, s.openAPI)
+}
@@ -0,0 +1,87 @@
+package ingestion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/openapi"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_OpenAPIDocumentsAllRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := NewServer(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+	router := gin.New()
+	server.registerRoutes(router)
+
+	var registered []string
+	for _, route := range router.Routes() {
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+	sort.Strings(registered)
+
+	if documented := server.openAPI.Operations(); !reflect.DeepEqual(registered, documented) {
+		t.Errorf("Expected the OpenAPI document to describe the registered routes\nregistered: %v\ndocumented: %v", registered, documented)
+	}
+
+	operationIDs := make(map[string]bool)
+	for _, route := range apiRoutes() {
+		if route.OperationID == "" || operationIDs[route.OperationID] {
+			t.Errorf("Expected a unique operation ID for %s %s, got %q", route.Method, route.Path, route.OperationID)
+		}
+		operationIDs[route.OperationID] = true
+	}
+}
+
+func TestServer_handleOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	server := NewServer(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil)
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	// Served without an API key
+	req, _ := http.NewRequest("GET", OpenAPIPath, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var document openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatalf("Failed to parse document: %v", err)
+	}
+	if document.OpenAPI != openapi.Version {
+		t.Errorf("Expected OpenAPI version %s, got %s", openapi.Version, document.OpenAPI)
+	}
+
+	ingest := document.Paths["/v1/logs"]["post"]
+	if ingest == nil || ingest.Permission != auth.PermissionIngestLogs {
+		t.Fatalf("Expected the ingestion endpoint with its permission, got %+v", ingest)
+	}
+	if ref := ingest.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/LogEntry" {
+		t.Errorf("Expected the log entry request schema, got %q", ref)
+	}
+	entry := document.Components.Schemas["LogEntry"]
+	if entry == nil || entry.Properties["message"] == nil || entry.Properties["timestamp"].Format != "date-time" {
+		t.Errorf("Expected the log entry schema to describe its fields, got %+v", entry)
+	}
+	if _, ok := document.Paths["/admin/symbols/{service}/{version}/{name}"]["put"]; !ok {
+		t.Error("Expected path parameters in OpenAPI syntax")
+	}
+}
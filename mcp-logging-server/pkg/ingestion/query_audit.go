@@ -0,0 +1,140 @@
+package ingestion
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+)
+
+// auditResultCountKey is the context key under which handlers of audited queries store the
+// number of log entries they returned
+const auditResultCountKey = "query_audit_result_count"
+
+// maxQueryAuditLimit is the largest number of records returned by the query audit endpoint
+const maxQueryAuditLimit = 1000
+
+// queryAuditMiddleware adds every request of a route reading log entries to the query audit once
+// it has been handled, with the API key that made it and its query parameters
+func (s *Server) queryAuditMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		path := c.FullPath()
+		if path == "" {
+			path = c.Request.URL.Path
+		}
+		record := models.QueryAudit{
+			Time:        start.UTC(),
+			Interface:   models.QueryInterfaceREST,
+			Source:      c.ClientIP(),
+			Operation:   c.Request.Method + " " + path,
+			ResultCount: c.GetInt(auditResultCountKey),
+			LatencyMs:   time.Since(start).Milliseconds(),
+			RequestID:   requestid.Get(c),
+		}
+		if keyInfo, ok := auth.GetAPIKeyInfo(c); ok {
+			record.Actor = keyInfo.ID
+		}
+		if params := c.Request.URL.Query(); len(params) > 0 {
+			record.Filter = make(map[string]interface{}, len(params))
+			for name, values := range params {
+				if len(values) == 1 {
+					record.Filter[name] = values[0]
+				} else {
+					record.Filter[name] = values
+				}
+			}
+		}
+		if status := c.Writer.Status(); status >= http.StatusBadRequest {
+			record.Error = fmt.Sprintf("%d %s", status, http.StatusText(status))
+		}
+
+		s.queryAudit.Record(record)
+	}
+}
+
+// auditResults records the number of log entries a request returned, for the query audit
+func auditResults(c *gin.Context, count int) {
+	c.Set(auditResultCountKey, count)
+}
+
+// queryAuditResponse is a query audit record as returned by the admin API, with the name of the
+// API key of REST queries
+type queryAuditResponse struct {
+	models.QueryAudit
+	APIKeyName string `json:"api_key_name,omitempty"`
+}
+
+// handleGetQueryAudit handles requests for the audit trail of log queries, newest first.
+// Query parameters: actor, interface (mcp or rest), operation, start_time, end_time and limit.
+func (s *Server) handleGetQueryAudit(c *gin.Context) {
+	if s.queryAudit == nil {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Query audit is not enabled", "")
+		return
+	}
+
+	filter := models.QueryAuditFilter{
+		Actor:     c.Query("actor"),
+		Interface: c.Query("interface"),
+		Operation: c.Query("operation"),
+	}
+	var err error
+	switch filter.Interface {
+	case "", models.QueryInterfaceMCP, models.QueryInterfaceREST:
+	default:
+		err = fmt.Errorf("interface must be %s or %s", models.QueryInterfaceMCP, models.QueryInterfaceREST)
+	}
+	now := time.Now()
+	if err == nil {
+		if filter.StartTime, err = models.ParseTimeExpression(c.Query("start_time"), now, time.UTC); err != nil {
+			err = fmt.Errorf("start_time: %w", err)
+		}
+	}
+	if err == nil {
+		if filter.EndTime, err = models.ParseTimeExpression(c.Query("end_time"), now, time.UTC); err != nil {
+			err = fmt.Errorf("end_time: %w", err)
+		}
+	}
+	if value := c.Query("limit"); err == nil && value != "" {
+		filter.Limit, err = strconv.Atoi(value)
+		if err != nil || filter.Limit < 1 || filter.Limit > maxQueryAuditLimit {
+			err = fmt.Errorf("limit must be between 1 and %d", maxQueryAuditLimit)
+		}
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Invalid query audit query", err.Error())
+		return
+	}
+
+	records, err := s.queryAudit.List(c.Request.Context(), filter)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to query the query audit", err.Error())
+		return
+	}
+
+	names := make(map[string]string)
+	for _, keyInfo := range s.authManager.ListAPIKeys() {
+		names[keyInfo.ID] = keyInfo.Name
+	}
+
+	response := make([]queryAuditResponse, len(records))
+	for i, record := range records {
+		response[i] = queryAuditResponse{QueryAudit: record}
+		if record.Interface == models.QueryInterfaceREST {
+			response[i].APIKeyName = names[record.Actor]
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"records":     response,
+		"total_count": len(response),
+	})
+}
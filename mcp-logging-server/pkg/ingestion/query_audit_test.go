@@ -0,0 +1,91 @@
+package ingestion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/queryaudit"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_QueryAudit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	adminKey, _ := manager.CreateAPIKey("admin", []auth.Permission{auth.PermissionAdmin}, 0, nil)
+	readerKey, _ := manager.CreateAPIKey("reader", []auth.Permission{auth.PermissionQueryLogs}, 0, nil)
+	writerKey, _ := manager.CreateAPIKey("writer", []auth.Permission{auth.PermissionIngestLogs}, 0, nil)
+
+	tmpDir := t.TempDir()
+	store, err := storage.NewSQLiteStorageWithSearch(filepath.Join(tmpDir, "audit.db"), filepath.Join(tmpDir, "index"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer store.Close()
+
+	err = store.Store(context.Background(), []models.LogEntry{
+		{ID: uuid.New().String(), Timestamp: time.Now(), Level: models.LogLevelError, Message: "Payment timeout", ServiceName: "checkout", AgentID: "edge-1", Platform: models.PlatformGo},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	server := NewServerWithOptions(8080, store, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		tmpDir, manager, nil, nil, nil, nil, Options{QueryAudit: queryaudit.NewRecorder(store, 0)})
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	serve := func(url, apiKey string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve("/v1/search?q=timeout&service_name=checkout", readerKey); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := serve("/v1/search?q=timeout", writerKey); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+
+	w := serve("/admin/query-audit?interface=rest", adminKey)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Records []queryAuditResponse `json:"records"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Records) != 2 {
+		t.Fatalf("Expected 2 audited searches, got %+v", response.Records)
+	}
+
+	rejected, search := response.Records[0], response.Records[1]
+	if search.APIKeyName != "reader" || search.Operation != "GET /v1/search" || search.ResultCount != 1 || search.Filter["service_name"] != "checkout" || search.Error != "" {
+		t.Errorf("Expected the search of the reader key to be recorded, got %+v", search)
+	}
+	if rejected.APIKeyName != "writer" || rejected.Error == "" || rejected.ResultCount != 0 {
+		t.Errorf("Expected the rejected search to be recorded with its error, got %+v", rejected)
+	}
+
+	if w := serve("/admin/query-audit?interface=grpc", adminKey); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an unknown interface, got %d", http.StatusBadRequest, w.Code)
+	}
+}
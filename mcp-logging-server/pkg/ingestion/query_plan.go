@@ -0,0 +1,36 @@
+package ingestion
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// handleExplainQuery handles requests for the execution plan of a log filter
+func (s *Server) handleExplainQuery(c *gin.Context) {
+	explainer, ok := s.storage.(storage.QueryExplainer)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support query plans", "")
+		return
+	}
+
+	var filter models.LogFilter
+	if err := c.ShouldBindJSON(&filter); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	plan, err := explainer.ExplainQuery(c.Request.Context(), filter)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to explain query", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filter": filter,
+		"plan":   plan,
+	})
+}
@@ -0,0 +1,118 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/replication"
+)
+
+// handleReplicateBatch stores a batch a peer server replicated. The entries were validated and
+// processed by data protection on the server they were ingested by, so they are stored as they
+// are. They are written straight to storage instead of the buffer, which keeps them from being
+// replicated again, and entries stored by an earlier attempt of the peer are skipped.
+func (s *Server) handleReplicateBatch(c *gin.Context) {
+	s.metrics.IncrementRequestsTotal()
+
+	origin := c.GetHeader(replication.OriginHeader)
+	if origin == "" {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Missing replication origin", replication.OriginHeader+" header is required")
+		return
+	}
+	if s.replicator != nil && origin == s.replicator.ServerID() {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusConflict, problem.CodeReplicationLoop, "Batch originates from this server", fmt.Sprintf("Server %s is configured as its own replication peer", origin))
+		return
+	}
+
+	var entries []models.LogEntry
+	if err := codec.Decode(c.Request.Body, &entries); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeEmptyBatch, "Batch cannot be empty", "")
+		return
+	}
+	for i := range entries {
+		if entries[i].ID == "" {
+			s.metrics.IncrementRequestsFailed()
+			problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Replicated entries must have an ID", fmt.Sprintf("Entry %d has no ID", i))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	missing := s.missingEntries(ctx, entries)
+	if len(missing) > 0 {
+		err := s.circuitBreaker.Execute(func() error {
+			return s.storage.Store(ctx, missing)
+		})
+		if err != nil {
+			s.metrics.IncrementRequestsFailed()
+			s.metrics.IncrementStorageErrors()
+			problem.Respond(c, http.StatusServiceUnavailable, problem.CodeStorageError, "Failed to store replicated entries", err.Error())
+			return
+		}
+		if s.commitListener != nil {
+			s.commitListener.Committed(missing)
+		}
+	}
+
+	s.metrics.IncrementRequestsSuccessful()
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Replicated entries stored successfully",
+		"origin":       origin,
+		"stored_count": len(missing),
+		"duplicates":   len(entries) - len(missing),
+	})
+}
+
+// missingEntries drops the entries the storage already holds. If the storage cannot be asked,
+// all entries are returned and the write decides.
+func (s *Server) missingEntries(ctx context.Context, entries []models.LogEntry) []models.LogEntry {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+	existing, err := s.storage.GetByIDs(ctx, ids)
+	if err != nil || len(existing) == 0 {
+		return entries
+	}
+
+	stored := make(map[string]bool, len(existing))
+	for _, entry := range existing {
+		stored[entry.ID] = true
+	}
+	missing := make([]models.LogEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !stored[entry.ID] {
+			missing = append(missing, entry)
+		}
+	}
+	return missing
+}
+
+// handleReplicationStats handles requests for the replication lag and counters of each peer
+func (s *Server) handleReplicationStats(c *gin.Context) {
+	if s.replicator == nil {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Replication is not configured", "")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"replication_stats": s.replicator.Stats(),
+		"timestamp":         time.Now().UTC(),
+	})
+}
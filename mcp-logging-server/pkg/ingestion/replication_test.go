@@ -0,0 +1,159 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/replication"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func newReplicatedTestLog(message string) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelInfo,
+		Message:     message,
+		ServiceName: "checkout",
+		AgentID:     "checkout-agent",
+		Platform:    models.PlatformGo,
+	}
+}
+
+// committedCounter counts the entries of the batches it is notified of
+type committedCounter struct {
+	entries int
+}
+
+func (c *committedCounter) Committed(batch []models.LogEntry) {
+	c.entries += len(batch)
+}
+
+func TestServer_ReplicateBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	// The peer is never contacted, the replicator does not run
+	replicator, err := replication.NewReplicator(replication.Config{
+		ServerID: "eu-central",
+		Peers:    []replication.PeerConfig{{URL: "http://127.0.0.1:1"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replicator: %v", err)
+	}
+	committed := &committedCounter{}
+	server := NewServerWithOptions(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{Replicator: replicator, CommitListener: committed})
+	router := gin.New()
+	server.registerRoutes(router)
+
+	serve := func(url, origin string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", url, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if origin != "" {
+			req.Header.Set(replication.OriginHeader, origin)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	entries := []models.LogEntry{
+		newReplicatedTestLog("Replicated first"),
+		newReplicatedTestLog("Replicated second"),
+	}
+
+	if w := serve(replication.BatchPath, "", entries); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without an origin, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	w := serve(replication.BatchPath, "eu-central", entries)
+	var details problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil || w.Code != http.StatusConflict || details.Code != problem.CodeReplicationLoop {
+		t.Errorf("Expected a replication loop conflict for the server's own ID, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = serve(replication.BatchPath, "us-east", entries)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// A retry of the peer only stores what is missing
+	w = serve(replication.BatchPath, "us-east", append(entries, newReplicatedTestLog("Replicated third")))
+	var response struct {
+		StoredCount int `json:"stored_count"`
+		Duplicates  int `json:"duplicates"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.StoredCount != 1 || response.Duplicates != 2 {
+		t.Errorf("Expected 1 stored entry and 2 duplicates, got %d: %s", w.Code, w.Body.String())
+	}
+
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{Limit: 10})
+	if err != nil || result.TotalCount != 3 {
+		t.Fatalf("Expected 3 stored entries, got %+v (%v)", result, err)
+	}
+
+	// Replicated entries are not replicated again, entries ingested here are
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if queued := replicator.Stats().Peers[0].Queued; queued != 0 {
+		t.Errorf("Expected replicated entries not to be queued for the peers, got %d batches", queued)
+	}
+
+	w = serve("/v1/logs/batch", "", []models.LogEntry{newReplicatedTestLog("Ingested here")})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if queued := replicator.Stats().Peers[0].Queued; queued != 1 {
+		t.Errorf("Expected the ingested batch to be queued for the peer, got %d batches", queued)
+	}
+
+	// The commit listener hears of replicated and ingested entries alike
+	if committed.entries != 4 {
+		t.Errorf("Expected the commit listener notified of 4 entries, got %d", committed.entries)
+	}
+}
+
+func TestServer_ReplicateBatchRequiresPermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	ingestKey, err := manager.CreateAPIKey("ingest", []auth.Permission{auth.PermissionIngestLogs}, 0, nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	server := NewServer(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil)
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	// Replicated entries skip data protection, so an ingestion key cannot store them
+	body, _ := json.Marshal([]models.LogEntry{newReplicatedTestLog("Unmasked")})
+	req, _ := http.NewRequest("POST", replication.BatchPath, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", ingestKey)
+	req.Header.Set(replication.OriginHeader, "us-east")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+}
@@ -14,8 +14,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
 	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
 )
 
 // FailingStorage simulates storage failures for testing resilience
@@ -50,12 +52,16 @@ func (fs *FailingStorage) Query(ctx context.Context, filter models.LogFilter) (*
 	return nil, nil
 }
 
+func (fs *FailingStorage) QueryStream(ctx context.Context, filter models.LogFilter) (storage.LogIterator, error) {
+	return storage.NewSliceIterator(nil), nil
+}
+
 func (fs *FailingStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
 	return nil, nil
 }
 
-func (fs *FailingStorage) GetServices(ctx context.Context) ([]models.ServiceInfo, error) {
-	return nil, nil
+func (fs *FailingStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	return &models.ServiceResult{}, nil
 }
 
 func (fs *FailingStorage) HealthCheck(ctx context.Context) models.HealthStatus {
@@ -105,7 +111,7 @@ func TestResilienceIntegration(t *testing.T) {
 		FlushTimeout: 100 * time.Millisecond, // Short timeout for testing
 	}
 
-	server := NewServer(8080, failingStorage, bufferConfig, tempDir)
+	server := NewServer(8080, failingStorage, bufferConfig, tempDir, auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 	// Start server context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -285,7 +291,7 @@ func TestErrorHandlingIntegration(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 	router := gin.New()
 	server.registerRoutes(router)
 
@@ -363,11 +369,9 @@ func TestErrorHandlingIntegration(t *testing.T) {
 					t.Fatalf("Failed to parse error response: %v", err)
 				}
 
-				if errorObj, ok := response["error"].(map[string]interface{}); ok {
-					if code, ok := errorObj["code"].(string); ok {
-						if code != tt.expectedError {
-							t.Errorf("Expected error code %s, got %s", tt.expectedError, code)
-						}
+				if code, ok := response["code"].(string); ok {
+					if code != tt.expectedError {
+						t.Errorf("Expected error code %s, got %s", tt.expectedError, code)
 					}
 				}
 			}
@@ -395,7 +399,7 @@ func TestRecoveryIntegration(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, tempDir)
+	server := NewServer(8080, mockStorage, bufferConfig, tempDir, auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 	// Test recovery stats endpoint
 	t.Run("recovery_stats_endpoint", func(t *testing.T) {
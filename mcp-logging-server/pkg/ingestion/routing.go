@@ -0,0 +1,43 @@
+package ingestion
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+)
+
+// handleGetRouting handles requests for the routing rules and the entries they matched
+func (s *Server) handleGetRouting(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"rules": s.routing.Rules(),
+		"stats": s.routing.Stats(),
+	})
+}
+
+// handleRoutingPreview handles requests for the decisions of the routing rules for a batch of
+// entries, normalized as at ingestion but neither validated nor stored
+func (s *Server) handleRoutingPreview(c *gin.Context) {
+	var entries []models.LogEntry
+	if err := codec.Decode(c.Request.Body, &entries); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeEmptyBatch, "Batch cannot be empty", "")
+		return
+	}
+	if len(entries) > MaxBatchSize {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeBatchTooLarge, fmt.Sprintf("Batch size cannot exceed %d entries", MaxBatchSize), fmt.Sprintf("Received %d entries, maximum allowed is %d", len(entries), MaxBatchSize))
+		return
+	}
+
+	s.normalizeEntries(entries, time.Now().UTC())
+	c.JSON(http.StatusOK, gin.H{
+		"decisions": s.routing.Preview(entries),
+	})
+}
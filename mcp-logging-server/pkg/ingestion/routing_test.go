@@ -0,0 +1,151 @@
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/routing"
+)
+
+func newRoutingServer(t *testing.T, mockStorage *MockStorage) (*Server, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := routing.New([]routing.Rule{
+		{Name: "drop-debug", Match: routing.Match{Levels: []models.LogLevel{models.LogLevelDebug}}, Action: routing.ActionDrop},
+		{Name: "tag-checkout", Match: routing.Match{Services: []string{"checkout"}}, Action: routing.ActionTag, Tags: []string{"orders"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create routing rules: %v", err)
+	}
+
+	bufferConfig := buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second}
+	server := NewServerWithOptions(8080, mockStorage, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{Routing: engine})
+
+	router := gin.New()
+	server.registerRoutes(router)
+	return server, router
+}
+
+func TestServer_RoutingRules(t *testing.T) {
+	mockStorage := &MockStorage{}
+	server, router := newRoutingServer(t, mockStorage)
+
+	body := []byte(`[
+		{"level": "DEBUG", "message": "Cache miss", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"},
+		{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"}
+	]`)
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	if len(mockStorage.storedLogs) != 1 {
+		t.Fatalf("Expected the debug entry to be dropped, got %d stored entries", len(mockStorage.storedLogs))
+	}
+	if stored := mockStorage.storedLogs[0]; len(stored.Tags) != 1 || stored.Tags[0] != "orders" {
+		t.Errorf("Expected the stored entry to be tagged, got %v", stored.Tags)
+	}
+
+	req, _ = http.NewRequest("GET", "/admin/routing", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var response struct {
+		Rules []routing.Rule `json:"rules"`
+		Stats routing.Stats  `json:"stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Rules) != 2 || response.Stats.Dropped != 1 {
+		t.Errorf("Expected 2 rules and 1 dropped entry, got %+v", response)
+	}
+}
+
+func TestServer_RoutingPreview(t *testing.T) {
+	mockStorage := &MockStorage{}
+	server, router := newRoutingServer(t, mockStorage)
+
+	body := []byte(`[
+		{"level": "debug", "message": "Cache miss", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"},
+		{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"}
+	]`)
+	req, _ := http.NewRequest("POST", "/admin/routing/preview", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Decisions []routing.Decision `json:"decisions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Decisions) != 2 {
+		t.Fatalf("Expected 2 decisions, got %d", len(response.Decisions))
+	}
+	if response.Decisions[0].Outcome != routing.OutcomeDrop {
+		t.Errorf("Expected the level alias to be normalized before the rules, got %+v", response.Decisions[0])
+	}
+	if decision := response.Decisions[1]; decision.Outcome != routing.OutcomeKeep || len(decision.Entry.Tags) != 1 {
+		t.Errorf("Expected the entry to be kept and tagged, got %+v", decision)
+	}
+
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	if len(mockStorage.storedLogs) != 0 || server.routing.Stats().Dropped != 0 {
+		t.Error("Expected previewed entries not to be stored or counted")
+	}
+}
+
+func TestServer_RoutingSetKeysProtected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	engine, err := routing.New([]routing.Rule{
+		{Name: "copy-header", Match: routing.Match{Expression: `metadata.header != nil`}, Action: routing.ActionSet, Set: map[string]string{"token": "metadata.header"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create routing rules: %v", err)
+	}
+	mockStorage := &MockStorage{}
+	bufferConfig := buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second}
+	server := NewServerWithOptions(8080, mockStorage, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{Routing: engine})
+	router := gin.New()
+	server.registerRoutes(router)
+
+	body := []byte(`[{"level": "INFO", "message": "Request", "service_name": "api", "agent_id": "edge-1", "platform": "go", "metadata": {"header": "s3cr3t-value"}}]`)
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	if len(mockStorage.storedLogs) != 1 {
+		t.Fatalf("Expected 1 stored entry, got %d", len(mockStorage.storedLogs))
+	}
+	if token := mockStorage.storedLogs[0].Metadata["token"]; token == "s3cr3t-value" || token == nil {
+		t.Errorf("Expected the key set by the rule to be masked, got %v", token)
+	}
+}
@@ -0,0 +1,163 @@
+package ingestion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// maxSearchLimit is the largest page size accepted by the search endpoint
+const maxSearchLimit = 1000
+
+// handleSearchLogs handles full-text search requests returning highlighted fragments
+func (s *Server) handleSearchLogs(c *gin.Context) {
+	queryText := strings.TrimSpace(c.Query("q"))
+	if queryText == "" {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidQuery, "Query parameter q is required", "")
+		return
+	}
+
+	filter, err := parseSearchFilter(c)
+	if err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidQuery, "Invalid search parameters", err.Error())
+		return
+	}
+
+	searcher, ok := s.storage.(storage.LogSearcher)
+	if !ok {
+		s.respondSearchDisabled(c)
+		return
+	}
+
+	result, err := searcher.SearchLogs(c.Request.Context(), queryText, filter)
+	if errors.Is(err, storage.ErrSearchDisabled) {
+		s.respondSearchDisabled(c)
+		return
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to search logs", err.Error())
+		return
+	}
+
+	auditResults(c, len(result.Hits))
+
+	response := gin.H{
+		"query":       queryText,
+		"hits":        result.Hits,
+		"total_count": result.TotalCount,
+		"has_more":    result.HasMore,
+		"limit":       filter.Limit,
+		"offset":      filter.Offset,
+	}
+	if len(result.Facets) > 0 {
+		response["facets"] = result.Facets
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondSearchDisabled responds that full-text search is unavailable
+func (s *Server) respondSearchDisabled(c *gin.Context) {
+	problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Full-text search is not enabled", "")
+}
+
+// parseSearchFilter builds a log filter from search query parameters
+func parseSearchFilter(c *gin.Context) (models.LogFilter, error) {
+	filter := models.LogFilter{
+		ServiceName: c.Query("service_name"),
+		AgentID:     c.Query("agent_id"),
+		RequestID:   c.Query("request_id"),
+		TemplateID:  c.Query("template_id"),
+		Level:       models.LogLevel(strings.ToUpper(c.Query("level"))),
+		MinLevel:    models.LogLevel(strings.ToUpper(c.Query("min_level"))),
+		Platform:    models.Platform(c.Query("platform")),
+		Sort:        models.SearchSort(c.Query("sort")),
+		TimeField:   models.TimeField(c.Query("time_field")),
+		TagsAny:     queryList(c, "tags_any"),
+		TagsAll:     queryList(c, "tags_all"),
+		Limit:       100,
+	}
+
+	if filter.MinLevel != "" && filter.MinLevel.Severity() == 0 {
+		return filter, fmt.Errorf("min_level must be DEBUG, INFO, WARN, ERROR or FATAL")
+	}
+
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+		filter.Limit = limit
+	}
+
+	if value := c.Query("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, fmt.Errorf("offset must be a non-negative integer")
+		}
+		filter.Offset = offset
+	}
+
+	if value := c.Query("fuzziness"); value != "" {
+		fuzziness, err := strconv.Atoi(value)
+		if err != nil || fuzziness < 0 || fuzziness > models.MaxSearchFuzziness {
+			return filter, fmt.Errorf("fuzziness must be between 0 and %d", models.MaxSearchFuzziness)
+		}
+		filter.Fuzziness = fuzziness
+	}
+
+	if value := c.Query("prefix"); value != "" {
+		prefix, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("prefix must be a boolean")
+		}
+		filter.Prefix = prefix
+	}
+
+	if !filter.Sort.IsValid() {
+		return filter, fmt.Errorf("sort must be %s or %s", models.SearchSortTime, models.SearchSortRelevance)
+	}
+
+	filter.Facets = queryList(c, "facets")
+	for _, name := range filter.Facets {
+		if !models.IsValidFacet(name) {
+			return filter, fmt.Errorf("unknown facet %q, expected one of: %s", name, strings.Join(models.SearchFacetNames(), ", "))
+		}
+	}
+
+	loc, err := models.LoadTimeZone(c.Query("time_zone"))
+	if err != nil {
+		return filter, err
+	}
+	now := time.Now()
+
+	if filter.StartTime, err = models.ParseTimeExpression(c.Query("start_time"), now, loc); err != nil {
+		return filter, fmt.Errorf("start_time: %w", err)
+	}
+	if filter.EndTime, err = models.ParseTimeExpression(c.Query("end_time"), now, loc); err != nil {
+		return filter, fmt.Errorf("end_time: %w", err)
+	}
+
+	return filter, nil
+}
+
+// queryList returns the values of a repeated or comma-separated query parameter
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range c.QueryArray(key) {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,72 @@
+package ingestion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_SearchLogs(t *testing.T) {
+	tmpDir := t.TempDir()
+	searchStorage, err := storage.NewSQLiteStorageWithSearch(filepath.Join(tmpDir, "search.db"), filepath.Join(tmpDir, "index"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer searchStorage.Close()
+
+	router := newServiceRegistryTestRouter(t, searchStorage)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{name: "missing query", url: "/v1/search", expectedStatus: http.StatusBadRequest},
+		{name: "invalid limit", url: "/v1/search?q=timeout&limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "invalid fuzziness", url: "/v1/search?q=timeout&fuzziness=3", expectedStatus: http.StatusBadRequest},
+		{name: "invalid prefix", url: "/v1/search?q=timeout&prefix=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "fuzzy prefix search", url: "/v1/search?q=timout&fuzziness=1&prefix=true", expectedStatus: http.StatusOK},
+		{name: "invalid sort", url: "/v1/search?q=timeout&sort=score", expectedStatus: http.StatusBadRequest},
+		{name: "relevance sort", url: "/v1/search?q=timeout&sort=relevance", expectedStatus: http.StatusOK},
+		{name: "unknown facet", url: "/v1/search?q=timeout&facets=level,agent", expectedStatus: http.StatusBadRequest},
+		{name: "search with facets", url: "/v1/search?q=timeout&facets=level,service&facets=day", expectedStatus: http.StatusOK},
+		{name: "invalid start time", url: "/v1/search?q=timeout&start_time=last+tuesday", expectedStatus: http.StatusBadRequest},
+		{name: "relative time range", url: "/v1/search?q=timeout&start_time=-2h&end_time=now", expectedStatus: http.StatusOK},
+		{name: "local time in zone", url: "/v1/search?q=timeout&start_time=2024-05-01+09:00&time_zone=Europe/Berlin", expectedStatus: http.StatusOK},
+		{name: "unknown time zone", url: "/v1/search?q=timeout&start_time=today&time_zone=Mars/Olympus", expectedStatus: http.StatusBadRequest},
+		{name: "valid search", url: "/v1/search?q=timeout&service_name=payment-service&tags_any=db,cache", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_SearchLogsDisabled(t *testing.T) {
+	sqliteStorage, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "search.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	router := newServiceRegistryTestRouter(t, sqliteStorage)
+
+	req, _ := http.NewRequest("GET", "/v1/search?q=timeout", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotImplemented, w.Code, w.Body.String())
+	}
+}
@@ -4,26 +4,50 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/accesslog"
 	"github.com/kerlexov/mcp-logging-server/pkg/auth"
 	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
 	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
 	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/openapi"
+	"github.com/kerlexov/mcp-logging-server/pkg/pool"
+	"github.com/kerlexov/mcp-logging-server/pkg/preflight"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/processor"
+	"github.com/kerlexov/mcp-logging-server/pkg/queryaudit"
 	"github.com/kerlexov/mcp-logging-server/pkg/ratelimit"
 	"github.com/kerlexov/mcp-logging-server/pkg/recovery"
+	"github.com/kerlexov/mcp-logging-server/pkg/replication"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+	"github.com/kerlexov/mcp-logging-server/pkg/routing"
+	"github.com/kerlexov/mcp-logging-server/pkg/sampling"
 	"github.com/kerlexov/mcp-logging-server/pkg/security"
+	"github.com/kerlexov/mcp-logging-server/pkg/sharding"
+	"github.com/kerlexov/mcp-logging-server/pkg/siem"
 	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	"github.com/kerlexov/mcp-logging-server/pkg/symbolication"
 	tlsconfig "github.com/kerlexov/mcp-logging-server/pkg/tls"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
 	"github.com/kerlexov/mcp-logging-server/pkg/validation"
 )
 
 // Server represents the log ingestion HTTP server
 type Server struct {
+	host                string
 	port                int
 	storage             storage.LogStorage
 	buffer              *buffer.MessageBuffer
@@ -38,17 +62,144 @@ type Server struct {
 	securityConfig      *security.SecurityConfig
 	dataProtection      *dataprotection.DataProtectionProcessor
 	auditStatsCollector *dataprotection.AuditStatsCollector
+	batchTracker        *BatchTracker
+	clockSkew           *ClockSkewConfig
+	levelNormalizer     *validation.LevelNormalizer
+	symbolicators       []Symbolicator
+	stackTraces         *symbolication.Symbolicator // Nil if the storage does not keep symbol files
+	syncMutex           sync.Mutex                  // Serializes delta sync uploads between the dedupe check and advancing the sequence
+	usage               *usageCounter               // Counts accepted entries until they are flushed to storage
+	siem                *siem.Forwarder             // Nil if audit events are not forwarded
+	replicator          *replication.Replicator     // Nil if stored batches are not replicated to peers
+	commitListener      buffer.CommitListener       // Notified of every stored batch besides the replicator, nil notifies nothing
+	router              *sharding.Router            // Nil unless services are sharded across writer nodes
+	routing             *routing.Engine             // Applies the routing rules to ingested entries
+	sampler             *sampling.Sampler           // Discards a fraction of the entries of noisy services
+	processors          *processor.Chain            // Nil if no custom processors are configured
+	accessLog           *accesslog.Logger           // Nil if requests are not logged
+	queryAudit          *queryaudit.Recorder        // Nil if queries are not audited
+	maintenance         *maintenance.Switch         // Read-only and maintenance mode, shared with the MCP server
+	tools               *toolswitch.Switch          // MCP tools switched at /admin/tools, nil if no MCP server runs
+	preflight           *preflight.Report           // Nil if the startup self-test did not run
+	openAPI             *openapi.Document           // Served at OpenAPIPath
+	shipperMapping      ShipperMapping              // Maps records posted to /v1/logs/shipper
+	requestSlots        chan struct{}               // Bounds the ingestion requests processed at once, nil is unbounded
+	asyncSlots          chan struct{}               // Bounds the async batches being stored at once
+	requestsWaited      atomic.Int64                // Ingestion requests that waited for a slot
+	archival            bool                        // Reported by /v1/capabilities, archiving runs outside the server
+}
+
+// Options contains optional configuration for the ingestion server
+type Options struct {
+	ClockSkew    *ClockSkewConfig
+	LevelAliases map[string]string // Custom level aliases on top of validation.DefaultLevelAliases
+	Platforms    []string          // Accepted platforms, defaults to models.DefaultPlatforms
+	Host         string            // Address the server listens on, empty for every interface
+	Validation   validation.Rules  // Rules applied to log entries on top of their struct tags
+
+	// Symbolicators resolve the raw frames of crash reports posted to /v1/crashes, in order
+	Symbolicators []Symbolicator
+
+	// Shipper maps the records of log shippers posted to /v1/logs/shipper, nil uses DefaultShipperMapping
+	Shipper *ShipperMapping
+
+	// SIEM receives data protection audit entries and admin API requests, nil forwards nothing
+	SIEM *siem.Forwarder
+
+	// Replicator receives every stored batch to replicate it to peer servers, nil replicates nothing
+	Replicator *replication.Replicator
+
+	// CommitListener is notified of every stored batch, including batches replicated from peers,
+	// such as to drop cached query results; nil notifies nothing
+	CommitListener buffer.CommitListener
+
+	// Router sends entries of services owned by other writer nodes to their owners, nil stores
+	// all entries on this node
+	Router *sharding.Router
+
+	// Routing tags, drops, re-levels or routes ingested entries to sinks before they are
+	// buffered, nil applies no rules
+	Routing *routing.Engine
+
+	// QueryAudit records every search with the API key that made it, nil records nothing
+	QueryAudit *queryaudit.Recorder
+
+	// Maintenance holds the mode switched at /admin/mode, shared with the MCP server so that
+	// maintenance mode stops tool calls too; nil creates a switch for this server alone
+	Maintenance *maintenance.Switch
+
+	// Tools enables and disables the tools of the MCP server, switched at /admin/tools; nil
+	// answers /admin/tools with 501, as in relay mode
+	Tools *toolswitch.Switch
+
+	// Sampler keeps a fraction of the entries of noisy services after validation, before the
+	// routing rules; nil keeps every entry
+	Sampler *sampling.Sampler
+
+	// Processors enrich and filter valid entries before data protection, nil runs none
+	Processors *processor.Chain
+
+	// AccessLog receives a record of every request, separate from the application log; nil
+	// logs none
+	AccessLog *accesslog.Logger
+
+	// Metrics records the operational metrics served at /metrics, so components outside the
+	// ingestion server can report to them too; nil creates metrics for this server alone
+	Metrics *metrics.Metrics
+
+	// Archival reports in /v1/capabilities that the database is continuously archived
+	Archival bool
+
+	// MaxConcurrentRequests bounds the ingestion requests processed at once, further requests
+	// wait for a slot until their deadline; 0 is unbounded
+	MaxConcurrentRequests int
+
+	// MaxAsyncBatches bounds the async batches being stored at once, further async batches are
+	// rejected with 503; 0 allows asyncBatchesPerCPU per CPU
+	MaxAsyncBatches int
+
+	// Fallback receives buffer flushes while the storage fails or its circuit breaker is open,
+	// nil disables failover. Entries are copied back every ReconcileInterval.
+	Fallback          storage.LogStorage
+	ReconcileInterval time.Duration
 }
 
 // NewServer creates a new ingestion server
 func NewServer(port int, storage storage.LogStorage, bufferConfig buffer.Config, recoveryDir string, authManager *auth.APIKeyManager, rateLimitConfig *ratelimit.RateLimitConfig, tlsConfig *tlsconfig.TLSConfig, securityConfig *security.SecurityConfig, dataProtectionConfig *dataprotection.DataProtectionConfig) *Server {
-	metricsReporter := metrics.NewMetrics()
+	return NewServerWithOptions(port, storage, bufferConfig, recoveryDir, authManager, rateLimitConfig, tlsConfig, securityConfig, dataProtectionConfig, Options{})
+}
+
+// NewServerWithOptions creates a new ingestion server with optional configuration
+func NewServerWithOptions(port int, storage storage.LogStorage, bufferConfig buffer.Config, recoveryDir string, authManager *auth.APIKeyManager, rateLimitConfig *ratelimit.RateLimitConfig, tlsConfig *tlsconfig.TLSConfig, securityConfig *security.SecurityConfig, dataProtectionConfig *dataprotection.DataProtectionConfig, options Options) *Server {
+	metricsReporter := options.Metrics
+	if metricsReporter == nil {
+		metricsReporter = metrics.NewMetrics()
+	}
 	recoveryManager := recovery.NewRecoveryManager(recoveryDir)
 
+	circuitBreaker := NewCircuitBreaker(5, 30*time.Second, 60*time.Second) // 5 failures, 30s timeout, 60s reset
+
 	bufferOptions := buffer.Options{
 		RecoveryManager: recoveryManager,
 		MetricsReporter: metricsReporter,
 	}
+	var listeners buffer.CommitListeners
+	if options.Replicator != nil {
+		listeners = append(listeners, options.Replicator)
+	}
+	if options.CommitListener != nil {
+		listeners = append(listeners, options.CommitListener)
+	}
+	if len(listeners) > 0 {
+		bufferOptions.CommitListener = listeners
+	}
+	if options.Fallback != nil {
+		bufferOptions.Failover = &buffer.FailoverConfig{
+			Fallback:          options.Fallback,
+			Breaker:           circuitBreaker,
+			ReconcileInterval: options.ReconcileInterval,
+		}
+	}
 
 	messageBuffer := buffer.NewMessageBufferWithOptions(storage, bufferConfig, bufferOptions)
 
@@ -65,6 +216,9 @@ func NewServer(port int, storage storage.LogStorage, bufferConfig buffer.Config,
 	if dataProtectionConfig == nil {
 		dataProtectionConfig = dataprotection.DefaultDataProtectionConfig()
 	}
+	if options.ClockSkew == nil {
+		options.ClockSkew = DefaultClockSkewConfig()
+	}
 
 	// Initialize data protection processor
 	dataProtectionProcessor, err := dataprotection.NewDataProtectionProcessor(dataProtectionConfig)
@@ -74,26 +228,103 @@ func NewServer(port int, storage storage.LogStorage, bufferConfig buffer.Config,
 		dataProtectionProcessor = nil
 	}
 
-	// Initialize audit stats collector
+	// Initialize level normalizer
+	levelNormalizer, err := validation.NewLevelNormalizer(options.LevelAliases)
+	if err != nil {
+		// Log error but continue with the default aliases
+		fmt.Printf("Failed to initialize level aliases: %v\n", err)
+		levelNormalizer, _ = validation.NewLevelNormalizer(nil)
+	}
+
+	// Initialize the log entry validator
+	validator, err := validation.NewLogValidatorWithRules(options.Platforms, options.Validation)
+	if err != nil {
+		// Log error but continue with the default rules
+		fmt.Printf("Failed to initialize validation rules: %v\n", err)
+		validator = validation.NewLogValidatorWithPlatforms(options.Platforms)
+	}
+
+	// Initialize audit stats collector, which report-only mode needs to measure matches
 	var auditStatsCollector *dataprotection.AuditStatsCollector
-	if dataProtectionConfig.AuditEnabled {
+	if dataProtectionConfig.AuditEnabled || dataProtectionConfig.ReportOnly {
 		auditStatsCollector = dataprotection.NewAuditStatsCollector()
+		if dataProtectionProcessor != nil {
+			dataProtectionProcessor.SetStatsCollector(auditStatsCollector)
+		}
+	}
+	if options.SIEM != nil && dataProtectionProcessor != nil {
+		dataProtectionProcessor.AddAuditSink(options.SIEM)
+	}
+
+	shipperMapping := DefaultShipperMapping()
+	if options.Shipper != nil {
+		shipperMapping = options.Shipper.withDefaults()
+	}
+
+	routingEngine := options.Routing
+	if routingEngine == nil {
+		routingEngine, _ = routing.New(nil, nil)
+	}
+	if dataProtectionProcessor != nil {
+		routingEngine.SetProtector(dataProtectionProcessor.ProcessMetadataContext)
+	}
+
+	sampler := options.Sampler
+	if sampler == nil {
+		sampler, _ = sampling.New(nil)
+	}
+
+	modeSwitch := options.Maintenance
+	if modeSwitch == nil {
+		modeSwitch = maintenance.New()
+	}
+
+	var requestSlots chan struct{}
+	if options.MaxConcurrentRequests > 0 {
+		requestSlots = make(chan struct{}, options.MaxConcurrentRequests)
+	}
+	maxAsyncBatches := options.MaxAsyncBatches
+	if maxAsyncBatches <= 0 {
+		maxAsyncBatches = asyncBatchesPerCPU * runtime.GOMAXPROCS(0)
 	}
 
 	return &Server{
+		host:                options.Host,
 		port:                port,
 		storage:             storage,
 		buffer:              messageBuffer,
 		metrics:             metricsReporter,
-		validator:           validation.NewLogValidator(),
+		validator:           validator,
 		recoveryManager:     recoveryManager,
 		rateLimiter:         ratelimit.NewRateLimiter(rateLimitConfig),
-		circuitBreaker:      NewCircuitBreaker(5, 30*time.Second, 60*time.Second), // 5 failures, 30s timeout, 60s reset
+		circuitBreaker:      circuitBreaker,
 		authManager:         authManager,
 		tlsConfig:           tlsConfig,
 		securityConfig:      securityConfig,
 		dataProtection:      dataProtectionProcessor,
 		auditStatsCollector: auditStatsCollector,
+		batchTracker:        NewBatchTracker(1 * time.Hour),
+		clockSkew:           options.ClockSkew,
+		levelNormalizer:     levelNormalizer,
+		symbolicators:       options.Symbolicators,
+		stackTraces:         symbolication.ForStorage(storage),
+		usage:               newUsageCounter(),
+		siem:                options.SIEM,
+		replicator:          options.Replicator,
+		commitListener:      options.CommitListener,
+		router:              options.Router,
+		routing:             routingEngine,
+		sampler:             sampler,
+		processors:          options.Processors,
+		accessLog:           options.AccessLog,
+		queryAudit:          options.QueryAudit,
+		maintenance:         modeSwitch,
+		tools:               options.Tools,
+		openAPI:             openapi.Build(apiInfo, apiRoutes()),
+		shipperMapping:      shipperMapping,
+		requestSlots:        requestSlots,
+		asyncSlots:          make(chan struct{}, maxAsyncBatches),
+		archival:            options.Archival,
 	}
 }
 
@@ -104,7 +335,13 @@ func (s *Server) Start(ctx context.Context) error {
 
 	router := gin.New()
 
-	// Apply security middleware first
+	// Assign request IDs first, so that every response and log line carries one
+	router.Use(requestid.Middleware())
+
+	// Count traffic before any middleware can reject the request, so that rejections are logged
+	router.Use(s.accessLogMiddleware())
+
+	// Apply security middleware
 	if err := security.ApplySecurityMiddleware(router, s.securityConfig); err != nil {
 		return fmt.Errorf("failed to apply security middleware: %w", err)
 	}
@@ -124,11 +361,13 @@ func (s *Server) Start(ctx context.Context) error {
 
 	// Create HTTP server
 	s.server = &http.Server{
-		Addr:         fmt.Sprintf(":%d", s.port),
+		Addr:         net.JoinHostPort(s.host, strconv.Itoa(s.port)),
 		Handler:      router,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		ConnState:    s.trackConnection,
+		ErrorLog:     log.New(&handshakeErrorCounter{metrics: s.metrics}, "", log.LstdFlags),
 	}
 
 	// Configure TLS if enabled
@@ -156,12 +395,20 @@ func (s *Server) Start(ctx context.Context) error {
 	// Start cleanup routine for old recovery files
 	go s.cleanupRoutine(ctx)
 
+	// Start flushing usage counts to storage
+	go s.usageRoutine(ctx)
+
 	// Start server in a goroutine
 	go func() {
 		var err error
 		if s.tlsConfig.Enabled {
 			fmt.Printf("Starting HTTPS ingestion server on port %d\n", s.port)
-			err = s.server.ListenAndServeTLS(s.tlsConfig.CertFile, s.tlsConfig.KeyFile)
+			// A certificate store serves the certificate through TLSConfig.GetCertificate
+			certFile, keyFile := s.tlsConfig.CertFile, s.tlsConfig.KeyFile
+			if s.tlsConfig.Certificates != nil {
+				certFile, keyFile = "", ""
+			}
+			err = s.server.ListenAndServeTLS(certFile, keyFile)
 		} else {
 			fmt.Printf("Starting HTTP ingestion server on port %d\n", s.port)
 			err = s.server.ListenAndServe()
@@ -187,6 +434,19 @@ func (s *Server) Start(ctx context.Context) error {
 	return s.server.Shutdown(shutdownCtx)
 }
 
+// SetHashSalt replaces the salt of fields hashed by data protection
+func (s *Server) SetHashSalt(salt string) {
+	if s.dataProtection != nil {
+		s.dataProtection.SetHashSalt(salt)
+	}
+}
+
+// SetPreflightReport sets the startup self-test report served by the health check. Failed
+// checks mark the server degraded. It must be called before Start.
+func (s *Server) SetPreflightReport(report *preflight.Report) {
+	s.preflight = report
+}
+
 // Stop stops the ingestion server
 func (s *Server) Stop() error {
 	// Stop buffer first
@@ -196,6 +456,10 @@ func (s *Server) Stop() error {
 		}
 	}
 
+	if err := s.flushUsage(context.Background()); err != nil {
+		fmt.Printf("Failed to flush usage: %v\n", err)
+	}
+
 	if s.server != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
@@ -206,8 +470,10 @@ func (s *Server) Stop() error {
 
 // registerRoutes registers all HTTP routes
 func (s *Server) registerRoutes(router *gin.Engine) {
-	// Health check endpoint (public)
+	// Health check, API description and capabilities endpoints (public)
 	router.GET("/health", s.handleHealthCheck)
+	router.GET(OpenAPIPath, s.handleOpenAPI)
+	router.GET(CapabilitiesPath, s.handleCapabilities)
 
 	// Metrics and stats endpoints (require metrics permission)
 	metricsGroup := router.Group("/")
@@ -217,26 +483,106 @@ func (s *Server) registerRoutes(router *gin.Engine) {
 		metricsGroup.GET("/stats", s.handleBufferStats)
 		metricsGroup.GET("/recovery/stats", s.handleRecoveryStats)
 		metricsGroup.GET("/circuit-breaker/stats", s.handleCircuitBreakerStats)
+		metricsGroup.GET("/replication/stats", s.handleReplicationStats)
+		metricsGroup.GET("/sharding/stats", s.handleShardingStats)
 	}
 
 	// Admin endpoints (require admin permission)
 	adminGroup := router.Group("/admin")
+	if s.siem != nil {
+		// Before the permission check, so that rejected requests are forwarded too
+		adminGroup.Use(s.adminAuditMiddleware())
+	}
 	adminGroup.Use(auth.RequirePermission(s.authManager, auth.PermissionAdmin))
 	adminGroup.Use(ratelimit.AdminRateLimitMiddleware(s.rateLimiter))
-	adminGroup.Use(dataprotection.AdminDataProtectionMiddleware(s.dataProtection, s.auditStatsCollector))
 	{
 		adminGroup.POST("/circuit-breaker/reset", s.handleCircuitBreakerReset)
 		adminGroup.POST("/flush", s.handleFlushBuffer)
+		adminGroup.POST("/query-plan", s.handleExplainQuery)
+		adminGroup.POST("/verify", s.handleVerifyIntegrity)
+		adminGroup.GET("/routing", s.handleGetRouting)
+		adminGroup.POST("/routing/preview", s.handleRoutingPreview)
+		adminGroup.GET("/legal-holds", s.handleListLegalHolds)
+		adminGroup.POST("/legal-holds", s.handlePlaceLegalHold)
+		adminGroup.DELETE("/legal-holds/:id", s.handleReleaseLegalHold)
+		adminGroup.GET("/symbols/:service", s.handleListSymbolFiles)
+		adminGroup.PUT("/symbols/:service/:version/:name", s.handleUploadSymbolFile)
+		adminGroup.DELETE("/symbols/:service/:version/:name", s.handleDeleteSymbolFile)
+		adminGroup.GET("/api-keys", s.handleListAPIKeys)
+		adminGroup.PUT("/api-keys/:id/rate-limit", s.handleSetAPIKeyRateLimit)
+		adminGroup.GET("/usage", s.handleGetUsage)
+		adminGroup.GET("/query-audit", s.handleGetQueryAudit)
+		adminGroup.GET("/mode", s.handleGetMode)
+		adminGroup.PUT("/mode", s.handleSetMode)
+		adminGroup.GET("/tools", s.handleListTools)
+		adminGroup.PUT("/tools/:name", s.handleSetTool)
+		adminGroup.POST("/services/purge", s.handlePurgeServices)
 		// Rate limit management endpoints are handled by AdminRateLimitMiddleware
-		// Data protection management endpoints are handled by AdminDataProtectionMiddleware
 	}
 
+	// Data protection endpoints (reads require dataprotection_read, changes dataprotection_write)
+	dataProtectionGroup := router.Group("/admin/data-protection")
+	if s.siem != nil {
+		dataProtectionGroup.Use(s.adminAuditMiddleware())
+	}
+	dataprotection.RegisterAdminRoutes(
+		dataProtectionGroup.Group("", auth.RequirePermission(s.authManager, auth.PermissionDataProtectionRead)),
+		dataProtectionGroup.Group("", auth.RequirePermission(s.authManager, auth.PermissionDataProtectionWrite)),
+		s.dataProtection,
+		s.auditStatsCollector,
+	)
+
+	// Endpoints reading or changing logs follow the mode set at /admin/mode, health, metrics
+	// and admin endpoints are always served
+	mode := s.modeMiddleware()
+
 	// Log ingestion endpoints (require ingest_logs permission)
 	v1 := router.Group("/v1")
-	v1.Use(auth.RequirePermission(s.authManager, auth.PermissionIngestLogs))
+	v1.Use(auth.RequirePermission(s.authManager, auth.PermissionIngestLogs), mode, s.concurrencyMiddleware())
 	{
 		v1.POST("/logs", s.handleIngestLogs)
 		v1.POST("/logs/batch", s.handleIngestLogsBatch)
+		v1.POST("/logs/batch/async", s.handleIngestLogsBatchAsync)
+		v1.POST("/logs/sync", s.handleSyncLogs)
+		v1.GET("/logs/sync/:agent_id", s.handleGetSyncState)
+		v1.GET("/batches/:token", s.handleGetBatchStatus)
+		v1.POST("/crashes", s.handleIngestCrash)
+		v1.POST("/logs/shipper", s.handleIngestShipper)
+	}
+
+	// Batches replicated by peers skip data protection, which the origin server applied
+	router.POST(replication.BatchPath, auth.RequirePermission(s.authManager, auth.PermissionReplicateLogs), mode, s.concurrencyMiddleware(), s.handleReplicateBatch)
+
+	// Entries routed by other nodes of a sharded cluster were processed by the node they were sent to
+	router.POST(sharding.RoutePath, auth.RequirePermission(s.authManager, auth.PermissionRouteLogs), mode, s.concurrencyMiddleware(), s.handleRoutedBatch)
+
+	// Full-text search endpoint (requires query_logs permission), the only one returning entries
+	searchHandlers := []gin.HandlerFunc{auth.RequirePermission(s.authManager, auth.PermissionQueryLogs), mode}
+	if s.queryAudit != nil {
+		// Before the permission check, so that rejected requests are audited too
+		searchHandlers = append([]gin.HandlerFunc{s.queryAuditMiddleware()}, searchHandlers...)
+	}
+	router.GET("/v1/search", append(searchHandlers, s.handleSearchLogs)...)
+
+	// Annotations leave the entry unchanged, so operators who can read logs can add them
+	router.POST("/v1/logs/:id/annotations", auth.RequirePermission(s.authManager, auth.PermissionQueryLogs), mode, s.handleAnnotateLog)
+
+	// Incidents only reference entries, so operators who can read logs can manage them
+	incidents := router.Group("/v1/incidents", auth.RequirePermission(s.authManager, auth.PermissionQueryLogs), mode)
+	{
+		incidents.GET("", s.handleListIncidents)
+		incidents.POST("", s.handleCreateIncident)
+		incidents.GET("/:id", s.handleGetIncident)
+		incidents.POST("/:id/logs", s.handleAttachToIncident)
+	}
+
+	// Service registry endpoints (reads need query_logs, writes ingest_logs, deletes admin)
+	services := router.Group("/v1/services", mode)
+	{
+		services.GET("", auth.RequirePermission(s.authManager, auth.PermissionQueryLogs), s.handleListServiceRegistrations)
+		services.GET("/:name", auth.RequirePermission(s.authManager, auth.PermissionQueryLogs), s.handleGetServiceRegistration)
+		services.PUT("/:name", auth.RequirePermission(s.authManager, auth.PermissionIngestLogs), s.handleRegisterService)
+		services.DELETE("/:name", auth.RequirePermission(s.authManager, auth.PermissionAdmin), s.handleDeleteServiceRegistration)
 	}
 }
 
@@ -245,11 +591,12 @@ func (s *Server) handleHealthCheck(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
 	defer cancel()
 
-	// Check storage health with circuit breaker protection
+	// Check storage health with circuit breaker protection. A degraded storage, such as a
+	// lagging read replica, still takes writes and does not trip the breaker.
 	var healthStatus models.HealthStatus
 	err := s.circuitBreaker.Execute(func() error {
 		healthStatus = s.storage.HealthCheck(ctx)
-		if healthStatus.Status != "healthy" {
+		if healthStatus.Status == "unhealthy" {
 			return errors.New("storage unhealthy")
 		}
 		return nil
@@ -272,6 +619,8 @@ func (s *Server) handleHealthCheck(c *gin.Context) {
 		statusCode = http.StatusServiceUnavailable
 	} else if bufferStats.Size > int(float64(bufferStats.Capacity)*0.9) {
 		overallStatus = "degraded" // Buffer is nearly full
+	} else if s.preflight != nil && !s.preflight.Passed {
+		overallStatus = "degraded" // Started despite failed startup checks
 	}
 
 	response := gin.H{
@@ -298,6 +647,25 @@ func (s *Server) handleHealthCheck(c *gin.Context) {
 			"storage_errors":    metricsSnapshot.StorageErrors,
 		},
 	}
+	if bufferStats.Failover != nil {
+		response["failover"] = bufferStats.Failover
+	}
+	if s.siem != nil {
+		response["siem"] = s.siem.Stats()
+	}
+	if s.replicator != nil {
+		response["replication"] = s.replicator.Stats()
+	}
+	if s.router != nil {
+		response["sharding"] = s.router.Stats()
+	}
+	if s.processors != nil {
+		response["processors"] = s.processors.Stats()
+	}
+	if s.preflight != nil {
+		response["preflight"] = s.preflight
+	}
+	response["mode"] = s.maintenance.State()
 
 	c.JSON(statusCode, response)
 }
@@ -309,75 +677,88 @@ func (s *Server) handleIngestLogs(c *gin.Context) {
 	var logEntry models.LogEntry
 
 	// Parse JSON request body
-	if err := c.ShouldBindJSON(&logEntry); err != nil {
+	start := time.Now()
+	err := codec.Decode(c.Request.Body, &logEntry)
+	s.observeStage(metrics.StageDecode, start)
+	if err != nil {
 		s.metrics.IncrementRequestsFailed()
 		s.metrics.IncrementValidationErrors()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "INVALID_JSON",
-				"message": "Invalid JSON format",
-				"details": err.Error(),
-			},
-		})
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
 		return
 	}
 
+	start = time.Now()
+
 	// Generate ID if not provided
 	if logEntry.ID == "" {
 		logEntry.ID = uuid.New().String()
 	}
 
-	// Set timestamp if not provided
-	if logEntry.Timestamp.IsZero() {
-		logEntry.Timestamp = time.Now().UTC()
+	// Record receive time, fill in a missing timestamp and correct clock skew
+	s.clockSkew.Apply(&logEntry, time.Now().UTC())
+
+	// Map level aliases to the canonical levels
+	logEntry.Level = s.levelNormalizer.Normalize(logEntry.Level)
+
+	// Clients that predate the request_id field log it as metadata
+	promoteRequestID(&logEntry)
+
+	// Group crash reports by cause unless the client chose its own grouping
+	if logEntry.Crash != nil && logEntry.Crash.Signature == "" {
+		logEntry.Crash.Signature = logEntry.Crash.ComputeSignature()
 	}
 
 	// Enhanced validation
 	validationResult := s.validator.ValidateLogEntry(&logEntry)
+	s.observeStage(metrics.StageValidation, start)
 	if !validationResult.IsValid {
 		s.metrics.IncrementRequestsFailed()
 		s.metrics.IncrementValidationErrors()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": "Log entry validation failed",
-				"details": validationResult.Errors,
-			},
-		})
+		s.countValidationRules(validationResult.Errors)
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Log entry validation failed", validationResult.Errors)
 		return
 	}
 
+	start = time.Now()
+	s.symbolicateStackTrace(c.Request.Context(), &logEntry)
+	s.observeStage(metrics.StageSymbolication, start)
+
+	// Run the custom processors and the sampling policies, which may drop the entry
+	entries, err := s.process(c.Request.Context(), []models.LogEntry{logEntry})
+	if err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeProcessorError, "Failed to process log entry", err.Error())
+		return
+	}
+	entries = s.sample(entries)
+
 	// Apply data protection
 	if s.dataProtection != nil {
-		if err := s.dataProtection.ProcessLogEntry(&logEntry); err != nil {
+		start = time.Now()
+		for i := range entries {
+			if err = s.dataProtection.ProcessLogEntryContext(c.Request.Context(), &entries[i]); err != nil {
+				break
+			}
+		}
+		s.observeStage(metrics.StageDataProtection, start)
+		if err != nil {
 			s.metrics.IncrementRequestsFailed()
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": gin.H{
-					"code":    "DATA_PROTECTION_ERROR",
-					"message": "Failed to apply data protection",
-					"details": err.Error(),
-				},
-			})
+			problem.Respond(c, http.StatusInternalServerError, problem.CodeDataProtectionError, "Failed to apply data protection", err.Error())
 			return
 		}
 	}
 
 	// Add to buffer
-	if err := s.buffer.Add([]models.LogEntry{logEntry}); err != nil {
+	if err := s.bufferEntries(c.Request.Context(), entries); err != nil {
 		s.metrics.IncrementRequestsFailed()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "BUFFER_ERROR",
-				"message": "Failed to buffer log entry",
-				"details": err.Error(),
-			},
-		})
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to buffer log entry", err.Error())
 		return
 	}
 
+	s.recordUsage(c, entries)
 	s.metrics.IncrementRequestsSuccessful()
-	s.metrics.IncrementLogsIngested(1)
-	s.metrics.IncrementLogsBuffered(1)
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Log entry buffered successfully",
@@ -389,137 +770,403 @@ func (s *Server) handleIngestLogs(c *gin.Context) {
 func (s *Server) handleIngestLogsBatch(c *gin.Context) {
 	s.metrics.IncrementRequestsTotal()
 
-	var logEntries []models.LogEntry
+	entries, ok := s.prepareBatch(c)
+	if !ok {
+		return
+	}
+
+	// Add to buffer
+	if err := s.bufferEntries(c.Request.Context(), entries); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to buffer log entries", err.Error())
+		return
+	}
+
+	s.recordUsage(c, entries)
+	s.metrics.IncrementRequestsSuccessful()
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Log entries buffered successfully",
+		"buffered_count": len(entries),
+		"total_count":    len(entries),
+	})
+}
+
+// asyncBatchesPerCPU is the number of async batches stored at once per CPU by default
+const asyncBatchesPerCPU = 4
+
+// asyncRetryAfter is the Retry-After of async batches rejected while too many are being stored,
+// in seconds
+const asyncRetryAfter = 1
+
+// handleIngestLogsBatchAsync accepts a batch and returns a token that can be
+// polled until the batch has been durably stored
+func (s *Server) handleIngestLogsBatchAsync(c *gin.Context) {
+	s.metrics.IncrementRequestsTotal()
+
+	entries, ok := s.prepareBatch(c)
+	if !ok {
+		return
+	}
+
+	// Async batches bypass the buffer and its backpressure, so the batches stored at once are
+	// bounded and clients are told to retry beyond that
+	select {
+	case s.asyncSlots <- struct{}{}:
+	default:
+		s.metrics.IncrementRequestsFailed()
+		c.Header("Retry-After", strconv.Itoa(asyncRetryAfter))
+		problem.Respond(c, http.StatusServiceUnavailable, problem.CodeServerBusy, "Too many async batches are being stored", fmt.Sprintf("At most %d async batches are stored at once", cap(s.asyncSlots)))
+		return
+	}
+
+	local, err := s.routeEntries(c.Request.Context(), entries)
+	if err != nil {
+		<-s.asyncSlots
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to route log entries", err.Error())
+		return
+	}
+
+	// Routed entries are buffered by the nodes owning them, the token tracks those stored here
+	token := s.batchTracker.Register(len(local))
+	if len(local) > 0 {
+		go func() {
+			defer func() { <-s.asyncSlots }()
+			s.storeBatchAsync(token, local)
+		}()
+	} else {
+		<-s.asyncSlots
+		s.batchTracker.MarkStored(token)
+	}
+
+	s.recordUsage(c, entries)
+	s.metrics.IncrementRequestsSuccessful()
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":     "Log entries accepted for storage",
+		"token":       token,
+		"total_count": len(entries),
+		"status_url":  "/v1/batches/" + token,
+	})
+}
+
+// handleGetBatchStatus handles batch delivery status requests
+func (s *Server) handleGetBatchStatus(c *gin.Context) {
+	token := c.Param("token")
+
+	record, exists := s.batchTracker.Get(token)
+	if !exists {
+		problem.Respond(c, http.StatusNotFound, problem.CodeBatchNotFound, "Unknown or expired batch token", token)
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// storeBatchAsync writes a batch straight to storage and records the outcome
+func (s *Server) storeBatchAsync(token string, entries []models.LogEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := s.circuitBreaker.Execute(func() error {
+		return s.storage.Store(ctx, entries)
+	})
+	if err != nil {
+		s.metrics.IncrementStorageErrors()
+
+		// Keep the entries around so they are retried on the next start
+		if saveErr := s.recoveryManager.SavePendingLogs(entries); saveErr != nil {
+			fmt.Printf("Failed to save batch %s for recovery: %v\n", token, saveErr)
+		}
+
+		s.batchTracker.MarkFailed(token, err)
+		return
+	}
+
+	if s.replicator != nil {
+		s.replicator.Committed(entries)
+	}
+	if s.commitListener != nil {
+		s.commitListener.Committed(entries)
+	}
+	s.batchTracker.MarkStored(token)
+}
+
+// prepareBatch parses, validates and applies data protection to a batch request.
+// It writes the error response itself and returns false if the batch was rejected.
+func (s *Server) prepareBatch(c *gin.Context) ([]models.LogEntry, bool) {
+	// Validation copies the valid entries out of the decoded batch, so its slice is reused
+	logEntries := pool.GetEntries(0)
+	defer func() { pool.PutEntries(logEntries) }()
 
 	// Parse JSON request body
-	if err := c.ShouldBindJSON(&logEntries); err != nil {
+	start := time.Now()
+	err := codec.Decode(c.Request.Body, &logEntries)
+	s.observeStage(metrics.StageDecode, start)
+	if err != nil {
 		s.metrics.IncrementRequestsFailed()
 		s.metrics.IncrementValidationErrors()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "INVALID_JSON",
-				"message": "Invalid JSON format",
-				"details": err.Error(),
-			},
-		})
-		return
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return nil, false
 	}
 
+	return s.prepareEntries(c, logEntries)
+}
+
+// prepareEntries validates and applies data protection to parsed batch entries.
+// It writes the error response itself and returns false if the batch was rejected.
+func (s *Server) prepareEntries(c *gin.Context, logEntries []models.LogEntry) ([]models.LogEntry, bool) {
 	// Validate batch size
 	if len(logEntries) == 0 {
 		s.metrics.IncrementRequestsFailed()
 		s.metrics.IncrementValidationErrors()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "EMPTY_BATCH",
-				"message": "Batch cannot be empty",
-			},
-		})
-		return
+		problem.Respond(c, http.StatusBadRequest, problem.CodeEmptyBatch, "Batch cannot be empty", "")
+		return nil, false
 	}
 
-	if len(logEntries) > 1000 {
+	if len(logEntries) > MaxBatchSize {
 		s.metrics.IncrementRequestsFailed()
 		s.metrics.IncrementValidationErrors()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "BATCH_TOO_LARGE",
-				"message": "Batch size cannot exceed 1000 entries",
-				"details": fmt.Sprintf("Received %d entries, maximum allowed is 1000", len(logEntries)),
-			},
-		})
-		return
+		problem.Respond(c, http.StatusBadRequest, problem.CodeBatchTooLarge, fmt.Sprintf("Batch size cannot exceed %d entries", MaxBatchSize), fmt.Sprintf("Received %d entries, maximum allowed is %d", len(logEntries), MaxBatchSize))
+		return nil, false
 	}
 
 	// Process each log entry with enhanced validation
+	start := time.Now()
+	s.normalizeEntries(logEntries, start.UTC())
+
+	// Batch validation
+	batchResult := s.validator.ValidateLogBatch(logEntries)
+	s.observeStage(metrics.StageValidation, start)
+
+	// Return validation errors if any invalid entries
+	if batchResult.InvalidCount > 0 {
+		s.metrics.IncrementRequestsFailed()
+		s.metrics.IncrementValidationErrors()
+		for _, invalid := range batchResult.InvalidEntries {
+			s.countValidationRules(invalid.Errors)
+		}
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, fmt.Sprintf("%d out of %d entries failed validation", batchResult.InvalidCount, batchResult.TotalEntries), batchResult.InvalidEntries)
+		return nil, false
+	}
+
+	start = time.Now()
+	for i := range batchResult.ValidEntries {
+		s.symbolicateStackTrace(c.Request.Context(), &batchResult.ValidEntries[i])
+	}
+	s.observeStage(metrics.StageSymbolication, start)
+
+	// Run the custom processors and the sampling policies, which may drop entries
+	entries, err := s.process(c.Request.Context(), batchResult.ValidEntries)
+	if err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeProcessorError, "Failed to process log entries", err.Error())
+		return nil, false
+	}
+	entries = s.sample(entries)
+
+	// Apply data protection to valid entries
+	if s.dataProtection != nil {
+		start = time.Now()
+		err := dataprotection.ProcessLogEntries(c.Request.Context(), s.dataProtection, entries)
+		s.observeStage(metrics.StageDataProtection, start)
+		if err != nil {
+			s.metrics.IncrementRequestsFailed()
+			problem.Respond(c, http.StatusInternalServerError, problem.CodeDataProtectionError, "Failed to apply data protection", err.Error())
+			return nil, false
+		}
+	}
+
+	return entries, true
+}
+
+// normalizeEntries fills in the fields the server derives before validation
+func (s *Server) normalizeEntries(logEntries []models.LogEntry, receivedAt time.Time) {
 	for i := range logEntries {
 		// Generate ID if not provided
 		if logEntries[i].ID == "" {
 			logEntries[i].ID = uuid.New().String()
 		}
 
-		// Set timestamp if not provided
-		if logEntries[i].Timestamp.IsZero() {
-			logEntries[i].Timestamp = time.Now().UTC()
+		// Record receive time, fill in a missing timestamp and correct clock skew
+		s.clockSkew.Apply(&logEntries[i], receivedAt)
+
+		// Map level aliases to the canonical levels
+		logEntries[i].Level = s.levelNormalizer.Normalize(logEntries[i].Level)
+
+		// Clients that predate the request_id field log it as metadata
+		promoteRequestID(&logEntries[i])
+
+		// Group crash reports by cause unless the client chose its own grouping
+		if crash := logEntries[i].Crash; crash != nil && crash.Signature == "" {
+			crash.Signature = crash.ComputeSignature()
 		}
 	}
+}
 
-	// Batch validation
-	batchResult := s.validator.ValidateLogBatch(logEntries)
+// maxRequestIDLength is the longest request ID the RequestID field of an entry may hold
+const maxRequestIDLength = 128
+
+// promoteRequestID moves a request_id metadata value into the RequestID field of an entry that
+// has none, so it can be filtered on like the field. Values too long for the field stay metadata.
+func promoteRequestID(entry *models.LogEntry) {
+	if entry.RequestID != "" {
+		return
+	}
+	requestID, ok := entry.Metadata["request_id"].(string)
+	if !ok || requestID == "" || len(requestID) > maxRequestIDLength {
+		return
+	}
+	entry.RequestID = requestID
+	delete(entry.Metadata, "request_id")
+	if len(entry.Metadata) == 0 {
+		entry.Metadata = nil
+	}
+}
+
+// Ingest normalizes, validates and buffers entries collected by the server itself rather than
+// posted by a client, such as Kubernetes events. Invalid entries are dropped and counted as
+// validation errors, it returns the number of entries buffered.
+func (s *Server) Ingest(ctx context.Context, entries []models.LogEntry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	if !s.maintenance.AllowsWrites() {
+		return 0, fmt.Errorf("server is in %s mode", s.maintenance.State().Mode)
+	}
+
+	start := time.Now()
+	s.normalizeEntries(entries, start.UTC())
+	batchResult := s.validator.ValidateLogBatch(entries)
+	s.observeStage(metrics.StageValidation, start)
 
-	// Return validation errors if any invalid entries
 	if batchResult.InvalidCount > 0 {
-		s.metrics.IncrementRequestsFailed()
 		s.metrics.IncrementValidationErrors()
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"code":    "VALIDATION_ERROR",
-				"message": fmt.Sprintf("%d out of %d entries failed validation", batchResult.InvalidCount, batchResult.TotalEntries),
-				"details": batchResult.InvalidEntries,
-			},
-		})
-		return
+		for _, invalid := range batchResult.InvalidEntries {
+			s.countValidationRules(invalid.Errors)
+		}
+	}
+	if batchResult.ValidCount == 0 {
+		reason := "invalid entry"
+		if errs := batchResult.InvalidEntries[0].Errors; len(errs) > 0 {
+			reason = errs[0].Field + ": " + errs[0].Message
+		}
+		return 0, fmt.Errorf("all %d entries failed validation, first: %s", batchResult.TotalEntries, reason)
 	}
 
-	// Apply data protection to valid entries
+	entries, err := s.process(ctx, batchResult.ValidEntries)
+	if err != nil {
+		return 0, err
+	}
+	entries = s.sample(entries)
+
 	if s.dataProtection != nil {
-		if err := dataprotection.ProcessLogEntries(s.dataProtection, batchResult.ValidEntries); err != nil {
-			s.metrics.IncrementRequestsFailed()
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": gin.H{
-					"code":    "DATA_PROTECTION_ERROR",
-					"message": "Failed to apply data protection",
-					"details": err.Error(),
-				},
-			})
-			return
+		start = time.Now()
+		err := dataprotection.ProcessLogEntries(ctx, s.dataProtection, entries)
+		s.observeStage(metrics.StageDataProtection, start)
+		if err != nil {
+			return 0, fmt.Errorf("failed to apply data protection: %w", err)
 		}
 	}
 
-	// Add to buffer
-	if err := s.buffer.Add(batchResult.ValidEntries); err != nil {
-		s.metrics.IncrementRequestsFailed()
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "BUFFER_ERROR",
-				"message": "Failed to buffer log entries",
-				"details": err.Error(),
-			},
-		})
-		return
+	if err := s.bufferEntries(ctx, entries); err != nil {
+		return 0, err
 	}
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
+	return len(entries), nil
+}
 
-	s.metrics.IncrementRequestsSuccessful()
-	s.metrics.IncrementLogsIngested(int64(len(batchResult.ValidEntries)))
-	s.metrics.IncrementLogsBuffered(int64(len(batchResult.ValidEntries)))
+// process runs the custom processors on valid entries and returns the entries they keep
+func (s *Server) process(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	if s.processors == nil {
+		return entries, nil
+	}
+	start := time.Now()
+	entries, err := s.processors.Process(ctx, entries)
+	s.observeStage(metrics.StageProcessors, start)
+	return entries, err
+}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":        "Log entries buffered successfully",
-		"buffered_count": batchResult.ValidCount,
-		"total_count":    batchResult.TotalEntries,
+// sample applies the sampling policies and returns the entries they keep. Entries sampled out
+// are accepted but neither stored nor accounted to the API key.
+func (s *Server) sample(entries []models.LogEntry) []models.LogEntry {
+	return s.sampler.Apply(entries, func(entry *models.LogEntry) {
+		s.metrics.IncrementSampledOut(entry.ServiceName, string(entry.Level))
 	})
 }
 
+// bufferEntries adds the entries of a request to the buffer, recording the time it took. In a
+// sharded cluster, entries of services owned by other nodes are sent to their owners first.
+func (s *Server) bufferEntries(ctx context.Context, entries []models.LogEntry) error {
+	entries, err := s.routeEntries(ctx, entries)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	start := time.Now()
+	err = s.buffer.Add(entries)
+	s.observeStage(metrics.StageBufferAdd, start)
+	return err
+}
+
+// routeEntries applies the routing rules, sends the entries of services owned by other nodes to
+// their owners and returns the entries this node stores. Entries dropped or routed to a sink by
+// the rules are accepted but not stored.
+func (s *Server) routeEntries(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	entries = s.routing.Apply(ctx, entries)
+	stampTemplateIDs(entries)
+	if s.router == nil || len(entries) == 0 {
+		return entries, nil
+	}
+	start := time.Now()
+	local, err := s.router.Route(ctx, entries)
+	s.observeStage(metrics.StageRouting, start)
+	return local, err
+}
+
+// stampTemplateIDs sets the template ID of entries from their message as it is stored, after data
+// protection and routing rules changed it. IDs sent by clients are replaced, as only IDs computed
+// by the server group entries consistently.
+func stampTemplateIDs(entries []models.LogEntry) {
+	for i := range entries {
+		entries[i].TemplateID = models.MessageTemplateID(entries[i].Message)
+	}
+}
+
+// observeStage records the time spent in a stage of handling an ingestion request since start
+func (s *Server) observeStage(stage string, start time.Time) {
+	s.metrics.ObserveIngestionStage(stage, time.Since(start))
+}
+
+// countValidationRules counts the failures of each validation rule
+func (s *Server) countValidationRules(errors []validation.ValidationError) {
+	for _, validationError := range errors {
+		if validationError.Rule != "" {
+			s.metrics.IncrementValidationRule(validationError.Rule)
+		}
+	}
+}
+
 // handleBufferStats handles buffer statistics requests
 func (s *Server) handleBufferStats(c *gin.Context) {
 	stats := s.buffer.GetStats()
 
 	c.JSON(http.StatusOK, gin.H{
-		"buffer_stats": stats,
-		"timestamp":    time.Now().UTC(),
+		"buffer_stats":      stats,
+		"concurrency_stats": s.concurrencyStats(),
+		"timestamp":         time.Now().UTC(),
 	})
 }
 
 // handleFlushBuffer handles manual buffer flush requests
 func (s *Server) handleFlushBuffer(c *gin.Context) {
 	if err := s.buffer.Flush(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "FLUSH_ERROR",
-				"message": "Failed to flush buffer",
-				"details": err.Error(),
-			},
-		})
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeFlushError, "Failed to flush buffer", err.Error())
 		return
 	}
 
@@ -533,6 +1180,15 @@ func (s *Server) handleFlushBuffer(c *gin.Context) {
 func (s *Server) handleMetrics(c *gin.Context) {
 	snapshot := s.metrics.GetSnapshot()
 
+	if c.Query("format") == "prometheus" {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", metrics.PrometheusContentType)
+		if err := snapshot.WritePrometheus(c.Writer); err != nil {
+			fmt.Printf("Failed to write metrics: %v\n", err)
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"metrics":   snapshot,
 		"timestamp": time.Now().UTC(),
@@ -543,13 +1199,7 @@ func (s *Server) handleMetrics(c *gin.Context) {
 func (s *Server) handleRecoveryStats(c *gin.Context) {
 	stats, err := s.recoveryManager.GetRecoveryStats()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "RECOVERY_STATS_ERROR",
-				"message": "Failed to get recovery statistics",
-				"details": err.Error(),
-			},
-		})
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeRecoveryStatsError, "Failed to get recovery statistics", err.Error())
 		return
 	}
 
@@ -593,6 +1243,9 @@ func (s *Server) cleanupRoutine(ctx context.Context) {
 			if err := s.recoveryManager.CleanupOldRecoveryFiles(24 * time.Hour); err != nil {
 				fmt.Printf("Failed to cleanup old recovery files: %v\n", err)
 			}
+
+			// Forget async batch tokens that completed more than an hour ago
+			s.batchTracker.Cleanup()
 		}
 	}
 }
@@ -602,13 +1255,15 @@ func (s *Server) cleanupRoutine(ctx context.Context) {
 // loggingMiddleware provides structured logging for all requests
 func (s *Server) loggingMiddleware() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		return fmt.Sprintf("[%s] %s %s %d %s %s\n",
+		requestID, _ := param.Keys["request_id"].(string)
+		return fmt.Sprintf("[%s] %s %s %d %s %s %s\n",
 			param.TimeStamp.Format("2006-01-02 15:04:05"),
 			param.Method,
 			param.Path,
 			param.StatusCode,
 			param.Latency,
 			param.ClientIP,
+			requestID,
 		)
 	})
 }
@@ -618,15 +1273,9 @@ func (s *Server) recoveryMiddleware() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
 		s.metrics.IncrementRequestsFailed()
 
-		fmt.Printf("Panic recovered: %v\n", recovered)
+		fmt.Printf("Panic recovered in request %s: %v\n", requestid.Get(c), recovered)
 
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": gin.H{
-				"code":    "INTERNAL_SERVER_ERROR",
-				"message": "An internal server error occurred",
-				"details": "The server encountered an unexpected error and has recovered",
-			},
-		})
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeInternalError, "An internal server error occurred", "The server encountered an unexpected error and has recovered")
 		c.Abort()
 	})
 }
@@ -656,13 +1305,7 @@ func (s *Server) requestSizeMiddleware() gin.HandlerFunc {
 			s.metrics.IncrementRequestsFailed()
 			s.metrics.IncrementValidationErrors()
 
-			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
-				"error": gin.H{
-					"code":    "REQUEST_TOO_LARGE",
-					"message": "Request body too large",
-					"details": fmt.Sprintf("Request body cannot exceed %d bytes", maxRequestSize),
-				},
-			})
+			problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeRequestTooLarge, "Request body too large", fmt.Sprintf("Request body cannot exceed %d bytes", maxRequestSize))
 			c.Abort()
 			return
 		}
@@ -671,6 +1314,49 @@ func (s *Server) requestSizeMiddleware() gin.HandlerFunc {
 	}
 }
 
+// concurrencyMiddleware bounds the ingestion requests processed at once, so that bursts on a
+// small node queue up instead of competing for its CPUs. Requests wait for a slot until their
+// deadline, when timeoutMiddleware responds.
+func (s *Server) concurrencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.requestSlots == nil {
+			c.Next()
+			return
+		}
+
+		select {
+		case s.requestSlots <- struct{}{}:
+		default:
+			s.requestsWaited.Add(1)
+			select {
+			case s.requestSlots <- struct{}{}:
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+		}
+		defer func() { <-s.requestSlots }()
+
+		c.Next()
+	}
+}
+
+// ConcurrencyStats reports the ingestion requests processed at once
+type ConcurrencyStats struct {
+	MaxRequests    int   `json:"max_requests"`    // 0 is unbounded
+	InFlight       int   `json:"in_flight"`       // Requests holding a slot
+	RequestsWaited int64 `json:"requests_waited"` // Requests that waited for a slot since the server started
+}
+
+// concurrencyStats returns the ingestion requests processed at once
+func (s *Server) concurrencyStats() ConcurrencyStats {
+	return ConcurrencyStats{
+		MaxRequests:    cap(s.requestSlots),
+		InFlight:       len(s.requestSlots),
+		RequestsWaited: s.requestsWaited.Load(),
+	}
+}
+
 // timeoutMiddleware adds request timeout handling
 func (s *Server) timeoutMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -692,13 +1378,7 @@ func (s *Server) timeoutMiddleware() gin.HandlerFunc {
 			// Request timed out
 			s.metrics.IncrementRequestsFailed()
 
-			c.JSON(http.StatusRequestTimeout, gin.H{
-				"error": gin.H{
-					"code":    "REQUEST_TIMEOUT",
-					"message": "Request timeout",
-					"details": "Request took too long to process",
-				},
-			})
+			problem.Respond(c, http.StatusRequestTimeout, problem.CodeRequestTimeout, "Request timeout", "Request took too long to process")
 			c.Abort()
 		}
 	}
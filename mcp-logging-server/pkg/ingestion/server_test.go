@@ -7,12 +7,21 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
 	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/preflight"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/processor"
+	"github.com/kerlexov/mcp-logging-server/pkg/sampling"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	"github.com/kerlexov/mcp-logging-server/pkg/validation"
 )
 
 // MockStorage implements storage.LogStorage for testing
@@ -36,12 +45,16 @@ func (m *MockStorage) Query(ctx context.Context, filter models.LogFilter) (*mode
 	return nil, nil
 }
 
+func (m *MockStorage) QueryStream(ctx context.Context, filter models.LogFilter) (storage.LogIterator, error) {
+	return storage.NewSliceIterator(nil), nil
+}
+
 func (m *MockStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
 	return nil, nil
 }
 
-func (m *MockStorage) GetServices(ctx context.Context) ([]models.ServiceInfo, error) {
-	return nil, nil
+func (m *MockStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	return &models.ServiceResult{}, nil
 }
 
 func (m *MockStorage) HealthCheck(ctx context.Context) models.HealthStatus {
@@ -90,7 +103,7 @@ func TestServer_handleHealthCheck(t *testing.T) {
 				FlushTimeout: 1 * time.Second,
 			}
 
-			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 			router := gin.New()
 			server.registerRoutes(router)
@@ -115,6 +128,78 @@ func TestServer_handleHealthCheck(t *testing.T) {
 	}
 }
 
+func TestServer_handleHealthCheckPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockStorage := &MockStorage{
+		healthStatus: models.HealthStatus{Status: "healthy", Timestamp: time.Now()},
+	}
+	server := NewServer(8080, mockStorage, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second}, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+	server.SetPreflightReport(&preflight.Report{
+		Checks: []preflight.CheckResult{{Name: preflight.CheckRecoveryDir, Status: preflight.StatusFailed, Detail: "not writable"}},
+	})
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response["status"] != "degraded" {
+		t.Errorf("Expected failed startup checks to degrade health, got %v", response["status"])
+	}
+	if _, ok := response["preflight"]; !ok {
+		t.Error("Expected the preflight report in the health response")
+	}
+}
+
+func TestServer_DataProtectionPermissions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	readKey, _ := manager.CreateAPIKey("auditor", []auth.Permission{auth.PermissionDataProtectionRead}, 0, nil)
+	writeKey, _ := manager.CreateAPIKey("privacy-team", []auth.Permission{auth.PermissionDataProtectionWrite}, 0, nil)
+	metricsKey, _ := manager.CreateAPIKey("monitoring", []auth.Permission{auth.PermissionMetrics}, 0, nil)
+
+	server := NewServer(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil)
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	serve := func(method, apiKey, body string) int {
+		req, _ := http.NewRequest(method, "/admin/data-protection/config", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	config, _ := json.Marshal(server.dataProtection.GetConfig())
+
+	if code := serve("GET", readKey, ""); code != http.StatusOK {
+		t.Errorf("Expected the read permission to view the config, got %d", code)
+	}
+	if code := serve("PUT", readKey, string(config)); code != http.StatusForbidden {
+		t.Errorf("Expected the read permission not to change the config, got %d", code)
+	}
+	if code := serve("GET", writeKey, ""); code != http.StatusOK {
+		t.Errorf("Expected the write permission to view the config, got %d", code)
+	}
+	if code := serve("PUT", writeKey, string(config)); code != http.StatusOK {
+		t.Errorf("Expected the write permission to change the config, got %d", code)
+	}
+	if code := serve("GET", metricsKey, ""); code != http.StatusForbidden {
+		t.Errorf("Expected other permissions to be rejected, got %d", code)
+	}
+}
+
 func TestServer_handleIngestLogs(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -170,7 +255,7 @@ func TestServer_handleIngestLogs(t *testing.T) {
 				FlushTimeout: 1 * time.Second,
 			}
 
-			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 			router := gin.New()
 			server.registerRoutes(router)
@@ -250,7 +335,7 @@ func TestServer_handleIngestLogsBatch(t *testing.T) {
 				FlushTimeout: 1 * time.Second,
 			}
 
-			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 			router := gin.New()
 			server.registerRoutes(router)
@@ -269,6 +354,134 @@ func TestServer_handleIngestLogsBatch(t *testing.T) {
 	}
 }
 
+func TestServer_handleIngestLogsBatchAsync(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockStorage := &MockStorage{}
+	bufferConfig := buffer.Config{
+		Size:         100,
+		MaxBatchSize: 10,
+		FlushTimeout: 1 * time.Second,
+	}
+
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	logEntries := []models.LogEntry{
+		{
+			ID:          "550e8400-e29b-41d4-a716-446655440010",
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Async message",
+			ServiceName: "test-service",
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+		},
+	}
+
+	jsonData, _ := json.Marshal(logEntries)
+	req, _ := http.NewRequest("POST", "/v1/logs/batch/async", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	token, ok := response["token"].(string)
+	if !ok || token == "" {
+		t.Fatalf("Expected batch token in response, got %v", response["token"])
+	}
+
+	// Poll until the batch has been stored
+	var record BatchRecord
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		req, _ = http.NewRequest("GET", "/v1/batches/"+token, nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		if record.Status != BatchStatusPending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if record.Status != BatchStatusStored {
+		t.Errorf("Expected batch status %s, got %s", BatchStatusStored, record.Status)
+	}
+	if len(mockStorage.storedLogs) != 1 {
+		t.Errorf("Expected 1 stored log, got %d", len(mockStorage.storedLogs))
+	}
+
+	// Unknown tokens are reported as not found
+	req, _ = http.NewRequest("GET", "/v1/batches/unknown", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown token, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestServer_handleIngestLogsBatchAsyncBusy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bufferConfig := buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second}
+	server := NewServerWithOptions(8080, &MockStorage{}, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{MaxAsyncBatches: 1})
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	post := func() *httptest.ResponseRecorder {
+		body := []byte(`[{"level": "INFO", "message": "Async message", "service_name": "test-service", "agent_id": "test-agent", "platform": "go"}]`)
+		req, _ := http.NewRequest("POST", "/v1/logs/batch/async", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The only slot is taken by a batch being stored
+	server.asyncSlots <- struct{}{}
+	w := post()
+	var details problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil || w.Code != http.StatusServiceUnavailable || details.Code != problem.CodeServerBusy {
+		t.Fatalf("Expected status %d with %s, got %d: %s", http.StatusServiceUnavailable, problem.CodeServerBusy, w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+
+	<-server.asyncSlots
+	if w := post(); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected status %d once the slot is free, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(server.asyncSlots) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(server.asyncSlots) != 0 {
+		t.Error("Expected the slot released once the batch was stored")
+	}
+}
+
 func TestServer_CORSHeaders(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -282,7 +495,7 @@ func TestServer_CORSHeaders(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 	router := gin.New()
 
@@ -336,7 +549,7 @@ func TestServer_ConcurrentRequests(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 	router := gin.New()
 	server.registerRoutes(router)
@@ -389,6 +602,51 @@ func TestServer_ConcurrentRequests(t *testing.T) {
 	}
 }
 
+func TestServer_MaxConcurrentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := NewServerWithOptions(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{MaxConcurrentRequests: 1})
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	body, _ := json.Marshal(models.LogEntry{
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelInfo,
+		Message:     "Test message",
+		ServiceName: "test-service",
+		AgentID:     "test-agent",
+		Platform:    models.PlatformGo,
+	})
+	post := func(ctx context.Context) *httptest.ResponseRecorder {
+		req, _ := http.NewRequestWithContext(ctx, "POST", "/v1/logs", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// With the only slot taken, requests wait until their deadline
+	server.requestSlots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if w := post(ctx); w.Code == http.StatusCreated || w.Body.Len() != 0 {
+		t.Errorf("Expected the request to wait for a slot, got %d: %s", w.Code, w.Body.String())
+	}
+	if stats := server.concurrencyStats(); stats.MaxRequests != 1 || stats.InFlight != 1 || stats.RequestsWaited != 1 {
+		t.Errorf("Unexpected concurrency stats %+v", stats)
+	}
+
+	<-server.requestSlots
+	if w := post(context.Background()); w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d once a slot is free, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if stats := server.concurrencyStats(); stats.InFlight != 0 {
+		t.Errorf("Expected the slot to be released, got %+v", stats)
+	}
+}
+
 func TestServer_ErrorHandling(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -431,7 +689,7 @@ func TestServer_ErrorHandling(t *testing.T) {
 				FlushTimeout: 1 * time.Second,
 			}
 
-			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+			server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 			router := gin.New()
 			server.registerRoutes(router)
 
@@ -452,16 +710,15 @@ func TestServer_ErrorHandling(t *testing.T) {
 				t.Fatalf("Failed to parse error response: %v", err)
 			}
 
-			if errorObj, ok := response["error"].(map[string]interface{}); ok {
-				if code, ok := errorObj["code"].(string); ok {
-					if code != tt.expectedError {
-						t.Errorf("Expected error code %s, got %s", tt.expectedError, code)
-					}
-				} else {
-					t.Error("Expected error code in response")
+			if contentType := w.Header().Get("Content-Type"); contentType != problem.ContentType {
+				t.Errorf("Expected content type %s, got %s", problem.ContentType, contentType)
+			}
+			if code, ok := response["code"].(string); ok {
+				if code != tt.expectedError {
+					t.Errorf("Expected error code %s, got %s", tt.expectedError, code)
 				}
 			} else {
-				t.Error("Expected error object in response")
+				t.Error("Expected error code in response")
 			}
 		})
 	}
@@ -477,7 +734,7 @@ func TestServer_BatchSizeValidation(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 	router := gin.New()
 	server.registerRoutes(router)
 
@@ -511,11 +768,9 @@ func TestServer_BatchSizeValidation(t *testing.T) {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	if errorObj, ok := response["error"].(map[string]interface{}); ok {
-		if code, ok := errorObj["code"].(string); ok {
-			if code != "BATCH_TOO_LARGE" {
-				t.Errorf("Expected error code BATCH_TOO_LARGE, got %s", code)
-			}
+	if code, ok := response["code"].(string); ok {
+		if code != "BATCH_TOO_LARGE" {
+			t.Errorf("Expected error code BATCH_TOO_LARGE, got %s", code)
 		}
 	}
 }
@@ -532,7 +787,7 @@ func TestServer_CircuitBreakerEndpoints(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 	router := gin.New()
 	server.registerRoutes(router)
 
@@ -555,7 +810,7 @@ func TestServer_CircuitBreakerEndpoints(t *testing.T) {
 	}
 
 	// Test circuit breaker reset endpoint
-	req, _ = http.NewRequest("POST", "/circuit-breaker/reset", nil)
+	req, _ = http.NewRequest("POST", "/admin/circuit-breaker/reset", nil)
 	w = httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
@@ -564,6 +819,268 @@ func TestServer_CircuitBreakerEndpoints(t *testing.T) {
 	}
 }
 
+func TestServer_IngestionStageMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bufferConfig := buffer.Config{
+		Size:         100,
+		MaxBatchSize: 10,
+		FlushTimeout: 1 * time.Second,
+	}
+	server := NewServer(8080, &MockStorage{}, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	body := []byte(`[{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"}]`)
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	stages := server.metrics.GetSnapshot().IngestionStages
+	for _, stage := range []string{metrics.StageDecode, metrics.StageValidation, metrics.StageSymbolication, metrics.StageDataProtection, metrics.StageBufferAdd} {
+		if stages[stage].Count != 1 {
+			t.Errorf("Expected 1 observation of stage %s, got %d", stage, stages[stage].Count)
+		}
+	}
+
+	// Stages are exported as a labeled histogram
+	req, _ = http.NewRequest("GET", "/metrics?format=prometheus", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `mcp_logging_ingestion_stage_duration_seconds_count{stage="decode"} 1`) {
+		t.Errorf("Expected stage histograms in Prometheus output, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestServer_ValidationRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	bufferConfig := buffer.Config{
+		Size:         100,
+		MaxBatchSize: 10,
+		FlushTimeout: 1 * time.Second,
+	}
+	server := NewServerWithOptions(8080, &MockStorage{}, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{
+		Validation: validation.Rules{
+			MaxMessageLength: 5,
+			RequiredFields:   []string{"metadata.region"},
+		},
+	})
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	body := []byte(`[{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"}]`)
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	rules := server.metrics.GetSnapshot().ValidationRules
+	if rules[validation.RuleMessageLength] != 1 || rules[validation.RuleRequiredFields] != 1 {
+		t.Errorf("Expected the failed rules to be counted, got %v", rules)
+	}
+}
+
+func TestServer_Sampling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sampler, err := sampling.New([]sampling.Policy{
+		{Service: "checkout", Rates: map[models.LogLevel]float64{models.LogLevelInfo: 0}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sampler: %v", err)
+	}
+
+	bufferConfig := buffer.Config{
+		Size:         100,
+		MaxBatchSize: 10,
+		FlushTimeout: 1 * time.Second,
+	}
+	mockStorage := &MockStorage{}
+	server := NewServerWithOptions(8080, mockStorage, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{Sampler: sampler})
+
+	router := gin.New()
+	server.registerRoutes(router)
+
+	body := []byte(`[
+		{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"},
+		{"level": "ERROR", "message": "Payment declined", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"},
+		{"level": "INFO", "message": "Page viewed", "service_name": "web", "agent_id": "edge-1", "platform": "go"}
+	]`)
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	if len(mockStorage.storedLogs) != 2 {
+		t.Fatalf("Expected the checkout info entry to be sampled out, got %d stored entries", len(mockStorage.storedLogs))
+	}
+	if sampledOut := server.metrics.GetSnapshot().SampledOut; sampledOut["checkout"]["INFO"] != 1 || len(sampledOut) != 1 {
+		t.Errorf("Expected 1 sampled out checkout info entry, got %v", sampledOut)
+	}
+
+	// A single entry sampled out is acknowledged but not counted as ingested
+	req, _ = http.NewRequest("POST", "/v1/logs", bytes.NewReader([]byte(`{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if snapshot := server.metrics.GetSnapshot(); snapshot.LogsIngested != 2 || snapshot.LogsBuffered != 2 {
+		t.Errorf("Expected only the 2 kept entries counted, got %d ingested and %d buffered", snapshot.LogsIngested, snapshot.LogsBuffered)
+	}
+}
+
+func TestPromoteRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		entry     models.LogEntry
+		requestID string
+		metadata  map[string]interface{}
+	}{
+		{
+			name:      "from metadata",
+			entry:     models.LogEntry{Metadata: map[string]interface{}{"request_id": "req-1", "user": "u-7"}},
+			requestID: "req-1",
+			metadata:  map[string]interface{}{"user": "u-7"},
+		},
+		{
+			name:      "only key",
+			entry:     models.LogEntry{Metadata: map[string]interface{}{"request_id": "req-1"}},
+			requestID: "req-1",
+		},
+		{
+			name:      "field wins",
+			entry:     models.LogEntry{RequestID: "req-2", Metadata: map[string]interface{}{"request_id": "req-1"}},
+			requestID: "req-2",
+			metadata:  map[string]interface{}{"request_id": "req-1"},
+		},
+		{
+			name:     "not a string",
+			entry:    models.LogEntry{Metadata: map[string]interface{}{"request_id": 42.0}},
+			metadata: map[string]interface{}{"request_id": 42.0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promoteRequestID(&tt.entry)
+			if tt.entry.RequestID != tt.requestID {
+				t.Errorf("Expected request ID %q, got %q", tt.requestID, tt.entry.RequestID)
+			}
+			if fmt.Sprint(tt.entry.Metadata) != fmt.Sprint(tt.metadata) || (tt.metadata == nil) != (tt.entry.Metadata == nil) {
+				t.Errorf("Expected metadata %v, got %v", tt.metadata, tt.entry.Metadata)
+			}
+		})
+	}
+}
+
+func TestServer_IngestTemplateID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 100, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+	router := gin.New()
+	server.registerRoutes(router)
+
+	// The template ID a client sends is replaced by the one computed from the message
+	body := `[
+		{"level": "INFO", "message": "Order 1 placed", "service_name": "checkout", "agent_id": "agent-1", "platform": "go", "template_id": "forged"},
+		{"level": "INFO", "message": "Order 22 placed", "service_name": "checkout", "agent_id": "agent-1", "platform": "go"},
+		{"level": "INFO", "message": "Cache warmed", "service_name": "checkout", "agent_id": "agent-1", "platform": "go"}
+	]`
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+
+	templateID := models.MessageTemplateID("Order 333 placed")
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{TemplateID: templateID})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 2 {
+		t.Errorf("Expected the 2 orders to have template ID %s, got %d entries", templateID, result.TotalCount)
+	}
+}
+
+func TestServer_IngestProcessors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	chain, err := processor.NewChain([]processor.Stage{{
+		Name: "drop-health-checks",
+		Processor: processor.Func(func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+			kept := entries[:0]
+			for _, entry := range entries {
+				if !strings.HasPrefix(entry.Message, "GET /health") {
+					entry.Tags = append(entry.Tags, "processed")
+					kept = append(kept, entry)
+				}
+			}
+			return kept, nil
+		}),
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create processors: %v", err)
+	}
+
+	server := NewServerWithOptions(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 100, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{
+		Processors: chain,
+	})
+	router := gin.New()
+	server.registerRoutes(router)
+
+	body := `[
+		{"level": "INFO", "message": "GET /health 200", "service_name": "checkout", "agent_id": "agent-1", "platform": "go"},
+		{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "agent-1", "platform": "go"}
+	]`
+	req, _ := http.NewRequest("POST", "/v1/logs/batch", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 1 || result.Logs[0].Message != "Order placed" || len(result.Logs[0].Tags) != 1 {
+		t.Errorf("Expected the processed order only, got %+v", result.Logs)
+	}
+}
+
 func TestServer_handleBufferStats(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -574,7 +1091,7 @@ func TestServer_handleBufferStats(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 	router := gin.New()
 	server.registerRoutes(router)
@@ -607,7 +1124,7 @@ func TestServer_handleFlushBuffer(t *testing.T) {
 		FlushTimeout: 1 * time.Second,
 	}
 
-	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery")
+	server := NewServer(8080, mockStorage, bufferConfig, "/tmp/test_recovery", auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
 
 	router := gin.New()
 	server.registerRoutes(router)
@@ -626,7 +1143,7 @@ func TestServer_handleFlushBuffer(t *testing.T) {
 	server.buffer.Add([]models.LogEntry{logEntry})
 
 	// Test flush endpoint
-	req, _ := http.NewRequest("POST", "/v1/flush", nil)
+	req, _ := http.NewRequest("POST", "/admin/flush", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
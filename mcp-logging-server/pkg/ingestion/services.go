@@ -0,0 +1,149 @@
+package ingestion
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// serviceRegistry returns the storage as a service registry, responding with an error if unsupported
+func (s *Server) serviceRegistry(c *gin.Context) (storage.ServiceRegistry, bool) {
+	registry, ok := s.storage.(storage.ServiceRegistry)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support the service registry", "")
+		return nil, false
+	}
+	return registry, true
+}
+
+// handleListServiceRegistrations handles requests listing all registered services
+func (s *Server) handleListServiceRegistrations(c *gin.Context) {
+	registry, ok := s.serviceRegistry(c)
+	if !ok {
+		return
+	}
+
+	registrations, err := registry.ListServiceRegistrations(c.Request.Context())
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to list services", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"services":    registrations,
+		"total_count": len(registrations),
+	})
+}
+
+// handleGetServiceRegistration handles requests for a single service registration
+func (s *Server) handleGetServiceRegistration(c *gin.Context) {
+	registry, ok := s.serviceRegistry(c)
+	if !ok {
+		return
+	}
+
+	serviceName := c.Param("name")
+	registration, err := registry.GetServiceRegistration(c.Request.Context(), serviceName)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to get service", err.Error())
+		return
+	}
+
+	if registration == nil {
+		problem.Respond(c, http.StatusNotFound, problem.CodeServiceNotFound, "Service is not registered", serviceName)
+		return
+	}
+
+	c.JSON(http.StatusOK, registration)
+}
+
+// handleRegisterService handles service registration create and update requests
+func (s *Server) handleRegisterService(c *gin.Context) {
+	registry, ok := s.serviceRegistry(c)
+	if !ok {
+		return
+	}
+
+	var registration models.ServiceRegistration
+	if err := c.ShouldBindJSON(&registration); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	// The path is authoritative for the service name
+	registration.ServiceName = c.Param("name")
+
+	validationResult := s.validator.ValidateServiceRegistration(&registration)
+	if !validationResult.IsValid {
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Service registration validation failed", validationResult.Errors)
+		return
+	}
+
+	stored, err := registry.RegisterService(c.Request.Context(), registration)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to register service", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, stored)
+}
+
+// handleDeleteServiceRegistration handles service registration removal requests
+func (s *Server) handleDeleteServiceRegistration(c *gin.Context) {
+	registry, ok := s.serviceRegistry(c)
+	if !ok {
+		return
+	}
+
+	serviceName := c.Param("name")
+	deleted, err := registry.DeleteServiceRegistration(c.Request.Context(), serviceName)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to delete service", err.Error())
+		return
+	}
+
+	if !deleted {
+		problem.Respond(c, http.StatusNotFound, problem.CodeServiceNotFound, "Service is not registered", serviceName)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Service registration deleted",
+		"service_name": serviceName,
+	})
+}
+
+// handlePurgeServices handles requests removing the agents that have not logged for the
+// not_seen_for duration from the listed services. Their entries are kept, and an agent that
+// logs again is listed again.
+func (s *Server) handlePurgeServices(c *gin.Context) {
+	pruner, ok := s.storage.(storage.ServicePruner)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support purging services", "")
+		return
+	}
+
+	notSeenFor, err := time.ParseDuration(c.Query("not_seen_for"))
+	if err != nil || notSeenFor <= 0 {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidQuery, "Query parameter not_seen_for must be a positive duration", "e.g. 720h")
+		return
+	}
+
+	notSeenSince := time.Now().UTC().Add(-notSeenFor)
+	purged, err := pruner.PurgeServices(c.Request.Context(), notSeenSince)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to purge services", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Services purged",
+		"not_seen_since": notSeenSince,
+		"purged":         purged,
+		"purged_count":   len(purged),
+	})
+}
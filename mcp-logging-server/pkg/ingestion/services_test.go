@@ -0,0 +1,181 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func newServiceRegistryTestRouter(t *testing.T, logStorage storage.LogStorage) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	bufferConfig := buffer.Config{
+		Size:         100,
+		MaxBatchSize: 10,
+		FlushTimeout: 1 * time.Second,
+	}
+
+	server := NewServer(8080, logStorage, bufferConfig, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+
+	router := gin.New()
+	server.registerRoutes(router)
+	return router
+}
+
+func TestServer_ServiceRegistry(t *testing.T) {
+	sqliteStorage, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "services.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	router := newServiceRegistryTestRouter(t, sqliteStorage)
+
+	body, _ := json.Marshal(models.ServiceRegistration{
+		OwnerTeam:   "payments",
+		RepoURL:     "https://example.com/payments/checkout",
+		RunbookURL:  "https://example.com/runbooks/checkout",
+		Environment: "production",
+	})
+
+	req, _ := http.NewRequest("PUT", "/v1/services/checkout-service", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/services/checkout-service", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var registration models.ServiceRegistration
+	if err := json.Unmarshal(w.Body.Bytes(), &registration); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if registration.ServiceName != "checkout-service" || registration.OwnerTeam != "payments" {
+		t.Errorf("Unexpected registration: %+v", registration)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/v1/services/checkout-service", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/v1/services/checkout-service", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestServer_ServiceRegistryValidation(t *testing.T) {
+	sqliteStorage, err := storage.NewSQLiteStorage(filepath.Join(t.TempDir(), "services.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	router := newServiceRegistryTestRouter(t, sqliteStorage)
+
+	body := []byte(`{"repo_url": "not a url"}`)
+	req, _ := http.NewRequest("PUT", "/v1/services/checkout-service", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestServer_ServiceRegistryUnsupported(t *testing.T) {
+	router := newServiceRegistryTestRouter(t, &MockStorage{})
+
+	req, _ := http.NewRequest("GET", "/v1/services", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
+
+func TestServer_PurgeServices(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	now := time.Now().UTC()
+	err := memoryStorage.Store(context.Background(), []models.LogEntry{
+		{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelInfo, Message: "m", ServiceName: "checkout", AgentID: "checkout-1", Platform: models.PlatformGo},
+		{ID: uuid.New().String(), Timestamp: now.Add(-60 * 24 * time.Hour), Level: models.LogLevelInfo, Message: "m", ServiceName: "checkout", AgentID: "checkout-2", Platform: models.PlatformGo},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	router := newServiceRegistryTestRouter(t, memoryStorage)
+
+	for _, notSeenFor := range []string{"", "30d", "-720h"} {
+		req, _ := http.NewRequest("POST", "/admin/services/purge?not_seen_for="+notSeenFor, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %q, got %d", http.StatusBadRequest, notSeenFor, w.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/admin/services/purge?not_seen_for=720h", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Purged      []models.ServiceInfo `json:"purged"`
+		PurgedCount int                  `json:"purged_count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.PurgedCount != 1 || response.Purged[0].AgentID != "checkout-2" {
+		t.Errorf("Expected checkout-2 to be purged, got %+v", response)
+	}
+
+	services, _ := memoryStorage.GetServices(context.Background(), models.ServiceFilter{})
+	if len(services.Services) != 1 || services.Services[0].AgentID != "checkout-1" {
+		t.Errorf("Expected only checkout-1 to be listed, got %+v", services.Services)
+	}
+
+	router = newServiceRegistryTestRouter(t, &MockStorage{})
+	req, _ = http.NewRequest("POST", "/admin/services/purge?not_seen_for=720h", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
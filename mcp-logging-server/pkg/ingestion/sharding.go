@@ -0,0 +1,100 @@
+package ingestion
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/sharding"
+)
+
+// handleRoutedBatch buffers entries that another node of a sharded cluster received for services
+// this node owns. The entries were validated and processed by data protection on that node, so
+// they are buffered as they are, and never routed again, so that nodes disagreeing about owners
+// cannot send entries back and forth.
+func (s *Server) handleRoutedBatch(c *gin.Context) {
+	s.metrics.IncrementRequestsTotal()
+
+	origin := c.GetHeader(sharding.OriginHeader)
+	if origin == "" {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Missing shard origin", sharding.OriginHeader+" header is required")
+		return
+	}
+
+	var entries []models.LogEntry
+	if err := codec.Decode(c.Request.Body, &entries); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+	if len(entries) == 0 {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeEmptyBatch, "Batch cannot be empty", "")
+		return
+	}
+	for i := range entries {
+		if entries[i].ID == "" {
+			s.metrics.IncrementRequestsFailed()
+			problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Routed entries must have an ID", fmt.Sprintf("Entry %d has no ID", i))
+			return
+		}
+		// Nodes that predate template IDs route entries without one
+		if entries[i].TemplateID == "" {
+			entries[i].TemplateID = models.MessageTemplateID(entries[i].Message)
+		}
+	}
+
+	if s.router != nil {
+		notOwned := 0
+		for _, entry := range entries {
+			if s.router.Owner(entry.ServiceName) != s.router.NodeID() {
+				notOwned++
+			}
+		}
+		if notOwned > 0 {
+			log.Printf("Sharding: buffering %d entries routed by node %s for services owned by other nodes, check that all nodes have the same sharding configuration", notOwned, origin)
+		}
+	}
+
+	start := time.Now()
+	err := s.buffer.Add(entries)
+	s.observeStage(metrics.StageBufferAdd, start)
+	if err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to buffer routed entries", err.Error())
+		return
+	}
+
+	s.metrics.IncrementRequestsSuccessful()
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Routed entries buffered successfully",
+		"origin":         origin,
+		"buffered_count": len(entries),
+	})
+}
+
+// handleShardingStats handles requests for the entries routed to the other nodes of a sharded
+// cluster. With a service parameter it also reports the node owning the service.
+func (s *Server) handleShardingStats(c *gin.Context) {
+	if s.router == nil {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Sharding is not configured", "")
+		return
+	}
+
+	response := gin.H{
+		"sharding_stats": s.router.Stats(),
+		"timestamp":      time.Now().UTC(),
+	}
+	if service := c.Query("service"); service != "" {
+		response["owner"] = s.router.Owner(service)
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,134 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/sharding"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_ShardedIngestion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// The other node only records what is routed to it
+	var (
+		mutex  sync.Mutex
+		routed []models.LogEntry
+	)
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var entries []models.LogEntry
+		json.NewDecoder(r.Body).Decode(&entries)
+		mutex.Lock()
+		routed = append(routed, entries...)
+		mutex.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer remote.Close()
+
+	shardRouter, err := sharding.NewRouter(sharding.Config{
+		NodeID: "writer-1",
+		Nodes: []sharding.Node{
+			{ID: "writer-1", URL: "http://127.0.0.1:1"},
+			{ID: "writer-2", URL: remote.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+	server := NewServerWithOptions(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{Router: shardRouter})
+	router := gin.New()
+	server.registerRoutes(router)
+
+	serve := func(url, origin string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", url, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		if origin != "" {
+			req.Header.Set(sharding.OriginHeader, origin)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var local, owned string
+	for i := 0; local == "" || owned == ""; i++ {
+		service := fmt.Sprintf("service-%d", i)
+		if shardRouter.Owner(service) == "writer-1" {
+			local = service
+		} else {
+			owned = service
+		}
+	}
+	newLog := func(service string) models.LogEntry {
+		entry := newReplicatedTestLog("Sharded message")
+		entry.ServiceName = service
+		return entry
+	}
+
+	// Entries of the other node's services are routed to it, the rest is stored here
+	w := serve("/v1/logs/batch", "", []models.LogEntry{newLog(local), newLog(owned), newLog(owned)})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{Limit: 10})
+	if err != nil || result.TotalCount != 1 || result.Logs[0].ServiceName != local {
+		t.Fatalf("Expected only the local entry to be stored, got %+v (%v)", result, err)
+	}
+	mutex.Lock()
+	if len(routed) != 2 || routed[0].ServiceName != owned {
+		t.Errorf("Expected 2 entries to be routed to writer-2, got %v", routed)
+	}
+	mutex.Unlock()
+
+	// Entries routed here are buffered without routing them again
+	if w := serve(sharding.RoutePath, "", []models.LogEntry{newLog(local)}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without an origin, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	w = serve(sharding.RoutePath, "writer-2", []models.LogEntry{newLog(local), newLog(owned)})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	result, err = memoryStorage.Query(context.Background(), models.LogFilter{Limit: 10})
+	if err != nil || result.TotalCount != 3 {
+		t.Errorf("Expected 3 stored entries, got %+v (%v)", result, err)
+	}
+	mutex.Lock()
+	if len(routed) != 2 {
+		t.Errorf("Expected routed entries not to be routed again, writer-2 received %d entries", len(routed))
+	}
+	mutex.Unlock()
+
+	req, _ := http.NewRequest("GET", "/sharding/stats?service="+owned, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var stats struct {
+		Owner string         `json:"owner"`
+		Stats sharding.Stats `json:"sharding_stats"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || stats.Owner != "writer-2" || stats.Stats.Nodes[0].Routed != 2 {
+		t.Errorf("Expected writer-2 as owner and 2 routed entries, got %d: %s", w.Code, w.Body.String())
+	}
+}
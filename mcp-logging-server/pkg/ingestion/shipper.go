@@ -0,0 +1,281 @@
+package ingestion
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+)
+
+// ShipperMapping maps the records posted by log shippers such as Fluent Bit's http output and
+// Vector's http sink to log entries. Each field lists the record keys tried in order, nested
+// keys are separated by dots (e.g. kubernetes.container_name). Keys that are not mapped are
+// kept as metadata.
+type ShipperMapping struct {
+	TimestampKeys []string
+	LevelKeys     []string
+	MessageKeys   []string
+	ServiceKeys   []string
+	AgentKeys     []string
+
+	DefaultService  string          // Service of records without a service key, overridden by the service query parameter
+	DefaultPlatform models.Platform // Platform of the entries, overridden by the platform query parameter
+}
+
+// DefaultShipperMapping returns the mapping of the field names Fluent Bit and Vector use by default
+func DefaultShipperMapping() ShipperMapping {
+	return ShipperMapping{
+		TimestampKeys:   []string{"timestamp", "date", "time", "@timestamp"},
+		LevelKeys:       []string{"level", "severity", "log_level"},
+		MessageKeys:     []string{"message", "log", "msg"},
+		ServiceKeys:     []string{"service_name", "service", "kubernetes.container_name", "container_name"},
+		AgentKeys:       []string{"agent_id", "host", "hostname", "kubernetes.pod_name"},
+		DefaultPlatform: models.PlatformGo,
+	}
+}
+
+// withDefaults fills the key lists that are not set from the default mapping
+func (m ShipperMapping) withDefaults() ShipperMapping {
+	defaults := DefaultShipperMapping()
+	if len(m.TimestampKeys) == 0 {
+		m.TimestampKeys = defaults.TimestampKeys
+	}
+	if len(m.LevelKeys) == 0 {
+		m.LevelKeys = defaults.LevelKeys
+	}
+	if len(m.MessageKeys) == 0 {
+		m.MessageKeys = defaults.MessageKeys
+	}
+	if len(m.ServiceKeys) == 0 {
+		m.ServiceKeys = defaults.ServiceKeys
+	}
+	if len(m.AgentKeys) == 0 {
+		m.AgentKeys = defaults.AgentKeys
+	}
+	if m.DefaultPlatform == "" {
+		m.DefaultPlatform = defaults.DefaultPlatform
+	}
+	return m
+}
+
+// toEntry converts a shipper record to a log entry. The record is consumed, its unmapped keys
+// become the metadata of the entry.
+func (m ShipperMapping) toEntry(record map[string]interface{}, service, agent string, platform models.Platform) (models.LogEntry, error) {
+	entry := models.LogEntry{
+		ServiceName: service,
+		AgentID:     agent,
+		Platform:    platform,
+		Level:       models.LogLevelInfo,
+	}
+
+	if value, ok := takeRecordField(record, m.TimestampKeys); ok {
+		timestamp, err := parseShipperTime(value)
+		if err != nil {
+			return entry, err
+		}
+		entry.Timestamp = timestamp
+	}
+	if value, ok := takeRecordField(record, m.LevelKeys); ok {
+		entry.Level = models.LogLevel(fmt.Sprint(value))
+	}
+	if value, ok := takeRecordField(record, m.MessageKeys); ok {
+		entry.Message = strings.TrimRight(fmt.Sprint(value), "\n")
+	}
+	if service == "" {
+		if value, ok := takeRecordField(record, m.ServiceKeys); ok {
+			entry.ServiceName = fmt.Sprint(value)
+		}
+	}
+	if agent == "" {
+		if value, ok := takeRecordField(record, m.AgentKeys); ok {
+			entry.AgentID = fmt.Sprint(value)
+		}
+	}
+	if entry.AgentID == "" {
+		entry.AgentID = entry.ServiceName
+	}
+	if len(record) > 0 {
+		entry.Metadata = record
+	}
+
+	return entry, nil
+}
+
+// takeRecordField removes and returns the value of the first key present in the record,
+// following dotted keys into nested objects
+func takeRecordField(record map[string]interface{}, keys []string) (interface{}, bool) {
+	for _, key := range keys {
+		if value, ok := record[key]; ok && value != nil && value != "" {
+			delete(record, key)
+			return value, true
+		}
+
+		path := strings.Split(key, ".")
+		if len(path) == 1 {
+			continue
+		}
+		parent := record
+		for _, segment := range path[:len(path)-1] {
+			nested, ok := parent[segment].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = nested
+		}
+		if parent == nil {
+			continue
+		}
+		if value, ok := parent[path[len(path)-1]]; ok && value != nil && value != "" {
+			// Nested values are left in place as they belong to a larger object
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// parseShipperTime parses RFC3339 timestamps and Unix timestamps in seconds (Fluent Bit's
+// default double format) or milliseconds
+func parseShipperTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case json.Number:
+		number, err := v.Float64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+		}
+		return unixTime(number), nil
+	case string:
+		if number, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixTime(number), nil
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC3339 or Unix time", v)
+		}
+		return timestamp, nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp %v", value)
+	}
+}
+
+// unixTime converts Unix seconds, or milliseconds for values beyond year 33658, to a time
+func unixTime(number float64) time.Time {
+	if number > 1e12 {
+		return time.UnixMilli(int64(number)).UTC()
+	}
+	seconds := int64(number)
+	return time.Unix(seconds, int64((number-float64(seconds))*1e9)).UTC()
+}
+
+// decodeShipperRecords decodes a JSON array of records, a single record, or newline delimited
+// records (Fluent Bit's json_lines format and Vector's newline_delimited framing)
+func decodeShipperRecords(body []byte) ([]map[string]interface{}, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	if body[0] == '[' {
+		var records []map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&records); err != nil {
+			return nil, err
+		}
+		return records, nil
+	}
+
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var record map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(text))
+		decoder.UseNumber()
+		if err := decoder.Decode(&record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// handleIngestShipper handles records posted by log shippers. The service, agent and platform
+// query parameters set those fields for all records, which is how shippers that cannot add them
+// to their records identify themselves.
+func (s *Server) handleIngestShipper(c *gin.Context) {
+	s.metrics.IncrementRequestsTotal()
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Failed to read request body", err.Error())
+		return
+	}
+
+	records, err := decodeShipperRecords(body)
+	if err != nil {
+		s.metrics.IncrementRequestsFailed()
+		s.metrics.IncrementValidationErrors()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	mapping := s.shipperMapping
+	service := c.DefaultQuery("service", mapping.DefaultService)
+	platform := models.Platform(c.DefaultQuery("platform", string(mapping.DefaultPlatform)))
+	agent := c.Query("agent")
+
+	entries := make([]models.LogEntry, 0, len(records))
+	for i, record := range records {
+		entry, err := mapping.toEntry(record, service, agent, platform)
+		if err != nil {
+			s.metrics.IncrementRequestsFailed()
+			s.metrics.IncrementValidationErrors()
+			problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Record validation failed", fmt.Sprintf("record %d: %v", i, err))
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	entries, ok := s.prepareEntries(c, entries)
+	if !ok {
+		return
+	}
+
+	if err := s.bufferEntries(c.Request.Context(), entries); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to buffer log entries", err.Error())
+		return
+	}
+
+	s.recordUsage(c, entries)
+	s.metrics.IncrementRequestsSuccessful()
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":        "Log entries buffered successfully",
+		"buffered_count": len(entries),
+		"total_count":    len(entries),
+	})
+}
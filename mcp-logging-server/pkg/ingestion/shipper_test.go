@@ -0,0 +1,109 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_IngestShipper(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServerWithOptions(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 100, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil, Options{
+		Shipper: &ShipperMapping{ServiceKeys: []string{"kubernetes.labels.app"}},
+	})
+	router := gin.New()
+	server.registerRoutes(router)
+
+	serve := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	now := time.Now().Truncate(time.Second).UTC()
+
+	// Fluent Bit json format: an array with Unix timestamps under date
+	w := serve("/v1/logs/shipper", fmt.Sprintf(`[
+		{"date": %d.5, "log": "connection reset\n", "level": "warning", "kubernetes": {"labels": {"app": "checkout"}, "pod_name": "checkout-1"}},
+		{"date": %d, "log": "retrying", "kubernetes": {"labels": {"app": "checkout"}}}
+	]`, now.Unix(), now.Unix()))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// Vector newline delimited JSON with RFC3339 timestamps, the service comes from the query
+	w = serve("/v1/logs/shipper?service=payments&agent=vector-1", fmt.Sprintf(`{"timestamp": %q, "message": "charge failed", "level": "error", "host": "node-2"}
+{"timestamp": %q, "message": "charge retried"}`, now.Format(time.RFC3339), now.Format(time.RFC3339)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	if w := serve("/v1/logs/shipper", `[{"date": "yesterday", "log": "x", "service": "a"}]`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid timestamp, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := serve("/v1/logs/shipper", `{"log": "no service"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d without a service, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{ServiceName: "checkout", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(result.Logs) != 2 {
+		t.Fatalf("Expected 2 checkout entries, got %d", len(result.Logs))
+	}
+	for _, entry := range result.Logs {
+		if entry.Message == "connection reset" {
+			if entry.Level != models.LogLevelWarn {
+				t.Errorf("Expected level WARN, got %s", entry.Level)
+			}
+			if entry.AgentID != "checkout-1" {
+				t.Errorf("Expected agent checkout-1 from the pod name, got %s", entry.AgentID)
+			}
+			if want := now.Add(500 * time.Millisecond); !entry.Timestamp.Equal(want) {
+				t.Errorf("Expected timestamp %v, got %v", want, entry.Timestamp)
+			}
+			if _, ok := entry.Metadata["kubernetes"]; !ok {
+				t.Errorf("Expected unmapped keys in metadata, got %v", entry.Metadata)
+			}
+		} else if entry.AgentID != "checkout" {
+			t.Errorf("Expected agent to default to the service, got %s", entry.AgentID)
+		}
+	}
+
+	result, err = memoryStorage.Query(context.Background(), models.LogFilter{ServiceName: "payments", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query: %v", err)
+	}
+	if len(result.Logs) != 2 {
+		t.Fatalf("Expected 2 payments entries, got %d", len(result.Logs))
+	}
+	for _, entry := range result.Logs {
+		if entry.AgentID != "vector-1" {
+			t.Errorf("Expected agent vector-1 from the query, got %s", entry.AgentID)
+		}
+		if entry.Message == "charge failed" && (entry.Level != models.LogLevelError || entry.Metadata["host"] != "node-2") {
+			t.Errorf("Unexpected entry %+v", entry)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	"github.com/kerlexov/mcp-logging-server/pkg/symbolication"
+)
+
+// symbolFileStore returns the storage as a symbol file store, responding with an error if unsupported
+func (s *Server) symbolFileStore(c *gin.Context) (storage.SymbolFileStore, bool) {
+	store, ok := s.storage.(storage.SymbolFileStore)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support symbol files", "")
+		return nil, false
+	}
+	return store, true
+}
+
+// symbolicateStackTrace rewrites the stack trace of an entry with the uploaded symbol files. A
+// failure keeps the raw stack trace, which can still be symbolicated on read.
+func (s *Server) symbolicateStackTrace(ctx context.Context, entry *models.LogEntry) {
+	if s.stackTraces == nil {
+		return
+	}
+	if _, err := s.stackTraces.SymbolicateEntry(ctx, entry); err != nil {
+		fmt.Printf("Failed to symbolicate stack trace of log entry %s: %v\n", entry.ID, err)
+	}
+}
+
+// handleUploadSymbolFile handles uploads of a source map or mapping file for a service version.
+// The request body is the file, the kind is taken from the kind query parameter or the file name.
+func (s *Server) handleUploadSymbolFile(c *gin.Context) {
+	store, ok := s.symbolFileStore(c)
+	if !ok {
+		return
+	}
+
+	content, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Failed to read symbol file", err.Error())
+		return
+	}
+
+	file := models.SymbolFile{
+		ServiceName: c.Param("service"),
+		Version:     c.Param("version"),
+		Name:        c.Param("name"),
+		Kind:        models.SymbolFileKind(c.Query("kind")),
+		Content:     content,
+	}
+	if file.Kind == "" {
+		file.Kind = symbolFileKind(file.Name)
+	}
+
+	validationResult := s.validator.ValidateSymbolFile(&file)
+	if !validationResult.IsValid {
+		problem.RespondDetails(c, http.StatusBadRequest, problem.CodeValidationError, "Symbol file validation failed", validationResult.Errors)
+		return
+	}
+
+	// Reject files that would fail every symbolication later
+	if _, err := symbolication.Parse(file.Kind, file.Content); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidSymbolFile, "Symbol file could not be parsed", err.Error())
+		return
+	}
+
+	stored, err := store.PutSymbolFile(c.Request.Context(), file)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to store symbol file", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, stored)
+}
+
+// handleListSymbolFiles handles requests listing the symbol files of a service, optionally of one version
+func (s *Server) handleListSymbolFiles(c *gin.Context) {
+	store, ok := s.symbolFileStore(c)
+	if !ok {
+		return
+	}
+
+	files, err := store.ListSymbolFiles(c.Request.Context(), c.Param("service"), c.Query("version"))
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to list symbol files", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"symbol_files": files,
+		"total_count":  len(files),
+	})
+}
+
+// handleDeleteSymbolFile handles requests deleting a symbol file
+func (s *Server) handleDeleteSymbolFile(c *gin.Context) {
+	store, ok := s.symbolFileStore(c)
+	if !ok {
+		return
+	}
+
+	serviceName, version, name := c.Param("service"), c.Param("version"), c.Param("name")
+	deleted, err := store.DeleteSymbolFile(c.Request.Context(), serviceName, version, name)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to delete symbol file", err.Error())
+		return
+	}
+
+	if !deleted {
+		problem.Respond(c, http.StatusNotFound, problem.CodeSymbolFileNotFound, "Symbol file does not exist", fmt.Sprintf("%s %s %s", serviceName, version, name))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Symbol file deleted",
+		"name":    name,
+	})
+}
+
+// symbolFileKind infers the kind of a symbol file from its name: .map files are source maps and
+// .txt files ProGuard mappings
+func symbolFileKind(name string) models.SymbolFileKind {
+	switch {
+	case strings.HasSuffix(name, ".map"):
+		return models.SymbolFileKindSourceMap
+	case strings.HasSuffix(name, ".txt"):
+		return models.SymbolFileKindProGuard
+	default:
+		return ""
+	}
+}
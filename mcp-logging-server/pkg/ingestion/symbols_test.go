@@ -0,0 +1,133 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_SymbolFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 100, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+	router := gin.New()
+	server.registerRoutes(router)
+
+	serve := func(method, url string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	sourceMap := `{"version": 3, "sources": ["src/cart.tsx"], "names": [], "mappings": "AAAA,KACE"}`
+
+	invalid := []struct {
+		name string
+		url  string
+		body string
+	}{
+		{name: "unknown kind", url: "/admin/symbols/mobile-app/2.0.0/main.jsbundle", body: sourceMap},
+		{name: "invalid service name", url: "/admin/symbols/mobile%20app/2.0.0/main.jsbundle.map", body: sourceMap},
+		{name: "unparseable source map", url: "/admin/symbols/mobile-app/2.0.0/main.jsbundle.map", body: `{"version": 2}`},
+		{name: "unparseable mapping", url: "/admin/symbols/mobile-app/2.0.0/mapping.txt", body: "not a mapping"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve("PUT", tt.url, tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := serve("PUT", "/admin/symbols/mobile-app/2.0.0/main.jsbundle?kind=sourcemap", sourceMap)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var uploaded models.SymbolFile
+	if err := json.Unmarshal(w.Body.Bytes(), &uploaded); err != nil || uploaded.Size != len(sourceMap) || uploaded.Kind != models.SymbolFileKindSourceMap {
+		t.Errorf("Unexpected upload response: %s", w.Body.String())
+	}
+
+	w = serve("GET", "/admin/symbols/mobile-app?version=2.0.0", "")
+	var list struct {
+		SymbolFiles []models.SymbolFile `json:"symbol_files"`
+		TotalCount  int                 `json:"total_count"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || list.TotalCount != 1 || list.SymbolFiles[0].Name != "main.jsbundle" {
+		t.Errorf("Unexpected symbol file list: %s", w.Body.String())
+	}
+
+	// Stack traces of the uploaded version are symbolicated before they are stored
+	body, _ := json.Marshal([]map[string]interface{}{
+		{
+			"level":        "ERROR",
+			"message":      "Checkout failed",
+			"service_name": "mobile-app",
+			"agent_id":     "device-1",
+			"platform":     "react-native",
+			"device_info":  map[string]string{"platform": "ios", "app_version": "2.0.0"},
+			"stack_trace":  "TypeError: undefined is not a function\n    at c (main.jsbundle:1:6)",
+		},
+		{
+			"level":        "ERROR",
+			"message":      "Checkout failed",
+			"service_name": "mobile-app",
+			"agent_id":     "device-1",
+			"platform":     "react-native",
+			"device_info":  map[string]string{"platform": "ios", "app_version": "1.9.0"},
+			"stack_trace":  "TypeError: undefined is not a function\n    at c (main.jsbundle:1:6)",
+		},
+	})
+	if w := serve("POST", "/v1/logs/batch", string(body)); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{ServiceName: "mobile-app"})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	traces := make(map[string]string)
+	for _, entry := range result.Logs {
+		traces[entry.DeviceInfo.AppVersion] = entry.StackTrace
+	}
+	if traces["2.0.0"] != "TypeError: undefined is not a function\n    at c (src/cart.tsx:2:3)" {
+		t.Errorf("Expected the 2.0.0 stack trace to be symbolicated, got %q", traces["2.0.0"])
+	}
+	if traces["1.9.0"] != "TypeError: undefined is not a function\n    at c (main.jsbundle:1:6)" {
+		t.Errorf("Expected the 1.9.0 stack trace to stay minified, got %q", traces["1.9.0"])
+	}
+
+	if w := serve("DELETE", "/admin/symbols/mobile-app/2.0.0/main.jsbundle", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := serve("DELETE", "/admin/symbols/mobile-app/2.0.0/main.jsbundle", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
+
+func TestServer_SymbolFilesNotSupported(t *testing.T) {
+	router := newServiceRegistryTestRouter(t, &MockStorage{})
+
+	req, _ := http.NewRequest("GET", "/admin/symbols/mobile-app", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package ingestion
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// syncStateStore returns the storage as a sync state store, responding with an error if unsupported
+func (s *Server) syncStateStore(c *gin.Context) (storage.SyncStateStore, bool) {
+	store, ok := s.storage.(storage.SyncStateStore)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support delta sync", "")
+		return nil, false
+	}
+	return store, true
+}
+
+// handleGetSyncState handles requests for the last sequence accepted from an agent, which a
+// reconnecting agent resumes after
+func (s *Server) handleGetSyncState(c *gin.Context) {
+	store, ok := s.syncStateStore(c)
+	if !ok {
+		return
+	}
+
+	state, err := store.GetSyncState(c.Request.Context(), c.Param("agent_id"))
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to get sync state", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// handleSyncLogs handles delta sync uploads. Entries at or below the last sequence accepted from
+// the agent are skipped as duplicates, the rest are buffered like a batch and the agent's sequence
+// is advanced to the highest one uploaded.
+func (s *Server) handleSyncLogs(c *gin.Context) {
+	s.metrics.IncrementRequestsTotal()
+
+	store, ok := s.syncStateStore(c)
+	if !ok {
+		s.metrics.IncrementRequestsFailed()
+		return
+	}
+
+	var request models.SyncRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		s.metrics.IncrementValidationErrors()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	if err := validateSyncRequest(&request); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		s.metrics.IncrementValidationErrors()
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidSequence, "Sync request validation failed", err.Error())
+		return
+	}
+
+	// Hold the lock until the sequence is advanced, so that a retried upload racing the
+	// original cannot pass the dedupe check twice
+	s.syncMutex.Lock()
+	defer s.syncMutex.Unlock()
+
+	ctx := c.Request.Context()
+	state, err := store.GetSyncState(ctx, request.AgentID)
+	if err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to get sync state", err.Error())
+		return
+	}
+
+	var pending []models.LogEntry
+	for _, entry := range request.Entries {
+		if entry.Sequence > state.LastSequence {
+			pending = append(pending, entry.LogEntry)
+		}
+	}
+	duplicates := len(request.Entries) - len(pending)
+
+	// Everything was uploaded before, confirm where the agent stands
+	if len(pending) == 0 {
+		s.metrics.IncrementRequestsSuccessful()
+		c.JSON(http.StatusOK, gin.H{
+			"agent_id":       request.AgentID,
+			"last_sequence":  state.LastSequence,
+			"accepted_count": 0,
+			"duplicates":     duplicates,
+		})
+		return
+	}
+
+	entries, ok := s.prepareEntries(c, pending)
+	if !ok {
+		return
+	}
+
+	if err := s.bufferEntries(c.Request.Context(), entries); err != nil {
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeBufferError, "Failed to buffer log entries", err.Error())
+		return
+	}
+
+	lastSequence := request.Entries[len(request.Entries)-1].Sequence
+	if err := store.AdvanceSyncState(ctx, request.AgentID, lastSequence); err != nil {
+		// The entries are buffered, a retry after this failure is the one case that stores them twice
+		s.metrics.IncrementRequestsFailed()
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to advance sync state", err.Error())
+		return
+	}
+
+	s.recordUsage(c, entries)
+	s.metrics.IncrementRequestsSuccessful()
+	s.metrics.IncrementLogsIngested(int64(len(entries)))
+	s.metrics.IncrementLogsBuffered(int64(len(entries)))
+
+	c.JSON(http.StatusOK, gin.H{
+		"agent_id":       request.AgentID,
+		"last_sequence":  lastSequence,
+		"accepted_count": len(entries),
+		"duplicates":     duplicates,
+	})
+}
+
+// validateSyncRequest checks the agent ID and that sequences are positive and strictly
+// increasing, filling in the agent ID of entries that omit it
+func validateSyncRequest(request *models.SyncRequest) error {
+	if request.AgentID == "" {
+		return fmt.Errorf("agent_id is required")
+	}
+	if len(request.Entries) == 0 {
+		return fmt.Errorf("entries cannot be empty")
+	}
+
+	var previous int64
+	for i := range request.Entries {
+		entry := &request.Entries[i]
+		if entry.Sequence <= previous {
+			return fmt.Errorf("entry %d: sequence %d must be positive and greater than the previous sequence", i, entry.Sequence)
+		}
+		previous = entry.Sequence
+
+		if entry.AgentID == "" {
+			entry.AgentID = request.AgentID
+		} else if entry.AgentID != request.AgentID {
+			return fmt.Errorf("entry %d: agent_id %s does not match the request agent_id %s", i, entry.AgentID, request.AgentID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_SyncLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8080, memoryStorage, buffer.Config{Size: 100, MaxBatchSize: 100, FlushTimeout: time.Second}, t.TempDir(), auth.NewAPIKeyManager(nil), nil, nil, nil, nil)
+	router := gin.New()
+	server.registerRoutes(router)
+
+	serve := func(method, url string, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	entry := func(sequence int, message string) string {
+		body, _ := json.Marshal(map[string]interface{}{
+			"sequence":     sequence,
+			"level":        "INFO",
+			"message":      message,
+			"service_name": "mobile-app",
+			"platform":     "swift",
+		})
+		return string(body)
+	}
+	type syncResponse struct {
+		LastSequence  int64 `json:"last_sequence"`
+		AcceptedCount int   `json:"accepted_count"`
+		Duplicates    int   `json:"duplicates"`
+	}
+	upload := func(body string) syncResponse {
+		t.Helper()
+		w := serve("POST", "/v1/logs/sync", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response syncResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return response
+	}
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{name: "missing agent", body: `{"entries": [` + entry(1, "First") + `]}`},
+		{name: "no entries", body: `{"agent_id": "device-1", "entries": []}`},
+		{name: "zero sequence", body: `{"agent_id": "device-1", "entries": [` + entry(0, "First") + `]}`},
+		{name: "decreasing sequence", body: `{"agent_id": "device-1", "entries": [` + entry(2, "Second") + `,` + entry(1, "First") + `]}`},
+		{name: "other agent", body: `{"agent_id": "device-1", "entries": [{"sequence": 1, "agent_id": "device-2", "level": "INFO", "message": "First", "service_name": "mobile-app", "platform": "swift"}]}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := serve("POST", "/v1/logs/sync", tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	response := upload(`{"agent_id": "device-1", "entries": [` + entry(1, "First") + `,` + entry(2, "Second") + `]}`)
+	if response.LastSequence != 2 || response.AcceptedCount != 2 || response.Duplicates != 0 {
+		t.Errorf("Unexpected first sync response: %+v", response)
+	}
+
+	// After reconnecting the agent re-sends the unacknowledged tail along with new entries
+	response = upload(`{"agent_id": "device-1", "entries": [` + entry(2, "Second") + `,` + entry(3, "Third") + `]}`)
+	if response.LastSequence != 3 || response.AcceptedCount != 1 || response.Duplicates != 1 {
+		t.Errorf("Unexpected resumed sync response: %+v", response)
+	}
+
+	response = upload(`{"agent_id": "device-1", "entries": [` + entry(3, "Third") + `]}`)
+	if response.LastSequence != 3 || response.AcceptedCount != 0 || response.Duplicates != 1 {
+		t.Errorf("Unexpected duplicate sync response: %+v", response)
+	}
+
+	w := serve("GET", "/v1/logs/sync/device-1", "")
+	var state models.SyncState
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil || state.LastSequence != 3 {
+		t.Errorf("Expected last sequence 3, got %s", w.Body.String())
+	}
+	w = serve("GET", "/v1/logs/sync/device-2", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil || state.LastSequence != 0 {
+		t.Errorf("Expected last sequence 0 for an unknown agent, got %s", w.Body.String())
+	}
+
+	// Each entry is stored once, with the agent ID of the request
+	if err := server.buffer.Flush(); err != nil {
+		t.Fatalf("Failed to flush buffer: %v", err)
+	}
+	result, err := memoryStorage.Query(context.Background(), models.LogFilter{AgentID: "device-1"})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 3 {
+		t.Errorf("Expected 3 stored entries, got %d", result.TotalCount)
+	}
+}
+
+func TestServer_SyncLogsNotSupported(t *testing.T) {
+	router := newServiceRegistryTestRouter(t, &MockStorage{})
+
+	req, _ := http.NewRequest("GET", "/v1/logs/sync/device-1", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
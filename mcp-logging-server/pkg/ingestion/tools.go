@@ -0,0 +1,69 @@
+package ingestion
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
+)
+
+// setToolRequest is the body of PUT /admin/tools/:name
+type setToolRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Reason  string `json:"reason"` // Why the tool is disabled, returned to clients calling it
+}
+
+// handleListTools handles requests for the MCP tools and whether they are enabled
+func (s *Server) handleListTools(c *gin.Context) {
+	if s.tools == nil {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "No MCP server runs", "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tools": s.tools.Tools()})
+}
+
+// handleSetTool handles requests enabling or disabling an MCP tool. Connected MCP clients are
+// sent notifications/tools/list_changed when the tool changed.
+func (s *Server) handleSetTool(c *gin.Context) {
+	if s.tools == nil {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "No MCP server runs", "")
+		return
+	}
+
+	var request setToolRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidJSON, "Invalid JSON format", err.Error())
+		return
+	}
+
+	name := c.Param("name")
+	previous := s.tools.Get(name)
+	state, err := s.tools.Set(name, *request.Enabled, request.Reason)
+	if errors.Is(err, toolswitch.ErrUnknownTool) {
+		problem.Respond(c, http.StatusNotFound, problem.CodeToolNotFound, "Tool does not exist", name)
+		return
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeInternalError, "Failed to switch tool", err.Error())
+		return
+	}
+	switch {
+	case state.Enabled && !previous.Enabled:
+		log.Printf("Enabled MCP tool %s", name)
+	case !state.Enabled && previous.Enabled:
+		log.Printf("Disabled MCP tool %s: %s", name, toolReason(state))
+	}
+
+	c.JSON(http.StatusOK, state)
+}
+
+// toolReason returns the reason a tool is disabled for log lines
+func toolReason(state toolswitch.State) string {
+	if state.Reason == "" {
+		return "no reason given"
+	}
+	return state.Reason
+}
@@ -0,0 +1,91 @@
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
+)
+
+func TestServer_Tools(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	adminKey, _ := manager.CreateAPIKey("admin", []auth.Permission{auth.PermissionAdmin}, 0, nil)
+
+	switches := toolswitch.New([]string{"annotate_log"})
+	switches.Register("query_logs", "annotate_log")
+	changes := 0
+	defer switches.Subscribe(func() { changes++ })()
+
+	newRouter := func(tools *toolswitch.Switch) *gin.Engine {
+		server := NewServerWithOptions(8080, storage.NewMemoryStorage(), buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+			t.TempDir(), manager, nil, nil, nil, nil, Options{Tools: tools})
+		t.Cleanup(server.rateLimiter.Stop)
+
+		router := gin.New()
+		router.Use(auth.AuthMiddleware(manager))
+		server.registerRoutes(router)
+		return router
+	}
+	router := newRouter(switches)
+
+	serve := func(router *gin.Engine, method, url string, body interface{}) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		req, _ := http.NewRequest(method, url, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", adminKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	var listed struct {
+		Tools []toolswitch.State `json:"tools"`
+	}
+	w := serve(router, "GET", "/admin/tools", nil)
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil || w.Code != http.StatusOK || len(listed.Tools) != 2 {
+		t.Fatalf("Expected 2 tools, got %d: %s", w.Code, w.Body.String())
+	}
+	if listed.Tools[0].Name != "annotate_log" || listed.Tools[0].Enabled || !listed.Tools[1].Enabled {
+		t.Errorf("Expected annotate_log disabled and query_logs enabled, got %+v", listed.Tools)
+	}
+
+	var state toolswitch.State
+	w = serve(router, "PUT", "/admin/tools/query_logs", gin.H{"enabled": false, "reason": "incident 42"})
+	if err := json.Unmarshal(w.Body.Bytes(), &state); err != nil || w.Code != http.StatusOK || state.Enabled || state.Reason != "incident 42" {
+		t.Fatalf("Expected query_logs disabled, got %d: %s", w.Code, w.Body.String())
+	}
+	if switches.Enabled("query_logs") || changes != 1 {
+		t.Errorf("Expected the switch changed once, got %d changes", changes)
+	}
+
+	if w := serve(router, "PUT", "/admin/tools/annotate_log", gin.H{"enabled": true}); w.Code != http.StatusOK || !switches.Enabled("annotate_log") {
+		t.Errorf("Expected annotate_log enabled, got %d: %s", w.Code, w.Body.String())
+	}
+
+	expectProblem := func(w *httptest.ResponseRecorder, status int, code problem.Code) {
+		t.Helper()
+		var details problem.Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil || w.Code != status || details.Code != code {
+			t.Errorf("Expected status %d with %s, got %d: %s", status, code, w.Code, w.Body.String())
+		}
+	}
+	expectProblem(serve(router, "PUT", "/admin/tools/delete_logs", gin.H{"enabled": false}), http.StatusNotFound, problem.CodeToolNotFound)
+	expectProblem(serve(router, "PUT", "/admin/tools/query_logs", gin.H{"reason": "no state"}), http.StatusBadRequest, problem.CodeInvalidJSON)
+
+	// Without an MCP server there are no tools to switch
+	expectProblem(serve(newRouter(nil), "GET", "/admin/tools", nil), http.StatusNotImplemented, problem.CodeNotSupported)
+}
@@ -0,0 +1,228 @@
+package ingestion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// usageFlushInterval is how often the usage counted in memory is added to storage
+const usageFlushInterval = time.Minute
+
+// usageKey identifies the usage of one API key for one service on one day
+type usageKey struct {
+	day         string
+	apiKeyID    string
+	serviceName string
+}
+
+// usageCounter counts accepted entries and bytes in memory until they are flushed to storage
+type usageCounter struct {
+	mutex  sync.Mutex
+	counts map[usageKey]models.UsageRecord
+}
+
+// newUsageCounter creates an empty usage counter
+func newUsageCounter() *usageCounter {
+	return &usageCounter{counts: make(map[usageKey]models.UsageRecord)}
+}
+
+// add counts accepted entries for an API key, by the service and UTC day they were received on
+func (u *usageCounter) add(apiKeyID string, entries []models.LogEntry, receivedAt time.Time) {
+	day := receivedAt.UTC().Format(models.UsageDayFormat)
+	sizes := make([]int64, len(entries))
+	for i := range entries {
+		if size, err := codec.EntrySize(&entries[i]); err == nil {
+			sizes[i] = size
+		}
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for i, entry := range entries {
+		u.addLocked(models.UsageRecord{
+			Day:         day,
+			APIKeyID:    apiKeyID,
+			ServiceName: entry.ServiceName,
+			Entries:     1,
+			Bytes:       sizes[i],
+		})
+	}
+}
+
+// addLocked adds a record to the counts, the caller holds the mutex
+func (u *usageCounter) addLocked(record models.UsageRecord) {
+	key := usageKey{record.Day, record.APIKeyID, record.ServiceName}
+	count, ok := u.counts[key]
+	if ok {
+		record.Entries += count.Entries
+		record.Bytes += count.Bytes
+	}
+	u.counts[key] = record
+}
+
+// drain returns the counts and resets them
+func (u *usageCounter) drain() []models.UsageRecord {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	records := make([]models.UsageRecord, 0, len(u.counts))
+	for _, record := range u.counts {
+		records = append(records, record)
+	}
+	u.counts = make(map[usageKey]models.UsageRecord)
+	return records
+}
+
+// restore adds drained records back, so a failed flush is retried with the next one
+func (u *usageCounter) restore(records []models.UsageRecord) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	for _, record := range records {
+		u.addLocked(record)
+	}
+}
+
+// recordUsage counts accepted entries for the API key of the request, if the storage accounts usage
+func (s *Server) recordUsage(c *gin.Context, entries []models.LogEntry) {
+	if _, ok := s.storage.(storage.UsageStore); !ok {
+		return
+	}
+
+	var apiKeyID string
+	if keyInfo, ok := auth.GetAPIKeyInfo(c); ok {
+		apiKeyID = keyInfo.ID
+	}
+	s.usage.add(apiKeyID, entries, time.Now())
+}
+
+// flushUsage adds the usage counted since the last flush to storage
+func (s *Server) flushUsage(ctx context.Context) error {
+	store, ok := s.storage.(storage.UsageStore)
+	if !ok {
+		return nil
+	}
+
+	records := s.usage.drain()
+	if len(records) == 0 {
+		return nil
+	}
+	if err := store.RecordUsage(ctx, records); err != nil {
+		s.usage.restore(records)
+		return err
+	}
+	return nil
+}
+
+// usageRoutine flushes usage periodically and once more when the context is cancelled
+func (s *Server) usageRoutine(ctx context.Context) {
+	ticker := time.NewTicker(usageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := s.flushUsage(flushCtx); err != nil {
+				fmt.Printf("Failed to flush usage: %v\n", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := s.flushUsage(ctx); err != nil {
+				fmt.Printf("Failed to flush usage: %v\n", err)
+			}
+		}
+	}
+}
+
+// usageResponse is a usage record as returned by the admin API, with the name of its API key
+type usageResponse struct {
+	models.UsageRecord
+	APIKeyName string `json:"api_key_name,omitempty"`
+}
+
+// handleGetUsage handles requests for the entries and bytes ingested per API key and service.
+// Query parameters: start_day, end_day (YYYY-MM-DD, inclusive), api_key_id, service, daily and limit.
+func (s *Server) handleGetUsage(c *gin.Context) {
+	store, ok := s.storage.(storage.UsageStore)
+	if !ok {
+		problem.Respond(c, http.StatusNotImplemented, problem.CodeNotSupported, "Storage does not support usage accounting", "")
+		return
+	}
+
+	filter := models.UsageFilter{
+		StartDay:    c.Query("start_day"),
+		EndDay:      c.Query("end_day"),
+		APIKeyID:    c.Query("api_key_id"),
+		ServiceName: c.Query("service"),
+	}
+	err := validateUsageDay(filter.StartDay)
+	if err == nil {
+		err = validateUsageDay(filter.EndDay)
+	}
+	if err == nil && c.Query("daily") != "" {
+		filter.Daily, err = strconv.ParseBool(c.Query("daily"))
+	}
+	if err == nil && c.Query("limit") != "" {
+		filter.Limit, err = strconv.Atoi(c.Query("limit"))
+	}
+	if err != nil {
+		problem.Respond(c, http.StatusBadRequest, problem.CodeValidationError, "Invalid usage query", err.Error())
+		return
+	}
+
+	// Include the usage not yet flushed so the numbers are current
+	if err := s.flushUsage(c.Request.Context()); err != nil {
+		fmt.Printf("Failed to flush usage: %v\n", err)
+	}
+
+	records, err := store.QueryUsage(c.Request.Context(), filter)
+	if err != nil {
+		problem.Respond(c, http.StatusInternalServerError, problem.CodeStorageError, "Failed to query usage", err.Error())
+		return
+	}
+
+	names := make(map[string]string)
+	for _, keyInfo := range s.authManager.ListAPIKeys() {
+		names[keyInfo.ID] = keyInfo.Name
+	}
+
+	var totalEntries, totalBytes int64
+	response := make([]usageResponse, len(records))
+	for i, record := range records {
+		response[i] = usageResponse{UsageRecord: record, APIKeyName: names[record.APIKeyID]}
+		totalEntries += record.Entries
+		totalBytes += record.Bytes
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"usage":         response,
+		"total_count":   len(response),
+		"total_entries": totalEntries,
+		"total_bytes":   totalBytes,
+	})
+}
+
+// validateUsageDay checks that a day is empty or in models.UsageDayFormat
+func validateUsageDay(day string) error {
+	if day == "" {
+		return nil
+	}
+	if _, err := time.Parse(models.UsageDayFormat, day); err != nil {
+		return fmt.Errorf("invalid day %q, expected YYYY-MM-DD", day)
+	}
+	return nil
+}
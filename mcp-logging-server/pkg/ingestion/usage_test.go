@@ -0,0 +1,93 @@
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestServer_Usage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	adminKey, _ := manager.CreateAPIKey("admin", []auth.Permission{auth.PermissionAdmin}, 0, nil)
+	edgeKey, _ := manager.CreateAPIKey("edge", []auth.Permission{auth.PermissionIngestLogs}, 0, nil)
+
+	store := storage.NewMemoryStorage()
+	server := NewServer(8080, store, buffer.Config{Size: 100, MaxBatchSize: 10, FlushTimeout: time.Second},
+		t.TempDir(), manager, nil, nil, nil, nil)
+	defer server.rateLimiter.Stop()
+
+	router := gin.New()
+	router.Use(auth.AuthMiddleware(manager))
+	server.registerRoutes(router)
+
+	serve := func(method, url, apiKey string, body []byte) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	batch := []byte(`[
+		{"level": "INFO", "message": "Order placed", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"},
+		{"level": "INFO", "message": "Order shipped", "service_name": "checkout", "agent_id": "edge-1", "platform": "go"},
+		{"level": "WARN", "message": "Slow query", "service_name": "search", "agent_id": "edge-1", "platform": "go"}
+	]`)
+	if w := serve("POST", "/v1/logs/batch", edgeKey, batch); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	invalid := []byte(`{"level": "INFO", "service_name": "checkout"}`)
+	if w := serve("POST", "/v1/logs", edgeKey, invalid); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	// Usage not yet flushed is included
+	w := serve("GET", "/admin/usage?service=checkout&daily=true", adminKey, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Usage        []usageResponse `json:"usage"`
+		TotalEntries int64           `json:"total_entries"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Usage) != 1 || response.TotalEntries != 2 {
+		t.Fatalf("Expected the two accepted checkout entries, got %+v", response)
+	}
+	record := response.Usage[0]
+	today := time.Now().UTC().Format(models.UsageDayFormat)
+	if record.APIKeyID != manager.KeyID(edgeKey) || record.APIKeyName != "edge" || record.Day != today || record.Bytes <= 0 {
+		t.Errorf("Unexpected usage record %+v", record)
+	}
+
+	// The counts were flushed to storage
+	stored, err := store.QueryUsage(context.Background(), models.UsageFilter{})
+	if err != nil {
+		t.Fatalf("Failed to query usage: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Errorf("Expected usage of both services in storage, got %+v", stored)
+	}
+
+	if w := serve("GET", "/admin/usage?start_day=yesterday", adminKey, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d for an invalid day, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := serve("GET", "/admin/usage", edgeKey, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d without admin permission, got %d", http.StatusForbidden, w.Code)
+	}
+}
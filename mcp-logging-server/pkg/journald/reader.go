@@ -0,0 +1,360 @@
+// Package journald follows the systemd journal and ingests its entries as log entries, with the
+// systemd unit as service, so that hosts and relays can ship system logs without a separate
+// agent. The journal is read through journalctl, which keeps the reader free of cgo.
+package journald
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+const (
+	// DefaultCommand is the journalctl binary looked up in PATH when none is configured
+	DefaultCommand = "journalctl"
+
+	// DefaultBatchSize is the number of journal entries ingested together when none is configured
+	DefaultBatchSize = 100
+
+	// DefaultFlushInterval is how long entries wait for a batch to fill when none is configured
+	DefaultFlushInterval = time.Second
+
+	// retryDelay is how long the reader waits before following the journal again after a failure
+	retryDelay = 5 * time.Second
+
+	// maxNameLength is the longest service name and agent ID entries accept
+	maxNameLength = 100
+)
+
+// Ingester buffers log entries for storage, implemented by the ingestion server
+type Ingester interface {
+	Ingest(ctx context.Context, entries []models.LogEntry) (int, error)
+}
+
+// Config configures a Reader
+type Config struct {
+	Command       string          // journalctl binary, defaults to DefaultCommand
+	Directory     string          // Journal directory, the system journal when empty
+	Units         []string        // Units to follow, empty follows all
+	CursorFile    string          // File the cursor of the last ingested entry is saved to, empty disables resume
+	BatchSize     int             // Entries ingested together, defaults to DefaultBatchSize
+	FlushInterval time.Duration   // How long entries wait for a batch to fill, defaults to DefaultFlushInterval
+	Platform      models.Platform // Platform of the entries, must be accepted by ingestion
+}
+
+// Reader follows the journal and ingests its entries in batches. The cursor of the last ingested
+// entry is saved after each batch, so that a restart resumes after it instead of skipping or
+// repeating entries.
+type Reader struct {
+	config   Config
+	ingester Ingester
+	cursor   string // Cursor of the last ingested entry, empty starts at the end of the journal
+}
+
+// NewReader creates a reader ingesting journal entries through ingester, resuming from the saved
+// cursor if there is one
+func NewReader(config Config, ingester Ingester) (*Reader, error) {
+	if config.Command == "" {
+		config.Command = DefaultCommand
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+	if config.Platform == "" {
+		config.Platform = models.PlatformGo
+	}
+
+	r := &Reader{config: config, ingester: ingester}
+	if config.CursorFile != "" {
+		data, err := os.ReadFile(config.CursorFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read journal cursor: %w", err)
+		}
+		r.cursor = strings.TrimSpace(string(data))
+	}
+	return r, nil
+}
+
+// Run follows the journal until ctx is done. Failures of journalctl or of ingestion are logged
+// and the journal is followed again from the last ingested entry.
+func (r *Reader) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		if err := r.follow(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Reading the journal failed, retrying in %s: %v", retryDelay, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+	return nil
+}
+
+// args returns the journalctl arguments following the journal after the current cursor
+func (r *Reader) args() []string {
+	args := []string{"--follow", "--output=json", "--no-pager"}
+	if r.cursor != "" {
+		args = append(args, "--after-cursor="+r.cursor)
+	} else {
+		args = append(args, "--lines=0")
+	}
+	if r.config.Directory != "" {
+		args = append(args, "--directory="+r.config.Directory)
+	}
+	for _, unit := range r.config.Units {
+		args = append(args, "--unit="+unit)
+	}
+	return args
+}
+
+// follow runs journalctl and ingests its output until it exits, ctx is done or a batch cannot
+// be ingested
+func (r *Reader) follow(ctx context.Context) error {
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(readCtx, r.config.Command, r.args()...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", r.config.Command, err)
+	}
+
+	lines := make(chan []byte)
+	readErrs := make(chan error, 1)
+	go func() {
+		readErrs <- readLines(readCtx, stdout, lines)
+		close(lines)
+	}()
+
+	err = r.ingestLines(ctx, lines)
+	// Stops journalctl if ingestion failed, the reader then sees the end of its output
+	cancel()
+	for range lines {
+	}
+	if readErr := <-readErrs; err == nil {
+		err = readErr
+	}
+	waitErr := cmd.Wait()
+	if err == nil && ctx.Err() == nil && waitErr != nil {
+		err = fmt.Errorf("%s exited: %w: %s", r.config.Command, waitErr, strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// ingestLines ingests the journal entries read from lines in batches until lines is closed
+func (r *Reader) ingestLines(ctx context.Context, lines <-chan []byte) error {
+	batch := make([]models.LogEntry, 0, r.config.BatchSize)
+	var batchCursor string
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := r.ingester.Ingest(ctx, batch); err != nil {
+			return fmt.Errorf("failed to ingest journal entries: %w", err)
+		}
+		batch = batch[:0]
+		return r.saveCursor(batchCursor)
+	}
+
+	ticker := time.NewTicker(r.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return flush()
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(line, &fields); err != nil {
+				log.Printf("Skipping unreadable journal entry: %v", err)
+				continue
+			}
+			entry, cursor := ToEntry(fields, r.config.Platform)
+			if cursor != "" {
+				batchCursor = cursor
+			}
+			if entry.Message == "" {
+				continue
+			}
+			batch = append(batch, entry)
+			if len(batch) >= r.config.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		case <-ticker.C:
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// saveCursor records the cursor of the last ingested entry, in memory and in the cursor file
+func (r *Reader) saveCursor(cursor string) error {
+	if cursor == "" || cursor == r.cursor {
+		return nil
+	}
+	r.cursor = cursor
+	if r.config.CursorFile == "" {
+		return nil
+	}
+
+	tmp := r.config.CursorFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(cursor+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to save journal cursor: %w", err)
+	}
+	if err := os.Rename(tmp, r.config.CursorFile); err != nil {
+		return fmt.Errorf("failed to save journal cursor: %w", err)
+	}
+	return nil
+}
+
+// readLines sends each line of r to lines until r ends or ctx is done
+func readLines(ctx context.Context, r io.Reader, lines chan<- []byte) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		select {
+		case lines <- line:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, os.ErrClosed) && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// Journal priorities, as in syslog
+var priorityLevels = [...]models.LogLevel{
+	0: models.LogLevelFatal, // emerg
+	1: models.LogLevelFatal, // alert
+	2: models.LogLevelFatal, // crit
+	3: models.LogLevelError, // err
+	4: models.LogLevelWarn,  // warning
+	5: models.LogLevelInfo,  // notice
+	6: models.LogLevelInfo,  // info
+	7: models.LogLevelDebug, // debug
+}
+
+// Journal fields kept as metadata, by metadata key
+var metadataFields = map[string]string{
+	"_SYSTEMD_UNIT":     "unit",
+	"SYSLOG_IDENTIFIER": "syslog_identifier",
+	"_PID":              "pid",
+	"_COMM":             "command",
+	"_TRANSPORT":        "transport",
+	"_BOOT_ID":          "boot_id",
+	"CODE_FILE":         "code_file",
+	"CODE_LINE":         "code_line",
+	"CODE_FUNC":         "code_func",
+}
+
+// ToEntry converts a journal entry in journalctl's JSON output to a log entry and returns it
+// with the entry's cursor. The service is the systemd unit without its .service suffix, or the
+// syslog identifier of entries outside a unit, and the agent ID is the host name.
+func ToEntry(fields map[string]interface{}, platform models.Platform) (models.LogEntry, string) {
+	level := models.LogLevelInfo
+	if priority, err := strconv.Atoi(fieldString(fields, "PRIORITY")); err == nil && priority >= 0 && priority < len(priorityLevels) {
+		level = priorityLevels[priority]
+	}
+
+	service := strings.TrimSuffix(fieldString(fields, "_SYSTEMD_UNIT"), ".service")
+	if service == "" {
+		service = fieldString(fields, "SYSLOG_IDENTIFIER")
+	}
+	if service == "" {
+		service = fieldString(fields, "_COMM")
+	}
+	if service == "" {
+		service = "journal"
+	}
+
+	agent := sanitizeName(fieldString(fields, "_HOSTNAME"))
+	if agent == "" {
+		agent = "journald"
+	}
+
+	var timestamp time.Time
+	if micros, err := strconv.ParseInt(fieldString(fields, "__REALTIME_TIMESTAMP"), 10, 64); err == nil {
+		timestamp = time.UnixMicro(micros).UTC()
+	}
+
+	metadata := make(map[string]interface{})
+	for field, key := range metadataFields {
+		if value := fieldString(fields, field); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	entry := models.LogEntry{
+		Timestamp:   timestamp,
+		Level:       level,
+		Message:     strings.TrimRight(fieldString(fields, "MESSAGE"), "\n"),
+		ServiceName: sanitizeName(service),
+		AgentID:     agent,
+		Platform:    platform,
+	}
+	if len(metadata) > 0 {
+		entry.Metadata = metadata
+	}
+	return entry, fieldString(fields, "__CURSOR")
+}
+
+// fieldString returns a journal field as a string. journalctl outputs fields that are not valid
+// UTF-8 as arrays of bytes.
+func fieldString(fields map[string]interface{}, name string) string {
+	switch value := fields[name].(type) {
+	case string:
+		return value
+	case []interface{}:
+		data := make([]byte, 0, len(value))
+		for _, b := range value {
+			if number, ok := b.(float64); ok {
+				data = append(data, byte(number))
+			}
+		}
+		return string(data)
+	default:
+		return ""
+	}
+}
+
+// sanitizeName replaces the characters service names and agent IDs cannot contain, such as the
+// @ of template units, with dashes
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	return name
+}
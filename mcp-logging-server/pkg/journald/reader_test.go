@@ -0,0 +1,138 @@
+package journald
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+type recordingIngester struct {
+	mu      sync.Mutex
+	entries []models.LogEntry
+	want    int
+	done    chan struct{}
+}
+
+func (r *recordingIngester) Ingest(ctx context.Context, entries []models.LogEntry) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entries...)
+	if len(r.entries) >= r.want {
+		select {
+		case <-r.done:
+		default:
+			close(r.done)
+		}
+	}
+	return len(entries), nil
+}
+
+// fakeJournalctl writes a script that records its arguments, prints the output of journalctl
+// and keeps following like journalctl --follow
+func fakeJournalctl(t *testing.T, output string) (command, argsFile string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake journalctl is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsFile = filepath.Join(dir, "args")
+	outputFile := filepath.Join(dir, "output")
+	if err := os.WriteFile(outputFile, []byte(output), 0644); err != nil {
+		t.Fatal(err)
+	}
+	command = filepath.Join(dir, "journalctl")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat " + outputFile + "\nexec sleep 30\n"
+	if err := os.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return command, argsFile
+}
+
+func runReader(t *testing.T, reader *Reader, ingester *recordingIngester) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- reader.Run(ctx) }()
+
+	select {
+	case <-ingester.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for journal entries")
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+}
+
+func TestReader_FollowsJournalWithCursor(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	timestamp := strconv.FormatInt(now.UnixMicro(), 10)
+	output := strings.Join([]string{
+		`{"__CURSOR": "s=1;i=1", "__REALTIME_TIMESTAMP": "` + timestamp + `", "PRIORITY": "3", "_SYSTEMD_UNIT": "nginx.service", "_HOSTNAME": "web-1.example.com", "_PID": "812", "MESSAGE": "upstream timed out"}`,
+		`{"__CURSOR": "s=1;i=2", "__REALTIME_TIMESTAMP": "` + timestamp + `", "PRIORITY": "6", "SYSLOG_IDENTIFIER": "sshd", "_HOSTNAME": "web-1", "MESSAGE": [65, 99, 99, 101, 112, 116, 101, 100]}`,
+		`not json`,
+	}, "\n") + "\n"
+	command, argsFile := fakeJournalctl(t, output)
+	cursorFile := filepath.Join(t.TempDir(), "cursor")
+
+	ingester := &recordingIngester{want: 2, done: make(chan struct{})}
+	reader, err := NewReader(Config{
+		Command:       command,
+		Units:         []string{"nginx.service"},
+		CursorFile:    cursorFile,
+		FlushInterval: 10 * time.Millisecond,
+	}, ingester)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	runReader(t, reader, ingester)
+
+	args, _ := os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "--lines=0") || !strings.Contains(string(args), "--unit=nginx.service") {
+		t.Errorf("Expected to follow new entries of the unit, got args %q", args)
+	}
+
+	first := ingester.entries[0]
+	if first.ServiceName != "nginx" || first.AgentID != "web-1-example-com" || first.Level != models.LogLevelError {
+		t.Errorf("Unexpected entry %+v", first)
+	}
+	if !first.Timestamp.Equal(now) || first.Metadata["pid"] != "812" || first.Metadata["unit"] != "nginx.service" {
+		t.Errorf("Unexpected timestamp or metadata %v %v", first.Timestamp, first.Metadata)
+	}
+
+	second := ingester.entries[1]
+	if second.ServiceName != "sshd" || second.Message != "Accepted" || second.Level != models.LogLevelInfo {
+		t.Errorf("Unexpected entry %+v", second)
+	}
+
+	cursor, err := os.ReadFile(cursorFile)
+	if err != nil || strings.TrimSpace(string(cursor)) != "s=1;i=2" {
+		t.Fatalf("Expected the cursor of the last entry to be saved, got %q (%v)", cursor, err)
+	}
+
+	// A new reader resumes after the saved cursor
+	command, argsFile = fakeJournalctl(t, `{"__CURSOR": "s=1;i=3", "PRIORITY": "4", "_SYSTEMD_UNIT": "nginx.service", "MESSAGE": "slow"}`+"\n")
+	ingester = &recordingIngester{want: 1, done: make(chan struct{})}
+	reader, err = NewReader(Config{Command: command, CursorFile: cursorFile, FlushInterval: 10 * time.Millisecond}, ingester)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	runReader(t, reader, ingester)
+
+	args, _ = os.ReadFile(argsFile)
+	if !strings.Contains(string(args), "--after-cursor=s=1;i=2") {
+		t.Errorf("Expected to resume after the saved cursor, got args %q", args)
+	}
+	if ingester.entries[0].Level != models.LogLevelWarn {
+		t.Errorf("Expected priority 4 to map to WARN, got %s", ingester.entries[0].Level)
+	}
+}
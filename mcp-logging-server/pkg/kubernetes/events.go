@@ -0,0 +1,366 @@
+// Package kubernetes watches the Events of a Kubernetes cluster and ingests them as log
+// entries, so that cluster events such as failed scheduling, image pull errors and container
+// restarts can be queried through the same MCP tools as application logs
+package kubernetes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+const (
+	// ServiceAccountDir holds the token and CA bundle mounted into pods
+	ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// DefaultAgentID is the agent of events without a reporting component
+	DefaultAgentID = "kubernetes"
+
+	// retryDelay is how long the watcher waits before listing events again after a failure
+	retryDelay = 5 * time.Second
+
+	// maxNameLength is the longest service name and agent ID entries accept
+	maxNameLength = 100
+)
+
+// Ingester buffers log entries for storage, implemented by the ingestion server
+type Ingester interface {
+	Ingest(ctx context.Context, entries []models.LogEntry) (int, error)
+}
+
+// Config configures a Watcher
+type Config struct {
+	APIServer string          // API server URL, the in-cluster address from KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT when empty
+	TokenFile string          // Bearer token file, the pod's service account token when empty
+	CAFile    string          // PEM CA bundle of the API server, the service account CA when empty
+	Namespace string          // Namespace to watch, empty watches all namespaces
+	Platform  models.Platform // Platform of the entries, must be accepted by ingestion
+}
+
+// Watcher ingests the Events of a cluster as they are created or repeated
+type Watcher struct {
+	config   Config
+	client   *http.Client
+	ingester Ingester
+}
+
+// NewWatcher creates a watcher ingesting events through ingester
+func NewWatcher(config Config, ingester Ingester) (*Watcher, error) {
+	if config.APIServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("not running in a cluster, set the Kubernetes API server URL")
+		}
+		config.APIServer = "https://" + net.JoinHostPort(host, port)
+	}
+	config.APIServer = strings.TrimRight(config.APIServer, "/")
+	if config.TokenFile == "" {
+		config.TokenFile = filepath.Join(ServiceAccountDir, "token")
+	}
+	if config.CAFile == "" && strings.HasPrefix(config.APIServer, "https://") {
+		config.CAFile = filepath.Join(ServiceAccountDir, "ca.crt")
+	}
+	if config.Platform == "" {
+		config.Platform = models.PlatformGo
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kubernetes CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in Kubernetes CA file %s", config.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &Watcher{
+		config:   config,
+		client:   &http.Client{Transport: transport},
+		ingester: ingester,
+	}, nil
+}
+
+// Run watches events until ctx is done. Events that exist when the watch starts are skipped,
+// so that restarts do not ingest them again. Failures are logged and the watch is restarted.
+func (w *Watcher) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		resourceVersion, err := w.list(ctx)
+		if err == nil {
+			err = w.watch(ctx, resourceVersion)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Watching Kubernetes events failed, retrying in %s: %v", retryDelay, err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+	return nil
+}
+
+// errExpired reports that the resource version of a watch is too old to resume from
+var errExpired = errors.New("resource version expired")
+
+// list returns the current resource version of the event list
+func (w *Watcher) list(ctx context.Context) (string, error) {
+	body, err := w.get(ctx, url.Values{"limit": {"1"}})
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	var list struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(body).Decode(&list); err != nil {
+		return "", fmt.Errorf("failed to decode event list: %w", err)
+	}
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch ingests the events changed after resourceVersion. The API server ends watches after a
+// while, they are resumed from the last resource version seen until it expires.
+func (w *Watcher) watch(ctx context.Context, resourceVersion string) error {
+	for ctx.Err() == nil {
+		next, err := w.watchOnce(ctx, resourceVersion)
+		if errors.Is(err, errExpired) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		resourceVersion = next
+	}
+	return nil
+}
+
+// watchOnce ingests events from a single watch request and returns the last resource version seen
+func (w *Watcher) watchOnce(ctx context.Context, resourceVersion string) (string, error) {
+	body, err := w.get(ctx, url.Values{
+		"watch":               {"1"},
+		"resourceVersion":     {resourceVersion},
+		"allowWatchBookmarks": {"true"},
+	})
+	if err != nil {
+		return resourceVersion, err
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var change watchEvent
+		if err := decoder.Decode(&change); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return resourceVersion, nil
+			}
+			return resourceVersion, fmt.Errorf("failed to decode watch event: %w", err)
+		}
+
+		switch change.Type {
+		case "ERROR":
+			var status struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			}
+			json.Unmarshal(change.Object, &status)
+			if status.Code == http.StatusGone {
+				return resourceVersion, errExpired
+			}
+			return resourceVersion, fmt.Errorf("watch failed: %s", status.Message)
+		case "ADDED", "MODIFIED", "BOOKMARK":
+			var event Event
+			if err := json.Unmarshal(change.Object, &event); err != nil {
+				return resourceVersion, fmt.Errorf("failed to decode event: %w", err)
+			}
+			resourceVersion = event.Metadata.ResourceVersion
+			if change.Type == "BOOKMARK" {
+				continue
+			}
+			// A modified event has been repeated, its count and last timestamp are updated
+			entry := event.ToEntry(w.config.Platform)
+			if _, err := w.ingester.Ingest(ctx, []models.LogEntry{entry}); err != nil {
+				log.Printf("Failed to ingest Kubernetes event %s/%s: %v", event.Metadata.Namespace, event.Metadata.Name, err)
+			}
+		}
+	}
+}
+
+// get performs a GET request on the event collection and fails on error statuses. The caller
+// closes the body.
+func (w *Watcher) get(ctx context.Context, query url.Values) (io.ReadCloser, error) {
+	path := "/api/v1/events"
+	if w.config.Namespace != "" {
+		path = "/api/v1/namespaces/" + url.PathEscape(w.config.Namespace) + "/events"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.config.APIServer+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	// The token is read on every request since the kubelet rotates it
+	if token, err := os.ReadFile(w.config.TokenFile); err == nil {
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read Kubernetes token: %w", err)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusGone {
+		resp.Body.Close()
+		return nil, errExpired
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("Kubernetes API %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp.Body, nil
+}
+
+// watchEvent is a change notification of a watch
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// Event is the part of a core/v1 Event that is ingested
+type Event struct {
+	Metadata struct {
+		Name              string    `json:"name"`
+		Namespace         string    `json:"namespace"`
+		UID               string    `json:"uid"`
+		ResourceVersion   string    `json:"resourceVersion"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+	} `json:"metadata"`
+	InvolvedObject struct {
+		Kind      string `json:"kind"`
+		Namespace string `json:"namespace"`
+		Name      string `json:"name"`
+		UID       string `json:"uid"`
+		FieldPath string `json:"fieldPath"`
+	} `json:"involvedObject"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Type           string    `json:"type"` // Normal or Warning
+	Count          int       `json:"count"`
+	FirstTimestamp time.Time `json:"firstTimestamp"`
+	LastTimestamp  time.Time `json:"lastTimestamp"`
+	EventTime      time.Time `json:"eventTime"`
+	Source         struct {
+		Component string `json:"component"`
+		Host      string `json:"host"`
+	} `json:"source"`
+	ReportingComponent string `json:"reportingComponent"`
+}
+
+// ToEntry converts the event to a log entry. The service is the namespace and name of the
+// involved object joined by an underscore, since service names cannot contain slashes and
+// object names cannot contain underscores, e.g. kube-system_coredns-5d78c9869d-x2v4q.
+// Warning events are logged at WARN, all others at INFO.
+func (e *Event) ToEntry(platform models.Platform) models.LogEntry {
+	namespace := e.InvolvedObject.Namespace
+	if namespace == "" {
+		// Cluster scoped objects such as nodes
+		namespace = "cluster"
+	}
+
+	level := models.LogLevelInfo
+	if e.Type == "Warning" {
+		level = models.LogLevelWarn
+	}
+
+	message := e.Message
+	if e.Reason != "" {
+		message = e.Reason + ": " + message
+	}
+
+	agent := e.ReportingComponent
+	if agent == "" {
+		agent = e.Source.Component
+	}
+	agent = sanitizeName(agent)
+	if agent == "" {
+		agent = DefaultAgentID
+	}
+
+	metadata := map[string]interface{}{
+		"kind":       e.InvolvedObject.Kind,
+		"name":       e.InvolvedObject.Name,
+		"namespace":  e.InvolvedObject.Namespace,
+		"reason":     e.Reason,
+		"event_type": e.Type,
+		"event_name": e.Metadata.Name,
+		"event_uid":  e.Metadata.UID,
+	}
+	if e.InvolvedObject.FieldPath != "" {
+		metadata["field_path"] = e.InvolvedObject.FieldPath
+	}
+	if e.Count > 0 {
+		metadata["count"] = e.Count
+	}
+	if !e.FirstTimestamp.IsZero() {
+		metadata["first_timestamp"] = e.FirstTimestamp.UTC().Format(time.RFC3339)
+	}
+	if e.Source.Host != "" {
+		metadata["host"] = e.Source.Host
+	}
+
+	return models.LogEntry{
+		Timestamp:   e.timestamp(),
+		Level:       level,
+		Message:     strings.TrimSpace(message),
+		ServiceName: sanitizeName(namespace + "_" + e.InvolvedObject.Name),
+		AgentID:     agent,
+		Platform:    platform,
+		Metadata:    metadata,
+	}
+}
+
+// timestamp returns when the event last occurred
+func (e *Event) timestamp() time.Time {
+	for _, timestamp := range []time.Time{e.LastTimestamp, e.EventTime, e.FirstTimestamp, e.Metadata.CreationTimestamp} {
+		if !timestamp.IsZero() {
+			return timestamp.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// sanitizeName replaces the characters service names and agent IDs cannot contain, such as the
+// dots of object names, with dashes
+func sanitizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	return name
+}
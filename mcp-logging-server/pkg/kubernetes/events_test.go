@@ -0,0 +1,115 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+type recordingIngester struct {
+	mu      sync.Mutex
+	entries []models.LogEntry
+	done    chan struct{}
+}
+
+func (r *recordingIngester) Ingest(ctx context.Context, entries []models.LogEntry) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entries...)
+	if len(r.entries) == 2 {
+		close(r.done)
+	}
+	return len(entries), nil
+}
+
+func TestWatcher_IngestsEvents(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	watchVersions := make(chan string, 10)
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/shop/events" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("watch") == "" {
+			fmt.Fprint(w, `{"kind": "EventList", "metadata": {"resourceVersion": "100"}, "items": [{"reason": "Old"}]}`)
+			return
+		}
+
+		watchVersions <- r.URL.Query().Get("resourceVersion")
+		fmt.Fprintf(w, `{"type": "ADDED", "object": {"metadata": {"name": "web-1.17a", "namespace": "shop", "resourceVersion": "101"},
+			"involvedObject": {"kind": "Pod", "namespace": "shop", "name": "web-1"}, "reason": "BackOff",
+			"message": "Back-off restarting failed container", "type": "Warning", "count": 3,
+			"lastTimestamp": %q, "source": {"component": "kubelet", "host": "node-1"}}}
+{"type": "BOOKMARK", "object": {"metadata": {"resourceVersion": "102"}}}
+{"type": "MODIFIED", "object": {"metadata": {"name": "node-1.18b", "resourceVersion": "103"},
+			"involvedObject": {"kind": "Node", "name": "node-1.example.com"}, "reason": "NodeReady",
+			"message": "Node is ready", "type": "Normal", "eventTime": %q}}
+`, now.Format(time.RFC3339), now.Format(time.RFC3339Nano))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer api.Close()
+
+	ingester := &recordingIngester{done: make(chan struct{})}
+	watcher, err := NewWatcher(Config{
+		APIServer: api.URL,
+		TokenFile: filepath.Join(t.TempDir(), "token"),
+		Namespace: "shop",
+	}, ingester)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- watcher.Run(ctx) }()
+
+	select {
+	case <-ingester.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for events")
+	}
+	cancel()
+	if err := <-stopped; err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+
+	if watchVersion := <-watchVersions; watchVersion != "100" {
+		t.Errorf("Expected the watch to start at the list version 100, got %q", watchVersion)
+	}
+
+	warning := ingester.entries[0]
+	if warning.ServiceName != "shop_web-1" || warning.AgentID != "kubelet" || warning.Level != models.LogLevelWarn {
+		t.Errorf("Unexpected warning entry %+v", warning)
+	}
+	if warning.Message != "BackOff: Back-off restarting failed container" {
+		t.Errorf("Unexpected message %q", warning.Message)
+	}
+	if !warning.Timestamp.Equal(now) || warning.Metadata["count"] != 3 || warning.Metadata["kind"] != "Pod" {
+		t.Errorf("Unexpected timestamp or metadata %v %v", warning.Timestamp, warning.Metadata)
+	}
+	if warning.Platform != models.PlatformGo {
+		t.Errorf("Expected the default platform, got %s", warning.Platform)
+	}
+
+	normal := ingester.entries[1]
+	if normal.ServiceName != "cluster_node-1-example-com" || normal.AgentID != DefaultAgentID || normal.Level != models.LogLevelInfo {
+		t.Errorf("Unexpected normal entry %+v", normal)
+	}
+	for _, entry := range ingester.entries {
+		entry.ID = uuid.New().String()
+		if err := entry.Validate(); err != nil {
+			t.Errorf("Expected a valid entry, got %v", err)
+		}
+	}
+}
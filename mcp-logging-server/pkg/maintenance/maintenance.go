@@ -0,0 +1,82 @@
+// Package maintenance holds the operating mode of the server, switched by operators during work
+// such as storage migrations: read-only mode rejects ingestion while queries keep working, and
+// maintenance mode rejects both.
+package maintenance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Mode is an operating mode of the server
+type Mode string
+
+const (
+	ModeNormal      Mode = "normal"      // Ingestion and queries are served
+	ModeReadOnly    Mode = "read_only"   // Ingestion and other writes are rejected, queries are served
+	ModeMaintenance Mode = "maintenance" // Ingestion and queries are rejected, only health, metrics and admin endpoints are served
+)
+
+// State is the current mode with why and since when it is set
+type State struct {
+	Mode   Mode      `json:"mode"`
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// Switch holds the mode shared by the ingestion and MCP servers. The zero value is not usable,
+// create one with New.
+type Switch struct {
+	mutex sync.RWMutex
+	state State
+}
+
+// New creates a switch in normal mode
+func New() *Switch {
+	return &Switch{state: State{Mode: ModeNormal, Since: time.Now().UTC()}}
+}
+
+// ParseMode checks that a mode is known
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case ModeNormal, ModeReadOnly, ModeMaintenance:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown mode %q, must be %s, %s or %s", value, ModeNormal, ModeReadOnly, ModeMaintenance)
+}
+
+// Set switches to a mode and returns the new state. Setting the current mode again only
+// updates the reason.
+func (s *Switch) Set(mode Mode, reason string) (State, error) {
+	if _, err := ParseMode(string(mode)); err != nil {
+		return State{}, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if mode != s.state.Mode {
+		s.state.Since = time.Now().UTC()
+	}
+	s.state.Mode = mode
+	s.state.Reason = reason
+	return s.state, nil
+}
+
+// State returns the current mode
+func (s *Switch) State() State {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.state
+}
+
+// AllowsWrites reports whether entries may be ingested and other data changed
+func (s *Switch) AllowsWrites() bool {
+	return s.State().Mode == ModeNormal
+}
+
+// AllowsQueries reports whether log entries may be queried
+func (s *Switch) AllowsQueries() bool {
+	return s.State().Mode != ModeMaintenance
+}
@@ -0,0 +1,34 @@
+package maintenance
+
+import "testing"
+
+func TestSwitch(t *testing.T) {
+	s := New()
+	if state := s.State(); state.Mode != ModeNormal || state.Since.IsZero() || !s.AllowsWrites() || !s.AllowsQueries() {
+		t.Fatalf("Expected a new switch in normal mode, got %+v", state)
+	}
+
+	readOnly, err := s.Set(ModeReadOnly, "storage migration")
+	if err != nil {
+		t.Fatalf("Failed to set read-only mode: %v", err)
+	}
+	if readOnly.Reason != "storage migration" || s.AllowsWrites() || !s.AllowsQueries() {
+		t.Errorf("Expected writes rejected and queries served in read-only mode, got %+v", readOnly)
+	}
+
+	// Setting the same mode keeps the time it was entered
+	if state, _ := s.Set(ModeReadOnly, "copying the database"); !state.Since.Equal(readOnly.Since) || state.Reason != "copying the database" {
+		t.Errorf("Expected the reason updated and the time kept, got %+v", state)
+	}
+
+	if _, err := s.Set(ModeMaintenance, ""); err != nil || s.AllowsWrites() || s.AllowsQueries() {
+		t.Errorf("Expected writes and queries rejected in maintenance mode, got %+v, %v", s.State(), err)
+	}
+
+	if _, err := s.Set("offline", ""); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+	if s.State().Mode != ModeMaintenance {
+		t.Errorf("Expected an unknown mode to leave the mode unchanged, got %s", s.State().Mode)
+	}
+}
@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// auditedCall collects what a tool call returned, for the query audit
+type auditedCall struct {
+	resultCount int
+}
+
+// auditedCallContextKey is the context key of the audited call a tool handler runs for
+type auditedCallContextKey struct{}
+
+// withAuditedCall returns a context carrying the audited call
+func withAuditedCall(ctx context.Context, call *auditedCall) context.Context {
+	return context.WithValue(ctx, auditedCallContextKey{}, call)
+}
+
+// countResults records the number of log entries a tool call returns. Tools returning no
+// entries, such as aggregates, do not call it.
+func countResults(ctx context.Context, count int) {
+	if call, ok := ctx.Value(auditedCallContextKey{}).(*auditedCall); ok {
+		call.resultCount = count
+	}
+}
+
+// countedResults returns the number of log entries recorded by countResults
+func countedResults(ctx context.Context) int {
+	if call, ok := ctx.Value(auditedCallContextKey{}).(*auditedCall); ok {
+		return call.resultCount
+	}
+	return 0
+}
+
+// countGroups returns the number of entries representing message groups
+func countGroups(groups ...[]models.MessageGroup) int {
+	count := 0
+	for _, g := range groups {
+		count += len(g)
+	}
+	return count
+}
+
+// auditToolCall adds a tool call to the query audit. The arguments, including session defaults,
+// are recorded as the filter, and the masked fields are taken from mask_fields.
+func (s *Server) auditToolCall(ctx context.Context, toolName string, arguments interface{}, start time.Time, elapsed time.Duration, resultCount int, err error) {
+	record := models.QueryAudit{
+		Time:        start.UTC(),
+		Interface:   models.QueryInterfaceMCP,
+		Operation:   toolName,
+		ResultCount: resultCount,
+		LatencyMs:   elapsed.Milliseconds(),
+	}
+	if sess := sessionFromContext(ctx); sess != nil {
+		record.Actor, record.Source = sess.client()
+	}
+	if given, ok := arguments.(map[string]interface{}); ok && len(given) > 0 {
+		record.Filter = given
+		record.MaskedFields = stringList(given["mask_fields"])
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	s.options.QueryAudit.Record(record)
+}
+
+// stringList returns the strings of a list argument, as decoded from JSON or set by session defaults
+func stringList(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		strs := make([]string, 0, len(list))
+		for _, item := range list {
+			if str, ok := item.(string); ok {
+				strs = append(strs, str)
+			}
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/queryaudit"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestHandleToolCall_QueryAudit(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	recorder := queryaudit.NewRecorder(memoryStorage, 0)
+	server := NewServerWithOptions(8081, memoryStorage, Options{
+		QueryAudit:  recorder,
+		ResultCache: NewResultCache(time.Minute, 10),
+	})
+	ctx := withSession(context.Background(), &session{address: "10.0.0.5:52100"})
+	now := time.Now().UTC()
+
+	err := memoryStorage.Store(ctx, []models.LogEntry{
+		{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelInfo, Message: "checkout started", ServiceName: "checkout", AgentID: "agent-1", Platform: models.PlatformGo},
+		{ID: uuid.New().String(), Timestamp: now.Add(-time.Second), Level: models.LogLevelInfo, Message: "order placed", ServiceName: "checkout", AgentID: "agent-1", Platform: models.PlatformGo},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	server.handleMessage(ctx, &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      "init",
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"clientInfo": map[string]interface{}{"name": "claude-desktop", "version": "1.0"},
+			"capabilities": map[string]interface{}{
+				"experimental": map[string]interface{}{
+					"sessionDefaults": map[string]interface{}{"mask_fields": []interface{}{"agent_id"}},
+				},
+			},
+		},
+	})
+
+	call := func(tool string, arguments map[string]interface{}) {
+		t.Helper()
+		server.handleMessage(ctx, &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      tool,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": tool, "arguments": arguments},
+		})
+	}
+	call("query_logs", map[string]interface{}{"service_name": "checkout"})
+	call("query_logs", map[string]interface{}{"service_name": "checkout"}) // Answered from the cache
+	call("get_log_context", map[string]interface{}{"id": "missing"})
+
+	records, err := recorder.List(ctx, models.QueryAuditFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list query audit: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 audited calls, got %+v", records)
+	}
+
+	failed, cached, first := records[0], records[1], records[2]
+	if first.Interface != models.QueryInterfaceMCP || first.Actor != "claude-desktop" || first.Source != "10.0.0.5:52100" || first.Operation != "query_logs" {
+		t.Errorf("Expected the call to be attributed to the client, got %+v", first)
+	}
+	if first.ResultCount != 2 || first.Filter["service_name"] != "checkout" || len(first.MaskedFields) != 1 || first.MaskedFields[0] != "agent_id" {
+		t.Errorf("Expected the filter, result count and masked fields of the session to be recorded, got %+v", first)
+	}
+	if cached.ResultCount != 2 {
+		t.Errorf("Expected a cached result to be counted too, got %d", cached.ResultCount)
+	}
+	if failed.Operation != "get_log_context" || failed.Error == "" || failed.ResultCount != 0 {
+		t.Errorf("Expected the failed call to be recorded with its error, got %+v", failed)
+	}
+}
@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// cacheable is implemented by the parameter structs of tools whose results may be cached.
+// cacheService returns the service the result covers, empty if it covers all services.
+type cacheable interface {
+	cacheService() string
+}
+
+// ResultCache keeps the results of query_logs, get_error_rate and diff_time_windows for a short
+// time, since assistants often repeat the same call several times within one conversation. A
+// result is dropped once entries of its service are stored, making the cache a
+// buffer.CommitListener, so a cached result misses at most the entries stored by other means
+// during its TTL.
+type ResultCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mutex         sync.Mutex
+	entries       map[string]cachedResult
+	generation    uint64 // Incremented by every invalidation, so results computed before one are not cached
+	hits          int64
+	misses        int64
+	invalidations int64
+}
+
+// cachedResult is a tool result kept by the cache
+type cachedResult struct {
+	result  *ToolResult
+	service string // Service the result covers, empty for all services
+	expires time.Time
+}
+
+// ResultCacheStats reports the use of a result cache
+type ResultCacheStats struct {
+	Entries       int    `json:"entries"`
+	MaxEntries    int    `json:"max_entries"`
+	TTL           string `json:"ttl"`
+	Hits          int64  `json:"hits"`
+	Misses        int64  `json:"misses"`
+	Invalidations int64  `json:"invalidations"` // Results dropped because entries of their service were stored
+}
+
+// NewResultCache creates a cache keeping results for ttl, at most maxEntries of them, or nil if
+// ttl is not positive. A nil cache caches nothing.
+func NewResultCache(ttl time.Duration, maxEntries int) *ResultCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	return &ResultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedResult),
+	}
+}
+
+// resultCacheKey returns the cache key of a tool call: the tool name and its decoded arguments
+// with defaults applied, so calls differing only in argument order or in omitting defaults share
+// a result. Time arguments are keyed as given, so a relative start time such as "1h" covers the
+// hour before the call that cached the result.
+func resultCacheKey(tool string, params interface{}) (string, bool) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	return tool + " " + string(data), true
+}
+
+// get returns the cached result of a key and the generation to pass to put when it is missing
+func (c *ResultCache) get(key string) (*ToolResult, uint64, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cached, ok := c.entries[key]
+	if ok && time.Now().Before(cached.expires) {
+		c.hits++
+		return cached.result, c.generation, true
+	}
+	if ok {
+		delete(c.entries, key)
+	}
+	c.misses++
+	return nil, c.generation, false
+}
+
+// put caches the result of a key computed after get returned generation, unless entries were
+// stored since
+func (c *ResultCache) put(key, service string, result *ToolResult, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictLocked(now)
+	}
+	c.entries[key] = cachedResult{result: result, service: service, expires: now.Add(c.ttl)}
+}
+
+// evictLocked drops the expired results, or the one expiring first if none has expired. The
+// caller holds the mutex.
+func (c *ResultCache) evictLocked(now time.Time) {
+	oldest := ""
+	for key, cached := range c.entries {
+		if !now.Before(cached.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == "" || cached.expires.Before(c.entries[oldest].expires) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.maxEntries && oldest != "" {
+		delete(c.entries, oldest)
+	}
+}
+
+// Committed drops the results covering the services of a stored batch, including those covering
+// all services
+func (c *ResultCache) Committed(batch []models.LogEntry) {
+	if c == nil || len(batch) == 0 {
+		return
+	}
+	services := make(map[string]bool)
+	for i := range batch {
+		services[batch[i].ServiceName] = true
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	for key, cached := range c.entries {
+		if cached.service == "" || services[cached.service] {
+			delete(c.entries, key)
+			c.invalidations++
+		}
+	}
+}
+
+// invalidateAll drops all results, such as after a tool changed stored entries
+func (c *ResultCache) invalidateAll() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.invalidations += int64(len(c.entries))
+	c.entries = make(map[string]cachedResult)
+}
+
+// Stats returns the use of the cache
+func (c *ResultCache) Stats() ResultCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return ResultCacheStats{
+		Entries:       len(c.entries),
+		MaxEntries:    c.maxEntries,
+		TTL:           c.ttl.String(),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Invalidations: c.invalidations,
+	}
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// CountingStorage counts the queries reaching the storage
+type CountingStorage struct {
+	MockStorage
+	queries int
+}
+
+func (c *CountingStorage) Query(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
+	c.queries++
+	return c.MockStorage.Query(ctx, filter)
+}
+
+func newCachingServer(t *testing.T) (*Server, *CountingStorage, *ResultCache) {
+	t.Helper()
+	storage := &CountingStorage{MockStorage: MockStorage{logs: []models.LogEntry{
+		{ID: "log-1", Timestamp: time.Now(), Level: models.LogLevelInfo, Message: "started", ServiceName: "api", AgentID: "agent-1", Platform: models.PlatformGo},
+	}}}
+	cache := NewResultCache(time.Minute, 10)
+	return NewServerWithOptions(8081, storage, Options{ResultCache: cache}), storage, cache
+}
+
+func TestResultCache_RepeatedQuery(t *testing.T) {
+	server, storage, cache := newCachingServer(t)
+
+	first, err := server.callTool(context.Background(), "query_logs", map[string]interface{}{"service_name": "api", "limit": float64(10)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	queries := storage.queries
+
+	// Same arguments in a different order
+	second, err := server.callTool(context.Background(), "query_logs", map[string]interface{}{"limit": float64(10), "service_name": "api"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if storage.queries != queries {
+		t.Errorf("Expected the repeated call to be served from the cache, storage queried %d more times", storage.queries-queries)
+	}
+	if second.Content[0].Text != first.Content[0].Text {
+		t.Error("Expected the cached result to match the first one")
+	}
+
+	if _, err := server.callTool(context.Background(), "query_logs", map[string]interface{}{"service_name": "api", "limit": float64(5)}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if storage.queries == queries {
+		t.Error("Expected different arguments to query the storage")
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Expected 1 hit, 2 misses and 2 entries, got %+v", stats)
+	}
+}
+
+func TestResultCache_Committed(t *testing.T) {
+	server, storage, cache := newCachingServer(t)
+	call := func(arguments map[string]interface{}) {
+		t.Helper()
+		if _, err := server.callTool(context.Background(), "query_logs", arguments); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	call(map[string]interface{}{"service_name": "api"})
+	call(map[string]interface{}{"service_name": "worker"})
+	call(map[string]interface{}{})
+	if stats := cache.Stats(); stats.Entries != 3 {
+		t.Fatalf("Expected 3 cached results, got %d", stats.Entries)
+	}
+
+	cache.Committed([]models.LogEntry{{ServiceName: "worker"}})
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Invalidations != 2 {
+		t.Errorf("Expected the worker and all-service results dropped, got %+v", stats)
+	}
+
+	queries := storage.queries
+	call(map[string]interface{}{"service_name": "api"})
+	if storage.queries != queries {
+		t.Error("Expected the result of another service to stay cached")
+	}
+	call(map[string]interface{}{"service_name": "worker"})
+	if storage.queries == queries {
+		t.Error("Expected the dropped result to query the storage again")
+	}
+}
+
+func TestResultCache_Expiry(t *testing.T) {
+	cache := NewResultCache(time.Millisecond, 1)
+	result := &ToolResult{Content: []ContentBlock{{Type: "text", Text: "{}"}}}
+
+	_, generation, _ := cache.get("a")
+	cache.put("a", "api", result, generation)
+	_, generation, _ = cache.get("b")
+	cache.put("b", "api", result, generation)
+	if stats := cache.Stats(); stats.Entries != 1 {
+		t.Errorf("Expected at most 1 cached result, got %d", stats.Entries)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if _, _, ok := cache.get("b"); ok {
+		t.Error("Expected the expired result to miss")
+	}
+
+	// A result computed while entries were stored is not cached
+	_, generation, _ = cache.get("c")
+	cache.Committed([]models.LogEntry{{ServiceName: "api"}})
+	cache.put("c", "api", result, generation)
+	if _, _, ok := cache.get("c"); ok {
+		t.Error("Expected a result computed before a commit not to be cached")
+	}
+
+	if NewResultCache(0, 10) != nil {
+		t.Error("Expected a zero TTL to disable the cache")
+	}
+}
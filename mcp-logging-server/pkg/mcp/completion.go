@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// maxCompletionValues is the number of values a completion returns at most, as set by MCP
+const maxCompletionValues = 100
+
+// completionRefTool references the tool whose argument is completed. MCP only defines prompt
+// and resource references; this server has neither, so it completes tool arguments instead.
+const completionRefTool = "ref/tool"
+
+// completeParams are the params of a completion/complete request
+type completeParams struct {
+	Ref struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"ref"`
+	Argument struct {
+		Name  string `json:"name" validate:"required"`
+		Value string `json:"value"`
+	} `json:"argument"`
+	Context struct {
+		Arguments map[string]string `json:"arguments"` // Arguments the client already resolved
+	} `json:"context"`
+}
+
+// completion is the result of a completion/complete request
+type completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total"`
+	HasMore bool     `json:"hasMore"`
+}
+
+// handleComplete handles the MCP completion/complete request, suggesting the services, agents
+// and platforms that have logged entries for the service_name, agent_id and platform arguments.
+// Other arguments have no suggestions.
+func (s *Server) handleComplete(ctx context.Context, msg *MCPMessage) *MCPMessage {
+	var params completeParams
+	err := decodeArguments(msg.Params, &params)
+	if err == nil {
+		err = s.checkCompletionRef(params)
+	}
+	if err != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	values, err := s.completeArgument(ctx, params.Argument.Name, params.Context.Arguments)
+	if err != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &MCPError{
+				Code:    -32603,
+				Message: err.Error(),
+			},
+		}
+	}
+
+	result := completion{Values: []string{}}
+	prefix := strings.ToLower(params.Argument.Value)
+	for _, value := range values {
+		if strings.HasPrefix(strings.ToLower(value), prefix) {
+			result.Values = append(result.Values, value)
+		}
+	}
+	result.Total = len(result.Values)
+	if len(result.Values) > maxCompletionValues {
+		result.Values = result.Values[:maxCompletionValues]
+		result.HasMore = true
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Result: map[string]interface{}{
+			"completion": result,
+		},
+	}
+}
+
+// checkCompletionRef checks that a referenced tool exists and accepts the completed argument
+func (s *Server) checkCompletionRef(params completeParams) error {
+	switch params.Ref.Type {
+	case "":
+		return nil
+	case completionRefTool:
+	default:
+		return fmt.Errorf("unsupported reference type %q, only %s is supported", params.Ref.Type, completionRefTool)
+	}
+
+	tool, ok := s.tools.get(params.Ref.Name)
+	if !ok {
+		return fmt.Errorf("tool %s not found", params.Ref.Name)
+	}
+	if !s.tools.enabled(tool.Name) {
+		return fmt.Errorf("tool %s is disabled", tool.Name)
+	}
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+	if _, ok := properties[params.Argument.Name]; !ok {
+		return fmt.Errorf("tool %s has no argument %s", tool.Name, params.Argument.Name)
+	}
+	return nil
+}
+
+// completeArgument returns the sorted values of an argument. Services and agents are narrowed to
+// those matching the service_name, agent_id and platform the client already resolved.
+func (s *Server) completeArgument(ctx context.Context, argument string, resolved map[string]string) ([]string, error) {
+	var value func(models.ServiceInfo) string
+	switch argument {
+	case "service_name":
+		value = func(service models.ServiceInfo) string { return service.ServiceName }
+	case "agent_id":
+		value = func(service models.ServiceInfo) string { return service.AgentID }
+	case "platform":
+		// Ingestion rejects other platforms, so entries cannot have them
+		if len(s.options.Platforms) > 0 {
+			return sortedValues(s.options.Platforms), nil
+		}
+		value = func(service models.ServiceInfo) string { return string(service.Platform) }
+	default:
+		return nil, nil
+	}
+
+	services, err := s.storage.GetServices(ctx, models.ServiceFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get services: %w", err)
+	}
+
+	var values []string
+	if argument == "platform" {
+		for _, platform := range models.DefaultPlatforms() {
+			values = append(values, string(platform))
+		}
+	}
+	for _, service := range services.Services {
+		if !matchesResolved(service, resolved, argument) {
+			continue
+		}
+		values = append(values, value(service))
+	}
+	return sortedValues(values), nil
+}
+
+// matchesResolved reports whether a service matches the resolved arguments other than the one
+// being completed
+func matchesResolved(service models.ServiceInfo, resolved map[string]string, argument string) bool {
+	fields := map[string]string{
+		"service_name": service.ServiceName,
+		"agent_id":     service.AgentID,
+		"platform":     string(service.Platform),
+	}
+	for name, field := range fields {
+		if want := resolved[name]; name != argument && want != "" && want != field {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedValues returns the distinct non-empty values, sorted
+func sortedValues(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	distinct := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct
+}
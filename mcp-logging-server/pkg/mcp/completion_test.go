@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestHandleComplete(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	newLog := func(service, agent string, platform models.Platform) models.LogEntry {
+		return models.LogEntry{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelInfo, Message: "started", ServiceName: service, AgentID: agent, Platform: platform}
+	}
+	err := memoryStorage.Store(ctx, []models.LogEntry{
+		newLog("checkout", "checkout-1", models.PlatformGo),
+		newLog("checkout", "checkout-2", models.PlatformGo),
+		newLog("Cart", "cart-1", models.PlatformExpress),
+		newLog("payments", "payments-1", models.PlatformKotlin),
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	complete := func(params map[string]interface{}) (completion, *MCPError) {
+		t.Helper()
+		response := server.handleMessage(ctx, &MCPMessage{JSONRPC: "2.0", ID: "complete", Method: "completion/complete", Params: params})
+		if response.Error != nil {
+			return completion{}, response.Error
+		}
+		return response.Result.(map[string]interface{})["completion"].(completion), nil
+	}
+
+	tests := []struct {
+		name   string
+		params map[string]interface{}
+		want   []string
+	}{
+		{
+			name:   "services by case-insensitive prefix",
+			params: map[string]interface{}{"argument": map[string]interface{}{"name": "service_name", "value": "c"}},
+			want:   []string{"Cart", "checkout"},
+		},
+		{
+			name: "agents of the resolved service",
+			params: map[string]interface{}{
+				"ref":      map[string]interface{}{"type": "ref/tool", "name": "query_logs"},
+				"argument": map[string]interface{}{"name": "agent_id", "value": ""},
+				"context":  map[string]interface{}{"arguments": map[string]interface{}{"service_name": "checkout"}},
+			},
+			want: []string{"checkout-1", "checkout-2"},
+		},
+		{
+			name:   "platforms",
+			params: map[string]interface{}{"argument": map[string]interface{}{"name": "platform", "value": "re"}},
+			want:   []string{"react", "react-native"},
+		},
+		{
+			name:   "other arguments",
+			params: map[string]interface{}{"argument": map[string]interface{}{"name": "level", "value": "E"}},
+			want:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, mcpErr := complete(tt.params)
+			if mcpErr != nil {
+				t.Fatalf("completion/complete failed: %s", mcpErr.Message)
+			}
+			if !reflect.DeepEqual(result.Values, tt.want) || result.Total != len(tt.want) || result.HasMore {
+				t.Errorf("Expected %v, got %+v", tt.want, result)
+			}
+		})
+	}
+
+	invalid := []map[string]interface{}{
+		{},
+		{"ref": map[string]interface{}{"type": "ref/prompt", "name": "triage"}, "argument": map[string]interface{}{"name": "service_name"}},
+		{"ref": map[string]interface{}{"type": "ref/tool", "name": "unknown"}, "argument": map[string]interface{}{"name": "service_name"}},
+		{"ref": map[string]interface{}{"type": "ref/tool", "name": "get_usage"}, "argument": map[string]interface{}{"name": "platform"}},
+	}
+	for _, params := range invalid {
+		if _, mcpErr := complete(params); mcpErr == nil || mcpErr.Code != -32602 {
+			t.Errorf("Expected invalid params for %v, got %v", params, mcpErr)
+		}
+	}
+
+	// Configured platforms replace the known ones
+	restricted := NewServerWithOptions(8081, memoryStorage, Options{Platforms: []string{"swift", "go"}})
+	values, err := restricted.completeArgument(ctx, "platform", nil)
+	if err != nil || !reflect.DeepEqual(values, []string{"go", "swift"}) {
+		t.Errorf("Expected the configured platforms, got %v (%v)", values, err)
+	}
+}
@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultMaxPendingResponses is the number of responses a client may leave unread by default
+const DefaultMaxPendingResponses = 32
+
+// errResponseQueueFull is returned when a client has more responses waiting than it may
+var errResponseQueueFull = errors.New("too many responses waiting to be read")
+
+// responseWriter writes the responses of a connection from its own goroutine, so a client that
+// stops reading blocks the writer rather than the handler. Responses wait in a bounded queue;
+// a client that lets it fill up, or does not read a response within the write timeout, is too
+// slow and disconnected.
+type responseWriter struct {
+	conn    net.Conn
+	timeout time.Duration // Deadline for writing one response, 0 waits indefinitely
+	queue   chan *MCPMessage
+	done    chan struct{} // Closed once the writer stopped
+	onSlow  func(error)   // Called once if the client is disconnected for being too slow
+
+	once sync.Once
+	err  error // Why the writer stopped early, set before done is closed
+}
+
+// newResponseWriter starts writing responses to conn, keeping at most maxPending waiting
+func newResponseWriter(conn net.Conn, timeout time.Duration, maxPending int, onSlow func(error)) *responseWriter {
+	if maxPending < 1 {
+		maxPending = 1
+	}
+	w := &responseWriter{
+		conn:    conn,
+		timeout: timeout,
+		queue:   make(chan *MCPMessage, maxPending),
+		done:    make(chan struct{}),
+		onSlow:  onSlow,
+	}
+	go w.run()
+	return w
+}
+
+// run writes queued responses until the queue is closed or a write fails
+func (w *responseWriter) run() {
+	defer close(w.done)
+
+	encoder := json.NewEncoder(w.conn)
+	for response := range w.queue {
+		if w.timeout > 0 {
+			w.conn.SetWriteDeadline(time.Now().Add(w.timeout))
+		}
+		if err := encoder.Encode(response); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = fmt.Errorf("response not read within %s", w.timeout)
+				w.fail(err, true)
+			} else {
+				w.fail(fmt.Errorf("failed to encode response: %w", err), false)
+			}
+			return
+		}
+	}
+}
+
+// fail stops the connection, closing it so the handler's pending read returns
+func (w *responseWriter) fail(err error, slow bool) {
+	w.once.Do(func() {
+		w.err = err
+		if slow && w.onSlow != nil {
+			w.onSlow(err)
+		}
+		w.conn.Close()
+	})
+}
+
+// send queues a response without blocking. It fails if the writer stopped or the queue is
+// full, in which case the client is disconnected.
+func (w *responseWriter) send(response *MCPMessage) error {
+	select {
+	case <-w.done:
+		return w.err
+	default:
+	}
+
+	select {
+	case w.queue <- response:
+		return nil
+	default:
+		w.fail(errResponseQueueFull, true)
+		return errResponseQueueFull
+	}
+}
+
+// close writes the queued responses and stops the writer, waiting at most one write timeout
+// per response
+func (w *responseWriter) close() {
+	close(w.queue)
+	<-w.done
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
+)
+
+// serveConnection handles one end of a pipe in the background and returns the other end and a
+// channel closed once the handler returned
+func serveConnection(t *testing.T, server *Server) (net.Conn, <-chan struct{}) {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.handleConnection(context.Background(), serverConn)
+	}()
+	t.Cleanup(func() { clientConn.Close() })
+	return clientConn, done
+}
+
+// sendRequests writes tools/list requests to conn in the background until it is closed
+func sendRequests(conn net.Conn, count int) {
+	go func() {
+		encoder := json.NewEncoder(conn)
+		for i := 0; i < count; i++ {
+			if err := encoder.Encode(MCPMessage{JSONRPC: "2.0", ID: i, Method: "tools/list"}); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func waitForDisconnect(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the slow client to be disconnected")
+	}
+}
+
+func TestHandleConnection_Responses(t *testing.T) {
+	conn, _ := serveConnection(t, NewServerWithOptions(8081, &MockStorage{}, Options{WriteTimeout: time.Second}))
+	sendRequests(conn, 3)
+
+	decoder := json.NewDecoder(conn)
+	for i := 0; i < 3; i++ {
+		var response MCPMessage
+		if err := decoder.Decode(&response); err != nil {
+			t.Fatalf("Failed to read response %d: %v", i, err)
+		}
+		if response.ID != float64(i) || response.Error != nil {
+			t.Errorf("Expected a result for request %d, got %+v", i, response)
+		}
+	}
+}
+
+func TestHandleConnection_WriteTimeout(t *testing.T) {
+	metricsReporter := metrics.NewMetrics()
+	conn, done := serveConnection(t, NewServerWithOptions(8081, &MockStorage{}, Options{
+		WriteTimeout: 50 * time.Millisecond,
+		Metrics:      metricsReporter,
+	}))
+
+	// The response is never read
+	sendRequests(conn, 1)
+	waitForDisconnect(t, done)
+
+	if slow := metricsReporter.GetSnapshot().SlowConsumers; slow != 1 {
+		t.Errorf("Expected 1 slow consumer, got %d", slow)
+	}
+}
+
+func TestHandleConnection_PendingResponses(t *testing.T) {
+	metricsReporter := metrics.NewMetrics()
+	conn, done := serveConnection(t, NewServerWithOptions(8081, &MockStorage{}, Options{
+		MaxPendingResponses: 2,
+		Metrics:             metricsReporter,
+	}))
+
+	// Without a write timeout the first response blocks the writer, two more fill the queue
+	// and the fourth disconnects the client
+	sendRequests(conn, 10)
+	waitForDisconnect(t, done)
+
+	if slow := metricsReporter.GetSnapshot().SlowConsumers; slow != 1 {
+		t.Errorf("Expected 1 slow consumer, got %d", slow)
+	}
+}
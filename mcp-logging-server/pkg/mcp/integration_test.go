@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
 )
 
 // IntegrationTestStorage implements a more realistic storage for integration tests
@@ -105,6 +106,10 @@ func (its *IntegrationTestStorage) Query(ctx context.Context, filter models.LogF
 	}, nil
 }
 
+func (its *IntegrationTestStorage) QueryStream(ctx context.Context, filter models.LogFilter) (storage.LogIterator, error) {
+	return storage.NewSliceIterator(its.logs), nil
+}
+
 func (its *IntegrationTestStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
 	var result []models.LogEntry
 	for _, log := range its.logs {
@@ -118,8 +123,8 @@ func (its *IntegrationTestStorage) GetByIDs(ctx context.Context, ids []string) (
 	return result, nil
 }
 
-func (its *IntegrationTestStorage) GetServices(ctx context.Context) ([]models.ServiceInfo, error) {
-	return its.services, nil
+func (its *IntegrationTestStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	return &models.ServiceResult{Services: its.services, TotalCount: len(its.services)}, nil
 }
 
 func (its *IntegrationTestStorage) HealthCheck(ctx context.Context) models.HealthStatus {
@@ -226,10 +231,18 @@ func TestMCPServerIntegration(t *testing.T) {
 		result := toolsResponse.Result.(map[string]interface{})
 		tools := result["tools"].([]Tool)
 		expectedTools := map[string]bool{
-			"query_logs":         false,
-			"get_log_details":    false,
-			"get_service_status": false,
-			"list_services":      false,
+			"query_logs":          false,
+			"search_logs":         false,
+			"get_log_details":     false,
+			"get_service_status":  false,
+			"list_services":       false,
+			"query_crashes":       false,
+			"get_usage":           false,
+			"get_error_rate":      false,
+			"query_log_summaries": false,
+			"annotate_log":        false,
+			"get_incident_logs":   false,
+			"set_context":         false,
 		}
 
 		for _, tool := range tools {
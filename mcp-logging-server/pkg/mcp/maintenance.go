@@ -0,0 +1,42 @@
+package mcp
+
+import (
+	"fmt"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+)
+
+// errorCodeUnavailable is the JSON-RPC error code of tool calls rejected by the mode of the server
+const errorCodeUnavailable = -32002
+
+// writingTools are the tools changing stored data, which read-only mode rejects
+var writingTools = map[string]bool{"annotate_log": true}
+
+// rejectedByMode returns the error of a tool call the current mode does not serve, nil if it is
+// served: maintenance mode rejects every call and read-only mode calls of writingTools
+func (s *Server) rejectedByMode(toolName string) *MCPError {
+	if s.options.Maintenance == nil {
+		return nil
+	}
+
+	state := s.options.Maintenance.State()
+	switch {
+	case state.Mode == maintenance.ModeMaintenance:
+	case state.Mode == maintenance.ModeReadOnly && writingTools[toolName]:
+	default:
+		return nil
+	}
+
+	data := map[string]interface{}{
+		"tool": toolName,
+		"mode": state.Mode,
+	}
+	if state.Reason != "" {
+		data["reason"] = state.Reason
+	}
+	return &MCPError{
+		Code:    errorCodeUnavailable,
+		Message: fmt.Sprintf("Server is in %s mode", state.Mode),
+		Data:    data,
+	}
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestHandleToolCall_Mode(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	modeSwitch := maintenance.New()
+	server := NewServerWithOptions(8081, memoryStorage, Options{Maintenance: modeSwitch})
+	ctx := context.Background()
+
+	call := func(tool string, arguments map[string]interface{}) *MCPMessage {
+		t.Helper()
+		return server.handleToolCall(ctx, &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      tool,
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": tool, "arguments": arguments},
+		})
+	}
+	annotate := map[string]interface{}{"id": "missing", "text": "known issue"}
+
+	// Read-only mode rejects annotations, queries keep working
+	modeSwitch.Set(maintenance.ModeReadOnly, "storage migration")
+	if response := call("query_logs", map[string]interface{}{}); response.Error != nil {
+		t.Errorf("Expected queries to be served in read-only mode, got %+v", response.Error)
+	}
+	response := call("annotate_log", annotate)
+	if response.Error == nil || response.Error.Code != errorCodeUnavailable {
+		t.Fatalf("Expected annotate_log to be rejected in read-only mode, got %+v", response)
+	}
+	if data := response.Error.Data.(map[string]interface{}); data["mode"] != maintenance.ModeReadOnly || data["reason"] != "storage migration" {
+		t.Errorf("Expected the mode and reason in the error, got %v", data)
+	}
+
+	// Maintenance mode rejects every call
+	modeSwitch.Set(maintenance.ModeMaintenance, "")
+	if response := call("query_logs", map[string]interface{}{}); response.Error == nil || response.Error.Code != errorCodeUnavailable {
+		t.Errorf("Expected queries to be rejected in maintenance mode, got %+v", response)
+	}
+
+	modeSwitch.Set(maintenance.ModeNormal, "")
+	if response := call("annotate_log", annotate); response.Error != nil && response.Error.Code == errorCodeUnavailable {
+		t.Errorf("Expected annotate_log to be served in normal mode, got %+v", response.Error)
+	}
+}
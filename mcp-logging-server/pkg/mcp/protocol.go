@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+)
+
+// MCP protocol versions, named by their release date so that later versions sort after earlier
+// ones as strings
+const (
+	protocolVersion20241105 = "2024-11-05"
+	protocolVersion20250326 = "2025-03-26" // Adds completions and tool annotations
+	protocolVersion20250618 = "2025-06-18"
+)
+
+// supportedProtocolVersions are the MCP protocol versions this server speaks, newest first. A
+// version is only removed in a major release, so that clients keep working across rolling
+// upgrades of the servers they connect to.
+var supportedProtocolVersions = []string{protocolVersion20250618, protocolVersion20250326, protocolVersion20241105}
+
+// Protocol versions introducing the features served only to clients that negotiated them
+const (
+	completionsSince     = protocolVersion20250326
+	toolAnnotationsSince = protocolVersion20250326
+)
+
+// negotiateProtocolVersion picks the protocol version of a session from the protocolVersion of
+// an initialize request. Clients send the version they prefer, or a list of versions in order of
+// preference, and get the first one this server supports. When none is supported the newest
+// supported version is returned, for the client to decide whether it can use it, as MCP
+// specifies. Clients predating version negotiation send none and get the oldest version.
+func negotiateProtocolVersion(offered interface{}) (string, error) {
+	var versions []string
+	switch value := offered.(type) {
+	case nil:
+		return protocolVersion20241105, nil
+	case string:
+		versions = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			version, ok := item.(string)
+			if !ok {
+				return "", fmt.Errorf("protocolVersion must be a string or a list of strings")
+			}
+			versions = append(versions, version)
+		}
+	default:
+		return "", fmt.Errorf("protocolVersion must be a string or a list of strings")
+	}
+
+	for _, version := range versions {
+		for _, supported := range supportedProtocolVersions {
+			if version == supported {
+				return version, nil
+			}
+		}
+	}
+	return supportedProtocolVersions[0], nil
+}
+
+// supportsProtocol reports whether the session of ctx negotiated a protocol version at least as
+// new as since. Calls outside of a session, or before initialize, get every feature.
+func supportsProtocol(ctx context.Context, since string) bool {
+	sess := sessionFromContext(ctx)
+	if sess == nil {
+		return true
+	}
+	version := sess.getProtocolVersion()
+	return version == "" || version >= since
+}
+
+// ToolAnnotations describe how a tool behaves, so that clients can decide which calls need
+// confirmation. They are listed to clients that negotiated toolAnnotationsSince.
+type ToolAnnotations struct {
+	ReadOnlyHint    bool `json:"readOnlyHint"`    // The tool does not change stored data
+	DestructiveHint bool `json:"destructiveHint"` // Changes may overwrite or delete data, meaningful for tools that are not read-only
+	OpenWorldHint   bool `json:"openWorldHint"`   // The tool reaches outside the server, none of these tools do
+}
+
+// toolAnnotations returns the annotations of a tool. Tools changing stored data, such as
+// annotate_log, only ever add to it.
+func toolAnnotations(name string) *ToolAnnotations {
+	return &ToolAnnotations{ReadOnlyHint: !writingTools[name]}
+}
@@ -0,0 +1,113 @@
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		offered interface{}
+		want    string
+		wantErr bool
+	}{
+		{"no version", nil, "2024-11-05", false},
+		{"supported version", "2025-03-26", "2025-03-26", false},
+		{"unknown version", "2099-01-01", supportedProtocolVersions[0], false},
+		{"first supported of a list", []interface{}{"2099-01-01", "2025-03-26", "2024-11-05"}, "2025-03-26", false},
+		{"list without a supported version", []interface{}{"2099-01-01"}, supportedProtocolVersions[0], false},
+		{"invalid list", []interface{}{"2025-03-26", 3}, "", true},
+		{"invalid type", 20241105, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := negotiateProtocolVersion(tt.offered)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestProtocolVersionFeatures(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+	server := NewServer(8081, memoryStorage)
+
+	// initialize negotiates the version of the connection and lists the capabilities it offers
+	connect := func(offered interface{}) (context.Context, map[string]interface{}) {
+		t.Helper()
+		ctx := withSession(context.Background(), &session{})
+		params := map[string]interface{}{}
+		if offered != nil {
+			params["protocolVersion"] = offered
+		}
+		response := server.handleMessage(ctx, &MCPMessage{JSONRPC: "2.0", ID: "init", Method: "initialize", Params: params})
+		if response.Error != nil {
+			t.Fatalf("initialize failed: %v", response.Error.Message)
+		}
+		return ctx, response.Result.(map[string]interface{})
+	}
+	annotations := func(ctx context.Context) map[string]*ToolAnnotations {
+		t.Helper()
+		tools := server.handleMessage(ctx, &MCPMessage{JSONRPC: "2.0", ID: "list", Method: "tools/list"}).Result.(map[string]interface{})["tools"].([]Tool)
+		byName := make(map[string]*ToolAnnotations, len(tools))
+		for _, tool := range tools {
+			byName[tool.Name] = tool.Annotations
+		}
+		return byName
+	}
+	complete := func(ctx context.Context) *MCPError {
+		return server.handleMessage(ctx, &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      "complete",
+			Method:  "completion/complete",
+			Params:  map[string]interface{}{"argument": map[string]interface{}{"name": "service_name", "value": ""}},
+		}).Error
+	}
+
+	// Clients of the first protocol version keep the features it had
+	ctx, result := connect("2024-11-05")
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("Expected protocol version 2024-11-05, got %v", result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]interface{})["completions"]; ok {
+		t.Error("Expected no completions capability for 2024-11-05")
+	}
+	if err := complete(ctx); err == nil || err.Code != -32601 {
+		t.Errorf("Expected completions to be unavailable for 2024-11-05, got %+v", err)
+	}
+	if listed := annotations(ctx); listed["query_logs"] != nil {
+		t.Errorf("Expected no tool annotations for 2024-11-05, got %+v", listed["query_logs"])
+	}
+
+	// Newer clients offering a list get the first version supported and its features
+	ctx, result = connect([]interface{}{"2099-01-01", "2025-03-26"})
+	if result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("Expected protocol version 2025-03-26, got %v", result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]interface{})["completions"]; !ok {
+		t.Error("Expected the completions capability for 2025-03-26")
+	}
+	if err := complete(ctx); err != nil {
+		t.Errorf("Expected completions for 2025-03-26, got %+v", err)
+	}
+	listed := annotations(ctx)
+	if query := listed["query_logs"]; query == nil || !query.ReadOnlyHint || query.OpenWorldHint {
+		t.Errorf("Expected query_logs to be annotated read-only, got %+v", query)
+	}
+	if annotate := listed["annotate_log"]; annotate == nil || annotate.ReadOnlyHint || annotate.DestructiveHint {
+		t.Errorf("Expected annotate_log to be annotated as writing without destroying, got %+v", annotate)
+	}
+
+	if response := server.handleMessage(context.Background(), &MCPMessage{JSONRPC: "2.0", ID: "init", Method: "initialize", Params: map[string]interface{}{"protocolVersion": true}}); response.Error == nil || response.Error.Code != -32602 {
+		t.Errorf("Expected an invalid protocolVersion to be rejected, got %+v", response)
+	}
+}
@@ -3,14 +3,25 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/queryaudit"
 	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	"github.com/kerlexov/mcp-logging-server/pkg/symbolication"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
 )
 
 // MCPMessage represents a generic MCP message
@@ -32,9 +43,10 @@ type MCPError struct {
 
 // Tool represents an MCP tool definition
 type Tool struct {
-	Name        string      `json:"name"`
-	Description string      `json:"description"`
-	InputSchema interface{} `json:"inputSchema"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	InputSchema interface{}      `json:"inputSchema"`
+	Annotations *ToolAnnotations `json:"annotations,omitempty"` // Set by tools/list for clients that negotiated them
 }
 
 // ToolCallParams represents parameters for a tool call
@@ -47,6 +59,8 @@ type ToolCallParams struct {
 type ToolResult struct {
 	Content []ContentBlock `json:"content"`
 	IsError bool           `json:"isError,omitempty"`
+
+	resultCount int // Log entries in the result, for the query audit of cached results
 }
 
 // ContentBlock represents a content block in MCP responses
@@ -55,20 +69,92 @@ type ContentBlock struct {
 	Text string `json:"text"`
 }
 
+const (
+	// defaultErrorRateWindow is the window get_error_rate compares when none is given
+	defaultErrorRateWindow = 24 * time.Hour
+
+	// errorRateTrendThreshold is the change in error rate below which get_error_rate reports a stable trend
+	errorRateTrendThreshold = 0.001
+)
+
+// JSON-RPC error codes for tool calls that did not complete
+const (
+	errorCodeTimeout   = -32001
+	errorCodeCancelled = -32800
+)
+
+// Options contains optional MCP server configuration
+type Options struct {
+	DefaultTimeout     time.Duration            // Deadline for tool calls without a per-tool timeout, 0 disables
+	ToolTimeouts       map[string]time.Duration // Per-tool deadlines keyed by tool name
+	SlowQueryThreshold time.Duration            // Tool calls slower than this are logged with their arguments, 0 disables
+	Host               string                   // Address the server listens on, empty for every interface
+	Masking            *dataprotection.Masker   // Masking of mask_fields, nil uses DefaultMasker
+	Platforms          []string                 // Platforms accepted by ingestion, platform arguments must be one of them; empty accepts any
+	Retention          storage.RetentionPolicy  // Queries reaching further back than the retention of a level are answered with a warning
+	CompactionAfter    time.Duration            // Age at which entries of CompactionLevels are replaced by summaries, 0 disables
+	CompactionLevels   []models.LogLevel
+	ServiceStaleAfter  time.Duration // list_services flags agents not seen for this long as stale, 0 disables
+	ServiceHideAfter   time.Duration // list_services leaves out agents not seen for this long unless include_hidden is set, 0 disables
+	ResultCache        *ResultCache  // Keeps the results of repeated queries, nil caches nothing
+
+	// WriteTimeout is the deadline for a client to read a response before it is disconnected,
+	// 0 waits indefinitely
+	WriteTimeout time.Duration
+	// MaxPendingResponses is the number of responses a client may leave unread before it is
+	// disconnected, 0 uses DefaultMaxPendingResponses
+	MaxPendingResponses int
+	// Metrics counts clients disconnected for being too slow, nil counts nothing
+	Metrics *metrics.Metrics
+	// QueryAudit records every tool call with the client that made it, nil records nothing
+	QueryAudit *queryaudit.Recorder
+	// Maintenance is the mode switched at the ingestion server's /admin/mode, nil serves every call
+	Maintenance *maintenance.Switch
+	// Tools enables and disables tools, switched at the ingestion server's /admin/tools. Clients
+	// are notified when the enabled tools change. Nil enables every tool.
+	Tools *toolswitch.Switch
+}
+
+// logLevels are the levels of log entries, least severe first
+var logLevels = []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn, models.LogLevelError, models.LogLevelFatal}
+
+// DefaultMasker masks mask_fields with [MASKED], showing the first and last 2 characters of values longer than 4
+var DefaultMasker = dataprotection.TokenMasker("[MASKED]")
+
 // Server represents the MCP server
 type Server struct {
-	port    int
-	storage storage.LogStorage
-	tools   map[string]Tool
+	port        int
+	storage     storage.LogStorage
+	tools       *toolRegistry
+	options     Options
+	masker      dataprotection.Masker
+	stackTraces *symbolication.Symbolicator // Nil if the storage does not keep symbol files
+
+	connectionsMutex sync.Mutex
+	connections      map[*session]*responseWriter // Open connections, notified when the tools change
 }
 
 // NewServer creates a new MCP server
 func NewServer(port int, storage storage.LogStorage) *Server {
+	return NewServerWithOptions(port, storage, Options{})
+}
+
+// NewServerWithOptions creates a new MCP server with optional configuration
+func NewServerWithOptions(port int, storage storage.LogStorage, options Options) *Server {
 	s := &Server{
-		port:    port,
-		storage: storage,
-		tools:   make(map[string]Tool),
+		port:        port,
+		storage:     storage,
+		tools:       newToolRegistry(options.Tools),
+		options:     options,
+		masker:      DefaultMasker,
+		connections: make(map[*session]*responseWriter),
 	}
+	if options.Masking != nil {
+		s.masker = *options.Masking
+	}
+
+	// Symbol files uploaded after an entry was stored still apply when it is read
+	s.stackTraces = symbolication.ForStorage(storage)
 
 	// Register available tools
 	s.registerTools()
@@ -79,7 +165,7 @@ func NewServer(port int, storage storage.LogStorage) *Server {
 // registerTools registers all available MCP tools
 func (s *Server) registerTools() {
 	// query_logs tool
-	s.tools["query_logs"] = Tool{
+	registerTool(s, Tool{
 		Name:        "query_logs",
 		Description: "Query logs with filtering options and pagination support",
 		InputSchema: map[string]interface{}{
@@ -93,20 +179,38 @@ func (s *Server) registerTools() {
 					"type":        "string",
 					"description": "Filter by agent ID",
 				},
+				"request_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by the ID of the request the entries were logged for, to follow one request across services",
+				},
+				"template_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by message template, the template_id of a message group, to list the entries of the group",
+				},
 				"level": map[string]interface{}{
 					"type":        "string",
 					"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"},
 					"description": "Filter by log level",
 				},
+				"min_level": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"},
+					"description": "Filter by minimum log level, e.g. WARN for warnings, errors and fatal errors",
+				},
 				"start_time": map[string]interface{}{
 					"type":        "string",
-					"format":      "date-time",
-					"description": "Start time for log query (RFC3339 format)",
+					"description": "Start time for log query: " + timeFormats,
 				},
 				"end_time": map[string]interface{}{
 					"type":        "string",
-					"format":      "date-time",
-					"description": "End time for log query (RFC3339 format)",
+					"description": "End time for log query: " + timeFormats,
+				},
+				"time_zone": timeZoneProperty,
+				"time_field": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"timestamp", "received_at"},
+					"default":     "timestamp",
+					"description": "Which time the start_time/end_time range applies to: client timestamp or server receive time",
 				},
 				"message_contains": map[string]interface{}{
 					"type":        "string",
@@ -114,8 +218,17 @@ func (s *Server) registerTools() {
 				},
 				"platform": map[string]interface{}{
 					"type":        "string",
-					"enum":        []string{"go", "swift", "express", "react", "react-native", "kotlin"},
-					"description": "Filter by platform",
+					"description": "Filter by platform (e.g. go, swift, express, react, react-native, kotlin)",
+				},
+				"tags_any": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Filter logs having at least one of these tags",
+				},
+				"tags_all": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Filter logs having all of these tags",
 				},
 				"limit": map[string]interface{}{
 					"type":        "integer",
@@ -135,14 +248,130 @@ func (s *Server) registerTools() {
 					"items":       map[string]interface{}{"type": "string"},
 					"description": "Array of field names to mask for sensitive data protection (e.g., ['message', 'agent_id', 'custom_field'])",
 				},
+				"facets": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": models.SearchFacetNames()},
+					"description": "Count matching logs by these fields, only computed when message_contains is answered by the full-text search index",
+				},
+				"group_by": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"message_template"},
+					"description": "Group matching logs by message with numbers, UUIDs, hex values and quoted strings stripped, returning one representative entry per group with its count. limit and offset then apply to groups.",
+				},
 			},
 		},
-	}
+	}, s.handleQueryLogs)
+
+	// search_logs tool
+	registerTool(s, Tool{
+		Name:        "search_logs",
+		Description: "Full-text search of log messages and stack traces, returning highlighted fragments and match positions that show why each entry matched",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Text to search for in messages and stack traces",
+				},
+				"fuzziness": map[string]interface{}{
+					"type":        "integer",
+					"default":     0,
+					"minimum":     0,
+					"maximum":     models.MaxSearchFuzziness,
+					"description": "Number of typos tolerated per term, for when the exact wording is unknown",
+				},
+				"prefix": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Also match words starting with the query terms (e.g. 'conn' matches 'connection')",
+				},
+				"sort": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{string(models.SearchSortTime), string(models.SearchSortRelevance)},
+					"default":     string(models.SearchSortTime),
+					"description": "Order hits newest first (time) or by how well they match with a boost for recent entries (relevance), e.g. to find the most relevant recent error",
+				},
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by service name",
+				},
+				"agent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by agent ID",
+				},
+				"request_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by the ID of the request the entries were logged for, to follow one request across services",
+				},
+				"template_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by message template, the template_id of a message group, to list the entries of the group",
+				},
+				"level": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"},
+					"description": "Filter by log level",
+				},
+				"min_level": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"},
+					"description": "Filter by minimum log level, e.g. WARN for warnings, errors and fatal errors",
+				},
+				"platform": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by platform (e.g. go, swift, express, react, react-native, kotlin)",
+				},
+				"start_time": map[string]interface{}{
+					"type":        "string",
+					"description": "Start time for log search: " + timeFormats,
+				},
+				"end_time": map[string]interface{}{
+					"type":        "string",
+					"description": "End time for log search: " + timeFormats,
+				},
+				"time_zone": timeZoneProperty,
+				"tags_any": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Filter logs having at least one of these tags",
+				},
+				"tags_all": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Filter logs having all of these tags",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     100,
+					"minimum":     1,
+					"maximum":     1000,
+					"description": "Maximum number of hits to return",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"default":     0,
+					"minimum":     0,
+					"description": "Number of hits to skip",
+				},
+				"mask_fields": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Array of field names to mask for sensitive data protection, masking message or stack_trace also drops their fragments",
+				},
+				"facets": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string", "enum": models.SearchFacetNames()},
+					"description": "Count all matching logs by these fields alongside the hits",
+				},
+			},
+			"required": []string{"query"},
+		},
+	}, s.handleSearchLogs)
 
 	// get_log_details tool
-	s.tools["get_log_details"] = Tool{
+	registerTool(s, Tool{
 		Name:        "get_log_details",
-		Description: "Retrieve specific log entries by their IDs with optional field masking",
+		Description: "Retrieve specific log entries by their IDs with optional field masking. Stack traces are symbolicated with the uploaded source maps and mapping files",
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -161,70 +390,484 @@ func (s *Server) registerTools() {
 			},
 			"required": []string{"ids"},
 		},
-	}
+	}, s.handleGetLogDetails)
+
+	// get_log_context tool
+	registerTool(s, Tool{
+		Name:        "get_log_context",
+		Description: "Get the entries logged right before and after a log entry by the same service, or by the same agent of the service, oldest first",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "string",
+					"description": "ID of the log entry",
+				},
+				"before": map[string]interface{}{
+					"type":        "integer",
+					"default":     10,
+					"minimum":     0,
+					"maximum":     100,
+					"description": "Number of entries logged before it",
+				},
+				"after": map[string]interface{}{
+					"type":        "integer",
+					"default":     10,
+					"minimum":     0,
+					"maximum":     100,
+					"description": "Number of entries logged after it",
+				},
+				"scope": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"service", "agent"},
+					"default":     "service",
+					"description": "Include the entries of the entry's service, or only those of its agent",
+				},
+				"mask_fields": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Array of field names to mask for sensitive data protection",
+				},
+			},
+			"required": []string{"id"},
+		},
+	}, s.handleGetLogContext)
 
 	// get_service_status tool
-	s.tools["get_service_status"] = Tool{
+	registerTool(s, Tool{
 		Name:        "get_service_status",
 		Description: "Get health status of the logging service",
 		InputSchema: map[string]interface{}{
 			"type":       "object",
 			"properties": map[string]interface{}{},
 		},
-	}
+	}, s.handleGetServiceStatus)
 
 	// list_services tool
-	s.tools["list_services"] = Tool{
+	registerTool(s, Tool{
 		Name:        "list_services",
-		Description: "List all available services and agents that have logged entries",
+		Description: "List the services and agents that have logged entries, including the owning team, repository, runbook and environment of registered services, most recently seen first by default. Agents that stopped logging are flagged stale and eventually hidden.",
 		InputSchema: map[string]interface{}{
-			"type":       "object",
-			"properties": map[string]interface{}{},
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name_prefix": map[string]interface{}{
+					"type":        "string",
+					"description": "Only services whose name starts with this prefix (case-sensitive)",
+				},
+				"platform": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by platform (e.g. go, swift, express, react, react-native, kotlin)",
+				},
+				"active_within": map[string]interface{}{
+					"type":        "string",
+					"description": "Only agents that logged within this duration before now, e.g. 1h or 168h",
+				},
+				"include_hidden": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Include agents hidden because they have not logged for longer than the configured hide period",
+				},
+				"sort": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"last_seen", "name", "log_count"},
+					"default":     "last_seen",
+					"description": "Order by most recently seen, by service name and agent ID, or by most stored entries",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     100,
+					"minimum":     1,
+					"maximum":     1000,
+					"description": "Maximum number of services",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"default":     0,
+					"minimum":     0,
+					"description": "Number of services to skip",
+				},
+			},
 		},
-	}
-}
+	}, s.handleListServices)
 
-// Start starts the MCP server
-func (s *Server) Start(ctx context.Context) error {
-	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", s.port))
-	if err != nil {
-		return fmt.Errorf("failed to listen on port %d: %w", s.port, err)
-	}
-	defer listener.Close()
+	// query_crashes tool
+	registerTool(s, Tool{
+		Name:        "query_crashes",
+		Description: "Group device crash reports by crash signature, most frequent first, or list the reports of one signature",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by service name",
+				},
+				"agent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by agent ID",
+				},
+				"request_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by the ID of the request the entries were logged for, to follow one request across services",
+				},
+				"template_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by message template, the template_id of a message group, to list the entries of the group",
+				},
+				"platform": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by platform (e.g. swift, kotlin, react-native)",
+				},
+				"start_time": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include crashes at or after this time: " + timeFormats,
+				},
+				"end_time": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include crashes at or before this time: " + timeFormats,
+				},
+				"time_zone": timeZoneProperty,
+				"signature": map[string]interface{}{
+					"type":        "string",
+					"description": "List the crash reports with this signature, including threads and breadcrumbs, instead of grouping",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     storage.DefaultCrashGroupLimit,
+					"minimum":     1,
+					"maximum":     100,
+					"description": "Maximum number of groups, or of reports when a signature is given",
+				},
+			},
+		},
+	}, s.handleQueryCrashes)
 
-	log.Printf("MCP server listening on port %d", s.port)
+	// get_usage tool
+	registerTool(s, Tool{
+		Name:        "get_usage",
+		Description: "Get the log entries and bytes ingested per API key and service, largest volume first, to find the noisiest producers. Counts are updated every minute",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"start_day": map[string]interface{}{
+					"type":        "string",
+					"format":      "date",
+					"description": "First UTC day to include (YYYY-MM-DD)",
+				},
+				"end_day": map[string]interface{}{
+					"type":        "string",
+					"format":      "date",
+					"description": "Last UTC day to include (YYYY-MM-DD)",
+				},
+				"api_key_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by API key ID",
+				},
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by service name",
+				},
+				"daily": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Report each day separately, most recent first, instead of summing the days",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     storage.DefaultUsageLimit,
+					"minimum":     1,
+					"maximum":     1000,
+					"description": "Maximum number of records",
+				},
+			},
+		},
+	}, s.handleGetUsage)
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-			conn, err := listener.Accept()
-			if err != nil {
-				log.Printf("Failed to accept connection: %v", err)
-				continue
-			}
+	// get_error_rate tool
+	registerTool(s, Tool{
+		Name:        "get_error_rate",
+		Description: "Compute the error rate (ERROR and FATAL entries of all entries) of a service over a time window and its trend against the window before, optionally with the error budget left for an SLO target",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Service to report on",
+				},
+				"window": map[string]interface{}{
+					"type":        "string",
+					"default":     defaultErrorRateWindow.String(),
+					"description": "Length of the window as a duration (e.g. 1h, 24h, 168h)",
+				},
+				"end_time": map[string]interface{}{
+					"type":        "string",
+					"description": "End of the window, defaults to now: " + timeFormats,
+				},
+				"time_zone": timeZoneProperty,
+				"slo_target": map[string]interface{}{
+					"type":             "number",
+					"exclusiveMinimum": 0,
+					"exclusiveMaximum": 1,
+					"description":      "Fraction of entries that should not be errors (e.g. 0.99), to report the error budget used",
+				},
+			},
+			"required": []string{"service_name"},
+		},
+	}, s.handleGetErrorRate)
 
-			go s.handleConnection(ctx, conn)
-		}
-	}
-}
+	// diff_time_windows tool
+	registerTool(s, Tool{
+		Name:        "diff_time_windows",
+		Description: "Compare a window of a service's logs with a baseline window, by default the same window a day earlier, to answer what changed after a deploy: message templates that are new or disappeared, and the change in entries per level",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Service to compare",
+				},
+				"window": map[string]interface{}{
+					"type":        "string",
+					"default":     "1h",
+					"description": "Length of both windows as a duration, e.g. 1h or 30m",
+				},
+				"end_time": map[string]interface{}{
+					"type":        "string",
+					"description": "End of the window, defaults to now. Accepts " + timeFormats,
+				},
+				"baseline_end_time": map[string]interface{}{
+					"type":        "string",
+					"description": "End of the baseline window, defaults to a day before end_time. Accepts " + timeFormats,
+				},
+				"time_zone": timeZoneProperty,
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     20,
+					"minimum":     1,
+					"maximum":     100,
+					"description": "Maximum number of new and of disappeared templates, largest first",
+				},
+				"mask_fields": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Array of field names to mask for sensitive data protection",
+				},
+			},
+			"required": []string{"service_name"},
+		},
+	}, s.handleDiffTimeWindows)
+
+	// query_log_summaries tool
+	registerTool(s, Tool{
+		Name:        "query_log_summaries",
+		Description: "List the hourly summaries that old low-severity entries were compacted into: per service, level and message template (numbers, IDs and quoted values replaced by placeholders), the number of entries and when they were first and last seen. Most recent hour and largest count first",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by service name",
+				},
+				"level": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"},
+					"description": "Filter by log level",
+				},
+				"start_time": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include hours starting at or after this time: " + timeFormats,
+				},
+				"end_time": map[string]interface{}{
+					"type":        "string",
+					"description": "Only include hours starting at or before this time: " + timeFormats,
+				},
+				"time_zone": timeZoneProperty,
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     storage.DefaultSummaryLimit,
+					"minimum":     1,
+					"maximum":     1000,
+					"description": "Maximum number of summaries",
+				},
+			},
+		},
+	}, s.handleQueryLogSummaries)
+
+	// annotate_log tool
+	registerTool(s, Tool{
+		Name:        "annotate_log",
+		Description: "Append an operator annotation (e.g. \"known issue JIRA-123\", \"false positive\") to a log entry. The entry itself is not changed; annotations are returned with it by query_logs, search_logs and get_log_details",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id": map[string]interface{}{
+					"type":        "string",
+					"description": "ID of the log entry to annotate",
+				},
+				"text": map[string]interface{}{
+					"type":        "string",
+					"maxLength":   1000,
+					"description": "Annotation text",
+				},
+				"author": map[string]interface{}{
+					"type":        "string",
+					"maxLength":   100,
+					"description": "Who added the annotation",
+				},
+			},
+			"required": []string{"id", "text"},
+		},
+	}, s.handleAnnotateLog)
+
+	// get_incident_logs tool
+	registerTool(s, Tool{
+		Name:        "get_incident_logs",
+		Description: "Get an incident with the log entries supporting it: the entries attached by ID, with the IDs of entries no longer stored, and the entries matching its filter snapshot",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"incident_id": map[string]interface{}{
+					"type":        "string",
+					"description": "ID of the incident",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"default":     100,
+					"minimum":     1,
+					"maximum":     1000,
+					"description": "Maximum number of attached entries, and of entries matching the filter",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"default":     0,
+					"minimum":     0,
+					"description": "Number of entries matching the filter to skip",
+				},
+				"mask_fields": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Array of field names to mask for sensitive data protection",
+				},
+			},
+			"required": []string{"incident_id"},
+		},
+	}, s.handleGetIncidentLogs)
+
+	// set_context tool
+	registerTool(s, Tool{
+		Name:        "set_context",
+		Description: "Set defaults for the service_name, agent_id, platform, mask_fields and time_zone arguments of the following tool calls on this connection. Arguments given in a call take precedence; omitted arguments keep their current default",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Default service name filter",
+				},
+				"agent_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Default agent ID filter",
+				},
+				"platform": map[string]interface{}{
+					"type":        "string",
+					"description": "Default platform filter (e.g. go, swift, express, react, react-native, kotlin)",
+				},
+				"mask_fields": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
+					"description": "Fields to mask by default, an empty list stops masking",
+				},
+				"time_zone": map[string]interface{}{
+					"type":        "string",
+					"description": "Default IANA time zone (e.g. Europe/Berlin) of local times in time arguments",
+				},
+				"clear": map[string]interface{}{
+					"type":        "boolean",
+					"default":     false,
+					"description": "Remove all defaults before applying the given ones",
+				},
+			},
+		},
+	}, s.handleSetContext)
+}
+
+// Start starts the MCP server
+func (s *Server) Start(ctx context.Context) error {
+	address := net.JoinHostPort(s.options.Host, strconv.Itoa(s.port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+	defer listener.Close()
+
+	log.Printf("MCP server listening on port %d", s.port)
+
+	if s.options.Tools != nil {
+		defer s.options.Tools.Subscribe(s.notifyToolsChanged)()
+	}
+
+	// Unblock Accept once the context is done
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("Failed to accept connection: %v", err)
+			continue
+		}
+
+		go s.handleConnection(ctx, conn)
+	}
+}
 
 // handleConnection handles a single MCP connection
 func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 	defer conn.Close()
 
+	// Cancel in-flight storage calls once the connection is gone
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Session defaults last as long as the connection
+	sess := &session{address: conn.RemoteAddr().String()}
+	ctx = withSession(ctx, sess)
+
 	decoder := json.NewDecoder(conn)
-	encoder := json.NewEncoder(conn)
+
+	// Responses are written by their own goroutine, a client that stops reading them is
+	// disconnected instead of blocking this one
+	writer := newResponseWriter(conn, s.options.WriteTimeout, s.maxPendingResponses(), func(err error) {
+		log.Printf("Disconnecting slow MCP client %s: %v", conn.RemoteAddr(), err)
+		if s.options.Metrics != nil {
+			s.options.Metrics.IncrementSlowConsumers()
+		}
+	})
+	defer writer.close()
+
+	// Removed before the writer is closed, so that no notification is sent to a closed writer
+	s.addConnection(sess, writer)
+	defer s.removeConnection(sess)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-writer.done:
+			if writer.err != nil {
+				log.Printf("Closing MCP connection: %v", writer.err)
+			}
+			return
 		default:
 			var msg MCPMessage
 			if err := decoder.Decode(&msg); err != nil {
-				if err == io.EOF {
+				if err == io.EOF || errors.Is(err, net.ErrClosed) {
 					return
 				}
 				log.Printf("Failed to decode message: %v", err)
@@ -233,8 +876,7 @@ func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 
 			response := s.handleMessage(ctx, &msg)
 			if response != nil {
-				if err := encoder.Encode(response); err != nil {
-					log.Printf("Failed to encode response: %v", err)
+				if err := writer.send(response); err != nil {
 					return
 				}
 			}
@@ -242,37 +884,111 @@ func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
 	}
 }
 
+// addConnection records an open connection, to be notified when the tools change
+func (s *Server) addConnection(sess *session, writer *responseWriter) {
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+	s.connections[sess] = writer
+}
+
+// removeConnection forgets a connection that is being closed
+func (s *Server) removeConnection(sess *session) {
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+	delete(s.connections, sess)
+}
+
+// notifyToolsChanged sends notifications/tools/list_changed to the initialized connections, for
+// their clients to list the tools again. Like responses, notifications a client leaves unread
+// count towards its pending responses.
+func (s *Server) notifyToolsChanged() {
+	notification := &MCPMessage{JSONRPC: "2.0", Method: "notifications/tools/list_changed"}
+
+	s.connectionsMutex.Lock()
+	defer s.connectionsMutex.Unlock()
+	for sess, writer := range s.connections {
+		if sess.getProtocolVersion() == "" {
+			continue
+		}
+		writer.send(notification)
+	}
+}
+
+// maxPendingResponses returns the responses a client may leave unread before it is disconnected
+func (s *Server) maxPendingResponses() int {
+	if s.options.MaxPendingResponses > 0 {
+		return s.options.MaxPendingResponses
+	}
+	return DefaultMaxPendingResponses
+}
+
 // handleMessage processes an MCP message and returns a response
 func (s *Server) handleMessage(ctx context.Context, msg *MCPMessage) *MCPMessage {
 	switch msg.Method {
 	case "initialize":
-		return s.handleInitialize(msg)
+		return s.handleInitialize(ctx, msg)
 	case "tools/list":
-		return s.handleToolsList(msg)
+		return s.handleToolsList(ctx, msg)
 	case "tools/call":
 		return s.handleToolCall(ctx, msg)
-	default:
+	case "completion/complete":
+		// Clients that negotiated an older protocol version were not offered completions
+		if supportsProtocol(ctx, completionsSince) {
+			return s.handleComplete(ctx, msg)
+		}
+	}
+
+	return &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      msg.ID,
+		Error: &MCPError{
+			Code:    -32601,
+			Message: "Method not found",
+		},
+	}
+}
+
+// handleInitialize handles the MCP initialize request. The protocol version is negotiated from
+// the versions the client offers, and clients may set session defaults with the experimental
+// sessionDefaults capability.
+func (s *Server) handleInitialize(ctx context.Context, msg *MCPMessage) *MCPMessage {
+	params, _ := msg.Params.(map[string]interface{})
+	version, err := negotiateProtocolVersion(params["protocolVersion"])
+	if err == nil {
+		err = s.initializeSession(ctx, msg.Params)
+	}
+	if err != nil {
 		return &MCPMessage{
 			JSONRPC: "2.0",
 			ID:      msg.ID,
 			Error: &MCPError{
-				Code:    -32601,
-				Message: "Method not found",
+				Code:    -32602,
+				Message: err.Error(),
 			},
 		}
 	}
-}
+	if sess := sessionFromContext(ctx); sess != nil {
+		sess.setProtocolVersion(version)
+	}
+
+	capabilities := map[string]interface{}{
+		"tools": map[string]interface{}{
+			"listChanged": s.options.Tools != nil,
+		},
+		"experimental": map[string]interface{}{
+			sessionDefaultsCapability: map[string]interface{}{},
+		},
+	}
+	if version >= completionsSince {
+		capabilities["completions"] = map[string]interface{}{}
+	}
 
-// handleInitialize handles the MCP initialize request
-func (s *Server) handleInitialize(msg *MCPMessage) *MCPMessage {
 	return &MCPMessage{
 		JSONRPC: "2.0",
 		ID:      msg.ID,
 		Result: map[string]interface{}{
-			"protocolVersion": "2024-11-05",
-			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
-			},
+			"protocolVersion": version,
+			"capabilities":    capabilities,
 			"serverInfo": map[string]interface{}{
 				"name":    "mcp-logging-server",
 				"version": "1.0.0",
@@ -281,11 +997,17 @@ func (s *Server) handleInitialize(msg *MCPMessage) *MCPMessage {
 	}
 }
 
-// handleToolsList handles the tools/list request
-func (s *Server) handleToolsList(msg *MCPMessage) *MCPMessage {
-	tools := make([]Tool, 0, len(s.tools))
-	for _, tool := range s.tools {
-		tools = append(tools, tool)
+// handleToolsList handles the tools/list request, with the tool annotations if the client
+// negotiated them
+func (s *Server) handleToolsList(ctx context.Context, msg *MCPMessage) *MCPMessage {
+	annotated := supportsProtocol(ctx, toolAnnotationsSince)
+	enabled := s.tools.list()
+	tools := make([]Tool, 0, len(enabled))
+	for _, tool := range enabled {
+		if annotated {
+			tool.Annotations = toolAnnotations(tool.Name)
+		}
+		tools = append(tools, tool.Tool)
 	}
 
 	return &MCPMessage{
@@ -325,19 +1047,8 @@ func (s *Server) handleToolCall(ctx context.Context, msg *MCPMessage) *MCPMessag
 
 	arguments := params["arguments"]
 
-	var result *ToolResult
-	var err error
-
-	switch toolName {
-	case "query_logs":
-		result, err = s.handleQueryLogs(ctx, arguments)
-	case "get_log_details":
-		result, err = s.handleGetLogDetails(ctx, arguments)
-	case "get_service_status":
-		result, err = s.handleGetServiceStatus(ctx, arguments)
-	case "list_services":
-		result, err = s.handleListServices(ctx, arguments)
-	default:
+	tool, exists := s.tools.get(toolName)
+	if !exists {
 		return &MCPMessage{
 			JSONRPC: "2.0",
 			ID:      msg.ID,
@@ -348,6 +1059,99 @@ func (s *Server) handleToolCall(ctx context.Context, msg *MCPMessage) *MCPMessag
 		}
 	}
 
+	// Disabled tools are not listed, calls to them are answered with why they are disabled
+	if !s.tools.enabled(toolName) {
+		data := map[string]interface{}{"tool": toolName}
+		if reason := s.options.Tools.Get(toolName).Reason; reason != "" {
+			data["reason"] = reason
+		}
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &MCPError{
+				Code:    -32601,
+				Message: "Tool is disabled",
+				Data:    data,
+			},
+		}
+	}
+
+	if rejected := s.rejectedByMode(toolName); rejected != nil {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error:   rejected,
+		}
+	}
+
+	arguments = applySessionDefaults(ctx, tool, arguments)
+
+	timeout := s.toolTimeout(toolName)
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	call := &auditedCall{}
+	callCtx = withAuditedCall(callCtx, call)
+
+	start := time.Now()
+	result, err := tool.handler(callCtx, arguments)
+	elapsed := time.Since(start)
+
+	if s.options.QueryAudit != nil {
+		s.auditToolCall(ctx, toolName, arguments, start, elapsed, call.resultCount, err)
+	}
+
+	if s.options.SlowQueryThreshold > 0 && elapsed >= s.options.SlowQueryThreshold {
+		s.logSlowQuery(toolName, arguments, elapsed, err)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || (err != nil && callCtx.Err() == context.DeadlineExceeded) {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &MCPError{
+				Code:    errorCodeTimeout,
+				Message: fmt.Sprintf("Tool %s timed out after %s", toolName, timeout),
+				Data: map[string]interface{}{
+					"tool":       toolName,
+					"timeout_ms": timeout.Milliseconds(),
+					"elapsed_ms": elapsed.Milliseconds(),
+				},
+			},
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &MCPError{
+				Code:    errorCodeCancelled,
+				Message: "Request cancelled",
+				Data: map[string]interface{}{
+					"tool": toolName,
+				},
+			},
+		}
+	}
+
+	var argErr *argumentError
+	if errors.As(err, &argErr) {
+		return &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      msg.ID,
+			Error: &MCPError{
+				Code:    -32602,
+				Message: err.Error(),
+				Data:    argErr.data(),
+			},
+		}
+	}
+
 	if err != nil {
 		return &MCPMessage{
 			JSONRPC: "2.0",
@@ -366,120 +1170,464 @@ func (s *Server) handleToolCall(ctx context.Context, msg *MCPMessage) *MCPMessag
 	}
 }
 
-// handleQueryLogs handles the query_logs tool call
-func (s *Server) handleQueryLogs(ctx context.Context, arguments interface{}) (*ToolResult, error) {
-	args, ok := arguments.(map[string]interface{})
-	if !ok {
-		args = make(map[string]interface{})
+// toolTimeout returns the deadline for a tool call, 0 when calls are not limited
+func (s *Server) toolTimeout(toolName string) time.Duration {
+	if timeout, ok := s.options.ToolTimeouts[toolName]; ok {
+		return timeout
+	}
+	return s.options.DefaultTimeout
+}
+
+// logSlowQuery logs a slow tool call together with the arguments that caused it
+func (s *Server) logSlowQuery(toolName string, arguments interface{}, elapsed time.Duration, err error) {
+	argumentsJSON, marshalErr := json.Marshal(arguments)
+	if marshalErr != nil {
+		argumentsJSON = []byte(fmt.Sprintf("%v", arguments))
+	}
+
+	status := "ok"
+	if err != nil {
+		status = err.Error()
 	}
 
-	filter := models.LogFilter{}
+	log.Printf("Slow MCP query: tool=%s duration=%s status=%q arguments=%s", toolName, elapsed, status, argumentsJSON)
+}
+
+// pagination describes the page of entries a tool returned
+type pagination struct {
+	TotalCount int  `json:"total_count"`
+	HasMore    bool `json:"has_more"`
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+}
+
+// logFilterParams are the filter arguments shared by the tools querying log entries
+type logFilterParams struct {
+	ServiceName string           `json:"service_name"`
+	AgentID     string           `json:"agent_id"`
+	RequestID   string           `json:"request_id"`
+	TemplateID  string           `json:"template_id"`
+	Level       models.LogLevel  `json:"level" validate:"omitempty,oneof=DEBUG INFO WARN ERROR FATAL"`
+	MinLevel    models.LogLevel  `json:"min_level" validate:"omitempty,oneof=DEBUG INFO WARN ERROR FATAL"`
+	Platform    models.Platform  `json:"platform"`
+	StartTime   timeArgument     `json:"start_time"`
+	EndTime     timeArgument     `json:"end_time"`
+	TimeZone    string           `json:"time_zone"`
+	TimeField   models.TimeField `json:"time_field" validate:"omitempty,oneof=timestamp received_at"`
+	TagsAny     []string         `json:"tags_any"`
+	TagsAll     []string         `json:"tags_all"`
+
+	startTime, endTime time.Time // Resolved from StartTime and EndTime
+}
 
-	if serviceName, ok := args["service_name"].(string); ok {
-		filter.ServiceName = serviceName
+func (p *logFilterParams) resolveTimes(now time.Time) error {
+	loc, err := loadTimeZone(p.TimeZone)
+	if err != nil {
+		return err
 	}
-	if agentID, ok := args["agent_id"].(string); ok {
-		filter.AgentID = agentID
+	if p.startTime, err = parseTimeArgument("start_time", p.StartTime, now, loc); err != nil {
+		return err
 	}
-	if level, ok := args["level"].(string); ok {
-		filter.Level = models.LogLevel(level)
+	p.endTime, err = parseTimeArgument("end_time", p.EndTime, now, loc)
+	return err
+}
+
+// filter returns the log filter the arguments describe
+func (p logFilterParams) filter() models.LogFilter {
+	return models.LogFilter{
+		ServiceName: p.ServiceName,
+		AgentID:     p.AgentID,
+		RequestID:   p.RequestID,
+		TemplateID:  p.TemplateID,
+		Level:       p.Level,
+		MinLevel:    p.MinLevel,
+		Platform:    p.Platform,
+		StartTime:   p.startTime,
+		EndTime:     p.endTime,
+		TimeField:   p.TimeField,
+		TagsAny:     p.TagsAny,
+		TagsAll:     p.TagsAll,
 	}
-	if platform, ok := args["platform"].(string); ok {
-		filter.Platform = models.Platform(platform)
+}
+
+// checkPlatform rejects a platform argument that no log entry can have. An empty platform does
+// not filter and is always accepted.
+func (s *Server) checkPlatform(platform models.Platform) error {
+	if platform == "" || len(s.options.Platforms) == 0 {
+		return nil
 	}
-	if messageContains, ok := args["message_contains"].(string); ok {
-		filter.MessageContains = messageContains
+	for _, accepted := range s.options.Platforms {
+		if string(platform) == accepted {
+			return nil
+		}
 	}
-	if limit, ok := args["limit"].(float64); ok {
-		filter.Limit = int(limit)
-	} else {
-		filter.Limit = 100
+	return invalidArgument("platform", s.options.Platforms, "platform must be one of: %s", strings.Join(s.options.Platforms, ", "))
+}
+
+// retentionWarnings explains why a query starting at start may miss entries of level, or of any
+// level when level is empty: older entries are deleted by retention or replaced by summaries.
+// A query without start time is not warned about, since it does not ask for old entries.
+func (s *Server) retentionWarnings(level models.LogLevel, start time.Time) []string {
+	if start.IsZero() {
+		return nil
 	}
-	if offset, ok := args["offset"].(float64); ok {
-		filter.Offset = int(offset)
+	levels := []models.LogLevel{level}
+	if level == "" {
+		levels = logLevels
 	}
 
-	// Parse time strings
-	if startTimeStr, ok := args["start_time"].(string); ok {
-		if startTime, err := time.Parse(time.RFC3339, startTimeStr); err == nil {
-			filter.StartTime = startTime
+	now := time.Now()
+	var warnings []string
+	for _, level := range levels {
+		days := s.options.Retention.DefaultDays
+		if levelDays, ok := s.options.Retention.ByLevel[level]; ok {
+			days = levelDays
 		}
-	}
-	if endTimeStr, ok := args["end_time"].(string); ok {
-		if endTime, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
-			filter.EndTime = endTime
+		if days > 0 && start.Before(now.AddDate(0, 0, -days)) {
+			warnings = append(warnings, fmt.Sprintf("%s logs older than %s are deleted", level, formatDays(days)))
+			continue
+		}
+		after := s.options.CompactionAfter
+		if after > 0 && s.compacted(level) && start.Before(now.Add(-after)) {
+			warnings = append(warnings, fmt.Sprintf("%s logs older than %s are compacted into hourly summaries, see query_log_summaries", level, formatAge(after)))
 		}
 	}
+	return warnings
+}
 
-	result, err := s.storage.Query(ctx, filter)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query logs: %w", err)
+// compacted reports whether old entries of level are replaced by summaries
+func (s *Server) compacted(level models.LogLevel) bool {
+	for _, compacted := range s.options.CompactionLevels {
+		if compacted == level {
+			return true
+		}
 	}
+	return false
+}
 
-	// Apply field masking for sensitive data protection
-	maskedFields := s.getMaskedFields(args)
-	if len(maskedFields) > 0 {
-		result = s.applyFieldMasking(result, maskedFields)
+// formatDays formats a number of days such as "7 days"
+func formatDays(days int) string {
+	if days == 1 {
+		return "1 day"
 	}
+	return fmt.Sprintf("%d days", days)
+}
 
-	// Add pagination information to the response
-	actualLimit := filter.Limit
-	if actualLimit == 0 {
-		actualLimit = 100 // default limit
+// formatAge formats an age in days if it is a whole number of them, such as "7 days" or "36h0m0s"
+func formatAge(age time.Duration) string {
+	if age%(24*time.Hour) == 0 {
+		return formatDays(int(age / (24 * time.Hour)))
 	}
+	return age.String()
+}
 
-	paginationInfo := map[string]interface{}{
-		"total_count": result.TotalCount,
-		"has_more":    result.HasMore,
-		"limit":       actualLimit,
-		"offset":      filter.Offset,
-	}
+// queryLogsParams are the arguments of the query_logs tool
+type queryLogsParams struct {
+	logFilterParams
+	MessageContains string   `json:"message_contains"`
+	Limit           int      `json:"limit" validate:"min=1,max=1000"`
+	Offset          int      `json:"offset" validate:"min=0"`
+	MaskFields      []string `json:"mask_fields"`
+	Facets          []string `json:"facets" validate:"dive,facet"`
+	GroupBy         string   `json:"group_by" validate:"omitempty,oneof=message_template"`
+}
+
+func (p *queryLogsParams) setDefaults() {
+	p.Limit = 100
+}
+
+func (p queryLogsParams) cacheService() string {
+	return p.ServiceName
+}
+
+// queryLogsResult is the result of the query_logs tool
+type queryLogsResult struct {
+	Logs       []models.LogEntry              `json:"logs"`
+	Pagination pagination                     `json:"pagination"`
+	Facets     map[string][]models.FacetCount `json:"facets,omitempty"`
+	Warnings   []string                       `json:"warnings,omitempty"` // Why entries in the time range may be missing, see retentionWarnings
+}
+
+// groupedLogsResult is the result of the query_logs tool with group_by, paginated by group
+type groupedLogsResult struct {
+	Groups       []models.MessageGroup          `json:"groups"`
+	Pagination   pagination                     `json:"pagination"`
+	ScannedCount int                            `json:"scanned_count"`
+	Truncated    bool                           `json:"truncated"` // More than maxGroupedEntries entries matched, later ones were not grouped
+	Facets       map[string][]models.FacetCount `json:"facets,omitempty"`
+	Warnings     []string                       `json:"warnings,omitempty"`
+}
+
+const (
+	// maxGroupedEntries is the number of matching entries query_logs groups at most, most recent first
+	maxGroupedEntries = 10000
 
-	response := map[string]interface{}{
-		"logs":       result.Logs,
-		"pagination": paginationInfo,
+	// groupPageSize is the number of entries queried at once for grouping
+	groupPageSize = 1000
+)
+
+// handleQueryLogs handles the query_logs tool call
+func (s *Server) handleQueryLogs(ctx context.Context, params queryLogsParams) (interface{}, error) {
+	if err := s.checkPlatform(params.Platform); err != nil {
+		return nil, err
+	}
+	filter := params.filter()
+	filter.MessageContains = params.MessageContains
+	filter.Facets = params.Facets
+	if params.GroupBy != "" {
+		return s.groupLogs(ctx, filter, params)
 	}
+	filter.Limit = params.Limit
+	filter.Offset = params.Offset
 
-	// Format result as JSON text
-	resultJSON, err := json.MarshalIndent(response, "", "  ")
+	result, err := s.storage.Query(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %w", err)
+		return nil, fmt.Errorf("failed to query logs: %w", err)
 	}
 
-	return &ToolResult{
-		Content: []ContentBlock{
-			{
-				Type: "text",
-				Text: string(resultJSON),
-			},
+	// Apply field masking for sensitive data protection
+	result = s.applyFieldMasking(result, params.MaskFields)
+	countResults(ctx, len(result.Logs))
+
+	return &queryLogsResult{
+		Logs: result.Logs,
+		Pagination: pagination{
+			TotalCount: result.TotalCount,
+			HasMore:    result.HasMore,
+			Limit:      filter.Limit,
+			Offset:     filter.Offset,
 		},
+		Facets:   maskFacets(result.Facets, params.MaskFields),
+		Warnings: s.retentionWarnings(params.Level, params.startTime),
 	}, nil
 }
 
-// getMaskedFields extracts field masking configuration from arguments
-func (s *Server) getMaskedFields(args map[string]interface{}) []string {
-	var maskedFields []string
+// maskGroups masks the representative entries of message groups. Templates are masked with the
+// message, since they keep all but its variable parts.
+func (s *Server) maskGroups(groups []models.MessageGroup, maskedFields []string) []models.MessageGroup {
+	if len(maskedFields) == 0 {
+		return groups
+	}
 
-	if maskFields, ok := args["mask_fields"].([]interface{}); ok {
-		for _, field := range maskFields {
-			if fieldStr, ok := field.(string); ok {
-				maskedFields = append(maskedFields, fieldStr)
-			}
+	representatives := &models.LogResult{Logs: make([]models.LogEntry, len(groups))}
+	for i, group := range groups {
+		representatives.Logs[i] = group.Entry
+	}
+	masked := s.applyFieldMasking(representatives, maskedFields)
+	for i := range groups {
+		groups[i].Entry = masked.Logs[i]
+		if containsString(maskedFields, "message") {
+			groups[i].Template = s.maskString(groups[i].Template)
 		}
 	}
-
-	return maskedFields
+	return groups
 }
 
-// applyFieldMasking applies field masking to sensitive data
-func (s *Server) applyFieldMasking(result *models.LogResult, maskedFields []string) *models.LogResult {
-	if len(maskedFields) == 0 {
+// scanLogs reads up to maxGroupedEntries entries matching the filter, most recent first, with the
+// facets of the filter, and reports whether more entries matched
+func (s *Server) scanLogs(ctx context.Context, filter models.LogFilter) ([]models.LogEntry, map[string][]models.FacetCount, bool, error) {
+	var entries []models.LogEntry
+	var facets map[string][]models.FacetCount
+	truncated := false
+	filter.Limit = groupPageSize
+	for filter.Offset = 0; ; filter.Offset += filter.Limit {
+		result, err := s.storage.Query(ctx, filter)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("failed to query logs: %w", err)
+		}
+		if filter.Offset == 0 {
+			facets = result.Facets
+			filter.Facets = nil
+		}
+		entries = append(entries, result.Logs...)
+		if len(entries) >= maxGroupedEntries {
+			truncated = result.HasMore || len(entries) > maxGroupedEntries
+			entries = entries[:maxGroupedEntries]
+			break
+		}
+		if !result.HasMore || len(result.Logs) == 0 {
+			break
+		}
+	}
+	return entries, facets, truncated, nil
+}
+
+// groupLogs answers query_logs with group_by, grouping up to maxGroupedEntries matching entries
+// by message template and returning the requested page of groups
+func (s *Server) groupLogs(ctx context.Context, filter models.LogFilter, params queryLogsParams) (*groupedLogsResult, error) {
+	entries, facets, truncated, err := s.scanLogs(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := models.GroupByTemplate(entries)
+	total := len(groups)
+	start := min(params.Offset, total)
+	end := min(start+params.Limit, total)
+	groups = groups[start:end]
+	countResults(ctx, len(groups))
+
+	return &groupedLogsResult{
+		Groups: s.maskGroups(groups, params.MaskFields),
+		Pagination: pagination{
+			TotalCount: total,
+			HasMore:    end < total,
+			Limit:      params.Limit,
+			Offset:     params.Offset,
+		},
+		ScannedCount: len(entries),
+		Truncated:    truncated,
+		Facets:       maskFacets(facets, params.MaskFields),
+		Warnings:     s.retentionWarnings(params.Level, params.startTime),
+	}, nil
+}
+
+// searchLogsParams are the arguments of the search_logs tool
+type searchLogsParams struct {
+	logFilterParams
+	Query      string            `json:"query" validate:"required"`
+	Fuzziness  int               `json:"fuzziness" validate:"min=0,max=2"` // Up to models.MaxSearchFuzziness
+	Prefix     bool              `json:"prefix"`
+	Sort       models.SearchSort `json:"sort" validate:"omitempty,oneof=time relevance"`
+	Limit      int               `json:"limit" validate:"min=1,max=1000"`
+	Offset     int               `json:"offset" validate:"min=0"`
+	MaskFields []string          `json:"mask_fields"`
+	Facets     []string          `json:"facets" validate:"dive,facet"`
+}
+
+func (p *searchLogsParams) setDefaults() {
+	p.Limit = 100
+}
+
+// searchLogsResult is the result of the search_logs tool
+type searchLogsResult struct {
+	Query      string                         `json:"query"`
+	Hits       []models.SearchHit             `json:"hits"`
+	Pagination pagination                     `json:"pagination"`
+	Facets     map[string][]models.FacetCount `json:"facets,omitempty"`
+	Warnings   []string                       `json:"warnings,omitempty"`
+}
+
+// handleSearchLogs handles the search_logs tool call
+func (s *Server) handleSearchLogs(ctx context.Context, params searchLogsParams) (*searchLogsResult, error) {
+	if strings.TrimSpace(params.Query) == "" {
+		return nil, invalidArgument("query", nil, "query must not be blank")
+	}
+	if err := s.checkPlatform(params.Platform); err != nil {
+		return nil, err
+	}
+
+	filter := params.filter()
+	filter.Fuzziness = params.Fuzziness
+	filter.Prefix = params.Prefix
+	filter.Sort = params.Sort
+	filter.Facets = params.Facets
+	filter.Limit = params.Limit
+	filter.Offset = params.Offset
+
+	searcher, ok := s.storage.(storage.LogSearcher)
+	if !ok {
+		return nil, storage.ErrSearchDisabled
+	}
+
+	result, err := searcher.SearchLogs(ctx, params.Query, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search logs: %w", err)
+	}
+
+	// Apply field masking for sensitive data protection
+	if len(params.MaskFields) > 0 {
+		result = s.applySearchMasking(result, params.MaskFields)
+	}
+	countResults(ctx, len(result.Hits))
+
+	return &searchLogsResult{
+		Query: params.Query,
+		Hits:  result.Hits,
+		Pagination: pagination{
+			TotalCount: result.TotalCount,
+			HasMore:    result.HasMore,
+			Limit:      filter.Limit,
+			Offset:     filter.Offset,
+		},
+		Facets:   maskFacets(result.Facets, params.MaskFields),
+		Warnings: s.retentionWarnings(params.Level, params.startTime),
+	}, nil
+}
+
+// applySearchMasking masks search hits, dropping fragments and matches of masked text fields
+func (s *Server) applySearchMasking(result *models.SearchResult, maskedFields []string) *models.SearchResult {
+	logs := make([]models.LogEntry, len(result.Hits))
+	for i, hit := range result.Hits {
+		logs[i] = hit.Log
+	}
+	masked := s.applyFieldMasking(&models.LogResult{Logs: logs}, maskedFields)
+
+	maskedResult := &models.SearchResult{
+		Hits:       make([]models.SearchHit, len(result.Hits)),
+		TotalCount: result.TotalCount,
+		HasMore:    result.HasMore,
+		Facets:     result.Facets,
+	}
+
+	for i, hit := range result.Hits {
+		maskedHit := hit
+		maskedHit.Log = masked.Logs[i]
+		maskedHit.Fragments = make(map[string][]string)
+		maskedHit.Matches = nil
+
+		for field, fragments := range hit.Fragments {
+			if !containsString(maskedFields, field) {
+				maskedHit.Fragments[field] = fragments
+			}
+		}
+		for _, match := range hit.Matches {
+			if !containsString(maskedFields, match.Field) {
+				maskedHit.Matches = append(maskedHit.Matches, match)
+			}
+		}
+
+		maskedResult.Hits[i] = maskedHit
+	}
+
+	return maskedResult
+}
+
+// maskFacets drops facets whose values come from a masked field
+func maskFacets(facets map[string][]models.FacetCount, maskedFields []string) map[string][]models.FacetCount {
+	if !containsString(maskedFields, "service_name") {
+		return facets
+	}
+
+	masked := make(map[string][]models.FacetCount, len(facets))
+	for name, counts := range facets {
+		if name != models.FacetService {
+			masked[name] = counts
+		}
+	}
+	return masked
+}
+
+// containsString checks if a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFieldMasking applies field masking to sensitive data
+func (s *Server) applyFieldMasking(result *models.LogResult, maskedFields []string) *models.LogResult {
+	if len(maskedFields) == 0 {
 		return result
 	}
 
 	maskedResult := &models.LogResult{
 		TotalCount: result.TotalCount,
 		HasMore:    result.HasMore,
+		Facets:     result.Facets,
 		Logs:       make([]models.LogEntry, len(result.Logs)),
 	}
 
@@ -501,6 +1649,14 @@ func (s *Server) applyFieldMasking(result *models.LogResult, maskedFields []stri
 				maskedLog.Message = s.maskString(maskedLog.Message)
 			case "agent_id":
 				maskedLog.AgentID = s.maskString(maskedLog.AgentID)
+			case "request_id":
+				// Request IDs logged as metadata before the field existed are masked too
+				maskedLog.RequestID = s.maskString(maskedLog.RequestID)
+				if maskedLog.Metadata != nil {
+					if strVal, ok := maskedLog.Metadata[field].(string); ok {
+						maskedLog.Metadata[field] = s.maskString(strVal)
+					}
+				}
 			case "service_name":
 				maskedLog.ServiceName = s.maskString(maskedLog.ServiceName)
 			case "stack_trace":
@@ -512,7 +1668,7 @@ func (s *Server) applyFieldMasking(result *models.LogResult, maskedFields []stri
 						if strVal, ok := maskedLog.Metadata[field].(string); ok {
 							maskedLog.Metadata[field] = s.maskString(strVal)
 						} else {
-							maskedLog.Metadata[field] = "[MASKED]"
+							maskedLog.Metadata[field] = s.masker.Redact(fmt.Sprint(maskedLog.Metadata[field]))
 						}
 					}
 				}
@@ -527,73 +1683,289 @@ func (s *Server) applyFieldMasking(result *models.LogResult, maskedFields []stri
 
 // maskString masks a string value for sensitive data protection
 func (s *Server) maskString(value string) string {
-	if len(value) <= 4 {
-		return "[MASKED]"
-	}
+	return s.masker.Mask(value)
+}
 
-	// Show first 2 and last 2 characters, mask the middle
-	return value[:2] + "[MASKED]" + value[len(value)-2:]
+// getLogDetailsParams are the arguments of the get_log_details tool
+type getLogDetailsParams struct {
+	IDs        []string `json:"ids" validate:"required,min=1,max=100"`
+	MaskFields []string `json:"mask_fields"`
 }
 
 // handleGetLogDetails handles the get_log_details tool call
-func (s *Server) handleGetLogDetails(ctx context.Context, arguments interface{}) (*ToolResult, error) {
-	args, ok := arguments.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid arguments")
-	}
-
-	idsInterface, ok := args["ids"].([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("missing or invalid ids parameter")
+func (s *Server) handleGetLogDetails(ctx context.Context, params getLogDetailsParams) ([]models.LogEntry, error) {
+	logs, err := s.storage.GetByIDs(ctx, params.IDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log details: %w", err)
 	}
 
-	ids := make([]string, len(idsInterface))
-	for i, id := range idsInterface {
-		idStr, ok := id.(string)
-		if !ok {
-			return nil, fmt.Errorf("invalid id at index %d", i)
+	// Symbolicate stack traces that were stored before their symbol files were uploaded
+	if s.stackTraces != nil {
+		for i := range logs {
+			if _, err := s.stackTraces.SymbolicateEntry(ctx, &logs[i]); err != nil {
+				log.Printf("Failed to symbolicate stack trace of log entry %s: %v", logs[i].ID, err)
+			}
 		}
-		ids[i] = idStr
-	}
-
-	logs, err := s.storage.GetByIDs(ctx, ids)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get log details: %w", err)
 	}
 
 	// Apply field masking for sensitive data protection
-	maskedFields := s.getMaskedFields(args)
-	if len(maskedFields) > 0 {
+	if len(params.MaskFields) > 0 {
 		// Create a temporary LogResult to use the existing masking function
 		tempResult := &models.LogResult{
 			Logs:       logs,
 			TotalCount: len(logs),
 			HasMore:    false,
 		}
-		maskedResult := s.applyFieldMasking(tempResult, maskedFields)
+		maskedResult := s.applyFieldMasking(tempResult, params.MaskFields)
 		logs = maskedResult.Logs
 	}
+	countResults(ctx, len(logs))
 
-	// Format result as JSON text
-	resultJSON, err := json.MarshalIndent(logs, "", "  ")
+	return logs, nil
+}
+
+// getLogContextParams are the arguments of the get_log_context tool
+type getLogContextParams struct {
+	ID         string   `json:"id" validate:"required"`
+	Before     int      `json:"before" validate:"min=0,max=100"`
+	After      int      `json:"after" validate:"min=0,max=100"`
+	Scope      string   `json:"scope" validate:"oneof=service agent"`
+	MaskFields []string `json:"mask_fields"`
+}
+
+func (p *getLogContextParams) setDefaults() {
+	p.Before = 10
+	p.After = 10
+	p.Scope = "service"
+}
+
+// getLogContextResult is the result of the get_log_context tool
+type getLogContextResult struct {
+	Log           models.LogEntry   `json:"log"`
+	Before        []models.LogEntry `json:"before"` // Oldest first
+	After         []models.LogEntry `json:"after"`  // Oldest first
+	HasMoreBefore bool              `json:"has_more_before"`
+	HasMoreAfter  bool              `json:"has_more_after"`
+}
+
+// handleGetLogContext handles the get_log_context tool call. Entries logged at the same time as
+// the entry are ordered by ID, as QueryStream orders them.
+func (s *Server) handleGetLogContext(ctx context.Context, params getLogContextParams) (*getLogContextResult, error) {
+	logs, err := s.storage.GetByIDs(ctx, []string{params.ID})
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %w", err)
+		return nil, fmt.Errorf("failed to get log entry: %w", err)
+	}
+	if len(logs) == 0 {
+		return nil, fmt.Errorf("log entry %s does not exist", params.ID)
 	}
+	entry := logs[0]
 
-	return &ToolResult{
-		Content: []ContentBlock{
-			{
-				Type: "text",
-				Text: string(resultJSON),
-			},
-		},
-	}, nil
+	filter := models.LogFilter{ServiceName: entry.ServiceName}
+	if params.Scope == "agent" {
+		filter.AgentID = entry.AgentID
+	}
+
+	simultaneous := filter
+	simultaneous.StartTime = entry.Timestamp
+	simultaneous.EndTime = entry.Timestamp
+	ties, err := s.streamLogs(ctx, simultaneous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log context: %w", err)
+	}
+	var tiesBefore, tiesAfter []models.LogEntry
+	for _, tie := range ties {
+		if tie.ID < entry.ID {
+			tiesBefore = append(tiesBefore, tie)
+		} else if tie.ID > entry.ID {
+			tiesAfter = append(tiesAfter, tie)
+		}
+	}
+
+	result := &getLogContextResult{}
+
+	// Earlier entries come newest first, so that the limit keeps the closest ones
+	earlier := filter
+	earlier.EndTime = entry.Timestamp.Add(-time.Nanosecond)
+	earlier.Limit = params.Before - len(tiesBefore)
+	if earlier.Limit < 1 {
+		earlier.Limit = 1
+	}
+	earlierLogs, err := s.storage.Query(ctx, earlier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log context: %w", err)
+	}
+	before := make([]models.LogEntry, 0, params.Before)
+	for i := len(earlierLogs.Logs) - 1; i >= 0; i-- {
+		before = append(before, earlierLogs.Logs[i])
+	}
+	before = append(before, tiesBefore...)
+	result.HasMoreBefore = len(before) > params.Before || earlierLogs.HasMore
+	if len(before) > params.Before {
+		before = before[len(before)-params.Before:]
+	}
+
+	later := filter
+	later.StartTime = entry.Timestamp.Add(time.Nanosecond)
+	later.Limit = params.After - len(tiesAfter) + 1
+	if later.Limit < 1 {
+		later.Limit = 1
+	}
+	laterLogs, err := s.streamLogs(ctx, later)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get log context: %w", err)
+	}
+	after := append(append(make([]models.LogEntry, 0, params.After), tiesAfter...), laterLogs...)
+	result.HasMoreAfter = len(after) > params.After
+	if len(after) > params.After {
+		after = after[:params.After]
+	}
+
+	result.Log = s.applyFieldMasking(&models.LogResult{Logs: []models.LogEntry{entry}}, params.MaskFields).Logs[0]
+	result.Before = s.applyFieldMasking(&models.LogResult{Logs: before}, params.MaskFields).Logs
+	result.After = s.applyFieldMasking(&models.LogResult{Logs: after}, params.MaskFields).Logs
+	countResults(ctx, 1+len(result.Before)+len(result.After))
+	return result, nil
+}
+
+// streamLogs reads the entries matching a filter, oldest first
+func (s *Server) streamLogs(ctx context.Context, filter models.LogFilter) ([]models.LogEntry, error) {
+	it, err := s.storage.QueryStream(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var logs []models.LogEntry
+	for it.Next() {
+		logs = append(logs, it.Entry())
+	}
+	return logs, it.Err()
+}
+
+// annotateLogParams are the arguments of the annotate_log tool
+type annotateLogParams struct {
+	ID     string `json:"id" validate:"required"`
+	Text   string `json:"text" validate:"required,max=1000"`
+	Author string `json:"author" validate:"max=100"`
+}
+
+// annotateLogResult is the result of the annotate_log tool
+type annotateLogResult struct {
+	ID          string                 `json:"id"`
+	Annotations []models.LogAnnotation `json:"annotations"`
+}
+
+// handleAnnotateLog handles the annotate_log tool call
+func (s *Server) handleAnnotateLog(ctx context.Context, params annotateLogParams) (*annotateLogResult, error) {
+	annotator, ok := s.storage.(storage.LogAnnotator)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support annotations")
+	}
+	if strings.TrimSpace(params.Text) == "" {
+		return nil, invalidArguments("text must not be blank")
+	}
+
+	annotations, err := annotator.AnnotateLog(ctx, params.ID, models.LogAnnotation{Text: params.Text, Author: params.Author})
+	if errors.Is(err, storage.ErrLogNotFound) {
+		return nil, fmt.Errorf("log entry %s does not exist", params.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to annotate log entry: %w", err)
+	}
+	// Cached query results would show the entry without its new annotation
+	s.options.ResultCache.invalidateAll()
+
+	return &annotateLogResult{ID: params.ID, Annotations: annotations}, nil
+}
+
+// getIncidentLogsParams are the arguments of the get_incident_logs tool
+type getIncidentLogsParams struct {
+	IncidentID string   `json:"incident_id" validate:"required"`
+	Limit      int      `json:"limit" validate:"min=1,max=1000"`
+	Offset     int      `json:"offset" validate:"min=0"`
+	MaskFields []string `json:"mask_fields"`
+}
+
+func (p *getIncidentLogsParams) setDefaults() {
+	p.Limit = 100
+}
+
+// getIncidentLogsResult is the result of the get_incident_logs tool
+type getIncidentLogsResult struct {
+	Incident         models.Incident   `json:"incident"`
+	AttachedLogs     []models.LogEntry `json:"attached_logs"`
+	MissingLogIDs    []string          `json:"missing_log_ids,omitempty"` // Attached, but deleted since
+	FilterLogs       []models.LogEntry `json:"filter_logs,omitempty"`
+	FilterPagination *pagination       `json:"filter_pagination,omitempty"`
+}
+
+// handleGetIncidentLogs handles the get_incident_logs tool call
+func (s *Server) handleGetIncidentLogs(ctx context.Context, params getIncidentLogsParams) (*getIncidentLogsResult, error) {
+	store, ok := s.storage.(storage.IncidentStore)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support incidents")
+	}
+
+	incident, err := store.GetIncident(ctx, params.IncidentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get incident: %w", err)
+	}
+	if incident == nil {
+		return nil, fmt.Errorf("incident %s does not exist", params.IncidentID)
+	}
+
+	result := &getIncidentLogsResult{Incident: *incident, AttachedLogs: []models.LogEntry{}}
+
+	ids := incident.LogIDs
+	if len(ids) > params.Limit {
+		ids = ids[:params.Limit]
+	}
+	if len(ids) > 0 {
+		logs, err := s.storage.GetByIDs(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attached logs: %w", err)
+		}
+		found := make(map[string]bool, len(logs))
+		for _, entry := range logs {
+			found[entry.ID] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				result.MissingLogIDs = append(result.MissingLogIDs, id)
+			}
+		}
+		result.AttachedLogs = s.applyFieldMasking(&models.LogResult{Logs: logs, TotalCount: len(logs)}, params.MaskFields).Logs
+	}
+
+	if incident.Filter != nil {
+		filter := *incident.Filter
+		filter.Limit = params.Limit
+		filter.Offset = params.Offset
+
+		matches, err := s.storage.Query(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query incident filter: %w", err)
+		}
+		matches = s.applyFieldMasking(matches, params.MaskFields)
+
+		result.FilterLogs = matches.Logs
+		result.FilterPagination = &pagination{
+			TotalCount: matches.TotalCount,
+			HasMore:    matches.HasMore,
+			Limit:      filter.Limit,
+			Offset:     filter.Offset,
+		}
+	}
+	countResults(ctx, len(result.AttachedLogs)+len(result.FilterLogs))
+
+	return result, nil
 }
 
 // handleGetServiceStatus handles the get_service_status tool call
-func (s *Server) handleGetServiceStatus(ctx context.Context, arguments interface{}) (*ToolResult, error) {
+func (s *Server) handleGetServiceStatus(ctx context.Context, _ noParams) (map[string]interface{}, error) {
 	// Get storage health status
 	storageStatus := s.storage.HealthCheck(ctx)
+	toolNames := s.tools.names()
 
 	// Create comprehensive system health report
 	systemHealth := map[string]interface{}{
@@ -608,47 +1980,28 @@ func (s *Server) handleGetServiceStatus(ctx context.Context, arguments interface
 			"mcp_server": map[string]interface{}{
 				"status":      "healthy",
 				"port":        s.port,
-				"tools_count": len(s.tools),
-				"tools":       s.getToolNames(),
+				"tools_count": len(toolNames),
+				"tools":       toolNames,
 			},
 		},
 		"metrics": s.getSystemMetrics(ctx),
 	}
+	if s.options.ResultCache != nil {
+		systemHealth["result_cache"] = s.options.ResultCache.Stats()
+	}
 
 	// Determine overall status based on components
 	if storageStatus.Status != "healthy" {
 		systemHealth["overall_status"] = "degraded"
 	}
 
-	// Format result as JSON text
-	resultJSON, err := json.MarshalIndent(systemHealth, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return &ToolResult{
-		Content: []ContentBlock{
-			{
-				Type: "text",
-				Text: string(resultJSON),
-			},
-		},
-	}, nil
-}
-
-// getToolNames returns a list of available tool names
-func (s *Server) getToolNames() []string {
-	names := make([]string, 0, len(s.tools))
-	for name := range s.tools {
-		names = append(names, name)
-	}
-	return names
+	return systemHealth, nil
 }
 
 // getSystemMetrics returns basic system metrics
 func (s *Server) getSystemMetrics(ctx context.Context) map[string]interface{} {
 	// Get basic metrics from storage
-	services, err := s.storage.GetServices(ctx)
+	services, err := s.storage.GetServices(ctx, models.ServiceFilter{})
 	if err != nil {
 		return map[string]interface{}{
 			"error": "failed to get metrics",
@@ -658,61 +2011,590 @@ func (s *Server) getSystemMetrics(ctx context.Context) map[string]interface{} {
 	totalLogCount := 0
 	platformCounts := make(map[string]int)
 
-	for _, service := range services {
-		totalLogCount += service.LogCount
-		platformCounts[string(service.Platform)]++
+	for platform, count := range services.Platforms {
+		totalLogCount += count.Logs
+		platformCounts[string(platform)] = count.Services
 	}
 
 	return map[string]interface{}{
-		"total_services":  len(services),
+		"total_services":  services.TotalCount,
 		"total_log_count": totalLogCount,
 		"platform_counts": platformCounts,
 		"uptime_seconds":  time.Since(time.Now().Add(-time.Hour)).Seconds(), // Mock uptime
 	}
 }
 
+// queryCrashesParams are the arguments of the query_crashes tool
+type queryCrashesParams struct {
+	logFilterParams
+	Signature string `json:"signature"`
+	Limit     int    `json:"limit" validate:"min=1,max=100"`
+	Offset    int    `json:"offset" validate:"min=0"`
+}
+
+func (p *queryCrashesParams) setDefaults() {
+	p.Limit = storage.DefaultCrashGroupLimit
+}
+
+// handleQueryCrashes handles the query_crashes tool call
+func (s *Server) handleQueryCrashes(ctx context.Context, params queryCrashesParams) (map[string]interface{}, error) {
+	if err := s.checkPlatform(params.Platform); err != nil {
+		return nil, err
+	}
+	filter := params.filter()
+	filter.CrashesOnly = true
+	filter.Limit = params.Limit
+	filter.Offset = params.Offset
+
+	if params.Signature != "" {
+		filter.CrashSignature = params.Signature
+		result, err := s.storage.Query(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query crashes: %w", err)
+		}
+		countResults(ctx, len(result.Logs))
+		return map[string]interface{}{
+			"signature": params.Signature,
+			"crashes":   result.Logs,
+			"pagination": pagination{
+				TotalCount: result.TotalCount,
+				HasMore:    result.HasMore,
+				Limit:      filter.Limit,
+				Offset:     filter.Offset,
+			},
+		}, nil
+	}
+
+	grouper, ok := s.storage.(storage.CrashGrouper)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support crash grouping")
+	}
+	groups, err := grouper.GroupCrashes(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group crashes: %w", err)
+	}
+	return map[string]interface{}{
+		"groups":      groups,
+		"total_count": len(groups),
+	}, nil
+}
+
+// getUsageParams are the arguments of the get_usage tool
+type getUsageParams struct {
+	StartDay    string `json:"start_day" validate:"omitempty,datetime=2006-01-02"`
+	EndDay      string `json:"end_day" validate:"omitempty,datetime=2006-01-02"`
+	APIKeyID    string `json:"api_key_id"`
+	ServiceName string `json:"service_name"`
+	Daily       bool   `json:"daily"`
+	Limit       int    `json:"limit" validate:"omitempty,min=1,max=1000"`
+}
+
+// getUsageResult is the result of the get_usage tool
+type getUsageResult struct {
+	Usage        []models.UsageRecord `json:"usage"`
+	TotalCount   int                  `json:"total_count"`
+	TotalEntries int64                `json:"total_entries"`
+	TotalBytes   int64                `json:"total_bytes"`
+}
+
+// handleGetUsage handles the get_usage tool call
+func (s *Server) handleGetUsage(ctx context.Context, params getUsageParams) (*getUsageResult, error) {
+	store, ok := s.storage.(storage.UsageStore)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support usage accounting")
+	}
+
+	records, err := store.QueryUsage(ctx, models.UsageFilter{
+		StartDay:    params.StartDay,
+		EndDay:      params.EndDay,
+		APIKeyID:    params.APIKeyID,
+		ServiceName: params.ServiceName,
+		Daily:       params.Daily,
+		Limit:       params.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	result := &getUsageResult{Usage: records, TotalCount: len(records)}
+	for _, record := range records {
+		result.TotalEntries += record.Entries
+		result.TotalBytes += record.Bytes
+	}
+	return result, nil
+}
+
+// errorRateWindow counts the entries and errors of a service in one window
+type errorRateWindow struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Total     int       `json:"total"`
+	Errors    int       `json:"errors"`
+	Fatal     int       `json:"fatal"`
+	Compacted int       `json:"compacted,omitempty"` // Entries of the total counted from summaries, by the hour they fall in
+	ErrorRate float64   `json:"error_rate"`          // ERROR and FATAL entries of all entries, 0 without entries
+}
+
+// countErrors counts the entries and errors of a service between two times, both inclusive
+func (s *Server) countErrors(ctx context.Context, serviceName string, start, end time.Time) (*errorRateWindow, error) {
+	window := &errorRateWindow{StartTime: start, EndTime: end}
+	counts := map[models.LogLevel]*int{"": &window.Total, models.LogLevelError: &window.Errors, models.LogLevelFatal: &window.Fatal}
+	for level, count := range counts {
+		result, err := s.storage.Query(ctx, models.LogFilter{
+			ServiceName: serviceName,
+			Level:       level,
+			StartTime:   start,
+			EndTime:     end,
+			Limit:       1,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count logs: %w", err)
+		}
+		*count = result.TotalCount
+	}
+
+	// Compacted entries no longer exist but still count towards the rate
+	if compactor, ok := s.storage.(storage.LogCompactor); ok {
+		compacted, err := compactor.SummaryCounts(ctx, models.SummaryFilter{
+			ServiceName: serviceName,
+			StartTime:   start,
+			EndTime:     end,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count summaries: %w", err)
+		}
+		for level, count := range compacted {
+			window.Compacted += int(count)
+			switch level {
+			case models.LogLevelError:
+				window.Errors += int(count)
+			case models.LogLevelFatal:
+				window.Fatal += int(count)
+			}
+		}
+		window.Total += window.Compacted
+	}
+
+	if window.Total > 0 {
+		window.ErrorRate = float64(window.Errors+window.Fatal) / float64(window.Total)
+	}
+	return window, nil
+}
+
+// getErrorRateParams are the arguments of the get_error_rate tool
+type getErrorRateParams struct {
+	ServiceName string       `json:"service_name" validate:"required"`
+	Window      duration     `json:"window" validate:"gt=0"`
+	EndTime     timeArgument `json:"end_time"` // Now when omitted
+	TimeZone    string       `json:"time_zone"`
+	SLOTarget   *float64     `json:"slo_target" validate:"omitempty,gt=0,lt=1"`
+
+	endTime time.Time // Resolved from EndTime
+}
+
+func (p getErrorRateParams) cacheService() string {
+	return p.ServiceName
+}
+
+func (p *getErrorRateParams) resolveTimes(now time.Time) error {
+	loc, err := loadTimeZone(p.TimeZone)
+	if err != nil {
+		return err
+	}
+	p.endTime, err = parseTimeArgument("end_time", p.EndTime, now, loc)
+	return err
+}
+
+func (p *getErrorRateParams) setDefaults() {
+	p.Window = duration(defaultErrorRateWindow)
+}
+
+// getErrorRateResult is the result of the get_error_rate tool
+type getErrorRateResult struct {
+	ServiceName string           `json:"service_name"`
+	Window      string           `json:"window"`
+	Current     *errorRateWindow `json:"current"`
+	Previous    *errorRateWindow `json:"previous"`
+	Trend       string           `json:"trend"`
+	Change      *float64         `json:"change,omitempty"` // Current minus previous error rate, when both windows have entries
+	SLO         *sloReport       `json:"slo,omitempty"`
+}
+
+// sloReport is the error budget of an SLO target in the current window
+type sloReport struct {
+	Target          float64 `json:"target"`
+	ErrorBudget     float64 `json:"error_budget"`
+	BudgetUsed      float64 `json:"budget_used"` // Above 1 when the budget is exhausted
+	BudgetRemaining float64 `json:"budget_remaining"`
+	Met             bool    `json:"met"`
+}
+
+// handleGetErrorRate handles the get_error_rate tool call
+func (s *Server) handleGetErrorRate(ctx context.Context, params getErrorRateParams) (*getErrorRateResult, error) {
+	window := time.Duration(params.Window)
+	end := params.endTime
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+
+	current, err := s.countErrors(ctx, params.ServiceName, end.Add(-window), end)
+	if err != nil {
+		return nil, err
+	}
+	// The previous window ends just before the current one so no entry is counted twice
+	previous, err := s.countErrors(ctx, params.ServiceName, end.Add(-2*window), end.Add(-window-time.Nanosecond))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &getErrorRateResult{
+		ServiceName: params.ServiceName,
+		Window:      window.String(),
+		Current:     current,
+		Previous:    previous,
+		Trend:       errorRateTrend(current, previous),
+	}
+	if previous.Total > 0 && current.Total > 0 {
+		change := current.ErrorRate - previous.ErrorRate
+		result.Change = &change
+	}
+
+	if params.SLOTarget != nil {
+		budget := 1 - *params.SLOTarget
+		result.SLO = &sloReport{
+			Target:          *params.SLOTarget,
+			ErrorBudget:     budget,
+			BudgetUsed:      current.ErrorRate / budget,
+			BudgetRemaining: math.Max(0, 1-current.ErrorRate/budget),
+			Met:             current.ErrorRate <= budget,
+		}
+	}
+	return result, nil
+}
+
+// errorRateTrend compares the error rates of two windows: improving, worsening or stable, or
+// no_data when either window has no entries
+func errorRateTrend(current, previous *errorRateWindow) string {
+	if current.Total == 0 || previous.Total == 0 {
+		return "no_data"
+	}
+
+	change := current.ErrorRate - previous.ErrorRate
+	switch {
+	case change > errorRateTrendThreshold:
+		return "worsening"
+	case change < -errorRateTrendThreshold:
+		return "improving"
+	default:
+		return "stable"
+	}
+}
+
+// diffTimeWindowsParams are the arguments of the diff_time_windows tool
+type diffTimeWindowsParams struct {
+	ServiceName     string       `json:"service_name" validate:"required"`
+	Window          duration     `json:"window" validate:"gt=0"`
+	EndTime         timeArgument `json:"end_time"`          // Now when omitted
+	BaselineEndTime timeArgument `json:"baseline_end_time"` // A day before the end time when omitted
+	TimeZone        string       `json:"time_zone"`
+	Limit           int          `json:"limit" validate:"min=1,max=100"`
+	MaskFields      []string     `json:"mask_fields"`
+
+	endTime, baselineEndTime time.Time // Resolved from EndTime and BaselineEndTime
+}
+
+func (p diffTimeWindowsParams) cacheService() string {
+	return p.ServiceName
+}
+
+func (p *diffTimeWindowsParams) resolveTimes(now time.Time) error {
+	loc, err := loadTimeZone(p.TimeZone)
+	if err != nil {
+		return err
+	}
+	if p.endTime, err = parseTimeArgument("end_time", p.EndTime, now, loc); err != nil {
+		return err
+	}
+	p.baselineEndTime, err = parseTimeArgument("baseline_end_time", p.BaselineEndTime, now, loc)
+	return err
+}
+
+func (p *diffTimeWindowsParams) setDefaults() {
+	p.Window = duration(defaultDiffWindow)
+	p.Limit = 20
+}
+
+const (
+	// defaultDiffWindow is the window diff_time_windows compares when none is given
+	defaultDiffWindow = time.Hour
+
+	// defaultBaselineOffset is how long before the window diff_time_windows' baseline ends by default
+	defaultBaselineOffset = 24 * time.Hour
+)
+
+// timeWindowSummary describes the entries of a service in one window of diff_time_windows
+type timeWindowSummary struct {
+	StartTime    time.Time               `json:"start_time"`
+	EndTime      time.Time               `json:"end_time"`
+	Total        int                     `json:"total"`
+	Levels       map[models.LogLevel]int `json:"levels"`
+	Templates    int                     `json:"templates"` // Distinct message templates of the scanned entries
+	ScannedCount int                     `json:"scanned_count"`
+	Truncated    bool                    `json:"truncated"` // More than maxGroupedEntries entries, older ones were not compared
+}
+
+// levelChange compares the entries of a level in both windows of diff_time_windows
+type levelChange struct {
+	Level         models.LogLevel `json:"level"`
+	Current       int             `json:"current"`
+	Baseline      int             `json:"baseline"`
+	CurrentShare  float64         `json:"current_share"`  // Of all entries in the window, 0 without entries
+	BaselineShare float64         `json:"baseline_share"` // Of all entries in the baseline window
+	ShareChange   float64         `json:"share_change"`   // Current minus baseline share
+}
+
+// diffTimeWindowsResult is the result of the diff_time_windows tool
+type diffTimeWindowsResult struct {
+	ServiceName              string                `json:"service_name"`
+	Window                   string                `json:"window"`
+	Current                  *timeWindowSummary    `json:"current"`
+	Baseline                 *timeWindowSummary    `json:"baseline"`
+	LevelChanges             []levelChange         `json:"level_changes"` // Levels with entries in either window, least severe first
+	NewTemplates             []models.MessageGroup `json:"new_templates"`
+	NewTemplateCount         int                   `json:"new_template_count"`
+	DisappearedTemplates     []models.MessageGroup `json:"disappeared_templates"` // Represented by their last entry in the baseline
+	DisappearedTemplateCount int                   `json:"disappeared_template_count"`
+}
+
+// handleDiffTimeWindows handles the diff_time_windows tool call
+func (s *Server) handleDiffTimeWindows(ctx context.Context, params diffTimeWindowsParams) (*diffTimeWindowsResult, error) {
+	window := time.Duration(params.Window)
+	end := params.endTime
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	baselineEnd := params.baselineEndTime
+	if baselineEnd.IsZero() {
+		baselineEnd = end.Add(-defaultBaselineOffset)
+	}
+
+	current, currentGroups, err := s.summarizeWindow(ctx, params.ServiceName, end.Add(-window), end)
+	if err != nil {
+		return nil, err
+	}
+	baseline, baselineGroups, err := s.summarizeWindow(ctx, params.ServiceName, baselineEnd.Add(-window), baselineEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &diffTimeWindowsResult{
+		ServiceName:  params.ServiceName,
+		Window:       window.String(),
+		Current:      current,
+		Baseline:     baseline,
+		LevelChanges: []levelChange{},
+	}
+	for _, level := range logLevels {
+		change := levelChange{Level: level, Current: current.Levels[level], Baseline: baseline.Levels[level]}
+		if change.Current == 0 && change.Baseline == 0 {
+			continue
+		}
+		if current.Total > 0 {
+			change.CurrentShare = float64(change.Current) / float64(current.Total)
+		}
+		if baseline.Total > 0 {
+			change.BaselineShare = float64(change.Baseline) / float64(baseline.Total)
+		}
+		change.ShareChange = change.CurrentShare - change.BaselineShare
+		result.LevelChanges = append(result.LevelChanges, change)
+	}
+
+	// Groups are ordered largest first, so the differences are as well
+	newTemplates := templatesMissingFrom(currentGroups, baselineGroups)
+	disappeared := templatesMissingFrom(baselineGroups, currentGroups)
+	result.NewTemplateCount = len(newTemplates)
+	result.DisappearedTemplateCount = len(disappeared)
+	result.NewTemplates = s.maskGroups(newTemplates[:min(params.Limit, len(newTemplates))], params.MaskFields)
+	result.DisappearedTemplates = s.maskGroups(disappeared[:min(params.Limit, len(disappeared))], params.MaskFields)
+	countResults(ctx, countGroups(result.NewTemplates, result.DisappearedTemplates))
+	return result, nil
+}
+
+// summarizeWindow counts the entries of a service per level between two times, both inclusive,
+// and groups the most recent of them by message template
+func (s *Server) summarizeWindow(ctx context.Context, serviceName string, start, end time.Time) (*timeWindowSummary, []models.MessageGroup, error) {
+	filter := models.LogFilter{ServiceName: serviceName, StartTime: start, EndTime: end}
+
+	summary := &timeWindowSummary{StartTime: start, EndTime: end, Levels: make(map[models.LogLevel]int)}
+	for _, level := range logLevels {
+		levelFilter := filter
+		levelFilter.Level = level
+		levelFilter.Limit = 1
+		result, err := s.storage.Query(ctx, levelFilter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to count logs: %w", err)
+		}
+		if result.TotalCount > 0 {
+			summary.Levels[level] = result.TotalCount
+			summary.Total += result.TotalCount
+		}
+	}
+
+	entries, _, truncated, err := s.scanLogs(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	groups := models.GroupByTemplate(entries)
+	summary.Templates = len(groups)
+	summary.ScannedCount = len(entries)
+	summary.Truncated = truncated
+	return summary, groups, nil
+}
+
+// templatesMissingFrom returns the groups whose template none of the other groups has
+func templatesMissingFrom(groups, other []models.MessageGroup) []models.MessageGroup {
+	templates := make(map[string]bool, len(other))
+	for _, group := range other {
+		templates[group.TemplateID] = true
+	}
+	missing := make([]models.MessageGroup, 0)
+	for _, group := range groups {
+		if !templates[group.TemplateID] {
+			missing = append(missing, group)
+		}
+	}
+	return missing
+}
+
+// queryLogSummariesParams are the arguments of the query_log_summaries tool
+type queryLogSummariesParams struct {
+	ServiceName string          `json:"service_name"`
+	Level       models.LogLevel `json:"level" validate:"omitempty,oneof=DEBUG INFO WARN ERROR FATAL"`
+	StartTime   timeArgument    `json:"start_time"`
+	EndTime     timeArgument    `json:"end_time"`
+	TimeZone    string          `json:"time_zone"`
+	Limit       int             `json:"limit" validate:"omitempty,min=1,max=1000"`
+
+	startTime, endTime time.Time // Resolved from StartTime and EndTime
+}
+
+func (p *queryLogSummariesParams) resolveTimes(now time.Time) error {
+	loc, err := loadTimeZone(p.TimeZone)
+	if err != nil {
+		return err
+	}
+	if p.startTime, err = parseTimeArgument("start_time", p.StartTime, now, loc); err != nil {
+		return err
+	}
+	p.endTime, err = parseTimeArgument("end_time", p.EndTime, now, loc)
+	return err
+}
+
+// queryLogSummariesResult is the result of the query_log_summaries tool
+type queryLogSummariesResult struct {
+	Summaries    []models.LogSummary `json:"summaries"`
+	TotalCount   int                 `json:"total_count"`
+	TotalEntries int64               `json:"total_entries"`
+}
+
+// handleQueryLogSummaries handles the query_log_summaries tool call
+func (s *Server) handleQueryLogSummaries(ctx context.Context, params queryLogSummariesParams) (*queryLogSummariesResult, error) {
+	compactor, ok := s.storage.(storage.LogCompactor)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support compaction")
+	}
+
+	summaries, err := compactor.QuerySummaries(ctx, models.SummaryFilter{
+		ServiceName: params.ServiceName,
+		Level:       params.Level,
+		StartTime:   params.startTime,
+		EndTime:     params.endTime,
+		Limit:       params.Limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summaries: %w", err)
+	}
+
+	result := &queryLogSummariesResult{Summaries: summaries, TotalCount: len(summaries)}
+	for _, summary := range summaries {
+		result.TotalEntries += summary.Count
+	}
+	return result, nil
+}
+
+// listServicesParams are the arguments of the list_services tool
+type listServicesParams struct {
+	NamePrefix    string             `json:"name_prefix"`
+	Platform      models.Platform    `json:"platform"`
+	ActiveWithin  duration           `json:"active_within" validate:"min=0"`
+	IncludeHidden bool               `json:"include_hidden"`
+	Sort          models.ServiceSort `json:"sort" validate:"omitempty,oneof=last_seen name log_count"`
+	Limit         int                `json:"limit" validate:"min=1,max=1000"`
+	Offset        int                `json:"offset" validate:"min=0"`
+}
+
+func (p *listServicesParams) setDefaults() {
+	p.Limit = 100
+}
+
 // handleListServices handles the list_services tool call
-func (s *Server) handleListServices(ctx context.Context, arguments interface{}) (*ToolResult, error) {
-	services, err := s.storage.GetServices(ctx)
+func (s *Server) handleListServices(ctx context.Context, params listServicesParams) (map[string]interface{}, error) {
+	if err := s.checkPlatform(params.Platform); err != nil {
+		return nil, err
+	}
+
+	filter := models.ServiceFilter{
+		NamePrefix: params.NamePrefix,
+		Platform:   params.Platform,
+		Sort:       params.Sort,
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+	}
+	now := time.Now()
+	if params.ActiveWithin > 0 {
+		filter.ActiveSince = now.Add(-time.Duration(params.ActiveWithin))
+	}
+	if s.options.ServiceHideAfter > 0 && !params.IncludeHidden {
+		if hideBefore := now.Add(-s.options.ServiceHideAfter); hideBefore.After(filter.ActiveSince) {
+			filter.ActiveSince = hideBefore
+		}
+	}
+	if s.options.ServiceStaleAfter > 0 {
+		filter.StaleBefore = now.Add(-s.options.ServiceStaleAfter)
+	}
+
+	result, err := s.storage.GetServices(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get services: %w", err)
 	}
 
 	// Create enhanced service listing with summary
 	serviceList := map[string]interface{}{
-		"services": services,
+		"services": result.Services,
+		"pagination": pagination{
+			TotalCount: result.TotalCount,
+			HasMore:    result.HasMore,
+			Limit:      params.Limit,
+			Offset:     params.Offset,
+		},
 		"summary": map[string]interface{}{
-			"total_services": len(services),
-			"platforms":      s.getPlatformSummary(services),
+			"total_services": result.TotalCount,
+			"platforms":      s.getPlatformSummary(result.Platforms),
 			"last_updated":   time.Now(),
 		},
 	}
 
-	// Format result as JSON text
-	resultJSON, err := json.MarshalIndent(serviceList, "", "  ")
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %w", err)
-	}
-
-	return &ToolResult{
-		Content: []ContentBlock{
-			{
-				Type: "text",
-				Text: string(resultJSON),
-			},
-		},
-	}, nil
+	return serviceList, nil
 }
 
 // getPlatformSummary creates a summary of services by platform
-func (s *Server) getPlatformSummary(services []models.ServiceInfo) map[string]interface{} {
+func (s *Server) getPlatformSummary(platforms map[models.Platform]models.PlatformCount) map[string]interface{} {
 	platformCounts := make(map[string]int)
 	platformLogCounts := make(map[string]int)
 
-	for _, service := range services {
-		platform := string(service.Platform)
-		platformCounts[platform]++
-		platformLogCounts[platform] += service.LogCount
+	for platform, count := range platforms {
+		platformCounts[string(platform)] = count.Services
+		platformLogCounts[string(platform)] = count.Logs
 	}
 
 	return map[string]interface{}{
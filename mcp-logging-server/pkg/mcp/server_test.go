@@ -3,10 +3,18 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
 )
 
 // MockStorage implements storage.LogStorage for testing
@@ -55,6 +63,10 @@ func (m *MockStorage) Query(ctx context.Context, filter models.LogFilter) (*mode
 	}, nil
 }
 
+func (m *MockStorage) QueryStream(ctx context.Context, filter models.LogFilter) (storage.LogIterator, error) {
+	return storage.NewSliceIterator(m.logs), nil
+}
+
 func (m *MockStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
 	var result []models.LogEntry
 	for _, log := range m.logs {
@@ -68,8 +80,8 @@ func (m *MockStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogE
 	return result, nil
 }
 
-func (m *MockStorage) GetServices(ctx context.Context) ([]models.ServiceInfo, error) {
-	return m.services, nil
+func (m *MockStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	return &models.ServiceResult{Services: m.services, TotalCount: len(m.services)}, nil
 }
 
 func (m *MockStorage) HealthCheck(ctx context.Context) models.HealthStatus {
@@ -97,14 +109,58 @@ func TestNewServer(t *testing.T) {
 	}
 
 	// Check that tools are registered
-	expectedTools := []string{"query_logs", "get_log_details", "get_service_status", "list_services"}
+	expectedTools := []string{"query_logs", "search_logs", "get_log_details", "get_service_status", "list_services", "query_crashes", "get_usage", "get_error_rate", "diff_time_windows", "query_log_summaries", "annotate_log", "get_incident_logs", "set_context"}
 	for _, toolName := range expectedTools {
-		if _, exists := server.tools[toolName]; !exists {
+		if _, exists := server.tools.get(toolName); !exists {
 			t.Errorf("Tool %s not registered", toolName)
 		}
 	}
 }
 
+func TestServer_StartHost(t *testing.T) {
+	// An address of this machine other machines could connect to
+	var external net.IP
+	addrs, _ := net.InterfaceAddrs()
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			external = ipNet.IP
+			break
+		}
+	}
+	if external == nil {
+		t.Skip("No non-loopback IPv4 address")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	server := NewServerWithOptions(port, &MockStorage{}, Options{Host: "127.0.0.1"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.Start(ctx)
+
+	for i := 0; i < 50; i++ {
+		var conn net.Conn
+		if conn, err = net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port)); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Server did not start: %v", err)
+	}
+
+	if conn, err := net.DialTimeout("tcp", fmt.Sprintf("%s:%d", external, port), time.Second); err == nil {
+		conn.Close()
+		t.Errorf("Expected the server not to listen on %s:%d", external, port)
+	}
+}
+
 func TestHandleInitialize(t *testing.T) {
 	storage := &MockStorage{}
 	server := NewServer(8081, storage)
@@ -115,7 +171,7 @@ func TestHandleInitialize(t *testing.T) {
 		Method:  "initialize",
 	}
 
-	response := server.handleInitialize(msg)
+	response := server.handleInitialize(context.Background(), msg)
 
 	if response.JSONRPC != "2.0" {
 		t.Errorf("Expected JSONRPC 2.0, got %s", response.JSONRPC)
@@ -149,7 +205,7 @@ func TestHandleToolsList(t *testing.T) {
 		Method:  "tools/list",
 	}
 
-	response := server.handleToolsList(msg)
+	response := server.handleToolsList(context.Background(), msg)
 
 	if response.Error != nil {
 		t.Errorf("Expected no error, got %v", response.Error)
@@ -165,8 +221,8 @@ func TestHandleToolsList(t *testing.T) {
 		t.Fatal("Tools is not a slice of Tool")
 	}
 
-	if len(tools) != 4 {
-		t.Errorf("Expected 4 tools, got %d", len(tools))
+	if len(tools) != 14 {
+		t.Errorf("Expected 14 tools, got %d", len(tools))
 	}
 
 	// Check that all expected tools are present
@@ -175,7 +231,7 @@ func TestHandleToolsList(t *testing.T) {
 		toolNames[tool.Name] = true
 	}
 
-	expectedTools := []string{"query_logs", "get_log_details", "get_service_status", "list_services"}
+	expectedTools := []string{"query_logs", "get_log_details", "get_log_context", "get_service_status", "list_services", "query_crashes", "get_usage", "get_error_rate", "query_log_summaries", "annotate_log", "get_incident_logs", "set_context"}
 	for _, expected := range expectedTools {
 		if !toolNames[expected] {
 			t.Errorf("Expected tool %s not found", expected)
@@ -206,7 +262,7 @@ func TestHandleQueryLogs(t *testing.T) {
 		"limit":        float64(10),
 	}
 
-	result, err := server.handleQueryLogs(context.Background(), arguments)
+	result, err := server.callTool(context.Background(), "query_logs", arguments)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -266,7 +322,7 @@ func TestHandleQueryLogsWithFieldMasking(t *testing.T) {
 		"mask_fields":  []interface{}{"message", "agent_id", "user_id"},
 	}
 
-	result, err := server.handleQueryLogs(context.Background(), arguments)
+	result, err := server.callTool(context.Background(), "query_logs", arguments)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -337,7 +393,7 @@ func TestHandleGetLogDetails(t *testing.T) {
 		"ids": []interface{}{"log-1"},
 	}
 
-	result, err := server.handleGetLogDetails(context.Background(), arguments)
+	result, err := server.callTool(context.Background(), "get_log_details", arguments)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -361,6 +417,153 @@ func TestHandleGetLogDetails(t *testing.T) {
 	}
 }
 
+func TestHandleQueryLogs_GroupByMessageTemplate(t *testing.T) {
+	ctx := context.Background()
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	now := time.Now()
+	var entries []models.LogEntry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   now.Add(-time.Duration(i) * time.Minute),
+			Level:       models.LogLevelError,
+			Message:     fmt.Sprintf("Payment %s failed after %d ms", uuid.New().String(), 100+i),
+			ServiceName: "checkout",
+			AgentID:     "agent-1",
+			Platform:    models.PlatformGo,
+		})
+	}
+	entries = append(entries, models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   now,
+		Level:       models.LogLevelInfo,
+		Message:     "Cache warmed",
+		ServiceName: "checkout",
+		AgentID:     "agent-1",
+		Platform:    models.PlatformGo,
+	})
+	if err := memoryStorage.Store(ctx, entries); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	server := NewServer(8081, memoryStorage)
+	result, err := server.callTool(ctx, "query_logs", map[string]interface{}{"group_by": "message_template", "limit": float64(1)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var grouped groupedLogsResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &grouped); err != nil {
+		t.Fatalf("Failed to parse result JSON: %v", err)
+	}
+	if len(grouped.Groups) != 1 || grouped.Pagination.TotalCount != 2 || !grouped.Pagination.HasMore {
+		t.Fatalf("Expected the first of 2 groups, got %+v", grouped)
+	}
+	group := grouped.Groups[0]
+	if group.Template != "Payment <uuid> failed after <num> ms" || group.Count != 5 || group.Entry.ID != entries[0].ID {
+		t.Errorf("Unexpected group %+v", group)
+	}
+	if grouped.ScannedCount != 6 || grouped.Truncated {
+		t.Errorf("Expected all 6 entries scanned, got %d (truncated %v)", grouped.ScannedCount, grouped.Truncated)
+	}
+
+	if _, err := server.callTool(ctx, "query_logs", map[string]interface{}{"group_by": "service"}); err == nil {
+		t.Error("Expected an unknown group_by to fail")
+	}
+}
+
+func TestHandleQueryLogs_RetentionWarnings(t *testing.T) {
+	server := NewServerWithOptions(8081, &MockStorage{}, Options{
+		Retention: storage.RetentionPolicy{
+			DefaultDays: 30,
+			ByLevel:     map[models.LogLevel]int{models.LogLevelDebug: 7, models.LogLevelError: 365},
+		},
+		CompactionAfter:  24 * time.Hour,
+		CompactionLevels: []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo},
+	})
+
+	testCases := []struct {
+		name      string
+		arguments map[string]interface{}
+		expected  []string
+	}{
+		{"no start time", map[string]interface{}{"level": "DEBUG"}, nil},
+		{"within retention", map[string]interface{}{"level": "ERROR", "start_time": "-30d"}, nil},
+		{"beyond level retention", map[string]interface{}{"level": "DEBUG", "start_time": "-10d"}, []string{"DEBUG logs older than 7 days are deleted"}},
+		{"compacted", map[string]interface{}{"level": "INFO", "start_time": "-2d"}, []string{"INFO logs older than 1 day are compacted into hourly summaries, see query_log_summaries"}},
+		{"all levels", map[string]interface{}{"start_time": "-40d"}, []string{
+			"DEBUG logs older than 7 days are deleted",
+			"INFO logs older than 30 days are deleted",
+			"WARN logs older than 30 days are deleted",
+			"FATAL logs older than 30 days are deleted",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := server.callTool(context.Background(), "query_logs", tc.arguments)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var response queryLogsResult
+			if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+				t.Fatalf("Failed to parse result JSON: %v", err)
+			}
+			if strings.Join(response.Warnings, "|") != strings.Join(tc.expected, "|") {
+				t.Errorf("Expected warnings %q, got %q", tc.expected, response.Warnings)
+			}
+		})
+	}
+}
+
+func TestHandleGetLogDetailsSymbolicatesStackTraces(t *testing.T) {
+	ctx := context.Background()
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	entry := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelError,
+		Message:     "Checkout failed",
+		ServiceName: "mobile-app",
+		AgentID:     "device-1",
+		Platform:    models.PlatformReactNative,
+		DeviceInfo:  &models.DeviceInfo{Platform: "ios", AppVersion: "2.0.0"},
+		StackTrace:  "    at c (main.jsbundle:1:6)",
+	}
+	if err := memoryStorage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+
+	// The source map is uploaded after the entry was stored
+	if _, err := memoryStorage.PutSymbolFile(ctx, models.SymbolFile{
+		ServiceName: "mobile-app",
+		Version:     "2.0.0",
+		Name:        "main.jsbundle",
+		Kind:        models.SymbolFileKindSourceMap,
+		Content:     []byte(`{"version": 3, "sources": ["src/cart.tsx"], "names": [], "mappings": "AAAA,KACE"}`),
+	}); err != nil {
+		t.Fatalf("Failed to store symbol file: %v", err)
+	}
+
+	server := NewServer(8081, memoryStorage)
+	result, err := server.callTool(ctx, "get_log_details", map[string]interface{}{"ids": []interface{}{entry.ID}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var logs []models.LogEntry
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &logs); err != nil {
+		t.Fatalf("Failed to parse result JSON: %v", err)
+	}
+	if len(logs) != 1 || logs[0].StackTrace != "    at c (src/cart.tsx:2:3)" {
+		t.Errorf("Expected the stack trace to be symbolicated on read, got %+v", logs)
+	}
+}
+
 func TestHandleGetLogDetailsWithFieldMasking(t *testing.T) {
 	storage := &MockStorage{
 		logs: []models.LogEntry{
@@ -383,7 +586,7 @@ func TestHandleGetLogDetailsWithFieldMasking(t *testing.T) {
 		"mask_fields": []interface{}{"message", "api_key"},
 	}
 
-	result, err := server.handleGetLogDetails(context.Background(), arguments)
+	result, err := server.callTool(context.Background(), "get_log_details", arguments)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -427,7 +630,7 @@ func TestHandleGetServiceStatus(t *testing.T) {
 	storage := &MockStorage{}
 	server := NewServer(8081, storage)
 
-	result, err := server.handleGetServiceStatus(context.Background(), nil)
+	result, err := server.callTool(context.Background(), "get_service_status", nil)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -473,7 +676,7 @@ func TestHandleListServices(t *testing.T) {
 	}
 	server := NewServer(8081, storage)
 
-	result, err := server.handleListServices(context.Background(), nil)
+	result, err := server.callTool(context.Background(), "list_services", nil)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -512,6 +715,135 @@ func TestHandleListServices(t *testing.T) {
 	}
 }
 
+func TestHandleListServices_Filters(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServerWithOptions(8081, memoryStorage, Options{Platforms: []string{"go", "swift"}})
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	newLog := func(service, agent string, platform models.Platform, at time.Time) models.LogEntry {
+		return models.LogEntry{ID: uuid.New().String(), Timestamp: at, Level: models.LogLevelInfo, Message: "m", ServiceName: service, AgentID: agent, Platform: platform}
+	}
+	err := memoryStorage.Store(ctx, []models.LogEntry{
+		newLog("checkout", "checkout-1", models.PlatformGo, now.Add(-time.Minute)),
+		newLog("checkout", "checkout-2", models.PlatformGo, now.Add(-48*time.Hour)),
+		newLog("cart", "cart-1", models.PlatformGo, now),
+		newLog("ios-app", "device-1", models.PlatformSwift, now.Add(-time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	type listResult struct {
+		Services   []models.ServiceInfo `json:"services"`
+		Pagination pagination           `json:"pagination"`
+		Summary    struct {
+			TotalServices int `json:"total_services"`
+			Platforms     struct {
+				ServiceCounts map[string]int `json:"service_counts"`
+			} `json:"platforms"`
+		} `json:"summary"`
+	}
+	list := func(arguments map[string]interface{}) listResult {
+		t.Helper()
+		result, err := server.callTool(ctx, "list_services", arguments)
+		if err != nil {
+			t.Fatalf("handleListServices failed: %v", err)
+		}
+		var response listResult
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		return response
+	}
+
+	response := list(map[string]interface{}{"name_prefix": "c", "active_within": "24h", "sort": "name"})
+	if len(response.Services) != 2 || response.Services[0].AgentID != "cart-1" || response.Services[1].AgentID != "checkout-1" {
+		t.Errorf("Expected cart-1 and checkout-1, got %+v", response.Services)
+	}
+
+	response = list(map[string]interface{}{"limit": 1, "offset": 1})
+	if len(response.Services) != 1 || response.Services[0].AgentID != "checkout-1" || !response.Pagination.HasMore || response.Pagination.TotalCount != 4 {
+		t.Errorf("Expected the second most recent agent with more to come, got %+v", response)
+	}
+	if response.Summary.TotalServices != 4 || response.Summary.Platforms.ServiceCounts["go"] != 3 {
+		t.Errorf("Expected the summary to cover all services, got %+v", response.Summary)
+	}
+
+	response = list(map[string]interface{}{"platform": "swift"})
+	if len(response.Services) != 1 || response.Services[0].ServiceName != "ios-app" {
+		t.Errorf("Expected the swift service, got %+v", response.Services)
+	}
+
+	invalid := []map[string]interface{}{
+		{"platform": "kotlin"},
+		{"sort": "platform"},
+		{"limit": 1001},
+		{"active_within": "yesterday"},
+	}
+	for _, arguments := range invalid {
+		if _, err := server.callTool(ctx, "list_services", arguments); err == nil {
+			t.Errorf("Expected an error for %v", arguments)
+		}
+	}
+}
+
+func TestHandleListServices_StaleAgents(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServerWithOptions(8081, memoryStorage, Options{ServiceStaleAfter: 24 * time.Hour, ServiceHideAfter: 7 * 24 * time.Hour})
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	newLog := func(agent string, at time.Time) models.LogEntry {
+		return models.LogEntry{ID: uuid.New().String(), Timestamp: at, Level: models.LogLevelInfo, Message: "m", ServiceName: "checkout", AgentID: agent, Platform: models.PlatformGo}
+	}
+	err := memoryStorage.Store(ctx, []models.LogEntry{
+		newLog("checkout-1", now),
+		newLog("checkout-2", now.Add(-48*time.Hour)),
+		newLog("checkout-3", now.Add(-30*24*time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	list := func(arguments map[string]interface{}) []models.ServiceInfo {
+		t.Helper()
+		result, err := server.callTool(ctx, "list_services", arguments)
+		if err != nil {
+			t.Fatalf("handleListServices failed: %v", err)
+		}
+		var response struct {
+			Services []models.ServiceInfo `json:"services"`
+		}
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		return response.Services
+	}
+
+	services := list(map[string]interface{}{})
+	if len(services) != 2 || services[0].Stale || !services[1].Stale {
+		t.Errorf("Expected checkout-1 and the stale checkout-2, got %+v", services)
+	}
+
+	services = list(map[string]interface{}{"include_hidden": true})
+	if len(services) != 3 || services[2].AgentID != "checkout-3" || !services[2].Stale {
+		t.Errorf("Expected the hidden checkout-3 to be included, got %+v", services)
+	}
+
+	// active_within narrows the listing further, but cannot reach past the hidden agents
+	if services = list(map[string]interface{}{"active_within": "1h"}); len(services) != 1 {
+		t.Errorf("Expected only checkout-1, got %+v", services)
+	}
+	if services = list(map[string]interface{}{"active_within": "720h"}); len(services) != 2 {
+		t.Errorf("Expected the hidden agent to stay hidden, got %+v", services)
+	}
+}
+
 func TestHandleMessage_UnknownMethod(t *testing.T) {
 	storage := &MockStorage{}
 	server := NewServer(8081, storage)
@@ -587,6 +919,35 @@ func TestHandleToolCall_InvalidParams(t *testing.T) {
 	}
 }
 
+func TestHandleToolCall_InvalidArguments(t *testing.T) {
+	storage := &MockStorage{}
+	server := NewServer(8081, storage)
+
+	msg := &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      "test-6",
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "query_logs",
+			"arguments": map[string]interface{}{"limit": "ten"},
+		},
+	}
+
+	response := server.handleToolCall(context.Background(), msg)
+
+	if response.Error == nil {
+		t.Fatal("Expected error for invalid arguments")
+	}
+
+	if response.Error.Code != -32602 {
+		t.Errorf("Expected error code -32602, got %d", response.Error.Code)
+	}
+
+	if response.Error.Message != "invalid argument limit: expected int" {
+		t.Errorf("Expected error message naming the argument, got %s", response.Error.Message)
+	}
+}
+
 func TestMCPProtocolCompliance(t *testing.T) {
 	// Test that all responses follow MCP protocol structure
 	storage := &MockStorage{}
@@ -665,59 +1026,182 @@ func TestMaskString(t *testing.T) {
 	}
 }
 
-func TestGetMaskedFields(t *testing.T) {
-	storage := &MockStorage{}
-	server := NewServer(8081, storage)
+func TestMaskString_CustomMasker(t *testing.T) {
+	server := NewServerWithOptions(8081, &MockStorage{}, Options{
+		Masking: &dataprotection.Masker{RevealChars: 3, Token: "*", RepeatToken: true, FullMaskThreshold: 6},
+	})
 
+	if result := server.maskString("sensitive-data-123"); result != "sen************123" {
+		t.Errorf("Expected configured masking, got '%s'", result)
+	}
+	if result := server.maskString("secret"); result != "******" {
+		t.Errorf("Expected full mask up to the threshold, got '%s'", result)
+	}
+}
+
+func TestDecodeArguments(t *testing.T) {
 	testCases := []struct {
-		name     string
-		args     map[string]interface{}
-		expected []string
+		name      string
+		args      interface{}
+		expected  []string
+		limit     int
+		errorText string
 	}{
 		{
-			name:     "no mask_fields",
-			args:     map[string]interface{}{},
-			expected: []string{},
-		},
-		{
-			name: "single field",
-			args: map[string]interface{}{
-				"mask_fields": []interface{}{"message"},
-			},
-			expected: []string{"message"},
+			name:     "no arguments",
+			args:     nil,
+			expected: nil,
+			limit:    100,
 		},
 		{
 			name: "multiple fields",
 			args: map[string]interface{}{
 				"mask_fields": []interface{}{"message", "agent_id", "user_id"},
+				"limit":       float64(10),
 			},
 			expected: []string{"message", "agent_id", "user_id"},
+			limit:    10,
 		},
 		{
 			name: "invalid mask_fields type",
 			args: map[string]interface{}{
 				"mask_fields": "not an array",
 			},
-			expected: []string{},
+			errorText: "invalid argument mask_fields: expected []string",
+		},
+		{
+			name: "limit out of range",
+			args: map[string]interface{}{
+				"limit": float64(5000),
+			},
+			errorText: "limit must be at most 1000",
+		},
+		{
+			name: "invalid level",
+			args: map[string]interface{}{
+				"level": "TRACE",
+			},
+			errorText: "level must be one of: DEBUG, INFO, WARN, ERROR, FATAL",
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result := server.getMaskedFields(tc.args)
-			if len(result) != len(tc.expected) {
-				t.Errorf("Expected %d fields, got %d", len(tc.expected), len(result))
+			var params queryLogsParams
+			err := decodeArguments(tc.args, &params)
+			if tc.errorText != "" {
+				var argErr *argumentError
+				if !errors.As(err, &argErr) {
+					t.Fatalf("Expected argument error, got %v", err)
+				}
+				if err.Error() != tc.errorText {
+					t.Errorf("Expected error '%s', got '%s'", tc.errorText, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if params.Limit != tc.limit {
+				t.Errorf("Expected limit %d, got %d", tc.limit, params.Limit)
+			}
+			if len(params.MaskFields) != len(tc.expected) {
+				t.Errorf("Expected %d fields, got %d", len(tc.expected), len(params.MaskFields))
 				return
 			}
 			for i, expected := range tc.expected {
-				if result[i] != expected {
-					t.Errorf("Expected field '%s' at index %d, got '%s'", expected, i, result[i])
+				if params.MaskFields[i] != expected {
+					t.Errorf("Expected field '%s' at index %d, got '%s'", expected, i, params.MaskFields[i])
 				}
 			}
 		})
 	}
 }
 
+func TestDecodeArguments_TimeExpressions(t *testing.T) {
+	before := time.Now()
+	var params queryLogsParams
+	if err := decodeArguments(map[string]interface{}{"start_time": "-2h", "end_time": "now"}, &params); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	filter := params.filter()
+	if filter.StartTime.Before(before.Add(-2*time.Hour)) || filter.StartTime.After(time.Now().Add(-2*time.Hour)) {
+		t.Errorf("Expected start_time two hours ago, got %v", filter.StartTime)
+	}
+	if filter.EndTime.Before(before) || filter.EndTime.After(time.Now()) {
+		t.Errorf("Expected end_time now, got %v", filter.EndTime)
+	}
+
+	params = queryLogsParams{}
+	if err := decodeArguments(map[string]interface{}{"start_time": "2024-05-01 09:00", "time_zone": "America/New_York"}, &params); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC); !params.filter().StartTime.Equal(expected) {
+		t.Errorf("Expected local time in New York %v, got %v", expected, params.filter().StartTime)
+	}
+
+	for args, errorText := range map[string]string{
+		"start_time": "start_time: invalid time",
+		"time_zone":  "unknown time zone",
+	} {
+		arguments := map[string]interface{}{"start_time": "-2h"}
+		arguments[args] = "last tuesday"
+		err := decodeArguments(arguments, &queryLogsParams{})
+		var argErr *argumentError
+		if !errors.As(err, &argErr) || !strings.Contains(err.Error(), errorText) {
+			t.Errorf("Expected argument error containing %q, got %v", errorText, err)
+		}
+	}
+}
+
+func TestHandleToolCall_InvalidArgumentData(t *testing.T) {
+	server := NewServerWithOptions(8081, &MockStorage{}, Options{Platforms: []string{"go", "swift"}})
+
+	testCases := []struct {
+		name      string
+		arguments map[string]interface{}
+		argument  string
+		accepted  []string
+	}{
+		{"invalid start_time", map[string]interface{}{"start_time": "last tuesday"}, "start_time", models.TimeExpressionFormats},
+		{"invalid level", map[string]interface{}{"level": "TRACE"}, "level", []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}},
+		{"invalid min_level", map[string]interface{}{"min_level": "TRACE"}, "min_level", []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}},
+		{"unknown platform", map[string]interface{}{"platform": "cobol"}, "platform", []string{"go", "swift"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := server.handleToolCall(context.Background(), &MCPMessage{
+				JSONRPC: "2.0",
+				ID:      "test-args",
+				Method:  "tools/call",
+				Params:  map[string]interface{}{"name": "query_logs", "arguments": tc.arguments},
+			})
+			if response.Error == nil || response.Error.Code != -32602 {
+				t.Fatalf("Expected an invalid params error, got %+v", response)
+			}
+			data, ok := response.Error.Data.(map[string]interface{})
+			if !ok || data["argument"] != tc.argument {
+				t.Fatalf("Expected error data naming %s, got %v", tc.argument, response.Error.Data)
+			}
+			if accepted, _ := data["accepted"].([]string); strings.Join(accepted, "|") != strings.Join(tc.accepted, "|") {
+				t.Errorf("Expected accepted %v, got %v", tc.accepted, data["accepted"])
+			}
+		})
+	}
+
+	// Platforms are not checked when ingestion accepts any
+	response := NewServer(8081, &MockStorage{}).handleToolCall(context.Background(), &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      "test-args",
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "query_logs", "arguments": map[string]interface{}{"platform": "cobol"}},
+	})
+	if response.Error != nil {
+		t.Errorf("Expected any platform to be accepted, got %v", response.Error.Message)
+	}
+}
+
 func TestApplyFieldMasking(t *testing.T) {
 	storage := &MockStorage{}
 	server := NewServer(8081, storage)
@@ -798,7 +1282,7 @@ func TestPaginationInformation(t *testing.T) {
 		"offset": float64(10),
 	}
 
-	result, err := server.handleQueryLogs(context.Background(), arguments)
+	result, err := server.callTool(context.Background(), "query_logs", arguments)
 	if err != nil {
 		t.Fatalf("Expected no error, got %v", err)
 	}
@@ -830,3 +1314,906 @@ func TestPaginationInformation(t *testing.T) {
 		t.Errorf("Expected has_more false, got %v", pagination["has_more"])
 	}
 }
+
+// SlowStorage blocks queries until the context is done
+type SlowStorage struct {
+	MockStorage
+}
+
+func (s *SlowStorage) Query(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestHandleToolCall_Timeout(t *testing.T) {
+	server := NewServerWithOptions(8081, &SlowStorage{}, Options{
+		DefaultTimeout: time.Second,
+		ToolTimeouts:   map[string]time.Duration{"query_logs": 20 * time.Millisecond},
+	})
+
+	msg := &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name":      "query_logs",
+			"arguments": map[string]interface{}{"service_name": "slow-service"},
+		},
+	}
+
+	response := server.handleToolCall(context.Background(), msg)
+	if response.Error == nil {
+		t.Fatal("Expected timeout error")
+	}
+	if response.Error.Code != errorCodeTimeout {
+		t.Errorf("Expected error code %d, got %d", errorCodeTimeout, response.Error.Code)
+	}
+
+	data, ok := response.Error.Data.(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected error data")
+	}
+	if data["tool"] != "query_logs" {
+		t.Errorf("Expected tool query_logs, got %v", data["tool"])
+	}
+	if data["timeout_ms"] != int64(20) {
+		t.Errorf("Expected timeout_ms 20, got %v", data["timeout_ms"])
+	}
+}
+
+func TestHandleToolCall_Cancelled(t *testing.T) {
+	server := NewServer(8081, &SlowStorage{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msg := &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "tools/call",
+		Params: map[string]interface{}{
+			"name": "query_logs",
+		},
+	}
+
+	response := server.handleToolCall(ctx, msg)
+	if response.Error == nil {
+		t.Fatal("Expected cancellation error")
+	}
+	if response.Error.Code != errorCodeCancelled {
+		t.Errorf("Expected error code %d, got %d", errorCodeCancelled, response.Error.Code)
+	}
+}
+
+func TestToolTimeout(t *testing.T) {
+	server := NewServerWithOptions(8081, &MockStorage{}, Options{
+		DefaultTimeout: 30 * time.Second,
+		ToolTimeouts:   map[string]time.Duration{"query_logs": 5 * time.Second},
+	})
+
+	if timeout := server.toolTimeout("query_logs"); timeout != 5*time.Second {
+		t.Errorf("Expected query_logs timeout 5s, got %v", timeout)
+	}
+	if timeout := server.toolTimeout("list_services"); timeout != 30*time.Second {
+		t.Errorf("Expected default timeout 30s, got %v", timeout)
+	}
+}
+
+// SearchingStorage implements storage.LogSearcher on top of MockStorage
+type SearchingStorage struct {
+	MockStorage
+}
+
+func (s *SearchingStorage) SearchLogs(ctx context.Context, queryText string, filter models.LogFilter) (*models.SearchResult, error) {
+	var hits []models.SearchHit
+	for _, entry := range s.logs {
+		start := strings.Index(entry.Message, queryText)
+		if start < 0 {
+			continue
+		}
+		hits = append(hits, models.SearchHit{
+			Log:   entry,
+			Score: 1,
+			Fragments: map[string][]string{
+				"message": {entry.Message[:start] + "<mark>" + queryText + "</mark>" + entry.Message[start+len(queryText):]},
+			},
+			Matches: []models.SearchMatch{
+				{Field: "message", Term: queryText, Start: start, End: start + len(queryText)},
+			},
+		})
+	}
+
+	facets := make(map[string][]models.FacetCount)
+	for _, name := range filter.Facets {
+		counts := make(map[string]int)
+		for _, hit := range hits {
+			switch name {
+			case models.FacetLevel:
+				counts[string(hit.Log.Level)]++
+			case models.FacetService:
+				counts[hit.Log.ServiceName]++
+			}
+		}
+		for value, count := range counts {
+			facets[name] = append(facets[name], models.FacetCount{Value: value, Count: count})
+		}
+	}
+
+	return &models.SearchResult{Hits: hits, TotalCount: len(hits), Facets: facets}, nil
+}
+
+func TestHandleSearchLogs(t *testing.T) {
+	searchStorage := &SearchingStorage{
+		MockStorage: MockStorage{
+			logs: []models.LogEntry{
+				{
+					ID:          "log-1",
+					Timestamp:   time.Now(),
+					Level:       models.LogLevelError,
+					Message:     "gateway timeout while charging card",
+					ServiceName: "payment-service",
+					AgentID:     "agent-1",
+					Platform:    models.PlatformGo,
+				},
+			},
+		},
+	}
+	server := NewServer(8081, searchStorage)
+
+	result, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{
+		"query": "timeout",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var response struct {
+		Hits []models.SearchHit `json:"hits"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result JSON: %v", err)
+	}
+	if len(response.Hits) != 1 {
+		t.Fatalf("Expected 1 hit, got %d", len(response.Hits))
+	}
+	if !strings.Contains(response.Hits[0].Fragments["message"][0], "<mark>timeout</mark>") {
+		t.Errorf("Expected highlighted fragment, got %v", response.Hits[0].Fragments)
+	}
+
+	// Masking the message also drops its fragments and match positions
+	result, err = server.callTool(context.Background(), "search_logs", map[string]interface{}{
+		"query":       "timeout",
+		"mask_fields": []interface{}{"message"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	var maskedResponse struct {
+		Hits []models.SearchHit `json:"hits"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &maskedResponse); err != nil {
+		t.Fatalf("Failed to parse result JSON: %v", err)
+	}
+	hit := maskedResponse.Hits[0]
+	if len(hit.Fragments) != 0 || len(hit.Matches) != 0 {
+		t.Errorf("Expected no fragments or matches for masked message, got %v %v", hit.Fragments, hit.Matches)
+	}
+	if strings.Contains(hit.Log.Message, "timeout") {
+		t.Errorf("Expected masked message, got %s", hit.Log.Message)
+	}
+}
+
+func TestHandleSearchLogs_Errors(t *testing.T) {
+	server := NewServer(8081, &MockStorage{})
+
+	if _, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{}); err == nil {
+		t.Error("Expected error for missing query")
+	}
+
+	if _, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{"query": "timeout", "fuzziness": float64(3)}); err == nil {
+		t.Error("Expected error for fuzziness above the maximum")
+	}
+
+	if _, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{"query": "timeout", "facets": []interface{}{"agent"}}); err == nil {
+		t.Error("Expected error for unknown facet")
+	}
+
+	if _, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{"query": "timeout", "sort": "score"}); err == nil {
+		t.Error("Expected error for unknown sort order")
+	}
+
+	_, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{"query": "timeout"})
+	if err != storage.ErrSearchDisabled {
+		t.Errorf("Expected ErrSearchDisabled, got %v", err)
+	}
+}
+
+func TestHandleSearchLogs_Facets(t *testing.T) {
+	searchStorage := &SearchingStorage{
+		MockStorage: MockStorage{
+			logs: []models.LogEntry{
+				{ID: "log-1", Level: models.LogLevelError, Message: "timeout charging card", ServiceName: "payment-service"},
+				{ID: "log-2", Level: models.LogLevelError, Message: "timeout reading cart", ServiceName: "cart-service"},
+				{ID: "log-3", Level: models.LogLevelWarn, Message: "slow response, timeout close", ServiceName: "cart-service"},
+			},
+		},
+	}
+	server := NewServer(8081, searchStorage)
+
+	result, err := server.callTool(context.Background(), "search_logs", map[string]interface{}{
+		"query":  "timeout",
+		"facets": []interface{}{"level", "service"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var response struct {
+		Facets map[string][]models.FacetCount `json:"facets"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result JSON: %v", err)
+	}
+
+	levels := make(map[string]int)
+	for _, count := range response.Facets[models.FacetLevel] {
+		levels[count.Value] = count.Count
+	}
+	if levels["ERROR"] != 2 || levels["WARN"] != 1 {
+		t.Errorf("Expected 2 ERROR and 1 WARN, got %v", response.Facets[models.FacetLevel])
+	}
+	if len(response.Facets[models.FacetService]) != 2 {
+		t.Errorf("Expected 2 service values, got %v", response.Facets[models.FacetService])
+	}
+
+	// Masking the service name also drops its facet
+	result, err = server.callTool(context.Background(), "search_logs", map[string]interface{}{
+		"query":       "timeout",
+		"facets":      []interface{}{"level", "service"},
+		"mask_fields": []interface{}{"service_name"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var maskedResponse struct {
+		Facets map[string][]models.FacetCount `json:"facets"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &maskedResponse); err != nil {
+		t.Fatalf("Failed to parse result JSON: %v", err)
+	}
+	if _, ok := maskedResponse.Facets[models.FacetService]; ok {
+		t.Errorf("Expected service facet to be dropped, got %v", maskedResponse.Facets)
+	}
+	if len(maskedResponse.Facets[models.FacetLevel]) != 2 {
+		t.Errorf("Expected level facet to be kept, got %v", maskedResponse.Facets)
+	}
+}
+
+func TestHandleQueryLogs_InvalidFacet(t *testing.T) {
+	server := NewServer(8081, &MockStorage{})
+
+	if _, err := server.callTool(context.Background(), "query_logs", map[string]interface{}{"facets": []interface{}{"agent"}}); err == nil {
+		t.Error("Expected error for unknown facet")
+	}
+}
+
+func TestHandleQueryCrashes(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+	now := time.Now()
+
+	crash := func(exceptionType, function, agentID string, timestamp time.Time) models.LogEntry {
+		info := &models.CrashInfo{
+			ExceptionType: exceptionType,
+			Threads: []models.ThreadDump{
+				{Crashed: true, Frames: []models.StackFrame{{Function: function, Module: "App", InApp: true}}},
+			},
+		}
+		info.Signature = info.ComputeSignature()
+		return models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   timestamp,
+			Level:       models.LogLevelFatal,
+			Message:     info.Title(),
+			ServiceName: "ios-app",
+			AgentID:     agentID,
+			Platform:    models.PlatformSwift,
+			Crash:       info,
+		}
+	}
+	logs := []models.LogEntry{
+		crash("NSRangeException", "CartView.render", "device-1", now.Add(-3*time.Minute)),
+		crash("NSRangeException", "CartView.render", "device-2", now.Add(-2*time.Minute)),
+		crash("EXC_BAD_ACCESS", "ImageCache.load", "device-1", now.Add(-time.Minute)),
+		{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelError, Message: "Not a crash", ServiceName: "ios-app", AgentID: "device-1", Platform: models.PlatformSwift},
+	}
+	if err := memoryStorage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "query_crashes", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("handleQueryCrashes failed: %v", err)
+	}
+	var grouped struct {
+		Groups []models.CrashGroup `json:"groups"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &grouped); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(grouped.Groups) != 2 {
+		t.Fatalf("Expected 2 crash groups, got %d", len(grouped.Groups))
+	}
+	top := grouped.Groups[0]
+	if top.Count != 2 || top.AffectedAgents != 2 || top.Title != "NSRangeException in App!CartView.render" || top.LatestID != logs[1].ID {
+		t.Errorf("Unexpected top crash group: %+v", top)
+	}
+
+	result, err = server.callTool(ctx, "query_crashes", map[string]interface{}{"signature": top.Signature})
+	if err != nil {
+		t.Fatalf("handleQueryCrashes failed: %v", err)
+	}
+	var reports struct {
+		Crashes []models.LogEntry `json:"crashes"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &reports); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(reports.Crashes) != 2 || reports.Crashes[0].Crash == nil || len(reports.Crashes[0].Crash.Threads) != 1 {
+		t.Errorf("Expected the 2 reports of the group with their threads, got %+v", reports.Crashes)
+	}
+
+	if _, err := NewServer(8081, &MockStorage{}).callTool(ctx, "query_crashes", map[string]interface{}{}); err == nil {
+		t.Error("Expected an error for storage without crash grouping")
+	}
+}
+
+func TestHandleGetUsage(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+
+	records := []models.UsageRecord{
+		{Day: "2024-03-01", APIKeyID: "key-a", ServiceName: "checkout", Entries: 10, Bytes: 1000},
+		{Day: "2024-03-02", APIKeyID: "key-a", ServiceName: "checkout", Entries: 20, Bytes: 2000},
+		{Day: "2024-03-02", APIKeyID: "key-b", ServiceName: "search", Entries: 5, Bytes: 500},
+	}
+	if err := memoryStorage.RecordUsage(ctx, records); err != nil {
+		t.Fatalf("Failed to record usage: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "get_usage", map[string]interface{}{"start_day": "2024-03-01"})
+	if err != nil {
+		t.Fatalf("handleGetUsage failed: %v", err)
+	}
+	var response struct {
+		Usage        []models.UsageRecord `json:"usage"`
+		TotalEntries int64                `json:"total_entries"`
+		TotalBytes   int64                `json:"total_bytes"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(response.Usage) != 2 || response.Usage[0].APIKeyID != "key-a" || response.Usage[0].Bytes != 3000 {
+		t.Errorf("Expected the checkout key first with its days summed, got %+v", response.Usage)
+	}
+	if response.TotalEntries != 35 || response.TotalBytes != 3500 {
+		t.Errorf("Unexpected totals %d entries and %d bytes", response.TotalEntries, response.TotalBytes)
+	}
+
+	result, err = server.callTool(ctx, "get_usage", map[string]interface{}{"daily": true, "api_key_id": "key-a"})
+	if err != nil {
+		t.Fatalf("handleGetUsage failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(response.Usage) != 2 || response.Usage[0].Day != "2024-03-02" {
+		t.Errorf("Expected the key's days, most recent first, got %+v", response.Usage)
+	}
+
+	if _, err := server.callTool(ctx, "get_usage", map[string]interface{}{"end_day": "March"}); err == nil {
+		t.Error("Expected an error for an invalid day")
+	}
+	if _, err := NewServer(8081, &MockStorage{}).callTool(ctx, "get_usage", map[string]interface{}{}); err == nil {
+		t.Error("Expected an error for storage without usage accounting")
+	}
+}
+
+func TestHandleGetLogContext(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+
+	// IDs sort like the names, which tell the entries apart in the assertions
+	names := make(map[string]string)
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	newLog := func(name string, offset time.Duration, agent string) models.LogEntry {
+		id := fmt.Sprintf("00000000-0000-4000-8000-%012d", name[0])
+		names[id] = name
+		return models.LogEntry{
+			ID:          id,
+			Timestamp:   base.Add(offset),
+			Level:       models.LogLevelInfo,
+			Message:     "Message " + name,
+			ServiceName: "checkout",
+			AgentID:     agent,
+			Platform:    models.PlatformGo,
+		}
+	}
+	logs := []models.LogEntry{
+		newLog("a", -3*time.Second, "agent-1"),
+		newLog("b", -2*time.Second, "agent-2"),
+		newLog("c", -time.Second, "agent-1"),
+		newLog("d", 0, "agent-1"), // Logged at the same time as e, ordered before it by ID
+		newLog("e", 0, "agent-1"),
+		newLog("f", 0, "agent-2"),
+		newLog("g", time.Second, "agent-1"),
+		newLog("h", 2*time.Second, "agent-1"),
+	}
+	other := newLog("x", 0, "agent-1")
+	other.ServiceName = "search"
+	if err := memoryStorage.Store(ctx, append(logs, other)); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	call := func(arguments map[string]interface{}) getLogContextResult {
+		t.Helper()
+		arguments["id"] = logs[4].ID
+		result, err := server.callTool(ctx, "get_log_context", arguments)
+		if err != nil {
+			t.Fatalf("handleGetLogContext failed: %v", err)
+		}
+		var response getLogContextResult
+		if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		return response
+	}
+	ids := func(entries []models.LogEntry) string {
+		var ids []string
+		for _, entry := range entries {
+			ids = append(ids, names[entry.ID])
+		}
+		return strings.Join(ids, ",")
+	}
+
+	response := call(map[string]interface{}{})
+	if names[response.Log.ID] != "e" || ids(response.Before) != "a,b,c,d" || ids(response.After) != "f,g,h" {
+		t.Errorf("Expected a,b,c,d before and f,g,h after, got %s and %s", ids(response.Before), ids(response.After))
+	}
+	if response.HasMoreBefore || response.HasMoreAfter {
+		t.Errorf("Expected no more entries, got %+v", response)
+	}
+
+	response = call(map[string]interface{}{"before": 2, "after": 1})
+	if ids(response.Before) != "c,d" || ids(response.After) != "f" || !response.HasMoreBefore || !response.HasMoreAfter {
+		t.Errorf("Expected the closest entries c,d and f with more on both sides, got %s and %s", ids(response.Before), ids(response.After))
+	}
+
+	response = call(map[string]interface{}{"scope": "agent", "before": 0, "mask_fields": []interface{}{"message"}})
+	if len(response.Before) != 0 || !response.HasMoreBefore || ids(response.After) != "g,h" {
+		t.Errorf("Expected only the entries of agent-1 after, got %s and %s", ids(response.Before), ids(response.After))
+	}
+	if response.Log.Message == "Message e" || response.After[0].Message == "Message g" {
+		t.Errorf("Expected messages to be masked, got %q and %q", response.Log.Message, response.After[0].Message)
+	}
+
+	invalid := []map[string]interface{}{
+		{},
+		{"id": logs[4].ID, "before": 101},
+		{"id": logs[4].ID, "scope": "platform"},
+		{"id": uuid.New().String()},
+	}
+	for _, arguments := range invalid {
+		if _, err := server.callTool(ctx, "get_log_context", arguments); err == nil {
+			t.Errorf("Expected an error for %v", arguments)
+		}
+	}
+}
+
+func TestHandleAnnotateLog(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+
+	entry := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now().UTC(),
+		Level:       models.LogLevelError,
+		Message:     "Payment declined",
+		ServiceName: "billing-service",
+		AgentID:     "agent-1",
+		Platform:    models.PlatformGo,
+	}
+	if err := memoryStorage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "annotate_log", map[string]interface{}{"id": entry.ID, "text": "false positive", "author": "oncall"})
+	if err != nil {
+		t.Fatalf("handleAnnotateLog failed: %v", err)
+	}
+	var response annotateLogResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(response.Annotations) != 1 || response.Annotations[0].Text != "false positive" || response.Annotations[0].Author != "oncall" {
+		t.Errorf("Unexpected annotations %+v", response.Annotations)
+	}
+
+	result, err = server.callTool(ctx, "get_log_details", map[string]interface{}{"ids": []interface{}{entry.ID}})
+	if err != nil {
+		t.Fatalf("handleGetLogDetails failed: %v", err)
+	}
+	var logs []models.LogEntry
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &logs); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if len(logs) != 1 || len(logs[0].Annotations) != 1 || logs[0].Message != entry.Message {
+		t.Errorf("Expected the annotation to be returned with the unchanged entry, got %+v", logs)
+	}
+
+	invalid := []map[string]interface{}{
+		{"text": "no id"},
+		{"id": entry.ID, "text": "  "},
+		{"id": uuid.New().String(), "text": "unknown entry"},
+	}
+	for _, arguments := range invalid {
+		if _, err := server.callTool(ctx, "annotate_log", arguments); err == nil {
+			t.Errorf("Expected an error for %v", arguments)
+		}
+	}
+	if _, err := NewServer(8081, &MockStorage{}).callTool(ctx, "annotate_log", map[string]interface{}{"id": entry.ID, "text": "x"}); err == nil {
+		t.Error("Expected an error for storage without annotations")
+	}
+}
+
+func TestHandleGetIncidentLogs(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+
+	newEntry := func(service, message string) models.LogEntry {
+		return models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now().UTC().Add(-time.Minute),
+			Level:       models.LogLevelError,
+			Message:     message,
+			ServiceName: service,
+			AgentID:     "agent-1",
+			Platform:    models.PlatformGo,
+		}
+	}
+	attached := newEntry("billing-service", "Payment declined")
+	matching := newEntry("checkout-service", "Card token secret-123 rejected")
+	if err := memoryStorage.Store(ctx, []models.LogEntry{attached, matching}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	deleted := uuid.New().String()
+	incident, err := memoryStorage.CreateIncident(ctx, models.Incident{
+		Title:  "Checkout outage",
+		LogIDs: []string{attached.ID, deleted},
+		Filter: &models.LogFilter{ServiceName: "checkout-service"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create incident: %v", err)
+	}
+
+	// Logged after the filter snapshot, so not part of the incident
+	if err := memoryStorage.Store(ctx, []models.LogEntry{func() models.LogEntry {
+		later := newEntry("checkout-service", "Recovered")
+		later.Timestamp = time.Now().UTC().Add(time.Minute)
+		return later
+	}()}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "get_incident_logs", map[string]interface{}{"incident_id": incident.ID, "mask_fields": []interface{}{"message"}})
+	if err != nil {
+		t.Fatalf("handleGetIncidentLogs failed: %v", err)
+	}
+	var response getIncidentLogsResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if response.Incident.ID != incident.ID || len(response.AttachedLogs) != 1 || response.AttachedLogs[0].ID != attached.ID {
+		t.Errorf("Expected the attached entry, got %+v", response)
+	}
+	if len(response.MissingLogIDs) != 1 || response.MissingLogIDs[0] != deleted {
+		t.Errorf("Expected the deleted entry to be reported missing, got %v", response.MissingLogIDs)
+	}
+	if len(response.FilterLogs) != 1 || response.FilterLogs[0].ID != matching.ID || response.FilterPagination.TotalCount != 1 {
+		t.Errorf("Expected only the entry matching the filter snapshot, got %+v", response.FilterLogs)
+	}
+	if len(response.FilterLogs) == 1 && response.FilterLogs[0].Message == matching.Message {
+		t.Error("Expected the message to be masked")
+	}
+
+	invalid := []map[string]interface{}{
+		{},
+		{"incident_id": "missing"},
+		{"incident_id": incident.ID, "limit": 0},
+	}
+	for _, arguments := range invalid {
+		if _, err := server.callTool(ctx, "get_incident_logs", arguments); err == nil {
+			t.Errorf("Expected an error for %v", arguments)
+		}
+	}
+	if _, err := NewServer(8081, &MockStorage{}).callTool(ctx, "get_incident_logs", map[string]interface{}{"incident_id": incident.ID}); err == nil {
+		t.Error("Expected an error for storage without incidents")
+	}
+}
+
+func TestHandleDiffTimeWindows(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+
+	end := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	newLog := func(at time.Time, level models.LogLevel, message string) models.LogEntry {
+		return models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   at,
+			Level:       level,
+			Message:     message,
+			ServiceName: "checkout",
+			AgentID:     "agent-1",
+			Platform:    models.PlatformGo,
+		}
+	}
+	current, baseline := end.Add(-30*time.Minute), end.Add(-24*time.Hour-30*time.Minute)
+	logs := []models.LogEntry{
+		newLog(current, models.LogLevelInfo, "User 1 logged in"),
+		newLog(current, models.LogLevelInfo, "User 2 logged in"),
+		newLog(current, models.LogLevelError, "Payment failed for order 17"),
+		newLog(current, models.LogLevelError, "Payment failed for order 18"),
+		newLog(baseline, models.LogLevelInfo, "User 3 logged in"),
+		newLog(baseline, models.LogLevelInfo, "User 4 logged in"),
+		newLog(baseline, models.LogLevelInfo, "User 5 logged in"),
+		newLog(baseline, models.LogLevelWarn, "Cache warmed in 120 ms"),
+		// Outside of both windows
+		newLog(end.Add(-2*time.Hour), models.LogLevelFatal, "Out of memory"),
+	}
+	if err := memoryStorage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "diff_time_windows", map[string]interface{}{
+		"service_name": "checkout",
+		"end_time":     end.Format(time.RFC3339),
+		"mask_fields":  []interface{}{"agent_id"},
+	})
+	if err != nil {
+		t.Fatalf("handleDiffTimeWindows failed: %v", err)
+	}
+	var response diffTimeWindowsResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+
+	if response.Current.Total != 4 || response.Baseline.Total != 4 || response.Window != "1h0m0s" {
+		t.Errorf("Expected 4 entries in each window, got %+v and %+v", response.Current, response.Baseline)
+	}
+	if response.NewTemplateCount != 1 || response.NewTemplates[0].Template != "Payment failed for order <num>" || response.NewTemplates[0].Count != 2 {
+		t.Errorf("Expected the payment failures as new template, got %+v", response.NewTemplates)
+	}
+	if response.NewTemplates[0].Entry.AgentID == "agent-1" {
+		t.Error("Expected the agent ID of the representative entry to be masked")
+	}
+	if response.DisappearedTemplateCount != 1 || response.DisappearedTemplates[0].Template != "Cache warmed in <num> ms" {
+		t.Errorf("Expected the cache message as disappeared template, got %+v", response.DisappearedTemplates)
+	}
+
+	changes := make(map[models.LogLevel]levelChange)
+	for _, change := range response.LevelChanges {
+		changes[change.Level] = change
+	}
+	if len(changes) != 3 || changes[models.LogLevelError].Current != 2 || changes[models.LogLevelError].ShareChange != 0.5 || changes[models.LogLevelInfo].ShareChange != -0.25 {
+		t.Errorf("Unexpected level changes %+v", response.LevelChanges)
+	}
+
+	// An explicit baseline replaces the same window a day earlier
+	result, err = server.callTool(ctx, "diff_time_windows", map[string]interface{}{
+		"service_name":      "checkout",
+		"end_time":          end.Format(time.RFC3339),
+		"baseline_end_time": end.Add(-time.Hour).Format(time.RFC3339),
+		"window":            "90m",
+	})
+	if err != nil {
+		t.Fatalf("handleDiffTimeWindows failed: %v", err)
+	}
+	response = diffTimeWindowsResult{}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if response.Baseline.Total != 1 || response.Baseline.Levels[models.LogLevelFatal] != 1 || response.DisappearedTemplates[0].Template != "Out of memory" {
+		t.Errorf("Expected the baseline to hold the fatal entry, got %+v", response.Baseline)
+	}
+
+	invalid := []map[string]interface{}{
+		{},
+		{"service_name": "checkout", "window": "0s"},
+		{"service_name": "checkout", "baseline_end_time": "last week"},
+		{"service_name": "checkout", "limit": 101},
+	}
+	for _, arguments := range invalid {
+		if _, err := server.callTool(ctx, "diff_time_windows", arguments); err == nil {
+			t.Errorf("Expected an error for %v", arguments)
+		}
+	}
+}
+
+func TestHandleQueryLogSummaries(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+	hour := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	var logs []models.LogEntry
+	for i := 0; i < 3; i++ {
+		logs = append(logs, models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   hour.Add(time.Duration(i) * time.Minute),
+			Level:       models.LogLevelInfo,
+			Message:     fmt.Sprintf("Cart %d checked out", i),
+			ServiceName: "checkout",
+			AgentID:     "agent-1",
+			Platform:    models.PlatformGo,
+		})
+	}
+	if err := memoryStorage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+	if _, err := memoryStorage.CompactLogs(ctx, models.LogLevelInfo, time.Now(), 100); err != nil {
+		t.Fatalf("Failed to compact logs: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "query_log_summaries", map[string]interface{}{"service_name": "checkout"})
+	if err != nil {
+		t.Fatalf("handleQueryLogSummaries failed: %v", err)
+	}
+	var response queryLogSummariesResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if response.TotalCount != 1 || response.TotalEntries != 3 || response.Summaries[0].Template != "Cart <num> checked out" {
+		t.Errorf("Expected one summary of 3 checkouts, got %+v", response)
+	}
+
+	// Compacted entries still count towards the error rate
+	result, err = server.callTool(ctx, "get_error_rate", map[string]interface{}{
+		"service_name": "checkout",
+		"end_time":     hour.Add(time.Hour).Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatalf("handleGetErrorRate failed: %v", err)
+	}
+	var rate getErrorRateResult
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &rate); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if rate.Current.Total != 3 || rate.Current.Compacted != 3 || rate.Current.ErrorRate != 0 {
+		t.Errorf("Expected 3 compacted entries in the window, got %+v", rate.Current)
+	}
+
+	if _, err := server.callTool(ctx, "query_log_summaries", map[string]interface{}{"level": "TRACE"}); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+	if _, err := NewServer(8081, &MockStorage{}).callTool(ctx, "query_log_summaries", map[string]interface{}{}); err == nil {
+		t.Error("Expected an error for storage without compaction")
+	}
+}
+
+func TestHandleGetErrorRate(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := context.Background()
+	end := time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	var logs []models.LogEntry
+	add := func(serviceName string, level models.LogLevel, count int, timestamp time.Time) {
+		for i := 0; i < count; i++ {
+			logs = append(logs, models.LogEntry{
+				ID:          uuid.New().String(),
+				Timestamp:   timestamp,
+				Level:       level,
+				Message:     "Request handled",
+				ServiceName: serviceName,
+				AgentID:     "agent-1",
+				Platform:    models.PlatformGo,
+			})
+		}
+	}
+	// Yesterday 4 of 20 requests failed, today 1 of 20
+	add("checkout", models.LogLevelInfo, 16, end.Add(-30*time.Hour))
+	add("checkout", models.LogLevelError, 3, end.Add(-30*time.Hour))
+	add("checkout", models.LogLevelFatal, 1, end.Add(-30*time.Hour))
+	add("checkout", models.LogLevelInfo, 19, end.Add(-time.Hour))
+	add("checkout", models.LogLevelError, 1, end.Add(-time.Hour))
+	add("search", models.LogLevelError, 5, end.Add(-time.Hour))
+	if err := memoryStorage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := server.callTool(ctx, "get_error_rate", map[string]interface{}{
+		"service_name": "checkout",
+		"end_time":     end.Format(time.RFC3339),
+		"slo_target":   0.9,
+	})
+	if err != nil {
+		t.Fatalf("handleGetErrorRate failed: %v", err)
+	}
+	var response struct {
+		Window   string          `json:"window"`
+		Current  errorRateWindow `json:"current"`
+		Previous errorRateWindow `json:"previous"`
+		Trend    string          `json:"trend"`
+		Change   float64         `json:"change"`
+		SLO      struct {
+			BudgetUsed float64 `json:"budget_used"`
+			Met        bool    `json:"met"`
+		} `json:"slo"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+
+	if response.Window != "24h0m0s" || response.Current.Total != 20 || response.Current.Errors != 1 || response.Current.ErrorRate != 0.05 {
+		t.Errorf("Unexpected current window %+v", response.Current)
+	}
+	if response.Previous.Total != 20 || response.Previous.Fatal != 1 || response.Previous.ErrorRate != 0.2 {
+		t.Errorf("Unexpected previous window %+v", response.Previous)
+	}
+	if response.Trend != "improving" || math.Abs(response.Change+0.15) > 1e-9 {
+		t.Errorf("Expected an improving trend of -0.15, got %s %v", response.Trend, response.Change)
+	}
+	if !response.SLO.Met || math.Abs(response.SLO.BudgetUsed-0.5) > 1e-9 {
+		t.Errorf("Expected half of the error budget used, got %+v", response.SLO)
+	}
+
+	// Without entries in the window before there is nothing to compare
+	result, err = server.callTool(ctx, "get_error_rate", map[string]interface{}{
+		"service_name": "checkout",
+		"window":       "2h",
+		"end_time":     end.Format(time.RFC3339),
+	})
+	if err != nil {
+		t.Fatalf("handleGetErrorRate failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &response); err != nil {
+		t.Fatalf("Failed to parse result: %v", err)
+	}
+	if response.Trend != "no_data" {
+		t.Errorf("Expected no_data, got %s", response.Trend)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{},
+		{"service_name": "checkout", "window": "-1h"},
+		{"service_name": "checkout", "slo_target": 1.0},
+	} {
+		if _, err := server.callTool(ctx, "get_error_rate", args); err == nil {
+			t.Errorf("Expected an error for arguments %v", args)
+		}
+	}
+}
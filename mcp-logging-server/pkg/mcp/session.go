@@ -0,0 +1,214 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// sessionDefaultsCapability is the experimental capability under which clients may send session
+// defaults with the initialize request
+const sessionDefaultsCapability = "sessionDefaults"
+
+// sessionDefaults are arguments applied to the tool calls of a session that omit them
+type sessionDefaults struct {
+	ServiceName string          `json:"service_name,omitempty"`
+	AgentID     string          `json:"agent_id,omitempty"`
+	Platform    models.Platform `json:"platform,omitempty"`
+	MaskFields  []string        `json:"mask_fields,omitempty"`
+	TimeZone    string          `json:"time_zone,omitempty" validate:"omitempty,timezone"`
+}
+
+// arguments returns the defaults that are set, keyed by argument name
+func (d sessionDefaults) arguments() map[string]interface{} {
+	arguments := make(map[string]interface{})
+	if d.ServiceName != "" {
+		arguments["service_name"] = d.ServiceName
+	}
+	if d.AgentID != "" {
+		arguments["agent_id"] = d.AgentID
+	}
+	if d.Platform != "" {
+		arguments["platform"] = string(d.Platform)
+	}
+	if len(d.MaskFields) > 0 {
+		arguments["mask_fields"] = d.MaskFields
+	}
+	if d.TimeZone != "" {
+		arguments["time_zone"] = d.TimeZone
+	}
+	return arguments
+}
+
+// session is the state of one MCP connection
+type session struct {
+	address string // Of the client
+
+	mutex           sync.RWMutex
+	defaults        sessionDefaults
+	clientName      string // From the clientInfo of the initialize request
+	protocolVersion string // Negotiated by the initialize request, empty before it
+}
+
+// sessionContextKey is the context key of the session a message arrived on
+type sessionContextKey struct{}
+
+// withSession returns a context carrying the session
+func withSession(ctx context.Context, sess *session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sess)
+}
+
+// sessionFromContext returns the session of a context, or nil outside of a connection
+func sessionFromContext(ctx context.Context) *session {
+	sess, _ := ctx.Value(sessionContextKey{}).(*session)
+	return sess
+}
+
+// getDefaults returns the current defaults of the session
+func (sess *session) getDefaults() sessionDefaults {
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+	return sess.defaults
+}
+
+// client returns the name the client gave in the initialize request and its address
+func (sess *session) client() (string, string) {
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+	return sess.clientName, sess.address
+}
+
+// getProtocolVersion returns the negotiated protocol version, empty before initialize
+func (sess *session) getProtocolVersion() string {
+	sess.mutex.RLock()
+	defer sess.mutex.RUnlock()
+	return sess.protocolVersion
+}
+
+// setProtocolVersion records the protocol version negotiated by the initialize request
+func (sess *session) setProtocolVersion(version string) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+	sess.protocolVersion = version
+}
+
+// setDefaults replaces the defaults of the session
+func (sess *session) setDefaults(defaults sessionDefaults) {
+	sess.mutex.Lock()
+	defer sess.mutex.Unlock()
+	sess.defaults = defaults
+}
+
+// applySessionDefaults adds the session defaults to the arguments of a tool call. A default only
+// applies to tools accepting the argument and never overrides one given in the call, so an empty
+// service_name still queries all services.
+func applySessionDefaults(ctx context.Context, tool registeredTool, arguments interface{}) interface{} {
+	sess := sessionFromContext(ctx)
+	if sess == nil || tool.Name == "set_context" {
+		return arguments
+	}
+	defaults := sess.getDefaults().arguments()
+	if len(defaults) == 0 {
+		return arguments
+	}
+
+	given, ok := arguments.(map[string]interface{})
+	if !ok {
+		if arguments != nil {
+			// Left to the argument decoding to reject
+			return arguments
+		}
+		given = map[string]interface{}{}
+	}
+	schema, _ := tool.InputSchema.(map[string]interface{})
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	merged := make(map[string]interface{}, len(given)+len(defaults))
+	for name, value := range given {
+		merged[name] = value
+	}
+	for name, value := range defaults {
+		if _, accepted := properties[name]; !accepted {
+			continue
+		}
+		if _, exists := merged[name]; !exists {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// initializeSession applies the session defaults of an initialize request, sent by the client as
+// capabilities.experimental.sessionDefaults
+func (s *Server) initializeSession(ctx context.Context, params interface{}) error {
+	sess := sessionFromContext(ctx)
+	paramsMap, _ := params.(map[string]interface{})
+	if clientInfo, ok := paramsMap["clientInfo"].(map[string]interface{}); ok && sess != nil {
+		if name, ok := clientInfo["name"].(string); ok {
+			sess.mutex.Lock()
+			sess.clientName = name
+			sess.mutex.Unlock()
+		}
+	}
+	capabilities, _ := paramsMap["capabilities"].(map[string]interface{})
+	experimental, _ := capabilities["experimental"].(map[string]interface{})
+	requested, ok := experimental[sessionDefaultsCapability]
+	if sess == nil || !ok {
+		return nil
+	}
+
+	var defaults sessionDefaults
+	if err := decodeArguments(requested, &defaults); err != nil {
+		return fmt.Errorf("invalid %s capability: %w", sessionDefaultsCapability, err)
+	}
+	sess.setDefaults(defaults)
+	return nil
+}
+
+// setContextParams are the arguments of the set_context tool
+type setContextParams struct {
+	sessionDefaults
+	Clear bool `json:"clear"`
+}
+
+// setContextResult is the result of the set_context tool
+type setContextResult struct {
+	Defaults sessionDefaults `json:"defaults"`
+}
+
+// handleSetContext handles the set_context tool call. Arguments that are given replace the
+// corresponding defaults, the others are kept unless clear is set.
+func (s *Server) handleSetContext(ctx context.Context, params setContextParams) (*setContextResult, error) {
+	sess := sessionFromContext(ctx)
+	if sess == nil {
+		return nil, fmt.Errorf("session defaults require an MCP connection")
+	}
+	if err := s.checkPlatform(params.Platform); err != nil {
+		return nil, err
+	}
+
+	defaults := sess.getDefaults()
+	if params.Clear {
+		defaults = sessionDefaults{}
+	}
+	if params.ServiceName != "" {
+		defaults.ServiceName = params.ServiceName
+	}
+	if params.AgentID != "" {
+		defaults.AgentID = params.AgentID
+	}
+	if params.Platform != "" {
+		defaults.Platform = params.Platform
+	}
+	if params.MaskFields != nil {
+		defaults.MaskFields = params.MaskFields
+	}
+	if params.TimeZone != "" {
+		defaults.TimeZone = params.TimeZone
+	}
+	sess.setDefaults(defaults)
+
+	return &setContextResult{Defaults: defaults}, nil
+}
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestSessionDefaults(t *testing.T) {
+	memoryStorage := storage.NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	server := NewServer(8081, memoryStorage)
+	ctx := withSession(context.Background(), &session{})
+	now := time.Now().UTC()
+
+	err := memoryStorage.Store(ctx, []models.LogEntry{
+		{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelInfo, Message: "checkout started", ServiceName: "checkout", AgentID: "agent-1", Platform: models.PlatformGo},
+		{ID: uuid.New().String(), Timestamp: now.Add(-time.Second), Level: models.LogLevelInfo, Message: "payment started", ServiceName: "payments", AgentID: "agent-2", Platform: models.PlatformGo},
+	})
+	if err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	queryLogs := func(arguments map[string]interface{}) queryLogsResult {
+		t.Helper()
+		response := server.handleMessage(ctx, &MCPMessage{
+			JSONRPC: "2.0",
+			ID:      "query",
+			Method:  "tools/call",
+			Params:  map[string]interface{}{"name": "query_logs", "arguments": arguments},
+		})
+		if response.Error != nil {
+			t.Fatalf("query_logs failed: %v", response.Error.Message)
+		}
+		var result queryLogsResult
+		if err := json.Unmarshal([]byte(response.Result.(*ToolResult).Content[0].Text), &result); err != nil {
+			t.Fatalf("Failed to parse result: %v", err)
+		}
+		return result
+	}
+
+	// Defaults set at initialization apply to calls that omit the argument
+	response := server.handleMessage(ctx, &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      "init",
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"experimental": map[string]interface{}{
+					"sessionDefaults": map[string]interface{}{"service_name": "checkout"},
+				},
+			},
+		},
+	})
+	if response.Error != nil {
+		t.Fatalf("initialize failed: %v", response.Error.Message)
+	}
+	result := queryLogs(nil)
+	if len(result.Logs) != 1 || result.Logs[0].ServiceName != "checkout" {
+		t.Fatalf("Expected only the checkout entry, got %+v", result.Logs)
+	}
+
+	// An argument given in the call takes precedence, an empty one removes the filter
+	if result := queryLogs(map[string]interface{}{"service_name": "payments"}); len(result.Logs) != 1 || result.Logs[0].ServiceName != "payments" {
+		t.Errorf("Expected only the payments entry, got %+v", result.Logs)
+	}
+	if result := queryLogs(map[string]interface{}{"service_name": ""}); len(result.Logs) != 2 {
+		t.Errorf("Expected both entries, got %d", len(result.Logs))
+	}
+
+	// set_context updates the given defaults and keeps the others
+	if _, err := server.callTool(ctx, "set_context", map[string]interface{}{"mask_fields": []interface{}{"agent_id"}}); err != nil {
+		t.Fatalf("set_context failed: %v", err)
+	}
+	result = queryLogs(nil)
+	if len(result.Logs) != 1 || result.Logs[0].ServiceName != "checkout" {
+		t.Fatalf("Expected the service default to be kept, got %+v", result.Logs)
+	}
+	if result.Logs[0].AgentID == "agent-1" {
+		t.Error("Expected agent_id to be masked by default")
+	}
+
+	// clear removes all defaults
+	if _, err := server.callTool(ctx, "set_context", map[string]interface{}{"clear": true}); err != nil {
+		t.Fatalf("set_context failed: %v", err)
+	}
+	if result := queryLogs(nil); len(result.Logs) != 2 || result.Logs[0].AgentID != "agent-1" {
+		t.Errorf("Expected both unmasked entries after clearing, got %+v", result.Logs)
+	}
+
+	// Defaults only apply to tools accepting the argument
+	if _, err := server.callTool(ctx, "set_context", map[string]interface{}{"service_name": "checkout"}); err != nil {
+		t.Fatalf("set_context failed: %v", err)
+	}
+	listServices, _ := server.tools.get("list_services")
+	if args, _ := applySessionDefaults(ctx, listServices, nil).(map[string]interface{}); len(args) != 0 {
+		t.Errorf("Expected no defaults for list_services, got %v", args)
+	}
+}
+
+func TestSessionDefaults_Invalid(t *testing.T) {
+	server := NewServer(8081, &MockStorage{})
+	ctx := withSession(context.Background(), &session{})
+
+	response := server.handleMessage(ctx, &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      "init",
+		Method:  "initialize",
+		Params: map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"experimental": map[string]interface{}{
+					"sessionDefaults": map[string]interface{}{"mask_fields": "message"},
+				},
+			},
+		},
+	})
+	if response.Error == nil || response.Error.Code != -32602 {
+		t.Errorf("Expected invalid params error, got %+v", response.Error)
+	}
+
+	// Without a connection there is no session to keep defaults in
+	if _, err := server.callTool(context.Background(), "set_context", map[string]interface{}{"service_name": "checkout"}); err == nil {
+		t.Error("Expected error outside of a session")
+	}
+}
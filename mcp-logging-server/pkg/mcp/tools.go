@@ -0,0 +1,342 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
+)
+
+// toolHandler runs a tool call with the arguments as received
+type toolHandler func(ctx context.Context, arguments interface{}) (*ToolResult, error)
+
+// registeredTool is a tool definition together with the handler of its calls
+type registeredTool struct {
+	Tool
+	handler toolHandler
+}
+
+// toolRegistry holds the tools of a server. Tools are registered while the server is created,
+// and enabled and disabled at any time by the tool switch, so tools/list and tools/call only see
+// the tools enabled when they are handled. It is safe for concurrent use.
+type toolRegistry struct {
+	mutex    sync.RWMutex
+	tools    map[string]registeredTool
+	switches *toolswitch.Switch // Nil enables every tool
+}
+
+// newToolRegistry creates a registry whose tools are enabled by switches, nil enables every tool
+func newToolRegistry(switches *toolswitch.Switch) *toolRegistry {
+	return &toolRegistry{tools: make(map[string]registeredTool), switches: switches}
+}
+
+// add registers a tool, replacing a tool of the same name
+func (r *toolRegistry) add(tool registeredTool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tools[tool.Name] = tool
+	if r.switches != nil {
+		r.switches.Register(tool.Name)
+	}
+}
+
+// get returns a registered tool, enabled or not
+func (r *toolRegistry) get(name string) (registeredTool, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// enabled reports whether a tool may be listed and called
+func (r *toolRegistry) enabled(name string) bool {
+	return r.switches == nil || r.switches.Enabled(name)
+}
+
+// list returns the enabled tools, sorted by name
+func (r *toolRegistry) list() []registeredTool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tools := make([]registeredTool, 0, len(r.tools))
+	for name, tool := range r.tools {
+		if r.enabled(name) {
+			tools = append(tools, tool)
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+	return tools
+}
+
+// names returns the names of the enabled tools, sorted
+func (r *toolRegistry) names() []string {
+	tools := r.list()
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+// defaulter is implemented by parameter structs with defaults for omitted arguments
+type defaulter interface {
+	setDefaults()
+}
+
+// timeResolver is implemented by parameter structs with time arguments, which are resolved
+// against the current time and the time_zone argument once all arguments are decoded
+type timeResolver interface {
+	resolveTimes(now time.Time) error
+}
+
+// noParams is the parameter struct of tools without arguments
+type noParams struct{}
+
+// argumentError reports tool arguments that do not decode or validate, answered as invalid params
+type argumentError struct {
+	message  string
+	argument string   // Name of the offending argument, empty if unknown
+	accepted []string // Accepted values or formats of the argument, if it has a fixed set
+}
+
+func (e *argumentError) Error() string {
+	return e.message
+}
+
+// data returns the error data of the invalid params response, nil if the argument is unknown
+func (e *argumentError) data() interface{} {
+	if e.argument == "" {
+		return nil
+	}
+	data := map[string]interface{}{"argument": e.argument}
+	if len(e.accepted) > 0 {
+		data["accepted"] = e.accepted
+	}
+	return data
+}
+
+// invalidArguments returns an argument error with a formatted message
+func invalidArguments(format string, args ...interface{}) error {
+	return &argumentError{message: fmt.Sprintf(format, args...)}
+}
+
+// invalidArgument returns an argument error naming the offending argument and what it accepts
+func invalidArgument(argument string, accepted []string, format string, args ...interface{}) error {
+	return &argumentError{message: fmt.Sprintf(format, args...), argument: argument, accepted: accepted}
+}
+
+// argumentValidator checks the validate tags of tool parameters, naming fields by their arguments
+var argumentValidator = func() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("facet", func(fl validator.FieldLevel) bool {
+		return models.IsValidFacet(fl.Field().String())
+	})
+	return v
+}()
+
+// registerTool registers a tool implemented by a typed function. The call arguments are decoded
+// into P, on top of its defaults if P implements defaulter, and checked against its validate tags.
+// The result is returned to the client as indented JSON text, and kept in the result cache if
+// P implements cacheable.
+func registerTool[P any, R any](s *Server, tool Tool, fn func(ctx context.Context, params P) (R, error)) {
+	s.tools.add(registeredTool{
+		Tool: tool,
+		handler: func(ctx context.Context, arguments interface{}) (*ToolResult, error) {
+			var params P
+			if err := decodeArguments(arguments, &params); err != nil {
+				return nil, err
+			}
+
+			// Results of cacheable tools are shared by calls with the same arguments
+			var cacheKey, cacheService string
+			var generation uint64
+			if c, ok := interface{}(&params).(cacheable); ok && s.options.ResultCache != nil {
+				if key, ok := resultCacheKey(tool.Name, params); ok {
+					cached, gen, hit := s.options.ResultCache.get(key)
+					if hit {
+						countResults(ctx, cached.resultCount)
+						return cached, nil
+					}
+					cacheKey, cacheService, generation = key, c.cacheService(), gen
+				}
+			}
+
+			result, err := fn(ctx, params)
+			if err != nil {
+				return nil, err
+			}
+
+			resultJSON, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal result: %w", err)
+			}
+
+			toolResult := &ToolResult{
+				Content: []ContentBlock{
+					{
+						Type: "text",
+						Text: string(resultJSON),
+					},
+				},
+				resultCount: countedResults(ctx),
+			}
+			if cacheKey != "" {
+				s.options.ResultCache.put(cacheKey, cacheService, toolResult, generation)
+			}
+			return toolResult, nil
+		},
+	})
+}
+
+// callTool runs a registered tool with the arguments of a call, enabled or not
+func (s *Server) callTool(ctx context.Context, name string, arguments interface{}) (*ToolResult, error) {
+	tool, ok := s.tools.get(name)
+	if !ok {
+		return nil, fmt.Errorf("tool %s not found", name)
+	}
+	return tool.handler(ctx, arguments)
+}
+
+// decodeArguments decodes and validates call arguments into a pointer to a parameter struct
+func decodeArguments(arguments interface{}, params interface{}) error {
+	if d, ok := params.(defaulter); ok {
+		d.setDefaults()
+	}
+
+	if arguments != nil {
+		data, err := json.Marshal(arguments)
+		if err != nil {
+			return invalidArguments("invalid arguments: %v", err)
+		}
+		if err := json.Unmarshal(data, params); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && typeErr.Field != "" {
+				return invalidArgument(typeErr.Field, nil, "invalid argument %s: expected %s", typeErr.Field, typeErr.Type)
+			}
+			return invalidArguments("invalid arguments: %v", err)
+		}
+	}
+
+	if r, ok := params.(timeResolver); ok {
+		if err := r.resolveTimes(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if err := argumentValidator.Struct(params); err != nil {
+		var fieldErrs validator.ValidationErrors
+		if errors.As(err, &fieldErrs) {
+			return invalidArgument(fieldErrs[0].Field(), acceptedValues(fieldErrs[0]), "%s", validationMessage(fieldErrs[0]))
+		}
+		return invalidArguments("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+// validationMessage describes a failed validate tag in terms of the tool arguments
+func validationMessage(e validator.FieldError) string {
+	switch e.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", e.Field())
+	case "min":
+		if e.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at least %s items", e.Field(), e.Param())
+		}
+		return fmt.Sprintf("%s must be at least %s", e.Field(), e.Param())
+	case "max":
+		if e.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at most %s items", e.Field(), e.Param())
+		}
+		return fmt.Sprintf("%s must be at most %s", e.Field(), e.Param())
+	case "gt":
+		return fmt.Sprintf("%s must be greater than %s", e.Field(), e.Param())
+	case "lt":
+		return fmt.Sprintf("%s must be less than %s", e.Field(), e.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", e.Field(), strings.ReplaceAll(e.Param(), " ", ", "))
+	case "datetime":
+		return fmt.Sprintf("%s must be formatted as %s", e.Field(), e.Param())
+	case "timezone":
+		return fmt.Sprintf("%s must be an IANA time zone such as Europe/Berlin", e.Field())
+	case "facet":
+		return fmt.Sprintf("unknown facet %q, expected one of: %s", e.Value(), strings.Join(models.SearchFacetNames(), ", "))
+	default:
+		return fmt.Sprintf("%s is invalid", e.Field())
+	}
+}
+
+// acceptedValues returns the values a failed validate tag accepts, nil if they are not a fixed set
+func acceptedValues(e validator.FieldError) []string {
+	switch e.Tag() {
+	case "oneof":
+		return strings.Fields(e.Param())
+	case "facet":
+		return models.SearchFacetNames()
+	default:
+		return nil
+	}
+}
+
+// duration is a duration argument given as a string such as "24h"
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("expected a duration such as 24h")
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q, expected e.g. 24h", value)
+	}
+	*d = duration(parsed)
+	return nil
+}
+
+// timeArgument is a time argument given as RFC3339, as a local time in the time_zone argument,
+// or relative to now such as -2h, see models.ParseTimeExpression
+type timeArgument string
+
+// timeFormats describes the accepted formats of time arguments in tool schemas
+const timeFormats = "RFC3339, a local time in time_zone such as 2024-05-01 10:00, now, today, yesterday, or relative to now such as -2h or -7d"
+
+// timeZoneProperty is the schema of the time_zone argument of tools with time arguments
+var timeZoneProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "IANA time zone (e.g. Europe/Berlin) of local times and of today and yesterday, defaults to UTC",
+}
+
+// loadTimeZone resolves the time_zone argument
+func loadTimeZone(name string) (*time.Location, error) {
+	loc, err := models.LoadTimeZone(name)
+	if err != nil {
+		return nil, invalidArgument("time_zone", nil, "time_zone: %v", err)
+	}
+	return loc, nil
+}
+
+// parseTimeArgument resolves a time argument in loc, naming the argument and its accepted
+// formats in the error
+func parseTimeArgument(name string, value timeArgument, now time.Time, loc *time.Location) (time.Time, error) {
+	t, err := models.ParseTimeExpression(string(value), now, loc)
+	if err != nil {
+		return time.Time{}, invalidArgument(name, models.TimeExpressionFormats, "%s: %v", name, err)
+	}
+	return t, nil
+}
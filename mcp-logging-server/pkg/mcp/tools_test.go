@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
+)
+
+func TestHandleToolCall_Disabled(t *testing.T) {
+	switches := toolswitch.New([]string{"annotate_log"})
+	server := NewServerWithOptions(8081, &MockStorage{}, Options{Tools: switches})
+	ctx := context.Background()
+
+	if unregistered := switches.Unregistered(); len(unregistered) != 0 {
+		t.Fatalf("Expected the server to register its tools, got %v unregistered", unregistered)
+	}
+
+	listed := func() map[string]bool {
+		response := server.handleToolsList(ctx, &MCPMessage{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+		names := make(map[string]bool)
+		for _, tool := range response.Result.(map[string]interface{})["tools"].([]Tool) {
+			names[tool.Name] = true
+		}
+		return names
+	}
+	if tools := listed(); tools["annotate_log"] || !tools["query_logs"] {
+		t.Errorf("Expected annotate_log left out of the tools, got %v", tools)
+	}
+
+	response := server.handleToolCall(ctx, &MCPMessage{
+		JSONRPC: "2.0",
+		ID:      2,
+		Method:  "tools/call",
+		Params:  map[string]interface{}{"name": "annotate_log", "arguments": map[string]interface{}{}},
+	})
+	if response.Error == nil || response.Error.Code != -32601 || response.Error.Message != "Tool is disabled" {
+		t.Fatalf("Expected the call rejected as disabled, got %+v", response)
+	}
+	if data, _ := response.Error.Data.(map[string]interface{}); data["reason"] != "disabled by configuration" {
+		t.Errorf("Expected the reason in the error data, got %v", response.Error.Data)
+	}
+
+	if _, err := switches.Set("annotate_log", true, ""); err != nil {
+		t.Fatalf("Failed to enable annotate_log: %v", err)
+	}
+	if tools := listed(); !tools["annotate_log"] {
+		t.Errorf("Expected annotate_log listed once enabled, got %v", tools)
+	}
+}
+
+func TestHandleConnection_ToolsListChanged(t *testing.T) {
+	switches := toolswitch.New(nil)
+	server := NewServerWithOptions(8081, &MockStorage{}, Options{Tools: switches, WriteTimeout: time.Second})
+	defer switches.Subscribe(server.notifyToolsChanged)()
+
+	conn, _ := serveConnection(t, server)
+	encoder := json.NewEncoder(conn)
+	decoder := json.NewDecoder(conn)
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if err := encoder.Encode(MCPMessage{JSONRPC: "2.0", ID: 1, Method: "initialize", Params: map[string]interface{}{"protocolVersion": protocolVersion20250618}}); err != nil {
+		t.Fatalf("Failed to send initialize: %v", err)
+	}
+	var initialized MCPMessage
+	if err := decoder.Decode(&initialized); err != nil {
+		t.Fatalf("Failed to read the initialize response: %v", err)
+	}
+	capabilities := initialized.Result.(map[string]interface{})["capabilities"].(map[string]interface{})
+	if tools := capabilities["tools"].(map[string]interface{}); tools["listChanged"] != true {
+		t.Errorf("Expected the tools capability to announce listChanged, got %v", tools)
+	}
+
+	if _, err := switches.Set("query_logs", false, "too expensive"); err != nil {
+		t.Fatalf("Failed to disable query_logs: %v", err)
+	}
+	var notification MCPMessage
+	if err := decoder.Decode(&notification); err != nil {
+		t.Fatalf("Failed to read the notification: %v", err)
+	}
+	if notification.Method != "notifications/tools/list_changed" || notification.ID != nil {
+		t.Errorf("Expected a tools/list_changed notification, got %+v", notification)
+	}
+}
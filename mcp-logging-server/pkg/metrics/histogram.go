@@ -0,0 +1,77 @@
+package metrics
+
+// Bucket upper bounds of the histograms
+var (
+	// FlushDurationBuckets are the bounds of storage write durations, in seconds
+	FlushDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+	// FlushBatchSizeBuckets are the bounds of the number of entries per storage write
+	FlushBatchSizeBuckets = []float64{1, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+	// StageDurationBuckets are the bounds of ingestion stage durations, in seconds
+	StageDurationBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 1}
+)
+
+// Stages of ingestion requests timed by ObserveIngestionStage
+const (
+	StageDecode         = "decode"          // Parsing the JSON request body
+	StageValidation     = "validation"      // Normalizing and validating entries
+	StageSymbolication  = "symbolication"   // Resolving stack trace frames
+	StageProcessors     = "processors"      // Running the custom processors
+	StageDataProtection = "data_protection" // Masking, hashing and dropping sensitive fields
+	StageRouting        = "routing"         // Sending entries to the nodes owning their services
+	StageBufferAdd      = "buffer_add"      // Adding entries to the message buffer
+)
+
+// histogram counts observations in buckets with fixed upper bounds, like a Prometheus
+// histogram. It is guarded by the mutex of the Metrics it belongs to.
+type histogram struct {
+	bounds []float64
+	counts []int64 // Observations per bucket, the last one above all bounds
+	sum    float64
+	count  int64
+}
+
+// newHistogram creates a histogram with the given ascending bucket upper bounds
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)+1),
+	}
+}
+
+// observe adds a value to the histogram
+func (h *histogram) observe(value float64) {
+	i := 0
+	for i < len(h.bounds) && value > h.bounds[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// snapshot returns the cumulative bucket counts of the histogram
+func (h *histogram) snapshot() HistogramSnapshot {
+	buckets := make([]HistogramBucket, len(h.bounds))
+	var cumulative int64
+	for i, bound := range h.bounds {
+		cumulative += h.counts[i]
+		buckets[i] = HistogramBucket{UpperBound: bound, Count: cumulative}
+	}
+	return HistogramSnapshot{Buckets: buckets, Sum: h.sum, Count: h.count}
+}
+
+// HistogramSnapshot is a point-in-time copy of a histogram. Bucket counts are cumulative, and
+// observations above the largest bound are only included in Count.
+type HistogramSnapshot struct {
+	Buckets []HistogramBucket `json:"buckets"`
+	Sum     float64           `json:"sum"`
+	Count   int64             `json:"count"`
+}
+
+// HistogramBucket counts the observations less than or equal to its upper bound
+type HistogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      int64   `json:"count"`
+}
@@ -20,12 +20,32 @@ type Metrics struct {
 	lastRequestTime      time.Time
 	serverStartTime      time.Time
 	bufferOverflows      int64
+	bufferDrops          int64
+	bufferRetries        int64
+	slowConsumers        int64
+	serviceDrops         map[string]int64
+	sampledOut           map[string]map[string]int64
+	flushDuration        *histogram
+	flushBatchSize       *histogram
+	ingestionStages      map[string]*histogram
+	validationRules      map[string]int64
+	connectionsOpen      int64
+	connectionsTotal     int64
+	tlsHandshakeErrors   int64
+	keyTraffic           map[string]*KeyTraffic
 }
 
 // NewMetrics creates a new metrics instance
 func NewMetrics() *Metrics {
 	return &Metrics{
 		serverStartTime: time.Now(),
+		serviceDrops:    make(map[string]int64),
+		sampledOut:      make(map[string]map[string]int64),
+		flushDuration:   newHistogram(FlushDurationBuckets),
+		flushBatchSize:  newHistogram(FlushBatchSizeBuckets),
+		ingestionStages: make(map[string]*histogram),
+		validationRules: make(map[string]int64),
+		keyTraffic:      make(map[string]*KeyTraffic),
 	}
 }
 
@@ -93,6 +113,13 @@ func (m *Metrics) IncrementValidationErrors() {
 	m.validationErrors++
 }
 
+// IncrementValidationRule increments the counter of failures of a validation rule
+func (m *Metrics) IncrementValidationRule(rule string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.validationRules[rule]++
+}
+
 // IncrementBufferOverflows increments the buffer overflows counter
 func (m *Metrics) IncrementBufferOverflows() {
 	m.mutex.Lock()
@@ -100,6 +127,64 @@ func (m *Metrics) IncrementBufferOverflows() {
 	m.bufferOverflows++
 }
 
+// IncrementServiceDrops increments the counters of entries dropped for lack of buffer space,
+// in total and for the service of the entry
+func (m *Metrics) IncrementServiceDrops(serviceName string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.bufferDrops++
+	m.serviceDrops[serviceName]++
+}
+
+// IncrementSampledOut increments the counter of entries discarded by the sampling policies for
+// the service and level of the entry
+func (m *Metrics) IncrementSampledOut(serviceName, level string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	levels, ok := m.sampledOut[serviceName]
+	if !ok {
+		levels = make(map[string]int64)
+		m.sampledOut[serviceName] = levels
+	}
+	levels[level]++
+}
+
+// IncrementBufferRetries increments the counter of failed storage writes returned to the
+// buffer to be retried
+func (m *Metrics) IncrementBufferRetries() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.bufferRetries++
+}
+
+// IncrementSlowConsumers increments the counter of MCP clients disconnected for not reading
+// their responses
+func (m *Metrics) IncrementSlowConsumers() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.slowConsumers++
+}
+
+// ObserveBufferFlush records the duration and number of entries of a storage write by the buffer
+func (m *Metrics) ObserveBufferFlush(duration time.Duration, entries int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.flushDuration.observe(duration.Seconds())
+	m.flushBatchSize.observe(float64(entries))
+}
+
+// ObserveIngestionStage records the time an ingestion request spent in one stage of its handling
+func (m *Metrics) ObserveIngestionStage(stage string, duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stageDuration, exists := m.ingestionStages[stage]
+	if !exists {
+		stageDuration = newHistogram(StageDurationBuckets)
+		m.ingestionStages[stage] = stageDuration
+	}
+	stageDuration.observe(duration.Seconds())
+}
+
 // GetSnapshot returns a snapshot of current metrics
 func (m *Metrics) GetSnapshot() MetricsSnapshot {
 	m.mutex.RLock()
@@ -107,6 +192,39 @@ func (m *Metrics) GetSnapshot() MetricsSnapshot {
 	
 	uptime := time.Since(m.serverStartTime)
 	
+	serviceDrops := make(map[string]int64, len(m.serviceDrops))
+	for service, count := range m.serviceDrops {
+		serviceDrops[service] = count
+	}
+
+	sampledOut := make(map[string]map[string]int64, len(m.sampledOut))
+	for service, levels := range m.sampledOut {
+		sampledOut[service] = make(map[string]int64, len(levels))
+		for level, count := range levels {
+			sampledOut[service][level] = count
+		}
+	}
+
+	ingestionStages := make(map[string]HistogramSnapshot, len(m.ingestionStages))
+	for stage, stageDuration := range m.ingestionStages {
+		ingestionStages[stage] = stageDuration.snapshot()
+	}
+
+	validationRules := make(map[string]int64, len(m.validationRules))
+	for rule, count := range m.validationRules {
+		validationRules[rule] = count
+	}
+
+	keyTraffic := make(map[string]KeyTraffic, len(m.keyTraffic))
+	for key, traffic := range m.keyTraffic {
+		snapshot := *traffic
+		snapshot.TLSVersions = make(map[string]int64, len(traffic.TLSVersions))
+		for version, count := range traffic.TLSVersions {
+			snapshot.TLSVersions[version] = count
+		}
+		keyTraffic[key] = snapshot
+	}
+	
 	return MetricsSnapshot{
 		RequestsTotal:        m.requestsTotal,
 		RequestsSuccessful:   m.requestsSuccessful,
@@ -118,6 +236,19 @@ func (m *Metrics) GetSnapshot() MetricsSnapshot {
 		StorageErrors:        m.storageErrors,
 		ValidationErrors:     m.validationErrors,
 		BufferOverflows:      m.bufferOverflows,
+		BufferDrops:          m.bufferDrops,
+		BufferRetries:        m.bufferRetries,
+		SlowConsumers:        m.slowConsumers,
+		ServiceDrops:         serviceDrops,
+		SampledOut:           sampledOut,
+		FlushDuration:        m.flushDuration.snapshot(),
+		FlushBatchSize:       m.flushBatchSize.snapshot(),
+		IngestionStages:      ingestionStages,
+		ValidationRules:      validationRules,
+		ConnectionsOpen:      m.connectionsOpen,
+		ConnectionsTotal:     m.connectionsTotal,
+		TLSHandshakeErrors:   m.tlsHandshakeErrors,
+		KeyTraffic:           keyTraffic,
 		LastRequestTime:      m.lastRequestTime,
 		ServerStartTime:      m.serverStartTime,
 		UptimeSeconds:        int64(uptime.Seconds()),
@@ -128,21 +259,34 @@ func (m *Metrics) GetSnapshot() MetricsSnapshot {
 
 // MetricsSnapshot represents a point-in-time snapshot of metrics
 type MetricsSnapshot struct {
-	RequestsTotal        int64     `json:"requests_total"`
-	RequestsSuccessful   int64     `json:"requests_successful"`
-	RequestsFailed       int64     `json:"requests_failed"`
-	LogsIngested         int64     `json:"logs_ingested"`
-	LogsBuffered         int64     `json:"logs_buffered"`
-	BufferFlushes        int64     `json:"buffer_flushes"`
-	BufferFlushErrors    int64     `json:"buffer_flush_errors"`
-	StorageErrors        int64     `json:"storage_errors"`
-	ValidationErrors     int64     `json:"validation_errors"`
-	BufferOverflows      int64     `json:"buffer_overflows"`
-	LastRequestTime      time.Time `json:"last_request_time"`
-	ServerStartTime      time.Time `json:"server_start_time"`
-	UptimeSeconds        int64     `json:"uptime_seconds"`
-	SuccessRate          float64   `json:"success_rate"`
-	ErrorRate            float64   `json:"error_rate"`
+	RequestsTotal      int64                        `json:"requests_total"`
+	RequestsSuccessful int64                        `json:"requests_successful"`
+	RequestsFailed     int64                        `json:"requests_failed"`
+	LogsIngested       int64                        `json:"logs_ingested"`
+	LogsBuffered       int64                        `json:"logs_buffered"`
+	BufferFlushes      int64                        `json:"buffer_flushes"`
+	BufferFlushErrors  int64                        `json:"buffer_flush_errors"`
+	StorageErrors      int64                        `json:"storage_errors"`
+	ValidationErrors   int64                        `json:"validation_errors"`
+	BufferOverflows    int64                        `json:"buffer_overflows"`
+	BufferDrops        int64                        `json:"buffer_drops"`   // Entries dropped for lack of buffer space
+	BufferRetries      int64                        `json:"buffer_retries"` // Failed storage writes kept in the buffer to be retried
+	SlowConsumers      int64                        `json:"slow_consumers"` // MCP clients disconnected for not reading their responses
+	ServiceDrops       map[string]int64             `json:"service_drops,omitempty"`
+	SampledOut         map[string]map[string]int64  `json:"sampled_out,omitempty"`              // Entries discarded by sampling policies, by service and level
+	FlushDuration      HistogramSnapshot            `json:"flush_duration_seconds"`             // Duration of storage writes by the buffer
+	FlushBatchSize     HistogramSnapshot            `json:"flush_batch_size"`                   // Entries per storage write by the buffer
+	IngestionStages    map[string]HistogramSnapshot `json:"ingestion_stage_seconds,omitempty"`  // Duration of each stage of ingestion requests
+	ValidationRules    map[string]int64             `json:"validation_rule_failures,omitempty"` // Failures of each validation rule
+	ConnectionsOpen    int64                        `json:"connections_open"`                   // Client connections to the ingestion server
+	ConnectionsTotal   int64                        `json:"connections_total"`                  // Client connections accepted since the server started
+	TLSHandshakeErrors int64                        `json:"tls_handshake_errors"`               // Connections closed because their TLS handshake failed
+	KeyTraffic         map[string]KeyTraffic        `json:"key_traffic,omitempty"`              // Requests by API key ID, AnonymousKey without a key
+	LastRequestTime    time.Time                    `json:"last_request_time"`
+	ServerStartTime    time.Time                    `json:"server_start_time"`
+	UptimeSeconds      int64                        `json:"uptime_seconds"`
+	SuccessRate        float64                      `json:"success_rate"`
+	ErrorRate          float64                      `json:"error_rate"`
 }
 
 // calculateSuccessRate calculates the success rate as a percentage
@@ -176,6 +320,19 @@ func (m *Metrics) Reset() {
 	m.storageErrors = 0
 	m.validationErrors = 0
 	m.bufferOverflows = 0
+	m.bufferDrops = 0
+	m.bufferRetries = 0
+	m.slowConsumers = 0
+	m.serviceDrops = make(map[string]int64)
+	m.sampledOut = make(map[string]map[string]int64)
+	m.flushDuration = newHistogram(FlushDurationBuckets)
+	m.flushBatchSize = newHistogram(FlushBatchSizeBuckets)
+	m.ingestionStages = make(map[string]*histogram)
+	m.validationRules = make(map[string]int64)
+	m.connectionsOpen = 0
+	m.connectionsTotal = 0
+	m.tlsHandshakeErrors = 0
+	m.keyTraffic = make(map[string]*KeyTraffic)
 	m.lastRequestTime = time.Time{}
 	m.serverStartTime = time.Now()
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -145,6 +146,168 @@ func TestMetrics_BufferMetrics(t *testing.T) {
 	}
 }
 
+func TestMetrics_ServiceDrops(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.IncrementServiceDrops("service-a")
+	metrics.IncrementServiceDrops("service-a")
+	metrics.IncrementServiceDrops("service-b")
+
+	snapshot := metrics.GetSnapshot()
+	if snapshot.ServiceDrops["service-a"] != 2 || snapshot.ServiceDrops["service-b"] != 1 {
+		t.Errorf("Unexpected service drops: %v", snapshot.ServiceDrops)
+	}
+
+	metrics.Reset()
+	if snapshot := metrics.GetSnapshot(); len(snapshot.ServiceDrops) != 0 {
+		t.Errorf("Expected service drops to be reset, got %v", snapshot.ServiceDrops)
+	}
+}
+
+func TestMetrics_SampledOut(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.IncrementSampledOut("service-a", "INFO")
+	metrics.IncrementSampledOut("service-a", "INFO")
+	metrics.IncrementSampledOut("service-a", "DEBUG")
+
+	snapshot := metrics.GetSnapshot()
+	if snapshot.SampledOut["service-a"]["INFO"] != 2 || snapshot.SampledOut["service-a"]["DEBUG"] != 1 {
+		t.Errorf("Unexpected sampled out entries: %v", snapshot.SampledOut)
+	}
+
+	metrics.Reset()
+	if snapshot := metrics.GetSnapshot(); len(snapshot.SampledOut) != 0 {
+		t.Errorf("Expected sampled out entries to be reset, got %v", snapshot.SampledOut)
+	}
+}
+
+func TestMetrics_Traffic(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.ConnectionOpened()
+	metrics.ConnectionOpened()
+	metrics.ConnectionClosed()
+	metrics.IncrementTLSHandshakeErrors()
+	metrics.ObserveKeyRequest("key-1", "TLS 1.3", false, 300, 40)
+	metrics.ObserveKeyRequest("key-1", "TLS 1.2", true, 100, 200)
+	metrics.ObserveKeyRequest(AnonymousKey, PlainHTTP, true, 0, 150)
+
+	snapshot := metrics.GetSnapshot()
+	if snapshot.ConnectionsOpen != 1 || snapshot.ConnectionsTotal != 2 || snapshot.TLSHandshakeErrors != 1 {
+		t.Errorf("Unexpected connection counts %d open, %d total, %d handshake errors", snapshot.ConnectionsOpen, snapshot.ConnectionsTotal, snapshot.TLSHandshakeErrors)
+	}
+	key := snapshot.KeyTraffic["key-1"]
+	if key.Requests != 2 || key.Rejected != 1 || key.BytesIn != 400 || key.BytesOut != 240 || key.TLSVersions["TLS 1.3"] != 1 || key.TLSVersions["TLS 1.2"] != 1 {
+		t.Errorf("Unexpected traffic of key-1: %+v", key)
+	}
+	if anonymous := snapshot.KeyTraffic[AnonymousKey]; anonymous.Requests != 1 || anonymous.TLSVersions[PlainHTTP] != 1 {
+		t.Errorf("Unexpected anonymous traffic: %+v", anonymous)
+	}
+
+	metrics.Reset()
+	if snapshot := metrics.GetSnapshot(); len(snapshot.KeyTraffic) != 0 || snapshot.ConnectionsTotal != 0 {
+		t.Errorf("Expected traffic to be reset, got %+v", snapshot.KeyTraffic)
+	}
+}
+
+func TestMetrics_FlushHistograms(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.ObserveBufferFlush(3*time.Millisecond, 1)
+	metrics.ObserveBufferFlush(40*time.Millisecond, 100)
+	metrics.ObserveBufferFlush(20*time.Second, 200)
+	metrics.IncrementBufferRetries()
+	metrics.IncrementServiceDrops("service-a")
+
+	snapshot := metrics.GetSnapshot()
+	if snapshot.FlushDuration.Count != 3 || snapshot.FlushBatchSize.Sum != 301 {
+		t.Errorf("Unexpected histograms: %+v %+v", snapshot.FlushDuration, snapshot.FlushBatchSize)
+	}
+	// Buckets are cumulative, the 20s write is above the largest bound
+	for _, bucket := range snapshot.FlushDuration.Buckets {
+		expected := int64(2)
+		if bucket.UpperBound < 0.04 {
+			expected = 1
+		}
+		if bucket.Count != expected {
+			t.Errorf("Expected %d observations up to %gs, got %d", expected, bucket.UpperBound, bucket.Count)
+		}
+	}
+	if snapshot.BufferRetries != 1 || snapshot.BufferDrops != 1 {
+		t.Errorf("Expected 1 retry and 1 drop, got %d and %d", snapshot.BufferRetries, snapshot.BufferDrops)
+	}
+}
+
+func TestMetrics_IngestionStages(t *testing.T) {
+	metrics := NewMetrics()
+
+	metrics.ObserveIngestionStage(StageDecode, 200*time.Microsecond)
+	metrics.ObserveIngestionStage(StageDecode, 3*time.Millisecond)
+	metrics.ObserveIngestionStage(StageBufferAdd, 50*time.Microsecond)
+
+	snapshot := metrics.GetSnapshot()
+	if len(snapshot.IngestionStages) != 2 {
+		t.Fatalf("Expected 2 stages, got %v", snapshot.IngestionStages)
+	}
+	decode := snapshot.IngestionStages[StageDecode]
+	if decode.Count != 2 || decode.Buckets[1].Count != 1 || decode.Buckets[5].Count != 2 {
+		t.Errorf("Unexpected decode histogram: %+v", decode)
+	}
+
+	metrics.Reset()
+	if snapshot := metrics.GetSnapshot(); len(snapshot.IngestionStages) != 0 {
+		t.Errorf("Expected stages to be reset, got %v", snapshot.IngestionStages)
+	}
+}
+
+func TestMetricsSnapshot_WritePrometheus(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.IncrementBufferFlushes()
+	metrics.IncrementServiceDrops(`service "a"`)
+	metrics.IncrementSampledOut("service-b", "INFO")
+	metrics.ObserveBufferFlush(30*time.Millisecond, 50)
+	metrics.ObserveIngestionStage(StageValidation, 2*time.Millisecond)
+	metrics.IncrementValidationRule("message_length")
+	metrics.IncrementValidationRule("message_length")
+	metrics.ConnectionOpened()
+	metrics.ObserveKeyRequest("key-1", "TLS 1.3", true, 120, 80)
+
+	var out strings.Builder
+	if err := metrics.GetSnapshot().WritePrometheus(&out); err != nil {
+		t.Fatalf("Failed to write metrics: %v", err)
+	}
+
+	for _, line := range []string{
+		"# TYPE mcp_logging_buffer_flushes_total counter",
+		"mcp_logging_buffer_flushes_total 1",
+		`mcp_logging_buffer_drops_total{service="service \"a\""} 1`,
+		`mcp_logging_sampled_out_total{service="service-b",level="INFO"} 1`,
+		"# TYPE mcp_logging_buffer_flush_duration_seconds histogram",
+		`mcp_logging_buffer_flush_duration_seconds_bucket{le="0.025"} 0`,
+		`mcp_logging_buffer_flush_duration_seconds_bucket{le="0.05"} 1`,
+		`mcp_logging_buffer_flush_duration_seconds_bucket{le="+Inf"} 1`,
+		`mcp_logging_buffer_flush_batch_size_bucket{le="50"} 1`,
+		"mcp_logging_buffer_flush_batch_size_sum 50",
+		"mcp_logging_buffer_flush_batch_size_count 1",
+		`mcp_logging_ingestion_stage_duration_seconds_bucket{stage="validation",le="0.001"} 0`,
+		`mcp_logging_ingestion_stage_duration_seconds_bucket{stage="validation",le="0.0025"} 1`,
+		`mcp_logging_ingestion_stage_duration_seconds_count{stage="validation"} 1`,
+		"# TYPE mcp_logging_validation_rule_failures_total counter",
+		`mcp_logging_validation_rule_failures_total{rule="message_length"} 2`,
+		"mcp_logging_connections_total 1",
+		"mcp_logging_connections_open 1",
+		`mcp_logging_key_requests_total{key="key-1",tls_version="TLS 1.3"} 1`,
+		`mcp_logging_key_rejected_requests_total{key="key-1"} 1`,
+		`mcp_logging_key_received_bytes_total{key="key-1"} 120`,
+		`mcp_logging_key_sent_bytes_total{key="key-1"} 80`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("Expected line %q in:\n%s", line, out.String())
+		}
+	}
+}
+
 func TestMetrics_ValidationAndStorageErrors(t *testing.T) {
 	metrics := NewMetrics()
 	
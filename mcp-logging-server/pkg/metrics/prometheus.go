@@ -0,0 +1,166 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// prometheusPrefix namespaces the exported metric names
+const prometheusPrefix = "mcp_logging_"
+
+// labelEscaper escapes label values as the text exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus writes the snapshot in the Prometheus text exposition format
+func (s MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	counters := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"requests_total", "Ingestion requests received.", s.RequestsTotal},
+		{"requests_successful_total", "Ingestion requests that succeeded.", s.RequestsSuccessful},
+		{"requests_failed_total", "Ingestion requests that failed.", s.RequestsFailed},
+		{"logs_ingested_total", "Log entries accepted.", s.LogsIngested},
+		{"logs_buffered_total", "Log entries added to the buffer.", s.LogsBuffered},
+		{"buffer_flushes_total", "Buffer flushes that succeeded.", s.BufferFlushes},
+		{"buffer_flush_errors_total", "Buffer flushes that failed.", s.BufferFlushErrors},
+		{"buffer_overflows_total", "Log entries that arrived at a full buffer.", s.BufferOverflows},
+		{"buffer_retries_total", "Failed storage writes kept in the buffer to be retried.", s.BufferRetries},
+		{"storage_errors_total", "Storage errors.", s.StorageErrors},
+		{"validation_errors_total", "Log entries rejected by validation.", s.ValidationErrors},
+		{"mcp_slow_consumers_total", "MCP clients disconnected for not reading their responses.", s.SlowConsumers},
+		{"connections_total", "Client connections accepted by the ingestion server.", s.ConnectionsTotal},
+		{"tls_handshake_errors_total", "Client connections whose TLS handshake failed.", s.TLSHandshakeErrors},
+	}
+	for _, counter := range counters {
+		writeHeader(out, counter.name, counter.help, "counter")
+		fmt.Fprintf(out, "%s%s %d\n", prometheusPrefix, counter.name, counter.value)
+	}
+
+	// Drops are labeled by service, sorted so that the output is stable
+	services := make([]string, 0, len(s.ServiceDrops))
+	for service := range s.ServiceDrops {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	writeHeader(out, "buffer_drops_total", "Log entries dropped for lack of buffer space.", "counter")
+	for _, service := range services {
+		fmt.Fprintf(out, "%sbuffer_drops_total{service=\"%s\"} %d\n", prometheusPrefix, labelEscaper.Replace(service), s.ServiceDrops[service])
+	}
+
+	// Sampled out entries are labeled by service and level
+	services = services[:0]
+	for service := range s.SampledOut {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	writeHeader(out, "sampled_out_total", "Log entries discarded by sampling policies.", "counter")
+	for _, service := range services {
+		levels := make([]string, 0, len(s.SampledOut[service]))
+		for level := range s.SampledOut[service] {
+			levels = append(levels, level)
+		}
+		sort.Strings(levels)
+		for _, level := range levels {
+			fmt.Fprintf(out, "%ssampled_out_total{service=\"%s\",level=\"%s\"} %d\n", prometheusPrefix, labelEscaper.Replace(service), labelEscaper.Replace(level), s.SampledOut[service][level])
+		}
+	}
+
+	rules := make([]string, 0, len(s.ValidationRules))
+	for rule := range s.ValidationRules {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	writeHeader(out, "validation_rule_failures_total", "Validation errors by the rule that failed.", "counter")
+	for _, rule := range rules {
+		fmt.Fprintf(out, "%svalidation_rule_failures_total{rule=\"%s\"} %d\n", prometheusPrefix, labelEscaper.Replace(rule), s.ValidationRules[rule])
+	}
+
+	// Traffic is labeled by API key ID, and requests by TLS version too
+	keys := make([]string, 0, len(s.KeyTraffic))
+	for key := range s.KeyTraffic {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	writeHeader(out, "key_requests_total", "Ingestion requests by API key and TLS version.", "counter")
+	for _, key := range keys {
+		versions := make([]string, 0, len(s.KeyTraffic[key].TLSVersions))
+		for version := range s.KeyTraffic[key].TLSVersions {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+		for _, version := range versions {
+			fmt.Fprintf(out, "%skey_requests_total{key=\"%s\",tls_version=\"%s\"} %d\n", prometheusPrefix, labelEscaper.Replace(key), labelEscaper.Replace(version), s.KeyTraffic[key].TLSVersions[version])
+		}
+	}
+	keyCounters := []struct {
+		name  string
+		help  string
+		value func(KeyTraffic) int64
+	}{
+		{"key_rejected_requests_total", "Ingestion requests answered with an error status by API key.", func(t KeyTraffic) int64 { return t.Rejected }},
+		{"key_received_bytes_total", "Request body bytes by API key.", func(t KeyTraffic) int64 { return t.BytesIn }},
+		{"key_sent_bytes_total", "Response body bytes by API key.", func(t KeyTraffic) int64 { return t.BytesOut }},
+	}
+	for _, counter := range keyCounters {
+		writeHeader(out, counter.name, counter.help, "counter")
+		for _, key := range keys {
+			fmt.Fprintf(out, "%s%s{key=\"%s\"} %d\n", prometheusPrefix, counter.name, labelEscaper.Replace(key), counter.value(s.KeyTraffic[key]))
+		}
+	}
+
+	writeHeader(out, "buffer_flush_duration_seconds", "Duration of storage writes by the buffer.", "histogram")
+	writeHistogram(out, "buffer_flush_duration_seconds", "", s.FlushDuration)
+	writeHeader(out, "buffer_flush_batch_size", "Log entries per storage write by the buffer.", "histogram")
+	writeHistogram(out, "buffer_flush_batch_size", "", s.FlushBatchSize)
+
+	stages := make([]string, 0, len(s.IngestionStages))
+	for stage := range s.IngestionStages {
+		stages = append(stages, stage)
+	}
+	sort.Strings(stages)
+	writeHeader(out, "ingestion_stage_duration_seconds", "Duration of each stage of ingestion requests.", "histogram")
+	for _, stage := range stages {
+		writeHistogram(out, "ingestion_stage_duration_seconds", `stage="`+labelEscaper.Replace(stage)+`"`, s.IngestionStages[stage])
+	}
+
+	writeHeader(out, "connections_open", "Client connections to the ingestion server.", "gauge")
+	fmt.Fprintf(out, "%sconnections_open %d\n", prometheusPrefix, s.ConnectionsOpen)
+
+	writeHeader(out, "uptime_seconds", "Seconds since the server started.", "gauge")
+	fmt.Fprintf(out, "%suptime_seconds %d\n", prometheusPrefix, s.UptimeSeconds)
+
+	return out.Flush()
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(out *bufio.Writer, name, help, metricType string) {
+	fmt.Fprintf(out, "# HELP %s%s %s\n", prometheusPrefix, name, help)
+	fmt.Fprintf(out, "# TYPE %s%s %s\n", prometheusPrefix, name, metricType)
+}
+
+// writeHistogram writes the buckets, sum and count of a histogram with the given labels, which
+// may be empty
+func writeHistogram(out *bufio.Writer, name, labels string, histogram HistogramSnapshot) {
+	bucketLabels := labels
+	if bucketLabels != "" {
+		bucketLabels += ","
+		labels = "{" + labels + "}"
+	}
+	for _, bucket := range histogram.Buckets {
+		fmt.Fprintf(out, "%s%s_bucket{%sle=\"%s\"} %d\n", prometheusPrefix, name, bucketLabels, strconv.FormatFloat(bucket.UpperBound, 'g', -1, 64), bucket.Count)
+	}
+	fmt.Fprintf(out, "%s%s_bucket{%sle=\"+Inf\"} %d\n", prometheusPrefix, name, bucketLabels, histogram.Count)
+	fmt.Fprintf(out, "%s%s_sum%s %s\n", prometheusPrefix, name, labels, strconv.FormatFloat(histogram.Sum, 'g', -1, 64))
+	fmt.Fprintf(out, "%s%s_count%s %d\n", prometheusPrefix, name, labels, histogram.Count)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+// AnonymousKey is the key requests without an API key are counted under
+const AnonymousKey = "anonymous"
+
+// PlainHTTP is the TLS version of requests that did not use TLS
+const PlainHTTP = "none"
+
+// KeyTraffic counts the ingestion requests of an API key
+type KeyTraffic struct {
+	Requests    int64            `json:"requests"`
+	Rejected    int64            `json:"rejected"`     // Answered with an error status
+	BytesIn     int64            `json:"bytes_in"`     // Request body bytes, compressed as sent
+	BytesOut    int64            `json:"bytes_out"`    // Response body bytes
+	TLSVersions map[string]int64 `json:"tls_versions"` // Requests by TLS version, PlainHTTP without TLS
+}
+
+// ConnectionOpened counts a client connection accepted by the ingestion server
+func (m *Metrics) ConnectionOpened() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.connectionsOpen++
+	m.connectionsTotal++
+}
+
+// ConnectionClosed counts a client connection that was closed or taken over by its handler
+func (m *Metrics) ConnectionClosed() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.connectionsOpen--
+}
+
+// IncrementTLSHandshakeErrors counts a connection whose TLS handshake failed
+func (m *Metrics) IncrementTLSHandshakeErrors() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.tlsHandshakeErrors++
+}
+
+// ObserveKeyRequest counts a request of an API key, identified by its ID, with the TLS version it
+// used and the bytes it transferred
+func (m *Metrics) ObserveKeyRequest(key, tlsVersion string, rejected bool, bytesIn, bytesOut int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	traffic, ok := m.keyTraffic[key]
+	if !ok {
+		traffic = &KeyTraffic{TLSVersions: make(map[string]int64)}
+		m.keyTraffic[key] = traffic
+	}
+	traffic.Requests++
+	if rejected {
+		traffic.Rejected++
+	}
+	traffic.BytesIn += bytesIn
+	traffic.BytesOut += bytesOut
+	traffic.TLSVersions[tlsVersion]++
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// crashSignatureFrames is the number of top frames of the crashed thread that identify a crash
+const crashSignatureFrames = 5
+
+// CrashInfo describes an application crash reported by a device
+type CrashInfo struct {
+	Signal        string       `json:"signal,omitempty" validate:"max=50"`          // e.g. SIGSEGV or EXC_BAD_ACCESS
+	ExceptionType string       `json:"exception_type,omitempty" validate:"max=200"` // e.g. java.lang.NullPointerException
+	Reason        string       `json:"reason,omitempty" validate:"max=2000"`
+	Threads       []ThreadDump `json:"threads,omitempty" validate:"max=500,dive"`
+	Breadcrumbs   []Breadcrumb `json:"breadcrumbs,omitempty" validate:"max=200,dive"`
+	Signature     string       `json:"signature,omitempty" validate:"max=100"` // Groups crashes with the same cause, computed at ingestion when empty
+	Symbolicated  bool         `json:"symbolicated,omitempty"`
+}
+
+// ThreadDump is the stack of one thread at the time of a crash, innermost frame first
+type ThreadDump struct {
+	ID      string       `json:"id,omitempty" validate:"max=100"`
+	Name    string       `json:"name,omitempty" validate:"max=200"`
+	Crashed bool         `json:"crashed,omitempty"`
+	Frames  []StackFrame `json:"frames" validate:"max=1000"`
+}
+
+// StackFrame is one frame of a thread dump. Unsymbolicated frames only carry an address
+// until a symbolication hook resolves the function, file and line
+type StackFrame struct {
+	Function string `json:"function,omitempty"`
+	Module   string `json:"module,omitempty"` // Binary image, library or package the frame belongs to
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Address  string `json:"address,omitempty"` // Instruction address, e.g. 0x1024a3f0c
+	InApp    bool   `json:"in_app,omitempty"`  // Frame belongs to the application rather than a system library
+}
+
+// Breadcrumb is an event recorded by the app shortly before it crashed
+type Breadcrumb struct {
+	Timestamp time.Time         `json:"timestamp"`
+	Category  string            `json:"category,omitempty" validate:"max=100"` // e.g. navigation, network or ui
+	Message   string            `json:"message" validate:"max=1000"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// CrashGroup summarizes the crash reports sharing a signature
+type CrashGroup struct {
+	Signature      string    `json:"signature"`
+	Title          string    `json:"title"`
+	Count          int       `json:"count"`
+	AffectedAgents int       `json:"affected_agents"`
+	Services       []string  `json:"services"`
+	FirstSeen      time.Time `json:"first_seen"`
+	LastSeen       time.Time `json:"last_seen"`
+	LatestID       string    `json:"latest_id"` // Most recent report, for get_log_details
+}
+
+// CrashedThread returns the thread marked as crashed, or the first thread if none is marked
+func (c *CrashInfo) CrashedThread() *ThreadDump {
+	for i := range c.Threads {
+		if c.Threads[i].Crashed {
+			return &c.Threads[i]
+		}
+	}
+	if len(c.Threads) > 0 {
+		return &c.Threads[0]
+	}
+	return nil
+}
+
+// Kind returns the exception type, or the signal for native crashes
+func (c *CrashInfo) Kind() string {
+	if c.ExceptionType != "" {
+		return c.ExceptionType
+	}
+	if c.Signal != "" {
+		return c.Signal
+	}
+	return "crash"
+}
+
+// Culprit returns the innermost named frame of the crashed thread, preferring application frames
+func (c *CrashInfo) Culprit() string {
+	frames := c.signatureFrames()
+	if len(frames) == 0 {
+		return ""
+	}
+	return frames[0]
+}
+
+// Title returns a short description of the crash for listings
+func (c *CrashInfo) Title() string {
+	title := c.Kind()
+	if culprit := c.Culprit(); culprit != "" {
+		title += " in " + culprit
+	}
+	return title
+}
+
+// ComputeSignature returns a stable identifier for crashes with the same cause, derived from the
+// crash kind and the top frames of the crashed thread. Lines and addresses are left out so that
+// the same crash keeps its signature across builds.
+func (c *CrashInfo) ComputeSignature() string {
+	parts := append([]string{c.Kind()}, c.signatureFrames()...)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// signatureFrames returns up to crashSignatureFrames named frames of the crashed thread, only
+// application frames when there are any
+func (c *CrashInfo) signatureFrames() []string {
+	thread := c.CrashedThread()
+	if thread == nil {
+		return nil
+	}
+
+	hasInApp := false
+	for _, frame := range thread.Frames {
+		if frame.InApp && frame.Function != "" {
+			hasInApp = true
+			break
+		}
+	}
+
+	var frames []string
+	for _, frame := range thread.Frames {
+		if frame.Function == "" || (hasInApp && !frame.InApp) {
+			continue
+		}
+		name := frame.Function
+		if frame.Module != "" {
+			name = frame.Module + "!" + frame.Function
+		}
+		frames = append(frames, name)
+		if len(frames) == crashSignatureFrames {
+			break
+		}
+	}
+	return frames
+}
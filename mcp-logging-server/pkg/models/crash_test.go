@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestCrashInfo_Signature(t *testing.T) {
+	crash := CrashInfo{
+		ExceptionType: "java.lang.NullPointerException",
+		Threads: []ThreadDump{
+			{Name: "worker", Frames: []StackFrame{{Function: "Worker.run", InApp: true}}},
+			{Name: "main", Crashed: true, Frames: []StackFrame{
+				{Function: "ArrayList.get", Module: "java.util"},
+				{Function: "CheckoutActivity.onResume", Module: "com.example", File: "CheckoutActivity.kt", Line: 42, InApp: true},
+				{Address: "0x1024a3f0c", InApp: true},
+				{Function: "Activity.performResume", Module: "android.app"},
+			}},
+		},
+	}
+
+	if title := crash.Title(); title != "java.lang.NullPointerException in com.example!CheckoutActivity.onResume" {
+		t.Errorf("Unexpected title: %s", title)
+	}
+
+	signature := crash.ComputeSignature()
+	if len(signature) != 32 {
+		t.Errorf("Expected a 32 character signature, got %q", signature)
+	}
+
+	// Lines, addresses and system frames do not change the signature
+	rebuilt := crash
+	rebuilt.Threads = []ThreadDump{{Crashed: true, Frames: []StackFrame{
+		{Function: "CheckoutActivity.onResume", Module: "com.example", Line: 57, InApp: true},
+		{Function: "Instrumentation.callActivityOnResume", Module: "android.app"},
+	}}}
+	if rebuilt.ComputeSignature() != signature {
+		t.Error("Expected the same signature for a crash in a different build")
+	}
+
+	other := crash
+	other.ExceptionType = "java.lang.IllegalStateException"
+	if other.ComputeSignature() == signature {
+		t.Error("Expected a different signature for a different exception")
+	}
+
+	native := CrashInfo{Signal: "SIGSEGV"}
+	if native.Title() != "SIGSEGV" || native.ComputeSignature() == signature {
+		t.Errorf("Unexpected native crash title %q", native.Title())
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// MaxIncidentLogIDs is the number of log IDs that can be attached to an incident at once
+const MaxIncidentLogIDs = 1000
+
+// Incident is a durable reference to the log entries supporting an incident, for postmortems.
+// Entries are attached by ID or matched by a snapshot of a filter.
+type Incident struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title" validate:"required,max=200"`
+	Description string     `json:"description,omitempty" validate:"max=5000"`
+	LogIDs      []string   `json:"log_ids,omitempty" validate:"max=1000,dive,required,max=100"` // In the order they were attached
+	Filter      *LogFilter `json:"filter,omitempty"`                                            // Snapshot taken by SnapshotFilter
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// IncidentAttachment adds evidence to an existing incident
+type IncidentAttachment struct {
+	LogIDs []string   `json:"log_ids,omitempty" validate:"max=1000,dive,required,max=100"` // Added to the attached IDs
+	Filter *LogFilter `json:"filter,omitempty"`                                            // Replaces the incident's filter
+}
+
+// SnapshotFilter returns a copy of a filter fit to be kept with an incident: paging and search
+// options are dropped, and an open end time is fixed at now, so that entries logged later do not
+// join the incident
+func SnapshotFilter(filter LogFilter, now time.Time) *LogFilter {
+	snapshot := LogFilter{
+		ServiceName:     filter.ServiceName,
+		AgentID:         filter.AgentID,
+		RequestID:       filter.RequestID,
+		TemplateID:      filter.TemplateID,
+		Level:           filter.Level,
+		MinLevel:        filter.MinLevel,
+		StartTime:       filter.StartTime,
+		EndTime:         filter.EndTime,
+		TimeField:       filter.TimeField,
+		MessageContains: filter.MessageContains,
+		Platform:        filter.Platform,
+		TagsAny:         filter.TagsAny,
+		TagsAll:         filter.TagsAll,
+		CrashesOnly:     filter.CrashesOnly,
+		CrashSignature:  filter.CrashSignature,
+	}
+	if snapshot.EndTime.IsZero() {
+		snapshot.EndTime = now.UTC()
+	}
+	return &snapshot
+}
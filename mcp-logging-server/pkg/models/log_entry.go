@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"regexp"
 	"strings"
 	"time"
@@ -20,6 +21,51 @@ const (
 	LogLevelFatal LogLevel = "FATAL"
 )
 
+// Severity returns the rank of the level, higher is more severe. Unknown levels rank lowest.
+func (l LogLevel) Severity() int {
+	switch l {
+	case LogLevelDebug:
+		return 1
+	case LogLevelInfo:
+		return 2
+	case LogLevelWarn:
+		return 3
+	case LogLevelError:
+		return 4
+	case LogLevelFatal:
+		return 5
+	}
+	return 0
+}
+
+// LevelsFrom returns the known levels at least as severe as min, least severe first
+func LevelsFrom(min LogLevel) []LogLevel {
+	var levels []LogLevel
+	for _, level := range []LogLevel{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal} {
+		if level.Severity() >= min.Severity() {
+			levels = append(levels, level)
+		}
+	}
+	return levels
+}
+
+// UnmarshalJSON accepts both string levels and numeric (syslog style) levels
+func (l *LogLevel) UnmarshalJSON(data []byte) error {
+	var level string
+	if err := json.Unmarshal(data, &level); err == nil {
+		*l = LogLevel(level)
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("level must be a string or a number: %w", err)
+	}
+
+	*l = LogLevel(number.String())
+	return nil
+}
+
 // Platform represents the platform/SDK that generated the log
 type Platform string
 
@@ -32,6 +78,18 @@ const (
 	PlatformKotlin       Platform = "kotlin"
 )
 
+// DefaultPlatforms returns the platforms accepted when no platform list is configured
+func DefaultPlatforms() []Platform {
+	return []Platform{
+		PlatformGo,
+		PlatformSwift,
+		PlatformExpress,
+		PlatformReact,
+		PlatformReactNative,
+		PlatformKotlin,
+	}
+}
+
 // DeviceInfo contains platform-specific device information
 type DeviceInfo struct {
 	Platform   string `json:"platform" validate:"required"`
@@ -52,14 +110,21 @@ type LogEntry struct {
 	ID             string                 `json:"id" validate:"required,uuid4"`
 	Timestamp      time.Time              `json:"timestamp" validate:"required"`
 	Level          LogLevel               `json:"level" validate:"required,oneof=DEBUG INFO WARN ERROR FATAL"`
-	Message        string                 `json:"message" validate:"required,max=10000,log_message"`
+	Message        string                 `json:"message" validate:"required,log_message"`
 	ServiceName    string                 `json:"service_name" validate:"required,max=100,service_name"`
 	AgentID        string                 `json:"agent_id" validate:"required,max=100,agent_id"`
-	Platform       Platform               `json:"platform" validate:"required,oneof=go swift express react react-native kotlin"`
+	Platform       Platform               `json:"platform" validate:"required,max=50,platform"`
+	RequestID      string                 `json:"request_id,omitempty" validate:"max=128"` // Request the entry was logged for, the most common correlation key
+	TemplateID     string                 `json:"template_id,omitempty"`                   // ID of the message template, set at ingestion, see MessageTemplateID
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 	DeviceInfo     *DeviceInfo            `json:"device_info,omitempty"`
 	StackTrace     string                 `json:"stack_trace,omitempty"`
 	SourceLocation *SourceLocation        `json:"source_location,omitempty"`
+	Tags           []string               `json:"tags,omitempty" validate:"max=20,dive,required,max=50"`
+	ReceivedAt     time.Time              `json:"received_at,omitempty"`
+	ClockSkewed    bool                   `json:"clock_skewed,omitempty"`
+	Crash          *CrashInfo             `json:"crash,omitempty"`
+	Annotations    []LogAnnotation        `json:"annotations,omitempty"` // Added by operators after ingestion, ignored when ingested
 }
 
 // Validate validates the log entry using struct tags
@@ -84,6 +149,13 @@ func (le *LogEntry) Validate() error {
 		return len(strings.TrimSpace(message)) > 0
 	})
 	
+	// The accepted platform list is configurable and enforced at ingestion,
+	// the model only requires a non-blank platform
+	validate.RegisterValidation("platform", func(fl validator.FieldLevel) bool {
+		platform := fl.Field().String()
+		return len(strings.TrimSpace(platform)) > 0
+	})
+	
 	return validate.Struct(le)
 }
 
@@ -101,24 +173,118 @@ func FromJSON(data []byte) (*LogEntry, error) {
 	return &le, nil
 }
 
+// TimeField selects which timestamp a time range filter applies to
+type TimeField string
+
+const (
+	// TimeFieldTimestamp filters on the client-reported timestamp
+	TimeFieldTimestamp TimeField = "timestamp"
+	// TimeFieldReceivedAt filters on the server receive time
+	TimeFieldReceivedAt TimeField = "received_at"
+)
+
 // LogFilter represents filtering criteria for log queries
 type LogFilter struct {
-	ServiceName     string    `json:"service_name,omitempty"`
-	AgentID         string    `json:"agent_id,omitempty"`
-	Level           LogLevel  `json:"level,omitempty"`
-	StartTime       time.Time `json:"start_time,omitempty"`
-	EndTime         time.Time `json:"end_time,omitempty"`
-	MessageContains string    `json:"message_contains,omitempty"`
-	Platform        Platform  `json:"platform,omitempty"`
-	Limit           int       `json:"limit,omitempty"`
-	Offset          int       `json:"offset,omitempty"`
+	ServiceName     string     `json:"service_name,omitempty"`
+	AgentID         string     `json:"agent_id,omitempty"`
+	RequestID       string     `json:"request_id,omitempty"`
+	TemplateID      string     `json:"template_id,omitempty"` // Match entries whose message has this template, see MessageTemplateID
+	Level           LogLevel   `json:"level,omitempty"`
+	MinLevel        LogLevel   `json:"min_level,omitempty"` // Match entries of this level or a more severe one
+	StartTime       time.Time  `json:"start_time,omitempty"`
+	EndTime         time.Time  `json:"end_time,omitempty"`
+	TimeField       TimeField  `json:"time_field,omitempty"`
+	MessageContains string     `json:"message_contains,omitempty"`
+	Platform        Platform   `json:"platform,omitempty"`
+	TagsAny         []string   `json:"tags_any,omitempty"`        // Match entries with at least one of these tags
+	TagsAll         []string   `json:"tags_all,omitempty"`        // Match entries with every one of these tags
+	Fuzziness       int        `json:"fuzziness,omitempty"`       // Full-text search only: allowed edit distance per term, 0 to MaxSearchFuzziness
+	Prefix          bool       `json:"prefix,omitempty"`          // Full-text search only: also match words starting with the query terms
+	Facets          []string   `json:"facets,omitempty"`          // Full-text search only: facet counts to compute alongside the hits
+	Sort            SearchSort `json:"sort,omitempty"`            // Full-text search only: order of the hits, time when empty
+	CrashesOnly     bool       `json:"crashes_only,omitempty"`    // Match crash reports only
+	CrashSignature  string     `json:"crash_signature,omitempty"` // Match crash reports with this signature
+	Limit           int        `json:"limit,omitempty"`
+	Offset          int        `json:"offset,omitempty"`
+}
+
+// MaxSearchFuzziness is the largest edit distance supported by fuzzy full-text search
+const MaxSearchFuzziness = 2
+
+// SearchSort orders full-text search hits
+type SearchSort string
+
+const (
+	// SearchSortTime orders hits newest first
+	SearchSortTime SearchSort = "time"
+	// SearchSortRelevance orders hits by search score boosted for recent entries
+	SearchSortRelevance SearchSort = "relevance"
+)
+
+// IsValid checks if the sort order is supported, empty defaults to time
+func (s SearchSort) IsValid() bool {
+	return s == "" || s == SearchSortTime || s == SearchSortRelevance
+}
+
+// Facets that can be requested with full-text search
+const (
+	FacetLevel    = "level"    // Counts by log level
+	FacetService  = "service"  // Counts by service name
+	FacetPlatform = "platform" // Counts by platform
+	FacetDay      = "day"      // Counts by UTC day of the log timestamp
+)
+
+// SearchFacetNames returns the facets that can be requested with full-text search
+func SearchFacetNames() []string {
+	return []string{FacetLevel, FacetService, FacetPlatform, FacetDay}
+}
+
+// IsValidFacet checks if a facet can be requested with full-text search
+func IsValidFacet(name string) bool {
+	for _, facet := range SearchFacetNames() {
+		if facet == name {
+			return true
+		}
+	}
+	return false
+}
+
+// FacetCount is the number of matching log entries sharing a facet value
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
 // LogResult represents the result of a log query
 type LogResult struct {
-	Logs       []LogEntry `json:"logs"`
-	TotalCount int        `json:"total_count"`
-	HasMore    bool       `json:"has_more"`
+	Logs       []LogEntry              `json:"logs"`
+	TotalCount int                     `json:"total_count"`
+	HasMore    bool                    `json:"has_more"`
+	Facets     map[string][]FacetCount `json:"facets,omitempty"` // Set when full-text search served a query requesting facets
+}
+
+// SearchMatch is the position of a matched term within a log entry field
+type SearchMatch struct {
+	Field string `json:"field"`
+	Term  string `json:"term"`
+	Start int    `json:"start"` // Byte offset of the match start
+	End   int    `json:"end"`   // Byte offset just past the match end
+}
+
+// SearchHit is a log entry matching a full-text search with the reasons it matched
+type SearchHit struct {
+	Log       LogEntry            `json:"log"`
+	Score     float64             `json:"score"`
+	Fragments map[string][]string `json:"fragments,omitempty"` // Highlighted snippets by field
+	Matches   []SearchMatch       `json:"matches,omitempty"`
+}
+
+// SearchResult represents the result of a full-text search
+type SearchResult struct {
+	Hits       []SearchHit             `json:"hits"`
+	TotalCount int                     `json:"total_count"`
+	HasMore    bool                    `json:"has_more"`
+	Facets     map[string][]FacetCount `json:"facets,omitempty"`
 }
 
 // HealthStatus represents the health status of a service
@@ -135,4 +301,100 @@ type ServiceInfo struct {
 	Platform    Platform  `json:"platform"`
 	LastSeen    time.Time `json:"last_seen"`
 	LogCount    int       `json:"log_count"`
-}
\ No newline at end of file
+
+	LevelCounts  map[LogLevel]int     `json:"level_counts,omitempty"` // Stored entries per level
+	Registration *ServiceRegistration `json:"registration,omitempty"`
+	Stale        bool                 `json:"stale,omitempty"` // Not seen since the filter's StaleBefore
+}
+
+// ServiceSort orders the services returned by GetServices
+type ServiceSort string
+
+const (
+	// ServiceSortLastSeen orders services most recently seen first
+	ServiceSortLastSeen ServiceSort = "last_seen"
+	// ServiceSortName orders services by name, then agent ID
+	ServiceSortName ServiceSort = "name"
+	// ServiceSortLogCount orders services with the most stored entries first
+	ServiceSortLogCount ServiceSort = "log_count"
+)
+
+// IsValid checks if the sort order is supported, empty defaults to last_seen
+func (s ServiceSort) IsValid() bool {
+	return s == "" || s == ServiceSortLastSeen || s == ServiceSortName || s == ServiceSortLogCount
+}
+
+// ServiceFilter selects and pages the services returned by GetServices, the zero value returns
+// all of them
+type ServiceFilter struct {
+	NamePrefix  string      `json:"name_prefix,omitempty"` // Case-sensitive prefix of the service name
+	Platform    Platform    `json:"platform,omitempty"`
+	ActiveSince time.Time   `json:"active_since,omitempty"` // Only services that logged at or after this time
+	StaleBefore time.Time   `json:"stale_before,omitempty"` // Flag services that last logged before this time as stale
+	Sort        ServiceSort `json:"sort,omitempty"`
+	Limit       int         `json:"limit,omitempty"` // 0 returns all matching services
+	Offset      int         `json:"offset,omitempty"`
+}
+
+// PlatformCount counts the services of a platform and their stored entries
+type PlatformCount struct {
+	Services int `json:"services"`
+	Logs     int `json:"logs"`
+}
+
+// ServiceResult is a page of the services matching a ServiceFilter
+type ServiceResult struct {
+	Services   []ServiceInfo              `json:"services"`
+	TotalCount int                        `json:"total_count"`
+	HasMore    bool                       `json:"has_more"`
+	Platforms  map[Platform]PlatformCount `json:"platforms"` // Of all matching services, not only the page
+}
+
+// ServiceRegistration contains ownership and operational metadata registered for a service
+type ServiceRegistration struct {
+	ServiceName string    `json:"service_name" validate:"required,max=100,service_name"`
+	OwnerTeam   string    `json:"owner_team" validate:"required,max=100"`
+	RepoURL     string    `json:"repo_url,omitempty" validate:"omitempty,url,max=500"`
+	RunbookURL  string    `json:"runbook_url,omitempty" validate:"omitempty,url,max=500"`
+	Environment string    `json:"environment,omitempty" validate:"omitempty,max=50"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// LogAnnotation is an operator note attached to a stored log entry, such as a ticket reference
+// or a false positive verdict. Annotations never change the content of the entry.
+type LogAnnotation struct {
+	Text      string    `json:"text" validate:"required,max=1000"`
+	Author    string    `json:"author,omitempty" validate:"max=100"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LegalHold protects the log entries of a service and time range from deletion until it is released
+type LegalHold struct {
+	ID          string    `json:"id"`
+	ServiceName string    `json:"service_name,omitempty" validate:"omitempty,max=100,service_name"` // Empty holds every service
+	StartTime   time.Time `json:"start_time,omitempty"`                                             // Zero holds all entries before EndTime
+	EndTime     time.Time `json:"end_time,omitempty"`                                               // Zero holds all entries after StartTime
+	Reason      string    `json:"reason" validate:"required,max=500"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SyncEntry is a log entry uploaded through delta sync, numbered by the agent that produced it
+type SyncEntry struct {
+	LogEntry
+	Sequence int64 `json:"sequence"`
+}
+
+// SyncRequest uploads an agent's log entries in increasing sequence order, so that the agent can
+// resume after reconnecting without creating duplicates
+type SyncRequest struct {
+	AgentID string      `json:"agent_id"`
+	Entries []SyncEntry `json:"entries"`
+}
+
+// SyncState records how far an agent's delta sync has progressed
+type SyncState struct {
+	AgentID      string    `json:"agent_id"`
+	LastSequence int64     `json:"last_sequence"` // Highest sequence accepted, 0 before the first upload
+	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Interfaces that queries are audited on
+const (
+	QueryInterfaceMCP  = "mcp"  // Tool calls of MCP clients
+	QueryInterfaceREST = "rest" // Requests to the HTTP API
+)
+
+// QueryAudit records who queried log entries, what they asked for and what they were shown, so
+// that it can be told afterwards which logs someone viewed
+type QueryAudit struct {
+	ID           int64                  `json:"id"`
+	Time         time.Time              `json:"time"`                    // When the query started
+	Interface    string                 `json:"interface"`               // QueryInterfaceMCP or QueryInterfaceREST
+	Actor        string                 `json:"actor,omitempty"`         // API key ID of REST queries, client name of MCP queries
+	Source       string                 `json:"source,omitempty"`        // Address of the client
+	Operation    string                 `json:"operation"`               // MCP tool, or method and route of REST queries
+	Filter       map[string]interface{} `json:"filter,omitempty"`        // Tool arguments or query parameters
+	ResultCount  int                    `json:"result_count"`            // Log entries returned
+	MaskedFields []string               `json:"masked_fields,omitempty"` // Fields masked in the returned entries
+	LatencyMs    int64                  `json:"latency_ms"`
+	Error        string                 `json:"error,omitempty"` // Why the query failed, empty if it succeeded
+	RequestID    string                 `json:"request_id,omitempty"`
+}
+
+// QueryAuditFilter selects query audit records. Empty fields match every record.
+type QueryAuditFilter struct {
+	Actor     string    `json:"actor,omitempty"`
+	Interface string    `json:"interface,omitempty"`
+	Operation string    `json:"operation,omitempty"`
+	StartTime time.Time `json:"start_time,omitempty"`
+	EndTime   time.Time `json:"end_time,omitempty"`
+	Limit     int       `json:"limit,omitempty"`
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogSummary counts the entries of one service, level and message template received in one
+// hour that compaction replaced
+type LogSummary struct {
+	Hour        time.Time `json:"hour"` // UTC start of the hour, by entry timestamp
+	ServiceName string    `json:"service_name"`
+	Level       LogLevel  `json:"level"`
+	Template    string    `json:"template"` // Message with variable parts replaced, see MessageTemplate
+	Count       int64     `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// SummaryFilter selects log summaries. Times are inclusive and compared with the summary hour.
+type SummaryFilter struct {
+	ServiceName string    `json:"service_name,omitempty"`
+	Level       LogLevel  `json:"level,omitempty"`
+	StartTime   time.Time `json:"start_time,omitempty"`
+	EndTime     time.Time `json:"end_time,omitempty"`
+	Limit       int       `json:"limit,omitempty"`
+}
+
+// MaxTemplateLength is the number of characters message templates are cut to
+const MaxTemplateLength = 500
+
+// Variable parts of messages replaced by MessageTemplate
+var (
+	quotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{8,}\b`)
+	numberPattern = regexp.MustCompile(`\b\d+(\.\d+)?\b`)
+)
+
+// MessageTemplate returns the message with quoted strings, UUIDs, hexadecimal values of at
+// least 8 digits and numbers replaced by placeholders, so that messages differing only in such
+// values share a template
+func MessageTemplate(message string) string {
+	template := quotedPattern.ReplaceAllString(message, "<str>")
+	template = uuidPattern.ReplaceAllString(template, "<uuid>")
+	template = hexPattern.ReplaceAllStringFunc(template, func(value string) string {
+		// Plain numbers are left to the number placeholder
+		if strings.Trim(value, "0123456789") == "" {
+			return value
+		}
+		return "<hex>"
+	})
+	template = numberPattern.ReplaceAllString(template, "<num>")
+	template = strings.Join(strings.Fields(template), " ")
+
+	if runes := []rune(template); len(runes) > MaxTemplateLength {
+		template = string(runes[:MaxTemplateLength])
+	}
+	return template
+}
+
+// TemplateID returns the ID of a message template, a hash of it short enough to store with every
+// entry and filter on
+func TemplateID(template string) string {
+	h := fnv.New64a()
+	h.Write([]byte(template))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// MessageTemplateID returns the ID of the template of a message. Entries store it at ingestion,
+// so that grouping and filtering by template need not normalize their messages again.
+func MessageTemplateID(message string) string {
+	return TemplateID(MessageTemplate(message))
+}
+
+// MessageGroup is a set of log entries sharing a message template, represented by the most
+// recent of them
+type MessageGroup struct {
+	TemplateID string    `json:"template_id"`
+	Template   string    `json:"template"`
+	Count      int       `json:"count"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Entry      LogEntry  `json:"entry"` // Most recent entry of the group
+}
+
+// GroupByTemplate groups entries by their message template, largest group first and groups of
+// the same size by most recent entry. Entries are grouped by their stored template ID, only the
+// messages of entries stored without one and of the first entry of each group are normalized.
+func GroupByTemplate(entries []LogEntry) []MessageGroup {
+	groups := make([]MessageGroup, 0)
+	index := make(map[string]int)
+	for _, entry := range entries {
+		id, template := entry.TemplateID, ""
+		if id == "" {
+			template = MessageTemplate(entry.Message)
+			id = TemplateID(template)
+		}
+		i, ok := index[id]
+		if !ok {
+			if template == "" {
+				template = MessageTemplate(entry.Message)
+			}
+			index[id] = len(groups)
+			groups = append(groups, MessageGroup{
+				TemplateID: id,
+				Template:   template,
+				Count:      1,
+				FirstSeen:  entry.Timestamp,
+				LastSeen:   entry.Timestamp,
+				Entry:      entry,
+			})
+			continue
+		}
+
+		group := &groups[i]
+		group.Count++
+		if entry.Timestamp.Before(group.FirstSeen) {
+			group.FirstSeen = entry.Timestamp
+		}
+		if entry.Timestamp.After(group.LastSeen) {
+			group.LastSeen = entry.Timestamp
+			group.Entry = entry
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].LastSeen.After(groups[j].LastSeen)
+	})
+	return groups
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageTemplate(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"Order 1234 placed for 19.99 EUR", "Order <num> placed for <num> EUR"},
+		{"User 'alice' logged in", "User <str> logged in"},
+		{`Request 550e8400-e29b-41d4-a716-446655440000 failed`, "Request <uuid> failed"},
+		{"Commit deadbeef42 deployed at 0x1f", "Commit <hex> deployed at <hex>"},
+		{"Retrying  in\t5 seconds", "Retrying in <num> seconds"},
+		{"Cache warmed", "Cache warmed"},
+	}
+
+	for _, tt := range tests {
+		if template := MessageTemplate(tt.message); template != tt.expected {
+			t.Errorf("MessageTemplate(%q) = %q, expected %q", tt.message, template, tt.expected)
+		}
+	}
+
+	// Messages differing only in their values share a template
+	if MessageTemplate("Order 1 placed") != MessageTemplate("Order 2 placed") {
+		t.Error("Expected orders to share a template")
+	}
+
+	if long := MessageTemplate(strings.Repeat("x", MaxTemplateLength+10)); len(long) != MaxTemplateLength {
+		t.Errorf("Expected template cut to %d characters, got %d", MaxTemplateLength, len(long))
+	}
+}
+
+func TestGroupByTemplate(t *testing.T) {
+	now := time.Now()
+	entries := []LogEntry{
+		{ID: "1", Message: "Order 1 placed", Timestamp: now.Add(-3 * time.Minute)},
+		{ID: "2", Message: "Cache warmed", Timestamp: now.Add(-2 * time.Minute)},
+		{ID: "3", Message: "Order 22 placed", Timestamp: now},
+		{ID: "4", Message: "Order 333 placed", Timestamp: now.Add(-time.Minute)},
+	}
+
+	groups := GroupByTemplate(entries)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	orders := groups[0]
+	if orders.Template != "Order <num> placed" || orders.Count != 3 || orders.Entry.ID != "3" {
+		t.Errorf("Expected the largest group first with its most recent entry, got %+v", orders)
+	}
+	if !orders.FirstSeen.Equal(entries[0].Timestamp) || !orders.LastSeen.Equal(now) {
+		t.Errorf("Unexpected first or last seen %v %v", orders.FirstSeen, orders.LastSeen)
+	}
+	if groups[1].Template != "Cache warmed" || groups[1].Count != 1 {
+		t.Errorf("Unexpected group %+v", groups[1])
+	}
+}
+
+func TestGroupByTemplate_StoredTemplateID(t *testing.T) {
+	now := time.Now()
+	entries := []LogEntry{
+		{ID: "1", Message: "Order 1 placed", TemplateID: MessageTemplateID("Order 1 placed"), Timestamp: now},
+		{ID: "2", Message: "Order 22 placed", Timestamp: now}, // Stored before template IDs
+		{ID: "3", Message: "Cache warmed", TemplateID: MessageTemplateID("Cache warmed"), Timestamp: now},
+	}
+
+	groups := GroupByTemplate(entries)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Count != 2 || groups[0].Template != "Order <num> placed" || groups[0].TemplateID != entries[0].TemplateID {
+		t.Errorf("Expected entries with and without a stored template ID in one group, got %+v", groups[0])
+	}
+	if MessageTemplateID("Order 1 placed") == MessageTemplateID("Cache warmed") {
+		t.Error("Expected different templates to have different IDs")
+	}
+}
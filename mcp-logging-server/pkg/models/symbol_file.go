@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// SymbolFileKind identifies the format of a symbol file
+type SymbolFileKind string
+
+const (
+	SymbolFileKindSourceMap SymbolFileKind = "sourcemap" // Source map v3 of a React or React Native bundle
+	SymbolFileKindProGuard  SymbolFileKind = "proguard"  // ProGuard or R8 mapping of an obfuscated Kotlin or Java build
+)
+
+// SymbolFile is a source map or mapping file uploaded for one version of a service, used to
+// symbolicate the stack traces that version reports
+type SymbolFile struct {
+	ServiceName string         `json:"service_name" validate:"required,max=100,service_name"`
+	Version     string         `json:"version" validate:"required,max=100"` // App version the stack traces report, see LogEntry.ReleaseVersion
+	Name        string         `json:"name" validate:"required,max=200"`    // Source maps are looked up by the name of the bundle they map, e.g. main.jsbundle
+	Kind        SymbolFileKind `json:"kind" validate:"required,oneof=sourcemap proguard"`
+	Size        int            `json:"size"`
+	UploadedAt  time.Time      `json:"uploaded_at"`
+	Content     []byte         `json:"-"`
+}
+
+// ReleaseVersion returns the app version that produced a log entry, which selects the symbol
+// files used for its stack trace: the device app version, or else a "version" metadata field
+func (le *LogEntry) ReleaseVersion() string {
+	if le.DeviceInfo != nil && le.DeviceInfo.AppVersion != "" {
+		return le.DeviceInfo.AppVersion
+	}
+	if version, ok := le.Metadata["version"].(string); ok {
+		return version
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Zone names resolve without the tz database of the host, which minimal containers lack
+	_ "time/tzdata"
+)
+
+// Layouts of times without a zone offset, interpreted in the time zone of the query
+var localTimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// TimeExpressionFormats describes the formats ParseTimeExpression accepts, for error hints
+var TimeExpressionFormats = []string{
+	"RFC3339 (2024-05-01T10:00:00Z)",
+	"local time (2024-05-01 10:00 or 2024-05-01)",
+	"now",
+	"today",
+	"yesterday",
+	"offset from now (-2h, now-30m, -7d, +1w)",
+}
+
+// LoadTimeZone returns the location of an IANA time zone name such as Europe/Berlin, UTC when
+// the name is empty
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q, expected an IANA name such as Europe/Berlin", name)
+	}
+	return loc, nil
+}
+
+// ParseTimeExpression parses a query time given as RFC3339, as a date or date and time without
+// offset in loc, or relative to now:
+//
+//	now, today, yesterday            now, or midnight in loc
+//	-2h, now-30m, -7d, +1w           an offset from now in s, m, h, d (24h) or w (7d)
+//
+// The result is in UTC. An empty value returns the zero time.
+func ParseTimeExpression(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range localTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	expression := strings.ToLower(value)
+	local := now.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	switch expression {
+	case "now":
+		return now.UTC(), nil
+	case "today":
+		return midnight.UTC(), nil
+	case "yesterday":
+		return midnight.AddDate(0, 0, -1).UTC(), nil
+	}
+
+	offset := strings.TrimPrefix(expression, "now")
+	if offset != "" && (offset[0] == '-' || offset[0] == '+') {
+		d, err := parseRelativeDuration(offset[1:])
+		if err == nil {
+			if offset[0] == '-' {
+				d = -d
+			}
+			return now.Add(d).UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC3339 (2024-05-01T10:00:00Z), a local time (2024-05-01 10:00), now, today, yesterday or an offset such as -2h or -7d", value)
+}
+
+// parseRelativeDuration parses a duration such as 90m or 1h30m, with d and w as days and weeks
+func parseRelativeDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("missing duration")
+	}
+	for unit, length := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if number, ok := strings.CutSuffix(value, unit); ok {
+			count, err := strconv.ParseFloat(number, 64)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(count * float64(length)), nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
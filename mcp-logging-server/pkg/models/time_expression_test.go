@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeExpression(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	berlin, err := LoadTimeZone("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Failed to load time zone: %v", err)
+	}
+
+	tests := []struct {
+		value    string
+		loc      *time.Location
+		expected time.Time
+	}{
+		{"", nil, time.Time{}},
+		{"2024-04-30T08:00:00+02:00", berlin, time.Date(2024, 4, 30, 6, 0, 0, 0, time.UTC)},
+		{"2024-04-30 08:00", berlin, time.Date(2024, 4, 30, 6, 0, 0, 0, time.UTC)},
+		{"2024-04-30", nil, time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)},
+		{"now", nil, now},
+		{"NOW", nil, now},
+		{"-2h", nil, now.Add(-2 * time.Hour)},
+		{"now-90m", nil, now.Add(-90 * time.Minute)},
+		{"-1h30m", nil, now.Add(-90 * time.Minute)},
+		{"-7d", nil, now.AddDate(0, 0, -7)},
+		{"+1w", nil, now.AddDate(0, 0, 7)},
+		{"today", berlin, time.Date(2024, 4, 30, 22, 0, 0, 0, time.UTC)},
+		{"yesterday", nil, time.Date(2024, 4, 30, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimeExpression(tt.value, now, tt.loc)
+		if err != nil {
+			t.Errorf("ParseTimeExpression(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.expected) {
+			t.Errorf("ParseTimeExpression(%q) = %v, expected %v", tt.value, got, tt.expected)
+		}
+	}
+
+	for _, value := range []string{"2h", "last tuesday", "-", "now-x", "-1y"} {
+		if _, err := ParseTimeExpression(value, now, nil); err == nil {
+			t.Errorf("Expected ParseTimeExpression(%q) to fail", value)
+		}
+	}
+
+	if _, err := LoadTimeZone("Mars/Olympus"); err == nil {
+		t.Error("Expected an unknown time zone to fail")
+	}
+}
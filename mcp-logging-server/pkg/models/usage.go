@@ -0,0 +1,23 @@
+package models
+
+// UsageDayFormat is the layout of the UTC days usage is accounted by
+const UsageDayFormat = "2006-01-02"
+
+// UsageRecord counts the log entries and bytes ingested with one API key for one service on one day
+type UsageRecord struct {
+	Day         string `json:"day,omitempty"` // UTC day, empty when summed over the queried days
+	APIKeyID    string `json:"api_key_id"`    // Empty for entries ingested without an API key
+	ServiceName string `json:"service_name"`
+	Entries     int64  `json:"entries"`
+	Bytes       int64  `json:"bytes"` // JSON size of the entries as accepted, after data protection
+}
+
+// UsageFilter selects usage records. Days are UsageDayFormat strings and inclusive.
+type UsageFilter struct {
+	StartDay    string `json:"start_day,omitempty"`
+	EndDay      string `json:"end_day,omitempty"`
+	APIKeyID    string `json:"api_key_id,omitempty"`
+	ServiceName string `json:"service_name,omitempty"`
+	Daily       bool   `json:"daily,omitempty"` // Keep one record per day instead of summing the days
+	Limit       int    `json:"limit,omitempty"`
+}
@@ -0,0 +1,268 @@
+// Package openapi builds OpenAPI 3 documents from a typed route registry. Request and response
+// schemas are derived from the Go types the handlers bind and return, so that the document
+// follows the code and clients for other platforms can be generated from it.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
+)
+
+// Version is the OpenAPI version of the generated documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem holds the operations of a path by lower case HTTP method
+type PathItem map[string]*Operation
+
+// Operation describes a single endpoint
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Permission  auth.Permission       `json:"x-permission,omitempty"` // API key permission required by the endpoint
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes the body of a request
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a response by its status code
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType holds the schema of a body in one content type
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema is a JSON schema as used by OpenAPI 3.0. A schema without a type accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// Components holds the named schemas referenced by operations and the security schemes
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+// SecurityScheme describes how API keys are sent
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+}
+
+// Security scheme names, API keys are accepted in either
+const (
+	SecurityAPIKey = "apiKey"
+	SecurityBearer = "bearer"
+)
+
+// problemSchema is the component name of the problem details schema of error responses
+const problemSchema = "Problem"
+
+// Route describes an endpoint in the registry a document is built from
+type Route struct {
+	Method      string          // HTTP method, e.g. http.MethodGet
+	Path        string          // Path in gin syntax, :name segments become path parameters
+	OperationID string          // Unique name of the operation, used for generated client methods
+	Summary     string          // What the endpoint does
+	Tag         string          // Group of the endpoint
+	Permission  auth.Permission // API key permission required, empty for public endpoints
+	Query       []Parameter     // Query parameters, see QueryParam
+	Request     interface{}     // Value of the JSON request body type, nil for no body
+	RawRequest  string          // Media type of a raw request body sent instead of JSON
+	Status      int             // Status of successful responses, 0 for 200
+	Response    interface{}     // Value of the JSON response body type, nil for a free-form object
+	Errors      []int           // Statuses of problem details responses, authentication and rate limit errors are added
+}
+
+// QueryParam returns an optional query parameter with the schema of value's type
+func QueryParam(name string, value interface{}, description string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Schema:      newGenerator(nil).schema(reflect.TypeOf(value)),
+	}
+}
+
+// Build builds the document of the routes
+func Build(info Info, routes []Route) *Document {
+	document := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+			SecuritySchemes: map[string]SecurityScheme{
+				SecurityAPIKey: {Type: "apiKey", In: "header", Name: "X-API-Key"},
+				SecurityBearer: {Type: "http", Scheme: "bearer", Description: "The API key as bearer token"},
+			},
+		},
+	}
+	generator := newGenerator(document.Components.Schemas)
+	generator.schema(reflect.TypeOf(problem.Problem{}))
+
+	for _, route := range routes {
+		path, parameters := convertPath(route.Path)
+		item, ok := document.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			document.Paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = generator.operation(route, parameters)
+	}
+
+	return document
+}
+
+// operation builds the operation of a route
+func (g *generator) operation(route Route, parameters []Parameter) *Operation {
+	operation := &Operation{
+		OperationID: route.OperationID,
+		Summary:     route.Summary,
+		Parameters:  append(parameters, route.Query...),
+		Responses:   make(map[string]Response),
+		Permission:  route.Permission,
+	}
+	if route.Tag != "" {
+		operation.Tags = []string{route.Tag}
+	}
+
+	switch {
+	case route.RawRequest != "":
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				route.RawRequest: {Schema: &Schema{Type: "string", Format: "binary"}},
+			},
+		}
+	case route.Request != nil:
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: g.schema(reflect.TypeOf(route.Request))},
+			},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := &Schema{Type: "object"}
+	if route.Response != nil {
+		response = g.schema(reflect.TypeOf(route.Response))
+	}
+	operation.Responses[strconv.Itoa(status)] = Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{"application/json": {Schema: response}},
+	}
+
+	errors := append([]int{http.StatusTooManyRequests}, route.Errors...)
+	if route.Permission != "" {
+		operation.Security = []map[string][]string{{SecurityAPIKey: {}}, {SecurityBearer: {}}}
+		errors = append(errors, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	for _, errorStatus := range errors {
+		operation.Responses[strconv.Itoa(errorStatus)] = Response{
+			Description: http.StatusText(errorStatus),
+			Content: map[string]MediaType{
+				problem.ContentType: {Schema: &Schema{Ref: schemaRef(problemSchema)}},
+			},
+		}
+	}
+
+	return operation
+}
+
+// convertPath turns a gin path into an OpenAPI path template and its path parameters
+func convertPath(path string) (string, []Parameter) {
+	var parameters []Parameter
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			name := segment[1:]
+			segments[i] = "{" + name + "}"
+			parameters = append(parameters, Parameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// Operations returns the method and gin path of every operation in the document, sorted
+func (d *Document) Operations() []string {
+	var operations []string
+	for path, item := range d.Paths {
+		for method := range item {
+			operations = append(operations, strings.ToUpper(method)+" "+ginPath(path))
+		}
+	}
+	sort.Strings(operations)
+	return operations
+}
+
+// ginPath turns an OpenAPI path template back into gin syntax
+func ginPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = ":" + segment[1:len(segment)-1]
+		}
+	}
+	return strings.Join(segments, "/")
+}
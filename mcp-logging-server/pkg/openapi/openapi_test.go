@@ -0,0 +1,138 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testItem struct {
+	testBase
+	Name     string            `json:"name" binding:"required"`
+	Tags     []string          `json:"tags,omitempty" binding:"max=5,dive,required"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Content  []byte            `json:"content,omitempty"`
+	Timeout  time.Duration     `json:"timeout"`
+	Parent   *testItem         `json:"parent,omitempty"`
+	Metadata interface{}       `json:"metadata,omitempty"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+func TestBuild(t *testing.T) {
+	document := Build(Info{Title: "Test API", Version: "1"}, []Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/health",
+			OperationID: "getHealth",
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/items/:id",
+			OperationID: "putItem",
+			Permission:  "admin",
+			Query:       []Parameter{QueryParam("dry_run", false, "Validate only")},
+			Request:     testItem{},
+			Status:      http.StatusCreated,
+			Response:    testItem{},
+			Errors:      []int{http.StatusBadRequest},
+		},
+	})
+
+	if document.OpenAPI != Version {
+		t.Errorf("Expected OpenAPI version %s, got %s", Version, document.OpenAPI)
+	}
+	if got := document.Operations(); !reflect.DeepEqual(got, []string{"GET /health", "PUT /items/:id"}) {
+		t.Errorf("Unexpected operations %v", got)
+	}
+
+	health := document.Paths["/health"]["get"]
+	if health.Security != nil || health.Responses["401"].Description != "" {
+		t.Error("Expected public endpoints not to require an API key")
+	}
+	if _, ok := health.Responses["200"]; !ok {
+		t.Error("Expected a 200 response by default")
+	}
+
+	put := document.Paths["/items/{id}"]["put"]
+	if put == nil {
+		t.Fatalf("Expected the path parameter to be converted, got paths %v", document.Paths)
+	}
+	if len(put.Parameters) != 2 || put.Parameters[0].Name != "id" || put.Parameters[0].In != "path" || !put.Parameters[0].Required {
+		t.Errorf("Unexpected parameters %+v", put.Parameters)
+	}
+	if put.Parameters[1].In != "query" || put.Parameters[1].Schema.Type != "boolean" {
+		t.Errorf("Unexpected query parameter %+v", put.Parameters[1])
+	}
+	if len(put.Security) != 2 || put.Permission != "admin" {
+		t.Errorf("Expected the API key security schemes, got %v", put.Security)
+	}
+	for _, status := range []string{"201", "400", "401", "403", "429"} {
+		if _, ok := put.Responses[status]; !ok {
+			t.Errorf("Expected a %s response", status)
+		}
+	}
+	if ref := put.Responses["400"].Content["application/problem+json"].Schema.Ref; ref != "#/components/schemas/Problem" {
+		t.Errorf("Expected errors to be problem details, got %q", ref)
+	}
+	if ref := put.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/TestItem" {
+		t.Errorf("Expected the request body to reference the item schema, got %q", ref)
+	}
+
+	item := document.Components.Schemas["TestItem"]
+	if item == nil {
+		t.Fatal("Expected the item schema to be registered")
+	}
+	expected := map[string]Schema{
+		"id":         {Type: "string"},
+		"created_at": {Type: "string", Format: "date-time"},
+		"name":       {Type: "string"},
+		"content":    {Type: "string", Format: "byte"},
+		"timeout":    {Type: "integer", Format: "int64"},
+		"parent":     {Ref: "#/components/schemas/TestItem"},
+		"metadata":   {},
+	}
+	for name, schema := range expected {
+		if got := item.Properties[name]; got == nil || !reflect.DeepEqual(*got, schema) {
+			t.Errorf("Expected property %s to be %+v, got %+v", name, schema, got)
+		}
+	}
+	if item.Properties["tags"].Items.Type != "string" || item.Properties["labels"].AdditionalProperties.Type != "string" {
+		t.Error("Expected slices and maps to describe their elements")
+	}
+	if len(item.Properties) != 9 {
+		t.Errorf("Expected ignored and unexported fields to be left out, got %d properties", len(item.Properties))
+	}
+	if !reflect.DeepEqual(item.Required, []string{"name"}) {
+		t.Errorf("Expected name to be required, got %v", item.Required)
+	}
+
+	if _, err := json.Marshal(document); err != nil {
+		t.Fatalf("Failed to marshal document: %v", err)
+	}
+}
+
+func TestBuild_SchemaNameCollision(t *testing.T) {
+	type Problem struct {
+		Message string `json:"message"`
+	}
+
+	document := Build(Info{Title: "Test API", Version: "1"}, []Route{
+		{Method: http.MethodGet, Path: "/problem", OperationID: "getProblem", Response: Problem{}},
+	})
+
+	schema := document.Paths["/problem"]["get"].Responses["200"].Content["application/json"].Schema
+	if schema.Ref != "#/components/schemas/openapi.Problem" {
+		t.Errorf("Expected the colliding name to be qualified, got %q", schema.Ref)
+	}
+	if document.Components.Schemas["Problem"].Properties["code"] == nil {
+		t.Error("Expected the problem details schema to be kept")
+	}
+}
@@ -0,0 +1,161 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// generator derives schemas from Go types the way encoding/json marshals them. Named structs
+// become component schemas referenced by name, other types are described inline.
+type generator struct {
+	schemas map[string]*Schema      // Component schemas by name
+	names   map[reflect.Type]string // Component names by type
+}
+
+func newGenerator(schemas map[string]*Schema) *generator {
+	if schemas == nil {
+		schemas = make(map[string]*Schema)
+	}
+	return &generator{schemas: schemas, names: make(map[reflect.Type]string)}
+}
+
+// schemaRef returns the reference to a component schema
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+// schema returns the schema of values of type t
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		// time.Duration is marshaled as nanoseconds
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"} // Base64
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: schemaRef(g.component(t))}
+	default:
+		// Interfaces hold any value
+		return &Schema{}
+	}
+}
+
+// component registers the schema of a named struct and returns its component name. Names are
+// capitalized for generated clients, and qualified with the package only when two packages
+// declare a type of the same name.
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := g.schemas[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+
+	// Registered before its fields are described, so that recursive types refer to themselves
+	schema := &Schema{}
+	g.schemas[name] = schema
+	*schema = *g.structSchema(t)
+	return name
+}
+
+// structSchema returns the object schema of a struct's JSON fields. Fields of embedded structs
+// without a JSON name are promoted like encoding/json does, fields tagged binding:"required" are
+// required.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			embedded := g.structSchema(fieldType)
+			for property, propertySchema := range embedded.Properties {
+				if _, ok := schema.Properties[property]; !ok {
+					schema.Properties[property] = propertySchema
+				}
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = g.schema(field.Type)
+		if isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// isRequired reports whether binding rejects requests missing the field. Validation rules are
+// ignored, they apply after the server filled in defaults such as IDs and timestamps.
+func isRequired(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		switch rule {
+		case "required":
+			return true
+		case "dive":
+			return false // The remaining rules apply to elements
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+// Package pool reuses the entry slices of the ingestion hot path, which batches are decoded into
+// and flushed from. With large batches these dominate the garbage the server produces, so reusing
+// them keeps the heap from growing with every request. The buffers entries are encoded into are
+// pooled by package codec.
+package pool
+
+import (
+	"sync"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// maxEntries is the capacity above which an entry slice is left to the garbage collector, so a
+// few huge batches do not keep their memory pinned in the pool
+const maxEntries = 10000
+
+var entrySlices = sync.Pool{
+	New: func() interface{} {
+		entries := make([]models.LogEntry, 0, 64)
+		return &entries
+	},
+}
+
+// GetEntries returns an empty entry slice with room for at least capacity entries, to be
+// returned with PutEntries once neither it nor a slice of it is used
+func GetEntries(capacity int) []models.LogEntry {
+	entries := *entrySlices.Get().(*[]models.LogEntry)
+	if cap(entries) < capacity {
+		return make([]models.LogEntry, 0, capacity)
+	}
+	return entries[:0]
+}
+
+// PutEntries returns an entry slice to the pool. The entries are cleared so the pool does not
+// keep their strings and metadata alive.
+func PutEntries(entries []models.LogEntry) {
+	if cap(entries) == 0 || cap(entries) > maxEntries {
+		return
+	}
+	entries = entries[:cap(entries)]
+	clear(entries)
+	entries = entries[:0]
+	entrySlices.Put(&entries)
+}
@@ -0,0 +1,21 @@
+package pool
+
+import (
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestEntries(t *testing.T) {
+	entries := GetEntries(10)
+	if len(entries) != 0 || cap(entries) < 10 {
+		t.Fatalf("Expected an empty slice with room for 10 entries, got %d/%d", len(entries), cap(entries))
+	}
+
+	entries = append(entries, models.LogEntry{Message: "kept alive"})
+	backing := entries[:1]
+	PutEntries(entries)
+	if backing[0].Message != "" {
+		t.Error("Expected the returned entries to be cleared")
+	}
+}
@@ -0,0 +1,346 @@
+// Package preflight runs self-tests of the storage, search index, recovery directory and TLS
+// certificates at startup, so that broken dependencies are found before the first request
+package preflight
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	tlsconfig "github.com/kerlexov/mcp-logging-server/pkg/tls"
+)
+
+// Check statuses
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Check names
+const (
+	CheckStorageWrite  = "storage_write"
+	CheckStorageRead   = "storage_read"
+	CheckSearch        = "search"
+	CheckStorageDelete = "storage_delete"
+	CheckRecoveryDir   = "recovery_dir"
+	CheckTLS           = "tls"
+)
+
+// DefaultTimeout bounds the storage and search checks when no timeout is configured
+const DefaultTimeout = 10 * time.Second
+
+// ProbeServiceName is the service of the probe entry written and deleted by the storage checks
+const ProbeServiceName = "mcp-logging-preflight"
+
+// Options selects the dependencies to check, zero values skip the respective checks
+type Options struct {
+	Storage     storage.LogStorage   // Written, read, searched and cleaned up with a probe entry
+	RecoveryDir string               // Must be writable
+	TLS         *tlsconfig.TLSConfig // Certificate chain is verified when TLS is enabled
+	Timeout     time.Duration        // Bounds the storage and search checks, 0 uses DefaultTimeout
+}
+
+// CheckResult is the outcome of a single check
+type CheckResult struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	StartedAt time.Time     `json:"started_at"`
+	Passed    bool          `json:"passed"` // No check failed
+	Checks    []CheckResult `json:"checks"`
+}
+
+// Err returns an error listing the failed checks, nil if none failed
+func (r *Report) Err() error {
+	var failures []string
+	for _, check := range r.Checks {
+		if check.Status == StatusFailed {
+			failures = append(failures, fmt.Sprintf("%s: %s", check.Name, check.Detail))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("preflight checks failed: %s", strings.Join(failures, "; "))
+}
+
+// skipped is returned by checks that do not apply
+type skipped string
+
+func (s skipped) Error() string { return string(s) }
+
+// Run runs all checks in order and reports their outcome. Checks never stop early, so the
+// report lists every broken dependency at once.
+func Run(ctx context.Context, options Options) *Report {
+	report := &Report{StartedAt: time.Now(), Passed: true}
+	run := func(name string, check func() (string, error)) {
+		start := time.Now()
+		detail, err := check()
+		result := CheckResult{Name: name, Status: StatusPassed, Detail: detail, Duration: time.Since(start)}
+
+		var skip skipped
+		switch {
+		case errors.As(err, &skip):
+			result.Status = StatusSkipped
+			result.Detail = skip.Error()
+		case err != nil:
+			result.Status = StatusFailed
+			result.Detail = err.Error()
+			report.Passed = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	// The storage checks share the probe entry, each is bounded by the timeout
+	probe := newProbe()
+	stored := false
+	runStorage := func(name string, check func(ctx context.Context) (string, error)) {
+		run(name, func() (string, error) {
+			if options.Storage == nil {
+				return "", skipped("no storage to check")
+			}
+			if name != CheckStorageWrite && !stored {
+				return "", skipped("probe entry was not stored")
+			}
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return check(checkCtx)
+		})
+	}
+
+	runStorage(CheckStorageWrite, func(ctx context.Context) (string, error) {
+		if err := options.Storage.Store(ctx, []models.LogEntry{probe}); err != nil {
+			return "", fmt.Errorf("failed to store probe entry: %w", err)
+		}
+		stored = true
+		return "", nil
+	})
+	runStorage(CheckStorageRead, func(ctx context.Context) (string, error) {
+		return "", checkRead(ctx, options.Storage, probe)
+	})
+	runStorage(CheckSearch, func(ctx context.Context) (string, error) {
+		return "", checkSearch(ctx, options.Storage, probe)
+	})
+	runStorage(CheckStorageDelete, func(ctx context.Context) (string, error) {
+		return checkDelete(ctx, options.Storage, probe)
+	})
+	run(CheckRecoveryDir, func() (string, error) {
+		return "", checkRecoveryDir(options.RecoveryDir)
+	})
+	run(CheckTLS, func() (string, error) {
+		return checkTLS(options.TLS)
+	})
+
+	return report
+}
+
+// newProbe returns the entry written by the storage checks. Its message holds a single unique
+// word, so that a full-text search for it matches the probe only.
+func newProbe() models.LogEntry {
+	id := uuid.New().String()
+	now := time.Now().UTC()
+	return models.LogEntry{
+		ID:          id,
+		Timestamp:   now,
+		Level:       models.LogLevelDebug,
+		Message:     "Startup self-test entry preflight" + strings.ReplaceAll(id, "-", ""),
+		ServiceName: ProbeServiceName,
+		AgentID:     ProbeServiceName,
+		Platform:    models.PlatformGo,
+		ReceivedAt:  now,
+	}
+}
+
+// probeWord returns the unique word of a probe message
+func probeWord(probe models.LogEntry) string {
+	return probe.Message[strings.LastIndex(probe.Message, " ")+1:]
+}
+
+// pollInterval is how often reads of the probe entry are retried until they succeed
+const pollInterval = 100 * time.Millisecond
+
+// poll retries a check until it succeeds or ctx is done, and returns its last error. Reads
+// may be served by a read replica that receives the probe entry with a delay.
+func poll(ctx context.Context, check func() error) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		var skip skipped
+		if errors.As(err, &skip) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkRead reads the probe entry back
+func checkRead(ctx context.Context, store storage.LogStorage, probe models.LogEntry) error {
+	return poll(ctx, func() error {
+		logs, err := store.GetByIDs(ctx, []string{probe.ID})
+		if err != nil {
+			return fmt.Errorf("failed to read probe entry: %w", err)
+		}
+		if len(logs) != 1 || logs[0].Message != probe.Message {
+			return errors.New("probe entry was not read back as stored")
+		}
+		return nil
+	})
+}
+
+// checkSearch finds the probe entry with a full-text search
+func checkSearch(ctx context.Context, store storage.LogStorage, probe models.LogEntry) error {
+	searcher, ok := store.(storage.LogSearcher)
+	if !ok {
+		return skipped("storage does not support full-text search")
+	}
+
+	return poll(ctx, func() error {
+		result, err := searcher.SearchLogs(ctx, probeWord(probe), models.LogFilter{ServiceName: ProbeServiceName, Limit: 1})
+		if errors.Is(err, storage.ErrSearchDisabled) {
+			return skipped("full-text search is not enabled")
+		}
+		if err != nil {
+			return fmt.Errorf("failed to search probe entry: %w", err)
+		}
+		if len(result.Hits) != 1 || result.Hits[0].Log.ID != probe.ID {
+			return errors.New("probe entry was not found in the search index")
+		}
+		return nil
+	})
+}
+
+// checkDelete removes the probe entry again
+func checkDelete(ctx context.Context, store storage.LogStorage, probe models.LogEntry) (string, error) {
+	deleter, ok := store.(storage.LogDeleter)
+	if !ok {
+		return "", skipped("storage does not support deleting entries, the probe entry was kept")
+	}
+
+	deleted, err := deleter.DeleteByIDs(ctx, []string{probe.ID})
+	if err != nil {
+		return "", fmt.Errorf("failed to delete probe entry: %w", err)
+	}
+	if deleted == 0 {
+		// Legal holds and the immutable window protect new entries from deletion by design
+		return "probe entry is protected from deletion and was kept", nil
+	}
+	return "", nil
+}
+
+// checkRecoveryDir creates the recovery directory if needed and writes a file to it
+func checkRecoveryDir(dir string) error {
+	if dir == "" {
+		return skipped("no recovery directory configured")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create recovery directory: %w", err)
+	}
+
+	file, err := os.CreateTemp(dir, ".preflight-*")
+	if err != nil {
+		return fmt.Errorf("recovery directory is not writable: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.WriteString("preflight"); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write to recovery directory: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write to recovery directory: %w", err)
+	}
+	return nil
+}
+
+// checkTLS loads the server certificate and verifies its chain against the system roots.
+// Self-signed certificates included in the certificate file are trusted as roots.
+func checkTLS(config *tlsconfig.TLSConfig) (string, error) {
+	if config == nil || !config.Enabled {
+		return "", skipped("TLS is not enabled")
+	}
+
+	tlsConf, err := config.GetTLSConfig()
+	if err != nil {
+		return "", err
+	}
+
+	var cert *tls.Certificate
+	if len(tlsConf.Certificates) > 0 {
+		cert = &tlsConf.Certificates[0]
+	} else if tlsConf.GetCertificate != nil {
+		if cert, err = tlsConf.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+			return "", fmt.Errorf("failed to get certificate: %w", err)
+		}
+	}
+	if cert == nil || len(cert.Certificate) == 0 {
+		return "", errors.New("no server certificate configured")
+	}
+
+	chain := make([]*x509.Certificate, len(cert.Certificate))
+	for i, der := range cert.Certificate {
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return "", fmt.Errorf("failed to parse certificate: %w", err)
+		}
+	}
+	leaf := chain[0]
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain {
+		if c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+		} else if c != leaf {
+			intermediates.AddCert(c)
+		}
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return "", fmt.Errorf("certificate chain is invalid: %w", err)
+	}
+
+	return fmt.Sprintf("certificate for %s valid until %s", leafName(leaf), leaf.NotAfter.UTC().Format(time.RFC3339)), nil
+}
+
+// leafName returns the name a certificate was issued for
+func leafName(cert *x509.Certificate) string {
+	if len(cert.DNSNames) > 0 {
+		return strings.Join(cert.DNSNames, ", ")
+	}
+	return cert.Subject.CommonName
+}
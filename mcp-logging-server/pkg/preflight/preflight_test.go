@@ -0,0 +1,138 @@
+package preflight
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	tlsconfig "github.com/kerlexov/mcp-logging-server/pkg/tls"
+)
+
+func checkStatuses(report *Report) map[string]string {
+	statuses := make(map[string]string)
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+// writeCertificate writes a self-signed certificate valid until notAfter and its key
+func writeCertificate(t *testing.T, dir string, notAfter time.Time) *tlsconfig.TLSConfig {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "logs.example.com"},
+		DNSNames:              []string{"logs.example.com"},
+		NotBefore:             notAfter.Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	config := tlsconfig.DefaultTLSConfig()
+	config.Enabled = true
+	config.CertFile = filepath.Join(dir, "server.crt")
+	config.KeyFile = filepath.Join(dir, "server.key")
+	if err := os.WriteFile(config.CertFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("Failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(config.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return config
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewSQLiteStorageWithSearch(filepath.Join(dir, "logs.db"), filepath.Join(dir, "search"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	report := Run(context.Background(), Options{
+		Storage:     store,
+		RecoveryDir: filepath.Join(dir, "recovery"),
+		TLS:         writeCertificate(t, dir, time.Now().Add(24*time.Hour)),
+	})
+
+	if !report.Passed || report.Err() != nil {
+		t.Fatalf("Expected all checks to pass, got %+v", report.Checks)
+	}
+	for name, status := range checkStatuses(report) {
+		if status != StatusPassed {
+			t.Errorf("Expected check %s to pass, got %s", name, status)
+		}
+	}
+
+	// The probe entry is cleaned up
+	result, err := store.Query(context.Background(), models.LogFilter{ServiceName: ProbeServiceName, Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 0 {
+		t.Errorf("Expected the probe entry to be deleted, got %d entries", result.TotalCount)
+	}
+}
+
+func TestRun_Skipped(t *testing.T) {
+	report := Run(context.Background(), Options{Storage: storage.NewMemoryStorage()})
+
+	statuses := checkStatuses(report)
+	if statuses[CheckStorageWrite] != StatusPassed || statuses[CheckStorageRead] != StatusPassed || statuses[CheckStorageDelete] != StatusPassed {
+		t.Errorf("Expected the storage checks to pass, got %v", statuses)
+	}
+	if statuses[CheckSearch] != StatusSkipped || statuses[CheckRecoveryDir] != StatusSkipped || statuses[CheckTLS] != StatusSkipped {
+		t.Errorf("Expected unconfigured checks to be skipped, got %v", statuses)
+	}
+	if !report.Passed {
+		t.Error("Expected skipped checks not to fail the report")
+	}
+}
+
+func TestRun_Failures(t *testing.T) {
+	dir := t.TempDir()
+
+	// A file where the recovery directory should be
+	recoveryDir := filepath.Join(dir, "recovery")
+	if err := os.WriteFile(recoveryDir, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	report := Run(context.Background(), Options{
+		RecoveryDir: recoveryDir,
+		TLS:         writeCertificate(t, dir, time.Now().Add(-time.Hour)),
+	})
+
+	statuses := checkStatuses(report)
+	if statuses[CheckRecoveryDir] != StatusFailed || statuses[CheckTLS] != StatusFailed {
+		t.Errorf("Expected the recovery directory and expired certificate to fail, got %v", statuses)
+	}
+	if report.Passed || report.Err() == nil {
+		t.Error("Expected the report to fail")
+	}
+}
@@ -0,0 +1,64 @@
+package problem
+
+// Code identifies the kind of an API error. Codes are part of the API: they are mirrored by the
+// SDKs and are never renamed or reused.
+type Code string
+
+// Request errors
+const (
+	CodeInvalidJSON          Code = "INVALID_JSON"          // The body is not valid JSON or does not match the expected shape
+	CodeInvalidRequest       Code = "INVALID_REQUEST"       // A required field or parameter is missing or malformed
+	CodeValidationError      Code = "VALIDATION_ERROR"      // One or more log entries failed validation
+	CodeInvalidQuery         Code = "INVALID_QUERY"         // The search or query parameters are invalid
+	CodeInvalidSequence      Code = "INVALID_SEQUENCE"      // An offline sync sequence number is invalid
+	CodeInvalidSymbolFile    Code = "INVALID_SYMBOL_FILE"   // An uploaded symbol file cannot be parsed
+	CodeInvalidConfiguration Code = "INVALID_CONFIGURATION" // A submitted configuration was rejected
+	CodeEmptyBatch           Code = "EMPTY_BATCH"           // A batch contains no entries
+	CodeBatchTooLarge        Code = "BATCH_TOO_LARGE"       // A batch contains more entries than allowed
+	CodeRequestTooLarge      Code = "REQUEST_TOO_LARGE"     // The body exceeds the maximum request size
+	CodeRequestTimeout       Code = "REQUEST_TIMEOUT"       // The request took too long to process
+)
+
+// Authentication and authorization errors
+const (
+	CodeMissingAPIKey           Code = "MISSING_API_KEY"          // No API key was sent
+	CodeInvalidAPIKey           Code = "INVALID_API_KEY"          // The API key is unknown, revoked or expired
+	CodeMissingSignature        Code = "MISSING_SIGNATURE"        // The key requires signed requests and none was sent
+	CodeInvalidSignature        Code = "INVALID_SIGNATURE"        // The request signature does not match
+	CodeSignatureExpired        Code = "SIGNATURE_EXPIRED"        // The signed timestamp is outside the allowed window
+	CodeReplayedRequest         Code = "REPLAYED_REQUEST"         // The signed request was already received
+	CodeAuthenticationRequired  Code = "AUTHENTICATION_REQUIRED"  // The endpoint requires an API key
+	CodeInvalidAuthContext      Code = "INVALID_AUTH_CONTEXT"     // The server could not read the authenticated key
+	CodeInsufficientPermissions Code = "INSUFFICIENT_PERMISSIONS" // The API key lacks the required permission
+	CodeRateLimitExceeded       Code = "RATE_LIMIT_EXCEEDED"      // Too many requests; retry after the Retry-After header
+)
+
+// Resource errors
+const (
+	CodeLogNotFound        Code = "LOG_NOT_FOUND"         // The log entry does not exist
+	CodeServiceNotFound    Code = "SERVICE_NOT_FOUND"     // No logs exist for the service
+	CodeAPIKeyNotFound     Code = "API_KEY_NOT_FOUND"     // The API key does not exist
+	CodeBatchNotFound      Code = "BATCH_NOT_FOUND"       // The batch is unknown or expired
+	CodeLegalHoldNotFound  Code = "LEGAL_HOLD_NOT_FOUND"  // The legal hold does not exist
+	CodeSymbolFileNotFound Code = "SYMBOL_FILE_NOT_FOUND" // The symbol file does not exist
+	CodeBlockedKeyNotFound Code = "BLOCKED_KEY_NOT_FOUND" // The key is not blocked by the rate limiter
+	CodeIncidentNotFound   Code = "INCIDENT_NOT_FOUND"    // The incident does not exist
+	CodeToolNotFound       Code = "TOOL_NOT_FOUND"        // The MCP server has no such tool
+	CodeNotSupported       Code = "NOT_SUPPORTED"         // The storage backend or configuration does not support the operation
+	CodeReplicationLoop    Code = "REPLICATION_LOOP"      // A replicated batch was sent back to the server it originates from
+)
+
+// Server errors
+const (
+	CodeStorageError        Code = "STORAGE_ERROR"         // Reading from or writing to storage failed
+	CodeBufferError         Code = "BUFFER_ERROR"          // Entries could not be added to the buffer
+	CodeFlushError          Code = "FLUSH_ERROR"           // Flushing the buffer failed
+	CodeDataProtectionError Code = "DATA_PROTECTION_ERROR" // Applying data protection rules failed
+	CodeProcessorError      Code = "PROCESSOR_ERROR"       // A custom processor failed on the entries
+	CodeConfigSaveError     Code = "CONFIG_SAVE_ERROR"     // A configuration change could not be persisted
+	CodeRecoveryStatsError  Code = "RECOVERY_STATS_ERROR"  // Recovery statistics are unavailable
+	CodeInternalError       Code = "INTERNAL_SERVER_ERROR" // An unexpected error; the server has recovered
+	CodeReadOnly            Code = "READ_ONLY"             // The server is in read-only mode and rejects writes
+	CodeMaintenance         Code = "MAINTENANCE"           // The server is in maintenance mode and rejects ingestion and queries
+	CodeServerBusy          Code = "SERVER_BUSY"           // Too many async batches are being stored; retry after the Retry-After header
+)
@@ -0,0 +1,80 @@
+// Package problem writes API error responses as RFC 7807 problem details, identified by a code
+// from a fixed catalog so that clients can branch on the kind of error instead of its message
+package problem
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+)
+
+// ContentType is the media type of problem details responses
+const ContentType = "application/problem+json"
+
+// typePrefix turns codes into the URIs identifying problem types
+const typePrefix = "urn:mcp-logging:error:"
+
+// contextKey is the gin context key of the code of the problem sent as the response
+const contextKey = "problem_code"
+
+// Problem is the body of every API error response. Code is a stable member of the catalog;
+// Title and Detail are meant for humans and may change between releases.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail,omitempty"`
+	Instance  string      `json:"instance,omitempty"`
+	Code      Code        `json:"code"`
+	RequestID string      `json:"request_id,omitempty"`
+	Details   interface{} `json:"details,omitempty"` // Structured information, such as validation errors
+}
+
+// TypeURI returns the URI identifying the problem type of a code
+func TypeURI(code Code) string {
+	return typePrefix + string(code)
+}
+
+// New creates the problem details of an error in the request being handled
+func New(c *gin.Context, status int, code Code, title, detail string) *Problem {
+	return &Problem{
+		Type:      TypeURI(code),
+		Title:     title,
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: requestid.Get(c),
+	}
+}
+
+// Write sends problem details as the response
+func Write(c *gin.Context, p *Problem) {
+	c.Set(contextKey, p.Code)
+	c.Header("Content-Type", ContentType)
+	c.JSON(p.Status, p)
+}
+
+// CodeOf returns the code of the problem sent as the response to a request, empty if none was
+func CodeOf(c *gin.Context) Code {
+	code, _ := c.Get(contextKey)
+	value, _ := code.(Code)
+	return value
+}
+
+// Respond sends an error response with a human-readable detail, which may be empty
+func Respond(c *gin.Context, status int, code Code, title, detail string) {
+	Write(c, New(c, status, code, title, detail))
+}
+
+// RespondDetails sends an error response with structured details
+func RespondDetails(c *gin.Context, status int, code Code, title string, details interface{}) {
+	p := New(c, status, code, title, "")
+	p.Details = details
+	Write(c, p)
+}
+
+// Abort sends an error response like Respond and stops the remaining handlers
+func Abort(c *gin.Context, status int, code Code, title, detail string) {
+	Respond(c, status, code, title, detail)
+	c.Abort()
+}
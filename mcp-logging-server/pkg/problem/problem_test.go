@@ -0,0 +1,69 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kerlexov/mcp-logging-server/pkg/requestid"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(requestid.Middleware())
+	router.GET("/logs", func(c *gin.Context) {
+		Respond(c, http.StatusBadRequest, CodeInvalidJSON, "Invalid JSON format", "unexpected EOF")
+	})
+	router.GET("/batch", func(c *gin.Context) {
+		RespondDetails(c, http.StatusBadRequest, CodeValidationError, "Log entry validation failed", []string{"message is required"})
+	})
+	router.GET("/admin", func(c *gin.Context) {
+		Abort(c, http.StatusForbidden, CodeInsufficientPermissions, "Insufficient permissions", "")
+	}, func(c *gin.Context) {
+		t.Error("Expected Abort to stop the remaining handlers")
+	})
+
+	serve := func(path string) (*httptest.ResponseRecorder, Problem) {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set(requestid.Header, "req-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var p Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		return w, p
+	}
+
+	w, p := serve("/logs")
+	if w.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, w.Header().Get("Content-Type"))
+	}
+	expected := Problem{
+		Type:      "urn:mcp-logging:error:INVALID_JSON",
+		Title:     "Invalid JSON format",
+		Status:    http.StatusBadRequest,
+		Detail:    "unexpected EOF",
+		Instance:  "/logs",
+		Code:      CodeInvalidJSON,
+		RequestID: "req-1",
+	}
+	if w.Code != http.StatusBadRequest || p != expected {
+		t.Errorf("Expected %+v, got %d %+v", expected, w.Code, p)
+	}
+
+	w, p = serve("/batch")
+	details, ok := p.Details.([]interface{})
+	if w.Code != http.StatusBadRequest || p.Code != CodeValidationError || p.Detail != "" || !ok || len(details) != 1 {
+		t.Errorf("Expected structured details, got %s", w.Body.String())
+	}
+
+	w, p = serve("/admin")
+	if w.Code != http.StatusForbidden || p.Code != CodeInsufficientPermissions {
+		t.Errorf("Expected a forbidden problem, got %s", w.Body.String())
+	}
+}
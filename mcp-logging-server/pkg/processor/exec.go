@@ -0,0 +1,250 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// VersionEnv is the environment variable telling external processors the APIVersion
+const VersionEnv = "MCP_LOGGING_PROCESSOR_VERSION"
+
+// closeTimeout is how long Close waits for a command to exit once its input is closed
+const closeTimeout = 5 * time.Second
+
+// ExecConfig configures an external processor
+type ExecConfig struct {
+	Command string   // Path or name of the executable
+	Args    []string // Arguments of the command
+	Env     []string // Variables added to the server's environment, as KEY=value
+	Workers int      // Instances of the command processing batches concurrently, at least 1
+}
+
+// execResponse is the line an external processor answers a batch with
+type execResponse struct {
+	Entries []models.LogEntry `json:"entries"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// Exec is an external processor: a pool of instances of a command, each started with the first
+// batch it gets and kept running, speaking JSON lines on its standard input and output. For each
+// batch an instance reads a line {"version": 1, "entries": [...]} and writes a line
+// {"entries": [...]} with the entries to keep, or {"error": "..."} to fail the batch. Its
+// standard error is logged. Each instance processes one batch at a time, so batches of
+// concurrent requests are spread over the instances and wait for a free one until their
+// deadline. An instance that exits, writes something else or misses the deadline of a batch is
+// stopped and started again with its next batch.
+type Exec struct {
+	config ExecConfig
+	name   string
+	idle   chan *execWorker // Instances not processing a batch, started or not
+}
+
+// execWorker is an instance of the command of an external processor. It is used by one batch at
+// a time, by whoever took it from the idle instances.
+type execWorker struct {
+	exec   *Exec
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output *os.File // Read end of the standard output, closed once the command exited
+	stdout *bufio.Reader
+	done   chan struct{} // Closed once the running command exited
+}
+
+// NewExec creates an external processor, its instances are started with their first batch
+func NewExec(name string, config ExecConfig) (*Exec, error) {
+	if config.Command == "" {
+		return nil, fmt.Errorf("processor %q has no command", name)
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	e := &Exec{config: config, name: name, idle: make(chan *execWorker, config.Workers)}
+	for i := 0; i < config.Workers; i++ {
+		e.idle <- &execWorker{exec: e}
+	}
+	return e, nil
+}
+
+// Process sends a batch to a free instance of the command and returns the entries it answers
+// with. It fails with the error of ctx if no instance frees up before ctx is done.
+func (e *Exec) Process(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	var w *execWorker
+	select {
+	case w = <-e.idle:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { e.idle <- w }()
+
+	if w.cmd == nil {
+		if err := w.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	request, err := codec.AppendEntries([]byte(`{"version":`+strconv.Itoa(APIVersion)+`,"entries":`), entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	request = append(request, "}\n"...)
+
+	type result struct {
+		response execResponse
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		var r result
+		if _, err := w.stdin.Write(request); err != nil {
+			r.err = fmt.Errorf("failed to send batch: %w", err)
+		} else if line, err := w.stdout.ReadBytes('\n'); err != nil {
+			r.err = fmt.Errorf("failed to read response: %w", err)
+		} else if err := codec.Decode(bytes.NewReader(line), &r.response); err != nil {
+			r.err = fmt.Errorf("invalid response: %w", err)
+		}
+		results <- r
+	}()
+
+	select {
+	case r := <-results:
+		if r.err != nil {
+			w.stop()
+			return nil, r.err
+		}
+		if r.response.Error != "" {
+			return nil, errors.New(r.response.Error)
+		}
+		return r.response.Entries, nil
+	case <-ctx.Done():
+		// Stopping the command ends the pending write and read
+		w.stop()
+		<-results
+		return nil, ctx.Err()
+	}
+}
+
+// start starts the command of the instance
+func (w *execWorker) start() error {
+	config := w.exec.config
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = append(os.Environ(), VersionEnv+"="+strconv.Itoa(APIVersion))
+	cmd.Env = append(cmd.Env, config.Env...)
+	cmd.Stderr = &stderrLogger{name: w.exec.name}
+	// Children the command leaves running, such as those of a shell script, may hold its output
+	// open after it exited
+	cmd.WaitDelay = time.Second
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	// Unlike the pipe of StdoutPipe, which Wait closes, this one is closed only once the command
+	// exited, so that Wait cannot close it under a batch reading the response
+	output, stdout, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd.Stdout = stdout
+	err = cmd.Start()
+	stdout.Close()
+	if err != nil {
+		stdin.Close()
+		output.Close()
+		return fmt.Errorf("failed to start %s: %w", config.Command, err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		close(done)
+		if err != nil {
+			log.Printf("Processor %s: %s exited: %v", w.exec.name, config.Command, err)
+		}
+	}()
+
+	w.cmd, w.stdin, w.output, w.stdout, w.done = cmd, stdin, output, bufio.NewReader(output), done
+	return nil
+}
+
+// stop kills the command of the instance and waits for it to exit
+func (w *execWorker) stop() {
+	if w.cmd == nil {
+		return
+	}
+	w.stdin.Close()
+	w.cmd.Process.Kill()
+	<-w.done
+	w.release()
+}
+
+// release closes the standard output of the exited command and forgets it
+func (w *execWorker) release() {
+	w.output.Close()
+	w.cmd, w.stdin, w.output, w.stdout, w.done = nil, nil, nil, nil, nil
+}
+
+// close closes the standard input of the command so that it can exit by itself, and kills it
+// if it has not exited after closeTimeout
+func (w *execWorker) close() {
+	if w.cmd == nil {
+		return
+	}
+	w.stdin.Close()
+	select {
+	case <-w.done:
+		w.release()
+	case <-time.After(closeTimeout):
+		w.stop()
+	}
+}
+
+// Close stops the instances of the command once their batches are processed. Their standard
+// input is closed first, so that they can exit by themselves, and they are killed if they have
+// not exited after closeTimeout. Instances are started again by later batches.
+func (e *Exec) Close() error {
+	workers := make([]*execWorker, 0, e.config.Workers)
+	for i := 0; i < e.config.Workers; i++ {
+		workers = append(workers, <-e.idle)
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *execWorker) {
+			defer wg.Done()
+			w.close()
+		}(w)
+	}
+	wg.Wait()
+
+	for _, w := range workers {
+		e.idle <- w
+	}
+	return nil
+}
+
+// stderrLogger logs the lines an external processor writes to its standard error
+type stderrLogger struct {
+	name string
+}
+
+func (l *stderrLogger) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		log.Printf("Processor %s: %s", l.name, line)
+	}
+	return len(p), nil
+}
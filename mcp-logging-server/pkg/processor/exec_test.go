@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// fakeProcessor writes a shell script acting as an external processor
+func fakeProcessor(t *testing.T, body string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake processor is a shell script")
+	}
+
+	command := filepath.Join(t.TempDir(), "processor")
+	if err := os.WriteFile(command, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return command
+}
+
+func TestExec_Process(t *testing.T) {
+	// Tags every entry by rewriting the batch, answering with the request line itself
+	command := fakeProcessor(t, `exec sed -u 's/"platform":"go"/"platform":"go","tags":["enriched"]/g'`)
+	processor, err := NewExec("enrich", ExecConfig{Command: command})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	for i := 0; i < 2; i++ {
+		entries, err := processor.Process(context.Background(), []models.LogEntry{newEntry("Order placed"), newEntry("Order shipped")})
+		if err != nil {
+			t.Fatalf("Failed to process entries: %v", err)
+		}
+		if len(entries) != 2 || entries[1].Message != "Order shipped" || len(entries[1].Tags) != 1 || entries[1].Tags[0] != "enriched" {
+			t.Errorf("Expected the enriched entries, got %+v", entries)
+		}
+	}
+}
+
+func TestExec_Failures(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "error response", body: `while read -r line; do echo '{"error": "lookup service down"}'; done`},
+		{name: "invalid response", body: `while read -r line; do echo 'not json'; done`},
+		{name: "exits", body: `read -r line; exit 1`},
+		{name: "misses deadline", body: `exec sleep 30`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := NewExec(tt.name, ExecConfig{Command: fakeProcessor(t, tt.body)})
+			if err != nil {
+				t.Fatalf("Failed to create processor: %v", err)
+			}
+			defer processor.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if _, err := processor.Process(ctx, []models.LogEntry{newEntry("Order placed")}); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestExec_RestartsCommand(t *testing.T) {
+	// Answers one batch, then exits
+	command := fakeProcessor(t, `read -r line; echo '{"entries": []}'`)
+	processor, err := NewExec("once", ExecConfig{Command: command})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	batch := []models.LogEntry{newEntry("Order placed")}
+	if _, err := processor.Process(context.Background(), batch); err != nil {
+		t.Fatalf("Failed to process entries: %v", err)
+	}
+	if _, err := processor.Process(context.Background(), batch); err == nil {
+		t.Fatal("Expected an error once the command exited")
+	}
+	if entries, err := processor.Process(context.Background(), batch); err != nil || len(entries) != 0 {
+		t.Errorf("Expected the command to be started again, got %v, %v", entries, err)
+	}
+}
+
+func TestExec_Workers(t *testing.T) {
+	// Takes half a second per batch
+	command := fakeProcessor(t, `while read -r line; do sleep 0.5; echo '{"entries": []}'; done`)
+	processor, err := NewExec("slow", ExecConfig{Command: command, Workers: 4})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	start := time.Now()
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := processor.Process(context.Background(), []models.LogEntry{newEntry("Order placed")})
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Failed to process entries: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+		t.Errorf("Expected the batches processed concurrently, took %s", elapsed)
+	}
+}
+
+func TestExec_WaitsForWorkerUntilDeadline(t *testing.T) {
+	command := fakeProcessor(t, `while read -r line; do sleep 2; echo '{"entries": []}'; done`)
+	processor, err := NewExec("busy", ExecConfig{Command: command, Workers: 1})
+	if err != nil {
+		t.Fatalf("Failed to create processor: %v", err)
+	}
+	defer processor.Close()
+
+	busy := make(chan error, 1)
+	go func() {
+		_, err := processor.Process(context.Background(), []models.LogEntry{newEntry("Order placed")})
+		busy <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	// The only instance is busy, so the batch fails at its deadline without reaching the command
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := processor.Process(ctx, []models.LogEntry{newEntry("Order shipped")}); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the wait to end at the deadline, took %s", elapsed)
+	}
+	if err := <-busy; err != nil {
+		t.Errorf("Expected the first batch processed, got %v", err)
+	}
+}
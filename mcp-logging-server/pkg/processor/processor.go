@@ -0,0 +1,188 @@
+// Package processor runs custom enrichment and filtering of log entries in the ingestion
+// pipeline, so that teams can add their own steps without forking the server: Go processors
+// passed by programs embedding the server, and external processors run as commands.
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// APIVersion is the version of the Processor interface and of the protocol spoken with external
+// processors. It only changes with changes that break existing processors.
+const APIVersion = 1
+
+// DefaultTimeout is the deadline of a processor for a batch when its stage sets none
+const DefaultTimeout = 5 * time.Second
+
+// Processor processes batches of valid entries after validation and before data protection.
+// It returns the entries to keep, changed as needed: entries it leaves out are dropped, and it
+// may add entries. Entries are validated again afterwards. Process is called concurrently for
+// different requests, and the entries belong to it until it returns.
+type Processor interface {
+	Process(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error)
+}
+
+// Func adapts a function to the Processor interface
+type Func func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error)
+
+// Process calls f(ctx, entries)
+func (f Func) Process(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	return f(ctx, entries)
+}
+
+// ErrorPolicy decides what happens to a batch a processor fails on
+type ErrorPolicy string
+
+const (
+	OnErrorFail ErrorPolicy = "fail" // The batch is rejected, clients retry it
+	OnErrorSkip ErrorPolicy = "skip" // The batch continues as the processor got it
+)
+
+// Stage is a processor of a chain
+type Stage struct {
+	Name      string // Identifies the processor in errors, logs and stats
+	Processor Processor
+	OnError   ErrorPolicy   // OnErrorFail when empty
+	Timeout   time.Duration // Deadline per batch, DefaultTimeout when 0
+}
+
+// Stats are the counts of a processor since the server started
+type Stats struct {
+	Name      string `json:"name"`
+	Processed int64  `json:"processed"` // Entries passed to the processor
+	Dropped   int64  `json:"dropped"`   // Entries it left out
+	Failures  int64  `json:"failures"`  // Batches it failed on, rejected or skipped by its error policy
+}
+
+// stage is a stage with its counts
+type stage struct {
+	Stage
+	processed atomic.Int64
+	dropped   atomic.Int64
+	failures  atomic.Int64
+}
+
+// Chain runs processors in order, each on the entries the previous one kept
+type Chain struct {
+	stages []*stage
+}
+
+// NewChain checks the stages and creates a chain running them
+func NewChain(stages []Stage) (*Chain, error) {
+	chain := &Chain{stages: make([]*stage, 0, len(stages))}
+	names := make(map[string]bool, len(stages))
+	for i, s := range stages {
+		if s.Name == "" {
+			return nil, fmt.Errorf("processor %d has no name", i+1)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("processor %q is defined twice", s.Name)
+		}
+		names[s.Name] = true
+		if s.Processor == nil {
+			return nil, fmt.Errorf("processor %q has no implementation", s.Name)
+		}
+		switch s.OnError {
+		case "":
+			s.OnError = OnErrorFail
+		case OnErrorFail, OnErrorSkip:
+		default:
+			return nil, fmt.Errorf("processor %q: on_error must be %s or %s", s.Name, OnErrorFail, OnErrorSkip)
+		}
+		if s.Timeout < 0 {
+			return nil, fmt.Errorf("processor %q: timeout cannot be negative", s.Name)
+		}
+		if s.Timeout == 0 {
+			s.Timeout = DefaultTimeout
+		}
+		chain.stages = append(chain.stages, &stage{Stage: s})
+	}
+	return chain, nil
+}
+
+// Len returns the number of processors of the chain
+func (c *Chain) Len() int {
+	return len(c.stages)
+}
+
+// Process runs the processors on a batch and returns the entries to store. A processor failing,
+// or returning an invalid entry, rejects the batch unless its error policy skips it.
+func (c *Chain) Process(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	for _, s := range c.stages {
+		if len(entries) == 0 {
+			break
+		}
+		processed, err := s.process(ctx, entries)
+		if err != nil {
+			s.failures.Add(1)
+			if s.OnError == OnErrorFail {
+				return nil, fmt.Errorf("processor %s: %w", s.Name, err)
+			}
+			log.Printf("Processor %s failed, batch of %d entries skips it: %v", s.Name, len(entries), err)
+			continue
+		}
+		s.processed.Add(int64(len(entries)))
+		if dropped := len(entries) - len(processed); dropped > 0 {
+			s.dropped.Add(int64(dropped))
+		}
+		entries = processed
+	}
+	return entries, nil
+}
+
+// process runs the processor of a stage with its deadline on a copy of the entries, so that a
+// batch skipping a failed processor is unchanged by it
+func (s *stage) process(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	batch := entries
+	if s.OnError == OnErrorSkip {
+		batch = make([]models.LogEntry, len(entries))
+		copy(batch, entries)
+	}
+	processed, err := s.Processor.Process(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	for i := range processed {
+		if err := processed[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid entry %s: %w", processed[i].ID, err)
+		}
+	}
+	return processed, nil
+}
+
+// Stats returns the counts of the processors, in order
+func (c *Chain) Stats() []Stats {
+	stats := make([]Stats, len(c.stages))
+	for i, s := range c.stages {
+		stats[i] = Stats{
+			Name:      s.Name,
+			Processed: s.processed.Load(),
+			Dropped:   s.dropped.Load(),
+			Failures:  s.failures.Load(),
+		}
+	}
+	return stats
+}
+
+// Close closes the processors holding resources, such as the commands of external processors
+func (c *Chain) Close() error {
+	var firstErr error
+	for _, s := range c.stages {
+		if closer, ok := s.Processor.(io.Closer); ok {
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("processor %s: %w", s.Name, err)
+			}
+		}
+	}
+	return firstErr
+}
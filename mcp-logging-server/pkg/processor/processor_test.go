@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newEntry(message string) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelInfo,
+		Message:     message,
+		ServiceName: "checkout",
+		AgentID:     "agent-1",
+		Platform:    models.PlatformGo,
+	}
+}
+
+func TestChain_Process(t *testing.T) {
+	enrich := Func(func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+		for i := range entries {
+			entries[i].Tags = append(entries[i].Tags, "enriched")
+		}
+		return entries, nil
+	})
+	dropHealthChecks := Func(func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+		kept := entries[:0]
+		for _, entry := range entries {
+			if !strings.HasPrefix(entry.Message, "GET /health") {
+				kept = append(kept, entry)
+			}
+		}
+		return kept, nil
+	})
+
+	chain, err := NewChain([]Stage{
+		{Name: "enrich", Processor: enrich},
+		{Name: "drop-health-checks", Processor: dropHealthChecks},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+
+	entries, err := chain.Process(context.Background(), []models.LogEntry{
+		newEntry("GET /health 200"),
+		newEntry("Order placed"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to process entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Message != "Order placed" || len(entries[0].Tags) != 1 || entries[0].Tags[0] != "enriched" {
+		t.Errorf("Expected the enriched order only, got %+v", entries)
+	}
+
+	stats := chain.Stats()
+	if stats[0].Processed != 2 || stats[0].Dropped != 0 || stats[1].Processed != 2 || stats[1].Dropped != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestChain_ErrorPolicy(t *testing.T) {
+	failing := Func(func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+		entries[0].Message = "changed before failing"
+		return nil, errors.New("lookup service down")
+	})
+	invalid := Func(func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+		entries[0].ServiceName = ""
+		return entries, nil
+	})
+
+	tests := []struct {
+		name      string
+		processor Processor
+		onError   ErrorPolicy
+		wantErr   bool
+	}{
+		{name: "fail", processor: failing, wantErr: true},
+		{name: "skip", processor: failing, onError: OnErrorSkip},
+		{name: "invalid entry", processor: invalid, wantErr: true},
+		{name: "skip invalid entry", processor: invalid, onError: OnErrorSkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := NewChain([]Stage{{Name: tt.name, Processor: tt.processor, OnError: tt.onError}})
+			if err != nil {
+				t.Fatalf("Failed to create chain: %v", err)
+			}
+			entries, err := chain.Process(context.Background(), []models.LogEntry{newEntry("Order placed")})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && (len(entries) != 1 || entries[0].Message != "Order placed" || entries[0].ServiceName != "checkout") {
+				t.Errorf("Expected the batch to skip the processor unchanged, got %+v", entries)
+			}
+			if chain.Stats()[0].Failures != 1 {
+				t.Errorf("Expected the failure to be counted, got %+v", chain.Stats()[0])
+			}
+		})
+	}
+}
+
+func TestNewChain_Invalid(t *testing.T) {
+	noop := Func(func(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+		return entries, nil
+	})
+
+	tests := []struct {
+		name   string
+		stages []Stage
+	}{
+		{name: "no name", stages: []Stage{{Processor: noop}}},
+		{name: "duplicate name", stages: []Stage{{Name: "a", Processor: noop}, {Name: "a", Processor: noop}}},
+		{name: "no processor", stages: []Stage{{Name: "a"}}},
+		{name: "unknown policy", stages: []Stage{{Name: "a", Processor: noop, OnError: "retry"}}},
+		{name: "negative timeout", stages: []Stage{{Name: "a", Processor: noop, Timeout: -time.Second}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewChain(tt.stages); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Package procs sizes the server's concurrency to the CPUs it may use. In a container these are
+// limited by the cgroup CPU quota rather than the CPUs of the node, which the Go runtime uses by
+// default, so a server on a large node with a small limit would be throttled.
+package procs
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// cgroupRoot is where the cgroup filesystem of the process is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// Sources of the GOMAXPROCS value chosen by Set
+const (
+	SourceConfig      = "config"
+	SourceEnvironment = "GOMAXPROCS environment variable"
+	SourceCPUQuota    = "container CPU limit"
+	SourceCPUs        = "CPUs of the node"
+)
+
+// Set sets GOMAXPROCS and returns the value with its source: the configured value when positive,
+// the GOMAXPROCS environment variable when set, or the cgroup CPU quota rounded down when it is
+// below the CPUs of the node, but at least 1
+func Set(configured int) (int, string) {
+	if configured > 0 {
+		runtime.GOMAXPROCS(configured)
+		return configured, SourceConfig
+	}
+	if os.Getenv("GOMAXPROCS") != "" {
+		return runtime.GOMAXPROCS(0), SourceEnvironment
+	}
+
+	quota, limited, err := CPUQuota(cgroupRoot)
+	if err != nil || !limited {
+		return runtime.GOMAXPROCS(0), SourceCPUs
+	}
+	procs := max(int(math.Floor(quota)), 1)
+	if procs >= runtime.NumCPU() {
+		return runtime.GOMAXPROCS(0), SourceCPUs
+	}
+	runtime.GOMAXPROCS(procs)
+	return procs, SourceCPUQuota
+}
+
+// Workers returns the configured number of workers when positive, otherwise one per GOMAXPROCS
+func Workers(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// CPUQuota returns the CPUs the cgroup mounted at root may use, from cpu.max with cgroup v2 or
+// cpu.cfs_quota_us and cpu.cfs_period_us with cgroup v1. limited is false without a quota or
+// cgroup filesystem.
+func CPUQuota(root string) (quota float64, limited bool, err error) {
+	if data, err := os.ReadFile(filepath.Join(root, "cpu.max")); err == nil {
+		return parseCPUMax(string(data))
+	}
+
+	quotaData, err := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_quota_us"))
+	if err != nil {
+		return 0, false, nil
+	}
+	periodData, err := os.ReadFile(filepath.Join(root, "cpu", "cpu.cfs_period_us"))
+	if err != nil {
+		return 0, false, nil
+	}
+	return cpuQuota(strings.TrimSpace(string(quotaData)), strings.TrimSpace(string(periodData)))
+}
+
+// parseCPUMax parses the "$MAX $PERIOD" contents of a cgroup v2 cpu.max file
+func parseCPUMax(data string) (float64, bool, error) {
+	fields := strings.Fields(data)
+	if len(fields) != 2 {
+		return 0, false, fmt.Errorf("invalid cpu.max %q", strings.TrimSpace(data))
+	}
+	if fields[0] == "max" {
+		return 0, false, nil
+	}
+	return cpuQuota(fields[0], fields[1])
+}
+
+// cpuQuota divides a quota by its period, both in microseconds. A negative quota is unlimited.
+func cpuQuota(quota, period string) (float64, bool, error) {
+	q, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid CPU quota %q: %w", quota, err)
+	}
+	p, err := strconv.ParseInt(period, 10, 64)
+	if err != nil || p <= 0 {
+		return 0, false, fmt.Errorf("invalid CPU period %q", period)
+	}
+	if q < 0 {
+		return 0, false, nil
+	}
+	return float64(q) / float64(p), true, nil
+}
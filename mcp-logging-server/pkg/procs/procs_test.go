@@ -0,0 +1,77 @@
+package procs
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func writeCgroupFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestCPUQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		quota   float64
+		limited bool
+		wantErr bool
+	}{
+		{"no cgroup", nil, 0, false, false},
+		{"v2 limited", map[string]string{"cpu.max": "150000 100000\n"}, 1.5, true, false},
+		{"v2 unlimited", map[string]string{"cpu.max": "max 100000\n"}, 0, false, false},
+		{"v2 invalid", map[string]string{"cpu.max": "lots\n"}, 0, false, true},
+		{"v1 limited", map[string]string{"cpu/cpu.cfs_quota_us": "400000\n", "cpu/cpu.cfs_period_us": "100000\n"}, 4, true, false},
+		{"v1 unlimited", map[string]string{"cpu/cpu.cfs_quota_us": "-1\n", "cpu/cpu.cfs_period_us": "100000\n"}, 0, false, false},
+		{"v1 without period", map[string]string{"cpu/cpu.cfs_quota_us": "400000\n"}, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for name, content := range tt.files {
+				writeCgroupFile(t, root, name, content)
+			}
+
+			quota, limited, err := CPUQuota(root)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if quota != tt.quota || limited != tt.limited {
+				t.Errorf("Expected quota %v (limited %v), got %v (limited %v)", tt.quota, tt.limited, quota, limited)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+
+	if procs, source := Set(3); procs != 3 || source != SourceConfig || runtime.GOMAXPROCS(0) != 3 {
+		t.Errorf("Expected the configured 3, got %d from %s", procs, source)
+	}
+
+	t.Setenv("GOMAXPROCS", "2")
+	runtime.GOMAXPROCS(2)
+	if procs, source := Set(0); procs != 2 || source != SourceEnvironment {
+		t.Errorf("Expected 2 from the environment, got %d from %s", procs, source)
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	if workers := Workers(5); workers != 5 {
+		t.Errorf("Expected the configured 5 workers, got %d", workers)
+	}
+	if workers := Workers(0); workers != runtime.GOMAXPROCS(0) {
+		t.Errorf("Expected one worker per GOMAXPROCS, got %d", workers)
+	}
+}
@@ -0,0 +1,132 @@
+// Package queryaudit keeps an audit trail of the queries of log entries made by MCP tool calls
+// and REST requests: who made them, what they asked for, how many entries they were shown and
+// which fields were masked, so that it can be told afterwards who viewed which logs.
+package queryaudit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// FlushInterval is how often recorded queries are added to storage
+const FlushInterval = 5 * time.Second
+
+// pruneInterval is how often records older than the retention are deleted
+const pruneInterval = time.Hour
+
+// maxPending is the number of records kept in memory while the storage does not take them, the
+// oldest are dropped beyond it
+const maxPending = 10000
+
+// Recorder collects query audit records in memory and adds them to storage periodically, so
+// that queries do not wait for the audit trail to be written
+type Recorder struct {
+	store     storage.QueryAuditStore
+	retention time.Duration
+
+	mutex   sync.Mutex
+	pending []models.QueryAudit
+	dropped int64 // Records dropped since the last successful flush
+}
+
+// NewRecorder creates a recorder adding records to the store and deleting them once they are
+// older than retention, 0 keeps them
+func NewRecorder(store storage.QueryAuditStore, retention time.Duration) *Recorder {
+	return &Recorder{store: store, retention: retention}
+}
+
+// Record adds a query to the audit trail
+func (r *Recorder) Record(record models.QueryAudit) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.pending) >= maxPending {
+		r.pending = r.pending[1:]
+		r.dropped++
+	}
+	r.pending = append(r.pending, record)
+}
+
+// Flush adds the records collected since the last flush to storage. Records that could not be
+// added are kept for the next flush.
+func (r *Recorder) Flush(ctx context.Context) error {
+	r.mutex.Lock()
+	records, dropped := r.pending, r.dropped
+	r.pending, r.dropped = nil, 0
+	r.mutex.Unlock()
+
+	if dropped > 0 {
+		log.Printf("Query audit: dropped %d records the storage did not take in time", dropped)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	if err := r.store.RecordQueryAudits(ctx, records); err != nil {
+		r.mutex.Lock()
+		r.pending = append(records, r.pending...)
+		if excess := len(r.pending) - maxPending; excess > 0 {
+			r.pending = r.pending[excess:]
+			r.dropped += int64(excess)
+		}
+		r.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// List returns the records matching the filter, newest first, including those not flushed yet
+func (r *Recorder) List(ctx context.Context, filter models.QueryAuditFilter) ([]models.QueryAudit, error) {
+	if err := r.Flush(ctx); err != nil {
+		log.Printf("Failed to flush query audit: %v", err)
+	}
+	return r.store.ListQueryAudits(ctx, filter)
+}
+
+// Run flushes the records periodically, and once more when ctx is done, and deletes the records
+// older than the retention
+func (r *Recorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	pruneTicker := time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+
+	r.prune(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := r.Flush(flushCtx); err != nil {
+				log.Printf("Failed to flush query audit: %v", err)
+			}
+			cancel()
+			return nil
+		case <-ticker.C:
+			if err := r.Flush(ctx); err != nil {
+				log.Printf("Failed to flush query audit: %v", err)
+			}
+		case <-pruneTicker.C:
+			r.prune(ctx)
+		}
+	}
+}
+
+// prune deletes the records older than the retention
+func (r *Recorder) prune(ctx context.Context) {
+	if r.retention <= 0 {
+		return
+	}
+	deleted, err := r.store.DeleteQueryAudits(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		log.Printf("Failed to delete expired query audit records: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Query audit: deleted %d records older than %s", deleted, r.retention)
+	}
+}
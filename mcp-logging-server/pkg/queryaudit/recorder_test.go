@@ -0,0 +1,67 @@
+package queryaudit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// failingStore fails to record audits while failing is set
+type failingStore struct {
+	*storage.MemoryStorage
+	failing bool
+}
+
+func (s *failingStore) RecordQueryAudits(ctx context.Context, records []models.QueryAudit) error {
+	if s.failing {
+		return errors.New("storage unavailable")
+	}
+	return s.MemoryStorage.RecordQueryAudits(ctx, records)
+}
+
+func TestRecorder_Flush(t *testing.T) {
+	ctx := context.Background()
+	store := &failingStore{MemoryStorage: storage.NewMemoryStorage(), failing: true}
+	recorder := NewRecorder(store, 0)
+
+	recorder.Record(models.QueryAudit{Time: time.Now(), Interface: models.QueryInterfaceMCP, Operation: "query_logs", ResultCount: 5})
+	if err := recorder.Flush(ctx); err == nil {
+		t.Fatal("Expected the flush to fail")
+	}
+
+	// Records are kept until the storage takes them
+	store.failing = false
+	recorder.Record(models.QueryAudit{Time: time.Now(), Interface: models.QueryInterfaceREST, Operation: "GET /v1/search"})
+	records, err := recorder.List(ctx, models.QueryAuditFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list records: %v", err)
+	}
+	if len(records) != 2 || records[1].Operation != "query_logs" {
+		t.Errorf("Expected both records once the storage recovered, got %+v", records)
+	}
+}
+
+func TestRecorder_Prune(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	recorder := NewRecorder(store, 24*time.Hour)
+
+	recorder.Record(models.QueryAudit{Time: time.Now().Add(-48 * time.Hour), Operation: "old"})
+	recorder.Record(models.QueryAudit{Time: time.Now(), Operation: "recent"})
+	if err := recorder.Flush(ctx); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	recorder.prune(ctx)
+
+	records, err := store.ListQueryAudits(ctx, models.QueryAuditFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list records: %v", err)
+	}
+	if len(records) != 1 || records[0].Operation != "recent" {
+		t.Errorf("Expected only the recent record to be kept, got %+v", records)
+	}
+}
@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Algorithm selects how requests are counted against a limit
+type Algorithm string
+
+const (
+	// AlgorithmTokenBucket refills the allowance continuously and allows bursts of BurstSize
+	AlgorithmTokenBucket Algorithm = "token_bucket"
+
+	// AlgorithmFixedWindow allows RequestsPerMinute requests per calendar minute, which permits
+	// twice the limit around the boundary between two minutes
+	AlgorithmFixedWindow Algorithm = "fixed_window"
+
+	// AlgorithmSlidingWindow allows RequestsPerMinute requests in any minute, estimated from the
+	// counts of the current and the previous minute, so neither bursts nor boundaries exceed it
+	AlgorithmSlidingWindow Algorithm = "sliding_window"
+)
+
+// window is the period RequestsPerMinute refers to
+const window = time.Minute
+
+// ParseAlgorithm parses the name of a rate limiting algorithm, empty for the token bucket
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch algorithm := Algorithm(name); algorithm {
+	case "":
+		return AlgorithmTokenBucket, nil
+	case AlgorithmTokenBucket, AlgorithmFixedWindow, AlgorithmSlidingWindow:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("unsupported rate limiting algorithm %q, use token_bucket, fixed_window or sliding_window", name)
+	}
+}
+
+// limiter counts the requests of one key with one of the algorithms. Callers serialize access.
+type limiter interface {
+	// allow reports whether a request at now is within the limit, counting it if it is
+	allow(now time.Time) bool
+	// remaining returns how many more requests would be allowed at now
+	remaining(now time.Time) int
+	// resetTime returns when the full allowance is available again
+	resetTime(now time.Time) time.Time
+	// requestsPerMinute returns the limit the limiter was created with
+	requestsPerMinute() int
+}
+
+// newLimiter creates a limiter for the algorithm, the token bucket for unknown algorithms
+func newLimiter(algorithm Algorithm, requestsPerMinute, burstSize int) limiter {
+	switch algorithm {
+	case AlgorithmFixedWindow:
+		return &fixedWindow{limit: requestsPerMinute}
+	case AlgorithmSlidingWindow:
+		return &slidingWindow{limit: requestsPerMinute}
+	default:
+		return &tokenBucket{
+			limiter: rate.NewLimiter(rate.Limit(float64(requestsPerMinute)/window.Seconds()), burstSize),
+			limit:   requestsPerMinute,
+		}
+	}
+}
+
+// tokenBucket is the AlgorithmTokenBucket limiter
+type tokenBucket struct {
+	limiter *rate.Limiter
+	limit   int
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	return b.limiter.AllowN(now, 1)
+}
+
+func (b *tokenBucket) remaining(now time.Time) int {
+	return int(math.Max(0, b.limiter.TokensAt(now)))
+}
+
+func (b *tokenBucket) resetTime(now time.Time) time.Time {
+	missing := float64(b.limiter.Burst()) - b.limiter.TokensAt(now)
+	if missing <= 0 || b.limiter.Limit() <= 0 {
+		return now
+	}
+	return now.Add(time.Duration(missing / float64(b.limiter.Limit()) * float64(time.Second)))
+}
+
+func (b *tokenBucket) requestsPerMinute() int {
+	return b.limit
+}
+
+// fixedWindow is the AlgorithmFixedWindow limiter
+type fixedWindow struct {
+	limit int
+	start time.Time
+	count int
+}
+
+// advance starts a new window if the current one has ended
+func (w *fixedWindow) advance(now time.Time) {
+	if !now.Before(w.start.Add(window)) {
+		w.start = now.Truncate(window)
+		w.count = 0
+	}
+}
+
+func (w *fixedWindow) allow(now time.Time) bool {
+	w.advance(now)
+	if w.count >= w.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+func (w *fixedWindow) remaining(now time.Time) int {
+	w.advance(now)
+	return max(0, w.limit-w.count)
+}
+
+func (w *fixedWindow) resetTime(now time.Time) time.Time {
+	w.advance(now)
+	return w.start.Add(window)
+}
+
+func (w *fixedWindow) requestsPerMinute() int {
+	return w.limit
+}
+
+// slidingWindow is the AlgorithmSlidingWindow limiter. It weights the previous minute's count
+// by how much of it still overlaps the minute before now.
+type slidingWindow struct {
+	limit    int
+	start    time.Time // Start of the current window
+	current  int
+	previous int
+}
+
+// advance moves the windows forward so that the current one contains now
+func (w *slidingWindow) advance(now time.Time) {
+	switch {
+	case now.Before(w.start.Add(window)):
+	case now.Before(w.start.Add(2 * window)):
+		w.previous, w.current = w.current, 0
+		w.start = w.start.Add(window)
+	default:
+		w.previous, w.current = 0, 0
+		w.start = now.Truncate(window)
+	}
+}
+
+// estimate returns the estimated number of requests in the minute before now
+func (w *slidingWindow) estimate(now time.Time) float64 {
+	overlap := 1 - float64(now.Sub(w.start))/float64(window)
+	return float64(w.previous)*overlap + float64(w.current)
+}
+
+func (w *slidingWindow) allow(now time.Time) bool {
+	w.advance(now)
+	if w.estimate(now)+1 > float64(w.limit) {
+		return false
+	}
+	w.current++
+	return true
+}
+
+func (w *slidingWindow) remaining(now time.Time) int {
+	w.advance(now)
+	return max(0, int(float64(w.limit)-w.estimate(now)))
+}
+
+func (w *slidingWindow) resetTime(now time.Time) time.Time {
+	w.advance(now)
+	// Requests of the current window count until a full window after it ends
+	if w.current > 0 {
+		return w.start.Add(2 * window)
+	}
+	return w.start.Add(window)
+}
+
+func (w *slidingWindow) requestsPerMinute() int {
+	return w.limit
+}
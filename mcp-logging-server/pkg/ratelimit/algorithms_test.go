@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	algorithm, err := ParseAlgorithm("")
+	if err != nil || algorithm != AlgorithmTokenBucket {
+		t.Errorf("Expected token bucket for an empty name, got %q, %v", algorithm, err)
+	}
+	algorithm, err = ParseAlgorithm("sliding_window")
+	if err != nil || algorithm != AlgorithmSlidingWindow {
+		t.Errorf("Expected sliding window, got %q, %v", algorithm, err)
+	}
+	if _, err := ParseAlgorithm("leaky_bucket"); err == nil {
+		t.Error("Expected an error for an unsupported algorithm")
+	}
+}
+
+func TestFixedWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := newLimiter(AlgorithmFixedWindow, 3, 0)
+
+	for i := 0; i < 3; i++ {
+		if !w.allow(start.Add(50 * time.Second)) {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+	}
+	if w.allow(start.Add(55 * time.Second)) {
+		t.Error("Request should be denied after the limit is reached")
+	}
+	if remaining := w.remaining(start.Add(55 * time.Second)); remaining != 0 {
+		t.Errorf("Expected 0 remaining, got %d", remaining)
+	}
+	if reset := w.resetTime(start.Add(55 * time.Second)); !reset.Equal(start.Add(time.Minute)) {
+		t.Errorf("Expected reset at the end of the minute, got %v", reset)
+	}
+
+	// The next minute starts with the full limit
+	if !w.allow(start.Add(61 * time.Second)) {
+		t.Error("Request should be allowed in the next window")
+	}
+	if remaining := w.remaining(start.Add(61 * time.Second)); remaining != 2 {
+		t.Errorf("Expected 2 remaining, got %d", remaining)
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	w := newLimiter(AlgorithmSlidingWindow, 10, 0)
+
+	for i := 0; i < 10; i++ {
+		if !w.allow(start.Add(50 * time.Second)) {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+	}
+	if w.allow(start.Add(55 * time.Second)) {
+		t.Error("Request should be denied after the limit is reached")
+	}
+
+	// Unlike a fixed window, the requests just before the boundary still count after it
+	now := start.Add(66 * time.Second) // 90% of the previous minute overlaps
+	if remaining := w.remaining(now); remaining != 1 {
+		t.Errorf("Expected 1 remaining, got %d", remaining)
+	}
+	if !w.allow(now) {
+		t.Error("Request should be allowed within the estimated limit")
+	}
+	if w.allow(now) {
+		t.Error("Request should be denied beyond the estimated limit")
+	}
+	if reset := w.resetTime(now); !reset.Equal(start.Add(3 * time.Minute)) {
+		t.Errorf("Expected reset two minutes after the current window started, got %v", reset)
+	}
+
+	// After two idle minutes nothing counts anymore
+	if remaining := w.remaining(start.Add(5 * time.Minute)); remaining != 10 {
+		t.Errorf("Expected 10 remaining, got %d", remaining)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newLimiter(AlgorithmTokenBucket, 60, 2)
+
+	if !b.allow(now) || !b.allow(now) {
+		t.Fatal("Requests within the burst should be allowed")
+	}
+	if b.allow(now) {
+		t.Error("Request should be denied after the burst is exhausted")
+	}
+	if remaining := b.remaining(now); remaining != 0 {
+		t.Errorf("Expected 0 remaining, got %d", remaining)
+	}
+	if reset := b.resetTime(now); reset.Sub(now) != 2*time.Second {
+		t.Errorf("Expected the bucket to refill in 2s, got %v", reset.Sub(now))
+	}
+}
+
+func TestRateLimiter_AllowAlgorithms(t *testing.T) {
+	for _, algorithm := range []Algorithm{AlgorithmTokenBucket, AlgorithmFixedWindow, AlgorithmSlidingWindow} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			config := DefaultRateLimitConfig()
+			config.Algorithm = algorithm
+			config.RequestsPerMinute = 5
+			config.BurstSize = 5
+			config.MaxViolations = 100
+
+			rl := NewRateLimiter(config)
+			defer rl.Stop()
+
+			for i := 0; i < 5; i++ {
+				allowed, info := rl.Allow("key")
+				if !allowed {
+					t.Fatalf("Request %d should be allowed", i+1)
+				}
+				if info.Limit != 5 || info.Remaining != 4-i {
+					t.Errorf("Expected limit 5 and %d remaining, got %d and %d", 4-i, info.Limit, info.Remaining)
+				}
+			}
+
+			allowed, info := rl.Allow("key")
+			if allowed {
+				t.Error("Request should be denied after the limit is reached")
+			}
+			if !info.ResetTime.After(time.Now()) {
+				t.Errorf("Expected the reset time in the future, got %v", info.ResetTime)
+			}
+		})
+	}
+}
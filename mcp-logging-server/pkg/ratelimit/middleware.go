@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/problem"
 )
 
 // RateLimitMiddleware creates a Gin middleware for rate limiting
@@ -28,24 +29,17 @@ func RateLimitMiddleware(rateLimiter *RateLimiter) gin.HandlerFunc {
 			return
 		}
 
-		// Check API key-based rate limit if authenticated
-		if keyInfo, exists := auth.GetAPIKeyInfo(c); exists {
-			if apiKey, hasKey := auth.GetAPIKey(c); hasKey {
-				// Use custom rate limit from API key info, or default
-				customLimit := keyInfo.RateLimit
-				if customLimit <= 0 {
-					customLimit = 1000 // Default
-				}
-
-				keyAllowed, keyInfo := rateLimiter.AllowAPIKey(apiKey, customLimit)
-				if !keyAllowed {
-					handleRateLimitExceeded(c, keyInfo, "API_KEY")
-					return
-				}
-
-				// Add API key rate limit headers
-				addRateLimitHeaders(c, keyInfo, "API-Key")
+		// Check API key-based rate limit if authenticated, with the key's own limit if it has one.
+		// Keys are tracked by ID so that stats and the admin API never show the key itself.
+		if keyInfo, exists := auth.GetAPIKeyInfo(c); exists && keyInfo.ID != "" {
+			keyAllowed, limitInfo := rateLimiter.AllowAPIKey(keyInfo.ID, keyInfo.RateLimit)
+			if !keyAllowed {
+				handleRateLimitExceeded(c, limitInfo, "API_KEY")
+				return
 			}
+
+			// Add API key rate limit headers
+			addRateLimitHeaders(c, limitInfo, "API-Key")
 		}
 
 		// Add IP rate limit headers
@@ -74,30 +68,28 @@ func handleRateLimitExceeded(c *gin.Context, info *RateLimitInfo, limitType stri
 
 	c.Header("Retry-After", strconv.Itoa(retryAfter))
 
-	response := gin.H{
-		"error": "Rate limit exceeded",
-		"code":  "RATE_LIMIT_EXCEEDED",
-		"details": gin.H{
-			"limit_type":  limitType,
-			"retry_after": retryAfter,
-			"blocked":     info.Blocked,
-		},
+	details := gin.H{
+		"limit_type":  limitType,
+		"retry_after": retryAfter,
+		"blocked":     info.Blocked,
 	}
+	response := problem.New(c, http.StatusTooManyRequests, problem.CodeRateLimitExceeded, "Rate limit exceeded", "Rate limit exceeded. Please slow down.")
+	response.Details = details
 
 	if info.Blocked {
-		response["message"] = "Too many violations. Temporarily blocked."
-		response["details"].(gin.H)["blocked_until"] = info.BlockedUntil
-		c.JSON(http.StatusTooManyRequests, response)
-	} else {
-		response["message"] = "Rate limit exceeded. Please slow down."
-		c.JSON(http.StatusTooManyRequests, response)
+		response.Detail = "Too many violations. Temporarily blocked."
+		details["blocked_until"] = info.BlockedUntil
 	}
 
+	problem.Write(c, response)
 	c.Abort()
 }
 
 // addRateLimitHeaders adds rate limiting headers to the response
 func addRateLimitHeaders(c *gin.Context, info *RateLimitInfo, prefix string) {
+	if info.Limit > 0 {
+		c.Header("X-RateLimit-"+prefix+"-Limit", strconv.Itoa(info.Limit))
+	}
 	c.Header("X-RateLimit-"+prefix+"-Remaining", strconv.Itoa(info.Remaining))
 	c.Header("X-RateLimit-"+prefix+"-Reset", strconv.FormatInt(info.ResetTime.Unix(), 10))
 
@@ -169,10 +161,7 @@ func handleUnblock(c *gin.Context, rateLimiter *RateLimiter) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+		problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request", err.Error())
 		return
 	}
 
@@ -183,9 +172,6 @@ func handleUnblock(c *gin.Context, rateLimiter *RateLimiter) {
 			"key":     request.Key,
 		})
 	} else {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Key not found in blocked list",
-			"key":   request.Key,
-		})
+		problem.Respond(c, http.StatusNotFound, problem.CodeBlockedKeyNotFound, "Key not found in blocked list", request.Key)
 	}
 }
@@ -5,8 +5,6 @@ import (
 	"net"
 	"sync"
 	"time"
-
-	"golang.org/x/time/rate"
 )
 
 // LimitType represents the type of rate limit
@@ -21,8 +19,9 @@ const (
 // RateLimitConfig represents rate limiting configuration
 type RateLimitConfig struct {
 	Enabled           bool          `yaml:"enabled" json:"enabled"`
+	Algorithm         Algorithm     `yaml:"algorithm" json:"algorithm"` // Defaults to AlgorithmTokenBucket
 	RequestsPerMinute int           `yaml:"requests_per_minute" json:"requests_per_minute"`
-	BurstSize         int           `yaml:"burst_size" json:"burst_size"`
+	BurstSize         int           `yaml:"burst_size" json:"burst_size"` // Only used by AlgorithmTokenBucket
 	CleanupInterval   time.Duration `yaml:"cleanup_interval" json:"cleanup_interval"`
 	BlockDuration     time.Duration `yaml:"block_duration" json:"block_duration"`
 	MaxViolations     int           `yaml:"max_violations" json:"max_violations"`
@@ -32,6 +31,7 @@ type RateLimitConfig struct {
 func DefaultRateLimitConfig() *RateLimitConfig {
 	return &RateLimitConfig{
 		Enabled:           true,
+		Algorithm:         AlgorithmTokenBucket,
 		RequestsPerMinute: 1000,
 		BurstSize:         100,
 		CleanupInterval:   5 * time.Minute,
@@ -43,9 +43,10 @@ func DefaultRateLimitConfig() *RateLimitConfig {
 // RateLimiter implements sophisticated rate limiting with abuse prevention
 type RateLimiter struct {
 	config     *RateLimitConfig
-	limiters   map[string]*rate.Limiter
+	limiters   map[string]limiter
 	violations map[string]*ViolationTracker
 	blocked    map[string]time.Time
+	apiKeys    map[string]*APIKeyUsage // By API key
 	mutex      sync.RWMutex
 	stopChan   chan struct{}
 }
@@ -57,6 +58,14 @@ type ViolationTracker struct {
 	LastSeen  time.Time `json:"last_seen"`
 }
 
+// APIKeyUsage counts the requests of one API key
+type APIKeyUsage struct {
+	RateLimit   int       `json:"rate_limit"` // Requests per minute currently applied
+	Allowed     int64     `json:"allowed"`
+	Rejected    int64     `json:"rejected"`
+	LastRequest time.Time `json:"last_request"`
+}
+
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(config *RateLimitConfig) *RateLimiter {
 	if config == nil {
@@ -65,9 +74,10 @@ func NewRateLimiter(config *RateLimitConfig) *RateLimiter {
 	
 	rl := &RateLimiter{
 		config:     config,
-		limiters:   make(map[string]*rate.Limiter),
+		limiters:   make(map[string]limiter),
 		violations: make(map[string]*ViolationTracker),
 		blocked:    make(map[string]time.Time),
+		apiKeys:    make(map[string]*APIKeyUsage),
 		stopChan:   make(chan struct{}),
 	}
 	
@@ -106,15 +116,17 @@ func (rl *RateLimiter) Allow(key string, customLimit ...int) (bool, *RateLimitIn
 	}
 	
 	// Get or create limiter for this key
-	limiter := rl.getLimiter(key, customLimit...)
+	keyLimiter := rl.getLimiter(key, customLimit...)
 	
 	// Check if request is allowed
-	allowed := limiter.Allow()
+	now := time.Now()
+	allowed := keyLimiter.allow(now)
 	
 	info := &RateLimitInfo{
 		Allowed:   allowed,
-		Remaining: int(limiter.Tokens()),
-		ResetTime: time.Now().Add(time.Minute),
+		Limit:     keyLimiter.requestsPerMinute(),
+		Remaining: keyLimiter.remaining(now),
+		ResetTime: keyLimiter.resetTime(now),
 	}
 	
 	if !allowed {
@@ -143,26 +155,48 @@ func (rl *RateLimiter) AllowIP(ip string) (bool, *RateLimitInfo) {
 	return rl.Allow(fmt.Sprintf("ip:%s", ip))
 }
 
-// AllowAPIKey checks if a request is allowed for the given API key
+// AllowAPIKey checks if a request is allowed for the given API key, with the key's own limit
+// in requests per minute or the configured one if customLimit is 0. A changed limit applies
+// from the key's next request.
 func (rl *RateLimiter) AllowAPIKey(apiKey string, customLimit int) (bool, *RateLimitInfo) {
-	return rl.Allow(fmt.Sprintf("api_key:%s", apiKey), customLimit)
+	allowed, info := rl.Allow(fmt.Sprintf("api_key:%s", apiKey), customLimit)
+	
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+	
+	usage, exists := rl.apiKeys[apiKey]
+	if !exists {
+		usage = &APIKeyUsage{}
+		rl.apiKeys[apiKey] = usage
+	}
+	usage.RateLimit = rl.config.RequestsPerMinute
+	if customLimit > 0 {
+		usage.RateLimit = customLimit
+	}
+	if allowed {
+		usage.Allowed++
+	} else {
+		usage.Rejected++
+	}
+	usage.LastRequest = time.Now()
+	
+	return allowed, info
 }
 
 // getLimiter gets or creates a rate limiter for the given key
-func (rl *RateLimiter) getLimiter(key string, customLimit ...int) *rate.Limiter {
-	limiter, exists := rl.limiters[key]
-	if !exists {
-		requestsPerMinute := rl.config.RequestsPerMinute
-		if len(customLimit) > 0 && customLimit[0] > 0 {
-			requestsPerMinute = customLimit[0]
-		}
-		
-		// Convert requests per minute to requests per second
-		rps := rate.Limit(float64(requestsPerMinute) / 60.0)
-		limiter = rate.NewLimiter(rps, rl.config.BurstSize)
-		rl.limiters[key] = limiter
+func (rl *RateLimiter) getLimiter(key string, customLimit ...int) limiter {
+	requestsPerMinute := rl.config.RequestsPerMinute
+	if len(customLimit) > 0 && customLimit[0] > 0 {
+		requestsPerMinute = customLimit[0]
+	}
+	
+	// A key whose limit was changed since its last request starts over with the new limit
+	keyLimiter, exists := rl.limiters[key]
+	if !exists || keyLimiter.requestsPerMinute() != requestsPerMinute {
+		keyLimiter = newLimiter(rl.config.Algorithm, requestsPerMinute, rl.config.BurstSize)
+		rl.limiters[key] = keyLimiter
 	}
-	return limiter
+	return keyLimiter
 }
 
 // trackViolation tracks a rate limit violation
@@ -234,6 +268,13 @@ func (rl *RateLimiter) cleanup() {
 		}
 	}
 	
+	// Clean up the usage of API keys that were not used for a day
+	for key, usage := range rl.apiKeys {
+		if usage.LastRequest.Before(now.Add(-24 * time.Hour)) {
+			delete(rl.apiKeys, key)
+		}
+	}
+	
 	// Clean up unused limiters (keep them for a while in case they're needed again)
 	// This is a simple cleanup - in production, you might want more sophisticated logic
 	if len(rl.limiters) > 10000 { // Arbitrary threshold
@@ -259,7 +300,24 @@ func (rl *RateLimiter) GetStats() *RateLimitStats {
 		ActiveViolators: len(rl.violations),
 		BlockedKeys:     len(rl.blocked),
 		Config:          *rl.config,
+		APIKeys:         rl.apiKeyUsage(),
+	}
+}
+
+// GetAPIKeyUsage returns the requests of each API key
+func (rl *RateLimiter) GetAPIKeyUsage() map[string]APIKeyUsage {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+	return rl.apiKeyUsage()
+}
+
+// apiKeyUsage copies the usage of the API keys, the caller holds the mutex
+func (rl *RateLimiter) apiKeyUsage() map[string]APIKeyUsage {
+	usage := make(map[string]APIKeyUsage, len(rl.apiKeys))
+	for key, keyUsage := range rl.apiKeys {
+		usage[key] = *keyUsage
 	}
+	return usage
 }
 
 // GetViolations returns current violations
@@ -311,6 +369,7 @@ func (rl *RateLimiter) Stop() {
 // RateLimitInfo contains information about a rate limit check
 type RateLimitInfo struct {
 	Allowed      bool      `json:"allowed"`
+	Limit        int       `json:"limit"` // Requests per minute
 	Remaining    int       `json:"remaining"`
 	ResetTime    time.Time `json:"reset_time"`
 	Blocked      bool      `json:"blocked"`
@@ -319,8 +378,9 @@ type RateLimitInfo struct {
 
 // RateLimitStats contains rate limiting statistics
 type RateLimitStats struct {
-	ActiveLimiters  int               `json:"active_limiters"`
-	ActiveViolators int               `json:"active_violators"`
-	BlockedKeys     int               `json:"blocked_keys"`
-	Config          RateLimitConfig   `json:"config"`
+	ActiveLimiters  int                    `json:"active_limiters"`
+	ActiveViolators int                    `json:"active_violators"`
+	BlockedKeys     int                    `json:"blocked_keys"`
+	Config          RateLimitConfig        `json:"config"`
+	APIKeys         map[string]APIKeyUsage `json:"api_keys"` // By API key ID
 }
\ No newline at end of file
@@ -76,6 +76,43 @@ func TestRateLimiter_AllowAPIKey(t *testing.T) {
 	}
 }
 
+func TestRateLimiter_AllowAPIKeyChangedLimit(t *testing.T) {
+	config := &RateLimitConfig{
+		Enabled:           true,
+		RequestsPerMinute: 60,
+		BurstSize:         1,
+		CleanupInterval:   time.Minute,
+		BlockDuration:     time.Minute,
+		MaxViolations:     100,
+	}
+	rl := NewRateLimiter(config)
+	defer rl.Stop()
+
+	// The key's own limit of one request per minute is exhausted by the burst
+	if allowed, _ := rl.AllowAPIKey("key-1", 1); !allowed {
+		t.Fatal("First request should be allowed")
+	}
+	if allowed, _ := rl.AllowAPIKey("key-1", 1); allowed {
+		t.Fatal("Second request should exceed the key's limit")
+	}
+
+	// Raising the limit applies to the next request
+	if allowed, _ := rl.AllowAPIKey("key-1", 6000); !allowed {
+		t.Error("Request should be allowed after the limit was raised")
+	}
+
+	usage := rl.GetStats().APIKeys["key-1"]
+	if usage.Allowed != 2 || usage.Rejected != 1 || usage.RateLimit != 6000 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+
+	// Keys without their own limit use the configured one
+	rl.AllowAPIKey("key-2", 0)
+	if limit := rl.GetAPIKeyUsage()["key-2"].RateLimit; limit != 60 {
+		t.Errorf("Expected the configured limit 60, got %d", limit)
+	}
+}
+
 func TestRateLimiter_Blocking(t *testing.T) {
 	config := &RateLimitConfig{
 		Enabled:           true,
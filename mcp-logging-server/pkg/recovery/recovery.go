@@ -1,6 +1,7 @@
 package recovery
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -186,14 +187,37 @@ type RecoveryStats struct {
 
 // loadLogsFromFile loads logs from a recovery file
 func (rm *RecoveryManager) loadLogsFromFile(filepath string) ([]models.LogEntry, error) {
-	data, err := os.ReadFile(filepath)
+	return LoadLogsFile(filepath)
+}
+
+// LoadLogsFile loads logs from a recovery or dead-letter file. Both JSON arrays
+// of log entries and newline delimited JSON (one entry per line) are supported.
+func LoadLogsFile(path string) ([]models.LogEntry, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
 	var logs []models.LogEntry
-	if err := json.Unmarshal(data, &logs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal logs: %w", err)
+	if trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &logs); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal logs: %w", err)
+		}
+		return logs, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	for decoder.More() {
+		var log models.LogEntry
+		if err := decoder.Decode(&log); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal log %d: %w", len(logs)+1, err)
+		}
+		logs = append(logs, log)
 	}
 
 	return logs, nil
@@ -352,3 +352,31 @@ func TestRecoveryManager_CorruptedRecoveryFile(t *testing.T) {
 		t.Errorf("Expected 1 file (corrupted) to remain, got %d", len(files))
 	}
 }
+
+func TestLoadLogsFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	arrayFile := filepath.Join(tempDir, "pending_logs_1.json")
+	arrayData := `[{"id": "550e8400-e29b-41d4-a716-446655440001", "message": "first"},
+		{"id": "550e8400-e29b-41d4-a716-446655440002", "message": "second"}]`
+	if err := os.WriteFile(arrayFile, []byte(arrayData), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	ndjsonFile := filepath.Join(tempDir, "dead_letter.jsonl")
+	ndjsonData := "{\"id\": \"550e8400-e29b-41d4-a716-446655440003\", \"message\": \"third\"}\n" +
+		"{\"id\": \"550e8400-e29b-41d4-a716-446655440004\", \"message\": \"fourth\"}\n"
+	if err := os.WriteFile(ndjsonFile, []byte(ndjsonData), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	for _, path := range []string{arrayFile, ndjsonFile} {
+		logs, err := LoadLogsFile(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", path, err)
+		}
+		if len(logs) != 2 {
+			t.Errorf("Expected 2 logs from %s, got %d", path, len(logs))
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package relay forwards logs received by an edge instance to a central logging server, so
+// that devices on a local network can log to a nearby relay while the relay deals with the
+// slow or unreliable uplink
+package relay
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+const (
+	// DefaultMaxBatchSize is the largest batch the central batch endpoint accepts
+	DefaultMaxBatchSize = 1000
+
+	// DefaultTimeout is the per-request timeout when none is configured
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultRetryBackoff is the initial delay between attempts when none is configured
+	DefaultRetryBackoff = time.Second
+
+	// maxRetryBackoff caps the exponential backoff between attempts
+	maxRetryBackoff = 30 * time.Second
+)
+
+// ErrNotSupported is returned for reads, which a relay cannot serve because it keeps no logs
+var ErrNotSupported = errors.New("not supported in relay mode, query the central server")
+
+// Config configures a Forwarder
+type Config struct {
+	URL           string        // Base URL of the central ingestion API, e.g. https://logs.example.com:8080
+	APIKey        string        // Sent as X-API-Key with every request
+	SigningSecret string        // Signs every request when the central server requires signatures for the key
+	CAFile        string        // PEM CA bundle for verifying the central server, system roots when empty
+	Timeout       time.Duration // Per-request timeout, defaults to DefaultTimeout
+	MaxRetries    int           // Attempts after the first before Store gives up
+	RetryBackoff  time.Duration // Initial delay between attempts, doubled after each one
+	MaxBatchSize  int           // Entries per request, defaults to DefaultMaxBatchSize
+}
+
+// Forwarder is a storage.LogStorage that sends stored logs to a central server in batches,
+// retrying transient failures. Placed behind the ingestion buffer it turns an instance into
+// a relay: a failed Store leaves the batch in the buffer for the next flush.
+type Forwarder struct {
+	config Config
+	url    string
+	client *http.Client
+
+	forwarded atomic.Int64 // Entries accepted by the central server
+	rejected  atomic.Int64 // Entries dropped because the central server refused them
+	retries   atomic.Int64 // Requests repeated after a transient failure
+
+	mutex     sync.Mutex
+	lastError string
+}
+
+// NewForwarder creates a forwarder to the central server described by config
+func NewForwarder(config Config) (*Forwarder, error) {
+	if config.URL == "" {
+		return nil, errors.New("relay URL is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+	if config.MaxBatchSize <= 0 || config.MaxBatchSize > DefaultMaxBatchSize {
+		config.MaxBatchSize = DefaultMaxBatchSize
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &Forwarder{
+		config: config,
+		url:    strings.TrimRight(config.URL, "/"),
+		client: &http.Client{Timeout: config.Timeout, Transport: transport},
+	}, nil
+}
+
+// Store forwards logs to the central server in batches of at most MaxBatchSize entries.
+// Batches the central server rejects as invalid are dropped and counted, so that they
+// cannot block the buffer; any other failure is returned once the retries are exhausted.
+// Batches forwarded before a failure are sent again when the caller retries, so callers
+// should pass at most MaxBatchSize entries at a time.
+func (f *Forwarder) Store(ctx context.Context, logs []models.LogEntry) error {
+	for start := 0; start < len(logs); start += f.config.MaxBatchSize {
+		end := start + f.config.MaxBatchSize
+		if end > len(logs) {
+			end = len(logs)
+		}
+		if err := f.forward(ctx, logs[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxBatchSize returns the number of entries forwarded per request
+func (f *Forwarder) MaxBatchSize() int {
+	return f.config.MaxBatchSize
+}
+
+// forward sends one batch, retrying transient failures with exponential backoff
+func (f *Forwarder) forward(ctx context.Context, batch []models.LogEntry) error {
+	body, err := codec.MarshalEntries(batch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	backoff := f.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := f.send(ctx, body)
+		if err == nil {
+			f.forwarded.Add(int64(len(batch)))
+			return nil
+		}
+		f.setLastError(err)
+
+		if !retryable {
+			f.rejected.Add(int64(len(batch)))
+			log.Printf("Relay: dropping %d entries rejected by the central server: %v", len(batch), err)
+			return nil
+		}
+		if attempt >= f.config.MaxRetries {
+			return fmt.Errorf("failed to forward %d entries after %d attempts: %w", len(batch), attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		f.retries.Add(1)
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// send posts an encoded batch and reports whether a failure is worth retrying
+func (f *Forwarder) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url+"/v1/logs/batch", bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if f.config.APIKey != "" {
+		req.Header.Set("X-API-Key", f.config.APIKey)
+	}
+	if f.config.SigningSecret != "" {
+		// Signed per attempt, the central server accepts each signature once
+		if err := auth.SignRequest(req, f.config.SigningSecret); err != nil {
+			return false, err
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	err = fmt.Errorf("central server responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	return isRetryable(resp.StatusCode), err
+}
+
+// isRetryable reports whether a response status may succeed when the request is repeated.
+// Authentication failures are retried as well, since dropping logs because of a revoked or
+// misconfigured key would lose them for good.
+func isRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return false
+	}
+	return true
+}
+
+// setLastError records the most recent forwarding failure for the health check
+func (f *Forwarder) setLastError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastError = err.Error()
+}
+
+// Query is not supported by a relay
+func (f *Forwarder) Query(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
+	return nil, ErrNotSupported
+}
+
+// QueryStream is not supported by a relay
+func (f *Forwarder) QueryStream(ctx context.Context, filter models.LogFilter) (storage.LogIterator, error) {
+	return nil, ErrNotSupported
+}
+
+// GetByIDs is not supported by a relay
+func (f *Forwarder) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
+	return nil, ErrNotSupported
+}
+
+// GetServices is not supported by a relay
+func (f *Forwarder) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	return nil, ErrNotSupported
+}
+
+// HealthCheck reports whether the central server is reachable along with the forwarding
+// counters. An unreachable central server degrades the relay, which keeps buffering.
+func (f *Forwarder) HealthCheck(ctx context.Context) models.HealthStatus {
+	status := models.HealthStatus{
+		Status:    "healthy",
+		Timestamp: time.Now(),
+		Details: map[string]string{
+			"relay_url": f.url,
+			"forwarded": fmt.Sprintf("%d", f.forwarded.Load()),
+			"rejected":  fmt.Sprintf("%d", f.rejected.Load()),
+			"retries":   fmt.Sprintf("%d", f.retries.Load()),
+		},
+	}
+
+	f.mutex.Lock()
+	if f.lastError != "" {
+		status.Details["last_error"] = f.lastError
+	}
+	f.mutex.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url+"/health", nil)
+	if err != nil {
+		status.Status = "unhealthy"
+		status.Details["central"] = fmt.Sprintf("invalid URL: %v", err)
+		return status
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		status.Status = "degraded"
+		status.Details["central"] = fmt.Sprintf("unreachable: %v", err)
+		return status
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		status.Status = "degraded"
+		status.Details["central"] = fmt.Sprintf("responded with %d", resp.StatusCode)
+		return status
+	}
+
+	status.Details["central"] = "reachable"
+	return status
+}
+
+// Close releases idle connections to the central server
+func (f *Forwarder) Close() error {
+	f.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,263 @@
+package relay
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// centralServer records the batches posted to it, answering with the given statuses in turn
+// and with 201 once they are used up
+type centralServer struct {
+	mutex    sync.Mutex
+	statuses []int
+	batches  [][]models.LogEntry
+	apiKeys  []string
+}
+
+func (c *centralServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/health" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var batch []models.LogEntry
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.apiKeys = append(c.apiKeys, r.Header.Get("X-API-Key"))
+	if len(c.statuses) > 0 {
+		status := c.statuses[0]
+		c.statuses = c.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	c.batches = append(c.batches, batch)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newRelayTestLogs(count int) []models.LogEntry {
+	logs := make([]models.LogEntry, count)
+	for i := range logs {
+		logs[i] = models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Relayed message",
+			ServiceName: "edge-service",
+			AgentID:     "edge-agent",
+			Platform:    models.PlatformGo,
+		}
+	}
+	return logs
+}
+
+func TestForwarder_StoreBatches(t *testing.T) {
+	central := &centralServer{}
+	server := httptest.NewServer(central)
+	defer server.Close()
+
+	forwarder, err := NewForwarder(Config{URL: server.URL + "/", APIKey: "edge-key", MaxBatchSize: 2})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	defer forwarder.Close()
+
+	if err := forwarder.Store(context.Background(), newRelayTestLogs(5)); err != nil {
+		t.Fatalf("Failed to forward logs: %v", err)
+	}
+
+	if len(central.batches) != 3 || len(central.batches[0]) != 2 || len(central.batches[2]) != 1 {
+		t.Errorf("Expected batches of 2, 2 and 1 entries, got %d batches", len(central.batches))
+	}
+	for _, apiKey := range central.apiKeys {
+		if apiKey != "edge-key" {
+			t.Errorf("Expected the API key to be sent, got %q", apiKey)
+		}
+	}
+
+	health := forwarder.HealthCheck(context.Background())
+	if health.Status != "healthy" || health.Details["forwarded"] != "5" {
+		t.Errorf("Unexpected health: %+v", health)
+	}
+}
+
+func TestForwarder_RetriesTransientFailures(t *testing.T) {
+	central := &centralServer{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(central)
+	defer server.Close()
+
+	forwarder, err := NewForwarder(Config{URL: server.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	if err := forwarder.Store(context.Background(), newRelayTestLogs(3)); err != nil {
+		t.Fatalf("Expected the batch to be forwarded after retrying, got %v", err)
+	}
+	if len(central.batches) != 1 {
+		t.Errorf("Expected 1 accepted batch, got %d", len(central.batches))
+	}
+	if health := forwarder.HealthCheck(context.Background()); health.Details["retries"] != "2" {
+		t.Errorf("Expected 2 retries, got %s", health.Details["retries"])
+	}
+}
+
+func TestForwarder_GivesUpAfterMaxRetries(t *testing.T) {
+	central := &centralServer{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusUnauthorized}}
+	server := httptest.NewServer(central)
+	defer server.Close()
+
+	forwarder, err := NewForwarder(Config{URL: server.URL, MaxRetries: 2, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	if err := forwarder.Store(context.Background(), newRelayTestLogs(1)); err == nil {
+		t.Fatal("Expected an error once the retries are exhausted")
+	}
+	if len(central.apiKeys) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(central.apiKeys))
+	}
+	if health := forwarder.HealthCheck(context.Background()); health.Details["last_error"] == "" {
+		t.Error("Expected the last error to be reported")
+	}
+
+	// A cancelled context stops waiting for the next attempt
+	central.statuses = []int{http.StatusServiceUnavailable}
+	forwarder.config.RetryBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := forwarder.Store(ctx, newRelayTestLogs(1)); err != context.DeadlineExceeded {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+}
+
+func TestForwarder_DropsRejectedBatches(t *testing.T) {
+	central := &centralServer{statuses: []int{http.StatusBadRequest}}
+	server := httptest.NewServer(central)
+	defer server.Close()
+
+	forwarder, err := NewForwarder(Config{URL: server.URL, MaxBatchSize: 1, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	// The rejected batch is not retried and does not stop the following ones
+	if err := forwarder.Store(context.Background(), newRelayTestLogs(2)); err != nil {
+		t.Fatalf("Expected rejected batches to be dropped, got %v", err)
+	}
+	if len(central.apiKeys) != 2 || len(central.batches) != 1 {
+		t.Errorf("Expected 2 requests and 1 accepted batch, got %d and %d", len(central.apiKeys), len(central.batches))
+	}
+	if health := forwarder.HealthCheck(context.Background()); health.Details["rejected"] != "1" {
+		t.Errorf("Expected 1 rejected entry, got %s", health.Details["rejected"])
+	}
+}
+
+func TestForwarder_TLS(t *testing.T) {
+	central := &centralServer{}
+	server := httptest.NewTLSServer(central)
+	defer server.Close()
+
+	// Without the CA the central certificate is not trusted
+	untrusted, err := NewForwarder(Config{URL: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	if health := untrusted.HealthCheck(context.Background()); health.Status != "degraded" {
+		t.Errorf("Expected an untrusted central server to degrade the relay, got %s", health.Status)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certificate, 0600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	forwarder, err := NewForwarder(Config{URL: server.URL, CAFile: caFile})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	if err := forwarder.Store(context.Background(), newRelayTestLogs(1)); err != nil {
+		t.Errorf("Failed to forward over TLS: %v", err)
+	}
+
+	if _, err := NewForwarder(Config{URL: server.URL, CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("Expected an error for a missing CA file")
+	}
+}
+
+func TestForwarder_ReadsNotSupported(t *testing.T) {
+	forwarder, err := NewForwarder(Config{URL: "http://localhost:1"})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	if _, err := forwarder.Query(context.Background(), models.LogFilter{}); err != ErrNotSupported {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	if _, err := forwarder.GetServices(context.Background(), models.ServiceFilter{}); err != ErrNotSupported {
+		t.Errorf("Expected ErrNotSupported, got %v", err)
+	}
+	if _, err := NewForwarder(Config{}); err == nil {
+		t.Error("Expected an error without a URL")
+	}
+}
+
+func TestForwarder_SignsRequests(t *testing.T) {
+	manager := auth.NewAPIKeyManager(&auth.APIKeyConfig{RequireAuth: true, APIKeys: make(map[string]auth.APIKeyInfo)})
+	apiKey, err := manager.CreateAPIKey("edge-relay", []auth.Permission{auth.PermissionIngestLogs}, 1000, nil)
+	if err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	secret, err := manager.EnableSigning(apiKey)
+	if err != nil {
+		t.Fatalf("Failed to enable signing: %v", err)
+	}
+
+	central := &centralServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyInfo, valid := manager.ValidateAPIKey(r.Header.Get("X-API-Key"))
+		if !valid || manager.VerifyRequest(keyInfo, r) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		central.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	unsigned, err := NewForwarder(Config{URL: server.URL, APIKey: apiKey})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	if err := unsigned.Store(context.Background(), newRelayTestLogs(1)); err == nil {
+		t.Error("Expected unsigned requests to be refused")
+	}
+
+	forwarder, err := NewForwarder(Config{URL: server.URL, APIKey: apiKey, SigningSecret: secret, MaxBatchSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+	if err := forwarder.Store(context.Background(), newRelayTestLogs(2)); err != nil {
+		t.Fatalf("Failed to forward signed batches: %v", err)
+	}
+	if len(central.batches) != 2 {
+		t.Errorf("Expected 2 accepted batches, got %d", len(central.batches))
+	}
+}
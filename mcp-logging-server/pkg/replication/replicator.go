@@ -0,0 +1,422 @@
+// Package replication copies the batches a server stores to peer servers in other regions, so
+// that a regional outage does not make the logs ingested there unreachable
+package replication
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+const (
+	// BatchPath is the path peers accept replicated batches on
+	BatchPath = "/v1/replication/batch"
+
+	// OriginHeader carries the ID of the server a replicated batch was stored on first
+	OriginHeader = "X-Replication-Origin"
+
+	// DefaultQueueSize is the number of batches held for each peer when none is configured
+	DefaultQueueSize = 1000
+
+	// DefaultTimeout is the per-request timeout when none is configured
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultRetryBackoff is the initial delay between attempts when none is configured
+	DefaultRetryBackoff = time.Second
+
+	// maxRetryBackoff caps the exponential backoff between attempts
+	maxRetryBackoff = 30 * time.Second
+
+	// drainTimeout bounds the delivery of queued batches once the replicator is stopped
+	drainTimeout = 5 * time.Second
+)
+
+// PeerConfig configures a peer server that batches are replicated to
+type PeerConfig struct {
+	Name          string // Identifies the peer in the stats, the host of URL when empty
+	URL           string // Base URL of the peer's ingestion API, e.g. https://logs.eu-west-1.example.com:8080
+	APIKey        string // Sent as X-API-Key, the key needs the replicate_logs permission
+	SigningSecret string // Signs every request when the peer requires signatures for the key
+	CAFile        string // PEM CA bundle for verifying the peer, system roots when empty
+}
+
+// Config configures a Replicator
+type Config struct {
+	ServerID     string        // Sent to peers as the origin of batches, which a server rejects as its own
+	Peers        []PeerConfig  // Servers every stored batch is replicated to
+	Timeout      time.Duration // Per-request timeout, defaults to DefaultTimeout
+	QueueSize    int           // Batches held for each peer, defaults to DefaultQueueSize
+	RetryBackoff time.Duration // Initial delay between attempts, doubled after each one
+}
+
+// batch is a stored batch waiting to be replicated
+type batch struct {
+	body    []byte // JSON array of the entries
+	entries int
+	stored  time.Time
+}
+
+// peer replicates batches to one peer server through its own queue, so that a slow or
+// unreachable peer does not hold back the others
+type peer struct {
+	name   string
+	url    string
+	config PeerConfig
+	client *http.Client
+	queue  chan batch
+
+	replicated atomic.Int64 // Entries accepted by the peer
+	dropped    atomic.Int64 // Entries lost to a full queue, a rejection or shutdown
+	retries    atomic.Int64 // Requests repeated after a transient failure
+
+	mutex          sync.Mutex
+	sending        time.Time // When the batch being sent was stored, zero while idle
+	lastReplicated time.Time // When the last batch the peer accepted was stored
+	lastError      string
+}
+
+// Replicator asynchronously sends every batch the server stores to its peers. Batches wait in
+// a queue per peer and transient failures are retried until the peer accepts them, so a peer
+// catches up once it is reachable again. Batches are dropped, and counted, when a peer's queue
+// is full or the peer rejects them as invalid, so that replication never blocks ingestion.
+//
+// Peers store replicated batches without replicating them again, so servers replicating to
+// each other never loop. Every server that should hold all logs must therefore be a peer of
+// every other server.
+type Replicator struct {
+	config Config
+	peers  []*peer
+}
+
+// NewReplicator creates a replicator to the peers described by config
+func NewReplicator(config Config) (*Replicator, error) {
+	if config.ServerID == "" {
+		return nil, errors.New("replication server ID is required")
+	}
+	if len(config.Peers) == 0 {
+		return nil, errors.New("at least one replication peer is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+
+	r := &Replicator{config: config}
+	names := make(map[string]bool, len(config.Peers))
+	for _, peerConfig := range config.Peers {
+		p, err := newPeer(peerConfig, config)
+		if err != nil {
+			return nil, err
+		}
+		if names[p.name] {
+			return nil, fmt.Errorf("duplicate replication peer %q", p.name)
+		}
+		names[p.name] = true
+		r.peers = append(r.peers, p)
+	}
+	return r, nil
+}
+
+// newPeer creates the client of a peer
+func newPeer(config PeerConfig, replication Config) (*peer, error) {
+	parsed, err := url.Parse(config.URL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid replication peer URL %q", config.URL)
+	}
+	name := config.Name
+	if name == "" {
+		name = parsed.Host
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file of peer %s: %w", name, err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", config.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &peer{
+		name:   name,
+		url:    strings.TrimRight(config.URL, "/"),
+		config: config,
+		client: &http.Client{Timeout: replication.Timeout, Transport: transport},
+		queue:  make(chan batch, replication.QueueSize),
+	}, nil
+}
+
+// ServerID returns the ID this server sends as the origin of replicated batches
+func (r *Replicator) ServerID() string {
+	return r.config.ServerID
+}
+
+// Committed queues a batch that was stored for replication to every peer, making the
+// replicator a buffer.CommitListener. A peer whose queue is full drops the batch.
+func (r *Replicator) Committed(entries []models.LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	body, err := codec.MarshalEntries(entries)
+	if err != nil {
+		log.Printf("Replication: failed to encode batch of %d entries: %v", len(entries), err)
+		return
+	}
+
+	pending := batch{body: body, entries: len(entries), stored: time.Now()}
+	for _, p := range r.peers {
+		select {
+		case p.queue <- pending:
+		default:
+			p.dropped.Add(int64(pending.entries))
+		}
+	}
+}
+
+// Run replicates queued batches until ctx is done, then makes a last attempt at the batches
+// still queued
+func (r *Replicator) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, p := range r.peers {
+		wg.Add(1)
+		go func(p *peer) {
+			defer wg.Done()
+			p.run(ctx, r.config)
+		}(p)
+	}
+	wg.Wait()
+	return nil
+}
+
+// run sends the batches queued for the peer in the order they were stored
+func (p *peer) run(ctx context.Context, config Config) {
+	defer p.client.CloseIdleConnections()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.drain(config.ServerID)
+			return
+		case pending := <-p.queue:
+			if !p.deliver(ctx, pending, config) {
+				p.drain(config.ServerID, pending)
+				return
+			}
+		}
+	}
+}
+
+// deliver sends a batch, retrying transient failures with exponential backoff for as long as
+// it takes. It returns false if ctx was done before the batch was accepted or dropped.
+func (p *peer) deliver(ctx context.Context, pending batch, config Config) bool {
+	p.mutex.Lock()
+	p.sending = pending.stored
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		p.sending = time.Time{}
+		p.mutex.Unlock()
+	}()
+
+	backoff := config.RetryBackoff
+	for {
+		retryable, err := p.send(ctx, pending, config.ServerID)
+		if err == nil {
+			p.accepted(pending)
+			return true
+		}
+		p.setLastError(err)
+
+		if !retryable {
+			p.dropped.Add(int64(pending.entries))
+			log.Printf("Replication: dropping %d entries rejected by peer %s: %v", pending.entries, p.name, err)
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		p.retries.Add(1)
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// drain sends the given and the queued batches without retrying, dropping what is left once
+// drainTimeout has passed
+func (p *peer) drain(serverID string, batches ...batch) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	for {
+		var pending batch
+		if len(batches) > 0 {
+			pending, batches = batches[0], batches[1:]
+		} else {
+			select {
+			case pending = <-p.queue:
+			default:
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			p.dropped.Add(int64(pending.entries))
+			continue
+		}
+		if _, err := p.send(ctx, pending, serverID); err != nil {
+			p.setLastError(err)
+			p.dropped.Add(int64(pending.entries))
+			continue
+		}
+		p.accepted(pending)
+	}
+}
+
+// send posts a batch to the peer and reports whether a failure is worth retrying
+func (p *peer) send(ctx context.Context, pending batch, serverID string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+BatchPath, bytes.NewReader(pending.body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(OriginHeader, serverID)
+	if p.config.APIKey != "" {
+		req.Header.Set("X-API-Key", p.config.APIKey)
+	}
+	if p.config.SigningSecret != "" {
+		// Signed per attempt, the peer accepts each signature once
+		if err := auth.SignRequest(req, p.config.SigningSecret); err != nil {
+			return false, err
+		}
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	err = fmt.Errorf("peer responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	return isRetryable(resp.StatusCode), err
+}
+
+// isRetryable reports whether a response status may succeed when the request is repeated. A
+// conflict means the peer is this server, which would never accept the batch. Authentication
+// failures are retried, so that a misconfigured key delays replication instead of losing logs.
+func isRetryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return false
+	}
+	return true
+}
+
+// accepted records a batch the peer stored
+func (p *peer) accepted(pending batch) {
+	p.replicated.Add(int64(pending.entries))
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if pending.stored.After(p.lastReplicated) {
+		p.lastReplicated = pending.stored
+	}
+}
+
+// setLastError records the most recent replication failure for the stats
+func (p *peer) setLastError(err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.lastError = err.Error()
+}
+
+// PeerStats reports the replication counters and lag of a peer
+type PeerStats struct {
+	Name           string     `json:"name"`
+	URL            string     `json:"url"`
+	Queued         int        `json:"queued"`          // Batches waiting, including the one being sent
+	LagSeconds     float64    `json:"lag_seconds"`     // Age of the oldest batch the peer has not accepted yet
+	Replicated     int64      `json:"replicated"`      // Entries the peer accepted
+	Dropped        int64      `json:"dropped"`         // Entries lost to a full queue, a rejection or shutdown
+	Retries        int64      `json:"retries"`         // Requests repeated after a transient failure
+	LastReplicated *time.Time `json:"last_replicated"` // When the newest batch the peer accepted was stored
+	LastError      string     `json:"last_error,omitempty"`
+}
+
+// Stats reports the replication of each peer
+type Stats struct {
+	ServerID string      `json:"server_id"`
+	Peers    []PeerStats `json:"peers"`
+}
+
+// Stats returns the replication counters and lag of each peer
+func (r *Replicator) Stats() Stats {
+	now := time.Now()
+	stats := Stats{ServerID: r.config.ServerID, Peers: make([]PeerStats, 0, len(r.peers))}
+	for _, p := range r.peers {
+		stats.Peers = append(stats.Peers, p.stats(now))
+	}
+	return stats
+}
+
+// stats returns the counters of the peer, with the lag as of now
+func (p *peer) stats(now time.Time) PeerStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	stats := PeerStats{
+		Name:       p.name,
+		URL:        p.url,
+		Queued:     len(p.queue),
+		Replicated: p.replicated.Load(),
+		Dropped:    p.dropped.Load(),
+		Retries:    p.retries.Load(),
+		LastError:  p.lastError,
+	}
+	if !p.sending.IsZero() {
+		stats.Queued++
+		stats.LagSeconds = now.Sub(p.sending).Seconds()
+	}
+	if !p.lastReplicated.IsZero() {
+		lastReplicated := p.lastReplicated
+		stats.LastReplicated = &lastReplicated
+	}
+	return stats
+}
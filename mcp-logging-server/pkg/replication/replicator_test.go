@@ -0,0 +1,243 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// peerServer records the batches replicated to it, answering with the given statuses in turn
+// and with 201 once they are used up
+type peerServer struct {
+	mutex    sync.Mutex
+	statuses []int
+	batches  [][]models.LogEntry
+	origins  []string
+	apiKeys  []string
+}
+
+func (p *peerServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != BatchPath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var batch []models.LogEntry
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.origins = append(p.origins, r.Header.Get(OriginHeader))
+	p.apiKeys = append(p.apiKeys, r.Header.Get("X-API-Key"))
+	if len(p.statuses) > 0 {
+		status := p.statuses[0]
+		p.statuses = p.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+	p.batches = append(p.batches, batch)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (p *peerServer) replicated() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	count := 0
+	for _, batch := range p.batches {
+		count += len(batch)
+	}
+	return count
+}
+
+func newReplicationTestLogs(count int) []models.LogEntry {
+	logs := make([]models.LogEntry, count)
+	for i := range logs {
+		logs[i] = models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Replicated message",
+			ServiceName: "checkout",
+			AgentID:     "checkout-agent",
+			Platform:    models.PlatformGo,
+		}
+	}
+	return logs
+}
+
+// waitFor polls condition until it holds or a second has passed
+func waitFor(t *testing.T, condition func() bool, description string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReplicator_ReplicatesToEveryPeer(t *testing.T) {
+	east, west := &peerServer{}, &peerServer{}
+	eastServer, westServer := httptest.NewServer(east), httptest.NewServer(west)
+	defer eastServer.Close()
+	defer westServer.Close()
+
+	replicator, err := NewReplicator(Config{
+		ServerID: "eu-central",
+		Peers: []PeerConfig{
+			{Name: "us-east", URL: eastServer.URL + "/", APIKey: "east-key"},
+			{URL: westServer.URL, APIKey: "west-key"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replicator: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		replicator.Run(ctx)
+		close(done)
+	}()
+
+	replicator.Committed(newReplicationTestLogs(3))
+	replicator.Committed(newReplicationTestLogs(2))
+	waitFor(t, func() bool { return east.replicated() == 5 && west.replicated() == 5 }, "both peers to receive all entries")
+
+	cancel()
+	<-done
+
+	if east.origins[0] != "eu-central" || east.apiKeys[0] != "east-key" || west.apiKeys[0] != "west-key" {
+		t.Errorf("Expected the origin and each peer's API key to be sent, got origins %v and keys %v, %v", east.origins, east.apiKeys, west.apiKeys)
+	}
+
+	stats := replicator.Stats()
+	if stats.ServerID != "eu-central" || len(stats.Peers) != 2 {
+		t.Fatalf("Expected stats of 2 peers, got %+v", stats)
+	}
+	if stats.Peers[0].Name != "us-east" || stats.Peers[1].Name == "" {
+		t.Errorf("Expected the configured name and the host as peer names, got %q and %q", stats.Peers[0].Name, stats.Peers[1].Name)
+	}
+	for _, peer := range stats.Peers {
+		if peer.Replicated != 5 || peer.Queued != 0 || peer.LagSeconds != 0 || peer.LastReplicated == nil {
+			t.Errorf("Expected 5 replicated entries and no lag, got %+v", peer)
+		}
+	}
+}
+
+func TestReplicator_RetriesUntilPeerRecovers(t *testing.T) {
+	peer := &peerServer{statuses: []int{http.StatusServiceUnavailable, http.StatusUnauthorized, http.StatusBadGateway}}
+	server := httptest.NewServer(peer)
+	defer server.Close()
+
+	replicator, err := NewReplicator(Config{
+		ServerID:     "eu-central",
+		Peers:        []PeerConfig{{URL: server.URL}},
+		RetryBackoff: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replicator: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replicator.Run(ctx)
+
+	replicator.Committed(newReplicationTestLogs(4))
+	replicator.Committed(newReplicationTestLogs(1))
+
+	// While the peer fails, the oldest batch ages and the newer one waits behind it
+	waitFor(t, func() bool {
+		stats := replicator.Stats().Peers[0]
+		return stats.Queued == 2 && stats.LagSeconds > 0 && stats.LastError != ""
+	}, "the peer to lag")
+
+	waitFor(t, func() bool { return peer.replicated() == 5 }, "the peer to catch up")
+	stats := replicator.Stats().Peers[0]
+	if stats.Retries != 3 || stats.Dropped != 0 {
+		t.Errorf("Expected 3 retries and nothing dropped, got %+v", stats)
+	}
+	if len(peer.batches) != 2 || len(peer.batches[0]) != 4 {
+		t.Errorf("Expected the batches in the order they were stored, got %v", peer.batches)
+	}
+}
+
+func TestReplicator_DropsRejectedBatches(t *testing.T) {
+	peer := &peerServer{statuses: []int{http.StatusConflict}}
+	server := httptest.NewServer(peer)
+	defer server.Close()
+
+	replicator, err := NewReplicator(Config{ServerID: "eu-central", Peers: []PeerConfig{{URL: server.URL}}})
+	if err != nil {
+		t.Fatalf("Failed to create replicator: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go replicator.Run(ctx)
+
+	replicator.Committed(newReplicationTestLogs(2))
+	replicator.Committed(newReplicationTestLogs(3))
+	// The replicator counts a batch once the peer answered it, after the peer stored it
+	waitFor(t, func() bool {
+		stats := replicator.Stats().Peers[0]
+		return stats.Replicated == 3 && stats.Queued == 0
+	}, "the second batch")
+
+	stats := replicator.Stats().Peers[0]
+	if stats.Dropped != 2 || stats.Retries != 0 || peer.replicated() != 3 {
+		t.Errorf("Expected the rejected batch to be dropped without retrying, got %+v", stats)
+	}
+}
+
+func TestReplicator_QueueFull(t *testing.T) {
+	replicator, err := NewReplicator(Config{
+		ServerID:  "eu-central",
+		Peers:     []PeerConfig{{URL: "http://127.0.0.1:1"}},
+		QueueSize: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replicator: %v", err)
+	}
+
+	// Without Run nothing is sent, so the second batch does not fit
+	replicator.Committed(newReplicationTestLogs(2))
+	replicator.Committed(newReplicationTestLogs(3))
+
+	stats := replicator.Stats().Peers[0]
+	if stats.Queued != 1 || stats.Dropped != 3 {
+		t.Errorf("Expected 1 queued batch and 3 dropped entries, got %+v", stats)
+	}
+}
+
+func TestNewReplicator_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"missing server ID", Config{Peers: []PeerConfig{{URL: "https://logs.example.com"}}}},
+		{"no peers", Config{ServerID: "eu-central"}},
+		{"invalid URL", Config{ServerID: "eu-central", Peers: []PeerConfig{{URL: "logs.example.com"}}}},
+		{"duplicate peer", Config{ServerID: "eu-central", Peers: []PeerConfig{{URL: "https://logs.example.com"}, {URL: "https://logs.example.com/"}}}},
+		{"missing CA file", Config{ServerID: "eu-central", Peers: []PeerConfig{{URL: "https://logs.example.com", CAFile: "/nonexistent/ca.pem"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReplicator(tt.config); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
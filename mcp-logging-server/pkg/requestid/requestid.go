@@ -0,0 +1,68 @@
+// Package requestid correlates client requests with server-side logs, error responses and audit
+// entries through the X-Request-ID header
+package requestid
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Header carries the request ID in requests and responses
+const Header = "X-Request-ID"
+
+// maxLength bounds request IDs chosen by clients, so that they cannot bloat logs
+const maxLength = 128
+
+// ginKey stores the request ID in the Gin context
+const ginKey = "request_id"
+
+// contextKey stores the request ID in request contexts
+type contextKey struct{}
+
+// Middleware assigns every request an ID, honoring a valid X-Request-ID sent by the client. The
+// ID is returned in the X-Request-ID response header.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if !valid(id) {
+			id = uuid.New().String()
+		}
+
+		c.Set(ginKey, id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Header(Header, id)
+
+		c.Next()
+	}
+}
+
+// Get returns the ID of the request being handled, empty if Middleware did not run
+func Get(c *gin.Context) string {
+	return c.GetString(ginKey)
+}
+
+// NewContext returns a copy of ctx carrying a request ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, empty if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// valid reports whether a client-chosen request ID is short and printable
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
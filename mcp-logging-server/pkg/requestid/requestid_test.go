@@ -0,0 +1,53 @@
+package requestid
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/ok", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"request_id": FromContext(c.Request.Context())})
+	})
+	return router
+}
+
+func TestMiddleware(t *testing.T) {
+	router := newRouter()
+
+	serve := func(path, requestID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		if requestID != "" {
+			req.Header.Set(Header, requestID)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// A valid client ID is honored and passed to handlers
+	w := serve("/ok", "client-123")
+	if w.Header().Get(Header) != "client-123" {
+		t.Errorf("Expected the client's request ID, got %q", w.Header().Get(Header))
+	}
+	var ok map[string]string
+	json.Unmarshal(w.Body.Bytes(), &ok)
+	if ok["request_id"] != "client-123" {
+		t.Errorf("Expected the request ID in the handler, got %s", w.Body.String())
+	}
+
+	// Invalid client IDs are replaced
+	for _, invalid := range []string{"has space", strings.Repeat("a", maxLength+1), "ünicode"} {
+		if id := serve("/ok", invalid).Header().Get(Header); id == invalid || id == "" {
+			t.Errorf("Expected %q to be replaced, got %q", invalid, id)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// Package routing applies routing rules to entries at ingestion. Rules match entries on their
+// service, level, metadata and expressions and tag, enrich, drop, re-level or route them to a
+// sink, so that noisy or misrouted entries are handled centrally instead of by filters in every
+// client.
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/expression"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// Actions of rules
+const (
+	ActionTag     = "tag"     // Adds tags and continues with the next rule
+	ActionSet     = "set"     // Sets metadata keys to the values of expressions and continues with the next rule
+	ActionRelevel = "relevel" // Changes the level and continues with the next rule
+	ActionDrop    = "drop"    // Discards the entry
+	ActionRoute   = "route"   // Sends the entry to a sink instead of storing it
+)
+
+// Outcomes of evaluating the rules for an entry
+const (
+	OutcomeKeep  = "keep"  // Stored by this server
+	OutcomeDrop  = "drop"  // Discarded
+	OutcomeRoute = "route" // Sent to a sink
+)
+
+// maxTags is the number of tags an entry may have, tags added beyond it are skipped
+const maxTags = 20
+
+// maxTagLength is the length of a tag in characters, as validated at ingestion
+const maxTagLength = 50
+
+// Match selects the entries a rule applies to. Empty conditions match every entry.
+type Match struct {
+	Services []string          `json:"services,omitempty"` // Service names or path.Match patterns such as payments-*, any of them
+	Levels   []models.LogLevel `json:"levels,omitempty"`   // Levels, any of them
+	Metadata map[string]string `json:"metadata,omitempty"` // Regular expressions the metadata values must all match, non-string values are formatted
+
+	// Expression is a condition such as metadata.latency_ms > 500 && service_name == "api", in
+	// the language of package expression. Entries it fails to evaluate for do not match.
+	Expression string `json:"expression,omitempty"`
+}
+
+// Rule is a routing rule
+type Rule struct {
+	Name   string            `json:"name"` // Reported in previews and stats
+	Match  Match             `json:"match"`
+	Action string            `json:"action"`          // One of the Action constants
+	Tags   []string          `json:"tags,omitempty"`  // Tags added by tag rules
+	Set    map[string]string `json:"set,omitempty"`   // Metadata keys set by set rules to the values of expressions
+	Level  models.LogLevel   `json:"level,omitempty"` // Level set by relevel rules
+	Sink   string            `json:"sink,omitempty"`  // Name of the sink route rules send entries to
+}
+
+// Sink receives the entries routed to it. A replication.Replicator with the sink's server as
+// its only peer delivers them asynchronously.
+type Sink interface {
+	Committed(entries []models.LogEntry)
+}
+
+// Protector applies data protection to the metadata keys of an entry written by set rules. Rules
+// are applied after data protection, so values set from other fields would skip it otherwise.
+type Protector func(ctx context.Context, entry *models.LogEntry, keys []string) error
+
+// rule is a Rule with its metadata patterns and expressions compiled
+type rule struct {
+	Rule
+	levels     map[models.LogLevel]bool
+	metadata   map[string]*regexp.Regexp
+	expression *expression.Expression
+	set        map[string]*expression.Expression
+	matched    atomic.Int64
+}
+
+// Engine evaluates the rules in order. Tag, set and relevel rules change the entry and
+// evaluation continues with the next rule, which sees the changes; the first matching drop or
+// route rule ends it. Entries no drop or route rule matches are kept.
+type Engine struct {
+	rules   []*rule
+	sinks   map[string]Sink
+	protect Protector
+
+	dropped atomic.Int64
+
+	mutex  sync.Mutex
+	routed map[string]int64 // Entries sent to each sink
+}
+
+// Decision is the outcome of the rules for an entry
+type Decision struct {
+	Rules   []string        `json:"rules"`          // Names of the matching rules, in order
+	Outcome string          `json:"outcome"`        // One of the Outcome constants
+	Sink    string          `json:"sink,omitempty"` // Sink of routed entries
+	Entry   models.LogEntry `json:"entry"`          // The entry as changed by the rules
+}
+
+// RuleStats reports the entries a rule matched
+type RuleStats struct {
+	Name    string `json:"name"`
+	Action  string `json:"action"`
+	Matched int64  `json:"matched"`
+}
+
+// Stats reports the use of the rules since the server started
+type Stats struct {
+	Rules   []RuleStats      `json:"rules"`
+	Dropped int64            `json:"dropped"`
+	Routed  map[string]int64 `json:"routed"` // Entries sent to each sink
+}
+
+// New checks the rules and creates an engine applying them. Route rules must name one of sinks.
+func New(rules []Rule, sinks map[string]Sink) (*Engine, error) {
+	engine := &Engine{
+		sinks:  sinks,
+		routed: make(map[string]int64, len(sinks)),
+	}
+	names := make(map[string]bool, len(rules))
+	for _, r := range rules {
+		if r.Name == "" {
+			return nil, errors.New("routing rule has no name")
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate routing rule %q", r.Name)
+		}
+		names[r.Name] = true
+
+		compiled, err := compile(r, sinks)
+		if err != nil {
+			return nil, fmt.Errorf("routing rule %q: %w", r.Name, err)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	for name := range sinks {
+		engine.routed[name] = 0
+	}
+	return engine, nil
+}
+
+// SetProtector makes the engine pass the metadata keys set rules write to protect, before later
+// rules see them. Entries it fails for are dropped. It must be set before entries are applied.
+func (e *Engine) SetProtector(protect Protector) {
+	e.protect = protect
+}
+
+// compile checks a rule and compiles its conditions
+func compile(r Rule, sinks map[string]Sink) (*rule, error) {
+	switch r.Action {
+	case ActionTag:
+		if len(r.Tags) == 0 {
+			return nil, errors.New("tag rule has no tags")
+		}
+		for _, tag := range r.Tags {
+			if tag == "" || len([]rune(tag)) > maxTagLength {
+				return nil, fmt.Errorf("invalid tag %q", tag)
+			}
+		}
+	case ActionSet:
+		if len(r.Set) == 0 {
+			return nil, errors.New("set rule has no values")
+		}
+	case ActionRelevel:
+		if r.Level.Severity() == 0 {
+			return nil, fmt.Errorf("invalid level %q", r.Level)
+		}
+	case ActionDrop:
+	case ActionRoute:
+		if _, ok := sinks[r.Sink]; !ok {
+			return nil, fmt.Errorf("unknown sink %q", r.Sink)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+
+	for _, pattern := range r.Match.Services {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid service pattern %q: %w", pattern, err)
+		}
+	}
+
+	compiled := &rule{Rule: r}
+	if len(r.Match.Levels) > 0 {
+		compiled.levels = make(map[models.LogLevel]bool, len(r.Match.Levels))
+		for _, level := range r.Match.Levels {
+			if level.Severity() == 0 {
+				return nil, fmt.Errorf("invalid level %q", level)
+			}
+			compiled.levels[level] = true
+		}
+	}
+	if len(r.Match.Metadata) > 0 {
+		compiled.metadata = make(map[string]*regexp.Regexp, len(r.Match.Metadata))
+		for key, expression := range r.Match.Metadata {
+			pattern, err := regexp.Compile(expression)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern of metadata.%s: %w", key, err)
+			}
+			compiled.metadata[key] = pattern
+		}
+	}
+	if r.Match.Expression != "" {
+		condition, err := expression.Compile(r.Match.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression: %w", err)
+		}
+		compiled.expression = condition
+	}
+	if r.Action == ActionSet {
+		compiled.set = make(map[string]*expression.Expression, len(r.Set))
+		for key, source := range r.Set {
+			if key == "" {
+				return nil, errors.New("set rule has an empty metadata key")
+			}
+			value, err := expression.Compile(source)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expression of metadata.%s: %w", key, err)
+			}
+			compiled.set[key] = value
+		}
+	}
+	return compiled, nil
+}
+
+// matches reports whether the rule applies to an entry
+func (r *rule) matches(entry *models.LogEntry) bool {
+	if len(r.Match.Services) > 0 {
+		matched := false
+		for _, pattern := range r.Match.Services {
+			if ok, _ := path.Match(pattern, entry.ServiceName); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if r.levels != nil && !r.levels[entry.Level] {
+		return false
+	}
+	for key, pattern := range r.metadata {
+		value, ok := entry.Metadata[key]
+		if !ok || value == nil {
+			return false
+		}
+		text, ok := value.(string)
+		if !ok {
+			text = fmt.Sprintf("%v", value)
+		}
+		if !pattern.MatchString(text) {
+			return false
+		}
+	}
+	if r.expression != nil {
+		matched, err := r.expression.Match(entry)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// apply changes the entry by a tag, set or relevel rule and returns the metadata keys it set
+func (r *rule) apply(entry *models.LogEntry) []string {
+	switch r.Action {
+	case ActionTag:
+		for _, tag := range r.Tags {
+			if len(entry.Tags) >= maxTags {
+				return nil
+			}
+			if !hasTag(entry.Tags, tag) {
+				entry.Tags = append(entry.Tags, tag)
+			}
+		}
+	case ActionRelevel:
+		entry.Level = r.Level
+	case ActionSet:
+		// The expressions see the entry as the rule got it, and the metadata is copied so that
+		// maps shared with other entries or callers are not changed
+		metadata := make(map[string]interface{}, len(entry.Metadata)+len(r.set))
+		for key, value := range entry.Metadata {
+			metadata[key] = value
+		}
+		var keys []string
+		for key, value := range r.set {
+			// Keys whose expression fails to evaluate or is nil are left as they are
+			result, err := value.Eval(entry)
+			if err != nil || result == nil {
+				continue
+			}
+			metadata[key] = result
+			keys = append(keys, key)
+		}
+		if len(metadata) > 0 {
+			entry.Metadata = metadata
+		}
+		return keys
+	}
+	return nil
+}
+
+// hasTag reports whether tags contains tag
+func hasTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate applies the rules to an entry, changing it in place, and returns the outcome. Each
+// matching rule is passed to matched. Keys written by set rules are passed to protect, unless it
+// is nil; entries it fails for are dropped.
+func (e *Engine) evaluate(ctx context.Context, entry *models.LogEntry, matched func(*rule), protect Protector) (string, string) {
+	for _, r := range e.rules {
+		if !r.matches(entry) {
+			continue
+		}
+		matched(r)
+		switch r.Action {
+		case ActionDrop:
+			return OutcomeDrop, ""
+		case ActionRoute:
+			return OutcomeRoute, r.Sink
+		default:
+			keys := r.apply(entry)
+			if protect == nil || len(keys) == 0 {
+				continue
+			}
+			if err := protect(ctx, entry, keys); err != nil {
+				log.Printf("Routing rule %s: dropping entry %s, failed to protect its metadata: %v", r.Name, entry.ID, err)
+				return OutcomeDrop, ""
+			}
+		}
+	}
+	return OutcomeKeep, ""
+}
+
+// Apply applies the rules to a batch, sends the routed entries to their sinks and returns the
+// entries to store. Kept entries are changed in place; the batch itself is only returned as is
+// when every entry is kept, so callers may keep using it. ctx is passed to the protector.
+func (e *Engine) Apply(ctx context.Context, entries []models.LogEntry) []models.LogEntry {
+	if len(e.rules) == 0 {
+		return entries
+	}
+
+	count := func(r *rule) { r.matched.Add(1) }
+	var kept []models.LogEntry
+	var routed map[string][]models.LogEntry
+	for i := range entries {
+		outcome, sink := e.evaluate(ctx, &entries[i], count, e.protect)
+		if outcome == OutcomeKeep {
+			if kept != nil {
+				kept = append(kept, entries[i])
+			}
+			continue
+		}
+		if kept == nil {
+			kept = make([]models.LogEntry, i, len(entries))
+			copy(kept, entries[:i])
+		}
+		if outcome == OutcomeDrop {
+			e.dropped.Add(1)
+			continue
+		}
+		if routed == nil {
+			routed = make(map[string][]models.LogEntry)
+		}
+		routed[sink] = append(routed[sink], entries[i])
+	}
+
+	for sink, batch := range routed {
+		e.sinks[sink].Committed(batch)
+		e.mutex.Lock()
+		e.routed[sink] += int64(len(batch))
+		e.mutex.Unlock()
+	}
+	if kept == nil {
+		return entries
+	}
+	return kept
+}
+
+// Preview returns the decisions of the rules for entries without changing them, counting them,
+// sending them to sinks or protecting the keys set rules write
+func (e *Engine) Preview(entries []models.LogEntry) []Decision {
+	decisions := make([]Decision, len(entries))
+	for i := range entries {
+		entry := entries[i]
+		entry.Tags = append([]string(nil), entry.Tags...)
+
+		decision := Decision{Rules: []string{}}
+		decision.Outcome, decision.Sink = e.evaluate(context.Background(), &entry, func(r *rule) {
+			decision.Rules = append(decision.Rules, r.Name)
+		}, nil)
+		decision.Entry = entry
+		decisions[i] = decision
+	}
+	return decisions
+}
+
+// Rules returns the rules in the order they are evaluated
+func (e *Engine) Rules() []Rule {
+	rules := make([]Rule, len(e.rules))
+	for i, r := range e.rules {
+		rules[i] = r.Rule
+	}
+	return rules
+}
+
+// Stats returns the entries each rule matched and the entries dropped and routed
+func (e *Engine) Stats() Stats {
+	stats := Stats{
+		Rules:   make([]RuleStats, len(e.rules)),
+		Dropped: e.dropped.Load(),
+		Routed:  make(map[string]int64, len(e.routed)),
+	}
+	for i, r := range e.rules {
+		stats.Rules[i] = RuleStats{Name: r.Name, Action: r.Action, Matched: r.matched.Load()}
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	for sink, count := range e.routed {
+		stats.Routed[sink] = count
+	}
+	return stats
+}
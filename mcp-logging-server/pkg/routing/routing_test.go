@@ -0,0 +1,240 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// recordingSink keeps the entries routed to it
+type recordingSink struct {
+	entries []models.LogEntry
+}
+
+func (s *recordingSink) Committed(entries []models.LogEntry) {
+	s.entries = append(s.entries, entries...)
+}
+
+func newEntry(service string, level models.LogLevel, metadata map[string]interface{}) models.LogEntry {
+	return models.LogEntry{
+		ID:          service + "-" + string(level),
+		Level:       level,
+		Message:     "message",
+		ServiceName: service,
+		AgentID:     "agent",
+		Platform:    models.PlatformGo,
+		Metadata:    metadata,
+	}
+}
+
+func testRules() []Rule {
+	return []Rule{
+		{Name: "tag-payments", Match: Match{Services: []string{"payments-*"}}, Action: ActionTag, Tags: []string{"payments"}},
+		{Name: "drop-health", Match: Match{Metadata: map[string]string{"path": "^/health"}}, Action: ActionDrop},
+		{Name: "quiet-retries", Match: Match{Levels: []models.LogLevel{models.LogLevelError}, Metadata: map[string]string{"retry": "^true$"}}, Action: ActionRelevel, Level: models.LogLevelWarn},
+		{Name: "route-acme", Match: Match{Services: []string{"acme"}, Levels: []models.LogLevel{models.LogLevelError}}, Action: ActionRoute, Sink: "acme"},
+	}
+}
+
+func TestEngine_Apply(t *testing.T) {
+	sink := &recordingSink{}
+	engine, err := New(testRules(), map[string]Sink{"acme": sink})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	entries := []models.LogEntry{
+		newEntry("payments-api", models.LogLevelInfo, nil),
+		newEntry("web", models.LogLevelDebug, map[string]interface{}{"path": "/health/live"}),
+		newEntry("acme", models.LogLevelError, map[string]interface{}{"retry": true}),
+		newEntry("acme", models.LogLevelError, nil),
+		newEntry("web", models.LogLevelInfo, nil),
+	}
+	kept := engine.Apply(context.Background(), entries)
+
+	if len(kept) != 3 {
+		t.Fatalf("Expected 3 kept entries, got %d", len(kept))
+	}
+	if len(kept[0].Tags) != 1 || kept[0].Tags[0] != "payments" {
+		t.Errorf("Expected payments entries to be tagged, got %v", kept[0].Tags)
+	}
+	if kept[1].ServiceName != "acme" || kept[1].Level != models.LogLevelWarn {
+		t.Errorf("Expected the retry to be re-leveled before routing was evaluated, got %+v", kept[1])
+	}
+	if len(sink.entries) != 1 || sink.entries[0].ID != entries[3].ID {
+		t.Errorf("Expected the acme error to be routed, got %+v", sink.entries)
+	}
+
+	stats := engine.Stats()
+	if stats.Dropped != 1 || stats.Routed["acme"] != 1 {
+		t.Errorf("Expected 1 dropped and 1 routed entry, got %+v", stats)
+	}
+	matched := map[string]int64{}
+	for _, rule := range stats.Rules {
+		matched[rule.Name] = rule.Matched
+	}
+	if matched["tag-payments"] != 1 || matched["drop-health"] != 1 || matched["quiet-retries"] != 1 || matched["route-acme"] != 1 {
+		t.Errorf("Unexpected matches per rule: %v", matched)
+	}
+}
+
+func TestEngine_ApplyKeepsBatch(t *testing.T) {
+	engine, err := New(testRules(), map[string]Sink{"acme": &recordingSink{}})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	entries := []models.LogEntry{newEntry("web", models.LogLevelInfo, nil)}
+	if kept := engine.Apply(context.Background(), entries); &kept[0] != &entries[0] {
+		t.Error("Expected a batch without dropped or routed entries to be returned as is")
+	}
+}
+
+func TestEngine_Preview(t *testing.T) {
+	sink := &recordingSink{}
+	engine, err := New(testRules(), map[string]Sink{"acme": sink})
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	entries := []models.LogEntry{
+		newEntry("payments-api", models.LogLevelDebug, map[string]interface{}{"path": "/health"}),
+		newEntry("acme", models.LogLevelError, nil),
+		newEntry("web", models.LogLevelInfo, nil),
+	}
+	decisions := engine.Preview(entries)
+
+	want := []struct {
+		outcome string
+		sink    string
+		rules   string
+	}{
+		{OutcomeDrop, "", "tag-payments,drop-health"},
+		{OutcomeRoute, "acme", "route-acme"},
+		{OutcomeKeep, "", ""},
+	}
+	for i, decision := range decisions {
+		if decision.Outcome != want[i].outcome || decision.Sink != want[i].sink || strings.Join(decision.Rules, ",") != want[i].rules {
+			t.Errorf("Entry %d: expected %+v, got %+v", i, want[i], decision)
+		}
+	}
+	if len(decisions[0].Entry.Tags) != 1 || len(entries[0].Tags) != 0 {
+		t.Errorf("Expected the decision to show the tags without changing the entry, got %v and %v", decisions[0].Entry.Tags, entries[0].Tags)
+	}
+	if len(sink.entries) != 0 || engine.Stats().Dropped != 0 {
+		t.Error("Expected previews not to route or count entries")
+	}
+}
+
+func TestEngine_ApplyExpressions(t *testing.T) {
+	engine, err := New([]Rule{
+		{Name: "flag-slow", Match: Match{Expression: `metadata.latency_ms > 500 && service_name == "api"`}, Action: ActionSet, Set: map[string]string{
+			"slow":       "true",
+			"latency_s":  "metadata.latency_ms / 1000",
+			"owner_team": "metadata.team?.name", // Absent, left unset
+		}},
+		{Name: "drop-fast-health", Match: Match{Expression: `metadata.path startsWith "/health" && metadata.slow == nil`}, Action: ActionDrop},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+
+	slow := map[string]interface{}{"latency_ms": 1500, "path": "/health"}
+	entries := []models.LogEntry{
+		newEntry("api", models.LogLevelInfo, slow),
+		newEntry("api", models.LogLevelInfo, map[string]interface{}{"latency_ms": 20, "path": "/health"}),
+		newEntry("web", models.LogLevelInfo, map[string]interface{}{"latency_ms": "unknown"}),
+	}
+	kept := engine.Apply(context.Background(), entries)
+
+	if len(kept) != 2 || kept[0].ID != entries[0].ID || kept[1].ServiceName != "web" {
+		t.Fatalf("Expected the fast health check to be dropped, got %+v", kept)
+	}
+	metadata := kept[0].Metadata
+	if metadata["slow"] != true || metadata["latency_s"] != 1.5 || metadata["path"] != "/health" {
+		t.Errorf("Expected the slow request to be enriched, got %v", metadata)
+	}
+	if _, ok := metadata["owner_team"]; ok {
+		t.Errorf("Expected null values not to be set, got %v", metadata)
+	}
+	if _, ok := slow["slow"]; ok {
+		t.Error("Expected the metadata map of the request to be left unchanged")
+	}
+	if _, ok := kept[1].Metadata["slow"]; ok {
+		t.Error("Expected an expression failing to evaluate not to match")
+	}
+}
+
+func TestEngine_ApplyProtectsSetKeys(t *testing.T) {
+	engine, err := New([]Rule{
+		{Name: "copy-user", Match: Match{Expression: `metadata.user != nil`}, Action: ActionSet, Set: map[string]string{"owner": "metadata.user"}},
+		{Name: "drop-unmasked", Match: Match{Expression: `metadata.owner == "alice@example.com"`}, Action: ActionDrop},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create engine: %v", err)
+	}
+	var protected []string
+	engine.SetProtector(func(ctx context.Context, entry *models.LogEntry, keys []string) error {
+		protected = append(protected, keys...)
+		if entry.ServiceName == "broken" {
+			return errors.New("protection failed")
+		}
+		entry.Metadata["owner"] = "***"
+		return nil
+	})
+
+	entries := []models.LogEntry{
+		newEntry("api", models.LogLevelInfo, map[string]interface{}{"user": "alice@example.com"}),
+		newEntry("broken", models.LogLevelInfo, map[string]interface{}{"user": "alice@example.com"}),
+		newEntry("web", models.LogLevelInfo, nil),
+	}
+	kept := engine.Apply(context.Background(), entries)
+
+	if len(kept) != 2 || kept[0].ServiceName != "api" || kept[1].ServiceName != "web" {
+		t.Fatalf("Expected the entry failing protection to be dropped, got %+v", kept)
+	}
+	if kept[0].Metadata["owner"] != "***" {
+		t.Errorf("Expected the set key to be protected before later rules, got %v", kept[0].Metadata)
+	}
+	if len(protected) != 2 || protected[0] != "owner" {
+		t.Errorf("Expected only the set keys to be protected, got %v", protected)
+	}
+
+	// Previews do not protect
+	protected = nil
+	if decisions := engine.Preview(entries[:1]); decisions[0].Outcome != OutcomeDrop || len(protected) != 0 {
+		t.Errorf("Expected the preview not to protect set keys, got %+v and %v", decisions, protected)
+	}
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []Rule
+		err   string
+	}{
+		{"no name", []Rule{{Action: ActionDrop}}, "no name"},
+		{"duplicate", []Rule{{Name: "a", Action: ActionDrop}, {Name: "a", Action: ActionDrop}}, "duplicate"},
+		{"unknown action", []Rule{{Name: "a", Action: "mirror"}}, "unknown action"},
+		{"unknown sink", []Rule{{Name: "a", Action: ActionRoute, Sink: "missing"}}, "unknown sink"},
+		{"invalid level", []Rule{{Name: "a", Action: ActionRelevel, Level: "LOUD"}}, "invalid level"},
+		{"no tags", []Rule{{Name: "a", Action: ActionTag}}, "no tags"},
+		{"invalid pattern", []Rule{{Name: "a", Action: ActionDrop, Match: Match{Metadata: map[string]string{"path": "("}}}}, "metadata.path"},
+		{"invalid service pattern", []Rule{{Name: "a", Action: ActionDrop, Match: Match{Services: []string{"["}}}}, "service pattern"},
+		{"invalid expression", []Rule{{Name: "a", Action: ActionDrop, Match: Match{Expression: "latency > 5"}}}, "invalid expression"},
+		{"no values", []Rule{{Name: "a", Action: ActionSet}}, "no values"},
+		{"invalid value", []Rule{{Name: "a", Action: ActionSet, Set: map[string]string{"slow": "latency >"}}}, "metadata.slow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rules, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package sampling keeps a fraction of the entries of noisy services, such as 1% of their INFO
+// entries, so their volume can be reduced on the server without losing their errors
+package sampling
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// Policy sets the fraction of the entries of a service kept at each level
+type Policy struct {
+	Service string                      // Service name or path.Match pattern such as payments-*
+	Rates   map[models.LogLevel]float64 // Fraction of the entries kept per level, from 0 to 1; levels not listed keep all
+}
+
+// Sampler applies the first policy whose service matches an entry. Whether an entry is kept
+// depends only on its ID, so an entry sent again, such as by a client retrying a request, is
+// kept or sampled out as it was the first time.
+type Sampler struct {
+	policies []Policy
+}
+
+// New checks the policies and creates a sampler applying them
+func New(policies []Policy) (*Sampler, error) {
+	for i, policy := range policies {
+		if policy.Service == "" {
+			return nil, fmt.Errorf("sampling policy %d has no service", i+1)
+		}
+		if _, err := path.Match(policy.Service, ""); err != nil {
+			return nil, fmt.Errorf("sampling policy for %q: invalid service pattern: %w", policy.Service, err)
+		}
+		if len(policy.Rates) == 0 {
+			return nil, fmt.Errorf("sampling policy for %q has no rates", policy.Service)
+		}
+		for level, rate := range policy.Rates {
+			if level.Severity() == 0 {
+				return nil, fmt.Errorf("sampling policy for %q: invalid level %q", policy.Service, level)
+			}
+			if math.IsNaN(rate) || rate < 0 || rate > 1 {
+				return nil, fmt.Errorf("sampling policy for %q: rate of %s must be between 0 and 1", policy.Service, level)
+			}
+		}
+	}
+	return &Sampler{policies: policies}, nil
+}
+
+// Rate returns the fraction of the entries like entry that are kept, 1 when no policy applies
+func (s *Sampler) Rate(entry *models.LogEntry) float64 {
+	for _, policy := range s.policies {
+		if ok, _ := path.Match(policy.Service, entry.ServiceName); !ok {
+			continue
+		}
+		if rate, ok := policy.Rates[entry.Level]; ok {
+			return rate
+		}
+		return 1
+	}
+	return 1
+}
+
+// Keep reports whether an entry is kept
+func (s *Sampler) Keep(entry *models.LogEntry) bool {
+	rate := s.Rate(entry)
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+	return position(entry.ID) < rate
+}
+
+// position maps an entry ID to a fraction in [0, 1). The hash is mixed further since the high
+// bits of FNV alone differ little between similar IDs such as sequential ones.
+func position(id string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return float64(x>>11) / (1 << 53)
+}
+
+// Apply returns the entries of a batch that are kept, passing each sampled out entry to
+// sampledOut. The batch itself is only returned as is when every entry is kept, so callers may
+// keep using it.
+func (s *Sampler) Apply(entries []models.LogEntry, sampledOut func(entry *models.LogEntry)) []models.LogEntry {
+	if len(s.policies) == 0 {
+		return entries
+	}
+
+	var kept []models.LogEntry
+	for i := range entries {
+		if s.Keep(&entries[i]) {
+			if kept != nil {
+				kept = append(kept, entries[i])
+			}
+			continue
+		}
+		if kept == nil {
+			kept = make([]models.LogEntry, i, len(entries))
+			copy(kept, entries[:i])
+		}
+		if sampledOut != nil {
+			sampledOut(&entries[i])
+		}
+	}
+	if kept == nil {
+		return entries
+	}
+	return kept
+}
+
+// Policies returns the policies in the order they are applied
+func (s *Sampler) Policies() []Policy {
+	return s.policies
+}
@@ -0,0 +1,94 @@
+package sampling
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newEntry(id, service string, level models.LogLevel) models.LogEntry {
+	return models.LogEntry{ID: id, Level: level, Message: "message", ServiceName: service}
+}
+
+func TestSampler_Apply(t *testing.T) {
+	sampler, err := New([]Policy{
+		{Service: "payments-*", Rates: map[models.LogLevel]float64{models.LogLevelDebug: 0, models.LogLevelInfo: 0.1}},
+		{Service: "*", Rates: map[models.LogLevel]float64{models.LogLevelDebug: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sampler: %v", err)
+	}
+
+	var entries []models.LogEntry
+	for i := 0; i < 1000; i++ {
+		entries = append(entries, newEntry(fmt.Sprintf("info-%d", i), "payments-api", models.LogLevelInfo))
+	}
+	entries = append(entries,
+		newEntry("debug", "payments-api", models.LogLevelDebug),
+		newEntry("error", "payments-api", models.LogLevelError),
+		newEntry("web", "web", models.LogLevelDebug),
+	)
+
+	sampledOut := map[models.LogLevel]int{}
+	kept := sampler.Apply(entries, func(entry *models.LogEntry) {
+		sampledOut[entry.Level]++
+	})
+
+	if sampledOut[models.LogLevelDebug] != 1 || sampledOut[models.LogLevelError] != 0 {
+		t.Errorf("Expected the debug entry to be sampled out and the error kept, got %v", sampledOut)
+	}
+	if infos := 1000 - sampledOut[models.LogLevelInfo]; infos < 50 || infos > 150 {
+		t.Errorf("Expected about 100 info entries to be kept, got %d", infos)
+	}
+	if len(kept)+sampledOut[models.LogLevelDebug]+sampledOut[models.LogLevelInfo] != len(entries) {
+		t.Errorf("Expected every entry to be kept or sampled out, got %d kept of %d", len(kept), len(entries))
+	}
+
+	// The same entries are kept when they are sent again
+	again := sampler.Apply(entries, nil)
+	if len(again) != len(kept) {
+		t.Errorf("Expected %d entries to be kept again, got %d", len(kept), len(again))
+	}
+	for i := range again {
+		if again[i].ID != kept[i].ID {
+			t.Fatalf("Expected the same entries to be kept, got %s instead of %s", again[i].ID, kept[i].ID)
+		}
+	}
+}
+
+func TestSampler_ApplyKeepsBatch(t *testing.T) {
+	sampler, err := New([]Policy{{Service: "payments-*", Rates: map[models.LogLevel]float64{models.LogLevelInfo: 0}}})
+	if err != nil {
+		t.Fatalf("Failed to create sampler: %v", err)
+	}
+
+	entries := []models.LogEntry{newEntry("1", "web", models.LogLevelInfo)}
+	if kept := sampler.Apply(entries, nil); &kept[0] != &entries[0] {
+		t.Error("Expected a batch without sampled out entries to be returned as is")
+	}
+}
+
+func TestNew_InvalidPolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		policies []Policy
+		err      string
+	}{
+		{"no service", []Policy{{Rates: map[models.LogLevel]float64{models.LogLevelInfo: 0.5}}}, "no service"},
+		{"invalid pattern", []Policy{{Service: "[", Rates: map[models.LogLevel]float64{models.LogLevelInfo: 0.5}}}, "service pattern"},
+		{"no rates", []Policy{{Service: "web"}}, "no rates"},
+		{"invalid level", []Policy{{Service: "web", Rates: map[models.LogLevel]float64{"LOUD": 0.5}}}, "invalid level"},
+		{"invalid rate", []Policy{{Service: "web", Rates: map[models.LogLevel]float64{models.LogLevelInfo: 1.5}}}, "between 0 and 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.policies)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
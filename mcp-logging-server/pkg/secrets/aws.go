@@ -0,0 +1,165 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSConfig configures an AWSProvider
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // For temporary credentials, optional
+	Endpoint        string // Overrides https://secretsmanager.<region>.amazonaws.com, e.g. for VPC endpoints
+}
+
+// AWSProvider reads secrets from AWS Secrets Manager. Secrets stored as key/value pairs are
+// JSON objects, so references select a key with #field, e.g. mcp-logging/tls#cert.
+type AWSProvider struct {
+	config   AWSConfig
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewAWSProvider creates a provider for Secrets Manager in the region described by config
+func NewAWSProvider(config AWSConfig) (*AWSProvider, error) {
+	if config.Region == "" {
+		return nil, fmt.Errorf("AWS region is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS access key ID and secret access key are required")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://secretsmanager.%s.amazonaws.com", config.Region)
+	}
+
+	return &AWSProvider{
+		config:   config,
+		endpoint: strings.TrimRight(endpoint, "/") + "/",
+		client:   &http.Client{Timeout: defaultProviderTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// GetSecret returns the current version of a secret, its SecretString or SecretBinary
+func (p *AWSProvider) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	body, err := json.Marshal(map[string]string{"SecretId": name})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signV4(req, body, p.config, "secretsmanager", p.now())
+
+	respBody, err := doRequest(p.client, req)
+	if err != nil {
+		// Secrets Manager reports missing secrets as a bad request
+		if strings.Contains(err.Error(), "ResourceNotFoundException") {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var response struct {
+		SecretString *string `json:"SecretString"`
+		SecretBinary []byte  `json:"SecretBinary"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("invalid Secrets Manager response: %w", err)
+	}
+	if response.SecretString != nil {
+		return []byte(*response.SecretString), nil
+	}
+	return response.SecretBinary, nil
+}
+
+// SignS3Request signs a request to S3 or an S3-compatible object store with AWS Signature
+// Version 4. S3 additionally requires the hash of the payload as a header.
+func SignS3Request(req *http.Request, body []byte, config AWSConfig, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	signV4(req, body, config, "s3", now)
+}
+
+// signV4 signs a request with AWS Signature Version 4, covering the host and all headers set
+// on the request
+func signV4(req *http.Request, body []byte, config AWSConfig, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", config.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", date, config.Region, service)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+config.SecretAccessKey), date)
+	key = hmacSHA256(key, config.Region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,90 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	config := AWSConfig{
+		Region:          "us-east-1",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	signV4(req, nil, config, "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Unexpected authorization header\n got: %s\nwant: %s", got, expected)
+	}
+}
+
+func TestAWSProvider_GetSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" ||
+			!strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var request struct {
+			SecretId string
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		switch request.SecretId {
+		case "mcp-logging/salt":
+			w.Write([]byte(`{"Name": "mcp-logging/salt", "SecretString": "{\"salt\": \"pepper\"}"}`))
+		case "mcp-logging/binary":
+			w.Write([]byte(`{"Name": "mcp-logging/binary", "SecretBinary": "AAEC"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewAWSProvider(AWSConfig{
+		Region:          "eu-west-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Endpoint:        server.URL,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	ctx := context.Background()
+
+	salt, err := Resolve(ctx, provider, "mcp-logging/salt#salt")
+	if err != nil {
+		t.Fatalf("Failed to resolve secret: %v", err)
+	}
+	if string(salt) != "pepper" {
+		t.Errorf("Expected pepper, got %q", salt)
+	}
+
+	binary, err := provider.GetSecret(ctx, "mcp-logging/binary")
+	if err != nil {
+		t.Fatalf("Failed to get binary secret: %v", err)
+	}
+	if string(binary) != "\x00\x01\x02" {
+		t.Errorf("Unexpected binary secret %v", binary)
+	}
+
+	if _, err := provider.GetSecret(ctx, "mcp-logging/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
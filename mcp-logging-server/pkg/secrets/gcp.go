@@ -0,0 +1,140 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// gcpEndpoint is the Secret Manager API
+	gcpEndpoint = "https://secretmanager.googleapis.com"
+
+	// gcpMetadataTokenURL issues access tokens for the service account of GCE, GKE and Cloud Run workloads
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCPConfig configures a GCPProvider
+type GCPConfig struct {
+	Project     string // Project of secret names that are not fully qualified
+	AccessToken string // OAuth access token, fetched from the metadata server when empty
+	Endpoint    string // Overrides https://secretmanager.googleapis.com
+	TokenURL    string // Overrides the token endpoint of the metadata server
+}
+
+// GCPProvider reads secrets from Google Cloud Secret Manager. Names are secret IDs in the
+// configured project or full resource names, optionally with a version, e.g. tls-key,
+// tls-key/versions/3 or projects/my-project/secrets/tls-key.
+type GCPProvider struct {
+	config GCPConfig
+	client *http.Client
+
+	mutex       sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewGCPProvider creates a provider for Secret Manager
+func NewGCPProvider(config GCPConfig) *GCPProvider {
+	if config.Endpoint == "" {
+		config.Endpoint = gcpEndpoint
+	}
+	if config.TokenURL == "" {
+		config.TokenURL = gcpMetadataTokenURL
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+
+	return &GCPProvider{
+		config: config,
+		client: &http.Client{Timeout: defaultProviderTimeout},
+	}
+}
+
+// GetSecret returns the payload of a secret version, the latest unless the name has one
+func (p *GCPProvider) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	resource, err := p.resourceName(name)
+	if err != nil {
+		return nil, err
+	}
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Endpoint+"/v1/"+resource+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	body, err := doRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Payload struct {
+			Data []byte `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid Secret Manager response: %w", err)
+	}
+	return response.Payload.Data, nil
+}
+
+// resourceName returns the resource name of the secret version a name refers to
+func (p *GCPProvider) resourceName(name string) (string, error) {
+	name = strings.Trim(name, "/")
+	if !strings.HasPrefix(name, "projects/") {
+		if p.config.Project == "" {
+			return "", fmt.Errorf("GCP project is required for secret %s", name)
+		}
+		name = fmt.Sprintf("projects/%s/secrets/%s", p.config.Project, name)
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	return name, nil
+}
+
+// accessToken returns the configured access token, or one from the metadata server that is
+// reused until shortly before it expires
+func (p *GCPProvider) accessToken(ctx context.Context) (string, error) {
+	if p.config.AccessToken != "" {
+		return p.config.AccessToken, nil
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.token != "" && time.Now().Before(p.tokenExpiry) {
+		return p.token, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.TokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := doRequest(p.client, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token from metadata server: %w", err)
+	}
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.AccessToken == "" {
+		return "", fmt.Errorf("invalid metadata server token response")
+	}
+
+	p.token = response.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(response.ExpiresIn)*time.Second - time.Minute)
+	return p.token, nil
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGCPProvider_GetSecret(t *testing.T) {
+	tokenRequests := 0
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		tokenRequests++
+		w.Write([]byte(`{"access_token": "metadata-token", "expires_in": 3600, "token_type": "Bearer"}`))
+	}))
+	defer metadata.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer metadata-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/projects/my-project/secrets/hash-salt/versions/latest:access":
+			w.Write([]byte(`{"name": "projects/123/secrets/hash-salt/versions/2", "payload": {"data": "cGVwcGVy"}}`))
+		case "/v1/projects/other/secrets/hash-salt/versions/1:access":
+			w.Write([]byte(`{"name": "projects/456/secrets/hash-salt/versions/1", "payload": {"data": "c2FsdA=="}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewGCPProvider(GCPConfig{
+		Project:  "my-project",
+		Endpoint: server.URL,
+		TokenURL: metadata.URL,
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"hash-salt", "pepper"},
+		{"projects/other/secrets/hash-salt/versions/1", "salt"},
+	}
+	for _, tt := range tests {
+		value, err := provider.GetSecret(ctx, tt.name)
+		if err != nil {
+			t.Errorf("GetSecret(%s) failed: %v", tt.name, err)
+			continue
+		}
+		if string(value) != tt.expected {
+			t.Errorf("GetSecret(%s) = %q, expected %q", tt.name, value, tt.expected)
+		}
+	}
+
+	if _, err := provider.GetSecret(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("Expected the access token to be reused, got %d token requests", tokenRequests)
+	}
+}
+
+func TestGCPProvider_RequiresProjectForSecretIDs(t *testing.T) {
+	provider := NewGCPProvider(GCPConfig{AccessToken: "token"})
+	if _, err := provider.GetSecret(context.Background(), "hash-salt"); err == nil {
+		t.Error("Expected error for a secret ID without a project")
+	}
+}
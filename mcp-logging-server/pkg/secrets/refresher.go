@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is how often secrets are reloaded when no interval is configured
+const DefaultRefreshInterval = 5 * time.Minute
+
+// Refresher loads secrets from a provider and reloads them periodically, passing the values
+// to their consumers whenever they changed
+type Refresher struct {
+	provider Provider
+	interval time.Duration
+
+	mutex   sync.Mutex
+	watches []*watch
+}
+
+// watch is a consumer of one or more secrets that are applied together, e.g. a certificate and its key
+type watch struct {
+	refs   []string
+	apply  func(values [][]byte) error
+	values [][]byte // Last applied values
+}
+
+// NewRefresher creates a refresher reloading secrets from provider every interval,
+// DefaultRefreshInterval if it is not positive
+func NewRefresher(provider Provider, interval time.Duration) *Refresher {
+	if interval <= 0 {
+		interval = DefaultRefreshInterval
+	}
+	return &Refresher{
+		provider: provider,
+		interval: interval,
+	}
+}
+
+// Watch registers a consumer of the secrets the references point to. apply is called with
+// their values, in the order of refs, on Load and whenever one of them changed.
+func (r *Refresher) Watch(apply func(values [][]byte) error, refs ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.watches = append(r.watches, &watch{refs: refs, apply: apply})
+}
+
+// Load reads all watched secrets and applies them, failing if any cannot be read or applied
+func (r *Refresher) Load(ctx context.Context) error {
+	return errors.Join(r.refresh(ctx)...)
+}
+
+// Run reloads the watched secrets every interval until ctx is done. Failures are logged and
+// leave the previous values in place.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, err := range r.refresh(ctx) {
+				log.Printf("Failed to refresh secrets: %v", err)
+			}
+		}
+	}
+}
+
+// refresh reads the watched secrets and applies the changed ones, returning the failures
+func (r *Refresher) refresh(ctx context.Context) []error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Each secret is read once, even if several references select fields of it
+	secrets := make(map[string][]byte)
+	read := func(ref string) ([]byte, error) {
+		name, field := SplitReference(ref)
+		value, ok := secrets[name]
+		if !ok {
+			var err error
+			if value, err = r.provider.GetSecret(ctx, name); err != nil {
+				return nil, fmt.Errorf("failed to read secret %s: %w", name, err)
+			}
+			secrets[name] = value
+		}
+		if field == "" {
+			return value, nil
+		}
+		return selectField(name, value, field)
+	}
+
+	var errs []error
+	for _, w := range r.watches {
+		values := make([][]byte, len(w.refs))
+		var err error
+		for i, ref := range w.refs {
+			if values[i], err = read(ref); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if w.values != nil && equalValues(w.values, values) {
+			continue
+		}
+		if err := w.apply(values); err != nil {
+			errs = append(errs, fmt.Errorf("failed to apply secret %s: %w", w.refs[0], err))
+			continue
+		}
+		w.values = values
+	}
+	return errs
+}
+
+// equalValues reports whether two lists of secret values are the same
+func equalValues(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+// Package secrets loads API keys, TLS key pairs and salts from a secrets manager instead of
+// plaintext files and environment variables, and reloads them periodically so that rotated
+// secrets are picked up without a restart
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned by providers for secrets that do not exist
+var ErrNotFound = errors.New("secret not found")
+
+// Provider reads secrets from a secrets manager
+type Provider interface {
+	// GetSecret returns the current value of the named secret
+	GetSecret(ctx context.Context, name string) ([]byte, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface
+type ProviderFunc func(ctx context.Context, name string) ([]byte, error)
+
+// GetSecret calls f(ctx, name)
+func (f ProviderFunc) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	return f(ctx, name)
+}
+
+// SplitReference splits a secret reference of the form name#field into the secret name and
+// the optional field, which selects a value of a secret holding a JSON object
+func SplitReference(ref string) (name, field string) {
+	name, field, _ = strings.Cut(ref, "#")
+	return name, field
+}
+
+// Resolve returns the value a secret reference points to
+func Resolve(ctx context.Context, provider Provider, ref string) ([]byte, error) {
+	name, field := SplitReference(ref)
+	value, err := provider.GetSecret(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	if field == "" {
+		return value, nil
+	}
+	return selectField(name, value, field)
+}
+
+// selectField returns a field of a secret holding a JSON object, strings without their quotes
+func selectField(name string, value []byte, field string) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(value, &fields); err != nil {
+		return nil, fmt.Errorf("secret %s does not hold a JSON object: %w", name, err)
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return nil, fmt.Errorf("secret %s has no field %s", name, field)
+	}
+
+	var str string
+	if json.Unmarshal(raw, &str) == nil {
+		return []byte(str), nil
+	}
+	return raw, nil
+}
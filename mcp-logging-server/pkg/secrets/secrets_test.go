@@ -0,0 +1,160 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryProvider is a provider serving secrets from a map and counting reads
+type memoryProvider struct {
+	mutex   sync.Mutex
+	secrets map[string]string
+	reads   int
+}
+
+func (p *memoryProvider) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.reads++
+	value, ok := p.secrets[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(value), nil
+}
+
+func (p *memoryProvider) set(name, value string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.secrets[name] = value
+}
+
+func TestResolve(t *testing.T) {
+	provider := &memoryProvider{secrets: map[string]string{
+		"salt": "plain-salt",
+		"tls":  `{"cert": "CERT\nPEM", "port": 8443}`,
+	}}
+	ctx := context.Background()
+
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"salt", "plain-salt"},
+		{"tls#cert", "CERT\nPEM"},
+		{"tls#port", "8443"},
+	}
+	for _, tt := range tests {
+		value, err := Resolve(ctx, provider, tt.ref)
+		if err != nil {
+			t.Errorf("Resolve(%s) failed: %v", tt.ref, err)
+			continue
+		}
+		if string(value) != tt.expected {
+			t.Errorf("Resolve(%s) = %q, expected %q", tt.ref, value, tt.expected)
+		}
+	}
+
+	if _, err := Resolve(ctx, provider, "tls#key"); err == nil {
+		t.Error("Expected error for a missing field")
+	}
+	if _, err := Resolve(ctx, provider, "salt#value"); err == nil {
+		t.Error("Expected error for a field of a secret that is not a JSON object")
+	}
+	if _, err := Resolve(ctx, provider, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRefresher_AppliesChangedSecrets(t *testing.T) {
+	provider := &memoryProvider{secrets: map[string]string{
+		"salt": "salt-1",
+		"tls":  `{"cert": "cert-1", "key": "key-1"}`,
+	}}
+	refresher := NewRefresher(provider, 0)
+
+	var salts []string
+	refresher.Watch(func(values [][]byte) error {
+		salts = append(salts, string(values[0]))
+		return nil
+	}, "salt")
+	var keyPairs []string
+	refresher.Watch(func(values [][]byte) error {
+		keyPairs = append(keyPairs, string(values[0])+"/"+string(values[1]))
+		return nil
+	}, "tls#cert", "tls#key")
+
+	ctx := context.Background()
+	if err := refresher.Load(ctx); err != nil {
+		t.Fatalf("Failed to load secrets: %v", err)
+	}
+	if provider.reads != 2 {
+		t.Errorf("Expected each secret to be read once, got %d reads", provider.reads)
+	}
+
+	// Unchanged secrets are not applied again
+	if errs := refresher.refresh(ctx); len(errs) > 0 {
+		t.Fatalf("Failed to refresh secrets: %v", errs)
+	}
+	provider.set("tls", `{"cert": "cert-2", "key": "key-2"}`)
+	if errs := refresher.refresh(ctx); len(errs) > 0 {
+		t.Fatalf("Failed to refresh secrets: %v", errs)
+	}
+
+	if strings.Join(salts, ",") != "salt-1" {
+		t.Errorf("Expected the salt to be applied once, got %v", salts)
+	}
+	if strings.Join(keyPairs, ",") != "cert-1/key-1,cert-2/key-2" {
+		t.Errorf("Expected both key pairs to be applied, got %v", keyPairs)
+	}
+}
+
+func TestRefresher_FailuresKeepPreviousValues(t *testing.T) {
+	provider := &memoryProvider{secrets: map[string]string{"salt": "salt-1"}}
+	refresher := NewRefresher(provider, 0)
+
+	var applied []string
+	refresher.Watch(func(values [][]byte) error {
+		if string(values[0]) == "invalid" {
+			return errors.New("invalid salt")
+		}
+		applied = append(applied, string(values[0]))
+		return nil
+	}, "salt")
+
+	ctx := context.Background()
+	if err := refresher.Load(ctx); err != nil {
+		t.Fatalf("Failed to load secrets: %v", err)
+	}
+
+	provider.set("salt", "invalid")
+	if errs := refresher.refresh(ctx); len(errs) != 1 {
+		t.Errorf("Expected the invalid value to fail, got %v", errs)
+	}
+
+	// The previous value is still applied, so returning to it is no change
+	provider.set("salt", "salt-1")
+	if errs := refresher.refresh(ctx); len(errs) > 0 {
+		t.Errorf("Failed to refresh secrets: %v", errs)
+	}
+	provider.set("salt", "salt-2")
+	if errs := refresher.refresh(ctx); len(errs) > 0 {
+		t.Errorf("Failed to refresh secrets: %v", errs)
+	}
+
+	if strings.Join(applied, ",") != "salt-1,salt-2" {
+		t.Errorf("Unexpected applied values %v", applied)
+	}
+}
+
+func TestRefresher_LoadFailsForMissingSecrets(t *testing.T) {
+	refresher := NewRefresher(&memoryProvider{secrets: map[string]string{}}, 0)
+	refresher.Watch(func(values [][]byte) error { return nil }, "missing")
+
+	if err := refresher.Load(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultProviderTimeout bounds each request to a secrets manager
+const defaultProviderTimeout = 10 * time.Second
+
+// VaultConfig configures a VaultProvider
+type VaultConfig struct {
+	Address   string // e.g. https://vault.example.com:8200
+	Token     string // Sent as X-Vault-Token
+	Namespace string // Vault Enterprise namespace, optional
+	Mount     string // Mount path of the KV version 2 engine, defaults to secret
+}
+
+// VaultProvider reads secrets from the KV version 2 secrets engine of HashiCorp Vault. A
+// secret is returned as a JSON object of its keys, so references select a key with #field,
+// e.g. mcp-logging/tls#cert.
+type VaultProvider struct {
+	config VaultConfig
+	client *http.Client
+}
+
+// NewVaultProvider creates a provider for the Vault server described by config
+func NewVaultProvider(config VaultConfig) (*VaultProvider, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("vault address is required")
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("vault token is required")
+	}
+	if config.Mount == "" {
+		config.Mount = "secret"
+	}
+	config.Address = strings.TrimRight(config.Address, "/")
+	config.Mount = strings.Trim(config.Mount, "/")
+
+	return &VaultProvider{
+		config: config,
+		client: &http.Client{Timeout: defaultProviderTimeout},
+	}, nil
+}
+
+// GetSecret returns the latest version of a KV secret as a JSON object
+func (p *VaultProvider) GetSecret(ctx context.Context, name string) ([]byte, error) {
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", p.config.Address, p.config.Mount, escapePath(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", p.config.Token)
+	if p.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", p.config.Namespace)
+	}
+
+	body, err := doRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid vault response: %w", err)
+	}
+	// Deleted versions are returned with null data
+	if len(response.Data.Data) == 0 || string(response.Data.Data) == "null" {
+		return nil, ErrNotFound
+	}
+	return response.Data.Data, nil
+}
+
+// escapePath escapes the segments of a slash separated secret path
+func escapePath(name string) string {
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// doRequest sends a request to a secrets manager and returns the body of a successful response
+func doRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode >= 300:
+		message := strings.TrimSpace(string(body))
+		if len(message) > 200 {
+			message = message[:200]
+		}
+		return nil, fmt.Errorf("secrets manager returned %s: %s", resp.Status, message)
+	}
+	return body, nil
+}
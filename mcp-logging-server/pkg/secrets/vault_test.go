@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVaultProvider_GetSecret(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "test-token" || r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/data/mcp-logging/tls":
+			w.Write([]byte(`{"data": {"data": {"cert": "CERT", "key": "KEY"}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/data/mcp-logging/deleted":
+			w.Write([]byte(`{"data": {"data": null, "metadata": {"version": 1}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewVaultProvider(VaultConfig{
+		Address:   server.URL + "/",
+		Token:     "test-token",
+		Namespace: "team",
+		Mount:     "kv",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+	ctx := context.Background()
+
+	key, err := Resolve(ctx, provider, "mcp-logging/tls#key")
+	if err != nil {
+		t.Fatalf("Failed to resolve secret: %v", err)
+	}
+	if string(key) != "KEY" {
+		t.Errorf("Expected KEY, got %q", key)
+	}
+
+	for _, name := range []string{"mcp-logging/missing", "mcp-logging/deleted"} {
+		if _, err := provider.GetSecret(ctx, name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestNewVaultProvider_RequiresAddressAndToken(t *testing.T) {
+	if _, err := NewVaultProvider(VaultConfig{Token: "test-token"}); err == nil {
+		t.Error("Expected error without an address")
+	}
+	if _, err := NewVaultProvider(VaultConfig{Address: "https://vault.example.com"}); err == nil {
+		t.Error("Expected error without a token")
+	}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/config"
+	"github.com/kerlexov/mcp-logging-server/pkg/ingestion"
+	"github.com/kerlexov/mcp-logging-server/pkg/secrets"
+	tlsconfig "github.com/kerlexov/mcp-logging-server/pkg/tls"
+)
+
+// NewSecretsProvider creates the provider of the configured secrets manager
+func NewSecretsProvider(cfg config.SecretsConfig) (secrets.Provider, error) {
+	switch cfg.Provider {
+	case "vault":
+		provider, err := secrets.NewVaultProvider(secrets.VaultConfig{
+			Address:   cfg.Vault.Address,
+			Token:     cfg.Vault.Token,
+			Namespace: cfg.Vault.Namespace,
+			Mount:     cfg.Vault.Mount,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	case "aws":
+		provider, err := secrets.NewAWSProvider(secrets.AWSConfig{
+			Region:          cfg.AWS.Region,
+			AccessKeyID:     cfg.AWS.AccessKeyID,
+			SecretAccessKey: cfg.AWS.SecretAccessKey,
+			SessionToken:    cfg.AWS.SessionToken,
+			Endpoint:        cfg.AWS.Endpoint,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	case "gcp":
+		return secrets.NewGCPProvider(secrets.GCPConfig{
+			Project:     cfg.GCP.Project,
+			AccessToken: cfg.GCP.AccessToken,
+			Endpoint:    cfg.GCP.Endpoint,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported secrets provider %q", cfg.Provider)
+	}
+}
+
+// secretsRefresher returns a refresher for the secrets of the configuration, or nil if no
+// secrets manager is configured
+func (s *Server) secretsRefresher() (*secrets.Refresher, error) {
+	provider := s.options.Secrets
+	if provider == nil {
+		if s.cfg.Secrets.Provider == "" {
+			return nil, nil
+		}
+		var err error
+		if provider, err = NewSecretsProvider(s.cfg.Secrets); err != nil {
+			return nil, err
+		}
+	}
+	return secrets.NewRefresher(provider, s.cfg.Secrets.RefreshInterval), nil
+}
+
+// loadEncryptionKey loads the storage encryption key from the secrets manager before the
+// storage is opened. The key cannot change while the storage is open: a changed key is
+// reported on every refresh until the server is restarted to rotate it.
+func (s *Server) loadEncryptionKey(ctx context.Context, refresher *secrets.Refresher) error {
+	if refresher == nil || s.cfg.Secrets.EncryptionKey == "" {
+		return nil
+	}
+
+	loaded := false
+	refresher.Watch(func(values [][]byte) error {
+		key := strings.TrimSpace(string(values[0]))
+		if key == "" {
+			return fmt.Errorf("encryption key is empty")
+		}
+		if loaded && key != s.cfg.Storage.Encryption.Key {
+			return fmt.Errorf("storage encryption key changed, restart the server to rotate it")
+		}
+		s.cfg.Storage.Encryption.Key = key
+		loaded = true
+		return nil
+	}, s.cfg.Secrets.EncryptionKey)
+	return refresher.Load(ctx)
+}
+
+// secretsTLSConfig returns the TLS configuration with a certificate store for the key pair in
+// the secrets manager, or the configured one if it does not hold the key pair
+func (s *Server) secretsTLSConfig() *tlsconfig.TLSConfig {
+	if s.cfg.Secrets.TLSCert == "" {
+		return s.options.TLS
+	}
+
+	tlsConfig := tlsconfig.DefaultTLSConfig()
+	if s.options.TLS != nil {
+		copied := *s.options.TLS
+		tlsConfig = &copied
+	}
+	tlsConfig.Certificates = tlsconfig.NewCertificateStore()
+	return tlsConfig
+}
+
+// watchSecrets registers the consumers of the configured secrets with the refresher
+func (s *Server) watchSecrets(refresher *secrets.Refresher, authManager *auth.APIKeyManager, tlsConfig *tlsconfig.TLSConfig, ingestionServer *ingestion.Server) {
+	cfg := s.cfg.Secrets
+
+	if cfg.APIKeys != "" {
+		refresher.Watch(func(values [][]byte) error {
+			keys, err := auth.ParseAPIKeyConfig(values[0])
+			if err != nil {
+				return err
+			}
+			authManager.SetConfig(keys)
+			return nil
+		}, cfg.APIKeys)
+	}
+
+	if cfg.TLSCert != "" {
+		refresher.Watch(func(values [][]byte) error {
+			return tlsConfig.Certificates.SetKeyPair(values[0], values[1])
+		}, cfg.TLSCert, cfg.TLSKey)
+	}
+
+	if cfg.HashSalt != "" {
+		refresher.Watch(func(values [][]byte) error {
+			if len(values[0]) == 0 {
+				return fmt.Errorf("hash salt is empty")
+			}
+			ingestionServer.SetHashSalt(string(values[0]))
+			return nil
+		}, cfg.HashSalt)
+	}
+}
@@ -0,0 +1,943 @@
+// Package server runs the complete logging server, ingestion API, MCP server and storage,
+// in-process, so that other Go programs can embed it for tests or edge deployments
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/accesslog"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/buffer"
+	"github.com/kerlexov/mcp-logging-server/pkg/config"
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+	"github.com/kerlexov/mcp-logging-server/pkg/ingestion"
+	"github.com/kerlexov/mcp-logging-server/pkg/journald"
+	"github.com/kerlexov/mcp-logging-server/pkg/kubernetes"
+	"github.com/kerlexov/mcp-logging-server/pkg/maintenance"
+	"github.com/kerlexov/mcp-logging-server/pkg/mcp"
+	"github.com/kerlexov/mcp-logging-server/pkg/metrics"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/preflight"
+	"github.com/kerlexov/mcp-logging-server/pkg/processor"
+	"github.com/kerlexov/mcp-logging-server/pkg/procs"
+	"github.com/kerlexov/mcp-logging-server/pkg/queryaudit"
+	"github.com/kerlexov/mcp-logging-server/pkg/ratelimit"
+	"github.com/kerlexov/mcp-logging-server/pkg/relay"
+	"github.com/kerlexov/mcp-logging-server/pkg/replication"
+	"github.com/kerlexov/mcp-logging-server/pkg/routing"
+	"github.com/kerlexov/mcp-logging-server/pkg/sampling"
+	"github.com/kerlexov/mcp-logging-server/pkg/secrets"
+	"github.com/kerlexov/mcp-logging-server/pkg/security"
+	"github.com/kerlexov/mcp-logging-server/pkg/sharding"
+	"github.com/kerlexov/mcp-logging-server/pkg/siem"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+	tlsconfig "github.com/kerlexov/mcp-logging-server/pkg/tls"
+	"github.com/kerlexov/mcp-logging-server/pkg/toolswitch"
+	"github.com/kerlexov/mcp-logging-server/pkg/validation"
+)
+
+// DefaultRecoveryDir is the directory for logs that could not be stored when none is configured
+const DefaultRecoveryDir = "./recovery"
+
+// Options configures the parts of the server that are not covered by config.Config, nil
+// values use the defaults of the respective package
+type Options struct {
+	Auth           *auth.APIKeyConfig // API keys, nil accepts every request
+	AuthPath       string             // API key file that changes made through the admin API are saved to
+	RateLimit      *ratelimit.RateLimitConfig
+	TLS            *tlsconfig.TLSConfig
+	Security       *security.SecurityConfig
+	DataProtection *dataprotection.DataProtectionConfig
+	RecoveryDir    string                   // Directory for logs that could not be stored, defaults to DefaultRecoveryDir
+	Symbolicators  []ingestion.Symbolicator // Resolve the frames of crash reports posted to /v1/crashes
+	Processors     []processor.Stage        // Enrich and filter ingested entries, before the processors of config.IngestionConfig
+	Secrets        secrets.Provider         // Reads the secrets of config.SecretsConfig instead of the configured secrets manager
+}
+
+// Server is an embeddable logging server
+type Server struct {
+	cfg     *config.Config
+	options Options
+}
+
+// New creates a server from the given configuration, nil uses config.DefaultConfig
+func New(cfg *config.Config) *Server {
+	return NewWithOptions(cfg, Options{})
+}
+
+// NewWithOptions creates a server from the given configuration and options
+func NewWithOptions(cfg *config.Config, options Options) *Server {
+	if cfg == nil {
+		cfg = config.DefaultConfig()
+	}
+	if options.RecoveryDir == "" {
+		options.RecoveryDir = DefaultRecoveryDir
+	}
+
+	return &Server{
+		cfg:     cfg,
+		options: options,
+	}
+}
+
+// Run opens the storage and runs the ingestion and MCP servers until ctx is done, which
+// returns nil, or until one of the servers fails. In relay mode only the ingestion server
+// runs and received logs are forwarded to the central server. Secrets configured in a secrets
+// manager are loaded before the servers start and then refreshed periodically.
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	refresher, err := s.secretsRefresher()
+	if err != nil {
+		return fmt.Errorf("failed to initialize secrets provider: %w", err)
+	}
+
+	if err := s.loadEncryptionKey(ctx, refresher); err != nil {
+		return fmt.Errorf("failed to load storage encryption key: %w", err)
+	}
+
+	store, err := OpenStorage(s.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	defer store.Close()
+
+	fallback, err := OpenFallbackStorage(s.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize fallback storage: %w", err)
+	}
+	if fallback != nil {
+		defer fallback.Close()
+	}
+
+	// A relay forwards each buffer flush as a single request, so a failed flush that the
+	// buffer retries never resends entries the central server already accepted
+	bufferSettings := bufferConfig(s.cfg.Buffer)
+	if forwarder, ok := store.(*relay.Forwarder); ok && bufferSettings.MaxBatchSize > forwarder.MaxBatchSize() {
+		bufferSettings.MaxBatchSize = forwarder.MaxBatchSize()
+	}
+
+	// API keys from a secrets manager are replaced on every refresh, so changes are not saved
+	authManager := auth.NewAPIKeyManager(s.options.Auth)
+	if s.cfg.Secrets.APIKeys == "" {
+		authManager.SetConfigPath(s.options.AuthPath)
+	}
+	tlsConfig := s.secretsTLSConfig()
+
+	forwarder, err := s.siemForwarder()
+	if err != nil {
+		return fmt.Errorf("failed to initialize SIEM forwarding: %w", err)
+	}
+
+	replicator, err := s.replicator()
+	if err != nil {
+		return fmt.Errorf("failed to initialize replication: %w", err)
+	}
+
+	shardRouter, err := s.shardRouter()
+	if err != nil {
+		return fmt.Errorf("failed to initialize sharding: %w", err)
+	}
+
+	routingEngine, sinks, err := s.routingEngine()
+	if err != nil {
+		return fmt.Errorf("failed to initialize routing rules: %w", err)
+	}
+	if shardRouter != nil {
+		defer shardRouter.Close()
+	}
+
+	sampler, err := s.sampler()
+	if err != nil {
+		return fmt.Errorf("invalid sampling policies: %w", err)
+	}
+
+	processors, err := s.processors()
+	if err != nil {
+		return fmt.Errorf("invalid processors: %w", err)
+	}
+	if processors != nil {
+		defer processors.Close()
+	}
+
+	rules := validationRules(s.cfg.Ingestion.Validation)
+	validator, err := validation.NewLogValidatorWithRules(s.cfg.Ingestion.Platforms, rules)
+	if err != nil {
+		return fmt.Errorf("invalid validation rules: %w", err)
+	}
+	// Platform filters are only checked when ingestion restricts the platforms of entries
+	platforms := validator.Platforms()
+	for _, name := range rules.Disabled {
+		if strings.TrimSpace(name) == validation.RulePlatform {
+			platforms = nil
+		}
+	}
+
+	// Stored batches drop the cached query results of their services
+	var resultCache *mcp.ResultCache
+	if !s.cfg.Relay.Enabled {
+		resultCache = mcp.NewResultCache(s.cfg.MCP.ResultCache.TTL, s.cfg.MCP.ResultCache.MaxEntries)
+	}
+	var commitListener buffer.CommitListener
+	if resultCache != nil {
+		commitListener = resultCache
+	}
+
+	queryAudit, err := s.queryAudit(store)
+	if err != nil {
+		return fmt.Errorf("failed to initialize query audit: %w", err)
+	}
+
+	accessLog, err := s.accessLog()
+	if err != nil {
+		return fmt.Errorf("failed to open access log: %w", err)
+	}
+	if accessLog != nil {
+		defer accessLog.Close()
+	}
+
+	// Switched at the ingestion server's /admin/mode, maintenance mode stops MCP tool calls too
+	modeSwitch := maintenance.New()
+
+	// Switched at the ingestion server's /admin/tools, a relay has no tools to switch
+	var toolSwitch *toolswitch.Switch
+	if !s.cfg.Relay.Enabled {
+		toolSwitch = toolswitch.New(s.cfg.MCP.DisabledTools)
+	}
+
+	// Served by the ingestion server at /metrics, the MCP server counts slow clients in them
+	metricsReporter := metrics.NewMetrics()
+
+	ingestionServer := ingestion.NewServerWithOptions(
+		s.cfg.Server.IngestionPort,
+		store,
+		bufferSettings,
+		s.options.RecoveryDir,
+		authManager,
+		s.options.RateLimit,
+		tlsConfig,
+		s.options.Security,
+		s.options.DataProtection,
+		ingestion.Options{
+			ClockSkew: &ingestion.ClockSkewConfig{
+				MaxSkew: s.cfg.Ingestion.MaxClockSkew,
+				Action:  ingestion.SkewAction(s.cfg.Ingestion.ClockSkewAction),
+			},
+			LevelAliases:   s.cfg.Ingestion.LevelAliases,
+			Platforms:      s.cfg.Ingestion.Platforms,
+			Validation:     rules,
+			Shipper:        shipperMapping(s.cfg.Ingestion.Shipper),
+			Symbolicators:  s.options.Symbolicators,
+			Host:           s.cfg.Server.Host,
+			SIEM:           forwarder,
+			Replicator:     replicator,
+			CommitListener: commitListener,
+			Router:         shardRouter,
+			Routing:        routingEngine,
+			Sampler:        sampler,
+			Processors:     processors,
+			Metrics:        metricsReporter,
+			QueryAudit:     queryAudit,
+			AccessLog:      accessLog,
+			Maintenance:    modeSwitch,
+			Tools:          toolSwitch,
+			Archival:       s.cfg.Storage.Archive.URL != "" && !s.cfg.Relay.Enabled,
+
+			MaxConcurrentRequests: maxRequests(s.cfg.Server.Concurrency),
+			MaxAsyncBatches:       s.cfg.Server.Concurrency.AsyncBatches,
+
+			Fallback:          fallback,
+			ReconcileInterval: s.cfg.Storage.Fallback.ReconcileInterval,
+		},
+	)
+
+	if refresher != nil {
+		s.watchSecrets(refresher, authManager, tlsConfig, ingestionServer)
+		if err := refresher.Load(ctx); err != nil {
+			return fmt.Errorf("failed to load secrets: %w", err)
+		}
+	}
+
+	// Runs once secrets are loaded, so that a TLS key pair from the secrets manager is checked
+	report, err := s.preflight(ctx, store, tlsConfig)
+	if err != nil {
+		return err
+	}
+	ingestionServer.SetPreflightReport(report)
+
+	servers := []func(context.Context) error{ingestionServer.Start}
+	if forwarder != nil {
+		servers = append(servers, forwarder.Run)
+	}
+	if replicator != nil {
+		servers = append(servers, replicator.Run)
+	}
+	for _, sink := range sinks {
+		servers = append(servers, sink.Run)
+	}
+
+	eventWatcher, err := s.kubernetesWatcher(ingestionServer)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Kubernetes event watch: %w", err)
+	}
+	if eventWatcher != nil {
+		servers = append(servers, eventWatcher.Run)
+	}
+
+	journalReader, err := s.journalReader(ingestionServer)
+	if err != nil {
+		return fmt.Errorf("failed to initialize journal reader: %w", err)
+	}
+	if journalReader != nil {
+		servers = append(servers, journalReader.Run)
+	}
+
+	compactor, err := s.compactor(store)
+	if err != nil {
+		return fmt.Errorf("failed to initialize log compaction: %w", err)
+	}
+	if compactor != nil {
+		servers = append(servers, compactor.Run)
+	}
+
+	archiver, err := s.walArchiver(store)
+	if err != nil {
+		return fmt.Errorf("failed to initialize WAL archiving: %w", err)
+	}
+	if archiver != nil {
+		servers = append(servers, archiver.Run)
+	}
+	if queryAudit != nil {
+		servers = append(servers, queryAudit.Run)
+	}
+
+	// A relay keeps no logs to query, so it only runs the ingestion server
+	if !s.cfg.Relay.Enabled {
+		mcpServer := mcp.NewServerWithOptions(s.cfg.Server.MCPPort, store, mcp.Options{
+			DefaultTimeout:      s.cfg.MCP.QueryTimeout,
+			ToolTimeouts:        s.cfg.MCP.ToolTimeouts,
+			SlowQueryThreshold:  s.cfg.MCP.SlowQueryThreshold,
+			Host:                s.cfg.Server.Host,
+			Platforms:           platforms,
+			Retention:           retentionPolicy(s.cfg.Retention),
+			CompactionAfter:     s.cfg.Retention.Compaction.After,
+			CompactionLevels:    logLevels(s.cfg.Retention.Compaction.Levels),
+			ServiceStaleAfter:   s.cfg.MCP.ServiceCatalog.StaleAfter,
+			ServiceHideAfter:    s.cfg.MCP.ServiceCatalog.HideAfter,
+			ResultCache:         resultCache,
+			WriteTimeout:        s.cfg.MCP.WriteTimeout,
+			MaxPendingResponses: s.cfg.MCP.MaxPendingResponses,
+			Metrics:             metricsReporter,
+			QueryAudit:          queryAudit,
+			Maintenance:         modeSwitch,
+			Tools:               toolSwitch,
+			Masking: &dataprotection.Masker{
+				RevealChars:       s.cfg.MCP.Masking.RevealChars,
+				Token:             s.cfg.MCP.Masking.Token,
+				RepeatToken:       s.cfg.MCP.Masking.RepeatToken,
+				FullMaskThreshold: s.cfg.MCP.Masking.FullMaskThreshold,
+			},
+		})
+		if unknown := toolSwitch.Unregistered(); len(unknown) > 0 {
+			return fmt.Errorf("unknown tools in mcp.disabled_tools: %s", strings.Join(unknown, ", "))
+		}
+		servers = append(servers, mcpServer.Start)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if refresher != nil {
+		go refresher.Run(ctx)
+	}
+
+	errs := make(chan error, len(servers))
+	for _, start := range servers {
+		go func(start func(context.Context) error) {
+			errs <- start(ctx)
+		}(start)
+	}
+
+	// Stop all servers as soon as any one returns
+	err = <-errs
+	stoppedEarly := ctx.Err() == nil
+	cancel()
+	for i := 1; i < len(servers); i++ {
+		<-errs
+	}
+
+	if !stoppedEarly {
+		return nil
+	}
+	if err == nil {
+		err = errors.New("server stopped unexpectedly")
+	}
+	return err
+}
+
+// preflight runs the startup self-test, nil if it is turned off. In strict mode a failed check
+// keeps the server from starting, in degraded mode it starts and reports the failure in /health.
+func (s *Server) preflight(ctx context.Context, store storage.LogStorage, tlsConfig *tlsconfig.TLSConfig) (*preflight.Report, error) {
+	cfg := s.cfg.Server.Preflight
+	if cfg.Mode == "off" {
+		return nil, nil
+	}
+
+	options := preflight.Options{
+		RecoveryDir: s.options.RecoveryDir,
+		TLS:         tlsConfig,
+		Timeout:     cfg.Timeout,
+	}
+	// A relay would forward the probe entry to the central server
+	if !s.cfg.Relay.Enabled {
+		options.Storage = store
+	}
+
+	report := preflight.Run(ctx, options)
+	if err := report.Err(); err != nil {
+		if cfg.Mode != "degraded" {
+			return nil, err
+		}
+		log.Printf("Warning: starting degraded, %v", err)
+	}
+	return report, nil
+}
+
+// compactor creates the job replacing old low-severity entries with summaries, nil if compaction
+// is disabled or in relay mode
+func (s *Server) compactor(store storage.LogStorage) (*storage.Compactor, error) {
+	cfg := s.cfg.Retention.Compaction
+	if cfg.After <= 0 || s.cfg.Relay.Enabled {
+		return nil, nil
+	}
+
+	return storage.NewCompactor(store, storage.CompactionPolicy{
+		After:    cfg.After,
+		Levels:   logLevels(cfg.Levels),
+		Interval: cfg.Interval,
+	})
+}
+
+// queryAudit creates the recorder of the query audit trail, nil if the audit is disabled or in
+// relay mode
+func (s *Server) queryAudit(store storage.LogStorage) (*queryaudit.Recorder, error) {
+	cfg := s.cfg.QueryAudit
+	if !cfg.Enabled || s.cfg.Relay.Enabled {
+		return nil, nil
+	}
+
+	auditStore, ok := store.(storage.QueryAuditStore)
+	if !ok {
+		return nil, fmt.Errorf("storage %s does not keep a query audit", s.cfg.Storage.Type)
+	}
+	return queryaudit.NewRecorder(auditStore, cfg.Retention), nil
+}
+
+// accessLog opens the access log of ingestion requests, nil if it is disabled
+func (s *Server) accessLog() (*accesslog.Logger, error) {
+	cfg := s.cfg.Server.AccessLog
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return accesslog.New(accesslog.Config{
+		Path:       cfg.Path,
+		MaxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+		MaxBackups: cfg.MaxBackups,
+	})
+}
+
+// walArchiver creates the job archiving the SQLite database for point-in-time recovery, nil
+// if archiving is disabled or in relay mode
+func (s *Server) walArchiver(store storage.LogStorage) (*storage.WALArchiver, error) {
+	cfg := s.cfg.Storage.Archive
+	if cfg.URL == "" || s.cfg.Relay.Enabled {
+		return nil, nil
+	}
+
+	objectStore, err := OpenArchiveStore(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	return storage.NewWALArchiver(store, storage.WALArchivePolicy{
+		Store:            objectStore,
+		Interval:         cfg.Interval,
+		SnapshotInterval: cfg.SnapshotInterval,
+		Retention:        cfg.Retention,
+	})
+}
+
+// OpenArchiveStore opens the object store of the configured WAL archive. S3 credentials and
+// region default to those of the AWS secrets provider.
+func OpenArchiveStore(cfg *config.Config) (storage.ObjectStore, error) {
+	archive := cfg.Storage.Archive
+	s3 := storage.S3Config{
+		Region:          archive.S3.Region,
+		Endpoint:        archive.S3.Endpoint,
+		AccessKeyID:     archive.S3.AccessKeyID,
+		SecretAccessKey: archive.S3.SecretAccessKey,
+		SessionToken:    archive.S3.SessionToken,
+	}
+	if s3.Region == "" {
+		s3.Region = cfg.Secrets.AWS.Region
+	}
+	if s3.AccessKeyID == "" {
+		s3.AccessKeyID = cfg.Secrets.AWS.AccessKeyID
+		s3.SecretAccessKey = cfg.Secrets.AWS.SecretAccessKey
+		s3.SessionToken = cfg.Secrets.AWS.SessionToken
+	}
+	return storage.OpenObjectStore(archive.URL, s3)
+}
+
+// logLevels converts configured level names to log levels
+func logLevels(names []string) []models.LogLevel {
+	levels := make([]models.LogLevel, len(names))
+	for i, name := range names {
+		levels[i] = models.LogLevel(name)
+	}
+	return levels
+}
+
+// retentionPolicy converts the retention configuration to the policy of the storage
+func retentionPolicy(cfg config.RetentionConfig) storage.RetentionPolicy {
+	policy := storage.RetentionPolicy{
+		DefaultDays: cfg.DefaultDays,
+		ByLevel:     make(map[models.LogLevel]int, len(cfg.ByLevel)),
+	}
+	for level, days := range cfg.ByLevel {
+		policy.ByLevel[models.LogLevel(level)] = days
+	}
+	return policy
+}
+
+// kubernetesWatcher creates the watch ingesting Kubernetes Events, nil if it is disabled. A
+// relay forwards the events to the central server like any other entries.
+func (s *Server) kubernetesWatcher(ingester kubernetes.Ingester) (*kubernetes.Watcher, error) {
+	cfg := s.cfg.Kubernetes
+	if !cfg.Events {
+		return nil, nil
+	}
+	return kubernetes.NewWatcher(kubernetes.Config{
+		APIServer: cfg.APIServer,
+		TokenFile: cfg.TokenFile,
+		CAFile:    cfg.CAFile,
+		Namespace: cfg.Namespace,
+		Platform:  models.Platform(cfg.Platform),
+	}, ingester)
+}
+
+// journalReader creates the reader ingesting the systemd journal, nil if it is disabled
+func (s *Server) journalReader(ingester journald.Ingester) (*journald.Reader, error) {
+	cfg := s.cfg.Journald
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return journald.NewReader(journald.Config{
+		Command:       cfg.Command,
+		Directory:     cfg.Directory,
+		Units:         cfg.Units,
+		CursorFile:    cfg.CursorFile,
+		BatchSize:     cfg.BatchSize,
+		FlushInterval: cfg.FlushInterval,
+		Platform:      models.Platform(cfg.Platform),
+	}, ingester)
+}
+
+// siemForwarder creates the forwarder of audit events to the configured SIEM, nil if none is
+// configured
+func (s *Server) siemForwarder() (*siem.Forwarder, error) {
+	cfg := s.cfg.SIEM
+	if cfg.Protocol == "" {
+		return nil, nil
+	}
+	if cfg.Protocol == siem.ProtocolHTTPS && !strings.HasPrefix(cfg.URL, "https://") {
+		log.Printf("Warning: SIEM URL %s does not use TLS, audit events are forwarded unencrypted", cfg.URL)
+	}
+	return siem.NewForwarder(siem.Config{
+		Protocol:     cfg.Protocol,
+		Address:      cfg.Address,
+		Network:      cfg.Network,
+		URL:          cfg.URL,
+		Token:        cfg.Token,
+		CAFile:       cfg.CAFile,
+		Timeout:      cfg.Timeout,
+		QueueSize:    cfg.QueueSize,
+		MaxRetries:   cfg.MaxRetries,
+		RetryBackoff: cfg.RetryBackoff,
+	})
+}
+
+// replicator creates the replicator of stored batches to the configured peers, nil if none are
+// configured
+func (s *Server) replicator() (*replication.Replicator, error) {
+	cfg := s.cfg.Replication
+	if len(cfg.Peers) == 0 {
+		return nil, nil
+	}
+
+	peers := make([]replication.PeerConfig, len(cfg.Peers))
+	for i, peer := range cfg.Peers {
+		if !strings.HasPrefix(peer.URL, "https://") {
+			log.Printf("Warning: replication peer URL %s does not use TLS, logs are replicated unencrypted", peer.URL)
+		}
+		peers[i] = replication.PeerConfig{
+			Name:          peer.Name,
+			URL:           peer.URL,
+			APIKey:        peer.APIKey,
+			SigningSecret: peer.SigningSecret,
+			CAFile:        peer.CAFile,
+		}
+	}
+	return replication.NewReplicator(replication.Config{
+		ServerID:     cfg.ServerID,
+		Peers:        peers,
+		Timeout:      cfg.Timeout,
+		QueueSize:    cfg.QueueSize,
+		RetryBackoff: cfg.RetryBackoff,
+	})
+}
+
+// routingEngine creates the engine applying the routing rules and a replicator delivering the
+// entries routed to each sink, which run alongside the servers
+func (s *Server) routingEngine() (*routing.Engine, []*replication.Replicator, error) {
+	cfg := s.cfg.Ingestion.Routing
+
+	// Sinks reject entries sent with their own server ID as origin, so it must identify this one
+	serverID := s.cfg.Replication.ServerID
+	if serverID == "" {
+		serverID, _ = os.Hostname()
+	}
+
+	sinks := make(map[string]routing.Sink, len(cfg.Sinks))
+	replicators := make([]*replication.Replicator, 0, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		if _, ok := sinks[sink.Name]; ok {
+			return nil, nil, fmt.Errorf("duplicate routing sink %q", sink.Name)
+		}
+		if !strings.HasPrefix(sink.URL, "https://") {
+			log.Printf("Warning: routing sink URL %s does not use TLS, logs are routed unencrypted", sink.URL)
+		}
+		replicator, err := replication.NewReplicator(replication.Config{
+			ServerID: serverID,
+			Peers: []replication.PeerConfig{{
+				Name:          sink.Name,
+				URL:           sink.URL,
+				APIKey:        sink.APIKey,
+				SigningSecret: sink.SigningSecret,
+				CAFile:        sink.CAFile,
+			}},
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("routing sink %q: %w", sink.Name, err)
+		}
+		sinks[sink.Name] = replicator
+		replicators = append(replicators, replicator)
+	}
+
+	rules := make([]routing.Rule, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		rules[i] = routing.Rule{
+			Name: rule.Name,
+			Match: routing.Match{
+				Services:   rule.Match.Services,
+				Levels:     logLevels(rule.Match.Levels),
+				Metadata:   rule.Match.Metadata,
+				Expression: rule.Match.Expression,
+			},
+			Action: rule.Action,
+			Tags:   rule.Tags,
+			Set:    rule.Set,
+			Level:  models.LogLevel(rule.Level),
+			Sink:   rule.Sink,
+		}
+	}
+
+	engine, err := routing.New(rules, sinks)
+	if err != nil {
+		return nil, nil, err
+	}
+	return engine, replicators, nil
+}
+
+// sampler creates the sampler applying the sampling policies of noisy services
+func (s *Server) sampler() (*sampling.Sampler, error) {
+	policies := make([]sampling.Policy, len(s.cfg.Ingestion.Sampling.Policies))
+	for i, policy := range s.cfg.Ingestion.Sampling.Policies {
+		rates := make(map[models.LogLevel]float64, len(policy.Rates))
+		for level, rate := range policy.Rates {
+			rates[models.LogLevel(level)] = rate
+		}
+		policies[i] = sampling.Policy{Service: policy.Service, Rates: rates}
+	}
+	return sampling.New(policies)
+}
+
+// processors creates the chain of the processors of Options followed by the configured external
+// processors, nil if there are none
+func (s *Server) processors() (*processor.Chain, error) {
+	stages := append([]processor.Stage(nil), s.options.Processors...)
+	for _, cfg := range s.cfg.Ingestion.Processors {
+		command, err := processor.NewExec(cfg.Name, processor.ExecConfig{
+			Command: cfg.Command,
+			Args:    cfg.Args,
+			Env:     cfg.Env,
+			Workers: procs.Workers(cfg.Workers),
+		})
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, processor.Stage{
+			Name:      cfg.Name,
+			Processor: command,
+			OnError:   processor.ErrorPolicy(cfg.OnError),
+			Timeout:   cfg.Timeout,
+		})
+	}
+	if len(stages) == 0 {
+		return nil, nil
+	}
+	return processor.NewChain(stages)
+}
+
+// shardRouter creates the router sending entries to the node owning their service, nil if
+// sharding is not configured
+func (s *Server) shardRouter() (*sharding.Router, error) {
+	cfg := s.cfg.Sharding
+	if len(cfg.Nodes) == 0 {
+		return nil, nil
+	}
+
+	nodes := make([]sharding.Node, len(cfg.Nodes))
+	for i, node := range cfg.Nodes {
+		if node.ID != cfg.NodeID && !strings.HasPrefix(node.URL, "https://") {
+			log.Printf("Warning: shard node URL %s does not use TLS, logs are routed unencrypted", node.URL)
+		}
+		nodes[i] = sharding.Node{
+			ID:            node.ID,
+			URL:           node.URL,
+			APIKey:        node.APIKey,
+			SigningSecret: node.SigningSecret,
+			CAFile:        node.CAFile,
+		}
+	}
+	return sharding.NewRouter(sharding.Config{
+		NodeID:       cfg.NodeID,
+		Nodes:        nodes,
+		VirtualNodes: cfg.VirtualNodes,
+		Timeout:      cfg.Timeout,
+	})
+}
+
+// OpenStorage opens the storage described by the configuration, with search and the
+// configured storage options enabled. In relay mode it returns a forwarder to the central
+// server instead.
+func OpenStorage(cfg *config.Config) (storage.LogStorage, error) {
+	if cfg.Relay.Enabled {
+		if !strings.HasPrefix(cfg.Relay.URL, "https://") {
+			log.Printf("Warning: relay URL %s does not use TLS, logs are forwarded unencrypted", cfg.Relay.URL)
+		}
+		return relay.NewForwarder(relay.Config{
+			URL:           cfg.Relay.URL,
+			APIKey:        cfg.Relay.APIKey,
+			SigningSecret: cfg.Relay.SigningSecret,
+			CAFile:        cfg.Relay.CAFile,
+			Timeout:       cfg.Relay.Timeout,
+			MaxRetries:    cfg.Relay.MaxRetries,
+			RetryBackoff:  cfg.Relay.RetryBackoff,
+			MaxBatchSize:  cfg.Relay.MaxBatchSize,
+		})
+	}
+
+	if cfg.Storage.Type == "memory" {
+		return storage.NewMemoryStorageWithConfig(storage.MemoryConfig{
+			MaxEntries:       cfg.Storage.MaxEntries,
+			SnapshotPath:     cfg.Storage.SnapshotPath,
+			SnapshotInterval: cfg.Storage.SnapshotInterval,
+		})
+	}
+
+	// Encrypted entries must be readable before the storage opens, to rebuild the search index
+	encryptionKey, err := registerEncryptionKeys(cfg.Storage.Encryption)
+	if err != nil {
+		return nil, err
+	}
+
+	searchConfig := storage.SearchConfig{
+		ShardDuration: cfg.Indexing.ShardDuration,
+		EncryptionKey: encryptionKey,
+		IndexWorkers:  procs.Workers(cfg.Server.Concurrency.IndexWorkers),
+	}
+	if cfg.Indexing.Enabled && cfg.Indexing.FullTextSearch {
+		searchConfig.IndexPath = cfg.Indexing.IndexPath
+	}
+
+	store, err := storage.NewSQLiteStorageWithSearchConfig(cfg.Storage.ConnectionString, searchConfig)
+	if err != nil {
+		return nil, err
+	}
+	if encryptionKey != nil {
+		if err := store.SetEncryption(encryptionKey); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+
+	store.SetSlowQueryThreshold(cfg.Storage.SlowQueryThreshold)
+	store.SetIntegrityHashing(cfg.Storage.IntegrityHashing, cfg.Storage.IntegrityKey)
+	store.SetImmutableWindow(cfg.Storage.ImmutableWindow)
+	if err := store.SetCompression(compressionConfig(cfg.Storage.Compression)); err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	if cfg.Storage.ReadConnectionString != "" {
+		if err := store.SetReadReplica(cfg.Storage.ReadConnectionString, cfg.Storage.MaxReplicaLag); err != nil {
+			store.Close()
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// OpenFallbackStorage opens the storage that receives buffer flushes while the primary storage
+// is failing. It returns nil if no fallback is configured or in relay mode, where the central
+// server is the only storage.
+func OpenFallbackStorage(cfg *config.Config) (storage.LogStorage, error) {
+	if cfg.Relay.Enabled {
+		return nil, nil
+	}
+
+	switch cfg.Storage.Fallback.Type {
+	case "":
+		return nil, nil
+	case "memory":
+		return storage.NewMemoryStorage(), nil
+	default:
+		store, err := storage.NewSQLiteStorage(cfg.Storage.Fallback.ConnectionString)
+		if err != nil {
+			return nil, err
+		}
+		if err := store.SetCompression(compressionConfig(cfg.Storage.Fallback.Compression)); err != nil {
+			store.Close()
+			return nil, err
+		}
+		// Entries wait in the fallback storage until they are copied back, so they are encrypted as well
+		encryptionKey, err := registerEncryptionKeys(cfg.Storage.Encryption)
+		if err == nil && encryptionKey != nil {
+			err = store.SetEncryption(encryptionKey)
+		}
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		return store, nil
+	}
+}
+
+// registerEncryptionKeys registers the configured encryption keys for decryption and returns the
+// key new entries are encrypted with, nil if encryption is disabled
+func registerEncryptionKeys(cfg config.EncryptionConfig) ([]byte, error) {
+	if cfg.Key == "" {
+		return nil, nil
+	}
+	key, err := storage.ParseEncryptionKey(cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+	keys := [][]byte{key}
+	for _, oldKey := range cfg.OldKeys {
+		parsed, err := storage.ParseEncryptionKey(oldKey)
+		if err != nil {
+			return nil, fmt.Errorf("old key: %w", err)
+		}
+		keys = append(keys, parsed)
+	}
+	if err := storage.RegisterEncryptionKeys(keys...); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// compressionConfig converts the column compression configuration to the SQLite storage settings
+func compressionConfig(cfg config.ColumnCompressionConfig) storage.CompressionConfig {
+	return storage.CompressionConfig{
+		Columns: cfg.Columns,
+		MinSize: cfg.MinSize,
+	}
+}
+
+// requestsPerCPU is the number of ingestion requests processed at once per CPU by default
+const requestsPerCPU = 16
+
+// maxRequests returns the number of ingestion requests processed at once
+func maxRequests(cfg config.ConcurrencyConfig) int {
+	if cfg.MaxRequests > 0 {
+		return cfg.MaxRequests
+	}
+	return requestsPerCPU * procs.Workers(0)
+}
+
+// bufferConfig converts the buffer configuration to the message buffer settings
+func bufferConfig(cfg config.BufferConfig) buffer.Config {
+	bufferConfig := buffer.Config{
+		Size:            cfg.Size,
+		MaxBytes:        cfg.MaxBytes,
+		MaxBatchSize:    cfg.MaxBatchSize,
+		FlushTimeout:    cfg.FlushTimeout,
+		MaxServiceShare: cfg.MaxServiceShare,
+		EvictionPolicy:  buffer.EvictionPolicy(cfg.EvictionPolicy),
+
+		FlushWorkers:      procs.Workers(cfg.FlushWorkers),
+		OrderedPerService: cfg.OrderedPerService,
+	}
+	if cfg.Adaptive {
+		bufferConfig.Adaptive = &buffer.AdaptiveConfig{
+			MinBatchSize:     cfg.MinBatchSize,
+			MinFlushInterval: cfg.MinFlushInterval,
+			TargetLatency:    cfg.TargetLatency,
+		}
+	}
+	return bufferConfig
+}
+
+// shipperMapping converts the shipper configuration to the record mapping of the ingestion server
+func shipperMapping(cfg config.ShipperConfig) *ingestion.ShipperMapping {
+	return &ingestion.ShipperMapping{
+		TimestampKeys:   cfg.TimestampKeys,
+		LevelKeys:       cfg.LevelKeys,
+		MessageKeys:     cfg.MessageKeys,
+		ServiceKeys:     cfg.ServiceKeys,
+		AgentKeys:       cfg.AgentKeys,
+		DefaultService:  cfg.DefaultService,
+		DefaultPlatform: models.Platform(cfg.DefaultPlatform),
+	}
+}
+
+// validationRules converts the validation configuration to the rules of the log validator
+func validationRules(cfg config.ValidationConfig) validation.Rules {
+	rules := validation.Rules{
+		MaxMessageLength:    cfg.MaxMessageLength,
+		RequiredFields:      cfg.RequiredFields,
+		MaxFutureSkew:       cfg.MaxFutureSkew,
+		MaxAge:              cfg.MaxAge,
+		MaxMetadataKeys:     cfg.MaxMetadataKeys,
+		MaxStackTraceLength: cfg.MaxStackTraceLength,
+		Disabled:            cfg.DisabledRules,
+	}
+	for _, fieldRule := range cfg.FieldRules {
+		rules.FieldRules = append(rules.FieldRules, validation.FieldRule{
+			Name:      fieldRule.Name,
+			Field:     fieldRule.Field,
+			Pattern:   fieldRule.Pattern,
+			MaxLength: fieldRule.MaxLength,
+			OneOf:     fieldRule.OneOf,
+		})
+	}
+	return rules
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/config"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/secrets"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// freePort returns a TCP port that is currently unused
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestServer_Run(t *testing.T) {
+	cfg := config.DevConfig()
+	cfg.Server.IngestionPort = freePort(t)
+	cfg.Server.MCPPort = freePort(t)
+	cfg.Buffer.FlushTimeout = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewWithOptions(cfg, Options{RecoveryDir: t.TempDir()}).Run(ctx)
+	}()
+
+	ingestionURL := fmt.Sprintf("http://localhost:%d", cfg.Server.IngestionPort)
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = http.Post(ingestionURL+"/v1/logs", "application/json", strings.NewReader(
+			`{"level": "INFO", "message": "Embedded server", "service_name": "embedded-service", "agent_id": "test-agent", "platform": "go"}`,
+		))
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Ingestion server did not start: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", cfg.Server.MCPPort))
+	if err != nil {
+		t.Fatalf("MCP server did not start: %v", err)
+	}
+	defer conn.Close()
+
+	// The entry becomes queryable over MCP once the buffer is flushed
+	reader := bufio.NewReader(conn)
+	found := false
+	for i := 0; i < 30 && !found; i++ {
+		request := `{"jsonrpc": "2.0", "id": 1, "method": "tools/call", "params": {"name": "list_services", "arguments": {}}}` + "\n"
+		if _, err := conn.Write([]byte(request)); err != nil {
+			t.Fatalf("Failed to send MCP request: %v", err)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read MCP response: %v", err)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			t.Fatalf("Failed to parse MCP response: %v", err)
+		}
+		found = strings.Contains(line, "embedded-service")
+		if !found {
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	if !found {
+		t.Error("Expected the ingested entry's service to be listed over MCP")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Server did not stop after the context was cancelled")
+	}
+}
+
+func TestServer_RunInvalidConfig(t *testing.T) {
+	cfg := config.DevConfig()
+	cfg.Server.MCPPort = cfg.Server.IngestionPort
+
+	if err := New(cfg).Run(context.Background()); err == nil {
+		t.Error("Expected an error for conflicting ports")
+	}
+}
+
+func TestServer_RunPreflightFailure(t *testing.T) {
+	cfg := config.DevConfig()
+	cfg.Server.IngestionPort = freePort(t)
+	cfg.Server.MCPPort = freePort(t)
+
+	// A file where the recovery directory should be
+	recoveryDir := filepath.Join(t.TempDir(), "recovery")
+	if err := os.WriteFile(recoveryDir, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	err := NewWithOptions(cfg, Options{RecoveryDir: recoveryDir}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "recovery_dir") {
+		t.Errorf("Expected the failed recovery directory check to stop the server, got %v", err)
+	}
+}
+
+func TestOpenStorage_Memory(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Storage.Type = "memory"
+	cfg.Storage.ConnectionString = ""
+	cfg.Storage.MaxEntries = 10
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Expected memory storage without connection string to be valid: %v", err)
+	}
+
+	store, err := OpenStorage(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	if _, ok := store.(*storage.MemoryStorage); !ok {
+		t.Errorf("Expected memory storage, got %T", store)
+	}
+	if max := store.HealthCheck(context.Background()).Details["max_entries"]; max != "10" {
+		t.Errorf("Expected max_entries 10, got %s", max)
+	}
+}
+
+func TestOpenStorage_Encryption(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.DefaultConfig()
+	cfg.Storage.ConnectionString = filepath.Join(dir, "logs.db")
+	cfg.Indexing.IndexPath = filepath.Join(dir, "index")
+	cfg.Storage.Encryption.Key = strings.Repeat("ab", 32)
+
+	store, err := OpenStorage(cfg)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	defer store.Close()
+
+	entry := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelInfo,
+		Message:     "patient record opened",
+		ServiceName: "records",
+		AgentID:     "agent-1",
+		Platform:    models.PlatformGo,
+	}
+	if err := store.Store(context.Background(), []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+	logs, err := store.GetByIDs(context.Background(), []string{entry.ID})
+	if err != nil || len(logs) != 1 || logs[0].Message != entry.Message {
+		t.Fatalf("Expected the entry to read back, got %v (%v)", logs, err)
+	}
+
+	for _, file := range []string{cfg.Storage.ConnectionString, cfg.Storage.ConnectionString + "-wal"} {
+		data, _ := os.ReadFile(file)
+		if strings.Contains(string(data), "patient record") {
+			t.Errorf("Expected the message to be encrypted in %s", file)
+		}
+	}
+	filepath.WalkDir(cfg.Indexing.IndexPath, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if data, _ := os.ReadFile(path); strings.Contains(string(data), "patient") {
+				t.Errorf("Expected the search index not to contain the message in %s", path)
+			}
+		}
+		return nil
+	})
+
+	cfg.Storage.Encryption.Key = "not-a-key"
+	if _, err := OpenStorage(cfg); err == nil {
+		t.Error("Expected an invalid key to fail")
+	}
+}
+
+func TestServer_RunRelay(t *testing.T) {
+	received := make(chan []models.LogEntry, 10)
+	central := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []models.LogEntry
+		json.NewDecoder(r.Body).Decode(&batch)
+		received <- batch
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer central.Close()
+
+	cfg := config.DevConfig()
+	cfg.Server.IngestionPort = freePort(t)
+	cfg.Server.MCPPort = freePort(t)
+	cfg.Buffer.FlushTimeout = time.Second
+	cfg.Relay.Enabled = true
+	cfg.Relay.URL = central.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewWithOptions(cfg, Options{RecoveryDir: t.TempDir()}).Run(ctx)
+	}()
+
+	ingestionURL := fmt.Sprintf("http://localhost:%d", cfg.Server.IngestionPort)
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = http.Post(ingestionURL+"/v1/logs", "application/json", strings.NewReader(
+			`{"level": "INFO", "message": "Relayed entry", "service_name": "edge-service", "agent_id": "edge-agent", "platform": "go"}`,
+		))
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Ingestion server did not start: %v", err)
+	}
+	resp.Body.Close()
+
+	// The entry reaches the central server once the buffer is flushed
+	select {
+	case batch := <-received:
+		if len(batch) != 1 || batch[0].ServiceName != "edge-service" {
+			t.Errorf("Unexpected forwarded batch: %+v", batch)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Entry was not forwarded to the central server")
+	}
+
+	// A relay does not serve MCP queries
+	if conn, err := net.Dial("tcp", fmt.Sprintf("localhost:%d", cfg.Server.MCPPort)); err == nil {
+		conn.Close()
+		t.Error("Expected the MCP server not to run in relay mode")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Server did not stop after the context was cancelled")
+	}
+}
+
+func TestServer_RunSecrets(t *testing.T) {
+	cfg := config.DevConfig()
+	cfg.Server.IngestionPort = freePort(t)
+	cfg.Server.MCPPort = freePort(t)
+	cfg.Secrets.APIKeys = "mcp-logging/api-keys"
+	cfg.Secrets.HashSalt = "mcp-logging/data-protection#salt"
+
+	apiKey := "mcp_secret_key"
+	keys := fmt.Sprintf("require_auth: true\napi_keys:\n  %s:\n    name: from-secrets\n    permissions: [ingest_logs]\n    is_active: true\n",
+		auth.NewAPIKeyManager(nil).HashAPIKey(apiKey))
+	provider := secrets.ProviderFunc(func(ctx context.Context, name string) ([]byte, error) {
+		switch name {
+		case "mcp-logging/api-keys":
+			return []byte(keys), nil
+		case "mcp-logging/data-protection":
+			return []byte(`{"salt": "pepper"}`), nil
+		}
+		return nil, secrets.ErrNotFound
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- NewWithOptions(cfg, Options{RecoveryDir: t.TempDir(), Secrets: provider}).Run(ctx)
+	}()
+
+	post := func(key string) (int, error) {
+		req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:%d/v1/logs", cfg.Server.IngestionPort), strings.NewReader(
+			`{"level": "INFO", "message": "Secrets", "service_name": "secrets-service", "agent_id": "test-agent", "platform": "go"}`,
+		))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	var status int
+	var err error
+	for i := 0; i < 50; i++ {
+		if status, err = post(""); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("Ingestion server did not start: %v", err)
+	}
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without an API key, got %d", http.StatusUnauthorized, status)
+	}
+
+	if status, err = post(apiKey); err != nil || status != http.StatusCreated {
+		t.Errorf("Expected status %d with the API key from the secrets manager, got %d (%v)", http.StatusCreated, status, err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected nil error on shutdown, got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Server did not stop after the context was cancelled")
+	}
+}
+
+func TestServer_RunMissingSecret(t *testing.T) {
+	cfg := config.DevConfig()
+	cfg.Server.IngestionPort = freePort(t)
+	cfg.Server.MCPPort = freePort(t)
+	cfg.Secrets.HashSalt = "missing"
+
+	provider := secrets.ProviderFunc(func(ctx context.Context, name string) ([]byte, error) {
+		return nil, secrets.ErrNotFound
+	})
+	err := NewWithOptions(cfg, Options{RecoveryDir: t.TempDir(), Secrets: provider}).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "failed to load secrets") {
+		t.Errorf("Expected the missing secret to stop the server, got %v", err)
+	}
+}
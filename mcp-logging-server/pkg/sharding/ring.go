@@ -0,0 +1,83 @@
+// Package sharding assigns services to the writer nodes of a cluster by consistent hashing of
+// the service name, so that nodes share the write load while all entries of a service are
+// stored by the same node
+package sharding
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// DefaultVirtualNodes is the number of points each node has on the ring when none is configured
+const DefaultVirtualNodes = 128
+
+// Ring is a consistent hash ring of nodes. Each node is placed at several points, its virtual
+// nodes, and owns the services hashing to the arc before each of them, so adding or removing a
+// node only moves the services of that node.
+type Ring struct {
+	points []uint64 // Sorted hashes of the virtual nodes
+	owners []string // Node ID of each point
+}
+
+// NewRing creates a ring of the given node IDs with virtualNodes points per node
+func NewRing(nodeIDs []string, virtualNodes int) *Ring {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultVirtualNodes
+	}
+
+	type point struct {
+		hash  uint64
+		owner string
+	}
+	points := make([]point, 0, len(nodeIDs)*virtualNodes)
+	for _, id := range nodeIDs {
+		for i := 0; i < virtualNodes; i++ {
+			points = append(points, point{hash: hashKey(id + "#" + strconv.Itoa(i)), owner: id})
+		}
+	}
+	// Ties are broken by node ID, so that every node builds the same ring
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].hash != points[j].hash {
+			return points[i].hash < points[j].hash
+		}
+		return points[i].owner < points[j].owner
+	})
+
+	ring := &Ring{
+		points: make([]uint64, len(points)),
+		owners: make([]string, len(points)),
+	}
+	for i, p := range points {
+		ring.points[i] = p.hash
+		ring.owners[i] = p.owner
+	}
+	return ring
+}
+
+// Owner returns the ID of the node owning a service, empty for a ring without nodes
+func (r *Ring) Owner(service string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	hash := hashKey(service)
+	i := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i] >= hash
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[i]
+}
+
+// hashKey hashes a service name or virtual node onto the ring
+func hashKey(key string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	// FNV spreads similar short keys poorly over the high bits, mix them in
+	sum := hash.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	return sum
+}
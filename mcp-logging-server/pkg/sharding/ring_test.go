@@ -0,0 +1,63 @@
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRing_Distribution(t *testing.T) {
+	ring := NewRing([]string{"writer-1", "writer-2", "writer-3"}, 0)
+
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		counts[ring.Owner(fmt.Sprintf("service-%d", i))]++
+	}
+	if len(counts) != 3 {
+		t.Fatalf("Expected services on all 3 nodes, got %v", counts)
+	}
+	// With the default virtual nodes each node owns roughly a third of the services
+	for node, count := range counts {
+		if count < 700 || count > 1300 {
+			t.Errorf("Expected node %s to own about 1000 services, got %d", node, count)
+		}
+	}
+}
+
+func TestRing_StableWhenNodeAdded(t *testing.T) {
+	before := NewRing([]string{"writer-1", "writer-2", "writer-3"}, 0)
+	after := NewRing([]string{"writer-1", "writer-2", "writer-3", "writer-4"}, 0)
+
+	moved := 0
+	for i := 0; i < 2000; i++ {
+		service := fmt.Sprintf("service-%d", i)
+		owner := after.Owner(service)
+		if owner == before.Owner(service) {
+			continue
+		}
+		if owner != "writer-4" {
+			t.Fatalf("Expected %s to move only to the new node, it moved to %s", service, owner)
+		}
+		moved++
+	}
+	if moved == 0 || moved > 800 {
+		t.Errorf("Expected about a quarter of the services to move, %d of 2000 moved", moved)
+	}
+}
+
+func TestRing_SameOnEveryNode(t *testing.T) {
+	a := NewRing([]string{"writer-1", "writer-2"}, 16)
+	b := NewRing([]string{"writer-2", "writer-1"}, 16)
+
+	for i := 0; i < 100; i++ {
+		service := fmt.Sprintf("service-%d", i)
+		if a.Owner(service) != b.Owner(service) {
+			t.Fatalf("Expected the order of nodes not to matter, %s is owned by %s and %s", service, a.Owner(service), b.Owner(service))
+		}
+	}
+}
+
+func TestRing_Empty(t *testing.T) {
+	if owner := NewRing(nil, 0).Owner("checkout"); owner != "" {
+		t.Errorf("Expected no owner without nodes, got %q", owner)
+	}
+}
@@ -0,0 +1,299 @@
+package sharding
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/auth"
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+const (
+	// RoutePath is the path nodes accept entries routed to them by other nodes on
+	RoutePath = "/v1/shard/batch"
+
+	// OriginHeader carries the ID of the node that received routed entries from the client
+	OriginHeader = "X-Shard-Origin"
+
+	// DefaultTimeout is the timeout of requests to other nodes when none is configured
+	DefaultTimeout = 10 * time.Second
+)
+
+// Node configures a writer node of the cluster
+type Node struct {
+	ID            string // Identifies the node on the ring, must be the same on every node
+	URL           string // Base URL of the node's ingestion API
+	APIKey        string // Sent as X-API-Key, the key needs the route_logs permission
+	SigningSecret string // Signs every request when the node requires signatures for the key
+	CAFile        string // PEM CA bundle for verifying the node, system roots when empty
+}
+
+// Config configures a Router. Every node of the cluster must be configured with the same nodes
+// and virtual nodes, or they would disagree on the owners of services.
+type Config struct {
+	NodeID       string        // ID of this node, one of Nodes
+	Nodes        []Node        // All writer nodes of the cluster, including this one
+	VirtualNodes int           // Points per node on the ring, defaults to DefaultVirtualNodes
+	Timeout      time.Duration // Per-request timeout, defaults to DefaultTimeout
+}
+
+// nodeClient sends routed entries to another node
+type nodeClient struct {
+	node   Node
+	url    string
+	client *http.Client
+
+	routed atomic.Int64 // Entries the node accepted
+	failed atomic.Int64 // Entries the node could not be sent
+
+	mutex     sync.Mutex
+	lastError string
+}
+
+// Router is the routing tier of a sharded cluster. Each service is owned by one node, chosen by
+// consistent hashing of the service name, and entries ingested by any node are sent on to the
+// node owning their service, so that each node only writes the entries of its own services.
+type Router struct {
+	config  Config
+	ring    *Ring
+	clients map[string]*nodeClient // Other nodes by ID
+}
+
+// NewRouter creates the router of the node config.NodeID
+func NewRouter(config Config) (*Router, error) {
+	if config.NodeID == "" {
+		return nil, errors.New("shard node ID is required")
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.VirtualNodes <= 0 {
+		config.VirtualNodes = DefaultVirtualNodes
+	}
+
+	r := &Router{config: config, clients: make(map[string]*nodeClient)}
+	ids := make([]string, 0, len(config.Nodes))
+	self := false
+	for _, node := range config.Nodes {
+		if node.ID == "" {
+			return nil, errors.New("shard node ID is required for every node")
+		}
+		for _, id := range ids {
+			if id == node.ID {
+				return nil, fmt.Errorf("duplicate shard node %q", node.ID)
+			}
+		}
+		ids = append(ids, node.ID)
+
+		// The URL of this node is only needed by the others
+		if node.ID == config.NodeID {
+			self = true
+			continue
+		}
+		client, err := newNodeClient(node, config.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		r.clients[node.ID] = client
+	}
+	if !self {
+		return nil, fmt.Errorf("shard node %q is not one of the configured nodes", config.NodeID)
+	}
+
+	r.ring = NewRing(ids, config.VirtualNodes)
+	return r, nil
+}
+
+// newNodeClient creates the client of another node
+func newNodeClient(node Node, timeout time.Duration) (*nodeClient, error) {
+	parsed, err := url.Parse(node.URL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q of shard node %s", node.URL, node.ID)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if node.CAFile != "" {
+		pem, err := os.ReadFile(node.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file of shard node %s: %w", node.ID, err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", node.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    roots,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	return &nodeClient{
+		node:   node,
+		url:    strings.TrimRight(node.URL, "/"),
+		client: &http.Client{Timeout: timeout, Transport: transport},
+	}, nil
+}
+
+// NodeID returns the ID of this node
+func (r *Router) NodeID() string {
+	return r.config.NodeID
+}
+
+// Owner returns the ID of the node owning a service
+func (r *Router) Owner(service string) string {
+	return r.ring.Owner(service)
+}
+
+// Route sends the entries of services owned by other nodes to their owners, concurrently, and
+// returns the entries this node owns in their original order. If any node cannot be sent its
+// entries, the error names it; entries sent to the other nodes before are stored by them.
+func (r *Router) Route(ctx context.Context, entries []models.LogEntry) ([]models.LogEntry, error) {
+	var (
+		local  []models.LogEntry
+		remote map[string][]models.LogEntry
+	)
+	for _, entry := range entries {
+		owner := r.ring.Owner(entry.ServiceName)
+		if owner == r.config.NodeID {
+			local = append(local, entry)
+			continue
+		}
+		if remote == nil {
+			remote = make(map[string][]models.LogEntry)
+		}
+		remote[owner] = append(remote[owner], entry)
+	}
+	if len(remote) == 0 {
+		return entries, nil
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		errs   []string
+		origin = r.config.NodeID
+	)
+	for owner, owned := range remote {
+		wg.Add(1)
+		go func(client *nodeClient, owned []models.LogEntry) {
+			defer wg.Done()
+			if err := client.send(ctx, owned, origin); err != nil {
+				mutex.Lock()
+				errs = append(errs, fmt.Sprintf("node %s: %v", client.node.ID, err))
+				mutex.Unlock()
+			}
+		}(r.clients[owner], owned)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to route entries to their shard: %s", strings.Join(errs, "; "))
+	}
+	return local, nil
+}
+
+// send posts entries to the node
+func (c *nodeClient) send(ctx context.Context, entries []models.LogEntry, origin string) error {
+	err := c.post(ctx, entries, origin)
+	if err != nil {
+		c.failed.Add(int64(len(entries)))
+		c.mutex.Lock()
+		c.lastError = err.Error()
+		c.mutex.Unlock()
+		return err
+	}
+	c.routed.Add(int64(len(entries)))
+	return nil
+}
+
+// post makes the request sending entries to the node
+func (c *nodeClient) post(ctx context.Context, entries []models.LogEntry, origin string) error {
+	body, err := codec.MarshalEntries(entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entries: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+RoutePath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(OriginHeader, origin)
+	if c.node.APIKey != "" {
+		req.Header.Set("X-API-Key", c.node.APIKey)
+	}
+	if c.node.SigningSecret != "" {
+		if err := auth.SignRequest(req, c.node.SigningSecret); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send entries: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("responded with %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// NodeStats reports the entries routed to another node
+type NodeStats struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	Routed    int64  `json:"routed"` // Entries the node accepted
+	Failed    int64  `json:"failed"` // Entries that could not be sent, the client was answered with an error
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Stats reports the routing of a node
+type Stats struct {
+	NodeID string      `json:"node_id"`
+	Nodes  []NodeStats `json:"nodes"` // The other nodes, in the configured order
+}
+
+// Stats returns the entries routed to each of the other nodes
+func (r *Router) Stats() Stats {
+	stats := Stats{NodeID: r.config.NodeID, Nodes: make([]NodeStats, 0, len(r.clients))}
+	for _, node := range r.config.Nodes {
+		client, ok := r.clients[node.ID]
+		if !ok {
+			continue
+		}
+		client.mutex.Lock()
+		stats.Nodes = append(stats.Nodes, NodeStats{
+			ID:        node.ID,
+			URL:       client.url,
+			Routed:    client.routed.Load(),
+			Failed:    client.failed.Load(),
+			LastError: client.lastError,
+		})
+		client.mutex.Unlock()
+	}
+	return stats
+}
+
+// Close releases idle connections to the other nodes
+func (r *Router) Close() error {
+	for _, client := range r.clients {
+		client.client.CloseIdleConnections()
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package sharding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// nodeServer records the entries routed to it, failing every request when status is set
+type nodeServer struct {
+	mutex   sync.Mutex
+	status  int
+	entries []models.LogEntry
+	origins []string
+	apiKeys []string
+}
+
+func (n *nodeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != RoutePath {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	var entries []models.LogEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.status != 0 {
+		w.WriteHeader(n.status)
+		return
+	}
+	n.entries = append(n.entries, entries...)
+	n.origins = append(n.origins, r.Header.Get(OriginHeader))
+	n.apiKeys = append(n.apiKeys, r.Header.Get("X-API-Key"))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func newShardTestLogs(services ...string) []models.LogEntry {
+	logs := make([]models.LogEntry, len(services))
+	for i, service := range services {
+		logs[i] = models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Routed message",
+			ServiceName: service,
+			AgentID:     service + "-agent",
+			Platform:    models.PlatformGo,
+		}
+	}
+	return logs
+}
+
+// serviceOwnedBy finds a service the router assigns to the node
+func serviceOwnedBy(t *testing.T, router *Router, node string) string {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		service := fmt.Sprintf("service-%d", i)
+		if router.Owner(service) == node {
+			return service
+		}
+	}
+	t.Fatalf("No service is owned by node %s", node)
+	return ""
+}
+
+func TestRouter_Route(t *testing.T) {
+	remote := &nodeServer{}
+	remoteServer := httptest.NewServer(remote)
+	defer remoteServer.Close()
+
+	router, err := NewRouter(Config{
+		NodeID: "writer-1",
+		Nodes: []Node{
+			{ID: "writer-1", URL: "http://127.0.0.1:1"},
+			{ID: "writer-2", URL: remoteServer.URL + "/", APIKey: "route-key"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+	defer router.Close()
+
+	local, owned := serviceOwnedBy(t, router, "writer-1"), serviceOwnedBy(t, router, "writer-2")
+	entries := newShardTestLogs(local, owned, local, owned, owned)
+
+	kept, err := router.Route(context.Background(), entries)
+	if err != nil {
+		t.Fatalf("Failed to route entries: %v", err)
+	}
+	if len(kept) != 2 || kept[0].ID != entries[0].ID || kept[1].ID != entries[2].ID {
+		t.Errorf("Expected the 2 local entries in their original order, got %v", kept)
+	}
+	if len(remote.entries) != 3 || remote.entries[0].ID != entries[1].ID {
+		t.Errorf("Expected the 3 remote entries to be sent to their owner, got %v", remote.entries)
+	}
+	if remote.origins[0] != "writer-1" || remote.apiKeys[0] != "route-key" {
+		t.Errorf("Expected the origin and the node's API key to be sent, got %v and %v", remote.origins, remote.apiKeys)
+	}
+
+	stats := router.Stats()
+	if stats.NodeID != "writer-1" || len(stats.Nodes) != 1 || stats.Nodes[0].ID != "writer-2" || stats.Nodes[0].Routed != 3 {
+		t.Errorf("Expected 3 entries routed to writer-2, got %+v", stats)
+	}
+
+	// Entries that are all owned locally are returned as they are
+	kept, err = router.Route(context.Background(), newShardTestLogs(local))
+	if err != nil || len(kept) != 1 {
+		t.Errorf("Expected the local entry to be kept, got %v (%v)", kept, err)
+	}
+}
+
+func TestRouter_RouteFailure(t *testing.T) {
+	remote := &nodeServer{status: http.StatusServiceUnavailable}
+	remoteServer := httptest.NewServer(remote)
+	defer remoteServer.Close()
+
+	router, err := NewRouter(Config{
+		NodeID: "writer-1",
+		Nodes: []Node{
+			{ID: "writer-1", URL: "http://127.0.0.1:1"},
+			{ID: "writer-2", URL: remoteServer.URL},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create router: %v", err)
+	}
+
+	owned := serviceOwnedBy(t, router, "writer-2")
+	if _, err := router.Route(context.Background(), newShardTestLogs(owned, owned)); err == nil {
+		t.Fatal("Expected an error when the owner rejects the entries")
+	}
+
+	stats := router.Stats().Nodes[0]
+	if stats.Failed != 2 || stats.Routed != 0 || stats.LastError == "" {
+		t.Errorf("Expected 2 failed entries and the last error, got %+v", stats)
+	}
+}
+
+func TestNewRouter_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"missing node ID", Config{Nodes: []Node{{ID: "writer-1", URL: "https://writer-1.example.com"}}}},
+		{"node not configured", Config{NodeID: "writer-3", Nodes: []Node{{ID: "writer-1", URL: "https://writer-1.example.com"}}}},
+		{"duplicate node", Config{NodeID: "writer-1", Nodes: []Node{{ID: "writer-1"}, {ID: "writer-1"}}}},
+		{"missing ID", Config{NodeID: "writer-1", Nodes: []Node{{ID: "writer-1"}, {URL: "https://writer-2.example.com"}}}},
+		{"invalid URL", Config{NodeID: "writer-1", Nodes: []Node{{ID: "writer-1"}, {ID: "writer-2", URL: "writer-2.example.com"}}}},
+		{"missing CA file", Config{NodeID: "writer-1", Nodes: []Node{{ID: "writer-1"}, {ID: "writer-2", URL: "https://writer-2.example.com", CAFile: "/nonexistent/ca.pem"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRouter(tt.config); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+// Package siem forwards data protection and admin audit events to an external SIEM, over syslog
+// in Common Event Format or as JSON over HTTPS, for compliance teams that cannot query the
+// server directly
+package siem
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+)
+
+const (
+	// DefaultQueueSize is the number of events held for delivery when none is configured
+	DefaultQueueSize = 10000
+
+	// DefaultBatchSize is the number of events delivered together over HTTPS
+	DefaultBatchSize = 100
+
+	// DefaultTimeout is the per-delivery timeout when none is configured
+	DefaultTimeout = 10 * time.Second
+
+	// DefaultRetryBackoff is the initial delay between attempts when none is configured
+	DefaultRetryBackoff = time.Second
+
+	// flushInterval is how long events wait for a batch to fill before they are delivered
+	flushInterval = time.Second
+
+	// maxRetryBackoff caps the exponential backoff between attempts
+	maxRetryBackoff = 30 * time.Second
+
+	// drainTimeout bounds the delivery of queued events once the forwarder is stopped
+	drainTimeout = 5 * time.Second
+)
+
+// Protocols events can be forwarded with
+const (
+	ProtocolSyslog = "syslog"
+	ProtocolHTTPS  = "https"
+)
+
+// Event categories
+const (
+	CategoryDataProtection = "data_protection"
+	CategoryAdmin          = "admin"
+)
+
+// Event is an audit event forwarded to the SIEM
+type Event struct {
+	Time     time.Time         `json:"time"`
+	Category string            `json:"category"`
+	Name     string            `json:"name"`
+	Severity int               `json:"severity"`          // 0 (lowest) to 10, as in CEF
+	Actor    string            `json:"actor,omitempty"`   // API key ID of the client
+	Source   string            `json:"source,omitempty"`  // Client IP address
+	Outcome  string            `json:"outcome,omitempty"` // success or failure
+	Fields   map[string]string `json:"fields,omitempty"`
+}
+
+// Config configures a Forwarder
+type Config struct {
+	Protocol     string        // ProtocolSyslog or ProtocolHTTPS
+	Address      string        // host:port of the syslog receiver
+	Network      string        // Syslog transport: tcp, udp or tls, defaults to tcp
+	URL          string        // HTTPS collector endpoint, receiving JSON arrays of events
+	Token        string        // Sent as a bearer token to the HTTPS collector
+	CAFile       string        // PEM CA bundle for verifying the receiver, system roots when empty
+	Timeout      time.Duration // Per-delivery timeout, defaults to DefaultTimeout
+	QueueSize    int           // Events held for delivery, defaults to DefaultQueueSize
+	MaxRetries   int           // Attempts after the first before a batch is dropped
+	RetryBackoff time.Duration // Initial delay between attempts, doubled after each one
+}
+
+// sender delivers batches of events and reports whether a failure is worth retrying
+type sender interface {
+	send(ctx context.Context, events []Event) (bool, error)
+	close() error
+}
+
+// Forwarder queues audit events and delivers them to the SIEM in the background, retrying
+// failed deliveries. Events are dropped, and counted, when the queue is full or the retries
+// are exhausted, so that a SIEM outage never blocks ingestion.
+type Forwarder struct {
+	config    Config
+	sender    sender
+	batchSize int
+	queue     chan Event
+
+	forwarded atomic.Int64 // Events accepted by the receiver
+	dropped   atomic.Int64 // Events lost to a full queue, a rejection or exhausted retries
+	retries   atomic.Int64 // Deliveries repeated after a failure
+
+	mutex     sync.Mutex
+	lastError string
+}
+
+// NewForwarder creates a forwarder to the receiver described by config
+func NewForwarder(config Config) (*Forwarder, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = DefaultRetryBackoff
+	}
+
+	f := &Forwarder{
+		config: config,
+		queue:  make(chan Event, config.QueueSize),
+	}
+
+	var err error
+	switch config.Protocol {
+	case ProtocolSyslog:
+		// Syslog messages carry one event each
+		f.batchSize = 1
+		f.sender, err = newSyslogSender(config)
+	case ProtocolHTTPS:
+		f.batchSize = DefaultBatchSize
+		f.sender, err = newHTTPSender(config)
+	default:
+		return nil, fmt.Errorf("unsupported SIEM protocol %q, expected syslog or https", config.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Send queues an event for delivery, dropping it if the queue is full
+func (f *Forwarder) Send(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	select {
+	case f.queue <- event:
+	default:
+		f.dropped.Add(1)
+	}
+}
+
+// RecordAuditEntry queues a data protection audit entry, making the forwarder a
+// dataprotection.AuditSink. Original values are never forwarded.
+func (f *Forwarder) RecordAuditEntry(entry dataprotection.AuditEntry) {
+	f.Send(DataProtectionEvent(entry))
+}
+
+// DataProtectionEvent describes the actions data protection took on a log entry
+func DataProtectionEvent(entry dataprotection.AuditEntry) Event {
+	actions := make([]string, 0, len(entry.ActionsPerformed))
+	for _, action := range entry.ActionsPerformed {
+		actions = append(actions, action.Field+":"+string(action.Action))
+	}
+	sort.Strings(actions)
+
+	event := Event{
+		Time:     entry.Timestamp.UTC(),
+		Category: CategoryDataProtection,
+		Name:     "Sensitive data processed",
+		Severity: 3,
+		Outcome:  "success",
+		Fields: map[string]string{
+			"log_entry_id": entry.LogEntryID,
+			"service_name": entry.ServiceName,
+			"agent_id":     entry.AgentID,
+			"actions":      strings.Join(actions, ","),
+		},
+	}
+	if entry.RequestID != "" {
+		event.Fields["request_id"] = entry.RequestID
+	}
+	return event
+}
+
+// Run delivers queued events until ctx is done, then makes a last attempt at the events still
+// queued and closes the connection to the receiver
+func (f *Forwarder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, f.batchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			f.drain(batch)
+			return f.sender.close()
+		case event := <-f.queue:
+			batch = append(batch, event)
+			if len(batch) < f.batchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		}
+		if !f.deliver(ctx, batch) {
+			f.drain(batch)
+			return f.sender.close()
+		}
+		batch = batch[:0]
+	}
+}
+
+// drain delivers the pending batch and the queued events without retrying
+func (f *Forwarder) drain(batch []Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	for {
+		select {
+		case event := <-f.queue:
+			batch = append(batch, event)
+			if len(batch) < f.batchSize {
+				continue
+			}
+		default:
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if ctx.Err() != nil {
+			f.dropped.Add(int64(len(batch) + len(f.queue)))
+			return
+		}
+		if _, err := f.sender.send(ctx, batch); err != nil {
+			f.setLastError(err)
+			f.dropped.Add(int64(len(batch)))
+		} else {
+			f.forwarded.Add(int64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+}
+
+// deliver sends a batch, retrying failures with exponential backoff. It returns false if ctx
+// was done before the batch was delivered or dropped.
+func (f *Forwarder) deliver(ctx context.Context, batch []Event) bool {
+	backoff := f.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := f.sender.send(ctx, batch)
+		if err == nil {
+			f.forwarded.Add(int64(len(batch)))
+			return true
+		}
+		f.setLastError(err)
+
+		if !retryable || attempt >= f.config.MaxRetries {
+			f.dropped.Add(int64(len(batch)))
+			log.Printf("SIEM: dropping %d audit events after %d attempts: %v", len(batch), attempt+1, err)
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		f.retries.Add(1)
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+	}
+}
+
+// setLastError records the most recent delivery failure for the stats
+func (f *Forwarder) setLastError(err error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.lastError = err.Error()
+}
+
+// Stats reports the delivery counters of a forwarder
+type Stats struct {
+	Protocol  string `json:"protocol"`
+	Queued    int    `json:"queued"`
+	Forwarded int64  `json:"forwarded"`
+	Dropped   int64  `json:"dropped"`
+	Retries   int64  `json:"retries"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Stats returns the delivery counters
+func (f *Forwarder) Stats() Stats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return Stats{
+		Protocol:  f.config.Protocol,
+		Queued:    len(f.queue),
+		Forwarded: f.forwarded.Load(),
+		Dropped:   f.dropped.Load(),
+		Retries:   f.retries.Load(),
+		LastError: f.lastError,
+	}
+}
@@ -0,0 +1,231 @@
+package siem
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/dataprotection"
+)
+
+func TestFormatCEF(t *testing.T) {
+	event := Event{
+		Time:     time.UnixMilli(1700000000000),
+		Category: CategoryAdmin,
+		Name:     "PUT /admin/a|b",
+		Severity: 5,
+		Actor:    "key-1",
+		Outcome:  "success",
+		Fields:   map[string]string{"path": `/admin/a=b\c`, "note": "line1\nline2"},
+	}
+
+	expected := `CEF:0|kerlexov|mcp-logging-server|1.0|admin|PUT /admin/a\|b|5|` +
+		`rt=1700000000000 cat=admin suser=key-1 outcome=success note=line1\nline2 path=/admin/a\=b\\c`
+	if result := FormatCEF(event); result != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, result)
+	}
+}
+
+func TestForwarder_HTTPS(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan []Event, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// The first delivery fails and is retried
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var events []Event
+		if err := json.NewDecoder(r.Body).Decode(&events); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- events
+	}))
+	defer collector.Close()
+
+	forwarder, err := NewForwarder(Config{
+		Protocol:     ProtocolHTTPS,
+		URL:          collector.URL,
+		Token:        "secret",
+		MaxRetries:   2,
+		RetryBackoff: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- forwarder.Run(ctx) }()
+
+	forwarder.RecordAuditEntry(dataprotection.AuditEntry{
+		Timestamp:   time.Now(),
+		LogEntryID:  "log-1",
+		ServiceName: "checkout",
+		ActionsPerformed: []dataprotection.AuditAction{
+			{Field: "message", Action: dataprotection.ActionMask, OriginalValue: "secret value"},
+		},
+	})
+
+	select {
+	case events := <-received:
+		if len(events) != 1 || events[0].Category != CategoryDataProtection || events[0].Fields["actions"] != "message:mask" {
+			t.Errorf("Unexpected events: %+v", events)
+		}
+		if strings.Contains(events[0].Fields["actions"], "secret value") {
+			t.Error("Expected original values not to be forwarded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for events")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned error: %v", err)
+	}
+	if stats := forwarder.Stats(); stats.Forwarded != 1 || stats.Retries != 1 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestForwarder_HTTPSRejected(t *testing.T) {
+	var attempts atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer collector.Close()
+
+	forwarder, err := NewForwarder(Config{Protocol: ProtocolHTTPS, URL: collector.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	// A rejected batch is dropped without retrying
+	if !forwarder.deliver(context.Background(), []Event{{Category: CategoryAdmin}}) {
+		t.Fatal("Expected the batch to be handled")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("Expected 1 attempt, got %d", attempts.Load())
+	}
+	if stats := forwarder.Stats(); stats.Dropped != 1 || stats.LastError == "" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestForwarder_Syslog(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	var (
+		mutex    sync.Mutex
+		messages []string
+	)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Read octet-counted frames
+		reader := bufio.NewReader(conn)
+		for {
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(reader, frame); err != nil {
+				return
+			}
+			mutex.Lock()
+			messages = append(messages, string(frame))
+			mutex.Unlock()
+		}
+	}()
+
+	forwarder, err := NewForwarder(Config{Protocol: ProtocolSyslog, Address: listener.Addr().String()})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- forwarder.Run(ctx) }()
+
+	forwarder.Send(Event{Category: CategoryAdmin, Name: "POST /admin/flush", Severity: 7, Actor: "key-1", Outcome: "failure"})
+	forwarder.Send(Event{Category: CategoryAdmin, Name: "GET /admin/usage", Severity: 3})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mutex.Lock()
+		count := len(messages)
+		mutex.Unlock()
+		if count == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for messages, got %d", count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	// Log audit facility with error severity, then informational severity
+	if !strings.HasPrefix(messages[0], "<107>1 ") || !strings.Contains(messages[0], "CEF:0|kerlexov|mcp-logging-server|1.0|admin|POST /admin/flush|7|") {
+		t.Errorf("Unexpected message: %s", messages[0])
+	}
+	if !strings.HasPrefix(messages[1], "<110>1 ") {
+		t.Errorf("Unexpected message: %s", messages[1])
+	}
+}
+
+func TestForwarder_QueueFull(t *testing.T) {
+	forwarder, err := NewForwarder(Config{Protocol: ProtocolHTTPS, URL: "https://siem.example.com", QueueSize: 1})
+	if err != nil {
+		t.Fatalf("Failed to create forwarder: %v", err)
+	}
+
+	forwarder.Send(Event{Category: CategoryAdmin})
+	forwarder.Send(Event{Category: CategoryAdmin})
+	if stats := forwarder.Stats(); stats.Queued != 1 || stats.Dropped != 1 {
+		t.Errorf("Expected 1 queued and 1 dropped event, got %+v", stats)
+	}
+}
+
+func TestNewForwarder_Invalid(t *testing.T) {
+	testCases := []Config{
+		{Protocol: "smtp"},
+		{Protocol: ProtocolSyslog},
+		{Protocol: ProtocolSyslog, Address: "localhost:514", Network: "sctp"},
+		{Protocol: ProtocolHTTPS},
+		{Protocol: ProtocolHTTPS, URL: "https://siem.example.com", CAFile: "/nonexistent/ca.pem"},
+	}
+
+	for _, config := range testCases {
+		if _, err := NewForwarder(config); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+}
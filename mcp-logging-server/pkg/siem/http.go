@@ -0,0 +1,101 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// httpSender posts batches of events as JSON arrays to an HTTPS collector
+type httpSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// newHTTPSender creates a sender to the HTTPS collector of config
+func newHTTPSender(config Config) (*httpSender, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("SIEM collector URL is required")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig, err := clientTLSConfig(config.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &httpSender{
+		url:    config.URL,
+		token:  config.Token,
+		client: &http.Client{Transport: transport, Timeout: config.Timeout},
+	}, nil
+}
+
+// send posts the events. Rejections of the request itself are not retried, since sending the
+// same batch again would be rejected too.
+func (s *httpSender) send(ctx context.Context, events []Event) (bool, error) {
+	body, err := json.Marshal(events)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode events: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post events: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	switch resp.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return false, fmt.Errorf("SIEM collector rejected events with status %d", resp.StatusCode)
+	default:
+		return true, fmt.Errorf("SIEM collector responded with status %d", resp.StatusCode)
+	}
+}
+
+// close releases idle connections to the collector
+func (s *httpSender) close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// clientTLSConfig returns a TLS configuration trusting the CAs in caFile, or nil to use the
+// system roots when caFile is empty
+func clientTLSConfig(caFile string) (*tls.Config, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+
+	caCert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SIEM CA file: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse SIEM CA file")
+	}
+	return &tls.Config{RootCAs: caPool, MinVersion: tls.VersionTLS12}, nil
+}
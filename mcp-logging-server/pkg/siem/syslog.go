@@ -0,0 +1,187 @@
+package siem
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacility is the log audit facility of RFC 5424
+	syslogFacility = 13
+
+	// appName identifies the server in syslog headers and as the CEF device product
+	appName = "mcp-logging-server"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+// syslogSender sends each event as an RFC 5424 syslog message with a CEF payload. Stream
+// transports use octet-counting framing as in RFC 6587 and RFC 5425.
+type syslogSender struct {
+	network   string
+	address   string
+	timeout   time.Duration
+	tlsConfig *tls.Config
+	hostname  string
+
+	mutex sync.Mutex
+	conn  net.Conn
+}
+
+// newSyslogSender creates a sender to the syslog receiver of config
+func newSyslogSender(config Config) (*syslogSender, error) {
+	if config.Address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+	network := config.Network
+	if network == "" {
+		network = "tcp"
+	}
+
+	sender := &syslogSender{
+		network: network,
+		address: config.Address,
+		timeout: config.Timeout,
+	}
+	switch network {
+	case "tcp", "udp":
+	case "tls":
+		tlsConfig, err := clientTLSConfig(config.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		sender.tlsConfig = tlsConfig
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q, expected tcp, udp or tls", network)
+	}
+
+	sender.hostname, _ = os.Hostname()
+	if sender.hostname == "" {
+		sender.hostname = "-"
+	}
+	return sender, nil
+}
+
+// send writes the events over the connection, reconnecting if it was lost
+func (s *syslogSender) send(ctx context.Context, events []Event) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return true, fmt.Errorf("failed to connect to syslog receiver: %w", err)
+		}
+		s.conn = conn
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	s.conn.SetWriteDeadline(deadline)
+
+	for _, event := range events {
+		message := s.format(event)
+		if s.network != "udp" {
+			message = strconv.Itoa(len(message)) + " " + message
+		}
+		if _, err := s.conn.Write([]byte(message)); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return true, fmt.Errorf("failed to write syslog message: %w", err)
+		}
+	}
+	return false, nil
+}
+
+// dial connects to the syslog receiver
+func (s *syslogSender) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: s.timeout}
+	if s.network == "tls" {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: s.tlsConfig}
+		return tlsDialer.DialContext(ctx, "tcp", s.address)
+	}
+	return dialer.DialContext(ctx, s.network, s.address)
+}
+
+// format returns the RFC 5424 message of an event
+func (s *syslogSender) format(event Event) string {
+	priority := syslogFacility*8 + syslogSeverity(event.Severity)
+	return fmt.Sprintf("<%d>1 %s %s %s - %s - %s",
+		priority, event.Time.UTC().Format(time.RFC3339Nano), s.hostname, appName, event.Category, FormatCEF(event))
+}
+
+// close closes the connection to the receiver
+func (s *syslogSender) close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// syslogSeverity maps a CEF severity to the closest syslog severity
+func syslogSeverity(severity int) int {
+	switch {
+	case severity >= 9:
+		return 2 // Critical
+	case severity >= 7:
+		return 3 // Error
+	case severity >= 4:
+		return 4 // Warning
+	default:
+		return 6 // Informational
+	}
+}
+
+// FormatCEF formats an event in ArcSight Common Event Format. The category is the signature ID
+// and the fields are extensions next to rt, cat, suser, src and outcome.
+func FormatCEF(event Event) string {
+	extensions := []string{
+		"rt=" + strconv.FormatInt(event.Time.UnixMilli(), 10),
+		"cat=" + cefExtensionEscaper.Replace(event.Category),
+	}
+	if event.Actor != "" {
+		extensions = append(extensions, "suser="+cefExtensionEscaper.Replace(event.Actor))
+	}
+	if event.Source != "" {
+		extensions = append(extensions, "src="+cefExtensionEscaper.Replace(event.Source))
+	}
+	if event.Outcome != "" {
+		extensions = append(extensions, "outcome="+cefExtensionEscaper.Replace(event.Outcome))
+	}
+
+	keys := make([]string, 0, len(event.Fields))
+	for key := range event.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		extensions = append(extensions, key+"="+cefExtensionEscaper.Replace(event.Fields[key]))
+	}
+
+	return fmt.Sprintf("CEF:0|kerlexov|%s|1.0|%s|%s|%d|%s",
+		appName,
+		cefHeaderEscaper.Replace(event.Category),
+		cefHeaderEscaper.Replace(event.Name),
+		event.Severity,
+		strings.Join(extensions, " "))
+}
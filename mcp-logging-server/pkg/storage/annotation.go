@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// AnnotateLog appends an annotation to a stored entry. Annotations are kept apart from the
+// entry's content, so that its content hash still verifies.
+func (s *SQLiteStorage) AnnotateLog(ctx context.Context, id string, annotation models.LogAnnotation) ([]models.LogAnnotation, error) {
+	if annotation.CreatedAt.IsZero() {
+		annotation.CreatedAt = time.Now().UTC()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var annotationsJSON sql.NullString
+	err = tx.QueryRowContext(ctx, "SELECT annotations FROM log_entries WHERE id = ?", id).Scan(&annotationsJSON)
+	if err == sql.ErrNoRows {
+		return nil, ErrLogNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get annotations of log %s: %w", id, err)
+	}
+
+	if err := decompressNullString(&annotationsJSON); err != nil {
+		return nil, fmt.Errorf("failed to read annotations of log %s: %w", id, err)
+	}
+	var annotations []models.LogAnnotation
+	if annotationsJSON.Valid {
+		if err := json.Unmarshal([]byte(annotationsJSON.String), &annotations); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal annotations for log %s: %w", id, err)
+		}
+	}
+	annotations = append(annotations, annotation)
+
+	data, err := json.Marshal(annotations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal annotations: %w", err)
+	}
+	text := string(data)
+	stored, err := s.encryptColumn(&text)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE log_entries SET annotations = ? WHERE id = ?", stored, id); err != nil {
+		return nil, fmt.Errorf("failed to annotate log %s: %w", id, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return annotations, nil
+}
+
+// AnnotateLog appends an annotation to a stored entry
+func (s *MemoryStorage) AnnotateLog(ctx context.Context, id string, annotation models.LogAnnotation) ([]models.LogAnnotation, error) {
+	if annotation.CreatedAt.IsZero() {
+		annotation.CreatedAt = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.entries {
+		if s.entries[i].ID != id {
+			continue
+		}
+		// Copied, so that entries returned by earlier queries do not change
+		annotations := make([]models.LogAnnotation, 0, len(s.entries[i].Annotations)+1)
+		annotations = append(annotations, s.entries[i].Annotations...)
+		annotations = append(annotations, annotation)
+		s.entries[i].Annotations = annotations
+		return append([]models.LogAnnotation(nil), annotations...), nil
+	}
+
+	return nil, ErrLogNotFound
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+type annotatingStorage interface {
+	LogStorage
+	LogAnnotator
+}
+
+func TestLogAnnotators(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "annotations.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]annotatingStorage{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			entry := models.LogEntry{
+				ID:          uuid.New().String(),
+				Timestamp:   time.Now().UTC(),
+				Level:       models.LogLevelError,
+				Message:     "Payment declined",
+				ServiceName: "billing-service",
+				AgentID:     "test-agent",
+				Platform:    models.PlatformGo,
+				Metadata:    map[string]interface{}{"order_id": "42"},
+				Annotations: []models.LogAnnotation{{Text: "ignored at ingestion"}},
+			}
+			if err := store.Store(ctx, []models.LogEntry{entry}); err != nil {
+				t.Fatalf("Failed to store log: %v", err)
+			}
+
+			logs, _ := store.GetByIDs(ctx, []string{entry.ID})
+			if len(logs) != 1 || len(logs[0].Annotations) != 0 {
+				t.Fatalf("Expected ingested annotations to be dropped, got %+v", logs)
+			}
+
+			first, err := store.AnnotateLog(ctx, entry.ID, models.LogAnnotation{Text: "known issue JIRA-123", Author: "oncall"})
+			if err != nil {
+				t.Fatalf("Failed to annotate log: %v", err)
+			}
+			if len(first) != 1 || first[0].CreatedAt.IsZero() {
+				t.Errorf("Expected 1 annotation with a creation time, got %+v", first)
+			}
+
+			annotations, err := store.AnnotateLog(ctx, entry.ID, models.LogAnnotation{Text: "false positive"})
+			if err != nil {
+				t.Fatalf("Failed to annotate log: %v", err)
+			}
+			if len(annotations) != 2 || annotations[0].Text != "known issue JIRA-123" || annotations[1].Text != "false positive" {
+				t.Errorf("Expected annotations in order, got %+v", annotations)
+			}
+			if len(first) != 1 {
+				t.Errorf("Expected earlier results to stay unchanged, got %+v", first)
+			}
+
+			result, err := store.Query(ctx, models.LogFilter{ServiceName: "billing-service"})
+			if err != nil {
+				t.Fatalf("Failed to query logs: %v", err)
+			}
+			if len(result.Logs) != 1 || len(result.Logs[0].Annotations) != 2 {
+				t.Fatalf("Expected the annotations to be returned with queries, got %+v", result.Logs)
+			}
+			if got := result.Logs[0]; got.Message != entry.Message || got.Metadata["order_id"] != "42" {
+				t.Errorf("Expected the entry content to be unchanged, got %+v", got)
+			}
+
+			if _, err := store.AnnotateLog(ctx, uuid.New().String(), models.LogAnnotation{Text: "missing"}); !errors.Is(err, ErrLogNotFound) {
+				t.Errorf("Expected ErrLogNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_AnnotationsKeepIntegrity(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	storage.SetIntegrityHashing(true, "secret")
+	ctx := context.Background()
+	entry := newLegalHoldTestLog("orders-service", time.Now().UTC())
+	if err := storage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+	if _, err := storage.AnnotateLog(ctx, entry.ID, models.LogAnnotation{Text: "reviewed"}); err != nil {
+		t.Fatalf("Failed to annotate log: %v", err)
+	}
+
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != 1 || report.Mismatched != 0 {
+		t.Errorf("Expected annotated entries to verify, got %+v", report)
+	}
+}
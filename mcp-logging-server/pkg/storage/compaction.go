@@ -0,0 +1,481 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// Defaults of the compaction policy
+const (
+	DefaultCompactionInterval  = time.Hour
+	DefaultCompactionBatchSize = 1000
+	DefaultSummaryLimit        = 100
+)
+
+// DefaultCompactionLevels are the levels compacted when the policy names none
+var DefaultCompactionLevels = []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo}
+
+// CompactionPolicy defines which entries are replaced with summaries
+type CompactionPolicy struct {
+	After     time.Duration     // Entries with a timestamp older than this are compacted
+	Levels    []models.LogLevel // Levels to compact, defaults to DefaultCompactionLevels
+	Interval  time.Duration     // How often Run compacts, defaults to DefaultCompactionInterval
+	BatchSize int               // Entries replaced per transaction, defaults to DefaultCompactionBatchSize
+}
+
+// CompactionResult represents the result of a compaction run
+type CompactionResult struct {
+	StartTime        time.Time               `json:"start_time"`
+	EndTime          time.Time               `json:"end_time"`
+	Duration         time.Duration           `json:"duration"`
+	TotalCompacted   int                     `json:"total_compacted"`
+	CompactedByLevel map[models.LogLevel]int `json:"compacted_by_level"`
+	Errors           []string                `json:"errors,omitempty"`
+}
+
+// Compactor periodically replaces old low-severity entries with hourly summaries
+type Compactor struct {
+	storage LogCompactor
+	policy  CompactionPolicy
+}
+
+// NewCompactor creates a compactor, failing if the storage does not support compaction
+func NewCompactor(storage LogStorage, policy CompactionPolicy) (*Compactor, error) {
+	compactor, ok := storage.(LogCompactor)
+	if !ok {
+		return nil, fmt.Errorf("storage does not support compaction")
+	}
+	if policy.After <= 0 {
+		return nil, fmt.Errorf("compaction age must be positive")
+	}
+
+	if len(policy.Levels) == 0 {
+		policy.Levels = DefaultCompactionLevels
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = DefaultCompactionInterval
+	}
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = DefaultCompactionBatchSize
+	}
+
+	return &Compactor{storage: compactor, policy: policy}, nil
+}
+
+// Compact replaces the entries past the compaction age with summaries, level by level
+func (c *Compactor) Compact(ctx context.Context) *CompactionResult {
+	result := &CompactionResult{
+		StartTime:        time.Now(),
+		CompactedByLevel: make(map[models.LogLevel]int),
+	}
+
+	cutoff := time.Now().Add(-c.policy.After)
+	for _, level := range c.policy.Levels {
+		for {
+			compacted, err := c.storage.CompactLogs(ctx, level, cutoff, c.policy.BatchSize)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Failed to compact %s logs: %v", level, err))
+				break
+			}
+
+			result.CompactedByLevel[level] += compacted
+			result.TotalCompacted += compacted
+
+			// Entries that may not be deleted are skipped, so a short batch means the level is done
+			if compacted < c.policy.BatchSize {
+				break
+			}
+		}
+	}
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	return result
+}
+
+// Run compacts every policy interval until the context is cancelled
+func (c *Compactor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			result := c.Compact(ctx)
+			for _, message := range result.Errors {
+				log.Printf("Log compaction: %s", message)
+			}
+			if result.TotalCompacted > 0 {
+				log.Printf("Log compaction completed: replaced %d logs with summaries in %v", result.TotalCompacted, result.Duration)
+			}
+		}
+	}
+}
+
+// summaryKey identifies a log summary, by the Unix time of its hour and the ID of its template
+type summaryKey struct {
+	hour        int64
+	serviceName string
+	level       models.LogLevel
+	templateID  string
+}
+
+// summarize adds an entry to the summaries it is compacted into. Only the messages of entries
+// stored without a template ID and of the first entry of each summary are normalized.
+func summarize(summaries map[summaryKey]models.LogSummary, entry models.LogEntry) {
+	timestamp := entry.Timestamp.UTC()
+	hour := timestamp.Truncate(time.Hour)
+	key := summaryKey{
+		hour:        hour.Unix(),
+		serviceName: entry.ServiceName,
+		level:       entry.Level,
+		templateID:  entry.TemplateID,
+	}
+	template := ""
+	if key.templateID == "" {
+		template = models.MessageTemplate(entry.Message)
+		key.templateID = models.TemplateID(template)
+	}
+
+	summary, exists := summaries[key]
+	if !exists {
+		if template == "" {
+			template = models.MessageTemplate(entry.Message)
+		}
+		summary = models.LogSummary{
+			Hour:        hour,
+			ServiceName: key.serviceName,
+			Level:       key.level,
+			Template:    template,
+			FirstSeen:   timestamp,
+			LastSeen:    timestamp,
+		}
+	}
+	summary.Count++
+	if timestamp.Before(summary.FirstSeen) {
+		summary.FirstSeen = timestamp
+	}
+	if timestamp.After(summary.LastSeen) {
+		summary.LastSeen = timestamp
+	}
+	summaries[key] = summary
+}
+
+// CompactLogs replaces up to limit entries of a level with a timestamp before the cutoff,
+// oldest first, with summaries. Entries under a legal hold or inside the immutable window are kept.
+func (s *SQLiteStorage) CompactLogs(ctx context.Context, level models.LogLevel, before time.Time, limit int) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	condition, conditionArgs := s.deletableCondition()
+	args := append([]interface{}{level, before}, conditionArgs...)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, timestamp, service_name, message, template_id FROM log_entries
+		WHERE level = ? AND timestamp < ? AND %s
+		ORDER BY timestamp ASC
+		LIMIT ?
+	`, condition), append(args, limit)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query logs to compact: %w", err)
+	}
+
+	var ids []string
+	summaries := make(map[summaryKey]models.LogSummary)
+	for rows.Next() {
+		entry := models.LogEntry{Level: level}
+		var templateID sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.Timestamp, &entry.ServiceName, &entry.Message, &templateID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan log to compact: %w", err)
+		}
+		if err := decompressString(&entry.Message); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to read log %s to compact: %w", entry.ID, err)
+		}
+		entry.TemplateID = templateID.String
+		ids = append(ids, entry.ID)
+		summarize(summaries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("failed to query logs to compact: %w", err)
+	}
+	rows.Close()
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO log_summaries (hour, service_name, level, template, count, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(hour, service_name, level, template) DO UPDATE SET
+			count = log_summaries.count + excluded.count,
+			first_seen = MIN(log_summaries.first_seen, excluded.first_seen),
+			last_seen = MAX(log_summaries.last_seen, excluded.last_seen)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare summary statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, summary := range summaries {
+		// Templates are part of the key that merges summaries, so equal templates are encrypted equally
+		template, err := s.encryptComparable(&summary.Template)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := stmt.ExecContext(ctx, summary.Hour, summary.ServiceName, summary.Level, template,
+			summary.Count, summary.FirstSeen, summary.LastSeen); err != nil {
+			return 0, fmt.Errorf("failed to store summary of %s: %w", summary.ServiceName, err)
+		}
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	idArgs := make([]interface{}, len(ids))
+	for i, id := range ids {
+		idArgs[i] = id
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM log_entry_tags WHERE log_id IN (%s)", placeholders), idArgs...); err != nil {
+		return 0, fmt.Errorf("failed to delete log entry tags: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM log_entries WHERE id IN (%s)", placeholders), idArgs...); err != nil {
+		return 0, fmt.Errorf("failed to delete compacted logs: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit compaction: %w", err)
+	}
+
+	if s.search != nil {
+		if err := s.search.DeleteLogEntries(ids); err != nil {
+			// Log error but don't fail the compaction
+			fmt.Printf("Warning: failed to delete %d compacted logs from search index: %v\n", len(ids), err)
+		}
+	}
+
+	return len(ids), nil
+}
+
+// summaryWhereClause builds the WHERE clause selecting the summaries of a filter
+func summaryWhereClause(filter models.SummaryFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if filter.ServiceName != "" {
+		conditions = append(conditions, "service_name = ?")
+		args = append(args, filter.ServiceName)
+	}
+	if filter.Level != "" {
+		conditions = append(conditions, "level = ?")
+		args = append(args, filter.Level)
+	}
+	// Hours are stored in UTC, so the bounds are too for them to compare
+	if !filter.StartTime.IsZero() {
+		conditions = append(conditions, "hour >= ?")
+		args = append(args, filter.StartTime.UTC())
+	}
+	if !filter.EndTime.IsZero() {
+		conditions = append(conditions, "hour <= ?")
+		args = append(args, filter.EndTime.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// QuerySummaries returns the summaries matching the filter, most recent hour and largest count first
+func (s *SQLiteStorage) QuerySummaries(ctx context.Context, filter models.SummaryFilter) ([]models.LogSummary, error) {
+	whereClause, args := summaryWhereClause(filter)
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultSummaryLimit
+	}
+
+	query := fmt.Sprintf(`
+		SELECT hour, service_name, level, template, count, first_seen, last_seen
+		FROM log_summaries %s
+		ORDER BY hour DESC, count DESC, service_name, level, template
+		LIMIT ?
+	`, whereClause)
+
+	rows, err := s.reader().QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]models.LogSummary, 0)
+	for rows.Next() {
+		var summary models.LogSummary
+		if err := rows.Scan(&summary.Hour, &summary.ServiceName, &summary.Level, &summary.Template,
+			&summary.Count, &summary.FirstSeen, &summary.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan summary: %w", err)
+		}
+		if err := decompressString(&summary.Template); err != nil {
+			return nil, fmt.Errorf("failed to read summary of %s: %w", summary.ServiceName, err)
+		}
+		summaries = append(summaries, summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query summaries: %w", err)
+	}
+
+	return summaries, nil
+}
+
+// SummaryCounts returns the number of compacted entries per level of the summaries matching the filter
+func (s *SQLiteStorage) SummaryCounts(ctx context.Context, filter models.SummaryFilter) (map[models.LogLevel]int64, error) {
+	whereClause, args := summaryWhereClause(filter)
+
+	rows, err := s.reader().QueryContext(ctx, fmt.Sprintf(`
+		SELECT level, SUM(count) FROM log_summaries %s GROUP BY level
+	`, whereClause), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count summaries: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.LogLevel]int64)
+	for rows.Next() {
+		var level models.LogLevel
+		var count int64
+		if err := rows.Scan(&level, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan summary count: %w", err)
+		}
+		counts[level] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count summaries: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CompactLogs replaces up to limit entries of a level with a timestamp before the cutoff,
+// oldest first, with summaries
+func (s *MemoryStorage) CompactLogs(ctx context.Context, level models.LogLevel, before time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var candidates []models.LogEntry
+	for _, entry := range s.entries {
+		if entry.Level == level && entry.Timestamp.Before(before) {
+			candidates = append(candidates, entry)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Timestamp.Before(candidates[j].Timestamp)
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	compacted := make(map[summaryKey]models.LogSummary)
+	remove := make(map[string]bool, len(candidates))
+	for _, entry := range candidates {
+		summarize(compacted, entry)
+		remove[entry.ID] = true
+	}
+
+	for key, summary := range compacted {
+		if stored, exists := s.summaries[key]; exists {
+			summary.Count += stored.Count
+			if stored.FirstSeen.Before(summary.FirstSeen) {
+				summary.FirstSeen = stored.FirstSeen
+			}
+			if stored.LastSeen.After(summary.LastSeen) {
+				summary.LastSeen = stored.LastSeen
+			}
+		}
+		s.summaries[key] = summary
+	}
+
+	kept := s.entries[:0]
+	for _, entry := range s.entries {
+		if remove[entry.ID] {
+			delete(s.ids, entry.ID)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	s.entries = kept
+
+	return len(candidates), nil
+}
+
+// QuerySummaries returns the summaries matching the filter, most recent hour and largest count first
+func (s *MemoryStorage) QuerySummaries(ctx context.Context, filter models.SummaryFilter) ([]models.LogSummary, error) {
+	s.mu.RLock()
+	summaries := make([]models.LogSummary, 0)
+	for _, summary := range s.summaries {
+		if summaryMatches(summary, filter) {
+			summaries = append(summaries, summary)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		switch {
+		case !a.Hour.Equal(b.Hour):
+			return a.Hour.After(b.Hour)
+		case a.Count != b.Count:
+			return a.Count > b.Count
+		case a.ServiceName != b.ServiceName:
+			return a.ServiceName < b.ServiceName
+		case a.Level != b.Level:
+			return a.Level < b.Level
+		default:
+			return a.Template < b.Template
+		}
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultSummaryLimit
+	}
+	if len(summaries) > limit {
+		summaries = summaries[:limit]
+	}
+	return summaries, nil
+}
+
+// SummaryCounts returns the number of compacted entries per level of the summaries matching the filter
+func (s *MemoryStorage) SummaryCounts(ctx context.Context, filter models.SummaryFilter) (map[models.LogLevel]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[models.LogLevel]int64)
+	for _, summary := range s.summaries {
+		if summaryMatches(summary, filter) {
+			counts[summary.Level] += summary.Count
+		}
+	}
+	return counts, nil
+}
+
+// summaryMatches reports whether a summary is selected by the filter
+func summaryMatches(summary models.LogSummary, filter models.SummaryFilter) bool {
+	return (filter.ServiceName == "" || summary.ServiceName == filter.ServiceName) &&
+		(filter.Level == "" || summary.Level == filter.Level) &&
+		(filter.StartTime.IsZero() || !summary.Hour.Before(filter.StartTime)) &&
+		(filter.EndTime.IsZero() || !summary.Hour.After(filter.EndTime))
+}
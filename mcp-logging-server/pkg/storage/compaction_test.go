@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newCompactionTestLog(serviceName string, level models.LogLevel, message string, timestamp time.Time) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		Level:       level,
+		Message:     message,
+		ServiceName: serviceName,
+		AgentID:     "test-agent",
+		Platform:    models.PlatformGo,
+	}
+}
+
+// compactionTestLogs returns three old INFO entries sharing a template, an old ERROR entry and
+// a recent INFO entry
+func compactionTestLogs(hour time.Time) []models.LogEntry {
+	return []models.LogEntry{
+		newCompactionTestLog("orders-service", models.LogLevelInfo, "Order 1 placed", hour.Add(5*time.Minute)),
+		newCompactionTestLog("orders-service", models.LogLevelInfo, "Order 2 placed", hour.Add(10*time.Minute)),
+		newCompactionTestLog("orders-service", models.LogLevelInfo, "Order 3 placed", hour.Add(15*time.Minute)),
+		newCompactionTestLog("orders-service", models.LogLevelError, "Order 4 failed", hour.Add(20*time.Minute)),
+		newCompactionTestLog("orders-service", models.LogLevelInfo, "Order 5 placed", time.Now()),
+	}
+}
+
+func TestCompactor_Compact(t *testing.T) {
+	stores := map[string]func(t *testing.T) LogStorage{
+		"sqlite": func(t *testing.T) LogStorage {
+			storage, err := NewSQLiteStorage(":memory:")
+			if err != nil {
+				t.Fatalf("Failed to create storage: %v", err)
+			}
+			return storage
+		},
+		"memory": func(t *testing.T) LogStorage { return NewMemoryStorage() },
+	}
+
+	for name, newStorage := range stores {
+		t.Run(name, func(t *testing.T) {
+			storage := newStorage(t)
+			defer storage.Close()
+
+			ctx := context.Background()
+			hour := time.Now().UTC().Add(-72 * time.Hour).Truncate(time.Hour)
+			if err := storage.Store(ctx, compactionTestLogs(hour)); err != nil {
+				t.Fatalf("Failed to store logs: %v", err)
+			}
+
+			compactor, err := NewCompactor(storage, CompactionPolicy{After: 24 * time.Hour, BatchSize: 2})
+			if err != nil {
+				t.Fatalf("Failed to create compactor: %v", err)
+			}
+			result := compactor.Compact(ctx)
+			if len(result.Errors) > 0 {
+				t.Fatalf("Unexpected compaction errors: %v", result.Errors)
+			}
+			if result.TotalCompacted != 3 || result.CompactedByLevel[models.LogLevelInfo] != 3 {
+				t.Errorf("Expected 3 compacted INFO logs, got %+v", result)
+			}
+
+			// The error and the recent entry are kept
+			remaining, err := storage.Query(ctx, models.LogFilter{Limit: 10})
+			if err != nil {
+				t.Fatalf("Failed to query logs: %v", err)
+			}
+			if remaining.TotalCount != 2 {
+				t.Errorf("Expected 2 remaining logs, got %d", remaining.TotalCount)
+			}
+
+			summaries, err := storage.(LogCompactor).QuerySummaries(ctx, models.SummaryFilter{ServiceName: "orders-service"})
+			if err != nil {
+				t.Fatalf("Failed to query summaries: %v", err)
+			}
+			if len(summaries) != 1 {
+				t.Fatalf("Expected 1 summary, got %d", len(summaries))
+			}
+			summary := summaries[0]
+			if summary.Template != "Order <num> placed" || summary.Count != 3 || summary.Level != models.LogLevelInfo {
+				t.Errorf("Unexpected summary: %+v", summary)
+			}
+			if !summary.Hour.Equal(hour) || !summary.FirstSeen.Equal(hour.Add(5*time.Minute)) || !summary.LastSeen.Equal(hour.Add(15*time.Minute)) {
+				t.Errorf("Unexpected summary times: %+v", summary)
+			}
+
+			// Compacting more entries of the same hour adds to the summary
+			late := newCompactionTestLog("orders-service", models.LogLevelInfo, "Order 6 placed", hour.Add(50*time.Minute))
+			if err := storage.Store(ctx, []models.LogEntry{late}); err != nil {
+				t.Fatalf("Failed to store logs: %v", err)
+			}
+			compactor.Compact(ctx)
+
+			summaries, err = storage.(LogCompactor).QuerySummaries(ctx, models.SummaryFilter{
+				Level:     models.LogLevelInfo,
+				StartTime: hour,
+				EndTime:   hour,
+			})
+			if err != nil {
+				t.Fatalf("Failed to query summaries: %v", err)
+			}
+			if len(summaries) != 1 || summaries[0].Count != 4 || !summaries[0].LastSeen.Equal(hour.Add(50*time.Minute)) {
+				t.Errorf("Expected the summary to count 4 entries, got %+v", summaries)
+			}
+
+			counts, err := storage.(LogCompactor).SummaryCounts(ctx, models.SummaryFilter{ServiceName: "orders-service", Limit: 1})
+			if err != nil {
+				t.Fatalf("Failed to count summaries: %v", err)
+			}
+			if counts[models.LogLevelInfo] != 4 || len(counts) != 1 {
+				t.Errorf("Expected 4 compacted INFO logs, got %v", counts)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_CompactLogsKeepsLegalHolds(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if _, err := storage.PlaceLegalHold(ctx, models.LegalHold{ServiceName: "orders-service", Reason: "Audit"}); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+
+	var logs []models.LogEntry
+	for i := 0; i < 3; i++ {
+		logs = append(logs,
+			newCompactionTestLog("orders-service", models.LogLevelInfo, fmt.Sprintf("Order %d placed", i), now.Add(-48*time.Hour)),
+			newCompactionTestLog("billing-service", models.LogLevelInfo, fmt.Sprintf("Invoice %d sent", i), now.Add(-48*time.Hour)))
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	compacted, err := storage.CompactLogs(ctx, models.LogLevelInfo, now.Add(-24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("Failed to compact logs: %v", err)
+	}
+	if compacted != 3 {
+		t.Errorf("Expected only the 3 billing logs to be compacted, got %d", compacted)
+	}
+
+	held, err := storage.Query(ctx, models.LogFilter{ServiceName: "orders-service", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if held.TotalCount != 3 {
+		t.Errorf("Expected 3 held logs to remain, got %d", held.TotalCount)
+	}
+}
+
+func TestNewCompactor_RequiresAge(t *testing.T) {
+	if _, err := NewCompactor(NewMemoryStorage(), CompactionPolicy{}); err == nil {
+		t.Error("Expected a compactor without age to be rejected")
+	}
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Columns that can be stored compressed
+const (
+	CompressMessage    = "message"
+	CompressStackTrace = "stack_trace"
+	CompressMetadata   = "metadata"
+)
+
+// DefaultCompressionMinSize is the size in bytes below which values are stored uncompressed
+const DefaultCompressionMinSize = 256
+
+// CompressionConfig selects the columns stored compressed. Compressed values are decompressed
+// transparently on read, and values stored before compression was enabled or after it was
+// disabled are read as they are.
+type CompressionConfig struct {
+	Columns []string // Columns to compress, see CompressMessage, CompressStackTrace and CompressMetadata
+	MinSize int      // Values smaller than this are stored as they are, 0 uses the default
+}
+
+// Compressed values are stored as blobs starting with the marker and the codec that compressed
+// them. The marker starts with a NUL byte, which text and JSON values never start with.
+const (
+	compressionMarker = "\x00mcz"
+	codecDeflate      = byte(1) // Only read, values are written with zstd
+	codecZstd         = byte(2)
+)
+
+// The zstd encoder and decoder are safe for concurrent use with EncodeAll and DecodeAll
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// SetCompression enables compressing the configured columns of new entries
+func (s *SQLiteStorage) SetCompression(config CompressionConfig) error {
+	columns := make(map[string]bool, len(config.Columns))
+	for _, column := range config.Columns {
+		switch column {
+		case CompressMessage, CompressStackTrace, CompressMetadata:
+			columns[column] = true
+		default:
+			return fmt.Errorf("column %q cannot be compressed", column)
+		}
+	}
+
+	if config.MinSize <= 0 {
+		config.MinSize = DefaultCompressionMinSize
+	}
+	s.compressedColumns = columns
+	s.compressionMinSize = config.MinSize
+	return nil
+}
+
+// compressColumn returns the value to store for a column: a compressed blob when the column is
+// compressed and the value is large enough, the value itself otherwise, encrypted when
+// encryption is enabled
+func (s *SQLiteStorage) compressColumn(column string, value *string) (interface{}, error) {
+	stored, err := s.compressValue(column, value)
+	if err != nil || s.encryption == nil {
+		return stored, err
+	}
+	return s.encryptValue(stored)
+}
+
+// compressValue compresses a column value if the column is compressed and the value is large enough
+func (s *SQLiteStorage) compressValue(column string, value *string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if !s.compressedColumns[column] || len(*value) < s.compressionMinSize {
+		return *value, nil
+	}
+
+	compressed := append([]byte(compressionMarker), codecZstd)
+	compressed = zstdEncoder.EncodeAll([]byte(*value), compressed)
+
+	// Incompressible values are kept as they are
+	if len(compressed) >= len(*value) {
+		return *value, nil
+	}
+	return compressed, nil
+}
+
+// decompressValue returns the text of a stored column value, decrypting and decompressing it if needed
+func decompressValue(value []byte) (string, error) {
+	if !bytes.HasPrefix(value, []byte(compressionMarker)) || len(value) <= len(compressionMarker) {
+		return string(value), nil
+	}
+
+	codec, compressed := value[len(compressionMarker)], value[len(compressionMarker)+1:]
+	var data []byte
+	var err error
+	switch codec {
+	case codecZstd:
+		data, err = zstdDecoder.DecodeAll(compressed, nil)
+	case codecDeflate:
+		reader := flate.NewReader(bytes.NewReader(compressed))
+		data, err = io.ReadAll(reader)
+		reader.Close()
+	case codecAESGCM:
+		// Encrypted values may have been compressed before encryption
+		if data, err = decryptValue(compressed); err != nil {
+			return "", err
+		}
+		return decompressValue(data)
+	default:
+		return "", fmt.Errorf("unknown compression codec %d", codec)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress value: %w", err)
+	}
+	return string(data), nil
+}
+
+// decompressString decompresses a column value scanned into a string
+func decompressString(value *string) error {
+	if !strings.HasPrefix(*value, compressionMarker) {
+		return nil
+	}
+	text, err := decompressValue([]byte(*value))
+	if err != nil {
+		return err
+	}
+	*value = text
+	return nil
+}
+
+// decompressNullString decompresses a column value scanned into a nullable string
+func decompressNullString(value *sql.NullString) error {
+	if !value.Valid {
+		return nil
+	}
+	return decompressString(&value.String)
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newCompressionTestLog(message string) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelError,
+		Message:     message,
+		ServiceName: "payments-service",
+		AgentID:     "payments-agent",
+		Platform:    models.PlatformGo,
+		Metadata:    map[string]interface{}{"payload": strings.Repeat("card declined ", 50)},
+		StackTrace:  strings.Repeat("main.charge()\n\t/app/payments/charge.go:42\n", 40),
+	}
+}
+
+func TestSQLiteStorage_Compression(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+	storage.SetIntegrityHashing(true, "")
+
+	ctx := context.Background()
+	plain := newCompressionTestLog("stored before compression was enabled")
+	if err := storage.Store(ctx, []models.LogEntry{plain}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	if err := storage.SetCompression(CompressionConfig{
+		Columns: []string{CompressMessage, CompressStackTrace, CompressMetadata},
+		MinSize: 64,
+	}); err != nil {
+		t.Fatalf("Failed to set compression: %v", err)
+	}
+	compressed := newCompressionTestLog("payment failed: " + strings.Repeat("upstream timeout ", 20))
+	if err := storage.Store(ctx, []models.LogEntry{compressed}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	// Large values are stored as compressed blobs, small ones as they are
+	var messageType, stackTraceType, metadataType string
+	var stackTraceSize int
+	err = storage.db.QueryRowContext(ctx,
+		"SELECT typeof(message), typeof(stack_trace), typeof(metadata), length(stack_trace) FROM log_entries WHERE id = ?",
+		compressed.ID,
+	).Scan(&messageType, &stackTraceType, &metadataType, &stackTraceSize)
+	if err != nil {
+		t.Fatalf("Failed to read stored columns: %v", err)
+	}
+	if messageType != "blob" || stackTraceType != "blob" || metadataType != "blob" {
+		t.Errorf("Expected compressed blobs, got %s, %s and %s", messageType, stackTraceType, metadataType)
+	}
+	if stackTraceSize >= len(compressed.StackTrace) {
+		t.Errorf("Expected the stack trace to shrink, got %d of %d bytes", stackTraceSize, len(compressed.StackTrace))
+	}
+
+	// Reads are transparent for both entries
+	result, err := storage.Query(ctx, models.LogFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if len(result.Logs) != 2 {
+		t.Fatalf("Expected 2 logs, got %d", len(result.Logs))
+	}
+	for _, log := range result.Logs {
+		expected := plain
+		if log.ID == compressed.ID {
+			expected = compressed
+		}
+		if log.Message != expected.Message || log.StackTrace != expected.StackTrace || log.Metadata["payload"] != expected.Metadata["payload"] {
+			t.Errorf("Expected log %s to read back unchanged", log.ID)
+		}
+	}
+
+	// Message filters match compressed messages
+	result, err = storage.Query(ctx, models.LogFilter{MessageContains: "upstream timeout", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 1 || len(result.Logs) != 1 || result.Logs[0].ID != compressed.ID {
+		t.Errorf("Expected the compressed message to match, got %d logs", result.TotalCount)
+	}
+
+	// Content hashes cover the uncompressed values
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != 2 || report.Mismatched != 0 {
+		t.Errorf("Expected 2 matching entries, got %+v", report)
+	}
+}
+
+func TestSQLiteStorage_SetCompressionRejectsUnknownColumns(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	if err := storage.SetCompression(CompressionConfig{Columns: []string{"device_info"}}); err == nil {
+		t.Error("Expected an unknown column to be rejected")
+	}
+}
+
+func TestDecompressValue_Deflate(t *testing.T) {
+	var buf bytes.Buffer
+	buf.WriteString(compressionMarker)
+	buf.WriteByte(codecDeflate)
+	writer, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	writer.Write([]byte("written before zstd"))
+	writer.Close()
+
+	text, err := decompressValue(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to decompress value: %v", err)
+	}
+	if text != "written before zstd" {
+		t.Errorf("Expected the deflate value to read back, got %q", text)
+	}
+}
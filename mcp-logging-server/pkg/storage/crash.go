@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// DefaultCrashGroupLimit is the number of crash groups returned when the filter sets no limit
+const DefaultCrashGroupLimit = 20
+
+// GroupCrashes groups the crash reports matching the filter by signature, most frequent first
+func (s *SQLiteStorage) GroupCrashes(ctx context.Context, filter models.LogFilter) ([]models.CrashGroup, error) {
+	filter.CrashesOnly = true
+	whereClause, args := buildWhereClause(filter)
+	whereClause += " AND crash_signature IS NOT NULL"
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultCrashGroupLimit
+	}
+
+	// Aggregates lose the column type, so times are read back as text
+	query := fmt.Sprintf(`
+		SELECT crash_signature, COUNT(*), COUNT(DISTINCT agent_id), GROUP_CONCAT(DISTINCT service_name),
+			   MIN(timestamp), MAX(timestamp)
+		FROM log_entries %s
+		GROUP BY crash_signature
+		ORDER BY COUNT(*) DESC, MAX(timestamp) DESC
+		LIMIT ?
+	`, whereClause)
+
+	rows, err := s.reader().QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to group crashes: %w", err)
+	}
+
+	groups := make([]models.CrashGroup, 0)
+	for rows.Next() {
+		var group models.CrashGroup
+		var services, firstSeen, lastSeen string
+
+		if err := rows.Scan(&group.Signature, &group.Count, &group.AffectedAgents, &services, &firstSeen, &lastSeen); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan crash group: %w", err)
+		}
+
+		group.Services = strings.Split(services, ",")
+		sort.Strings(group.Services)
+		if group.FirstSeen, err = parseAggregateTime(firstSeen); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if group.LastSeen, err = parseAggregateTime(lastSeen); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	rows.Close()
+
+	// Title each group after its most recent report
+	for i := range groups {
+		var crashJSON string
+		err := s.reader().QueryRowContext(ctx, `
+			SELECT id, crash FROM log_entries
+			WHERE crash_signature = ?
+			ORDER BY timestamp DESC
+			LIMIT 1
+		`, groups[i].Signature).Scan(&groups[i].LatestID, &crashJSON)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest crash of group %s: %w", groups[i].Signature, err)
+		}
+
+		if err := decompressString(&crashJSON); err != nil {
+			return nil, fmt.Errorf("failed to read crash of log %s: %w", groups[i].LatestID, err)
+		}
+		var crash models.CrashInfo
+		if err := json.Unmarshal([]byte(crashJSON), &crash); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal crash for log %s: %w", groups[i].LatestID, err)
+		}
+		groups[i].Title = crash.Title()
+	}
+
+	return groups, nil
+}
+
+// parseAggregateTime parses a timestamp returned by an SQLite aggregate function
+func parseAggregateTime(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("failed to parse timestamp %q", value)
+}
+
+// GroupCrashes groups the crash reports matching the filter by signature, most frequent first
+func (s *MemoryStorage) GroupCrashes(ctx context.Context, filter models.LogFilter) ([]models.CrashGroup, error) {
+	filter.CrashesOnly = true
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	type crashGroupState struct {
+		group    models.CrashGroup
+		agents   map[string]bool
+		services map[string]bool
+	}
+	states := make(map[string]*crashGroupState)
+
+	for _, entry := range s.entries {
+		if !matchesFilter(entry, filter) || entry.Crash.Signature == "" {
+			continue
+		}
+
+		state, ok := states[entry.Crash.Signature]
+		if !ok {
+			state = &crashGroupState{
+				group: models.CrashGroup{
+					Signature: entry.Crash.Signature,
+					FirstSeen: entry.Timestamp,
+				},
+				agents:   make(map[string]bool),
+				services: make(map[string]bool),
+			}
+			states[entry.Crash.Signature] = state
+		}
+
+		group := &state.group
+		group.Count++
+		state.agents[entry.AgentID] = true
+		state.services[entry.ServiceName] = true
+		if entry.Timestamp.Before(group.FirstSeen) {
+			group.FirstSeen = entry.Timestamp
+		}
+		if group.LatestID == "" || !entry.Timestamp.Before(group.LastSeen) {
+			group.LastSeen = entry.Timestamp
+			group.LatestID = entry.ID
+			group.Title = entry.Crash.Title()
+		}
+	}
+
+	groups := make([]models.CrashGroup, 0, len(states))
+	for _, state := range states {
+		state.group.AffectedAgents = len(state.agents)
+		for service := range state.services {
+			state.group.Services = append(state.group.Services, service)
+		}
+		sort.Strings(state.group.Services)
+		groups = append(groups, state.group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].LastSeen.After(groups[j].LastSeen)
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultCrashGroupLimit
+	}
+	if len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newCrashTestLog(serviceName, agentID, exceptionType string, timestamp time.Time) models.LogEntry {
+	crash := &models.CrashInfo{
+		ExceptionType: exceptionType,
+		Threads: []models.ThreadDump{
+			{Crashed: true, Frames: []models.StackFrame{{Function: "MainActivity.onCreate", Module: "com.example", InApp: true}}},
+		},
+		Breadcrumbs: []models.Breadcrumb{{Timestamp: timestamp.Add(-time.Second), Category: "navigation", Message: "Opened checkout"}},
+	}
+	crash.Signature = crash.ComputeSignature()
+
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		Level:       models.LogLevelFatal,
+		Message:     crash.Title(),
+		ServiceName: serviceName,
+		AgentID:     agentID,
+		Platform:    models.PlatformKotlin,
+		Crash:       crash,
+	}
+}
+
+func TestSQLiteStorage_Crashes(t *testing.T) {
+	storage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "crashes.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+	storage.SetIntegrityHashing(true, "")
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	logs := []models.LogEntry{
+		newCrashTestLog("android-app", "device-1", "java.lang.NullPointerException", now.Add(-3*time.Hour)),
+		newCrashTestLog("android-app", "device-2", "java.lang.NullPointerException", now.Add(-2*time.Hour)),
+		newCrashTestLog("tv-app", "device-2", "java.lang.NullPointerException", now.Add(-time.Hour)),
+		newCrashTestLog("android-app", "device-1", "java.lang.OutOfMemoryError", now.Add(-30*time.Minute)),
+		{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelInfo, Message: "Not a crash", ServiceName: "android-app", AgentID: "device-1", Platform: models.PlatformKotlin},
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	// Crash details survive the round trip
+	stored, err := storage.GetByIDs(ctx, []string{logs[0].ID})
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Failed to get crash report: %v", err)
+	}
+	if stored[0].Crash == nil || stored[0].Crash.Signature != logs[0].Crash.Signature || len(stored[0].Crash.Breadcrumbs) != 1 {
+		t.Errorf("Expected crash details to be stored, got %+v", stored[0].Crash)
+	}
+
+	result, err := storage.Query(ctx, models.LogFilter{CrashesOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to query crashes: %v", err)
+	}
+	if result.TotalCount != 4 {
+		t.Errorf("Expected 4 crash reports, got %d", result.TotalCount)
+	}
+
+	groups, err := storage.GroupCrashes(ctx, models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to group crashes: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 crash groups, got %d", len(groups))
+	}
+	top := groups[0]
+	if top.Signature != logs[0].Crash.Signature || top.Count != 3 || top.AffectedAgents != 2 || top.LatestID != logs[2].ID {
+		t.Errorf("Unexpected top crash group: %+v", top)
+	}
+	if len(top.Services) != 2 || top.Services[0] != "android-app" || top.Services[1] != "tv-app" {
+		t.Errorf("Expected both services in the group, got %v", top.Services)
+	}
+	if !top.FirstSeen.Equal(logs[0].Timestamp) || !top.LastSeen.Equal(logs[2].Timestamp) {
+		t.Errorf("Unexpected first and last seen: %v %v", top.FirstSeen, top.LastSeen)
+	}
+	if top.Title != "java.lang.NullPointerException in com.example!MainActivity.onCreate" {
+		t.Errorf("Unexpected title: %s", top.Title)
+	}
+
+	groups, _ = storage.GroupCrashes(ctx, models.LogFilter{ServiceName: "android-app", Limit: 1})
+	if len(groups) != 1 || groups[0].Count != 2 {
+		t.Errorf("Expected the filtered top group with 2 crashes, got %+v", groups)
+	}
+
+	// Crash reports are covered by integrity hashes
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != 5 || report.Mismatched != 0 {
+		t.Errorf("Expected 5 matching entries, got %+v", report)
+	}
+	if _, err := storage.db.Exec("UPDATE log_entries SET crash = REPLACE(crash, 'Opened checkout', 'Opened cart') WHERE id = ?", logs[0].ID); err != nil {
+		t.Fatalf("Failed to tamper with crash: %v", err)
+	}
+	report, _ = storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if report.Mismatched != 1 {
+		t.Errorf("Expected the altered crash report to mismatch, got %+v", report)
+	}
+}
+
+func TestMemoryStorage_GroupCrashes(t *testing.T) {
+	storage := NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	logs := []models.LogEntry{
+		newCrashTestLog("android-app", "device-1", "java.lang.OutOfMemoryError", now.Add(-2*time.Hour)),
+		newCrashTestLog("android-app", "device-1", "java.lang.NullPointerException", now.Add(-time.Hour)),
+		newCrashTestLog("android-app", "device-2", "java.lang.NullPointerException", now),
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	groups, err := storage.GroupCrashes(ctx, models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to group crashes: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Count != 2 || groups[0].AffectedAgents != 2 || groups[0].LatestID != logs[2].ID {
+		t.Errorf("Unexpected crash groups: %+v", groups)
+	}
+
+	result, _ := storage.Query(ctx, models.LogFilter{CrashSignature: logs[0].Crash.Signature})
+	if result.TotalCount != 1 || result.Logs[0].ID != logs[0].ID {
+		t.Errorf("Expected the crash with the signature, got %+v", result.Logs)
+	}
+}
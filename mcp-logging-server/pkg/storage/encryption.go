@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// EncryptionKeySize is the size in bytes of the AES-256 keys that encrypt stored values
+const EncryptionKeySize = 32
+
+// Encrypted values are stored as blobs starting with the compression marker, the codec, the ID
+// of the key and the nonce, followed by the sealed value. The sealed value is the value as it
+// would be stored without encryption, so compressed values are compressed before encryption.
+const (
+	codecAESGCM = byte(3)
+	keyIDSize   = 4
+)
+
+// encryptionKey is a registered encryption key. Besides sealing values with random nonces, it
+// seals values compared in SQL, such as request IDs, with nonces derived from the value, so
+// that equal values are stored as equal blobs, and hashes the terms of the search index.
+type encryptionKey struct {
+	id       [keyIDSize]byte
+	aead     cipher.AEAD
+	nonceKey []byte // HMAC key of the nonces of values compared in SQL
+	termKey  []byte // HMAC key of search index terms, see blindTerm
+}
+
+// encryptionKeys holds the keys values can be decrypted with, by key ID. Keys are registered
+// process-wide since values are also decrypted by the decompress SQL function of the driver
+// and search index terms are hashed by a Bleve token filter.
+var (
+	encryptionKeysMu sync.RWMutex
+	encryptionKeys   = make(map[[keyIDSize]byte]*encryptionKey)
+)
+
+// ParseEncryptionKey decodes a 32-byte key given as 64 hexadecimal digits or in base64
+func ParseEncryptionKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	key, err := hex.DecodeString(value)
+	if err != nil {
+		key, err = base64.StdEncoding.DecodeString(value)
+	}
+	if err != nil || len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, given as hexadecimal digits or in base64", EncryptionKeySize)
+	}
+	return key, nil
+}
+
+// RegisterEncryptionKeys makes values encrypted with the keys readable. Register the keys of
+// entries written before a key rotation along with the current key, and before opening a
+// storage that rebuilds its search index from encrypted entries.
+func RegisterEncryptionKeys(keys ...[]byte) error {
+	for _, key := range keys {
+		registered, err := newEncryptionKey(key)
+		if err != nil {
+			return err
+		}
+		encryptionKeysMu.Lock()
+		encryptionKeys[registered.id] = registered
+		encryptionKeysMu.Unlock()
+	}
+	return nil
+}
+
+// registeredKey returns the registered key with an ID, nil if there is none
+func registeredKey(id [keyIDSize]byte) *encryptionKey {
+	encryptionKeysMu.RLock()
+	defer encryptionKeysMu.RUnlock()
+	return encryptionKeys[id]
+}
+
+// SetEncryption encrypts the free-text columns of new entries, summaries, query audit records,
+// incidents and legal holds with key, which is registered for decryption as well. Values stored
+// without encryption stay readable.
+func (s *SQLiteStorage) SetEncryption(key []byte) error {
+	if err := RegisterEncryptionKeys(key); err != nil {
+		return err
+	}
+	s.encryption = registeredKey(encryptionKeyID(key))
+	return nil
+}
+
+// newEncryptionKey derives the cipher and HMAC keys of a key
+func newEncryptionKey(key []byte) (*encryptionKey, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonceKey := sha256.Sum256(append([]byte("mcp-logging nonce key "), key...))
+	termKey := sha256.Sum256(append([]byte("mcp-logging search term key "), key...))
+	return &encryptionKey{
+		id:       encryptionKeyID(key),
+		aead:     aead,
+		nonceKey: nonceKey[:],
+		termKey:  termKey[:],
+	}, nil
+}
+
+// encryptionKeyID identifies the key a value was encrypted with, without revealing the key
+func encryptionKeyID(key []byte) [keyIDSize]byte {
+	sum := sha256.Sum256(append([]byte("mcp-logging encryption key "), key...))
+	var id [keyIDSize]byte
+	copy(id[:], sum[:])
+	return id
+}
+
+// seal encrypts a value with a random nonce, or with a nonce derived from the value when
+// deterministic, so that equal values are sealed to equal blobs
+func (k *encryptionKey) seal(plaintext []byte, deterministic bool) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize())
+	if deterministic {
+		mac := hmac.New(sha256.New, k.nonceKey)
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := make([]byte, 0, len(compressionMarker)+1+keyIDSize+len(nonce)+len(plaintext)+k.aead.Overhead())
+	sealed = append(sealed, compressionMarker...)
+	sealed = append(sealed, codecAESGCM)
+	sealed = append(sealed, k.id[:]...)
+	sealed = append(sealed, nonce...)
+	return k.aead.Seal(sealed, nonce, plaintext, nil), nil
+}
+
+// encryptValue seals a column value as it would be stored without encryption, a string or a
+// compressed blob
+func (s *SQLiteStorage) encryptValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return s.encryption.seal([]byte(v), false)
+	case []byte:
+		return s.encryption.seal(v, false)
+	default:
+		return nil, fmt.Errorf("cannot encrypt value of type %T", value)
+	}
+}
+
+// encryptColumn returns the value to store for a free-text column that is not compressed:
+// the value encrypted when encryption is enabled, the value itself otherwise
+func (s *SQLiteStorage) encryptColumn(value *string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if s.encryption == nil {
+		return *value, nil
+	}
+	return s.encryption.seal([]byte(*value), false)
+}
+
+// encryptNullString encrypts a nullable free-text column like encryptColumn
+func (s *SQLiteStorage) encryptNullString(value sql.NullString) (interface{}, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	return s.encryptColumn(&value.String)
+}
+
+// encryptComparable returns the value to store for a column compared in SQL, such as request
+// IDs: the value sealed to the same blob every time it is encrypted with the same key when
+// encryption is enabled, the value itself otherwise. See comparableValues for the values to
+// compare it with.
+func (s *SQLiteStorage) encryptComparable(value *string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if s.encryption == nil {
+		return *value, nil
+	}
+	return s.encryption.seal([]byte(*value), true)
+}
+
+// comparableValues returns the values a column stored with encryptComparable may hold for a
+// value: the value itself, for rows stored without encryption, and its encryption with each
+// registered key
+func comparableValues(value string) []interface{} {
+	encryptionKeysMu.RLock()
+	defer encryptionKeysMu.RUnlock()
+
+	values := []interface{}{value}
+	for _, key := range encryptionKeys {
+		if sealed, err := key.seal([]byte(value), true); err == nil {
+			values = append(values, sealed)
+		}
+	}
+	return values
+}
+
+// decryptValue opens a value sealed by encryptValue, given without the marker and codec, and
+// returns the value as it would be stored without encryption
+func decryptValue(sealed []byte) ([]byte, error) {
+	if len(sealed) < keyIDSize {
+		return nil, fmt.Errorf("encrypted value is truncated")
+	}
+	var id [keyIDSize]byte
+	copy(id[:], sealed)
+
+	key := registeredKey(id)
+	if key == nil {
+		return nil, fmt.Errorf("value is encrypted with an unknown key %x, configure it under storage.encryption", id)
+	}
+
+	sealed = sealed[keyIDSize:]
+	if len(sealed) < key.aead.NonceSize() {
+		return nil, fmt.Errorf("encrypted value is truncated")
+	}
+	plaintext, err := key.aead.Open(nil, sealed[:key.aead.NonceSize()], sealed[key.aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newTestEncryptionKey(t *testing.T) []byte {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestSQLiteStorage_Encryption(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "logs.db")
+	indexPath := filepath.Join(dir, "index")
+	key := newTestEncryptionKey(t)
+
+	// Entries indexed before encryption was enabled leave plaintext shards behind
+	storage, err := NewSQLiteStorageWithSearchConfig(dbPath, SearchConfig{IndexPath: indexPath})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	ctx := context.Background()
+	plain := newCompressionTestLog("refund 5500-0000-0000-0004 approved")
+	if err := storage.Store(ctx, []models.LogEntry{plain}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+	storage.Close()
+	if !dirContains(t, indexPath, "approved") {
+		t.Fatal("Expected the unencrypted index to hold the message")
+	}
+
+	storage, err = NewSQLiteStorageWithSearchConfig(dbPath, SearchConfig{IndexPath: indexPath, EncryptionKey: key})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	storage.SetIntegrityHashing(true, "")
+	if err := storage.SetCompression(CompressionConfig{Columns: []string{CompressStackTrace}, MinSize: 64}); err != nil {
+		t.Fatalf("Failed to set compression: %v", err)
+	}
+	if err := storage.SetEncryption(key); err != nil {
+		t.Fatalf("Failed to set encryption: %v", err)
+	}
+
+	entry := newCompressionTestLog("card 4111-1111-1111-1111 declined")
+	entry.RequestID = "req-checkout-7731"
+	entry.DeviceInfo = &models.DeviceInfo{Platform: "ios", Model: "iPhone of Dana Scully"}
+	entry.SourceLocation = &models.SourceLocation{File: "/app/payments/charge.go", Line: 42, Function: "chargeCard"}
+	if err := storage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+	if _, err := storage.AnnotateLog(ctx, entry.ID, models.LogAnnotation{Text: "customer called support"}); err != nil {
+		t.Fatalf("Failed to annotate log: %v", err)
+	}
+
+	// No free-text column holds the plaintext
+	var message, stackTrace, metadata, requestID, deviceInfo, sourceLocation, annotations []byte
+	err = storage.db.QueryRowContext(ctx, `SELECT message, stack_trace, metadata, request_id, device_info, source_location, annotations
+		FROM log_entries WHERE id = ?`, entry.ID).
+		Scan(&message, &stackTrace, &metadata, &requestID, &deviceInfo, &sourceLocation, &annotations)
+	if err != nil {
+		t.Fatalf("Failed to read stored columns: %v", err)
+	}
+	for _, value := range [][]byte{message, stackTrace, metadata, requestID, deviceInfo, sourceLocation, annotations} {
+		if !bytes.HasPrefix(value, []byte(compressionMarker+string(codecAESGCM))) {
+			t.Errorf("Expected an encrypted value, got %q", value)
+		}
+	}
+	if bytes.Contains(message, []byte("declined")) || bytes.Contains(deviceInfo, []byte("Scully")) {
+		t.Error("Expected the columns to be encrypted")
+	}
+
+	// Reads, filters, search and integrity verification see the plaintext
+	result, err := storage.Query(ctx, models.LogFilter{MessageContains: "4111", Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if len(result.Logs) != 1 || result.Logs[0].Message != entry.Message || result.Logs[0].StackTrace != entry.StackTrace ||
+		result.Logs[0].RequestID != entry.RequestID || result.Logs[0].DeviceInfo == nil || *result.Logs[0].DeviceInfo != *entry.DeviceInfo ||
+		result.Logs[0].SourceLocation == nil || *result.Logs[0].SourceLocation != *entry.SourceLocation {
+		t.Fatalf("Expected the entry to read back unchanged, got %+v", result.Logs)
+	}
+	if len(result.Logs[0].Annotations) != 1 || result.Logs[0].Annotations[0].Text != "customer called support" {
+		t.Errorf("Expected the annotation to read back, got %+v", result.Logs[0].Annotations)
+	}
+	result, err = storage.Query(ctx, models.LogFilter{RequestID: entry.RequestID, Limit: 10})
+	if err != nil || len(result.Logs) != 1 || result.Logs[0].ID != entry.ID {
+		t.Errorf("Expected the request ID filter to find the entry, got %+v (%v)", result, err)
+	}
+	search, err := storage.SearchLogs(ctx, "Declined", models.LogFilter{Limit: 10})
+	if err != nil || search.TotalCount != 1 {
+		t.Fatalf("Expected the entry to be found by search, got %v (%v)", search, err)
+	}
+	hit := search.Hits[0]
+	if fragments := hit.Fragments["message"]; len(fragments) != 1 || !strings.Contains(fragments[0], "<mark>declined</mark>") {
+		t.Errorf("Expected the message to be highlighted, got %v", hit.Fragments)
+	}
+	if len(hit.Matches) != 1 || hit.Matches[0].Term != "declined" {
+		t.Errorf("Expected the matched term, got %+v", hit.Matches)
+	}
+	search, err = storage.SearchLogs(ctx, "", models.LogFilter{RequestID: entry.RequestID, Limit: 10})
+	if err != nil || search.TotalCount != 1 {
+		t.Errorf("Expected the entry to be found by request ID, got %v (%v)", search, err)
+	}
+	search, err = storage.SearchLogs(ctx, "approved", models.LogFilter{Limit: 10})
+	if err != nil || search.TotalCount != 1 {
+		t.Errorf("Expected the rebuilt index to find the earlier entry, got %v (%v)", search, err)
+	}
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil || report.Mismatched != 0 {
+		t.Errorf("Expected content hashes to match, got %+v (%v)", report, err)
+	}
+	storage.Close()
+
+	// The index files hold neither the new entry nor the earlier one
+	for _, text := range []string{"declined", "approved", "req-checkout-7731", "Scully", "chargeCard"} {
+		if dirContains(t, indexPath, text) {
+			t.Errorf("Expected the index files not to contain %q", text)
+		}
+	}
+
+	// The encrypted shards are reopened rather than rebuilt
+	storage, err = NewSQLiteStorageWithSearchConfig(dbPath, SearchConfig{IndexPath: indexPath, EncryptionKey: key})
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer storage.Close()
+	if storage.search.NeedsRebuild() {
+		t.Error("Expected the encrypted shards to be kept")
+	}
+	search, err = storage.SearchLogs(ctx, "declined", models.LogFilter{Limit: 10})
+	if err != nil || search.TotalCount != 1 {
+		t.Errorf("Expected the reopened index to find the entry, got %v (%v)", search, err)
+	}
+}
+
+// dirContains reports whether any file under a directory contains text
+func dirContains(t *testing.T, dir, text string) bool {
+	found := false
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		found = found || bytes.Contains(data, []byte(text))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	return found
+}
+
+func TestDecryptValue_UnknownKey(t *testing.T) {
+	key := newTestEncryptionKey(t)
+	encryption, err := newEncryptionKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage := &SQLiteStorage{encryption: encryption}
+
+	sealed, err := storage.encryptValue("secret")
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if _, err := decompressValue(sealed.([]byte)); err == nil || !strings.Contains(err.Error(), "unknown key") {
+		t.Errorf("Expected values of an unregistered key to fail, got %v", err)
+	}
+
+	if err := RegisterEncryptionKeys(key); err != nil {
+		t.Fatal(err)
+	}
+	if text, err := decompressValue(sealed.([]byte)); err != nil || text != "secret" {
+		t.Errorf("Expected the registered key to decrypt, got %q (%v)", text, err)
+	}
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := newTestEncryptionKey(t)
+	if parsed, err := ParseEncryptionKey(hex.EncodeToString(key)); err != nil || !bytes.Equal(parsed, key) {
+		t.Errorf("Expected a hexadecimal key to parse, got %v", err)
+	}
+	for _, value := range []string{"", "too-short", hex.EncodeToString(key[:16])} {
+		if _, err := ParseEncryptionKey(value); err == nil {
+			t.Errorf("Expected ParseEncryptionKey(%q) to fail", value)
+		}
+	}
+}
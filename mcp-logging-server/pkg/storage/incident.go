@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// CreateIncident stores an incident with its initial evidence. The filter is stored as a
+// snapshot, see models.SnapshotFilter.
+func (s *SQLiteStorage) CreateIncident(ctx context.Context, incident models.Incident) (*models.Incident, error) {
+	now := time.Now().UTC()
+	incident.ID = uuid.New().String()
+	incident.CreatedAt = now
+	incident.UpdatedAt = now
+	incident.LogIDs = uniqueIDs(nil, incident.LogIDs)
+	if incident.Filter != nil {
+		incident.Filter = models.SnapshotFilter(*incident.Filter, now)
+	}
+
+	filterJSON, err := marshalFilter(incident.Filter)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := s.encryptNullString(filterJSON)
+	if err != nil {
+		return nil, err
+	}
+	title, err := s.encryptColumn(&incident.Title)
+	if err != nil {
+		return nil, err
+	}
+	description, err := s.encryptNullString(nullString(incident.Description))
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO incidents (id, title, description, filter, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`,
+		incident.ID,
+		title,
+		description,
+		filter,
+		incident.CreatedAt,
+		incident.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create incident: %w", err)
+	}
+
+	if err := insertIncidentLogs(ctx, tx, incident.ID, incident.LogIDs, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &incident, nil
+}
+
+// GetIncident returns an incident with its attached log IDs, nil if it does not exist
+func (s *SQLiteStorage) GetIncident(ctx context.Context, id string) (*models.Incident, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, title, description, filter, created_at, updated_at
+		FROM incidents
+		WHERE id = ?
+	`, id)
+	incident, err := scanIncident(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT log_id FROM incident_logs
+		WHERE incident_id = ?
+		ORDER BY attached_at, rowid
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs of incident %s: %w", id, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var logID string
+		if err := rows.Scan(&logID); err != nil {
+			return nil, fmt.Errorf("failed to scan incident log: %w", err)
+		}
+		incident.LogIDs = append(incident.LogIDs, logID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return incident, nil
+}
+
+// ListIncidents returns all incidents without their log IDs, newest first
+func (s *SQLiteStorage) ListIncidents(ctx context.Context) ([]models.Incident, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, title, description, filter, created_at, updated_at
+		FROM incidents
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query incidents: %w", err)
+	}
+	defer rows.Close()
+
+	incidents := make([]models.Incident, 0)
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, *incident)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return incidents, nil
+}
+
+// AttachToIncident adds log IDs to an incident and replaces its filter if the attachment has one
+func (s *SQLiteStorage) AttachToIncident(ctx context.Context, id string, attachment models.IncidentAttachment) (*models.Incident, error) {
+	now := time.Now().UTC()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "UPDATE incidents SET updated_at = ? WHERE id = ?", now, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update incident %s: %w", id, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrIncidentNotFound
+	}
+
+	if attachment.Filter != nil {
+		filterJSON, err := marshalFilter(models.SnapshotFilter(*attachment.Filter, now))
+		if err != nil {
+			return nil, err
+		}
+		filter, err := s.encryptNullString(filterJSON)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE incidents SET filter = ? WHERE id = ?", filter, id); err != nil {
+			return nil, fmt.Errorf("failed to update filter of incident %s: %w", id, err)
+		}
+	}
+
+	if err := insertIncidentLogs(ctx, tx, id, uniqueIDs(nil, attachment.LogIDs), now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetIncident(ctx, id)
+}
+
+// insertIncidentLogs attaches log IDs to an incident, ignoring those already attached
+func insertIncidentLogs(ctx context.Context, tx *sql.Tx, incidentID string, logIDs []string, attachedAt time.Time) error {
+	for _, logID := range logIDs {
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO incident_logs (incident_id, log_id, attached_at)
+			VALUES (?, ?, ?)
+		`, incidentID, logID, attachedAt)
+		if err != nil {
+			return fmt.Errorf("failed to attach log %s to incident %s: %w", logID, incidentID, err)
+		}
+	}
+	return nil
+}
+
+// scanIncident scans an incident row without its log IDs
+func scanIncident(row interface{ Scan(...interface{}) error }) (*models.Incident, error) {
+	var incident models.Incident
+	var description, filterJSON sql.NullString
+
+	err := row.Scan(&incident.ID, &incident.Title, &description, &filterJSON, &incident.CreatedAt, &incident.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan incident: %w", err)
+	}
+	for _, value := range []*string{&incident.Title, &description.String, &filterJSON.String} {
+		if err := decompressString(value); err != nil {
+			return nil, fmt.Errorf("failed to read incident %s: %w", incident.ID, err)
+		}
+	}
+
+	incident.Description = description.String
+	if filterJSON.Valid {
+		var filter models.LogFilter
+		if err := json.Unmarshal([]byte(filterJSON.String), &filter); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal filter of incident %s: %w", incident.ID, err)
+		}
+		incident.Filter = &filter
+	}
+
+	return &incident, nil
+}
+
+// marshalFilter encodes an incident's filter for storage, nil stays NULL
+func marshalFilter(filter *models.LogFilter) (sql.NullString, error) {
+	if filter == nil {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(filter)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal filter: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// uniqueIDs appends the IDs that are not yet in existing, in order
+func uniqueIDs(existing []string, ids []string) []string {
+	seen := make(map[string]bool, len(existing)+len(ids))
+	for _, id := range existing {
+		seen[id] = true
+	}
+	result := existing
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// CreateIncident stores an incident with its initial evidence
+func (s *MemoryStorage) CreateIncident(ctx context.Context, incident models.Incident) (*models.Incident, error) {
+	now := time.Now().UTC()
+	incident.ID = uuid.New().String()
+	incident.CreatedAt = now
+	incident.UpdatedAt = now
+	incident.LogIDs = uniqueIDs(nil, incident.LogIDs)
+	if incident.Filter != nil {
+		incident.Filter = models.SnapshotFilter(*incident.Filter, now)
+	}
+
+	s.mu.Lock()
+	s.incidents[incident.ID] = incident
+	s.mu.Unlock()
+
+	return copyIncident(incident), nil
+}
+
+// GetIncident returns an incident with its attached log IDs, nil if it does not exist
+func (s *MemoryStorage) GetIncident(ctx context.Context, id string) (*models.Incident, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	incident, ok := s.incidents[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyIncident(incident), nil
+}
+
+// ListIncidents returns all incidents without their log IDs, newest first
+func (s *MemoryStorage) ListIncidents(ctx context.Context) ([]models.Incident, error) {
+	s.mu.RLock()
+	incidents := make([]models.Incident, 0, len(s.incidents))
+	for _, incident := range s.incidents {
+		incident.LogIDs = nil
+		incidents = append(incidents, *copyIncident(incident))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].CreatedAt.After(incidents[j].CreatedAt)
+	})
+	return incidents, nil
+}
+
+// AttachToIncident adds log IDs to an incident and replaces its filter if the attachment has one
+func (s *MemoryStorage) AttachToIncident(ctx context.Context, id string, attachment models.IncidentAttachment) (*models.Incident, error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, ok := s.incidents[id]
+	if !ok {
+		return nil, ErrIncidentNotFound
+	}
+
+	// Copied, so that incidents returned earlier do not change
+	incident.LogIDs = uniqueIDs(append([]string(nil), incident.LogIDs...), attachment.LogIDs)
+	if attachment.Filter != nil {
+		incident.Filter = models.SnapshotFilter(*attachment.Filter, now)
+	}
+	incident.UpdatedAt = now
+	s.incidents[id] = incident
+
+	return copyIncident(incident), nil
+}
+
+// copyIncident returns a copy of an incident that shares no slices with the stored one
+func copyIncident(incident models.Incident) *models.Incident {
+	incident.LogIDs = append([]string(nil), incident.LogIDs...)
+	if incident.Filter != nil {
+		filter := *incident.Filter
+		incident.Filter = &filter
+	}
+	return &incident
+}
@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestIncidentStores(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "incidents.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]IncidentStore{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			created, err := store.CreateIncident(ctx, models.Incident{
+				Title:  "Checkout outage",
+				LogIDs: []string{"log-1", "log-2", "log-1"},
+				Filter: &models.LogFilter{ServiceName: "checkout", Level: models.LogLevelError, Limit: 10, Offset: 20},
+			})
+			if err != nil {
+				t.Fatalf("Failed to create incident: %v", err)
+			}
+			if created.ID == "" || created.CreatedAt.IsZero() {
+				t.Errorf("Expected a generated ID and creation time, got %+v", created)
+			}
+			if !reflect.DeepEqual(created.LogIDs, []string{"log-1", "log-2"}) {
+				t.Errorf("Expected duplicate log IDs to be dropped, got %v", created.LogIDs)
+			}
+			if created.Filter.EndTime.IsZero() || created.Filter.Limit != 0 || created.Filter.Offset != 0 {
+				t.Errorf("Expected a snapshot of the filter, got %+v", created.Filter)
+			}
+
+			if _, err := store.CreateIncident(ctx, models.Incident{Title: "Login errors"}); err != nil {
+				t.Fatalf("Failed to create incident: %v", err)
+			}
+
+			updated, err := store.AttachToIncident(ctx, created.ID, models.IncidentAttachment{LogIDs: []string{"log-2", "log-3"}})
+			if err != nil {
+				t.Fatalf("Failed to attach logs: %v", err)
+			}
+			if !reflect.DeepEqual(updated.LogIDs, []string{"log-1", "log-2", "log-3"}) {
+				t.Errorf("Expected the new log IDs to be appended, got %v", updated.LogIDs)
+			}
+			if updated.Filter == nil || updated.Filter.ServiceName != "checkout" {
+				t.Errorf("Expected the filter to be kept, got %+v", updated.Filter)
+			}
+			if !reflect.DeepEqual(created.LogIDs, []string{"log-1", "log-2"}) {
+				t.Errorf("Expected the incident returned earlier to be unchanged, got %v", created.LogIDs)
+			}
+
+			end := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+			updated, err = store.AttachToIncident(ctx, created.ID, models.IncidentAttachment{Filter: &models.LogFilter{ServiceName: "payments", EndTime: end}})
+			if err != nil {
+				t.Fatalf("Failed to attach filter: %v", err)
+			}
+			if updated.Filter.ServiceName != "payments" || !updated.Filter.EndTime.Equal(end) {
+				t.Errorf("Expected the filter to be replaced, got %+v", updated.Filter)
+			}
+
+			incident, err := store.GetIncident(ctx, created.ID)
+			if err != nil {
+				t.Fatalf("Failed to get incident: %v", err)
+			}
+			if incident == nil || incident.Title != "Checkout outage" || len(incident.LogIDs) != 3 || incident.Filter.ServiceName != "payments" {
+				t.Errorf("Expected the stored incident, got %+v", incident)
+			}
+
+			incidents, err := store.ListIncidents(ctx)
+			if err != nil {
+				t.Fatalf("Failed to list incidents: %v", err)
+			}
+			if len(incidents) != 2 || incidents[0].Title != "Login errors" || incidents[1].LogIDs != nil {
+				t.Errorf("Expected the incidents newest first without log IDs, got %+v", incidents)
+			}
+
+			if missing, err := store.GetIncident(ctx, "missing"); err != nil || missing != nil {
+				t.Errorf("Expected nil for an unknown incident, got %+v, %v", missing, err)
+			}
+			if _, err := store.AttachToIncident(ctx, "missing", models.IncidentAttachment{LogIDs: []string{"log-1"}}); !errors.Is(err, ErrIncidentNotFound) {
+				t.Errorf("Expected ErrIncidentNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_SnapshotIncidents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	ctx := context.Background()
+
+	storage, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	created, err := storage.CreateIncident(ctx, models.Incident{Title: "Checkout outage", LogIDs: []string{"log-1"}})
+	if err != nil {
+		t.Fatalf("Failed to create incident: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	restored, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Failed to restore storage: %v", err)
+	}
+	defer restored.Close()
+
+	incident, _ := restored.GetIncident(ctx, created.ID)
+	if incident == nil || incident.Title != "Checkout outage" || len(incident.LogIDs) != 1 {
+		t.Errorf("Expected the incident to be restored, got %+v", incident)
+	}
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxIntegrityMismatches is the number of mismatches listed in a report when no limit is given
+const DefaultMaxIntegrityMismatches = 100
+
+// IntegrityVerifyOptions selects the stored entries to verify
+type IntegrityVerifyOptions struct {
+	StartTime     time.Time `json:"start_time,omitempty"`     // Only verify entries logged at or after this time
+	EndTime       time.Time `json:"end_time,omitempty"`       // Only verify entries logged at or before this time
+	MaxMismatches int       `json:"max_mismatches,omitempty"` // Mismatches listed in the report, all are still counted
+}
+
+// IntegrityMismatch is a stored entry whose content no longer matches its hash
+type IntegrityMismatch struct {
+	ID        string    `json:"id"`
+	Timestamp time.Time `json:"timestamp"`
+	Reason    string    `json:"reason"`
+}
+
+// IntegrityReport is the result of re-hashing stored log entries
+type IntegrityReport struct {
+	StartedAt  time.Time           `json:"started_at"`
+	Duration   time.Duration       `json:"duration"`
+	Checked    int                 `json:"checked"`    // Entries with a content hash that were re-hashed
+	Unhashed   int                 `json:"unhashed"`   // Entries stored without a content hash
+	Mismatched int                 `json:"mismatched"` // Entries whose content does not match their hash
+	Mismatches []IntegrityMismatch `json:"mismatches"`
+	Truncated  bool                `json:"truncated"` // More mismatches were found than listed
+}
+
+// integrityRecord holds the stored column values covered by a content hash. JSON columns
+// are hashed as stored so that re-encoding them cannot change the hash
+type integrityRecord struct {
+	ID             string
+	Timestamp      time.Time
+	Level          string
+	Message        string
+	ServiceName    string
+	AgentID        string
+	Platform       string
+	Metadata       sql.NullString
+	DeviceInfo     sql.NullString
+	StackTrace     sql.NullString
+	SourceLocation sql.NullString
+	ReceivedAt     sql.NullTime
+	ClockSkewed    bool
+	Tags           sql.NullString
+	Crash          sql.NullString
+	RequestID      sql.NullString
+}
+
+// SetIntegrityHashing enables storing a content hash with every new entry. A non-empty key
+// makes the hash an HMAC, so rows cannot be altered and re-hashed without knowing the key
+func (s *SQLiteStorage) SetIntegrityHashing(enabled bool, key string) {
+	s.integrityHashing = enabled
+	s.integrityKey = []byte(key)
+}
+
+// IntegrityHashing reports whether new entries are stored with a content hash
+func (s *SQLiteStorage) IntegrityHashing() bool {
+	return s.integrityHashing
+}
+
+// contentHash returns the hex encoded hash of a record
+func (s *SQLiteStorage) contentHash(record integrityRecord) string {
+	var h hash.Hash
+	if len(s.integrityKey) > 0 {
+		h = hmac.New(sha256.New, s.integrityKey)
+	} else {
+		h = sha256.New()
+	}
+
+	// Length prefixes keep field boundaries unambiguous, null values have no length
+	writeString := func(value string) {
+		fmt.Fprintf(h, "%d:%s;", len(value), value)
+	}
+	writeNullString := func(value sql.NullString) {
+		if !value.Valid {
+			h.Write([]byte("-;"))
+			return
+		}
+		writeString(value.String)
+	}
+	writeTime := func(value time.Time) {
+		writeString(value.UTC().Format(time.RFC3339Nano))
+	}
+
+	writeString(record.ID)
+	writeTime(record.Timestamp)
+	writeString(record.Level)
+	writeString(record.Message)
+	writeString(record.ServiceName)
+	writeString(record.AgentID)
+	writeString(record.Platform)
+	writeNullString(record.Metadata)
+	writeNullString(record.DeviceInfo)
+	writeNullString(record.StackTrace)
+	writeNullString(record.SourceLocation)
+	if record.ReceivedAt.Valid {
+		writeTime(record.ReceivedAt.Time)
+	} else {
+		h.Write([]byte("-;"))
+	}
+	writeString(strconv.FormatBool(record.ClockSkewed))
+	writeNullString(record.Tags)
+
+	// Added after hashing was introduced, only covered when present so earlier hashes still match
+	if record.Crash.Valid {
+		writeString(record.Crash.String)
+	}
+	if record.RequestID.Valid {
+		// Marked so that a request ID cannot be mistaken for a crash report
+		h.Write([]byte("request_id;"))
+		writeString(record.RequestID.String)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyIntegrity re-hashes stored entries and reports those whose content no longer matches
+// the hash stored at ingest. Entries stored while hashing was disabled are counted as unhashed
+func (s *SQLiteStorage) VerifyIntegrity(ctx context.Context, options IntegrityVerifyOptions) (*IntegrityReport, error) {
+	report := &IntegrityReport{
+		StartedAt:  time.Now(),
+		Mismatches: []IntegrityMismatch{},
+	}
+
+	maxMismatches := options.MaxMismatches
+	if maxMismatches <= 0 {
+		maxMismatches = DefaultMaxIntegrityMismatches
+	}
+
+	var conditions []string
+	var args []interface{}
+	if !options.StartTime.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, options.StartTime)
+	}
+	if !options.EndTime.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, options.EndTime)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, content_hash
+		FROM log_entries %s
+		ORDER BY timestamp ASC
+	`, logEntryColumns, whereClause)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query log entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record integrityRecord
+		// Annotations are added after ingestion and not hashed, template IDs are derived from the message
+		var annotations, templateID, storedHash sql.NullString
+
+		err := rows.Scan(
+			&record.ID,
+			&record.Timestamp,
+			&record.Level,
+			&record.Message,
+			&record.ServiceName,
+			&record.AgentID,
+			&record.Platform,
+			&record.Metadata,
+			&record.DeviceInfo,
+			&record.StackTrace,
+			&record.SourceLocation,
+			&record.ReceivedAt,
+			&record.ClockSkewed,
+			&record.Tags,
+			&record.Crash,
+			&annotations,
+			&record.RequestID,
+			&templateID,
+			&storedHash,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan log entry: %w", err)
+		}
+		if err := decompressString(&record.Message); err != nil {
+			return nil, fmt.Errorf("failed to read log entry %s: %w", record.ID, err)
+		}
+		if err := decompressNullString(&record.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to read log entry %s: %w", record.ID, err)
+		}
+		for _, value := range []*sql.NullString{&record.StackTrace, &record.DeviceInfo, &record.SourceLocation, &record.Crash, &record.RequestID} {
+			if err := decompressNullString(value); err != nil {
+				return nil, fmt.Errorf("failed to read log entry %s: %w", record.ID, err)
+			}
+		}
+
+		if !storedHash.Valid || storedHash.String == "" {
+			report.Unhashed++
+			continue
+		}
+
+		report.Checked++
+		if hmac.Equal([]byte(s.contentHash(record)), []byte(storedHash.String)) {
+			continue
+		}
+
+		report.Mismatched++
+		if len(report.Mismatches) < maxMismatches {
+			report.Mismatches = append(report.Mismatches, IntegrityMismatch{
+				ID:        record.ID,
+				Timestamp: record.Timestamp,
+				Reason:    "content hash mismatch",
+			})
+		} else {
+			report.Truncated = true
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	return report, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newIntegrityTestLogs(count int) []models.LogEntry {
+	logs := make([]models.LogEntry, count)
+	for i := range logs {
+		logs[i] = models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now().Add(time.Duration(i) * time.Second),
+			Level:       models.LogLevelInfo,
+			Message:     "Payment captured",
+			ServiceName: "payment-service",
+			AgentID:     "payment-agent",
+			Platform:    models.PlatformGo,
+			Metadata:    map[string]interface{}{"amount": 12345678901234567, "currency": "EUR"},
+			DeviceInfo:  &models.DeviceInfo{Platform: "Server", Version: "1.21"},
+			Tags:        []string{"payments"},
+			ReceivedAt:  time.Now(),
+		}
+	}
+	return logs
+}
+
+func TestSQLiteStorage_VerifyIntegrity(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	// Entries stored before hashing was enabled cannot be verified
+	if err := storage.Store(ctx, newIntegrityTestLogs(1)); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	storage.SetIntegrityHashing(true, "secret")
+	logs := newIntegrityTestLogs(3)
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != 3 || report.Unhashed != 1 || report.Mismatched != 0 {
+		t.Fatalf("Expected 3 checked, 1 unhashed and no mismatches, got %+v", report)
+	}
+
+	// Tamper with a stored message and metadata
+	if _, err := storage.db.Exec("UPDATE log_entries SET message = ? WHERE id = ?", "Payment refunded", logs[0].ID); err != nil {
+		t.Fatalf("Failed to tamper with log entry: %v", err)
+	}
+	if _, err := storage.db.Exec(`UPDATE log_entries SET metadata = '{"amount":1,"currency":"EUR"}' WHERE id = ?`, logs[2].ID); err != nil {
+		t.Fatalf("Failed to tamper with log entry: %v", err)
+	}
+
+	report, err = storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{MaxMismatches: 1})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Mismatched != 2 {
+		t.Errorf("Expected 2 mismatches, got %d", report.Mismatched)
+	}
+	if len(report.Mismatches) != 1 || !report.Truncated {
+		t.Errorf("Expected 1 listed mismatch and a truncated report, got %+v", report)
+	}
+	if len(report.Mismatches) > 0 && report.Mismatches[0].ID != logs[0].ID {
+		t.Errorf("Expected oldest mismatch %s first, got %s", logs[0].ID, report.Mismatches[0].ID)
+	}
+
+	// A different key fails every hashed entry
+	storage.SetIntegrityHashing(true, "other-secret")
+	report, err = storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Mismatched != 3 {
+		t.Errorf("Expected all 3 hashed entries to mismatch with another key, got %d", report.Mismatched)
+	}
+
+	// The time range limits the verified entries
+	storage.SetIntegrityHashing(true, "secret")
+	report, err = storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{StartTime: logs[1].Timestamp})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != 2 || report.Mismatched != 1 {
+		t.Errorf("Expected 2 checked entries with 1 mismatch, got %+v", report)
+	}
+}
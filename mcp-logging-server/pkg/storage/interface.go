@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
 )
@@ -14,11 +16,17 @@ type LogStorage interface {
 	// Query retrieves logs based on filter criteria
 	Query(ctx context.Context, filter models.LogFilter) (*models.LogResult, error)
 
+	// QueryStream returns an iterator over all logs matching the filter, oldest first,
+	// reading rows incrementally. Limit and Offset are honored when set. The caller
+	// must close the iterator.
+	QueryStream(ctx context.Context, filter models.LogFilter) (LogIterator, error)
+
 	// GetByIDs retrieves specific log entries by their IDs
 	GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error)
 
-	// GetServices returns a list of services that have logged entries
-	GetServices(ctx context.Context) ([]models.ServiceInfo, error)
+	// GetServices returns the services that have logged entries and match the filter, most
+	// recently seen first unless the filter sorts them otherwise
+	GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error)
 
 	// HealthCheck returns the health status of the storage system
 	HealthCheck(ctx context.Context) models.HealthStatus
@@ -26,3 +34,168 @@ type LogStorage interface {
 	// Close closes the storage connection
 	Close() error
 }
+
+// ServiceRegistry defines the interface for storages that keep service ownership metadata
+type ServiceRegistry interface {
+	// RegisterService creates or updates a service registration
+	RegisterService(ctx context.Context, registration models.ServiceRegistration) (*models.ServiceRegistration, error)
+
+	// GetServiceRegistration returns the registration for a service, or nil if it is not registered
+	GetServiceRegistration(ctx context.Context, serviceName string) (*models.ServiceRegistration, error)
+
+	// ListServiceRegistrations returns all registered services
+	ListServiceRegistrations(ctx context.Context) ([]models.ServiceRegistration, error)
+
+	// DeleteServiceRegistration removes a service registration and reports whether it existed
+	DeleteServiceRegistration(ctx context.Context, serviceName string) (bool, error)
+}
+
+// ServicePruner defines the interface for storages that can remove agents that stopped logging
+// from the services GetServices returns, without deleting their entries
+type ServicePruner interface {
+	// PurgeServices removes the agents last seen before a time from the services and returns them.
+	// An agent that logs again is returned by GetServices again.
+	PurgeServices(ctx context.Context, notSeenSince time.Time) ([]models.ServiceInfo, error)
+}
+
+// QueryExplainer defines the interface for storages that can report how a query would be executed
+type QueryExplainer interface {
+	// ExplainQuery returns the execution plan for the query a filter produces
+	ExplainQuery(ctx context.Context, filter models.LogFilter) (*QueryPlan, error)
+}
+
+// IntegrityVerifier defines the interface for storages that can detect altered or corrupted entries
+type IntegrityVerifier interface {
+	// VerifyIntegrity re-hashes stored entries and reports those that no longer match their content hash
+	VerifyIntegrity(ctx context.Context, options IntegrityVerifyOptions) (*IntegrityReport, error)
+}
+
+// ErrSearchDisabled is returned by LogSearcher implementations when full-text search is not enabled
+var ErrSearchDisabled = errors.New("full-text search is not enabled")
+
+// LogSearcher defines the interface for storages supporting full-text search with highlighting
+type LogSearcher interface {
+	// SearchLogs searches messages and stack traces for the query text, narrowed by the filter,
+	// and returns the matching entries with highlighted fragments and match positions
+	SearchLogs(ctx context.Context, queryText string, filter models.LogFilter) (*models.SearchResult, error)
+}
+
+// ErrLogNotFound is returned for operations on log entries that do not exist
+var ErrLogNotFound = errors.New("log entry not found")
+
+// LogAnnotator defines the interface for storages that can attach operator annotations to entries
+type LogAnnotator interface {
+	// AnnotateLog appends an annotation to a stored entry without changing its content and returns
+	// all annotations of the entry, oldest first, or ErrLogNotFound
+	AnnotateLog(ctx context.Context, id string, annotation models.LogAnnotation) ([]models.LogAnnotation, error)
+}
+
+// ErrIncidentNotFound is returned for operations on incidents that do not exist
+var ErrIncidentNotFound = errors.New("incident not found")
+
+// IncidentStore defines the interface for storages that can group log entries into incidents
+type IncidentStore interface {
+	// CreateIncident stores an incident and returns it with its generated ID
+	CreateIncident(ctx context.Context, incident models.Incident) (*models.Incident, error)
+
+	// GetIncident returns an incident with its attached log IDs, nil if it does not exist
+	GetIncident(ctx context.Context, id string) (*models.Incident, error)
+
+	// ListIncidents returns all incidents without their log IDs, newest first
+	ListIncidents(ctx context.Context) ([]models.Incident, error)
+
+	// AttachToIncident adds log IDs to an incident, skipping those already attached, and replaces
+	// its filter if the attachment has one. Returns ErrIncidentNotFound for unknown incidents.
+	AttachToIncident(ctx context.Context, id string, attachment models.IncidentAttachment) (*models.Incident, error)
+}
+
+// LegalHoldManager defines the interface for storages that can protect entries from deletion
+type LegalHoldManager interface {
+	// PlaceLegalHold stores a legal hold and returns it with its generated ID
+	PlaceLegalHold(ctx context.Context, hold models.LegalHold) (*models.LegalHold, error)
+
+	// ListLegalHolds returns all active legal holds
+	ListLegalHolds(ctx context.Context) ([]models.LegalHold, error)
+
+	// ReleaseLegalHold removes a legal hold and reports whether it existed
+	ReleaseLegalHold(ctx context.Context, id string) (bool, error)
+}
+
+// SyncStateStore defines the interface for storages that track the delta sync progress of agents
+type SyncStateStore interface {
+	// GetSyncState returns the sync state of an agent, with LastSequence 0 if it never synced
+	GetSyncState(ctx context.Context, agentID string) (*models.SyncState, error)
+
+	// AdvanceSyncState records the highest sequence accepted from an agent, never moving it backwards
+	AdvanceSyncState(ctx context.Context, agentID string, lastSequence int64) error
+}
+
+// CrashGrouper defines the interface for storages that can group crash reports by signature
+type CrashGrouper interface {
+	// GroupCrashes groups the crash reports matching the filter by signature, most frequent first.
+	// Limit caps the number of groups, the crash filters are implied.
+	GroupCrashes(ctx context.Context, filter models.LogFilter) ([]models.CrashGroup, error)
+}
+
+// SymbolFileStore defines the interface for storages that keep the source maps and mapping files
+// used to symbolicate stack traces
+type SymbolFileStore interface {
+	// PutSymbolFile stores a symbol file, replacing the one with the same service, version and name
+	PutSymbolFile(ctx context.Context, file models.SymbolFile) (*models.SymbolFile, error)
+
+	// GetSymbolFile returns a symbol file with its content, or nil if it does not exist
+	GetSymbolFile(ctx context.Context, serviceName, version, name string) (*models.SymbolFile, error)
+
+	// ListSymbolFiles returns the symbol files of a service without their content, limited to one
+	// version unless version is empty, ordered by version and name
+	ListSymbolFiles(ctx context.Context, serviceName, version string) ([]models.SymbolFile, error)
+
+	// DeleteSymbolFile removes a symbol file and reports whether it existed
+	DeleteSymbolFile(ctx context.Context, serviceName, version, name string) (bool, error)
+}
+
+// UsageStore defines the interface for storages that account ingested volume per API key and service
+type UsageStore interface {
+	// RecordUsage adds the entry and byte counts of the records to the stored ones
+	RecordUsage(ctx context.Context, records []models.UsageRecord) error
+
+	// QueryUsage returns the usage matching the filter, largest volume first, and for daily
+	// records most recent day first
+	QueryUsage(ctx context.Context, filter models.UsageFilter) ([]models.UsageRecord, error)
+}
+
+// QueryAuditStore defines the interface for storages that keep an audit trail of log queries
+type QueryAuditStore interface {
+	// RecordQueryAudits adds query audit records
+	RecordQueryAudits(ctx context.Context, records []models.QueryAudit) error
+
+	// ListQueryAudits returns the records matching the filter, newest first
+	ListQueryAudits(ctx context.Context, filter models.QueryAuditFilter) ([]models.QueryAudit, error)
+
+	// DeleteQueryAudits removes the records of queries made before a time and returns how many
+	// it removed
+	DeleteQueryAudits(ctx context.Context, before time.Time) (int64, error)
+}
+
+// LogCompactor defines the interface for storages that can replace old entries with hourly
+// summaries per service, level and message template
+type LogCompactor interface {
+	// CompactLogs replaces up to limit entries of a level with a timestamp before the cutoff,
+	// oldest first, with summaries and returns how many entries it replaced. Entries that may
+	// not be deleted, such as those under a legal hold, are kept.
+	CompactLogs(ctx context.Context, level models.LogLevel, before time.Time, limit int) (int, error)
+
+	// QuerySummaries returns the summaries matching the filter, most recent hour and largest
+	// count first
+	QuerySummaries(ctx context.Context, filter models.SummaryFilter) ([]models.LogSummary, error)
+
+	// SummaryCounts returns the number of compacted entries per level of the summaries matching
+	// the filter, ignoring its limit
+	SummaryCounts(ctx context.Context, filter models.SummaryFilter) (map[models.LogLevel]int64, error)
+}
+
+// ConcurrentWriter defines the interface for storages that limit how many Store calls may run at once
+type ConcurrentWriter interface {
+	// MaxConcurrentWrites returns how many Store calls may run at once, 0 means no limit
+	MaxConcurrentWrites() int
+}
@@ -0,0 +1,61 @@
+package storage
+
+import "github.com/kerlexov/mcp-logging-server/pkg/models"
+
+// LogIterator iterates over log entries returned by a streaming query.
+//
+//	it, err := store.QueryStream(ctx, filter)
+//	if err != nil { ... }
+//	defer it.Close()
+//	for it.Next() {
+//		entry := it.Entry()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type LogIterator interface {
+	// Next advances to the next entry and reports whether there is one
+	Next() bool
+
+	// Entry returns the current entry
+	Entry() models.LogEntry
+
+	// Err returns the error that stopped the iteration, if any
+	Err() error
+
+	// Close releases the resources held by the iterator
+	Close() error
+}
+
+// SliceIterator iterates over an in-memory slice of log entries
+type SliceIterator struct {
+	entries []models.LogEntry
+	index   int
+}
+
+// NewSliceIterator creates an iterator over the given entries
+func NewSliceIterator(entries []models.LogEntry) *SliceIterator {
+	return &SliceIterator{entries: entries, index: -1}
+}
+
+// Next advances to the next entry
+func (it *SliceIterator) Next() bool {
+	if it.index+1 >= len(it.entries) {
+		return false
+	}
+	it.index++
+	return true
+}
+
+// Entry returns the current entry
+func (it *SliceIterator) Entry() models.LogEntry {
+	return it.entries[it.index]
+}
+
+// Err always returns nil
+func (it *SliceIterator) Err() error {
+	return nil
+}
+
+// Close is a no-op
+func (it *SliceIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// SetImmutableWindow enables write-once mode, in which entries received less than window ago
+// cannot be deleted, 0 disables
+func (s *SQLiteStorage) SetImmutableWindow(window time.Duration) {
+	s.immutableWindow = window
+}
+
+// PlaceLegalHold stores a legal hold, protecting the matching entries from deletion
+func (s *SQLiteStorage) PlaceLegalHold(ctx context.Context, hold models.LegalHold) (*models.LegalHold, error) {
+	hold.ID = uuid.New().String()
+	hold.CreatedAt = time.Now().UTC()
+
+	reason, err := s.encryptColumn(&hold.Reason)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO legal_holds (id, service_name, start_time, end_time, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`,
+		hold.ID,
+		nullString(hold.ServiceName),
+		nullTime(hold.StartTime),
+		nullTime(hold.EndTime),
+		reason,
+		hold.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place legal hold: %w", err)
+	}
+
+	return &hold, nil
+}
+
+// ListLegalHolds returns all active legal holds, oldest first
+func (s *SQLiteStorage) ListLegalHolds(ctx context.Context) ([]models.LegalHold, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, service_name, start_time, end_time, reason, created_at
+		FROM legal_holds
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := make([]models.LegalHold, 0)
+	for rows.Next() {
+		var hold models.LegalHold
+		var serviceName sql.NullString
+		var startTime, endTime sql.NullTime
+
+		if err := rows.Scan(&hold.ID, &serviceName, &startTime, &endTime, &hold.Reason, &hold.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan legal hold: %w", err)
+		}
+		if err := decompressString(&hold.Reason); err != nil {
+			return nil, fmt.Errorf("failed to read legal hold %s: %w", hold.ID, err)
+		}
+
+		hold.ServiceName = serviceName.String
+		hold.StartTime = startTime.Time
+		hold.EndTime = endTime.Time
+		holds = append(holds, hold)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return holds, nil
+}
+
+// ReleaseLegalHold removes a legal hold and reports whether it existed
+func (s *SQLiteStorage) ReleaseLegalHold(ctx context.Context, id string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM legal_holds WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("failed to release legal hold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// deletableCondition returns the SQL condition excluding log entries that are protected by a
+// legal hold or still inside the immutable window
+func (s *SQLiteStorage) deletableCondition() (string, []interface{}) {
+	condition := `NOT EXISTS (
+		SELECT 1 FROM legal_holds h
+		WHERE (h.service_name IS NULL OR h.service_name = log_entries.service_name)
+		AND (h.start_time IS NULL OR log_entries.timestamp >= h.start_time)
+		AND (h.end_time IS NULL OR log_entries.timestamp <= h.end_time)
+	)`
+	var args []interface{}
+
+	if s.immutableWindow > 0 {
+		condition += " AND COALESCE(received_at, timestamp) < ?"
+		args = append(args, time.Now().Add(-s.immutableWindow))
+	}
+
+	return condition, args
+}
+
+// protectedCutoff moves a retention cutoff back so that it does not cover entries inside the
+// immutable window or the time range of any legal hold
+func (s *SQLiteStorage) protectedCutoff(ctx context.Context, before time.Time) (time.Time, error) {
+	if s.immutableWindow > 0 {
+		if windowStart := time.Now().Add(-s.immutableWindow); windowStart.Before(before) {
+			before = windowStart
+		}
+	}
+
+	holds, err := s.ListLegalHolds(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, hold := range holds {
+		if hold.StartTime.IsZero() {
+			// Held from the beginning, nothing can be retired
+			return time.Time{}, nil
+		}
+		if hold.StartTime.Before(before) {
+			before = hold.StartTime
+		}
+	}
+
+	return before, nil
+}
+
+// nullTime converts a zero time to NULL
+func nullTime(value time.Time) sql.NullTime {
+	return sql.NullTime{Time: value, Valid: !value.IsZero()}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newLegalHoldTestLog(serviceName string, timestamp time.Time) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		Level:       models.LogLevelInfo,
+		Message:     "Order placed",
+		ServiceName: serviceName,
+		AgentID:     "test-agent",
+		Platform:    models.PlatformGo,
+	}
+}
+
+func TestSQLiteStorage_LegalHolds(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	hold, err := storage.PlaceLegalHold(ctx, models.LegalHold{
+		ServiceName: "orders-service",
+		StartTime:   now.Add(-48 * time.Hour),
+		EndTime:     now.Add(-24 * time.Hour),
+		Reason:      "Litigation 2024-17",
+	})
+	if err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+	if hold.ID == "" || hold.CreatedAt.IsZero() {
+		t.Errorf("Expected generated ID and creation time, got %+v", hold)
+	}
+
+	held := newLegalHoldTestLog("orders-service", now.Add(-36*time.Hour))
+	outsideRange := newLegalHoldTestLog("orders-service", now.Add(-72*time.Hour))
+	otherService := newLegalHoldTestLog("billing-service", now.Add(-36*time.Hour))
+	if err := storage.Store(ctx, []models.LogEntry{held, outsideRange, otherService}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	deleted, err := storage.DeleteByIDs(ctx, []string{held.ID, outsideRange.ID, otherService.ID})
+	if err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted logs, got %d", deleted)
+	}
+
+	remaining, err := storage.GetByIDs(ctx, []string{held.ID, outsideRange.ID, otherService.ID})
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(remaining) != 1 || remaining[0].ID != held.ID {
+		t.Errorf("Expected only the held log to remain, got %v", remaining)
+	}
+
+	holds, err := storage.ListLegalHolds(ctx)
+	if err != nil {
+		t.Fatalf("Failed to list legal holds: %v", err)
+	}
+	if len(holds) != 1 || holds[0].ID != hold.ID || holds[0].ServiceName != "orders-service" || !holds[0].StartTime.Equal(hold.StartTime) {
+		t.Errorf("Expected the placed hold, got %+v", holds)
+	}
+
+	released, err := storage.ReleaseLegalHold(ctx, hold.ID)
+	if err != nil || !released {
+		t.Fatalf("Expected hold to be released, got %v %v", released, err)
+	}
+	if released, _ := storage.ReleaseLegalHold(ctx, hold.ID); released {
+		t.Error("Expected releasing an unknown hold to report false")
+	}
+
+	deleted, err = storage.DeleteByIDs(ctx, []string{held.ID})
+	if err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected released log to be deleted, got %d", deleted)
+	}
+}
+
+func TestSQLiteStorage_ImmutableWindow(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+	storage.SetImmutableWindow(24 * time.Hour)
+
+	ctx := context.Background()
+	now := time.Now()
+
+	recent := newLegalHoldTestLog("orders-service", now.Add(-72*time.Hour))
+	recent.ReceivedAt = now.Add(-time.Hour)
+	old := newLegalHoldTestLog("orders-service", now.Add(-72*time.Hour))
+	old.ReceivedAt = now.Add(-48 * time.Hour)
+	if err := storage.Store(ctx, []models.LogEntry{recent, old}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	deleted, err := storage.DeleteByIDs(ctx, []string{recent.ID, old.ID})
+	if err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected only the entry received outside the window to be deleted, got %d", deleted)
+	}
+
+	cutoff, err := storage.protectedCutoff(ctx, now)
+	if err != nil {
+		t.Fatalf("Failed to get protected cutoff: %v", err)
+	}
+	if cutoff.After(time.Now().Add(-24 * time.Hour)) {
+		t.Errorf("Expected cutoff before the immutable window, got %v", cutoff)
+	}
+}
+
+func TestSQLiteStorage_ProtectedCutoff(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	cutoff, err := storage.protectedCutoff(ctx, now)
+	if err != nil || !cutoff.Equal(now) {
+		t.Errorf("Expected unchanged cutoff without holds, got %v %v", cutoff, err)
+	}
+
+	holdStart := now.Add(-10 * 24 * time.Hour)
+	if _, err := storage.PlaceLegalHold(ctx, models.LegalHold{StartTime: holdStart, Reason: "Audit"}); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+	cutoff, err = storage.protectedCutoff(ctx, now)
+	if err != nil || !cutoff.Equal(holdStart) {
+		t.Errorf("Expected cutoff at the hold start %v, got %v %v", holdStart, cutoff, err)
+	}
+
+	if _, err := storage.PlaceLegalHold(ctx, models.LegalHold{EndTime: now, Reason: "Audit"}); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+	cutoff, err = storage.protectedCutoff(ctx, now)
+	if err != nil || !cutoff.IsZero() {
+		t.Errorf("Expected no cutoff with an unbounded hold, got %v %v", cutoff, err)
+	}
+}
+
+func TestRetentionService_CleanupExpiredLogsSkipsLegalHolds(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := storage.PlaceLegalHold(ctx, models.LegalHold{ServiceName: "held-service", Reason: "Investigation"}); err != nil {
+		t.Fatalf("Failed to place legal hold: %v", err)
+	}
+
+	// A full cleanup batch of held entries comes before the expired entries that may be deleted
+	var logs []models.LogEntry
+	for i := 0; i < 1000; i++ {
+		logs = append(logs, newLegalHoldTestLog("held-service", now.AddDate(0, 0, -40).Add(-time.Duration(i)*time.Second)))
+	}
+	for i := 0; i < 5; i++ {
+		logs = append(logs, newLegalHoldTestLog(fmt.Sprintf("service-%d", i), now.AddDate(0, 0, -50)))
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	retentionService := NewRetentionService(storage, RetentionPolicy{DefaultDays: 30})
+	result, err := retentionService.CleanupExpiredLogs(ctx)
+	if err != nil {
+		t.Fatalf("Failed to cleanup expired logs: %v", err)
+	}
+	if result.TotalDeleted != 5 {
+		t.Errorf("Expected the 5 unheld logs to be deleted, got %d", result.TotalDeleted)
+	}
+
+	remaining, err := storage.Query(ctx, models.LogFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if remaining.TotalCount != 1000 {
+		t.Errorf("Expected 1000 held logs to remain, got %d", remaining.TotalCount)
+	}
+}
@@ -0,0 +1,642 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// DefaultMemoryMaxEntries is the number of entries kept by an in-memory storage when no limit is configured
+const DefaultMemoryMaxEntries = 100000
+
+// MemoryConfig configures an in-memory storage
+type MemoryConfig struct {
+	MaxEntries       int           // Entries kept before the oldest are evicted, 0 uses DefaultMemoryMaxEntries
+	SnapshotPath     string        // File the storage is restored from on creation and saved to on Close, empty disables
+	SnapshotInterval time.Duration // How often to save a snapshot in the background, 0 only saves on Close
+}
+
+// memorySnapshot is the on-disk format of an in-memory storage
+type memorySnapshot struct {
+	Entries       []models.LogEntry            `json:"entries"`
+	Registrations []models.ServiceRegistration `json:"registrations"`
+	SyncStates    []models.SyncState           `json:"sync_states,omitempty"`
+	SymbolFiles   []snapshotSymbolFile         `json:"symbol_files,omitempty"`
+	Usage         []models.UsageRecord         `json:"usage,omitempty"`
+	Summaries     []models.LogSummary          `json:"summaries,omitempty"`
+	Incidents     []models.Incident            `json:"incidents,omitempty"`
+	Purged        []models.ServiceInfo         `json:"purged_services,omitempty"`
+	QueryAudits   []models.QueryAudit          `json:"query_audits,omitempty"`
+}
+
+// snapshotSymbolFile includes the content that is left out of a symbol file's JSON
+type snapshotSymbolFile struct {
+	models.SymbolFile
+	Content []byte `json:"content"`
+}
+
+// MemoryStorage keeps log entries in memory, for tests, CI environments and ephemeral runs.
+// Entries are kept in the order they were stored and the oldest are evicted beyond MaxEntries
+type MemoryStorage struct {
+	mu            sync.RWMutex
+	config        MemoryConfig
+	entries       []models.LogEntry
+	ids           map[string]bool
+	registrations map[string]models.ServiceRegistration
+	syncStates    map[string]models.SyncState
+	symbolFiles   map[symbolFileKey]models.SymbolFile
+	usage         map[usageKey]models.UsageRecord
+	summaries     map[summaryKey]models.LogSummary
+	incidents     map[string]models.Incident
+	purged        map[serviceKey]time.Time // Last seen time of purged agents
+	evicted       int
+
+	queryAudits      []models.QueryAudit // In the order they were recorded
+	lastQueryAuditID int64
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	closed  bool
+}
+
+// NewMemoryStorage creates an empty in-memory storage with the default size limit
+func NewMemoryStorage() *MemoryStorage {
+	storage, _ := NewMemoryStorageWithConfig(MemoryConfig{})
+	return storage
+}
+
+// NewMemoryStorageWithConfig creates an in-memory storage, restoring the snapshot if one exists
+func NewMemoryStorageWithConfig(config MemoryConfig) (*MemoryStorage, error) {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = DefaultMemoryMaxEntries
+	}
+
+	storage := &MemoryStorage{
+		config:        config,
+		ids:           make(map[string]bool),
+		registrations: make(map[string]models.ServiceRegistration),
+		syncStates:    make(map[string]models.SyncState),
+		symbolFiles:   make(map[symbolFileKey]models.SymbolFile),
+		usage:         make(map[usageKey]models.UsageRecord),
+		summaries:     make(map[summaryKey]models.LogSummary),
+		incidents:     make(map[string]models.Incident),
+		purged:        make(map[serviceKey]time.Time),
+		stop:          make(chan struct{}),
+	}
+
+	if config.SnapshotPath != "" {
+		if err := storage.restore(config.SnapshotPath); err != nil {
+			return nil, err
+		}
+
+		if config.SnapshotInterval > 0 {
+			storage.stopped.Add(1)
+			go storage.snapshotRoutine()
+		}
+	}
+
+	return storage, nil
+}
+
+// Store stores a batch of log entries, either all or none of them
+func (s *MemoryStorage) Store(ctx context.Context, logs []models.LogEntry) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	batchIDs := make(map[string]bool, len(logs))
+	for i := range logs {
+		if err := logs[i].Validate(); err != nil {
+			return fmt.Errorf("invalid log entry %s: %w", logs[i].ID, err)
+		}
+		if batchIDs[logs[i].ID] {
+			return fmt.Errorf("duplicate log entry %s", logs[i].ID)
+		}
+		batchIDs[logs[i].ID] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range batchIDs {
+		if s.ids[id] {
+			return fmt.Errorf("log entry %s already exists", id)
+		}
+	}
+
+	for _, entry := range logs {
+		entry.Annotations = nil // Only added through AnnotateLog
+		s.entries = append(s.entries, entry)
+		s.ids[entry.ID] = true
+	}
+	s.evictLocked()
+
+	return nil
+}
+
+// evictLocked drops the oldest entries beyond the size limit, the caller must hold the write lock
+func (s *MemoryStorage) evictLocked() {
+	excess := len(s.entries) - s.config.MaxEntries
+	if excess <= 0 {
+		return
+	}
+
+	for _, entry := range s.entries[:excess] {
+		delete(s.ids, entry.ID)
+	}
+	s.entries = s.entries[excess:]
+	s.evicted += excess
+}
+
+// Query retrieves logs matching the filter, newest first
+func (s *MemoryStorage) Query(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
+	matches := s.matching(filter)
+	sortByTime(matches, filter.TimeField, false)
+
+	limit, offset := queryPage(filter)
+	totalCount := len(matches)
+
+	start := offset
+	if start > totalCount {
+		start = totalCount
+	}
+	end := start + limit
+	if end > totalCount {
+		end = totalCount
+	}
+
+	return &models.LogResult{
+		Logs:       matches[start:end],
+		TotalCount: totalCount,
+		HasMore:    end < totalCount,
+	}, nil
+}
+
+// QueryStream returns an iterator over the logs matching the filter, oldest first
+func (s *MemoryStorage) QueryStream(ctx context.Context, filter models.LogFilter) (LogIterator, error) {
+	matches := s.matching(filter)
+	sortByTime(matches, filter.TimeField, true)
+
+	if filter.Offset > 0 {
+		if filter.Offset >= len(matches) {
+			matches = nil
+		} else {
+			matches = matches[filter.Offset:]
+		}
+	}
+	if filter.Limit > 0 && filter.Limit < len(matches) {
+		matches = matches[:filter.Limit]
+	}
+
+	return NewSliceIterator(matches), nil
+}
+
+// matching returns a copy of the entries matching the filter, in storage order
+func (s *MemoryStorage) matching(filter models.LogFilter) []models.LogEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matches := make([]models.LogEntry, 0)
+	for _, entry := range s.entries {
+		if matchesFilter(entry, filter) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// matchesFilter checks if an entry passes every condition of a filter, with the same
+// semantics as the SQL filters of the SQLite storage
+func matchesFilter(entry models.LogEntry, filter models.LogFilter) bool {
+	if filter.ServiceName != "" && entry.ServiceName != filter.ServiceName {
+		return false
+	}
+	if filter.AgentID != "" && entry.AgentID != filter.AgentID {
+		return false
+	}
+	if filter.RequestID != "" && entry.RequestID != filter.RequestID {
+		return false
+	}
+	if filter.TemplateID != "" && entry.TemplateID != filter.TemplateID {
+		return false
+	}
+	if filter.Level != "" && entry.Level != filter.Level {
+		return false
+	}
+	if filter.MinLevel != "" && entry.Level.Severity() < filter.MinLevel.Severity() {
+		return false
+	}
+	if filter.Platform != "" && entry.Platform != filter.Platform {
+		return false
+	}
+
+	entryTime := filterTime(entry, filter.TimeField)
+	if !filter.StartTime.IsZero() && entryTime.Before(filter.StartTime) {
+		return false
+	}
+	if !filter.EndTime.IsZero() && entryTime.After(filter.EndTime) {
+		return false
+	}
+
+	// Like SQL LIKE, message matching ignores case
+	if filter.MessageContains != "" && !strings.Contains(strings.ToLower(entry.Message), strings.ToLower(filter.MessageContains)) {
+		return false
+	}
+
+	return matchesTags(entry.Tags, filter) && matchesCrash(entry.Crash, filter)
+}
+
+// filterTime returns the time of an entry that a time filter applies to
+func filterTime(entry models.LogEntry, field models.TimeField) time.Time {
+	if field == models.TimeFieldReceivedAt {
+		return entry.ReceivedAt
+	}
+	return entry.Timestamp
+}
+
+// sortByTime orders entries by the filtered time field, ascending ties are broken by ID like
+// the SQLite stream and descending ties keep storage order
+func sortByTime(entries []models.LogEntry, field models.TimeField, ascending bool) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := filterTime(entries[i], field), filterTime(entries[j], field)
+		if ti.Equal(tj) {
+			if ascending {
+				return entries[i].ID < entries[j].ID
+			}
+			return false
+		}
+		if ascending {
+			return ti.Before(tj)
+		}
+		return ti.After(tj)
+	})
+}
+
+// GetByIDs retrieves specific log entries by their IDs
+func (s *MemoryStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
+	if len(ids) == 0 {
+		return []models.LogEntry{}, nil
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	logs := make([]models.LogEntry, 0, len(ids))
+	for _, entry := range s.entries {
+		if wanted[entry.ID] {
+			logs = append(logs, entry)
+		}
+	}
+	return logs, nil
+}
+
+// serviceKey identifies an agent of a service in memory storage
+type serviceKey struct {
+	serviceName string
+	agentID     string
+	platform    models.Platform
+}
+
+// GetServices returns the services that have logged entries and match the filter
+func (s *MemoryStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return filterServices(s.servicesLocked(), filter), nil
+}
+
+// servicesLocked returns the agents of all services, leaving out purged agents that did not log
+// since they were purged. The caller must hold the lock.
+func (s *MemoryStorage) servicesLocked() []models.ServiceInfo {
+	byKey := make(map[serviceKey]*models.ServiceInfo)
+	var services []*models.ServiceInfo
+	for _, entry := range s.entries {
+		key := serviceKey{entry.ServiceName, entry.AgentID, entry.Platform}
+		service, ok := byKey[key]
+		if !ok {
+			service = &models.ServiceInfo{
+				ServiceName: entry.ServiceName,
+				AgentID:     entry.AgentID,
+				Platform:    entry.Platform,
+				LevelCounts: make(map[models.LogLevel]int),
+			}
+			if registration, registered := s.registrations[entry.ServiceName]; registered {
+				service.Registration = &registration
+			}
+			byKey[key] = service
+			services = append(services, service)
+		}
+
+		service.LogCount++
+		service.LevelCounts[entry.Level]++
+		if entry.Timestamp.After(service.LastSeen) {
+			service.LastSeen = entry.Timestamp
+		}
+	}
+
+	result := make([]models.ServiceInfo, 0, len(services))
+	for _, service := range services {
+		key := serviceKey{service.ServiceName, service.AgentID, service.Platform}
+		if purgedUntil, purged := s.purged[key]; purged && !service.LastSeen.After(purgedUntil) {
+			continue
+		}
+		result = append(result, *service)
+	}
+	return result
+}
+
+// PurgeServices removes the agents last seen before notSeenSince from the services, keeping
+// their entries, until they store a newer entry
+func (s *MemoryStorage) PurgeServices(ctx context.Context, notSeenSince time.Time) ([]models.ServiceInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := make([]models.ServiceInfo, 0)
+	for _, service := range s.servicesLocked() {
+		if service.LastSeen.Before(notSeenSince) {
+			s.purged[serviceKey{service.ServiceName, service.AgentID, service.Platform}] = service.LastSeen
+			purged = append(purged, service)
+		}
+	}
+	return purged, nil
+}
+
+// DeleteByIDs deletes log entries by their IDs and returns how many existed
+func (s *MemoryStorage) DeleteByIDs(ctx context.Context, ids []string) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if s.ids[id] {
+			remove[id] = true
+		}
+	}
+	if len(remove) == 0 {
+		return 0, nil
+	}
+
+	kept := s.entries[:0]
+	for _, entry := range s.entries {
+		if remove[entry.ID] {
+			delete(s.ids, entry.ID)
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	s.entries = kept
+
+	return len(remove), nil
+}
+
+// RegisterService creates or updates a service registration
+func (s *MemoryStorage) RegisterService(ctx context.Context, registration models.ServiceRegistration) (*models.ServiceRegistration, error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registration.CreatedAt = now
+	if existing, ok := s.registrations[registration.ServiceName]; ok {
+		registration.CreatedAt = existing.CreatedAt
+	}
+	registration.UpdatedAt = now
+	s.registrations[registration.ServiceName] = registration
+
+	return &registration, nil
+}
+
+// GetServiceRegistration returns the registration for a service, or nil if it is not registered
+func (s *MemoryStorage) GetServiceRegistration(ctx context.Context, serviceName string) (*models.ServiceRegistration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	registration, ok := s.registrations[serviceName]
+	if !ok {
+		return nil, nil
+	}
+	return &registration, nil
+}
+
+// ListServiceRegistrations returns all registered services ordered by name
+func (s *MemoryStorage) ListServiceRegistrations(ctx context.Context) ([]models.ServiceRegistration, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	registrations := make([]models.ServiceRegistration, 0, len(s.registrations))
+	for _, registration := range s.registrations {
+		registrations = append(registrations, registration)
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].ServiceName < registrations[j].ServiceName
+	})
+	return registrations, nil
+}
+
+// DeleteServiceRegistration removes a service registration and reports whether it existed
+func (s *MemoryStorage) DeleteServiceRegistration(ctx context.Context, serviceName string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.registrations[serviceName]
+	delete(s.registrations, serviceName)
+	return ok, nil
+}
+
+// HealthCheck returns the health status of the storage
+func (s *MemoryStorage) HealthCheck(ctx context.Context) models.HealthStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := models.HealthStatus{
+		Status:    "healthy",
+		Timestamp: time.Now(),
+		Details: map[string]string{
+			"database":    "memory",
+			"log_count":   fmt.Sprintf("%d", len(s.entries)),
+			"max_entries": fmt.Sprintf("%d", s.config.MaxEntries),
+			"evicted":     fmt.Sprintf("%d", s.evicted),
+		},
+	}
+	if s.closed {
+		status.Status = "unhealthy"
+		status.Details["database"] = "closed"
+	}
+	return status
+}
+
+// Snapshot writes all entries, service registrations, sync states, symbol files, usage, summaries and query audit records to the snapshot file. The file is
+// replaced atomically, so a crash while saving keeps the previous snapshot
+func (s *MemoryStorage) Snapshot() error {
+	if s.config.SnapshotPath == "" {
+		return errors.New("no snapshot path configured")
+	}
+
+	s.mu.RLock()
+	snapshot := memorySnapshot{
+		Entries:       append([]models.LogEntry(nil), s.entries...),
+		Registrations: make([]models.ServiceRegistration, 0, len(s.registrations)),
+		SyncStates:    make([]models.SyncState, 0, len(s.syncStates)),
+	}
+	for _, registration := range s.registrations {
+		snapshot.Registrations = append(snapshot.Registrations, registration)
+	}
+	for _, state := range s.syncStates {
+		snapshot.SyncStates = append(snapshot.SyncStates, state)
+	}
+	for _, file := range s.symbolFiles {
+		snapshot.SymbolFiles = append(snapshot.SymbolFiles, snapshotSymbolFile{SymbolFile: file, Content: file.Content})
+	}
+	for _, record := range s.usage {
+		snapshot.Usage = append(snapshot.Usage, record)
+	}
+	for _, summary := range s.summaries {
+		snapshot.Summaries = append(snapshot.Summaries, summary)
+	}
+	for _, incident := range s.incidents {
+		snapshot.Incidents = append(snapshot.Incidents, incident)
+	}
+	for key, lastSeen := range s.purged {
+		snapshot.Purged = append(snapshot.Purged, models.ServiceInfo{ServiceName: key.serviceName, AgentID: key.agentID, Platform: key.platform, LastSeen: lastSeen})
+	}
+	snapshot.QueryAudits = append(snapshot.QueryAudits, s.queryAudits...)
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.config.SnapshotPath), filepath.Base(s.config.SnapshotPath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.config.SnapshotPath); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+	return nil
+}
+
+// restore loads the snapshot file if it exists
+func (s *MemoryStorage) restore(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snapshot memorySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse snapshot %s: %w", path, err)
+	}
+
+	for _, entry := range snapshot.Entries {
+		if s.ids[entry.ID] {
+			continue
+		}
+		s.entries = append(s.entries, entry)
+		s.ids[entry.ID] = true
+	}
+	s.evictLocked()
+
+	for _, registration := range snapshot.Registrations {
+		s.registrations[registration.ServiceName] = registration
+	}
+	for _, state := range snapshot.SyncStates {
+		s.syncStates[state.AgentID] = state
+	}
+	for _, file := range snapshot.SymbolFiles {
+		file.SymbolFile.Content = file.Content
+		s.symbolFiles[symbolFileKey{file.ServiceName, file.Version, file.Name}] = file.SymbolFile
+	}
+	for _, record := range snapshot.Usage {
+		s.usage[usageKey{record.Day, record.APIKeyID, record.ServiceName}] = record
+	}
+	for _, summary := range snapshot.Summaries {
+		s.summaries[summaryKey{summary.Hour.Unix(), summary.ServiceName, summary.Level, summary.Template}] = summary
+	}
+	for _, incident := range snapshot.Incidents {
+		s.incidents[incident.ID] = incident
+	}
+	for _, service := range snapshot.Purged {
+		s.purged[serviceKey{service.ServiceName, service.AgentID, service.Platform}] = service.LastSeen
+	}
+	s.queryAudits = append(s.queryAudits, snapshot.QueryAudits...)
+	for _, record := range snapshot.QueryAudits {
+		if record.ID > s.lastQueryAuditID {
+			s.lastQueryAuditID = record.ID
+		}
+	}
+
+	log.Printf("Restored %d log entries from snapshot %s", len(s.entries), path)
+	return nil
+}
+
+// snapshotRoutine saves snapshots periodically until the storage is closed
+func (s *MemoryStorage) snapshotRoutine() {
+	defer s.stopped.Done()
+
+	ticker := time.NewTicker(s.config.SnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Snapshot(); err != nil {
+				log.Printf("Failed to save memory storage snapshot: %v", err)
+			}
+		}
+	}
+}
+
+// Close stops background snapshots and saves a final snapshot when a path is configured
+func (s *MemoryStorage) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.stopped.Wait()
+
+	if s.config.SnapshotPath != "" {
+		return s.Snapshot()
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newMemoryTestLog(serviceName string, level models.LogLevel, message string, timestamp time.Time) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		Level:       level,
+		Message:     message,
+		ServiceName: serviceName,
+		AgentID:     "test-agent",
+		Platform:    models.PlatformGo,
+	}
+}
+
+func TestMemoryStorage_Query(t *testing.T) {
+	storage := NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	tagged := newMemoryTestLog("payment-service", models.LogLevelError, "Payment TIMEOUT", now.Add(-time.Minute))
+	tagged.Tags = []string{"payments", "db"}
+	tagged.RequestID = "req-42"
+	logs := []models.LogEntry{
+		newMemoryTestLog("user-service", models.LogLevelInfo, "User logged in", now.Add(-3*time.Minute)),
+		newMemoryTestLog("user-service", models.LogLevelWarn, "Slow login", now.Add(-2*time.Minute)),
+		tagged,
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.LogFilter
+		expected []string
+	}{
+		{name: "all newest first", filter: models.LogFilter{}, expected: []string{tagged.ID, logs[1].ID, logs[0].ID}},
+		{name: "service", filter: models.LogFilter{ServiceName: "user-service"}, expected: []string{logs[1].ID, logs[0].ID}},
+		{name: "level", filter: models.LogFilter{Level: models.LogLevelWarn}, expected: []string{logs[1].ID}},
+		{name: "min level", filter: models.LogFilter{MinLevel: models.LogLevelWarn}, expected: []string{tagged.ID, logs[1].ID}},
+		{name: "message ignores case", filter: models.LogFilter{MessageContains: "timeout"}, expected: []string{tagged.ID}},
+		{name: "time range", filter: models.LogFilter{StartTime: now.Add(-150 * time.Second), EndTime: now.Add(-90 * time.Second)}, expected: []string{logs[1].ID}},
+		{name: "tags all", filter: models.LogFilter{TagsAll: []string{"payments", "db"}}, expected: []string{tagged.ID}},
+		{name: "request ID", filter: models.LogFilter{RequestID: "req-42"}, expected: []string{tagged.ID}},
+		{name: "tags any without match", filter: models.LogFilter{TagsAny: []string{"cache"}}, expected: []string{}},
+		{name: "pagination", filter: models.LogFilter{Limit: 1, Offset: 1}, expected: []string{logs[1].ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to query logs: %v", err)
+			}
+			if len(result.Logs) != len(tt.expected) {
+				t.Fatalf("Expected %d logs, got %d", len(tt.expected), len(result.Logs))
+			}
+			for i, id := range tt.expected {
+				if result.Logs[i].ID != id {
+					t.Errorf("Expected log %s at position %d, got %s", id, i, result.Logs[i].ID)
+				}
+			}
+		})
+	}
+
+	result, _ := storage.Query(ctx, models.LogFilter{Limit: 2})
+	if result.TotalCount != 3 || !result.HasMore {
+		t.Errorf("Expected total 3 with more pages, got %d %v", result.TotalCount, result.HasMore)
+	}
+
+	it, err := storage.QueryStream(ctx, models.LogFilter{ServiceName: "user-service"})
+	if err != nil {
+		t.Fatalf("Failed to stream logs: %v", err)
+	}
+	defer it.Close()
+	var streamed []string
+	for it.Next() {
+		streamed = append(streamed, it.Entry().ID)
+	}
+	if len(streamed) != 2 || streamed[0] != logs[0].ID {
+		t.Errorf("Expected user-service logs oldest first, got %v", streamed)
+	}
+}
+
+func TestMemoryStorage_StoreRejectsInvalidAndDuplicates(t *testing.T) {
+	storage := NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+	entry := newMemoryTestLog("user-service", models.LogLevelInfo, "Hello", time.Now())
+	if err := storage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+
+	invalid := newMemoryTestLog("user-service", models.LogLevelInfo, "", time.Now())
+	valid := newMemoryTestLog("user-service", models.LogLevelInfo, "Valid", time.Now())
+	if err := storage.Store(ctx, []models.LogEntry{valid, invalid}); err == nil {
+		t.Error("Expected error for an invalid entry")
+	}
+	if err := storage.Store(ctx, []models.LogEntry{valid, entry}); err == nil {
+		t.Error("Expected error for an existing entry")
+	}
+
+	// Rejected batches are not partially stored
+	result, _ := storage.Query(ctx, models.LogFilter{})
+	if result.TotalCount != 1 {
+		t.Errorf("Expected 1 stored log, got %d", result.TotalCount)
+	}
+}
+
+func TestMemoryStorage_Eviction(t *testing.T) {
+	storage, err := NewMemoryStorageWithConfig(MemoryConfig{MaxEntries: 3})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		entry := newMemoryTestLog("user-service", models.LogLevelInfo, "Message", now.Add(time.Duration(i)*time.Second))
+		ids = append(ids, entry.ID)
+		if err := storage.Store(ctx, []models.LogEntry{entry}); err != nil {
+			t.Fatalf("Failed to store log: %v", err)
+		}
+	}
+
+	evicted, _ := storage.GetByIDs(ctx, ids[:2])
+	if len(evicted) != 0 {
+		t.Errorf("Expected the oldest entries to be evicted, got %d", len(evicted))
+	}
+	kept, _ := storage.GetByIDs(ctx, ids[2:])
+	if len(kept) != 3 {
+		t.Errorf("Expected 3 kept entries, got %d", len(kept))
+	}
+
+	health := storage.HealthCheck(ctx)
+	if health.Details["log_count"] != "3" || health.Details["evicted"] != "2" {
+		t.Errorf("Unexpected health details: %v", health.Details)
+	}
+}
+
+func TestMemoryStorage_ServicesAndDeletion(t *testing.T) {
+	storage := NewMemoryStorage()
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	logs := []models.LogEntry{
+		newMemoryTestLog("user-service", models.LogLevelInfo, "First", now.Add(-time.Hour)),
+		newMemoryTestLog("user-service", models.LogLevelInfo, "Second", now.Add(-time.Minute)),
+		newMemoryTestLog("payment-service", models.LogLevelInfo, "Third", now.Add(-30*time.Minute)),
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+	if _, err := storage.RegisterService(ctx, models.ServiceRegistration{ServiceName: "user-service", OwnerTeam: "identity"}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+
+	result, err := storage.GetServices(ctx, models.ServiceFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get services: %v", err)
+	}
+	services := result.Services
+	if len(services) != 2 || services[0].ServiceName != "user-service" || services[0].LogCount != 2 {
+		t.Fatalf("Unexpected services: %+v", services)
+	}
+	if services[0].Registration == nil || services[0].Registration.OwnerTeam != "identity" {
+		t.Errorf("Expected registration to be attached, got %+v", services[0].Registration)
+	}
+
+	deleted, err := storage.DeleteByIDs(ctx, []string{logs[0].ID, logs[2].ID, uuid.New().String()})
+	if err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted logs, got %d", deleted)
+	}
+
+	// Deleted entries can be stored again
+	if err := storage.Store(ctx, []models.LogEntry{logs[0]}); err != nil {
+		t.Errorf("Failed to store deleted log again: %v", err)
+	}
+}
+
+func TestMemoryStorage_Snapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	ctx := context.Background()
+
+	storage, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	entry := newMemoryTestLog("user-service", models.LogLevelInfo, "Persisted", time.Now())
+	entry.Tags = []string{"snapshot"}
+	if err := storage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+	if _, err := storage.RegisterService(ctx, models.ServiceRegistration{ServiceName: "user-service", OwnerTeam: "identity"}); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	restored, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Failed to restore storage: %v", err)
+	}
+	defer restored.Close()
+
+	logs, _ := restored.GetByIDs(ctx, []string{entry.ID})
+	if len(logs) != 1 || logs[0].Message != "Persisted" || len(logs[0].Tags) != 1 {
+		t.Errorf("Expected the entry to be restored, got %+v", logs)
+	}
+	registration, _ := restored.GetServiceRegistration(ctx, "user-service")
+	if registration == nil || registration.OwnerTeam != "identity" {
+		t.Errorf("Expected the registration to be restored, got %+v", registration)
+	}
+}
+
+func TestMemoryStorage_SnapshotInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	ctx := context.Background()
+
+	storage, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path, SnapshotInterval: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	entry := newMemoryTestLog("user-service", models.LogLevelInfo, "Persisted", time.Now())
+	if err := storage.Store(ctx, []models.LogEntry{entry}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+
+	// A background snapshot is readable before the storage is closed
+	for i := 0; i < 50; i++ {
+		snapshot, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+		if err == nil {
+			if logs, _ := snapshot.GetByIDs(ctx, []string{entry.ID}); len(logs) == 1 {
+				return
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Error("Expected a background snapshot to be written")
+}
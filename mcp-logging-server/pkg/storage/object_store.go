@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/secrets"
+)
+
+// ErrObjectNotFound is returned by ObjectStore.Get for keys that do not exist
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore holds the snapshots and WAL segments of a WAL archive. Keys are slash-separated
+// paths relative to the root of the store.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	List(ctx context.Context, prefix string) ([]string, error) // Keys starting with prefix, sorted
+	Delete(ctx context.Context, key string) error
+}
+
+// S3Config configures an S3ObjectStore
+type S3Config struct {
+	Bucket          string
+	Prefix          string // Prepended to all keys
+	Region          string
+	Endpoint        string // Overrides https://s3.<region>.amazonaws.com, e.g. for MinIO or R2
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // For temporary credentials, optional
+}
+
+// OpenObjectStore opens the store at a URL: s3://bucket/prefix for S3 or an S3-compatible
+// service configured by s3, or a local directory given as a path or file:// URL
+func OpenObjectStore(rawURL string, s3 S3Config) (ObjectStore, error) {
+	if !strings.HasPrefix(rawURL, "s3://") {
+		return NewDirObjectStore(strings.TrimPrefix(rawURL, "file://"))
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(rawURL, "s3://"), "/")
+	s3.Bucket = bucket
+	s3.Prefix = prefix
+	return NewS3ObjectStore(s3)
+}
+
+// DirObjectStore keeps objects as files in a local directory, such as a mounted network share
+type DirObjectStore struct {
+	dir string
+}
+
+// NewDirObjectStore creates a store in dir, creating the directory if needed
+func NewDirObjectStore(dir string) (*DirObjectStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("object store directory is required")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create object store directory: %w", err)
+	}
+	return &DirObjectStore{dir: dir}, nil
+}
+
+// Put writes an object atomically, so that readers never see a partial object
+func (s *DirObjectStore) Put(ctx context.Context, key string, data []byte) error {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get reads an object
+func (s *DirObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrObjectNotFound
+	}
+	return data, err
+}
+
+// List returns the keys of the objects under prefix
+func (s *DirObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := filepath.WalkDir(s.dir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(path, ".tmp") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.dir, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	sort.Strings(keys)
+	return keys, err
+}
+
+// Delete removes an object, ignoring objects that do not exist
+func (s *DirObjectStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// S3ObjectStore keeps objects in an S3 bucket or a bucket of an S3-compatible service.
+// Requests use path-style addressing, which all S3-compatible services support.
+type S3ObjectStore struct {
+	config   S3Config
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3ObjectStore creates a store for the bucket described by config
+func NewS3ObjectStore(config S3Config) (*S3ObjectStore, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("S3 region is required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key ID and secret access key are required")
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", config.Region)
+	}
+	if config.Prefix != "" {
+		config.Prefix = strings.TrimSuffix(config.Prefix, "/") + "/"
+	}
+
+	return &S3ObjectStore{
+		config:   config,
+		endpoint: strings.TrimRight(endpoint, "/"),
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// Put uploads an object
+func (s *S3ObjectStore) Put(ctx context.Context, key string, data []byte) error {
+	_, err := s.do(ctx, http.MethodPut, s.config.Prefix+key, nil, data)
+	return err
+}
+
+// Get downloads an object
+func (s *S3ObjectStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, s.config.Prefix+key, nil, nil)
+}
+
+// List returns the keys of the objects under prefix, following continuation tokens
+func (s *S3ObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.config.Prefix + prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		body, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("invalid S3 list response: %w", err)
+		}
+		for _, object := range result.Contents {
+			keys = append(keys, strings.TrimPrefix(object.Key, s.config.Prefix))
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Delete removes an object. S3 reports success for objects that do not exist.
+func (s *S3ObjectStore) Delete(ctx context.Context, key string) error {
+	_, err := s.do(ctx, http.MethodDelete, s.config.Prefix+key, nil, nil)
+	return err
+}
+
+// do sends a signed request for an object of the bucket, or the bucket itself when key is empty
+func (s *S3ObjectStore) do(ctx context.Context, method, key string, query url.Values, body []byte) ([]byte, error) {
+	target := s.endpoint + "/" + s.config.Bucket
+	if key != "" {
+		target += "/" + key
+	}
+	if len(query) > 0 {
+		target += "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	secrets.SignS3Request(req, body, secrets.AWSConfig{
+		Region:          s.config.Region,
+		AccessKeyID:     s.config.AccessKeyID,
+		SecretAccessKey: s.config.SecretAccessKey,
+		SessionToken:    s.config.SessionToken,
+	}, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read S3 response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet && key != "" {
+		return nil, ErrObjectNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("S3 %s %s returned %s: %s", method, key, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// DefaultQueryAuditLimit is the number of query audit records returned when the filter sets no limit
+const DefaultQueryAuditLimit = 100
+
+// RecordQueryAudits adds query audit records
+func (s *SQLiteStorage) RecordQueryAudits(ctx context.Context, records []models.QueryAudit) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO query_audit (
+			time, interface, actor, source, operation, filter,
+			result_count, masked_fields, latency_ms, error, request_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare query audit statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		var filter interface{}
+		var maskedFields sql.NullString
+		if len(record.Filter) > 0 {
+			data, err := json.Marshal(record.Filter)
+			if err != nil {
+				return fmt.Errorf("failed to marshal query filter: %w", err)
+			}
+			text := string(data)
+			if filter, err = s.encryptColumn(&text); err != nil {
+				return err
+			}
+		}
+		// Errors may quote the filter
+		queryError, err := s.encryptColumn(&record.Error)
+		if err != nil {
+			return err
+		}
+		if len(record.MaskedFields) > 0 {
+			data, err := json.Marshal(record.MaskedFields)
+			if err != nil {
+				return fmt.Errorf("failed to marshal masked fields: %w", err)
+			}
+			maskedFields = sql.NullString{String: string(data), Valid: true}
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			record.Time.UTC(),
+			record.Interface,
+			record.Actor,
+			record.Source,
+			record.Operation,
+			filter,
+			record.ResultCount,
+			maskedFields,
+			record.LatencyMs,
+			queryError,
+			record.RequestID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record query audit: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit query audit: %w", err)
+	}
+	return nil
+}
+
+// ListQueryAudits returns the records matching the filter, newest first
+func (s *SQLiteStorage) ListQueryAudits(ctx context.Context, filter models.QueryAuditFilter) ([]models.QueryAudit, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, filter.Actor)
+	}
+	if filter.Interface != "" {
+		conditions = append(conditions, "interface = ?")
+		args = append(args, filter.Interface)
+	}
+	if filter.Operation != "" {
+		conditions = append(conditions, "operation = ?")
+		args = append(args, filter.Operation)
+	}
+	if !filter.StartTime.IsZero() {
+		conditions = append(conditions, "time >= ?")
+		args = append(args, filter.StartTime.UTC())
+	}
+	if !filter.EndTime.IsZero() {
+		conditions = append(conditions, "time <= ?")
+		args = append(args, filter.EndTime.UTC())
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQueryAuditLimit
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, time, interface, actor, source, operation, filter,
+			result_count, masked_fields, latency_ms, error, request_id
+		FROM query_audit %s
+		ORDER BY time DESC, id DESC
+		LIMIT ?
+	`, whereClause)
+
+	rows, err := s.reader().QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]models.QueryAudit, 0)
+	for rows.Next() {
+		var record models.QueryAudit
+		var filterJSON, maskedFields sql.NullString
+
+		err := rows.Scan(
+			&record.ID,
+			&record.Time,
+			&record.Interface,
+			&record.Actor,
+			&record.Source,
+			&record.Operation,
+			&filterJSON,
+			&record.ResultCount,
+			&maskedFields,
+			&record.LatencyMs,
+			&record.Error,
+			&record.RequestID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan query audit record: %w", err)
+		}
+
+		if err := decompressNullString(&filterJSON); err != nil {
+			return nil, fmt.Errorf("failed to read query filter of audit record %d: %w", record.ID, err)
+		}
+		if err := decompressString(&record.Error); err != nil {
+			return nil, fmt.Errorf("failed to read error of audit record %d: %w", record.ID, err)
+		}
+		if filterJSON.Valid {
+			if err := json.Unmarshal([]byte(filterJSON.String), &record.Filter); err != nil {
+				return nil, fmt.Errorf("failed to parse query filter of audit record %d: %w", record.ID, err)
+			}
+		}
+		if maskedFields.Valid {
+			if err := json.Unmarshal([]byte(maskedFields.String), &record.MaskedFields); err != nil {
+				return nil, fmt.Errorf("failed to parse masked fields of audit record %d: %w", record.ID, err)
+			}
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+
+	return records, nil
+}
+
+// DeleteQueryAudits removes the records of queries made before a time and returns how many it removed
+func (s *SQLiteStorage) DeleteQueryAudits(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM query_audit WHERE time < ?", before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete query audit records: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RecordQueryAudits adds query audit records
+func (s *MemoryStorage) RecordQueryAudits(ctx context.Context, records []models.QueryAudit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		s.lastQueryAuditID++
+		record.ID = s.lastQueryAuditID
+		record.Time = record.Time.UTC()
+		s.queryAudits = append(s.queryAudits, record)
+	}
+	return nil
+}
+
+// ListQueryAudits returns the records matching the filter, newest first
+func (s *MemoryStorage) ListQueryAudits(ctx context.Context, filter models.QueryAuditFilter) ([]models.QueryAudit, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultQueryAuditLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Records are kept in the order they were recorded, which is the order of their times
+	// unless a recorder flushed late
+	records := make([]models.QueryAudit, 0)
+	for i := len(s.queryAudits) - 1; i >= 0 && len(records) < limit; i-- {
+		record := s.queryAudits[i]
+		if (filter.Actor != "" && record.Actor != filter.Actor) ||
+			(filter.Interface != "" && record.Interface != filter.Interface) ||
+			(filter.Operation != "" && record.Operation != filter.Operation) ||
+			(!filter.StartTime.IsZero() && record.Time.Before(filter.StartTime)) ||
+			(!filter.EndTime.IsZero() && record.Time.After(filter.EndTime)) {
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// DeleteQueryAudits removes the records of queries made before a time and returns how many it removed
+func (s *MemoryStorage) DeleteQueryAudits(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.queryAudits[:0]
+	for _, record := range s.queryAudits {
+		if !record.Time.Before(before) {
+			kept = append(kept, record)
+		}
+	}
+	deleted := int64(len(s.queryAudits) - len(kept))
+	s.queryAudits = kept
+	return deleted, nil
+}
@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestQueryAuditStores(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]QueryAuditStore{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().UTC().Truncate(time.Second)
+
+			records := []models.QueryAudit{
+				{Time: now.Add(-48 * time.Hour), Interface: models.QueryInterfaceREST, Actor: "key-a", Source: "10.0.0.1", Operation: "GET /v1/search", ResultCount: 3, LatencyMs: 12},
+				{Time: now.Add(-time.Hour), Interface: models.QueryInterfaceMCP, Actor: "claude-desktop", Source: "10.0.0.2", Operation: "query_logs",
+					Filter: map[string]interface{}{"service_name": "checkout"}, ResultCount: 20, MaskedFields: []string{"message"}, LatencyMs: 40},
+				{Time: now, Interface: models.QueryInterfaceREST, Actor: "key-a", Source: "10.0.0.1", Operation: "GET /v1/search", Error: "timeout", LatencyMs: 30000},
+			}
+			if err := store.RecordQueryAudits(ctx, records); err != nil {
+				t.Fatalf("Failed to record query audits: %v", err)
+			}
+
+			all, err := store.ListQueryAudits(ctx, models.QueryAuditFilter{})
+			if err != nil {
+				t.Fatalf("Failed to list query audits: %v", err)
+			}
+			if len(all) != 3 || all[0].Error != "timeout" || all[2].Operation != "GET /v1/search" {
+				t.Fatalf("Expected the records newest first, got %+v", all)
+			}
+			mcpRecord := all[1]
+			if mcpRecord.ID == 0 || mcpRecord.Filter["service_name"] != "checkout" || len(mcpRecord.MaskedFields) != 1 || mcpRecord.ResultCount != 20 {
+				t.Errorf("Expected the MCP record to be kept as recorded, got %+v", mcpRecord)
+			}
+
+			byActor, err := store.ListQueryAudits(ctx, models.QueryAuditFilter{Actor: "key-a", StartTime: now.Add(-24 * time.Hour)})
+			if err != nil {
+				t.Fatalf("Failed to list query audits: %v", err)
+			}
+			if len(byActor) != 1 || byActor[0].Error != "timeout" {
+				t.Errorf("Expected the recent query of key-a, got %+v", byActor)
+			}
+
+			deleted, err := store.DeleteQueryAudits(ctx, now.Add(-24*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to delete query audits: %v", err)
+			}
+			if deleted != 1 {
+				t.Errorf("Expected 1 deleted record, got %d", deleted)
+			}
+			if remaining, _ := store.ListQueryAudits(ctx, models.QueryAuditFilter{Limit: 1}); len(remaining) != 1 {
+				t.Errorf("Expected the limit to apply, got %d records", len(remaining))
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// QueryPlanStep is a single step of a query execution plan
+type QueryPlanStep struct {
+	ID     int    `json:"id"`
+	Parent int    `json:"parent"`
+	Detail string `json:"detail"`
+}
+
+// QueryPlan describes how the storage would execute the query for a filter
+type QueryPlan struct {
+	Query    string          `json:"query"`
+	Steps    []QueryPlanStep `json:"steps"`
+	Warnings []string        `json:"warnings"`
+}
+
+// ExplainQuery runs EXPLAIN QUERY PLAN for the query a filter produces and reports missing index warnings
+func (s *SQLiteStorage) ExplainQuery(ctx context.Context, filter models.LogFilter) (*QueryPlan, error) {
+	query, args := buildSelectQuery(filter)
+
+	rows, err := s.reader().QueryContext(ctx, "EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	defer rows.Close()
+
+	plan := &QueryPlan{
+		Query:    strings.Join(strings.Fields(query), " "),
+		Steps:    []QueryPlanStep{},
+		Warnings: []string{},
+	}
+
+	for rows.Next() {
+		var step QueryPlanStep
+		var notUsed int
+		if err := rows.Scan(&step.ID, &step.Parent, &notUsed, &step.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan query plan: %w", err)
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read query plan: %w", err)
+	}
+
+	plan.Warnings = planWarnings(plan.Steps, filter)
+
+	return plan, nil
+}
+
+// planWarnings reports plan steps that indicate a missing or unusable index
+func planWarnings(steps []QueryPlanStep, filter models.LogFilter) []string {
+	warnings := []string{}
+
+	for _, step := range steps {
+		detail := step.Detail
+		switch {
+		case strings.HasPrefix(detail, "SCAN ") && !strings.Contains(detail, " USING "):
+			warnings = append(warnings, fmt.Sprintf("full table scan without an index: %s", detail))
+		case strings.Contains(detail, "USE TEMP B-TREE"):
+			warnings = append(warnings, fmt.Sprintf("results are sorted without an index: %s", detail))
+		}
+	}
+
+	if filter.MessageContains != "" {
+		warnings = append(warnings, "message_contains uses a leading wildcard LIKE that cannot use an index, enable full-text search for large tables")
+	}
+
+	return warnings
+}
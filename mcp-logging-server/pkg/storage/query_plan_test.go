@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestSQLiteStorage_ExplainQuery(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	plan, err := storage.ExplainQuery(context.Background(), models.LogFilter{
+		ServiceName: "checkout-service",
+		Limit:       10,
+	})
+	if err != nil {
+		t.Fatalf("Failed to explain query: %v", err)
+	}
+
+	if !strings.Contains(plan.Query, "service_name = ?") {
+		t.Errorf("Expected query to filter on service_name, got %s", plan.Query)
+	}
+	if len(plan.Steps) == 0 {
+		t.Fatal("Expected query plan steps")
+	}
+	for _, warning := range plan.Warnings {
+		if strings.Contains(warning, "full table scan") {
+			t.Errorf("Expected service_name filter to use an index, got warning: %s", warning)
+		}
+	}
+
+	plan, err = storage.ExplainQuery(context.Background(), models.LogFilter{MessageContains: "timeout"})
+	if err != nil {
+		t.Fatalf("Failed to explain query: %v", err)
+	}
+
+	found := false
+	for _, warning := range plan.Warnings {
+		if strings.Contains(warning, "message_contains") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected message_contains warning, got %v", plan.Warnings)
+	}
+}
+
+func TestPlanWarnings(t *testing.T) {
+	steps := []QueryPlanStep{
+		{ID: 2, Detail: "SCAN log_entries"},
+		{ID: 3, Detail: "SCAN log_entries USING INDEX idx_log_entries_timestamp"},
+		{ID: 4, Detail: "SEARCH log_entries USING INDEX idx_log_entries_level (level=?)"},
+		{ID: 5, Detail: "USE TEMP B-TREE FOR ORDER BY"},
+	}
+
+	warnings := planWarnings(steps, models.LogFilter{})
+	if len(warnings) != 2 {
+		t.Fatalf("Expected 2 warnings, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "full table scan") {
+		t.Errorf("Expected full table scan warning, got %s", warnings[0])
+	}
+	if !strings.Contains(warnings[1], "sorted without an index") {
+		t.Errorf("Expected sort warning, got %s", warnings[1])
+	}
+}
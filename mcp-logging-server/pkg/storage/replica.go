@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SetReadReplica routes queries to a read-only replica of the database, such as a LiteFS or
+// Litestream copy, while writes and admin operations stay on the primary. The replica must
+// already hold the schema of the primary. Health checks report the replica lag, and mark the
+// storage degraded when it exceeds maxLag; zero disables the limit.
+func (s *SQLiteStorage) SetReadReplica(connectionString string, maxLag time.Duration) error {
+	replica, err := sql.Open(sqliteDriver, withParam(sqliteDSN(connectionString), queryOnlyParam))
+	if err != nil {
+		return fmt.Errorf("failed to open read replica: %w", err)
+	}
+
+	var version sql.NullInt64
+	if err := replica.QueryRow("SELECT MAX(version) FROM migrations").Scan(&version); err != nil {
+		replica.Close()
+		return fmt.Errorf("read replica has no schema: %w", err)
+	}
+	primaryVersion, err := s.SchemaVersion(context.Background())
+	if err != nil {
+		replica.Close()
+		return err
+	}
+	if int(version.Int64) != primaryVersion {
+		replica.Close()
+		return fmt.Errorf("read replica schema version %d does not match primary version %d", version.Int64, primaryVersion)
+	}
+
+	if s.replica != nil {
+		s.replica.Close()
+	}
+	s.replica = replica
+	s.maxReplicaLag = maxLag
+	return nil
+}
+
+// withParam adds a driver parameter to a connection string
+func withParam(connectionString, param string) string {
+	if strings.Contains(connectionString, "?") {
+		return connectionString + "&" + param
+	}
+	return connectionString + "?" + param
+}
+
+// reader returns the database that serves queries: the read replica if there is one
+func (s *SQLiteStorage) reader() *sql.DB {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.db
+}
+
+// ReplicaLag returns how far the newest entry of the read replica is behind the newest entry
+// of the primary, by receive time
+func (s *SQLiteStorage) ReplicaLag(ctx context.Context) (time.Duration, error) {
+	if s.replica == nil {
+		return 0, errors.New("no read replica configured")
+	}
+
+	primaryNewest, err := newestReceived(ctx, s.db)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read primary: %w", err)
+	}
+	replicaNewest, err := newestReceived(ctx, s.replica)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read replica: %w", err)
+	}
+
+	if !primaryNewest.Valid || (replicaNewest.Valid && !replicaNewest.Time.Before(primaryNewest.Time)) {
+		return 0, nil
+	}
+	if !replicaNewest.Valid {
+		// The replica has not received any entry yet
+		return time.Since(primaryNewest.Time), nil
+	}
+	return primaryNewest.Time.Sub(replicaNewest.Time), nil
+}
+
+// newestReceived returns the receive time of the newest entry of a database
+func newestReceived(ctx context.Context, db *sql.DB) (sql.NullTime, error) {
+	var newest sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT received_at FROM log_entries
+		WHERE received_at IS NOT NULL
+		ORDER BY received_at DESC
+		LIMIT 1
+	`).Scan(&newest)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullTime{}, nil
+	}
+	return newest, err
+}
+
+// replicaHealth adds the state of the read replica to a health check, degrading it when the
+// replica is unreachable or lags behind more than allowed
+func (s *SQLiteStorage) replicaHealth(ctx context.Context, details map[string]string) bool {
+	if err := s.replica.PingContext(ctx); err != nil {
+		details["replica"] = fmt.Sprintf("ping failed: %v", err)
+		return false
+	}
+
+	lag, err := s.ReplicaLag(ctx)
+	if err != nil {
+		details["replica"] = err.Error()
+		return false
+	}
+
+	details["replica"] = "connected"
+	details["replica_lag"] = lag.Round(time.Millisecond).String()
+	if s.maxReplicaLag > 0 && lag > s.maxReplicaLag {
+		details["replica_lag_exceeded"] = s.maxReplicaLag.String()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newReplicaTestLog(message string, receivedAt time.Time) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   receivedAt,
+		Level:       models.LogLevelInfo,
+		Message:     message,
+		ServiceName: "checkout-service",
+		AgentID:     "checkout-agent",
+		Platform:    models.PlatformGo,
+		ReceivedAt:  receivedAt,
+	}
+}
+
+func TestSQLiteStorage_ReadReplica(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	now := time.Now()
+
+	// The replica is a copy of the primary that has not caught up with the newest entry yet
+	older := newReplicaTestLog("order placed", now.Add(-time.Minute))
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica, err := NewSQLiteStorage(replicaPath)
+	if err != nil {
+		t.Fatalf("Failed to create replica: %v", err)
+	}
+	if err := replica.Store(ctx, []models.LogEntry{older}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+	replica.Close()
+
+	primary, err := NewSQLiteStorage(filepath.Join(dir, "primary.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer primary.Close()
+	if err := primary.Store(ctx, []models.LogEntry{older, newReplicaTestLog("order shipped", now)}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	if err := primary.SetReadReplica(replicaPath, 10*time.Second); err != nil {
+		t.Fatalf("Failed to set read replica: %v", err)
+	}
+
+	// Queries are served by the replica
+	result, err := primary.Query(ctx, models.LogFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if len(result.Logs) != 1 || result.Logs[0].ID != older.ID {
+		t.Errorf("Expected the replica's single entry, got %d entries", len(result.Logs))
+	}
+
+	lag, err := primary.ReplicaLag(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get replica lag: %v", err)
+	}
+	if lag < 59*time.Second || lag > 61*time.Second {
+		t.Errorf("Expected a replica lag of one minute, got %v", lag)
+	}
+
+	health := primary.HealthCheck(ctx)
+	if health.Status != "degraded" {
+		t.Errorf("Expected a lagging replica to degrade health, got %s", health.Status)
+	}
+	if health.Details["replica"] != "connected" || health.Details["replica_lag"] == "" {
+		t.Errorf("Expected replica details in health, got %v", health.Details)
+	}
+
+	// Writes still go to the primary, and the replica connection is read-only
+	if _, err := primary.replica.ExecContext(ctx, "DELETE FROM log_entries"); err == nil {
+		t.Error("Expected the read replica to reject writes")
+	}
+}
+
+func TestSQLiteStorage_ReadReplicaSchema(t *testing.T) {
+	dir := t.TempDir()
+
+	primary, err := NewSQLiteStorage(filepath.Join(dir, "primary.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer primary.Close()
+
+	emptyPath := filepath.Join(dir, "empty.db")
+	if err := os.WriteFile(emptyPath, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create empty database: %v", err)
+	}
+	if err := primary.SetReadReplica(emptyPath, 0); err == nil {
+		t.Error("Expected a replica without schema to be rejected")
+	}
+	if primary.replica != nil {
+		t.Error("Expected queries to stay on the primary")
+	}
+}
@@ -112,6 +112,20 @@ func (r *RetentionService) CleanupExpiredLogs(ctx context.Context) (*CleanupResu
 			if len(logs.Logs) < filter.Limit {
 				break
 			}
+
+			// Entries under a legal hold stay in place, skip past them
+			filter.Offset += len(logs.Logs) - deleted
+		}
+	}
+
+	// Retire search index shards once every level has expired their entries
+	if retirer, ok := r.storage.(SearchIndexRetirer); ok {
+		if cutoff := r.GetIndexRetentionDate(); !cutoff.IsZero() {
+			retired, err := retirer.RetireSearchShards(ctx, cutoff)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Failed to retire search index shards: %v", err))
+			}
+			result.RetiredIndexShards = retired
 		}
 	}
 
@@ -122,6 +136,31 @@ func (r *RetentionService) CleanupExpiredLogs(ctx context.Context) (*CleanupResu
 	return result, nil
 }
 
+// GetIndexRetentionDate returns the cutoff before which no log of any level is retained,
+// or the zero time when some level is kept forever
+func (r *RetentionService) GetIndexRetentionDate() time.Time {
+	levels := []models.LogLevel{
+		models.LogLevelDebug,
+		models.LogLevelInfo,
+		models.LogLevelWarn,
+		models.LogLevelError,
+		models.LogLevelFatal,
+	}
+
+	var cutoff time.Time
+	for _, level := range levels {
+		levelCutoff := r.GetRetentionDate(level)
+		if levelCutoff.IsZero() {
+			return time.Time{}
+		}
+		if cutoff.IsZero() || levelCutoff.Before(cutoff) {
+			cutoff = levelCutoff
+		}
+	}
+
+	return cutoff
+}
+
 // CleanupByCount removes oldest logs when count limits are exceeded
 func (r *RetentionService) CleanupByCount(ctx context.Context) (*CleanupResult, error) {
 	result := &CleanupResult{
@@ -190,14 +229,14 @@ func (r *RetentionService) cleanupByTotalCount(ctx context.Context, maxLogs int)
 // cleanupByServiceCount removes oldest logs per service when count exceeds limit
 func (r *RetentionService) cleanupByServiceCount(ctx context.Context, maxLogsPerService int) (int, error) {
 	// Get all services
-	services, err := r.storage.GetServices(ctx)
+	services, err := r.storage.GetServices(ctx, models.ServiceFilter{})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get services: %w", err)
 	}
 
 	totalDeleted := 0
 
-	for _, service := range services {
+	for _, service := range services.Services {
 		if service.LogCount <= maxLogsPerService {
 			continue // No cleanup needed for this service
 		}
@@ -254,7 +293,9 @@ type CleanupResult struct {
 	Duration       time.Duration           `json:"duration"`
 	TotalDeleted   int                     `json:"total_deleted"`
 	DeletedByLevel map[models.LogLevel]int `json:"deleted_by_level"`
-	Errors         []string                `json:"errors,omitempty"`
+	// RetiredIndexShards is the number of search index shards removed
+	RetiredIndexShards int      `json:"retired_index_shards,omitempty"`
+	Errors             []string `json:"errors,omitempty"`
 }
 
 // LogDeleter interface for storages that support log deletion
@@ -262,6 +303,11 @@ type LogDeleter interface {
 	DeleteByIDs(ctx context.Context, ids []string) (int, error)
 }
 
+// SearchIndexRetirer interface for storages whose search index can drop whole time ranges
+type SearchIndexRetirer interface {
+	RetireSearchShards(ctx context.Context, before time.Time) (int, error)
+}
+
 // RetentionScheduler manages automatic cleanup scheduling
 type RetentionScheduler struct {
 	retentionService *RetentionService
@@ -486,3 +486,37 @@ func TestRetentionPolicy_NoRetention(t *testing.T) {
 		t.Errorf("Expected 1 log to remain, got %d", len(logs.Logs))
 	}
 }
+
+func TestRetentionService_GetIndexRetentionDate(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	retentionService := NewRetentionService(storage, RetentionPolicy{
+		DefaultDays: 30,
+		ByLevel: map[models.LogLevel]int{
+			models.LogLevelDebug: 7,
+			models.LogLevelError: 90,
+		},
+	})
+
+	// Shards may only be retired once the longest retention has passed
+	cutoff := retentionService.GetIndexRetentionDate()
+	expected := time.Now().AddDate(0, 0, -90)
+	if cutoff.Sub(expected).Abs() > time.Minute {
+		t.Errorf("Expected index retention date around %v, got %v", expected, cutoff)
+	}
+
+	retentionService = NewRetentionService(storage, RetentionPolicy{
+		DefaultDays: 30,
+		ByLevel: map[models.LogLevel]int{
+			models.LogLevelFatal: 0,
+		},
+	})
+
+	if cutoff := retentionService.GetIndexRetentionDate(); !cutoff.IsZero() {
+		t.Errorf("Expected zero index retention date when a level is kept forever, got %v", cutoff)
+	}
+}
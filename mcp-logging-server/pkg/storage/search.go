@@ -3,12 +3,16 @@ package storage
 import (
 	"context"
 	"fmt"
-	"os"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/blevesearch/bleve/v2"
 	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search"
 	"github.com/blevesearch/bleve/v2/search/query"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
 )
@@ -18,49 +22,81 @@ type SearchableLogEntry struct {
 	ID             string                 `json:"id"`
 	Timestamp      time.Time              `json:"timestamp"`
 	Level          string                 `json:"level"`
+	Severity       int                    `json:"severity"`
 	Message        string                 `json:"message"`
 	ServiceName    string                 `json:"service_name"`
 	AgentID        string                 `json:"agent_id"`
 	Platform       string                 `json:"platform"`
+	RequestID      string                 `json:"request_id,omitempty"`
+	TemplateID     string                 `json:"template_id,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
 	StackTrace     string                 `json:"stack_trace,omitempty"`
 	DevicePlatform string                 `json:"device_platform,omitempty"`
 	DeviceModel    string                 `json:"device_model,omitempty"`
 	SourceFile     string                 `json:"source_file,omitempty"`
 	SourceFunction string                 `json:"source_function,omitempty"`
+	Tags           []string               `json:"tags,omitempty"`
+	ReceivedAt     time.Time              `json:"received_at,omitempty"`
+	Day            string                 `json:"day"` // UTC day of the timestamp, for the day facet
 }
 
-// SearchService provides full-text search capabilities for log entries
+// SearchService provides full-text search capabilities for log entries.
+// Entries are indexed into time-based shards by timestamp, a new shard is
+// created when entries for a new time span arrive and whole shards are
+// retired once retention has expired every entry they cover.
 type SearchService struct {
-	index bleve.Index
+	mutex         sync.RWMutex
+	path          string
+	shardDuration time.Duration
+	shards        []*indexShard
+	alias         bleve.IndexAlias
+	needsRebuild  bool
+	encryption    *encryptionKey // Key hashing the terms of new shards, see SearchConfig.EncryptionKey
 }
 
-// NewSearchService creates a new search service with Bleve index
+// analysisWorkers sizes Bleve's analysis queue once, before the first index is opened. The queue
+// is shared by all indexes of the process and indexes keep the queue they were opened with, so
+// it cannot be resized while any is open.
+var analysisWorkers sync.Once
+
+// NewSearchService creates a new search service with daily Bleve index shards
 func NewSearchService(indexPath string) (*SearchService, error) {
-	var index bleve.Index
-	var err error
-
-	// Check if index already exists
-	if _, statErr := os.Stat(indexPath); os.IsNotExist(statErr) {
-		// Create new index
-		mapping := buildIndexMapping()
-		index, err = bleve.New(indexPath, mapping)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create search index: %w", err)
+	return NewSearchServiceWithConfig(SearchConfig{IndexPath: indexPath})
+}
+
+// NewSearchServiceWithConfig creates a new search service with the given shard configuration
+func NewSearchServiceWithConfig(config SearchConfig) (*SearchService, error) {
+	if config.ShardDuration <= 0 {
+		config.ShardDuration = DefaultShardDuration
+	}
+	analysisWorkers.Do(func() {
+		if config.IndexWorkers > 0 {
+			bleve.Config.SetAnalysisQueueSize(config.IndexWorkers)
 		}
-	} else {
-		// Open existing index
-		index, err = bleve.Open(indexPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to open search index: %w", err)
+	})
+
+	s := &SearchService{
+		path:          config.IndexPath,
+		shardDuration: config.ShardDuration,
+		alias:         bleve.NewIndexAlias(),
+	}
+	if config.EncryptionKey != nil {
+		if err := RegisterEncryptionKeys(config.EncryptionKey); err != nil {
+			return nil, err
 		}
+		s.encryption = registeredKey(encryptionKeyID(config.EncryptionKey))
 	}
 
-	return &SearchService{index: index}, nil
+	if err := s.openShards(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
 }
 
-// buildIndexMapping creates the Bleve index mapping for log entries
-func buildIndexMapping() mapping.IndexMapping {
+// buildIndexMapping creates the Bleve index mapping for log entries, hashing the terms of the
+// free-text fields with key unless it is nil
+func buildIndexMapping(key *encryptionKey) (mapping.IndexMapping, error) {
 	// Create a mapping
 	logMapping := bleve.NewDocumentMapping()
 
@@ -73,11 +109,19 @@ func buildIndexMapping() mapping.IndexMapping {
 	timestampFieldMapping := bleve.NewDateTimeFieldMapping()
 	logMapping.AddFieldMappingsAt("timestamp", timestampFieldMapping)
 
+	// Received at field - datetime (server receive time)
+	receivedAtFieldMapping := bleve.NewDateTimeFieldMapping()
+	logMapping.AddFieldMappingsAt("received_at", receivedAtFieldMapping)
+
 	// Level field - keyword (exact match)
 	levelFieldMapping := bleve.NewTextFieldMapping()
 	levelFieldMapping.Analyzer = "keyword"
 	logMapping.AddFieldMappingsAt("level", levelFieldMapping)
 
+	// Severity field - numeric, the rank of the level for level ranges
+	severityFieldMapping := bleve.NewNumericFieldMapping()
+	logMapping.AddFieldMappingsAt("severity", severityFieldMapping)
+
 	// Message field - full text search
 	messageFieldMapping := bleve.NewTextFieldMapping()
 	messageFieldMapping.Analyzer = "standard"
@@ -93,11 +137,31 @@ func buildIndexMapping() mapping.IndexMapping {
 	agentFieldMapping.Analyzer = "keyword"
 	logMapping.AddFieldMappingsAt("agent_id", agentFieldMapping)
 
+	// Request ID field - keyword (exact match)
+	requestFieldMapping := bleve.NewTextFieldMapping()
+	requestFieldMapping.Analyzer = "keyword"
+	logMapping.AddFieldMappingsAt("request_id", requestFieldMapping)
+
+	// Template ID field - keyword (exact match)
+	templateFieldMapping := bleve.NewTextFieldMapping()
+	templateFieldMapping.Analyzer = "keyword"
+	logMapping.AddFieldMappingsAt("template_id", templateFieldMapping)
+
 	// Platform field - keyword (exact match)
 	platformFieldMapping := bleve.NewTextFieldMapping()
 	platformFieldMapping.Analyzer = "keyword"
 	logMapping.AddFieldMappingsAt("platform", platformFieldMapping)
 
+	// Tags field - keyword (exact match)
+	tagsFieldMapping := bleve.NewTextFieldMapping()
+	tagsFieldMapping.Analyzer = "keyword"
+	logMapping.AddFieldMappingsAt("tags", tagsFieldMapping)
+
+	// Day field - keyword (exact match), used for the day facet
+	dayFieldMapping := bleve.NewTextFieldMapping()
+	dayFieldMapping.Analyzer = "keyword"
+	logMapping.AddFieldMappingsAt("day", dayFieldMapping)
+
 	// Stack trace field - full text search
 	stackTraceFieldMapping := bleve.NewTextFieldMapping()
 	stackTraceFieldMapping.Analyzer = "standard"
@@ -126,61 +190,303 @@ func buildIndexMapping() mapping.IndexMapping {
 	indexMapping.AddDocumentMapping("log", logMapping)
 	indexMapping.DefaultMapping = logMapping
 
-	return indexMapping
+	if key != nil {
+		if err := encryptMapping(indexMapping, logMapping, key); err != nil {
+			return nil, err
+		}
+	}
+
+	return indexMapping, nil
 }
 
 // IndexLogEntry adds or updates a log entry in the search index
 func (s *SearchService) IndexLogEntry(logEntry models.LogEntry) error {
-	searchableEntry := s.convertToSearchable(logEntry)
-	return s.index.Index(logEntry.ID, searchableEntry)
+	return s.IndexLogEntries([]models.LogEntry{logEntry})
 }
 
 // IndexLogEntries adds or updates multiple log entries in the search index
 func (s *SearchService) IndexLogEntries(logEntries []models.LogEntry) error {
-	batch := s.index.NewBatch()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	batches := make(map[*indexShard]*bleve.Batch)
 
 	for _, logEntry := range logEntries {
+		shard, err := s.shardFor(logEntry.Timestamp)
+		if err != nil {
+			return err
+		}
+
+		batch, ok := batches[shard]
+		if !ok {
+			batch = shard.index.NewBatch()
+			batches[shard] = batch
+		}
+
 		searchableEntry := s.convertToSearchable(logEntry)
 		if err := batch.Index(logEntry.ID, searchableEntry); err != nil {
 			return fmt.Errorf("failed to add log entry %s to batch: %w", logEntry.ID, err)
 		}
 	}
 
-	return s.index.Batch(batch)
+	for shard, batch := range batches {
+		if err := shard.index.Batch(batch); err != nil {
+			return fmt.Errorf("failed to index batch in shard %s: %w", shard.name, err)
+		}
+	}
+
+	return nil
+}
+
+// highlightedFields are the full-text fields highlighted in search results
+var highlightedFields = []string{"message", "stack_trace"}
+
+const (
+	// recencyHalfLife is the log age at which the relevance sort recency boost halves
+	recencyHalfLife = 24 * time.Hour
+
+	// relevanceCandidates is the minimum number of best scoring hits re-ranked by recency
+	relevanceCandidates = 200
+)
+
+// searchFacet maps a facet name to the indexed field it counts and the number of values returned
+type searchFacet struct {
+	field string
+	size  int
+}
+
+// searchFacets are the facets that can be requested with full-text search
+var searchFacets = map[string]searchFacet{
+	models.FacetLevel:    {field: "level", size: 5},
+	models.FacetService:  {field: "service_name", size: 20},
+	models.FacetPlatform: {field: "platform", size: 20},
+	models.FacetDay:      {field: "day", size: 31},
+}
+
+// SearchHit is a search index match with highlighted fragments and match positions
+type SearchHit struct {
+	ID        string
+	Score     float64
+	Fragments map[string][]string
+	Matches   []models.SearchMatch
+	locations search.FieldTermLocationMap // Highlights the entry when the index does not store the fields
+}
+
+// SearchLogs performs a full-text search on log entries and returns the matching log IDs
+// together with the facet counts requested in the filter
+func (s *SearchService) SearchLogs(ctx context.Context, query string, filter models.LogFilter) ([]string, map[string][]models.FacetCount, error) {
+	searchResult, err := s.search(ctx, s.newSearchRequest(query, filter), filter)
+	if err != nil || searchResult == nil {
+		return nil, nil, err
+	}
+
+	// Extract log IDs from search results
+	var logIDs []string
+	for _, hit := range searchResult.Hits {
+		logIDs = append(logIDs, hit.ID)
+	}
+
+	return logIDs, facetCounts(searchResult.Facets), nil
+}
+
+// SearchWithHighlights performs a full-text search and returns highlighted fragments and
+// match positions in the message and stack trace of each hit, plus the total match count
+func (s *SearchService) SearchWithHighlights(ctx context.Context, query string, filter models.LogFilter) ([]SearchHit, int, map[string][]models.FacetCount, error) {
+	searchRequest := s.newSearchRequest(query, filter)
+	searchRequest.Highlight = bleve.NewHighlightWithStyle("html")
+	for _, field := range highlightedFields {
+		searchRequest.Highlight.AddField(field)
+	}
+	searchRequest.IncludeLocations = true
+
+	searchResult, err := s.search(ctx, searchRequest, filter)
+	if err != nil || searchResult == nil {
+		return nil, 0, nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(searchResult.Hits))
+	for _, match := range searchResult.Hits {
+		hit := SearchHit{
+			ID:        match.ID,
+			Score:     match.Score,
+			Fragments: make(map[string][]string),
+			Matches:   matchLocations(match.Locations),
+			locations: match.Locations,
+		}
+		for _, field := range highlightedFields {
+			if fragments := match.Fragments[field]; len(fragments) > 0 {
+				hit.Fragments[field] = fragments
+			}
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, int(searchResult.Total), facetCounts(searchResult.Facets), nil
 }
 
-// SearchLogs performs a full-text search on log entries
-func (s *SearchService) SearchLogs(ctx context.Context, query string, filter models.LogFilter) ([]string, error) {
+// newSearchRequest builds a paginated search request, newest first unless sorted by relevance
+func (s *SearchService) newSearchRequest(query string, filter models.LogFilter) *bleve.SearchRequest {
 	// Build search query
 	searchQuery := s.buildSearchQuery(query, filter)
 
 	// Create search request
 	searchRequest := bleve.NewSearchRequest(searchQuery)
-	searchRequest.Size = filter.Limit
-	if filter.Limit <= 0 {
-		searchRequest.Size = 100
-	}
-	searchRequest.From = filter.Offset
-	if filter.Offset < 0 {
+	searchRequest.From, searchRequest.Size = searchPage(filter)
+
+	if filter.Sort == models.SearchSortRelevance {
+		// Fetch the best scoring candidates from the start, they are re-ranked by recency
+		// and paginated after the search
+		searchRequest.Size = searchRequest.From + searchRequest.Size
+		if searchRequest.Size < relevanceCandidates {
+			searchRequest.Size = relevanceCandidates
+		}
 		searchRequest.From = 0
+		searchRequest.Fields = []string{"timestamp"}
+		searchRequest.SortBy([]string{"-_score", "-timestamp"})
+	} else {
+		// Sort by timestamp descending
+		searchRequest.SortBy([]string{"-timestamp"})
+	}
+
+	for _, name := range filter.Facets {
+		if facet, ok := searchFacets[name]; ok {
+			searchRequest.AddFacet(name, bleve.NewFacetRequest(facet.field, facet.size))
+		}
 	}
 
-	// Sort by timestamp descending
-	searchRequest.SortBy([]string{"-timestamp"})
+	return searchRequest
+}
+
+// facetCounts converts Bleve term facets to value counts, most frequent first
+func facetCounts(facetResults search.FacetResults) map[string][]models.FacetCount {
+	if len(facetResults) == 0 {
+		return nil
+	}
 
-	// Execute search
-	searchResult, err := s.index.Search(searchRequest)
+	counts := make(map[string][]models.FacetCount, len(facetResults))
+	for name, facetResult := range facetResults {
+		terms := facetResult.Terms.Terms()
+		values := make([]models.FacetCount, 0, len(terms))
+		for _, term := range terms {
+			values = append(values, models.FacetCount{Value: term.Term, Count: term.Count})
+		}
+		counts[name] = values
+	}
+
+	return counts
+}
+
+// searchPage returns the offset and size of the requested page of hits
+func searchPage(filter models.LogFilter) (int, int) {
+	from := filter.Offset
+	if from < 0 {
+		from = 0
+	}
+	size := filter.Limit
+	if size <= 0 {
+		size = 100
+	}
+	return from, size
+}
+
+// search executes a request across all shards, returning nil when there are no shards yet
+func (s *SearchService) search(ctx context.Context, searchRequest *bleve.SearchRequest, filter models.LogFilter) (*bleve.SearchResult, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if len(s.shards) == 0 {
+		return nil, nil
+	}
+
+	searchResult, err := s.alias.SearchInContext(ctx, searchRequest)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
 
-	// Extract log IDs from search results
-	var logIDs []string
-	for _, hit := range searchResult.Hits {
-		logIDs = append(logIDs, hit.ID)
+	if filter.Sort == models.SearchSortRelevance {
+		from, size := searchPage(filter)
+		searchResult.Hits = rankByRecency(searchResult.Hits, from, size, time.Now())
 	}
 
-	return logIDs, nil
+	return searchResult, nil
+}
+
+// rankByRecency multiplies each hit's score by one plus a recency boost that starts at 1
+// for new entries and halves every recencyHalfLife, so a fresh entry ranks up to twice
+// as high as an old one with the same relevance, and returns the requested page
+func rankByRecency(hits search.DocumentMatchCollection, from, size int, now time.Time) search.DocumentMatchCollection {
+	for _, hit := range hits {
+		hit.Score *= 1 + recencyBoost(hitTimestamp(hit), now)
+	}
+
+	// Hits arrive newest first among equal scores, a stable sort keeps that order
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+
+	if from >= len(hits) {
+		return hits[:0]
+	}
+	end := from + size
+	if end > len(hits) {
+		end = len(hits)
+	}
+	return hits[from:end]
+}
+
+// recencyBoost returns 1 for entries logged now, halving every recencyHalfLife, and 0
+// for entries without a timestamp
+func recencyBoost(timestamp, now time.Time) float64 {
+	if timestamp.IsZero() {
+		return 0
+	}
+
+	age := now.Sub(timestamp)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(recencyHalfLife))
+}
+
+// hitTimestamp returns the stored timestamp of a hit, or the zero time if it was not loaded
+func hitTimestamp(hit *search.DocumentMatch) time.Time {
+	value, ok := hit.Fields["timestamp"].(string)
+	if !ok {
+		return time.Time{}
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return timestamp
+}
+
+// matchLocations flattens the term locations of the highlighted fields, ordered by field and offset
+func matchLocations(locations search.FieldTermLocationMap) []models.SearchMatch {
+	var matches []models.SearchMatch
+
+	for _, field := range highlightedFields {
+		for term, termLocations := range locations[field] {
+			for _, location := range termLocations {
+				matches = append(matches, models.SearchMatch{
+					Field: field,
+					Term:  term,
+					Start: int(location.Start),
+					End:   int(location.End),
+				})
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Field != matches[j].Field {
+			return matches[i].Field < matches[j].Field
+		}
+		return matches[i].Start < matches[j].Start
+	})
+
+	return matches
 }
 
 // buildSearchQuery constructs a Bleve query based on search text and filters
@@ -189,15 +495,7 @@ func (s *SearchService) buildSearchQuery(queryText string, filter models.LogFilt
 
 	// Full-text search query
 	if queryText != "" {
-		// Search in message and stack trace fields
-		messageQuery := bleve.NewMatchQuery(queryText)
-		messageQuery.SetField("message")
-
-		stackTraceQuery := bleve.NewMatchQuery(queryText)
-		stackTraceQuery.SetField("stack_trace")
-
-		textQuery := bleve.NewDisjunctionQuery(messageQuery, stackTraceQuery)
-		queries = append(queries, textQuery)
+		queries = append(queries, s.buildTextQuery(queryText, filter))
 	}
 
 	// Filter by service name
@@ -214,6 +512,23 @@ func (s *SearchService) buildSearchQuery(queryText string, filter models.LogFilt
 		queries = append(queries, agentQuery)
 	}
 
+	// Filter by request ID. Indexes created before the field was mapped analyze it as text, a
+	// match query of all its terms finds it in both and the storage checks the exact value.
+	if filter.RequestID != "" {
+		requestQuery := bleve.NewMatchQuery(filter.RequestID)
+		requestQuery.SetField("request_id")
+		requestQuery.SetOperator(query.MatchQueryOperatorAnd)
+		queries = append(queries, requestQuery)
+	}
+
+	// Filter by template ID. Template IDs are lowercase hex, a single term in indexes created
+	// before the field was mapped too.
+	if filter.TemplateID != "" {
+		templateQuery := bleve.NewTermQuery(filter.TemplateID)
+		templateQuery.SetField("template_id")
+		queries = append(queries, templateQuery)
+	}
+
 	// Filter by level
 	if filter.Level != "" {
 		levelQuery := bleve.NewTermQuery(string(filter.Level))
@@ -221,6 +536,21 @@ func (s *SearchService) buildSearchQuery(queryText string, filter models.LogFilt
 		queries = append(queries, levelQuery)
 	}
 
+	// Filter by minimum level. Entries indexed before the severity field existed have none, so
+	// their levels are matched by name too.
+	if filter.MinLevel != "" {
+		minSeverity, inclusive := float64(filter.MinLevel.Severity()), true
+		severityQuery := bleve.NewNumericRangeInclusiveQuery(&minSeverity, nil, &inclusive, nil)
+		severityQuery.SetField("severity")
+		levelQueries := []query.Query{severityQuery}
+		for _, level := range models.LevelsFrom(filter.MinLevel) {
+			levelQuery := bleve.NewTermQuery(string(level))
+			levelQuery.SetField("level")
+			levelQueries = append(levelQueries, levelQuery)
+		}
+		queries = append(queries, bleve.NewDisjunctionQuery(levelQueries...))
+	}
+
 	// Filter by platform
 	if filter.Platform != "" {
 		platformQuery := bleve.NewTermQuery(string(filter.Platform))
@@ -228,6 +558,24 @@ func (s *SearchService) buildSearchQuery(queryText string, filter models.LogFilt
 		queries = append(queries, platformQuery)
 	}
 
+	// Filter by tags, any of
+	if len(filter.TagsAny) > 0 {
+		tagQueries := make([]query.Query, 0, len(filter.TagsAny))
+		for _, tag := range filter.TagsAny {
+			tagQuery := bleve.NewTermQuery(tag)
+			tagQuery.SetField("tags")
+			tagQueries = append(tagQueries, tagQuery)
+		}
+		queries = append(queries, bleve.NewDisjunctionQuery(tagQueries...))
+	}
+
+	// Filter by tags, all of
+	for _, tag := range filter.TagsAll {
+		tagQuery := bleve.NewTermQuery(tag)
+		tagQuery.SetField("tags")
+		queries = append(queries, tagQuery)
+	}
+
 	// Filter by time range
 	if !filter.StartTime.IsZero() || !filter.EndTime.IsZero() {
 		var timeQuery *query.DateRangeQuery
@@ -245,7 +593,11 @@ func (s *SearchService) buildSearchQuery(queryText string, filter models.LogFilt
 			timeQuery = bleve.NewDateRangeQuery(epoch, filter.EndTime)
 		}
 
-		timeQuery.SetField("timestamp")
+		if filter.TimeField == models.TimeFieldReceivedAt {
+			timeQuery.SetField("received_at")
+		} else {
+			timeQuery.SetField("timestamp")
+		}
 		queries = append(queries, timeQuery)
 	}
 
@@ -263,18 +615,61 @@ func (s *SearchService) buildSearchQuery(queryText string, filter models.LogFilt
 	return bleve.NewConjunctionQuery(queries...)
 }
 
+// buildTextQuery matches the query text in the message and stack trace fields, optionally
+// tolerating typos up to the filter fuzziness and matching words starting with each term
+func (s *SearchService) buildTextQuery(queryText string, filter models.LogFilter) query.Query {
+	fuzziness := filter.Fuzziness
+	if fuzziness < 0 {
+		fuzziness = 0
+	}
+	if fuzziness > models.MaxSearchFuzziness {
+		fuzziness = models.MaxSearchFuzziness
+	}
+
+	// Terms of encrypted shards are hashes, they are only matched exactly
+	if s.encryption != nil {
+		fuzziness = 0
+		filter.Prefix = false
+	}
+
+	var textQueries []query.Query
+	for _, field := range highlightedFields {
+		matchQuery := bleve.NewMatchQuery(queryText)
+		matchQuery.SetField(field)
+		matchQuery.SetFuzziness(fuzziness)
+		textQueries = append(textQueries, matchQuery)
+
+		// Prefix queries are not analyzed, the standard analyzer indexes lowercase terms
+		if filter.Prefix {
+			for _, term := range strings.Fields(strings.ToLower(queryText)) {
+				prefixQuery := bleve.NewPrefixQuery(term)
+				prefixQuery.SetField(field)
+				textQueries = append(textQueries, prefixQuery)
+			}
+		}
+	}
+
+	return bleve.NewDisjunctionQuery(textQueries...)
+}
+
 // convertToSearchable converts a LogEntry to SearchableLogEntry
 func (s *SearchService) convertToSearchable(logEntry models.LogEntry) SearchableLogEntry {
 	searchable := SearchableLogEntry{
 		ID:          logEntry.ID,
 		Timestamp:   logEntry.Timestamp,
 		Level:       string(logEntry.Level),
+		Severity:    logEntry.Level.Severity(),
 		Message:     logEntry.Message,
 		ServiceName: logEntry.ServiceName,
 		AgentID:     logEntry.AgentID,
 		Platform:    string(logEntry.Platform),
+		RequestID:   logEntry.RequestID,
+		TemplateID:  logEntry.TemplateID,
 		Metadata:    logEntry.Metadata,
 		StackTrace:  logEntry.StackTrace,
+		Tags:        logEntry.Tags,
+		ReceivedAt:  logEntry.ReceivedAt,
+		Day:         logEntry.Timestamp.UTC().Format("2006-01-02"),
 	}
 
 	// Extract device information
@@ -294,32 +689,55 @@ func (s *SearchService) convertToSearchable(logEntry models.LogEntry) Searchable
 
 // DeleteLogEntry removes a log entry from the search index
 func (s *SearchService) DeleteLogEntry(id string) error {
-	return s.index.Delete(id)
+	return s.DeleteLogEntries([]string{id})
+}
+
+// DeleteLogEntries removes log entries from every shard of the search index
+func (s *SearchService) DeleteLogEntries(ids []string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, shard := range s.shards {
+		batch := shard.index.NewBatch()
+		for _, id := range ids {
+			batch.Delete(id)
+		}
+		if err := shard.index.Batch(batch); err != nil {
+			return fmt.Errorf("failed to delete from shard %s: %w", shard.name, err)
+		}
+	}
+
+	return nil
 }
 
 // GetIndexStats returns statistics about the search index
 func (s *SearchService) GetIndexStats() (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
-
-	// Get document count
-	docCount, err := s.index.DocCount()
+	shards, err := s.ShardStats()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get document count: %w", err)
+		return nil, err
 	}
-	stats["document_count"] = docCount
 
-	// Get index size (approximate)
-	if indexImpl, ok := s.index.(interface{ StatsMap() map[string]interface{} }); ok {
-		indexStats := indexImpl.StatsMap()
-		stats["index_stats"] = indexStats
+	var docCount uint64
+	var sizeBytes int64
+	for _, shard := range shards {
+		docCount += shard.DocumentCount
+		sizeBytes += shard.SizeBytes
 	}
 
-	return stats, nil
+	return map[string]interface{}{
+		"document_count": docCount,
+		"size_bytes":     sizeBytes,
+		"shard_count":    len(shards),
+		"shards":         shards,
+	}, nil
 }
 
-// Close closes the search index
+// Close closes all shards of the search index
 func (s *SearchService) Close() error {
-	return s.index.Close()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closeShards()
 }
 
 // HealthCheck returns the health status of the search service
@@ -330,16 +748,29 @@ func (s *SearchService) HealthCheck(ctx context.Context) models.HealthStatus {
 		Details:   make(map[string]string),
 	}
 
-	// Check if index is accessible
-	docCount, err := s.index.DocCount()
+	// Check if every shard is accessible
+	shards, err := s.ShardStats()
 	if err != nil {
 		status.Status = "unhealthy"
-		status.Details["index"] = fmt.Sprintf("failed to get document count: %v", err)
+		status.Details["index"] = err.Error()
 		return status
 	}
 
+	var docCount uint64
+	var sizeBytes int64
+	for _, shard := range shards {
+		docCount += shard.DocumentCount
+		sizeBytes += shard.SizeBytes
+	}
+
 	status.Details["index"] = "accessible"
 	status.Details["document_count"] = strconv.FormatUint(docCount, 10)
+	status.Details["size_bytes"] = strconv.FormatInt(sizeBytes, 10)
+	status.Details["shard_count"] = strconv.Itoa(len(shards))
+	if len(shards) > 0 {
+		status.Details["oldest_shard"] = shards[0].Name
+		status.Details["newest_shard"] = shards[len(shards)-1].Name
+	}
 
 	return status
 }
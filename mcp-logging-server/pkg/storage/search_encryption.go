@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/custom"
+	"github.com/blevesearch/bleve/v2/analysis/lang/en"
+	"github.com/blevesearch/bleve/v2/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/v2/analysis/tokenizer/unicode"
+	"github.com/blevesearch/bleve/v2/document"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/registry"
+	"github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// Encrypted shards index keyed hashes of the terms of the free-text fields instead of the terms
+// and do not store the values of these fields, so their files hold no log contents. Queries are
+// analyzed the same way, so words and request IDs are still matched, but terms cannot be
+// compared for fuzzy and prefix matches. A shard names the ID of its key in its mapping and
+// stays searchable after a key rotation while the key is registered.
+const (
+	blindTermsType       = "mcp_blind_terms" // Type of the token filter, registered with Bleve
+	blindTermsFilter     = "blind_terms"     // Name of the token filter in the mapping of a shard
+	blindTextAnalyzer    = "blind_text"      // The standard analyzer with hashed terms
+	blindKeywordAnalyzer = "blind_keyword"   // The keyword analyzer with hashed terms
+	blindTermSize        = 12                // Bytes of the HMAC kept per term
+)
+
+// blindTextFields and blindKeywordFields are the fields encrypted shards index by hashed terms
+var (
+	blindTextFields    = []string{"message", "stack_trace"}
+	blindKeywordFields = []string{"request_id", "device_platform", "device_model", "source_file", "source_function"}
+)
+
+// blindTerms is a token filter replacing terms with their keyed hashes
+type blindTerms struct {
+	key []byte
+}
+
+// Filter implements analysis.TokenFilter
+func (f *blindTerms) Filter(input analysis.TokenStream) analysis.TokenStream {
+	for _, token := range input {
+		token.Term = blindTerm(f.key, token.Term)
+	}
+	return input
+}
+
+// blindTerm returns the hexadecimal keyed hash of a term
+func blindTerm(key, term []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(term)
+	hash := make([]byte, hex.EncodedLen(blindTermSize))
+	hex.Encode(hash, mac.Sum(nil)[:blindTermSize])
+	return hash
+}
+
+// newBlindTerms creates the token filter of a shard from its mapping, with the registered key
+// of the key ID in the mapping
+func newBlindTerms(config map[string]interface{}, cache *registry.Cache) (analysis.TokenFilter, error) {
+	value, _ := config["key_id"].(string)
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) != keyIDSize {
+		return nil, fmt.Errorf("invalid search index key ID %q", value)
+	}
+	var id [keyIDSize]byte
+	copy(id[:], decoded)
+
+	key := registeredKey(id)
+	if key == nil {
+		return nil, fmt.Errorf("search index terms are hashed with an unknown key %x, configure it under storage.encryption", id)
+	}
+	return &blindTerms{key: key.termKey}, nil
+}
+
+func init() {
+	if err := registry.RegisterTokenFilter(blindTermsType, newBlindTerms); err != nil {
+		panic(err)
+	}
+}
+
+// encryptMapping makes an index mapping hash the terms of the free-text fields with key and
+// not store their values. Metadata is not indexed, it is not searched.
+func encryptMapping(indexMapping *mapping.IndexMappingImpl, logMapping *mapping.DocumentMapping, key *encryptionKey) error {
+	if err := indexMapping.AddCustomTokenFilter(blindTermsFilter, map[string]interface{}{
+		"type":   blindTermsType,
+		"key_id": hex.EncodeToString(key.id[:]),
+	}); err != nil {
+		return err
+	}
+	if err := indexMapping.AddCustomAnalyzer(blindTextAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     unicode.Name,
+		"token_filters": []string{lowercase.Name, en.StopName, blindTermsFilter},
+	}); err != nil {
+		return err
+	}
+	if err := indexMapping.AddCustomAnalyzer(blindKeywordAnalyzer, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []string{blindTermsFilter},
+	}); err != nil {
+		return err
+	}
+
+	for analyzer, fields := range map[string][]string{blindTextAnalyzer: blindTextFields, blindKeywordAnalyzer: blindKeywordFields} {
+		for _, field := range fields {
+			fieldMapping := bleve.NewTextFieldMapping()
+			fieldMapping.Analyzer = analyzer
+			fieldMapping.Store = false
+			delete(logMapping.Properties, field)
+			logMapping.AddFieldMappingsAt(field, fieldMapping)
+		}
+	}
+	logMapping.AddSubDocumentMapping("metadata", bleve.NewDocumentDisabledMapping())
+	return nil
+}
+
+// isEncrypted reports whether a shard hashes its terms
+func isEncrypted(index bleve.Index) bool {
+	indexMapping, ok := index.Mapping().(*mapping.IndexMappingImpl)
+	if !ok || indexMapping.CustomAnalysis == nil {
+		return false
+	}
+	_, ok = indexMapping.CustomAnalysis.TokenFilters[blindTermsFilter]
+	return ok
+}
+
+// highlightEntry fills in the fragments and matched terms of the fields of a hit that the index
+// does not store the values of, from the stored entry
+func (h *SearchHit) highlightEntry(entry models.LogEntry) {
+	values := map[string]string{"message": entry.Message, "stack_trace": entry.StackTrace}
+	var fields []string
+	for _, field := range highlightedFields {
+		// Fields of encrypted shards have locations but no fragments
+		if len(h.locations[field]) > 0 && len(h.Fragments[field]) == 0 {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return
+	}
+
+	highlighter, err := bleve.Config.Cache.HighlighterNamed(html.Name)
+	if err != nil {
+		return
+	}
+	doc := document.NewDocument(h.ID)
+	for _, field := range fields {
+		doc.AddField(document.NewTextField(field, nil, []byte(values[field])))
+	}
+	match := &search.DocumentMatch{ID: h.ID, Locations: h.locations}
+	for _, field := range fields {
+		if fragments := highlighter.BestFragmentsInField(match, doc, field, 1); len(fragments) > 0 {
+			h.Fragments[field] = fragments
+		}
+	}
+
+	// Matched terms are hashes of the lowercase words at their locations
+	for i, match := range h.Matches {
+		value := values[match.Field]
+		if slices.Contains(fields, match.Field) && match.Start >= 0 && match.Start <= match.End && match.End <= len(value) {
+			h.Matches[i].Term = strings.ToLower(value[match.Start:match.End])
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+)
+
+// DefaultShardDuration is the time span covered by one search index shard
+const DefaultShardDuration = 24 * time.Hour
+
+// shardPrefix and shardTimeFormat name shard directories after the start of their time span
+const (
+	shardPrefix     = "shard-"
+	shardTimeFormat = "20060102T150405Z"
+)
+
+// SearchConfig contains search index configuration
+type SearchConfig struct {
+	IndexPath     string        // Directory holding one Bleve index per shard
+	ShardDuration time.Duration // Time span covered by each shard, defaults to DefaultShardDuration
+	EncryptionKey []byte        // Hash the terms of the free-text fields with this key and do not store them, see encryptMapping
+	IndexWorkers  int           // Goroutines analyzing entries for indexing, 0 keeps Bleve's default of 4
+}
+
+// indexShard is a Bleve index holding the log entries of one time span
+type indexShard struct {
+	name  string
+	start time.Time
+	end   time.Time
+	index bleve.Index
+}
+
+// ShardStats contains statistics about a single search index shard
+type ShardStats struct {
+	Name          string    `json:"name"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	DocumentCount uint64    `json:"document_count"`
+	SizeBytes     int64     `json:"size_bytes"`
+}
+
+// shardStart returns the start of the shard time span containing t
+func (s *SearchService) shardStart(t time.Time) time.Time {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Truncate(s.shardDuration)
+}
+
+// openShards opens all shards in the index directory, moving a pre-shard single index aside.
+// With encryption, shards holding plaintext terms are removed and the index is rebuilt.
+func (s *SearchService) openShards() error {
+	if _, err := os.Stat(filepath.Join(s.path, "index_meta.json")); err == nil {
+		legacyPath := s.path + ".legacy"
+		if err := os.Rename(s.path, legacyPath); err != nil {
+			return fmt.Errorf("failed to move unsharded index aside: %w", err)
+		}
+		log.Printf("Moved unsharded search index to %s", legacyPath)
+		s.needsRebuild = true
+	}
+
+	if err := os.MkdirAll(s.path, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read index directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), shardPrefix) {
+			continue
+		}
+
+		start, err := time.Parse(shardTimeFormat, strings.TrimPrefix(entry.Name(), shardPrefix))
+		if err != nil {
+			continue
+		}
+
+		index, err := bleve.Open(filepath.Join(s.path, entry.Name()))
+		if err != nil {
+			s.closeShards()
+			return fmt.Errorf("failed to open search index shard %s: %w", entry.Name(), err)
+		}
+
+		if s.encryption != nil && !isEncrypted(index) {
+			index.Close()
+			if err := os.RemoveAll(filepath.Join(s.path, entry.Name())); err != nil {
+				s.closeShards()
+				return fmt.Errorf("failed to remove unencrypted search index shard %s: %w", entry.Name(), err)
+			}
+			log.Printf("Removed unencrypted search index shard %s", entry.Name())
+			s.needsRebuild = true
+			continue
+		}
+
+		s.addShard(&indexShard{
+			name:  entry.Name(),
+			start: start,
+			end:   start.Add(s.shardDuration),
+			index: index,
+		})
+	}
+
+	return nil
+}
+
+// NeedsRebuild reports whether existing logs are missing from the index because an unsharded
+// index was moved aside or unencrypted shards were removed
+func (s *SearchService) NeedsRebuild() bool {
+	return s.needsRebuild
+}
+
+// addShard registers an open shard, keeping shards ordered oldest first
+func (s *SearchService) addShard(shard *indexShard) {
+	s.shards = append(s.shards, shard)
+	sort.Slice(s.shards, func(i, j int) bool {
+		return s.shards[i].start.Before(s.shards[j].start)
+	})
+	s.alias.Add(shard.index)
+}
+
+// shardFor returns the shard covering t, creating it when the time span has no shard yet.
+// The caller must hold the write lock.
+func (s *SearchService) shardFor(t time.Time) (*indexShard, error) {
+	start := s.shardStart(t)
+	for _, shard := range s.shards {
+		if shard.start.Equal(start) {
+			return shard, nil
+		}
+	}
+
+	name := shardPrefix + start.Format(shardTimeFormat)
+	indexMapping, err := buildIndexMapping(s.encryption)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search index shard %s: %w", name, err)
+	}
+	index, err := bleve.New(filepath.Join(s.path, name), indexMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search index shard %s: %w", name, err)
+	}
+
+	shard := &indexShard{
+		name:  name,
+		start: start,
+		end:   start.Add(s.shardDuration),
+		index: index,
+	}
+	s.addShard(shard)
+
+	return shard, nil
+}
+
+// RetireShards closes and removes shards whose whole time span ended before the cutoff
+// and returns how many were retired
+func (s *SearchService) RetireShards(before time.Time) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	retired := 0
+	kept := s.shards[:0]
+	var errs []string
+
+	for _, shard := range s.shards {
+		if shard.end.After(before) {
+			kept = append(kept, shard)
+			continue
+		}
+
+		s.alias.Remove(shard.index)
+		if err := shard.index.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", shard.name, err))
+		}
+		if err := os.RemoveAll(filepath.Join(s.path, shard.name)); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", shard.name, err))
+		}
+		retired++
+	}
+	s.shards = kept
+
+	if len(errs) > 0 {
+		return retired, fmt.Errorf("failed to retire search index shards: %s", strings.Join(errs, "; "))
+	}
+
+	return retired, nil
+}
+
+// ShardStats returns statistics for each shard, oldest first
+func (s *SearchService) ShardStats() ([]ShardStats, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stats := make([]ShardStats, 0, len(s.shards))
+	for _, shard := range s.shards {
+		docCount, err := shard.index.DocCount()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document count of shard %s: %w", shard.name, err)
+		}
+
+		stats = append(stats, ShardStats{
+			Name:          shard.name,
+			Start:         shard.start,
+			End:           shard.end,
+			DocumentCount: docCount,
+			SizeBytes:     dirSize(filepath.Join(s.path, shard.name)),
+		})
+	}
+
+	return stats, nil
+}
+
+// closeShards closes every open shard
+func (s *SearchService) closeShards() error {
+	var errs []string
+	for _, shard := range s.shards {
+		s.alias.Remove(shard.index)
+		if err := shard.index.Close(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", shard.name, err))
+		}
+	}
+	s.shards = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close search index shards: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// dirSize returns the total size of the files below a directory
+func dirSize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/blevesearch/bleve/v2/search"
 	"github.com/google/uuid"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
 )
@@ -126,6 +128,7 @@ func TestSearchService_SearchLogs(t *testing.T) {
 			ServiceName: "db-service",
 			AgentID:     "db-agent",
 			Platform:    models.PlatformSwift,
+			RequestID:   "req-7f3a-01",
 		},
 		{
 			ID:          uuid.New().String(),
@@ -144,7 +147,7 @@ func TestSearchService_SearchLogs(t *testing.T) {
 	}
 
 	// Test search by message content
-	logIDs, err := searchService.SearchLogs(ctx, "authentication", models.LogFilter{})
+	logIDs, _, err := searchService.SearchLogs(ctx, "authentication", models.LogFilter{})
 	if err != nil {
 		t.Fatalf("Failed to search logs: %v", err)
 	}
@@ -156,7 +159,7 @@ func TestSearchService_SearchLogs(t *testing.T) {
 	}
 
 	// Test search by partial message
-	logIDs, err = searchService.SearchLogs(ctx, "connection", models.LogFilter{})
+	logIDs, _, err = searchService.SearchLogs(ctx, "connection", models.LogFilter{})
 	if err != nil {
 		t.Fatalf("Failed to search logs: %v", err)
 	}
@@ -165,7 +168,7 @@ func TestSearchService_SearchLogs(t *testing.T) {
 	}
 
 	// Test search with service filter
-	logIDs, err = searchService.SearchLogs(ctx, "", models.LogFilter{
+	logIDs, _, err = searchService.SearchLogs(ctx, "", models.LogFilter{
 		ServiceName: "auth-service",
 	})
 	if err != nil {
@@ -176,7 +179,7 @@ func TestSearchService_SearchLogs(t *testing.T) {
 	}
 
 	// Test search with level filter
-	logIDs, err = searchService.SearchLogs(ctx, "", models.LogFilter{
+	logIDs, _, err = searchService.SearchLogs(ctx, "", models.LogFilter{
 		Level: models.LogLevelError,
 	})
 	if err != nil {
@@ -186,8 +189,30 @@ func TestSearchService_SearchLogs(t *testing.T) {
 		t.Errorf("Expected 1 result for ERROR level, got %d", len(logIDs))
 	}
 
+	// Test search with minimum level filter
+	logIDs, _, err = searchService.SearchLogs(ctx, "", models.LogFilter{
+		MinLevel: models.LogLevelWarn,
+	})
+	if err != nil {
+		t.Fatalf("Failed to search logs with minimum level filter: %v", err)
+	}
+	if len(logIDs) != 2 {
+		t.Errorf("Expected 2 results for WARN and above, got %d", len(logIDs))
+	}
+
+	// Test search with request ID filter
+	logIDs, _, err = searchService.SearchLogs(ctx, "", models.LogFilter{
+		RequestID: "req-7f3a-01",
+	})
+	if err != nil {
+		t.Fatalf("Failed to search logs with request ID filter: %v", err)
+	}
+	if len(logIDs) != 1 || logIDs[0] != logEntries[1].ID {
+		t.Errorf("Expected the entry of the request, got %v", logIDs)
+	}
+
 	// Test search with time range
-	logIDs, err = searchService.SearchLogs(ctx, "", models.LogFilter{
+	logIDs, _, err = searchService.SearchLogs(ctx, "", models.LogFilter{
 		StartTime: now.Add(30 * time.Second),
 		EndTime:   now.Add(90 * time.Second),
 	})
@@ -199,7 +224,7 @@ func TestSearchService_SearchLogs(t *testing.T) {
 	}
 
 	// Test search with pagination
-	logIDs, err = searchService.SearchLogs(ctx, "", models.LogFilter{
+	logIDs, _, err = searchService.SearchLogs(ctx, "", models.LogFilter{
 		Limit: 2,
 	})
 	if err != nil {
@@ -242,7 +267,7 @@ func TestSearchService_DeleteLogEntry(t *testing.T) {
 
 	// Verify entry exists
 	ctx := context.Background()
-	logIDs, err := searchService.SearchLogs(ctx, "deletion", models.LogFilter{})
+	logIDs, _, err := searchService.SearchLogs(ctx, "deletion", models.LogFilter{})
 	if err != nil {
 		t.Fatalf("Failed to search logs: %v", err)
 	}
@@ -256,7 +281,7 @@ func TestSearchService_DeleteLogEntry(t *testing.T) {
 	}
 
 	// Verify entry is deleted
-	logIDs, err = searchService.SearchLogs(ctx, "deletion", models.LogFilter{})
+	logIDs, _, err = searchService.SearchLogs(ctx, "deletion", models.LogFilter{})
 	if err != nil {
 		t.Fatalf("Failed to search logs after deletion: %v", err)
 	}
@@ -404,7 +429,7 @@ func TestSearchService_ReopenIndex(t *testing.T) {
 
 	// Verify the indexed entry is still there
 	ctx := context.Background()
-	logIDs, err := searchService.SearchLogs(ctx, "persistent", models.LogFilter{})
+	logIDs, _, err := searchService.SearchLogs(ctx, "persistent", models.LogFilter{})
 	if err != nil {
 		t.Fatalf("Failed to search logs after reopen: %v", err)
 	}
@@ -417,3 +442,402 @@ func TestSearchService_ReopenIndex(t *testing.T) {
 		t.Errorf("Expected log ID %s after reopen, got %s", logEntry.ID, logIDs[0])
 	}
 }
+
+func TestSearchService_ShardRollover(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "test_index")
+
+	searchService, err := NewSearchServiceWithConfig(SearchConfig{
+		IndexPath:     indexPath,
+		ShardDuration: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create search service: %v", err)
+	}
+	defer searchService.Close()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour).Add(time.Hour)
+	var logEntries []models.LogEntry
+	for day := 0; day < 3; day++ {
+		logEntries = append(logEntries, models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   today.AddDate(0, 0, -day),
+			Level:       models.LogLevelError,
+			Message:     "Rollover test message",
+			ServiceName: "test-service",
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+		})
+	}
+
+	if err := searchService.IndexLogEntries(logEntries); err != nil {
+		t.Fatalf("Failed to index log entries: %v", err)
+	}
+
+	shards, err := searchService.ShardStats()
+	if err != nil {
+		t.Fatalf("Failed to get shard stats: %v", err)
+	}
+	if len(shards) != 3 {
+		t.Fatalf("Expected 3 shards, got %d", len(shards))
+	}
+	for _, shard := range shards {
+		if shard.DocumentCount != 1 {
+			t.Errorf("Expected 1 document in shard %s, got %d", shard.Name, shard.DocumentCount)
+		}
+		if shard.SizeBytes <= 0 {
+			t.Errorf("Expected shard %s to have a size", shard.Name)
+		}
+	}
+
+	ctx := context.Background()
+	logIDs, _, err := searchService.SearchLogs(ctx, "rollover", models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to search logs: %v", err)
+	}
+	if len(logIDs) != 3 {
+		t.Errorf("Expected 3 results across shards, got %d", len(logIDs))
+	}
+
+	// Retire everything older than the start of yesterday
+	retired, err := searchService.RetireShards(today.Truncate(24*time.Hour).AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("Failed to retire shards: %v", err)
+	}
+	if retired != 1 {
+		t.Errorf("Expected 1 retired shard, got %d", retired)
+	}
+
+	logIDs, _, err = searchService.SearchLogs(ctx, "rollover", models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to search logs: %v", err)
+	}
+	if len(logIDs) != 2 {
+		t.Errorf("Expected 2 results after retirement, got %d", len(logIDs))
+	}
+
+	health := searchService.HealthCheck(ctx)
+	if health.Details["shard_count"] != "2" {
+		t.Errorf("Expected shard_count 2 in health details, got %s", health.Details["shard_count"])
+	}
+	if _, ok := health.Details["size_bytes"]; !ok {
+		t.Error("Expected size_bytes in health details")
+	}
+
+	// Reopening picks up the remaining shards
+	searchService.Close()
+	searchService, err = NewSearchService(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen search service: %v", err)
+	}
+	defer searchService.Close()
+
+	shards, err = searchService.ShardStats()
+	if err != nil {
+		t.Fatalf("Failed to get shard stats: %v", err)
+	}
+	if len(shards) != 2 {
+		t.Errorf("Expected 2 shards after reopen, got %d", len(shards))
+	}
+}
+
+func TestSQLiteStorage_SearchLogsHighlights(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storage, err := NewSQLiteStorageWithSearch(filepath.Join(tmpDir, "test.db"), filepath.Join(tmpDir, "search_index"))
+	if err != nil {
+		t.Fatalf("Failed to create storage with search: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	logs := []models.LogEntry{
+		{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelError,
+			Message:     "Payment request failed after gateway timeout",
+			ServiceName: "payment-service",
+			AgentID:     "payment-agent",
+			Platform:    models.PlatformGo,
+		},
+		{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Payment completed",
+			ServiceName: "payment-service",
+			AgentID:     "payment-agent",
+			Platform:    models.PlatformGo,
+		},
+	}
+
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.SearchLogs(ctx, "timeout", models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to search logs: %v", err)
+	}
+
+	if len(result.Hits) != 1 {
+		t.Fatalf("Expected 1 hit, got %d", len(result.Hits))
+	}
+
+	hit := result.Hits[0]
+	if hit.Log.ID != logs[0].ID {
+		t.Errorf("Expected hit %s, got %s", logs[0].ID, hit.Log.ID)
+	}
+
+	fragments := hit.Fragments["message"]
+	if len(fragments) == 0 || !strings.Contains(fragments[0], "<mark>timeout</mark>") {
+		t.Errorf("Expected highlighted message fragment, got %v", fragments)
+	}
+
+	if len(hit.Matches) != 1 {
+		t.Fatalf("Expected 1 match, got %v", hit.Matches)
+	}
+	match := hit.Matches[0]
+	if match.Field != "message" || hit.Log.Message[match.Start:match.End] != "timeout" {
+		t.Errorf("Expected match of timeout in message, got %+v", match)
+	}
+}
+
+func TestSQLiteStorage_SearchLogsFacets(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	storage, err := NewSQLiteStorageWithSearch(filepath.Join(tmpDir, "test.db"), filepath.Join(tmpDir, "search_index"))
+	if err != nil {
+		t.Fatalf("Failed to create storage with search: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	logs := []models.LogEntry{
+		{
+			ID:          uuid.New().String(),
+			Timestamp:   now,
+			Level:       models.LogLevelError,
+			Message:     "Gateway timeout while charging card",
+			ServiceName: "payment-service",
+			AgentID:     "payment-agent",
+			Platform:    models.PlatformGo,
+		},
+		{
+			ID:          uuid.New().String(),
+			Timestamp:   now,
+			Level:       models.LogLevelError,
+			Message:     "Upstream timeout loading cart",
+			ServiceName: "cart-service",
+			AgentID:     "cart-agent",
+			Platform:    models.PlatformExpress,
+		},
+		{
+			ID:          uuid.New().String(),
+			Timestamp:   now.Add(-48 * time.Hour),
+			Level:       models.LogLevelWarn,
+			Message:     "Retrying after timeout",
+			ServiceName: "cart-service",
+			AgentID:     "cart-agent",
+			Platform:    models.PlatformExpress,
+		},
+	}
+
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.SearchLogs(ctx, "timeout", models.LogFilter{
+		Limit:  1,
+		Facets: models.SearchFacetNames(),
+	})
+	if err != nil {
+		t.Fatalf("Failed to search logs: %v", err)
+	}
+
+	// Facets count every match, not just the returned page
+	expected := map[string]map[string]int{
+		models.FacetLevel:    {"ERROR": 2, "WARN": 1},
+		models.FacetService:  {"cart-service": 2, "payment-service": 1},
+		models.FacetPlatform: {"express": 2, "go": 1},
+		models.FacetDay: {
+			now.Format("2006-01-02"):                      2,
+			now.Add(-48 * time.Hour).Format("2006-01-02"): 1,
+		},
+	}
+	for name, expectedCounts := range expected {
+		counts := make(map[string]int)
+		for _, count := range result.Facets[name] {
+			counts[count.Value] = count.Count
+		}
+		for value, count := range expectedCounts {
+			if counts[value] != count {
+				t.Errorf("Expected %s facet %s=%d, got %v", name, value, count, result.Facets[name])
+			}
+		}
+	}
+
+	// Facets are only computed when requested
+	result, err = storage.SearchLogs(ctx, "timeout", models.LogFilter{})
+	if err != nil {
+		t.Fatalf("Failed to search logs: %v", err)
+	}
+	if len(result.Facets) != 0 {
+		t.Errorf("Expected no facets, got %v", result.Facets)
+	}
+}
+
+func TestSQLiteStorage_SearchLogsDisabled(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	if _, err := storage.SearchLogs(context.Background(), "timeout", models.LogFilter{}); err != ErrSearchDisabled {
+		t.Errorf("Expected ErrSearchDisabled, got %v", err)
+	}
+}
+
+func TestSearchService_FuzzyAndPrefix(t *testing.T) {
+	searchService, err := NewSearchService(filepath.Join(t.TempDir(), "test_index"))
+	if err != nil {
+		t.Fatalf("Failed to create search service: %v", err)
+	}
+	defer searchService.Close()
+
+	logEntry := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		Level:       models.LogLevelError,
+		Message:     "Database connection timeout",
+		ServiceName: "test-service",
+		AgentID:     "test-agent",
+		Platform:    models.PlatformGo,
+	}
+	if err := searchService.IndexLogEntry(logEntry); err != nil {
+		t.Fatalf("Failed to index log entry: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		filter   models.LogFilter
+		expected int
+	}{
+		{name: "typo without fuzziness", query: "timout", expected: 0},
+		{name: "typo with fuzziness", query: "timout", filter: models.LogFilter{Fuzziness: 1}, expected: 1},
+		{name: "fuzziness above maximum is clamped", query: "timout", filter: models.LogFilter{Fuzziness: 5}, expected: 1},
+		{name: "partial word without prefix", query: "conn", expected: 0},
+		{name: "partial word with prefix", query: "Conn", filter: models.LogFilter{Prefix: true}, expected: 1},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logIDs, _, err := searchService.SearchLogs(ctx, tt.query, tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to search logs: %v", err)
+			}
+			if len(logIDs) != tt.expected {
+				t.Errorf("Expected %d results, got %d", tt.expected, len(logIDs))
+			}
+		})
+	}
+}
+
+func TestSearchService_RelevanceSort(t *testing.T) {
+	searchService, err := NewSearchService(filepath.Join(t.TempDir(), "test_index"))
+	if err != nil {
+		t.Fatalf("Failed to create search service: %v", err)
+	}
+	defer searchService.Close()
+
+	now := time.Now()
+	strongMatch := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   now.Add(-48 * time.Hour),
+		Level:       models.LogLevelError,
+		Message:     "Payment timeout",
+		ServiceName: "payment-service",
+		AgentID:     "payment-agent",
+		Platform:    models.PlatformGo,
+	}
+	weakMatch := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   now,
+		Level:       models.LogLevelWarn,
+		Message:     "Cache warmed up again after a long idle timeout",
+		ServiceName: "cache-service",
+		AgentID:     "cache-agent",
+		Platform:    models.PlatformGo,
+	}
+	for _, entry := range []models.LogEntry{strongMatch, weakMatch} {
+		if err := searchService.IndexLogEntry(entry); err != nil {
+			t.Fatalf("Failed to index log entry: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.LogFilter
+		expected []string
+	}{
+		{name: "time sort is newest first", filter: models.LogFilter{}, expected: []string{weakMatch.ID, strongMatch.ID}},
+		{name: "explicit time sort", filter: models.LogFilter{Sort: models.SearchSortTime}, expected: []string{weakMatch.ID, strongMatch.ID}},
+		{name: "relevance sort prefers the better match", filter: models.LogFilter{Sort: models.SearchSortRelevance}, expected: []string{strongMatch.ID, weakMatch.ID}},
+		{name: "relevance sort paginates after ranking", filter: models.LogFilter{Sort: models.SearchSortRelevance, Limit: 1, Offset: 1}, expected: []string{weakMatch.ID}},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logIDs, _, err := searchService.SearchLogs(ctx, "payment timeout", tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to search logs: %v", err)
+			}
+			if strings.Join(logIDs, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, logIDs)
+			}
+		})
+	}
+}
+
+func TestRankByRecency(t *testing.T) {
+	now := time.Now()
+	hit := func(id string, score float64, age time.Duration) *search.DocumentMatch {
+		return &search.DocumentMatch{
+			ID:     id,
+			Score:  score,
+			Fields: map[string]interface{}{"timestamp": now.Add(-age).Format(time.RFC3339)},
+		}
+	}
+
+	hits := search.DocumentMatchCollection{
+		hit("old", 1, 30*24*time.Hour),
+		hit("new", 1, 0),
+		hit("day-old", 1, 24*time.Hour),
+		{ID: "no-timestamp", Score: 1.2},
+	}
+
+	ranked := rankByRecency(hits, 0, 10, now)
+
+	var ids []string
+	for _, h := range ranked {
+		ids = append(ids, h.ID)
+	}
+	if strings.Join(ids, ",") != "new,day-old,no-timestamp,old" {
+		t.Errorf("Unexpected ranking: %v", ids)
+	}
+	if ranked[0].Score < 1.99 || ranked[0].Score > 2 {
+		t.Errorf("Expected a new entry to score about twice its relevance, got %f", ranked[0].Score)
+	}
+
+	if page := rankByRecency(hits, 3, 10, now); len(page) != 1 {
+		t.Errorf("Expected 1 hit on the last page, got %d", len(page))
+	}
+	if page := rankByRecency(hits, 10, 10, now); len(page) != 0 {
+		t.Errorf("Expected no hits past the end, got %d", len(page))
+	}
+}
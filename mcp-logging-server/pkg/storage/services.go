@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// filterServices applies a service filter to all services of a storage: it keeps the matching
+// services, counts them per platform, orders them and returns the requested page
+func filterServices(services []models.ServiceInfo, filter models.ServiceFilter) *models.ServiceResult {
+	result := &models.ServiceResult{
+		Services:  make([]models.ServiceInfo, 0),
+		Platforms: make(map[models.Platform]models.PlatformCount),
+	}
+
+	matching := make([]models.ServiceInfo, 0, len(services))
+	for _, service := range services {
+		if filter.NamePrefix != "" && !strings.HasPrefix(service.ServiceName, filter.NamePrefix) {
+			continue
+		}
+		if filter.Platform != "" && service.Platform != filter.Platform {
+			continue
+		}
+		if !filter.ActiveSince.IsZero() && service.LastSeen.Before(filter.ActiveSince) {
+			continue
+		}
+		service.Stale = !filter.StaleBefore.IsZero() && service.LastSeen.Before(filter.StaleBefore)
+		matching = append(matching, service)
+
+		count := result.Platforms[service.Platform]
+		count.Services++
+		count.Logs += service.LogCount
+		result.Platforms[service.Platform] = count
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		a, b := matching[i], matching[j]
+		switch filter.Sort {
+		case models.ServiceSortName:
+			if a.ServiceName != b.ServiceName {
+				return a.ServiceName < b.ServiceName
+			}
+			return a.AgentID < b.AgentID
+		case models.ServiceSortLogCount:
+			if a.LogCount != b.LogCount {
+				return a.LogCount > b.LogCount
+			}
+		}
+		return a.LastSeen.After(b.LastSeen)
+	})
+
+	result.TotalCount = len(matching)
+	start := min(max(filter.Offset, 0), len(matching))
+	end := len(matching)
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, end)
+	}
+	result.Services = append(result.Services, matching[start:end]...)
+	result.HasMore = end < len(matching)
+	return result
+}
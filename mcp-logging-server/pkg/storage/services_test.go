@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func newServiceTestLog(agentID string, timestamp time.Time) models.LogEntry {
+	return models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   timestamp,
+		Level:       models.LogLevelInfo,
+		Message:     "heartbeat",
+		ServiceName: "checkout",
+		AgentID:     agentID,
+		Platform:    models.PlatformGo,
+	}
+}
+
+func TestServicePruners(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "services.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]interface {
+		LogStorage
+		ServicePruner
+	}{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now().UTC()
+
+			err := store.Store(ctx, []models.LogEntry{
+				newServiceTestLog("checkout-1", now.Add(-time.Hour)),
+				newServiceTestLog("checkout-2", now.Add(-10*24*time.Hour)),
+				newServiceTestLog("checkout-2", now.Add(-11*24*time.Hour)),
+				newServiceTestLog("checkout-3", now.Add(-40*24*time.Hour)),
+			})
+			if err != nil {
+				t.Fatalf("Failed to store logs: %v", err)
+			}
+
+			agents := func(filter models.ServiceFilter) string {
+				t.Helper()
+				result, err := store.GetServices(ctx, filter)
+				if err != nil {
+					t.Fatalf("Failed to get services: %v", err)
+				}
+				var agents []string
+				for _, service := range result.Services {
+					agent := service.AgentID
+					if service.Stale {
+						agent += " (stale)"
+					}
+					agents = append(agents, agent)
+				}
+				return strings.Join(agents, ",")
+			}
+
+			staleBefore := now.Add(-7 * 24 * time.Hour)
+			if got := agents(models.ServiceFilter{StaleBefore: staleBefore}); got != "checkout-1,checkout-2 (stale),checkout-3 (stale)" {
+				t.Errorf("Unexpected services %s", got)
+			}
+
+			purged, err := store.PurgeServices(ctx, now.Add(-30*24*time.Hour))
+			if err != nil {
+				t.Fatalf("Failed to purge services: %v", err)
+			}
+			if len(purged) != 1 || purged[0].AgentID != "checkout-3" {
+				t.Errorf("Expected checkout-3 to be purged, got %+v", purged)
+			}
+			if got := agents(models.ServiceFilter{}); got != "checkout-1,checkout-2" {
+				t.Errorf("Expected the purged agent to be left out, got %s", got)
+			}
+
+			// Purged agents are not purged again, the entries are kept
+			purged, err = store.PurgeServices(ctx, now.Add(-30*24*time.Hour))
+			if err != nil || len(purged) != 0 {
+				t.Errorf("Expected nothing left to purge, got %+v (%v)", purged, err)
+			}
+			logs, err := store.Query(ctx, models.LogFilter{AgentID: "checkout-3"})
+			if err != nil || logs.TotalCount != 1 {
+				t.Errorf("Expected the entries of the purged agent to be kept, got %+v (%v)", logs, err)
+			}
+
+			// An agent that logs again is listed again
+			if err := store.Store(ctx, []models.LogEntry{newServiceTestLog("checkout-3", now)}); err != nil {
+				t.Fatalf("Failed to store log: %v", err)
+			}
+			result, err := store.GetServices(ctx, models.ServiceFilter{Sort: models.ServiceSortName})
+			if err != nil {
+				t.Fatalf("Failed to get services: %v", err)
+			}
+			if len(result.Services) != 3 || result.Services[2].AgentID != "checkout-3" || result.Services[2].LogCount != 2 {
+				t.Errorf("Expected checkout-3 to be listed with both entries, got %+v", result.Services)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_SnapshotPurgedServices(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	ctx := context.Background()
+
+	storage, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	if err := storage.Store(ctx, []models.LogEntry{newServiceTestLog("checkout-1", time.Now().UTC().Add(-time.Hour))}); err != nil {
+		t.Fatalf("Failed to store log: %v", err)
+	}
+	if _, err := storage.PurgeServices(ctx, time.Now().UTC()); err != nil {
+		t.Fatalf("Failed to purge services: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close storage: %v", err)
+	}
+
+	restored, err := NewMemoryStorageWithConfig(MemoryConfig{SnapshotPath: path})
+	if err != nil {
+		t.Fatalf("Failed to restore storage: %v", err)
+	}
+	defer restored.Close()
+
+	result, _ := restored.GetServices(ctx, models.ServiceFilter{})
+	if result == nil || len(result.Services) != 0 {
+		t.Errorf("Expected the purged agent to stay purged, got %+v", result)
+	}
+}
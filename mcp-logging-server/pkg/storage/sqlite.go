@@ -5,17 +5,31 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
+	"github.com/kerlexov/mcp-logging-server/pkg/codec"
 	"github.com/kerlexov/mcp-logging-server/pkg/models"
-	_ "github.com/mattn/go-sqlite3"
 )
 
 // SQLiteStorage implements LogStorage using SQLite
 type SQLiteStorage struct {
-	db     *sql.DB
-	search *SearchService
+	db                 *sql.DB
+	path               string // Database file, empty for in-memory databases
+	search             *SearchService
+	slowQueryThreshold time.Duration
+	integrityHashing   bool
+	integrityKey       []byte
+	immutableWindow    time.Duration
+
+	replica       *sql.DB // Serves queries when set, see SetReadReplica
+	maxReplicaLag time.Duration
+
+	compressedColumns  map[string]bool // See SetCompression
+	compressionMinSize int
+
+	encryption *encryptionKey // Encrypts the free-text columns when set, see SetEncryption
 }
 
 // NewSQLiteStorage creates a new SQLite storage instance
@@ -25,11 +39,21 @@ func NewSQLiteStorage(connectionString string) (*SQLiteStorage, error) {
 
 // NewSQLiteStorageWithSearch creates a new SQLite storage instance with search capabilities
 func NewSQLiteStorageWithSearch(connectionString, searchIndexPath string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite3", connectionString)
+	return NewSQLiteStorageWithSearchConfig(connectionString, SearchConfig{IndexPath: searchIndexPath})
+}
+
+// NewSQLiteStorageWithSearchConfig creates a new SQLite storage instance, enabling search when an index path is configured
+func NewSQLiteStorageWithSearchConfig(connectionString string, searchConfig SearchConfig) (*SQLiteStorage, error) {
+	db, err := sql.Open(sqliteDriver, sqliteDSN(connectionString))
 	if err != nil {
 		return nil, err
 	}
 
+	// Every connection to an in-memory database opens its own empty database
+	if isInMemory(connectionString) {
+		db.SetMaxOpenConns(1)
+	}
+
 	// Enable foreign keys and WAL mode for better performance
 	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
 		db.Close()
@@ -41,7 +65,7 @@ func NewSQLiteStorageWithSearch(connectionString, searchIndexPath string) (*SQLi
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	storage := &SQLiteStorage{db: db}
+	storage := &SQLiteStorage{db: db, path: databasePath(connectionString)}
 
 	// Initialize database schema
 	if err := storage.migrate(); err != nil {
@@ -50,18 +74,32 @@ func NewSQLiteStorageWithSearch(connectionString, searchIndexPath string) (*SQLi
 	}
 
 	// Initialize search service if path is provided
-	if searchIndexPath != "" {
-		searchService, err := NewSearchService(searchIndexPath)
+	if searchConfig.IndexPath != "" {
+		searchService, err := NewSearchServiceWithConfig(searchConfig)
 		if err != nil {
 			db.Close()
 			return nil, fmt.Errorf("failed to initialize search service: %w", err)
 		}
 		storage.search = searchService
+
+		if searchService.NeedsRebuild() {
+			indexed, err := storage.RebuildSearchIndex(context.Background(), 0)
+			if err != nil {
+				storage.Close()
+				return nil, fmt.Errorf("failed to rebuild search index: %w", err)
+			}
+			log.Printf("Rebuilt search index with %d logs", indexed)
+		}
 	}
 
 	return storage, nil
 }
 
+// isInMemory reports whether a connection string refers to an in-memory database
+func isInMemory(connectionString string) bool {
+	return strings.HasPrefix(connectionString, ":memory:") || strings.Contains(connectionString, "mode=memory")
+}
+
 // migrate runs database migrations
 func (s *SQLiteStorage) migrate() error {
 	// Create migrations table if it doesn't exist
@@ -106,6 +144,315 @@ func (s *SQLiteStorage) migrate() error {
 			CREATE INDEX IF NOT EXISTS idx_log_entries_service_agent ON log_entries(service_name, agent_id);
 			`,
 		},
+		{
+			version: 2,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN received_at DATETIME;
+			ALTER TABLE log_entries ADD COLUMN clock_skewed INTEGER NOT NULL DEFAULT 0;
+
+			CREATE INDEX IF NOT EXISTS idx_log_entries_received_at ON log_entries(received_at);
+			`,
+		},
+		{
+			// Drop the platform CHECK constraint, allowed platforms are enforced by validation.
+			// SQLite cannot drop constraints in place, so the table is rebuilt.
+			version: 3,
+			sql: `
+			CREATE TABLE log_entries_new (
+				id TEXT PRIMARY KEY,
+				timestamp DATETIME NOT NULL,
+				level TEXT NOT NULL CHECK (level IN ('DEBUG', 'INFO', 'WARN', 'ERROR', 'FATAL')),
+				message TEXT NOT NULL,
+				service_name TEXT NOT NULL,
+				agent_id TEXT NOT NULL,
+				platform TEXT NOT NULL,
+				metadata TEXT, -- JSON
+				device_info TEXT, -- JSON
+				stack_trace TEXT,
+				source_location TEXT, -- JSON
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				received_at DATETIME,
+				clock_skewed INTEGER NOT NULL DEFAULT 0
+			);
+
+			INSERT INTO log_entries_new (
+				id, timestamp, level, message, service_name, agent_id, platform,
+				metadata, device_info, stack_trace, source_location, created_at,
+				received_at, clock_skewed
+			)
+			SELECT
+				id, timestamp, level, message, service_name, agent_id, platform,
+				metadata, device_info, stack_trace, source_location, created_at,
+				received_at, clock_skewed
+			FROM log_entries;
+
+			DROP TABLE log_entries;
+			ALTER TABLE log_entries_new RENAME TO log_entries;
+
+			CREATE INDEX IF NOT EXISTS idx_log_entries_timestamp ON log_entries(timestamp);
+			CREATE INDEX IF NOT EXISTS idx_log_entries_level ON log_entries(level);
+			CREATE INDEX IF NOT EXISTS idx_log_entries_service_name ON log_entries(service_name);
+			CREATE INDEX IF NOT EXISTS idx_log_entries_agent_id ON log_entries(agent_id);
+			CREATE INDEX IF NOT EXISTS idx_log_entries_platform ON log_entries(platform);
+			CREATE INDEX IF NOT EXISTS idx_log_entries_service_agent ON log_entries(service_name, agent_id);
+			CREATE INDEX IF NOT EXISTS idx_log_entries_received_at ON log_entries(received_at);
+			`,
+		},
+		{
+			version: 4,
+			sql: `
+			CREATE TABLE IF NOT EXISTS services (
+				service_name TEXT PRIMARY KEY,
+				owner_team TEXT NOT NULL,
+				repo_url TEXT,
+				runbook_url TEXT,
+				environment TEXT,
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL
+			);
+			`,
+		},
+		{
+			version: 5,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN tags TEXT; -- JSON
+
+			CREATE TABLE IF NOT EXISTS log_entry_tags (
+				log_id TEXT NOT NULL,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (log_id, tag)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_log_entry_tags_tag ON log_entry_tags(tag, log_id);
+			`,
+		},
+		{
+			version: 6,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN content_hash TEXT;
+			`,
+		},
+		{
+			version: 7,
+			sql: `
+			CREATE TABLE IF NOT EXISTS legal_holds (
+				id TEXT PRIMARY KEY,
+				service_name TEXT,
+				start_time DATETIME,
+				end_time DATETIME,
+				reason TEXT NOT NULL,
+				created_at DATETIME NOT NULL
+			);
+			`,
+		},
+		{
+			version: 8,
+			sql: `
+			CREATE TABLE IF NOT EXISTS sync_states (
+				agent_id TEXT PRIMARY KEY,
+				last_sequence INTEGER NOT NULL,
+				updated_at DATETIME NOT NULL
+			);
+			`,
+		},
+		{
+			version: 9,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN crash TEXT; -- JSON
+			ALTER TABLE log_entries ADD COLUMN crash_signature TEXT;
+
+			CREATE INDEX IF NOT EXISTS idx_crash_signature ON log_entries(crash_signature, timestamp) WHERE crash_signature IS NOT NULL;
+			`,
+		},
+		{
+			version: 10,
+			sql: `
+			CREATE TABLE IF NOT EXISTS symbol_files (
+				service_name TEXT NOT NULL,
+				version TEXT NOT NULL,
+				name TEXT NOT NULL,
+				kind TEXT NOT NULL,
+				size INTEGER NOT NULL,
+				content BLOB NOT NULL,
+				uploaded_at DATETIME NOT NULL,
+				PRIMARY KEY (service_name, version, name)
+			);
+			`,
+		},
+		{
+			version: 11,
+			sql: `
+			CREATE TABLE IF NOT EXISTS ingestion_usage (
+				day TEXT NOT NULL, -- UTC, YYYY-MM-DD
+				api_key_id TEXT NOT NULL,
+				service_name TEXT NOT NULL,
+				entries INTEGER NOT NULL,
+				bytes INTEGER NOT NULL,
+				PRIMARY KEY (day, api_key_id, service_name)
+			);
+			`,
+		},
+		{
+			version: 12,
+			sql: `
+			CREATE TABLE IF NOT EXISTS log_summaries (
+				hour DATETIME NOT NULL, -- UTC
+				service_name TEXT NOT NULL,
+				level TEXT NOT NULL,
+				template TEXT NOT NULL,
+				count INTEGER NOT NULL,
+				first_seen DATETIME NOT NULL,
+				last_seen DATETIME NOT NULL,
+				PRIMARY KEY (hour, service_name, level, template)
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_log_summaries_service_hour ON log_summaries(service_name, hour);
+			`,
+		},
+		{
+			version: 13,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN annotations TEXT; -- JSON
+			`,
+		},
+		{
+			version: 14,
+			sql: `
+			CREATE TABLE IF NOT EXISTS incidents (
+				id TEXT PRIMARY KEY,
+				title TEXT NOT NULL,
+				description TEXT,
+				filter TEXT, -- JSON
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL
+			);
+
+			CREATE TABLE IF NOT EXISTS incident_logs (
+				incident_id TEXT NOT NULL,
+				log_id TEXT NOT NULL,
+				attached_at DATETIME NOT NULL,
+				PRIMARY KEY (incident_id, log_id)
+			);
+			`,
+		},
+		{
+			version: 15,
+			sql: `
+			CREATE TABLE IF NOT EXISTS service_stats (
+				service_name TEXT NOT NULL,
+				agent_id TEXT NOT NULL,
+				platform TEXT NOT NULL,
+				level TEXT NOT NULL,
+				log_count INTEGER NOT NULL,
+				last_seen DATETIME NOT NULL,
+				PRIMARY KEY (service_name, agent_id, platform, level)
+			);
+
+			INSERT OR REPLACE INTO service_stats (service_name, agent_id, platform, level, log_count, last_seen)
+			SELECT service_name, agent_id, platform, level, COUNT(*), MAX(timestamp)
+			FROM log_entries
+			GROUP BY service_name, agent_id, platform, level;
+
+			CREATE TRIGGER IF NOT EXISTS service_stats_insert AFTER INSERT ON log_entries
+			BEGIN
+				INSERT INTO service_stats (service_name, agent_id, platform, level, log_count, last_seen)
+				VALUES (NEW.service_name, NEW.agent_id, NEW.platform, NEW.level, 1, NEW.timestamp)
+				ON CONFLICT (service_name, agent_id, platform, level) DO UPDATE SET
+					log_count = log_count + 1,
+					last_seen = MAX(last_seen, excluded.last_seen);
+			END;
+
+			-- Deleting the newest entry of a group looks up the next newest
+			CREATE TRIGGER IF NOT EXISTS service_stats_delete AFTER DELETE ON log_entries
+			BEGIN
+				UPDATE service_stats SET
+					log_count = log_count - 1,
+					last_seen = CASE WHEN last_seen = OLD.timestamp THEN COALESCE((
+						SELECT MAX(timestamp) FROM log_entries
+						WHERE service_name = OLD.service_name AND agent_id = OLD.agent_id
+						AND platform = OLD.platform AND level = OLD.level
+					), last_seen) ELSE last_seen END
+				WHERE service_name = OLD.service_name AND agent_id = OLD.agent_id
+				AND platform = OLD.platform AND level = OLD.level;
+
+				DELETE FROM service_stats
+				WHERE service_name = OLD.service_name AND agent_id = OLD.agent_id
+				AND platform = OLD.platform AND level = OLD.level AND log_count <= 0;
+			END;
+			`,
+		},
+		{
+			version: 16,
+			sql: `
+			-- Agents purged from the services, hidden until they log after last_seen
+			CREATE TABLE IF NOT EXISTS purged_services (
+				service_name TEXT NOT NULL,
+				agent_id TEXT NOT NULL,
+				platform TEXT NOT NULL,
+				last_seen DATETIME NOT NULL,
+				purged_at DATETIME NOT NULL,
+				PRIMARY KEY (service_name, agent_id, platform)
+			);
+			`,
+		},
+		{
+			// Entries stored before keep their request ID in the metadata
+			version: 17,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN request_id TEXT;
+
+			CREATE INDEX IF NOT EXISTS idx_log_entries_request_id ON log_entries(request_id) WHERE request_id IS NOT NULL;
+			`,
+		},
+		{
+			version: 18,
+			sql: `
+			CREATE TABLE IF NOT EXISTS query_audit (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				time DATETIME NOT NULL,
+				interface TEXT NOT NULL,
+				actor TEXT NOT NULL,
+				source TEXT NOT NULL,
+				operation TEXT NOT NULL,
+				filter TEXT, -- JSON
+				result_count INTEGER NOT NULL,
+				masked_fields TEXT, -- JSON
+				latency_ms INTEGER NOT NULL,
+				error TEXT NOT NULL,
+				request_id TEXT NOT NULL
+			);
+
+			CREATE INDEX IF NOT EXISTS idx_query_audit_time ON query_audit(time);
+			CREATE INDEX IF NOT EXISTS idx_query_audit_actor_time ON query_audit(actor, time);
+			`,
+		},
+		{
+			// Entries stored before have no template ID, grouping computes it from their message
+			version: 19,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN template_id TEXT;
+
+			CREATE INDEX IF NOT EXISTS idx_log_entries_template_id ON log_entries(template_id) WHERE template_id IS NOT NULL;
+			`,
+		},
+		{
+			// Severities must match models.LogLevel.Severity
+			version: 20,
+			sql: `
+			ALTER TABLE log_entries ADD COLUMN severity INTEGER NOT NULL DEFAULT 0;
+
+			UPDATE log_entries SET severity = CASE level
+				WHEN 'DEBUG' THEN 1
+				WHEN 'INFO' THEN 2
+				WHEN 'WARN' THEN 3
+				WHEN 'ERROR' THEN 4
+				WHEN 'FATAL' THEN 5
+				ELSE 0
+			END;
+
+			CREATE INDEX IF NOT EXISTS idx_log_entries_severity_timestamp ON log_entries(severity, timestamp);
+			`,
+		},
 	}
 
 	// Apply migrations
@@ -132,6 +479,15 @@ func (s *SQLiteStorage) migrate() error {
 	return nil
 }
 
+// SchemaVersion returns the version of the latest applied migration
+func (s *SQLiteStorage) SchemaVersion(ctx context.Context) (int, error) {
+	var version sql.NullInt64
+	if err := s.db.QueryRowContext(ctx, "SELECT MAX(version) FROM migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
 // Store stores a batch of log entries
 func (s *SQLiteStorage) Store(ctx context.Context, logs []models.LogEntry) error {
 	if len(logs) == 0 {
@@ -147,14 +503,22 @@ func (s *SQLiteStorage) Store(ctx context.Context, logs []models.LogEntry) error
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO log_entries (
 			id, timestamp, level, message, service_name, agent_id, platform,
-			metadata, device_info, stack_trace, source_location
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			metadata, device_info, stack_trace, source_location,
+			received_at, clock_skewed, tags, content_hash, crash, crash_signature, request_id, template_id,
+			severity
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
+	tagStmt, err := tx.PrepareContext(ctx, "INSERT OR IGNORE INTO log_entry_tags (log_id, tag) VALUES (?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare tag statement: %w", err)
+	}
+	defer tagStmt.Close()
+
 	for _, log := range logs {
 		// Validate log entry
 		if err := log.Validate(); err != nil {
@@ -165,53 +529,154 @@ func (s *SQLiteStorage) Store(ctx context.Context, logs []models.LogEntry) error
 		var metadataJSON, deviceInfoJSON, sourceLocationJSON *string
 
 		if log.Metadata != nil {
-			if data, err := json.Marshal(log.Metadata); err != nil {
+			if metadataStr, err := codec.MarshalString(log.Metadata); err != nil {
 				return fmt.Errorf("failed to marshal metadata for log %s: %w", log.ID, err)
 			} else {
-				metadataStr := string(data)
 				metadataJSON = &metadataStr
 			}
 		}
 
 		if log.DeviceInfo != nil {
-			if data, err := json.Marshal(log.DeviceInfo); err != nil {
+			if deviceInfoStr, err := codec.MarshalString(log.DeviceInfo); err != nil {
 				return fmt.Errorf("failed to marshal device info for log %s: %w", log.ID, err)
 			} else {
-				deviceInfoStr := string(data)
 				deviceInfoJSON = &deviceInfoStr
 			}
 		}
 
 		if log.SourceLocation != nil {
-			if data, err := json.Marshal(log.SourceLocation); err != nil {
+			if sourceLocationStr, err := codec.MarshalString(log.SourceLocation); err != nil {
 				return fmt.Errorf("failed to marshal source location for log %s: %w", log.ID, err)
 			} else {
-				sourceLocationStr := string(data)
 				sourceLocationJSON = &sourceLocationStr
 			}
 		}
 
+		var tagsJSON *string
+		if len(log.Tags) > 0 {
+			if tagsStr, err := codec.MarshalString(log.Tags); err != nil {
+				return fmt.Errorf("failed to marshal tags for log %s: %w", log.ID, err)
+			} else {
+				tagsJSON = &tagsStr
+			}
+		}
+
+		var crashJSON, crashSignature *string
+		if log.Crash != nil {
+			if crashStr, err := codec.MarshalString(log.Crash); err != nil {
+				return fmt.Errorf("failed to marshal crash for log %s: %w", log.ID, err)
+			} else {
+				crashJSON = &crashStr
+			}
+			if log.Crash.Signature != "" {
+				crashSignature = &log.Crash.Signature
+			}
+		}
+
 		var stackTrace *string
 		if log.StackTrace != "" {
 			stackTrace = &log.StackTrace
 		}
 
-		_, err := stmt.ExecContext(ctx,
+		var receivedAt *time.Time
+		if !log.ReceivedAt.IsZero() {
+			receivedAt = &log.ReceivedAt
+		}
+
+		var requestID *string
+		if log.RequestID != "" {
+			requestID = &log.RequestID
+		}
+
+		var templateID *string
+		if log.TemplateID != "" {
+			templateID = &log.TemplateID
+		}
+
+		var contentHash *string
+		if s.integrityHashing {
+			hash := s.contentHash(integrityRecord{
+				ID:             log.ID,
+				Timestamp:      log.Timestamp,
+				Level:          string(log.Level),
+				Message:        log.Message,
+				ServiceName:    log.ServiceName,
+				AgentID:        log.AgentID,
+				Platform:       string(log.Platform),
+				Metadata:       nullStringPtr(metadataJSON),
+				DeviceInfo:     nullStringPtr(deviceInfoJSON),
+				StackTrace:     nullStringPtr(stackTrace),
+				SourceLocation: nullStringPtr(sourceLocationJSON),
+				ReceivedAt:     sql.NullTime{Time: log.ReceivedAt, Valid: receivedAt != nil},
+				ClockSkewed:    log.ClockSkewed,
+				Tags:           nullStringPtr(tagsJSON),
+				Crash:          nullStringPtr(crashJSON),
+				RequestID:      nullStringPtr(requestID),
+			})
+			contentHash = &hash
+		}
+
+		// Hashes cover the uncompressed values, so they do not depend on the compression settings
+		message, err := s.compressColumn(CompressMessage, &log.Message)
+		if err != nil {
+			return err
+		}
+		storedMetadata, err := s.compressColumn(CompressMetadata, metadataJSON)
+		if err != nil {
+			return err
+		}
+		storedStackTrace, err := s.compressColumn(CompressStackTrace, stackTrace)
+		if err != nil {
+			return err
+		}
+		storedDeviceInfo, err := s.encryptColumn(deviceInfoJSON)
+		if err != nil {
+			return err
+		}
+		storedSourceLocation, err := s.encryptColumn(sourceLocationJSON)
+		if err != nil {
+			return err
+		}
+		storedCrash, err := s.encryptColumn(crashJSON)
+		if err != nil {
+			return err
+		}
+		storedRequestID, err := s.encryptComparable(requestID)
+		if err != nil {
+			return err
+		}
+
+		_, err = stmt.ExecContext(ctx,
 			log.ID,
 			log.Timestamp,
 			string(log.Level),
-			log.Message,
+			message,
 			log.ServiceName,
 			log.AgentID,
 			string(log.Platform),
-			metadataJSON,
-			deviceInfoJSON,
-			stackTrace,
-			sourceLocationJSON,
+			storedMetadata,
+			storedDeviceInfo,
+			storedStackTrace,
+			storedSourceLocation,
+			receivedAt,
+			log.ClockSkewed,
+			tagsJSON,
+			contentHash,
+			storedCrash,
+			crashSignature,
+			storedRequestID,
+			templateID,
+			log.Level.Severity(),
 		)
 		if err != nil {
 			return fmt.Errorf("failed to insert log entry %s: %w", log.ID, err)
 		}
+
+		for _, tag := range log.Tags {
+			if _, err := tagStmt.ExecContext(ctx, log.ID, tag); err != nil {
+				return fmt.Errorf("failed to insert tag for log entry %s: %w", log.ID, err)
+			}
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -231,6 +696,11 @@ func (s *SQLiteStorage) Store(ctx context.Context, logs []models.LogEntry) error
 
 // Query retrieves logs based on filter criteria
 func (s *SQLiteStorage) Query(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
+	start := time.Now()
+	defer func() {
+		s.logSlowQuery(filter, time.Since(start))
+	}()
+
 	// If search service is available and message contains filter is used, use full-text search
 	if s.search != nil && filter.MessageContains != "" {
 		return s.queryWithSearch(ctx, filter)
@@ -242,7 +712,7 @@ func (s *SQLiteStorage) Query(ctx context.Context, filter models.LogFilter) (*mo
 // queryWithSearch performs a search using the Bleve index and then retrieves full records from SQL
 func (s *SQLiteStorage) queryWithSearch(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
 	// Perform search to get log IDs
-	logIDs, err := s.search.SearchLogs(ctx, filter.MessageContains, filter)
+	logIDs, facets, err := s.search.SearchLogs(ctx, filter.MessageContains, filter)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
@@ -252,6 +722,7 @@ func (s *SQLiteStorage) queryWithSearch(ctx context.Context, filter models.LogFi
 			Logs:       []models.LogEntry{},
 			TotalCount: 0,
 			HasMore:    false,
+			Facets:     facets,
 		}, nil
 	}
 
@@ -292,169 +763,352 @@ func (s *SQLiteStorage) queryWithSearch(ctx context.Context, filter models.LogFi
 		Logs:       paginatedLogs,
 		TotalCount: totalCount,
 		HasMore:    hasMore,
+		Facets:     facets,
 	}, nil
 }
 
-// applyAdditionalFiltering applies filters that weren't handled by the search
-func (s *SQLiteStorage) applyAdditionalFiltering(logs []models.LogEntry, filter models.LogFilter) []models.LogEntry {
-	var filtered []models.LogEntry
-
-	for _, log := range logs {
-		// Additional time range filtering (search might be less precise)
-		if !filter.StartTime.IsZero() && log.Timestamp.Before(filter.StartTime) {
-			continue
-		}
-		if !filter.EndTime.IsZero() && log.Timestamp.After(filter.EndTime) {
-			continue
-		}
-
-		filtered = append(filtered, log)
-	}
-
-	return filtered
+// SearchEnabled reports whether full-text search is enabled, SearchLogs fails with
+// ErrSearchDisabled otherwise
+func (s *SQLiteStorage) SearchEnabled() bool {
+	return s.search != nil
 }
 
-// queryWithSQL performs a traditional SQL-based query
-func (s *SQLiteStorage) queryWithSQL(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
-	// Build WHERE clause and args
-	var conditions []string
-	var args []interface{}
-	argIndex := 0
-
-	if filter.ServiceName != "" {
-		conditions = append(conditions, "service_name = ?")
-		args = append(args, filter.ServiceName)
-		argIndex++
-	}
-
-	if filter.AgentID != "" {
-		conditions = append(conditions, "agent_id = ?")
-		args = append(args, filter.AgentID)
-		argIndex++
-	}
-
-	if filter.Level != "" {
-		conditions = append(conditions, "level = ?")
-		args = append(args, string(filter.Level))
-		argIndex++
+// SearchLogs performs a full-text search and returns the matching entries with highlighted fragments
+func (s *SQLiteStorage) SearchLogs(ctx context.Context, queryText string, filter models.LogFilter) (*models.SearchResult, error) {
+	if s.search == nil {
+		return nil, ErrSearchDisabled
 	}
 
-	if filter.Platform != "" {
-		conditions = append(conditions, "platform = ?")
-		args = append(args, string(filter.Platform))
-		argIndex++
+	hits, totalCount, facets, err := s.search.SearchWithHighlights(ctx, queryText, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	if !filter.StartTime.IsZero() {
-		conditions = append(conditions, "timestamp >= ?")
-		args = append(args, filter.StartTime)
-		argIndex++
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
 	}
 
-	if !filter.EndTime.IsZero() {
-		conditions = append(conditions, "timestamp <= ?")
-		args = append(args, filter.EndTime)
-		argIndex++
+	logs, err := s.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs by IDs: %w", err)
 	}
 
-	if filter.MessageContains != "" {
-		conditions = append(conditions, "message LIKE ?")
-		args = append(args, "%"+filter.MessageContains+"%")
-		argIndex++
+	logsByID := make(map[string]models.LogEntry, len(logs))
+	for _, entry := range logs {
+		logsByID[entry.ID] = entry
 	}
 
-	whereClause := ""
-	if len(conditions) > 0 {
-		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	// Keep the search order, skipping index entries whose log was deleted
+	result := &models.SearchResult{
+		Hits:       make([]models.SearchHit, 0, len(hits)),
+		TotalCount: totalCount,
+		Facets:     facets,
 	}
-
-	// Set default limit if not specified
-	limit := filter.Limit
-	if limit <= 0 {
-		limit = 100
+	for _, hit := range hits {
+		entry, ok := logsByID[hit.ID]
+		if !ok {
+			continue
+		}
+		hit.highlightEntry(entry)
+		result.Hits = append(result.Hits, models.SearchHit{
+			Log:       entry,
+			Score:     hit.Score,
+			Fragments: hit.Fragments,
+			Matches:   hit.Matches,
+		})
 	}
 
 	offset := filter.Offset
 	if offset < 0 {
 		offset = 0
 	}
+	result.HasMore = offset+len(hits) < totalCount
 
-	// Get total count
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM log_entries %s", whereClause)
-	var totalCount int
-	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalCount); err != nil {
-		return nil, fmt.Errorf("failed to get total count: %w", err)
-	}
+	return result, nil
+}
+
+// QueryStream returns an iterator over all logs matching the filter, oldest first
+func (s *SQLiteStorage) QueryStream(ctx context.Context, filter models.LogFilter) (LogIterator, error) {
+	whereClause, args := buildWhereClause(filter)
+	timeColumn := timeFilterColumn(filter.TimeField)
 
-	// Get logs
 	query := fmt.Sprintf(`
-		SELECT id, timestamp, level, message, service_name, agent_id, platform,
-			   metadata, device_info, stack_trace, source_location
+		SELECT %s
 		FROM log_entries %s
-		ORDER BY timestamp DESC
-		LIMIT ? OFFSET ?
-	`, whereClause)
+		ORDER BY %s ASC, id ASC
+	`, logEntryColumns, whereClause, timeColumn)
 
-	args = append(args, limit, offset)
+	if filter.Limit > 0 || filter.Offset > 0 {
+		limit := filter.Limit
+		if limit <= 0 {
+			limit = -1 // SQLite: no limit
+		}
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, filter.Offset)
+	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.reader().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query logs: %w", err)
 	}
-	defer rows.Close()
 
-	var logs []models.LogEntry
-	for rows.Next() {
-		var log models.LogEntry
-		var metadataJSON, deviceInfoJSON, sourceLocationJSON, stackTrace sql.NullString
+	return &sqlLogIterator{rows: rows}, nil
+}
 
-		err := rows.Scan(
-			&log.ID,
-			&log.Timestamp,
-			&log.Level,
-			&log.Message,
-			&log.ServiceName,
-			&log.AgentID,
-			&log.Platform,
-			&metadataJSON,
-			&deviceInfoJSON,
-			&stackTrace,
-			&sourceLocationJSON,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan log entry: %w", err)
+// sqlLogIterator iterates over log entry rows selected with logEntryColumns
+type sqlLogIterator struct {
+	rows  *sql.Rows
+	entry models.LogEntry
+	err   error
+}
+
+// Next advances to the next log entry
+func (it *sqlLogIterator) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+
+	it.entry, it.err = scanLogEntry(it.rows)
+	return it.err == nil
+}
+
+// Entry returns the current log entry
+func (it *sqlLogIterator) Entry() models.LogEntry {
+	return it.entry
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *sqlLogIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	if err := it.rows.Err(); err != nil {
+		return fmt.Errorf("error iterating rows: %w", err)
+	}
+	return nil
+}
+
+// Close releases the underlying rows
+func (it *sqlLogIterator) Close() error {
+	return it.rows.Close()
+}
+
+// RebuildSearchIndex re-indexes all stored logs into the search index in batches
+func (s *SQLiteStorage) RebuildSearchIndex(ctx context.Context, batchSize int) (int, error) {
+	if s.search == nil {
+		return 0, fmt.Errorf("search is not enabled")
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	it, err := s.QueryStream(ctx, models.LogFilter{})
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	indexed := 0
+	batch := make([]models.LogEntry, 0, batchSize)
+	for it.Next() {
+		batch = append(batch, it.Entry())
+		if len(batch) < batchSize {
+			continue
 		}
+		if err := s.search.IndexLogEntries(batch); err != nil {
+			return indexed, fmt.Errorf("failed to index logs: %w", err)
+		}
+		indexed += len(batch)
+		batch = batch[:0]
+	}
+	if err := it.Err(); err != nil {
+		return indexed, err
+	}
 
-		// Deserialize JSON fields
-		if metadataJSON.Valid {
-			if err := json.Unmarshal([]byte(metadataJSON.String), &log.Metadata); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal metadata for log %s: %w", log.ID, err)
-			}
+	if len(batch) > 0 {
+		if err := s.search.IndexLogEntries(batch); err != nil {
+			return indexed, fmt.Errorf("failed to index logs: %w", err)
 		}
+		indexed += len(batch)
+	}
 
-		if deviceInfoJSON.Valid {
-			log.DeviceInfo = &models.DeviceInfo{}
-			if err := json.Unmarshal([]byte(deviceInfoJSON.String), log.DeviceInfo); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal device info for log %s: %w", log.ID, err)
-			}
+	return indexed, nil
+}
+
+// applyAdditionalFiltering applies filters that weren't handled by the search
+func (s *SQLiteStorage) applyAdditionalFiltering(logs []models.LogEntry, filter models.LogFilter) []models.LogEntry {
+	var filtered []models.LogEntry
+
+	for _, log := range logs {
+		// Additional time range filtering (search might be less precise)
+		logTime := log.Timestamp
+		if filter.TimeField == models.TimeFieldReceivedAt {
+			logTime = log.ReceivedAt
+		}
+		if !filter.StartTime.IsZero() && logTime.Before(filter.StartTime) {
+			continue
+		}
+		if !filter.EndTime.IsZero() && logTime.After(filter.EndTime) {
+			continue
 		}
 
-		if sourceLocationJSON.Valid {
-			log.SourceLocation = &models.SourceLocation{}
-			if err := json.Unmarshal([]byte(sourceLocationJSON.String), log.SourceLocation); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal source location for log %s: %w", log.ID, err)
-			}
+		if filter.RequestID != "" && log.RequestID != filter.RequestID {
+			continue
 		}
 
-		if stackTrace.Valid {
-			log.StackTrace = stackTrace.String
+		if filter.TemplateID != "" && log.TemplateID != filter.TemplateID {
+			continue
 		}
 
-		logs = append(logs, log)
+		if filter.MinLevel != "" && log.Level.Severity() < filter.MinLevel.Severity() {
+			continue
+		}
+
+		if !matchesTags(log.Tags, filter) || !matchesCrash(log.Crash, filter) {
+			continue
+		}
+
+		filtered = append(filtered, log)
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+	return filtered
+}
+
+// buildWhereClause builds the WHERE clause and its arguments for a log filter
+func buildWhereClause(filter models.LogFilter) (string, []interface{}) {
+	// Build WHERE clause and args
+	var conditions []string
+	var args []interface{}
+	argIndex := 0
+
+	if filter.ServiceName != "" {
+		conditions = append(conditions, "service_name = ?")
+		args = append(args, filter.ServiceName)
+		argIndex++
+	}
+
+	if filter.AgentID != "" {
+		conditions = append(conditions, "agent_id = ?")
+		args = append(args, filter.AgentID)
+		argIndex++
+	}
+
+	// Encrypted request IDs are sealed to the same blob for the same key
+	if filter.RequestID != "" {
+		values := comparableValues(filter.RequestID)
+		conditions = append(conditions, fmt.Sprintf("request_id IN (%s)", placeholderList(len(values))))
+		args = append(args, values...)
+		argIndex += len(values)
+	}
+
+	if filter.TemplateID != "" {
+		conditions = append(conditions, "template_id = ?")
+		args = append(args, filter.TemplateID)
+		argIndex++
+	}
+
+	if filter.Level != "" {
+		conditions = append(conditions, "level = ?")
+		args = append(args, string(filter.Level))
+		argIndex++
+	}
+
+	if filter.MinLevel != "" {
+		conditions = append(conditions, "severity >= ?")
+		args = append(args, filter.MinLevel.Severity())
+		argIndex++
+	}
+
+	if filter.Platform != "" {
+		conditions = append(conditions, "platform = ?")
+		args = append(args, string(filter.Platform))
+		argIndex++
+	}
+
+	timeColumn := timeFilterColumn(filter.TimeField)
+
+	if !filter.StartTime.IsZero() {
+		conditions = append(conditions, timeColumn+" >= ?")
+		args = append(args, filter.StartTime)
+		argIndex++
+	}
+
+	if !filter.EndTime.IsZero() {
+		conditions = append(conditions, timeColumn+" <= ?")
+		args = append(args, filter.EndTime)
+		argIndex++
+	}
+
+	if filter.MessageContains != "" {
+		// Compressed messages are stored as blobs and only decompressed to be matched
+		conditions = append(conditions, "(message LIKE ? OR (typeof(message) = 'blob' AND decompress(message) LIKE ?))")
+		args = append(args, "%"+filter.MessageContains+"%", "%"+filter.MessageContains+"%")
+		argIndex += 2
+	}
+
+	if len(filter.TagsAny) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"id IN (SELECT log_id FROM log_entry_tags WHERE tag IN (%s))",
+			placeholderList(len(filter.TagsAny))))
+		for _, tag := range filter.TagsAny {
+			args = append(args, tag)
+			argIndex++
+		}
+	}
+
+	if tagsAll := uniqueStrings(filter.TagsAll); len(tagsAll) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"id IN (SELECT log_id FROM log_entry_tags WHERE tag IN (%s) GROUP BY log_id HAVING COUNT(*) = ?)",
+			placeholderList(len(tagsAll))))
+		for _, tag := range tagsAll {
+			args = append(args, tag)
+			argIndex++
+		}
+		args = append(args, len(tagsAll))
+		argIndex++
+	}
+
+	if filter.CrashSignature != "" {
+		conditions = append(conditions, "crash_signature = ?")
+		args = append(args, filter.CrashSignature)
+		argIndex++
+	} else if filter.CrashesOnly {
+		conditions = append(conditions, "crash IS NOT NULL")
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	return whereClause, args
+}
+
+// queryWithSQL performs a traditional SQL-based query
+func (s *SQLiteStorage) queryWithSQL(ctx context.Context, filter models.LogFilter) (*models.LogResult, error) {
+	whereClause, args := buildWhereClause(filter)
+	_, offset := queryPage(filter)
+
+	// Get total count
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM log_entries %s", whereClause)
+	var totalCount int
+	if err := s.reader().QueryRowContext(ctx, countQuery, args...).Scan(&totalCount); err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	// Get logs
+	query, queryArgs := buildSelectQuery(filter)
+
+	rows, err := s.reader().QueryContext(ctx, query, queryArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs, err := scanLogEntries(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	hasMore := offset+len(logs) < totalCount
@@ -466,6 +1120,57 @@ func (s *SQLiteStorage) queryWithSQL(ctx context.Context, filter models.LogFilte
 	}, nil
 }
 
+// queryPage returns the limit and offset for a paginated query, applying defaults
+func queryPage(filter models.LogFilter) (int, int) {
+	// Set default limit if not specified
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// buildSelectQuery builds the paginated, newest first query for a log filter
+func buildSelectQuery(filter models.LogFilter) (string, []interface{}) {
+	whereClause, args := buildWhereClause(filter)
+	timeColumn := timeFilterColumn(filter.TimeField)
+	limit, offset := queryPage(filter)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM log_entries %s
+		ORDER BY %s DESC
+		LIMIT ? OFFSET ?
+	`, logEntryColumns, whereClause, timeColumn)
+
+	return query, append(args, limit, offset)
+}
+
+// SetSlowQueryThreshold enables logging of queries slower than threshold, 0 disables
+func (s *SQLiteStorage) SetSlowQueryThreshold(threshold time.Duration) {
+	s.slowQueryThreshold = threshold
+}
+
+// logSlowQuery logs a query together with its filter when it exceeded the slow query threshold
+func (s *SQLiteStorage) logSlowQuery(filter models.LogFilter, elapsed time.Duration) {
+	if s.slowQueryThreshold <= 0 || elapsed < s.slowQueryThreshold {
+		return
+	}
+
+	filterJSON, err := json.Marshal(filter)
+	if err != nil {
+		filterJSON = []byte(fmt.Sprintf("%+v", filter))
+	}
+
+	log.Printf("Slow SQL query: duration=%s filter=%s", elapsed, filterJSON)
+}
+
 // GetByIDs retrieves specific log entries by their IDs
 func (s *SQLiteStorage) GetByIDs(ctx context.Context, ids []string) ([]models.LogEntry, error) {
 	if len(ids) == 0 {
@@ -481,131 +1186,498 @@ func (s *SQLiteStorage) GetByIDs(ctx context.Context, ids []string) ([]models.Lo
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, timestamp, level, message, service_name, agent_id, platform,
-			   metadata, device_info, stack_trace, source_location
+		SELECT %s
 		FROM log_entries
 		WHERE id IN (%s)
 		ORDER BY timestamp DESC
-	`, strings.Join(placeholders, ","))
+	`, logEntryColumns, strings.Join(placeholders, ","))
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.reader().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query logs by IDs: %w", err)
 	}
 	defer rows.Close()
 
+	return scanLogEntries(rows)
+}
+
+// logEntryColumns lists the log_entries columns in the order scanLogEntries expects them
+const logEntryColumns = `id, timestamp, level, message, service_name, agent_id, platform,
+			   metadata, device_info, stack_trace, source_location,
+			   received_at, clock_skewed, tags, crash, annotations, request_id, template_id`
+
+// scanLogEntries reads all rows selected with logEntryColumns into log entries
+func scanLogEntries(rows *sql.Rows) ([]models.LogEntry, error) {
 	var logs []models.LogEntry
 	for rows.Next() {
-		var log models.LogEntry
-		var metadataJSON, deviceInfoJSON, sourceLocationJSON, stackTrace sql.NullString
-
-		err := rows.Scan(
-			&log.ID,
-			&log.Timestamp,
-			&log.Level,
-			&log.Message,
-			&log.ServiceName,
-			&log.AgentID,
-			&log.Platform,
-			&metadataJSON,
-			&deviceInfoJSON,
-			&stackTrace,
-			&sourceLocationJSON,
-		)
+		log, err := scanLogEntry(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan log entry: %w", err)
+			return nil, err
 		}
+		logs = append(logs, log)
+	}
 
-		// Deserialize JSON fields
-		if metadataJSON.Valid {
-			if err := json.Unmarshal([]byte(metadataJSON.String), &log.Metadata); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal metadata for log %s: %w", log.ID, err)
-			}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return logs, nil
+}
+
+// scanLogEntry reads the current row selected with logEntryColumns into a log entry
+func scanLogEntry(rows *sql.Rows) (models.LogEntry, error) {
+	var log models.LogEntry
+	var metadataJSON, deviceInfoJSON, sourceLocationJSON, stackTrace, tagsJSON, crashJSON, annotationsJSON, requestID, templateID sql.NullString
+	var receivedAt sql.NullTime
+
+	err := rows.Scan(
+		&log.ID,
+		&log.Timestamp,
+		&log.Level,
+		&log.Message,
+		&log.ServiceName,
+		&log.AgentID,
+		&log.Platform,
+		&metadataJSON,
+		&deviceInfoJSON,
+		&stackTrace,
+		&sourceLocationJSON,
+		&receivedAt,
+		&log.ClockSkewed,
+		&tagsJSON,
+		&crashJSON,
+		&annotationsJSON,
+		&requestID,
+		&templateID,
+	)
+	if err != nil {
+		return log, fmt.Errorf("failed to scan log entry: %w", err)
+	}
+
+	for _, value := range []*string{&log.Message, &metadataJSON.String, &stackTrace.String, &deviceInfoJSON.String,
+		&sourceLocationJSON.String, &crashJSON.String, &annotationsJSON.String, &requestID.String} {
+		if err := decompressString(value); err != nil {
+			return log, fmt.Errorf("failed to read log %s: %w", log.ID, err)
 		}
+	}
 
-		if deviceInfoJSON.Valid {
-			log.DeviceInfo = &models.DeviceInfo{}
-			if err := json.Unmarshal([]byte(deviceInfoJSON.String), log.DeviceInfo); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal device info for log %s: %w", log.ID, err)
-			}
+	// Deserialize JSON fields
+	if metadataJSON.Valid {
+		if err := json.Unmarshal([]byte(metadataJSON.String), &log.Metadata); err != nil {
+			return log, fmt.Errorf("failed to unmarshal metadata for log %s: %w", log.ID, err)
+		}
+	}
+
+	if deviceInfoJSON.Valid {
+		log.DeviceInfo = &models.DeviceInfo{}
+		if err := json.Unmarshal([]byte(deviceInfoJSON.String), log.DeviceInfo); err != nil {
+			return log, fmt.Errorf("failed to unmarshal device info for log %s: %w", log.ID, err)
+		}
+	}
+
+	if sourceLocationJSON.Valid {
+		log.SourceLocation = &models.SourceLocation{}
+		if err := json.Unmarshal([]byte(sourceLocationJSON.String), log.SourceLocation); err != nil {
+			return log, fmt.Errorf("failed to unmarshal source location for log %s: %w", log.ID, err)
+		}
+	}
+
+	if tagsJSON.Valid {
+		if err := json.Unmarshal([]byte(tagsJSON.String), &log.Tags); err != nil {
+			return log, fmt.Errorf("failed to unmarshal tags for log %s: %w", log.ID, err)
+		}
+	}
+
+	if crashJSON.Valid {
+		log.Crash = &models.CrashInfo{}
+		if err := json.Unmarshal([]byte(crashJSON.String), log.Crash); err != nil {
+			return log, fmt.Errorf("failed to unmarshal crash for log %s: %w", log.ID, err)
 		}
+	}
+
+	if annotationsJSON.Valid {
+		if err := json.Unmarshal([]byte(annotationsJSON.String), &log.Annotations); err != nil {
+			return log, fmt.Errorf("failed to unmarshal annotations for log %s: %w", log.ID, err)
+		}
+	}
 
-		if sourceLocationJSON.Valid {
-			log.SourceLocation = &models.SourceLocation{}
-			if err := json.Unmarshal([]byte(sourceLocationJSON.String), log.SourceLocation); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal source location for log %s: %w", log.ID, err)
+	if stackTrace.Valid {
+		log.StackTrace = stackTrace.String
+	}
+
+	if receivedAt.Valid {
+		log.ReceivedAt = receivedAt.Time
+	}
+
+	log.RequestID = requestID.String
+	log.TemplateID = templateID.String
+
+	return log, nil
+}
+
+// matchesCrash checks if an entry's crash report satisfies the crash filters
+func matchesCrash(crash *models.CrashInfo, filter models.LogFilter) bool {
+	if filter.CrashSignature != "" {
+		return crash != nil && crash.Signature == filter.CrashSignature
+	}
+	return !filter.CrashesOnly || crash != nil
+}
+
+// matchesTags checks if a set of tags satisfies the tags_any and tags_all filters
+func matchesTags(tags []string, filter models.LogFilter) bool {
+	tagSet := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tagSet[tag] = true
+	}
+
+	if len(filter.TagsAny) > 0 {
+		found := false
+		for _, tag := range filter.TagsAny {
+			if tagSet[tag] {
+				found = true
+				break
 			}
 		}
+		if !found {
+			return false
+		}
+	}
 
-		if stackTrace.Valid {
-			log.StackTrace = stackTrace.String
+	for _, tag := range filter.TagsAll {
+		if !tagSet[tag] {
+			return false
 		}
+	}
 
-		logs = append(logs, log)
+	return true
+}
+
+// placeholderList returns a comma separated list of n SQL placeholders
+func placeholderList(n int) string {
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = "?"
 	}
+	return strings.Join(placeholders, ",")
+}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+// uniqueStrings returns the values with duplicates removed, preserving order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
 	}
+	return unique
+}
 
-	return logs, nil
+// timeFilterColumn returns the column a time range filter applies to
+func timeFilterColumn(field models.TimeField) string {
+	if field == models.TimeFieldReceivedAt {
+		return "received_at"
+	}
+	return "timestamp"
 }
 
-// GetServices returns a list of services that have logged entries
-func (s *SQLiteStorage) GetServices(ctx context.Context) ([]models.ServiceInfo, error) {
-	query := `
-		SELECT service_name, agent_id, platform, MAX(timestamp) as last_seen, COUNT(*) as log_count
-		FROM log_entries
-		GROUP BY service_name, agent_id, platform
-		ORDER BY last_seen DESC
-	`
+// GetServices returns the services that have logged entries and match the filter. The counts
+// are read from the service_stats table, which triggers keep up to date as entries are stored
+// and deleted, so the cost does not grow with the number of entries.
+func (s *SQLiteStorage) GetServices(ctx context.Context, filter models.ServiceFilter) (*models.ServiceResult, error) {
+	// Name and platform narrow the rows read, the rest is applied once rows are merged per agent
+	var conditions []string
+	var args []interface{}
+	if filter.NamePrefix != "" {
+		conditions = append(conditions, "substr(st.service_name, 1, length(?)) = ?")
+		args = append(args, filter.NamePrefix, filter.NamePrefix)
+	}
+	if filter.Platform != "" {
+		conditions = append(conditions, "st.platform = ?")
+		args = append(args, string(filter.Platform))
+	}
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
 
-	rows, err := s.db.QueryContext(ctx, query)
+	query := fmt.Sprintf(`
+		SELECT st.service_name, st.agent_id, st.platform, st.level, st.last_seen, st.log_count,
+			   s.owner_team, s.repo_url, s.runbook_url, s.environment, s.created_at, s.updated_at,
+			   p.last_seen
+		FROM service_stats st
+		LEFT JOIN services s ON s.service_name = st.service_name
+		LEFT JOIN purged_services p ON p.service_name = st.service_name
+			AND p.agent_id = st.agent_id AND p.platform = st.platform
+		%s
+		ORDER BY st.service_name, st.agent_id, st.platform
+	`, whereClause)
+
+	rows, err := s.reader().QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query services: %w", err)
 	}
 	defer rows.Close()
 
+	// Rows are per level, consecutive rows of the same service and agent are merged
 	var services []models.ServiceInfo
+	var purgedUntil []sql.NullTime
 	for rows.Next() {
 		var service models.ServiceInfo
-		var platformStr string
-		var lastSeenStr string
+		var platformStr, levelStr string
+		var lastSeen time.Time
+		var logCount int
+		var ownerTeam, repoURL, runbookURL, environment sql.NullString
+		var createdAt, updatedAt, purged sql.NullTime
 
 		err := rows.Scan(
 			&service.ServiceName,
 			&service.AgentID,
 			&platformStr,
-			&lastSeenStr,
-			&service.LogCount,
+			&levelStr,
+			&lastSeen,
+			&logCount,
+			&ownerTeam,
+			&repoURL,
+			&runbookURL,
+			&environment,
+			&createdAt,
+			&updatedAt,
+			&purged,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan service info: %w", err)
 		}
 
-		// Parse timestamp string
-		lastSeen, err := time.Parse("2006-01-02 15:04:05.999999999-07:00", lastSeenStr)
-		if err != nil {
-			// Try alternative format
-			lastSeen, err = time.Parse("2006-01-02 15:04:05", lastSeenStr)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse last_seen timestamp: %w", err)
+		service.Platform = models.Platform(platformStr)
+		if n := len(services); n > 0 {
+			last := &services[n-1]
+			if last.ServiceName == service.ServiceName && last.AgentID == service.AgentID && last.Platform == service.Platform {
+				last.LogCount += logCount
+				last.LevelCounts[models.LogLevel(levelStr)] = logCount
+				if lastSeen.After(last.LastSeen) {
+					last.LastSeen = lastSeen
+				}
+				continue
 			}
 		}
 
-		service.Platform = models.Platform(platformStr)
 		service.LastSeen = lastSeen
+		service.LogCount = logCount
+		service.LevelCounts = map[models.LogLevel]int{models.LogLevel(levelStr): logCount}
+
+		// Attach ownership metadata for registered services
+		if ownerTeam.Valid {
+			service.Registration = &models.ServiceRegistration{
+				ServiceName: service.ServiceName,
+				OwnerTeam:   ownerTeam.String,
+				RepoURL:     repoURL.String,
+				RunbookURL:  runbookURL.String,
+				Environment: environment.String,
+				CreatedAt:   createdAt.Time,
+				UpdatedAt:   updatedAt.Time,
+			}
+		}
+
 		services = append(services, service)
+		purgedUntil = append(purgedUntil, purged)
 	}
 
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating rows: %w", err)
 	}
 
-	return services, nil
+	// Purged agents are left out until they log again
+	listed := services[:0]
+	for i, service := range services {
+		if !purgedUntil[i].Valid || service.LastSeen.After(purgedUntil[i].Time) {
+			listed = append(listed, service)
+		}
+	}
+
+	return filterServices(listed, filter), nil
+}
+
+// PurgeServices removes the agents last seen before notSeenSince from the services, keeping
+// their entries. The time each agent was last seen is recorded, so that it is listed again once
+// it stores a newer entry.
+func (s *SQLiteStorage) PurgeServices(ctx context.Context, notSeenSince time.Time) ([]models.ServiceInfo, error) {
+	result, err := s.GetServices(ctx, models.ServiceFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	purged := make([]models.ServiceInfo, 0)
+	for _, service := range result.Services {
+		if !service.LastSeen.Before(notSeenSince) {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT OR REPLACE INTO purged_services (service_name, agent_id, platform, last_seen, purged_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, service.ServiceName, service.AgentID, string(service.Platform), service.LastSeen, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge service %s: %w", service.ServiceName, err)
+		}
+		purged = append(purged, service)
+	}
+
+	// Agents whose entries were all deleted since they were purged no longer need hiding
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM purged_services WHERE NOT EXISTS (
+			SELECT 1 FROM service_stats st
+			WHERE st.service_name = purged_services.service_name AND st.agent_id = purged_services.agent_id
+			AND st.platform = purged_services.platform
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clean up purged services: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return purged, nil
+}
+
+// RegisterService creates or updates a service registration
+func (s *SQLiteStorage) RegisterService(ctx context.Context, registration models.ServiceRegistration) (*models.ServiceRegistration, error) {
+	now := time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO services (service_name, owner_team, repo_url, runbook_url, environment, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(service_name) DO UPDATE SET
+			owner_team = excluded.owner_team,
+			repo_url = excluded.repo_url,
+			runbook_url = excluded.runbook_url,
+			environment = excluded.environment,
+			updated_at = excluded.updated_at
+	`,
+		registration.ServiceName,
+		registration.OwnerTeam,
+		nullString(registration.RepoURL),
+		nullString(registration.RunbookURL),
+		nullString(registration.Environment),
+		now,
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register service %s: %w", registration.ServiceName, err)
+	}
+
+	return s.GetServiceRegistration(ctx, registration.ServiceName)
 }
 
-// DeleteByIDs deletes log entries by their IDs and returns the number of deleted entries
+// GetServiceRegistration returns the registration for a service, or nil if it is not registered
+func (s *SQLiteStorage) GetServiceRegistration(ctx context.Context, serviceName string) (*models.ServiceRegistration, error) {
+	query := fmt.Sprintf("SELECT %s FROM services WHERE service_name = ?", serviceRegistrationColumns)
+
+	rows, err := s.db.QueryContext(ctx, query, serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service registration: %w", err)
+	}
+	defer rows.Close()
+
+	registrations, err := scanServiceRegistrations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(registrations) == 0 {
+		return nil, nil
+	}
+
+	return &registrations[0], nil
+}
+
+// ListServiceRegistrations returns all registered services
+func (s *SQLiteStorage) ListServiceRegistrations(ctx context.Context) ([]models.ServiceRegistration, error) {
+	query := fmt.Sprintf("SELECT %s FROM services ORDER BY service_name", serviceRegistrationColumns)
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query service registrations: %w", err)
+	}
+	defer rows.Close()
+
+	return scanServiceRegistrations(rows)
+}
+
+// DeleteServiceRegistration removes a service registration and reports whether it existed
+func (s *SQLiteStorage) DeleteServiceRegistration(ctx context.Context, serviceName string) (bool, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM services WHERE service_name = ?", serviceName)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete service registration: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// serviceRegistrationColumns lists the services columns in the order scanServiceRegistrations expects them
+const serviceRegistrationColumns = `service_name, owner_team, repo_url, runbook_url, environment, created_at, updated_at`
+
+// scanServiceRegistrations reads all rows selected with serviceRegistrationColumns into registrations
+func scanServiceRegistrations(rows *sql.Rows) ([]models.ServiceRegistration, error) {
+	registrations := make([]models.ServiceRegistration, 0)
+	for rows.Next() {
+		var registration models.ServiceRegistration
+		var repoURL, runbookURL, environment sql.NullString
+
+		err := rows.Scan(
+			&registration.ServiceName,
+			&registration.OwnerTeam,
+			&repoURL,
+			&runbookURL,
+			&environment,
+			&registration.CreatedAt,
+			&registration.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan service registration: %w", err)
+		}
+
+		registration.RepoURL = repoURL.String
+		registration.RunbookURL = runbookURL.String
+		registration.Environment = environment.String
+
+		registrations = append(registrations, registration)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return registrations, nil
+}
+
+// nullString converts an empty string to a SQL NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// nullStringPtr converts an optional string to a sql.NullString
+func nullStringPtr(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
+
+// DeleteByIDs deletes log entries by their IDs and returns the number of deleted entries.
+// Entries under a legal hold or inside the immutable window are skipped
 func (s *SQLiteStorage) DeleteByIDs(ctx context.Context, ids []string) (int, error) {
 	if len(ids) == 0 {
 		return 0, nil
@@ -617,6 +1689,19 @@ func (s *SQLiteStorage) DeleteByIDs(ctx context.Context, ids []string) (int, err
 	}
 	defer tx.Rollback()
 
+	// Narrow the IDs down to the entries that may be deleted
+	requested := len(ids)
+	ids, err = s.deletableIDs(ctx, tx, ids)
+	if err != nil {
+		return 0, err
+	}
+	if skipped := requested - len(ids); skipped > 0 {
+		log.Printf("Skipped deleting %d log entries that are protected or no longer exist", skipped)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
 	// Build IN clause with placeholders
 	placeholders := make([]string, len(ids))
 	args := make([]interface{}, len(ids))
@@ -625,6 +1710,11 @@ func (s *SQLiteStorage) DeleteByIDs(ctx context.Context, ids []string) (int, err
 		args[i] = id
 	}
 
+	tagsQuery := fmt.Sprintf("DELETE FROM log_entry_tags WHERE log_id IN (%s)", strings.Join(placeholders, ","))
+	if _, err := tx.ExecContext(ctx, tagsQuery, args...); err != nil {
+		return 0, fmt.Errorf("failed to delete log entry tags: %w", err)
+	}
+
 	query := fmt.Sprintf("DELETE FROM log_entries WHERE id IN (%s)", strings.Join(placeholders, ","))
 
 	result, err := tx.ExecContext(ctx, query, args...)
@@ -643,17 +1733,49 @@ func (s *SQLiteStorage) DeleteByIDs(ctx context.Context, ids []string) (int, err
 
 	// Remove from search index if available
 	if s.search != nil {
-		for _, id := range ids {
-			if err := s.search.DeleteLogEntry(id); err != nil {
-				// Log error but don't fail the deletion
-				fmt.Printf("Warning: failed to delete log %s from search index: %v\n", id, err)
-			}
+		if err := s.search.DeleteLogEntries(ids); err != nil {
+			// Log error but don't fail the deletion
+			fmt.Printf("Warning: failed to delete %d logs from search index: %v\n", len(ids), err)
 		}
 	}
 
 	return int(rowsAffected), nil
 }
 
+// deletableIDs returns the IDs of existing entries that are not protected from deletion
+func (s *SQLiteStorage) deletableIDs(ctx context.Context, tx *sql.Tx, ids []string) ([]string, error) {
+	condition, conditionArgs := s.deletableCondition()
+
+	args := make([]interface{}, 0, len(ids)+len(conditionArgs))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, conditionArgs...)
+
+	query := fmt.Sprintf("SELECT id FROM log_entries WHERE id IN (%s) AND %s", placeholderList(len(ids)), condition)
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check protected log entries: %w", err)
+	}
+	defer rows.Close()
+
+	deletable := make([]string, 0, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan log entry id: %w", err)
+		}
+		deletable = append(deletable, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return deletable, nil
+}
+
 // HealthCheck returns the health status of the storage system
 func (s *SQLiteStorage) HealthCheck(ctx context.Context) models.HealthStatus {
 	status := models.HealthStatus{
@@ -680,9 +1802,48 @@ func (s *SQLiteStorage) HealthCheck(ctx context.Context) models.HealthStatus {
 	status.Details["database"] = "connected"
 	status.Details["log_count"] = fmt.Sprintf("%d", count)
 
+	// Include search index size and shard stats
+	if s.search != nil {
+		searchStatus := s.search.HealthCheck(ctx)
+		for key, value := range searchStatus.Details {
+			status.Details["search_"+key] = value
+		}
+		if searchStatus.Status != "healthy" {
+			status.Status = "degraded"
+		}
+	}
+
+	if s.replica != nil && !s.replicaHealth(ctx, status.Details) {
+		status.Status = "degraded"
+	}
+
 	return status
 }
 
+// RetireSearchShards removes search index shards covering only entries older than the cutoff,
+// keeping shards that may contain entries under a legal hold or inside the immutable window
+func (s *SQLiteStorage) RetireSearchShards(ctx context.Context, before time.Time) (int, error) {
+	if s.search == nil {
+		return 0, nil
+	}
+
+	before, err := s.protectedCutoff(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	if before.IsZero() {
+		return 0, nil
+	}
+
+	return s.search.RetireShards(before)
+}
+
+// MaxConcurrentWrites returns 1, SQLite allows a single writer and concurrent write
+// transactions would fail with SQLITE_BUSY
+func (s *SQLiteStorage) MaxConcurrentWrites() int {
+	return 1
+}
+
 // Close closes the storage connection
 func (s *SQLiteStorage) Close() error {
 	var err error
@@ -693,6 +1854,16 @@ func (s *SQLiteStorage) Close() error {
 		}
 	}
 
+	if s.replica != nil {
+		if replicaErr := s.replica.Close(); replicaErr != nil {
+			if err != nil {
+				err = fmt.Errorf("%w; failed to close read replica: %w", err, replicaErr)
+			} else {
+				err = fmt.Errorf("failed to close read replica: %w", replicaErr)
+			}
+		}
+	}
+
 	if s.db != nil {
 		if dbErr := s.db.Close(); dbErr != nil {
 			if err != nil {
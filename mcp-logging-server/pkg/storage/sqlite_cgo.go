@@ -0,0 +1,31 @@
+//go:build !purego
+
+package storage
+
+import (
+	"database/sql"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is the database/sql driver name of SQLite connections that can decompress
+// values in SQL with the decompress function
+const sqliteDriver = "sqlite3_mcp"
+
+// queryOnlyParam is the connection string parameter that rejects writes
+const queryOnlyParam = "_query_only=true"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("decompress", func(value []byte) (string, error) {
+				return decompressValue(value)
+			}, true)
+		},
+	})
+}
+
+// sqliteDSN returns the connection string passed to the driver
+func sqliteDSN(connectionString string) string {
+	return connectionString
+}
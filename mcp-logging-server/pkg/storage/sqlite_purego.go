@@ -0,0 +1,41 @@
+//go:build purego
+
+package storage
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"modernc.org/sqlite"
+)
+
+// sqliteDriver is the database/sql driver name of modernc.org/sqlite, a pure Go SQLite that
+// allows building without CGO. The decompress function is registered for all its connections.
+const sqliteDriver = "sqlite"
+
+// queryOnlyParam is the connection string parameter that rejects writes
+const queryOnlyParam = "_pragma=query_only(1)"
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("decompress", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch value := args[0].(type) {
+			case nil:
+				return nil, nil
+			case []byte:
+				return decompressValue(value)
+			case string:
+				return value, nil
+			default:
+				return nil, fmt.Errorf("decompress: unsupported value type %T", value)
+			}
+		})
+}
+
+// sqliteDSN returns the connection string passed to the driver. Times are written in the
+// format of mattn/go-sqlite3, so that databases can be shared between both builds and times
+// compare correctly in SQL. Like mattn/go-sqlite3, connections wait up to 5 seconds for a
+// lock, e.g. while the WAL archiver checkpoints.
+func sqliteDSN(connectionString string) string {
+	return withParam(withParam(connectionString, "_time_format=sqlite"), "_pragma=busy_timeout(5000)")
+}
@@ -3,6 +3,8 @@ package storage
 import (
 	"context"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -327,10 +329,11 @@ func TestSQLiteStorage_GetServices(t *testing.T) {
 	}
 
 	// Test getting services
-	services, err := storage.GetServices(ctx)
+	result, err := storage.GetServices(ctx, models.ServiceFilter{})
 	if err != nil {
 		t.Fatalf("Failed to get services: %v", err)
 	}
+	services := result.Services
 
 	if len(services) != 2 {
 		t.Errorf("Expected 2 services, got %d", len(services))
@@ -356,6 +359,129 @@ func TestSQLiteStorage_GetServices(t *testing.T) {
 	}
 }
 
+func TestSQLiteStorage_GetServicesTracksDeletes(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	logs := []models.LogEntry{
+		{ID: uuid.New().String(), Timestamp: now, Level: models.LogLevelInfo, Message: "a", ServiceName: "service-1", AgentID: "agent-1", Platform: models.PlatformGo},
+		{ID: uuid.New().String(), Timestamp: now.Add(time.Minute), Level: models.LogLevelInfo, Message: "b", ServiceName: "service-1", AgentID: "agent-1", Platform: models.PlatformGo},
+		{ID: uuid.New().String(), Timestamp: now.Add(2 * time.Minute), Level: models.LogLevelError, Message: "c", ServiceName: "service-1", AgentID: "agent-1", Platform: models.PlatformGo},
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.GetServices(ctx, models.ServiceFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get services: %v", err)
+	}
+	services := result.Services
+	if len(services) != 1 {
+		t.Fatalf("Expected 1 service, got %d", len(services))
+	}
+	if services[0].LogCount != 3 || services[0].LevelCounts[models.LogLevelInfo] != 2 || services[0].LevelCounts[models.LogLevelError] != 1 {
+		t.Errorf("Unexpected counts: %d %v", services[0].LogCount, services[0].LevelCounts)
+	}
+
+	// Deleting the newest entry moves last_seen back and drops the empty level
+	if _, err := storage.DeleteByIDs(ctx, []string{logs[2].ID}); err != nil {
+		t.Fatalf("Failed to delete logs: %v", err)
+	}
+
+	result, err = storage.GetServices(ctx, models.ServiceFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get services: %v", err)
+	}
+	services = result.Services
+	if services[0].LogCount != 2 {
+		t.Errorf("Expected 2 logs after delete, got %d", services[0].LogCount)
+	}
+	if _, ok := services[0].LevelCounts[models.LogLevelError]; ok {
+		t.Errorf("Expected ERROR count to be removed, got %v", services[0].LevelCounts)
+	}
+	if !services[0].LastSeen.Equal(logs[1].Timestamp) {
+		t.Errorf("Expected last_seen %v, got %v", logs[1].Timestamp, services[0].LastSeen)
+	}
+}
+
+func TestSQLiteStorage_GetServicesFilter(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	newLog := func(service, agent string, platform models.Platform, at time.Time) models.LogEntry {
+		return models.LogEntry{ID: uuid.New().String(), Timestamp: at, Level: models.LogLevelInfo, Message: "m", ServiceName: service, AgentID: agent, Platform: platform}
+	}
+	logs := []models.LogEntry{
+		newLog("checkout", "checkout-1", models.PlatformGo, now.Add(-time.Minute)),
+		newLog("checkout", "checkout-2", models.PlatformGo, now.Add(-2*time.Hour)),
+		newLog("checkout", "checkout-2", models.PlatformGo, now.Add(-3*time.Hour)),
+		newLog("check_in", "check-in-1", models.PlatformSwift, now.Add(-time.Hour)),
+		newLog("cart", "cart-1", models.PlatformGo, now),
+		newLog("cart", "cart-1", models.PlatformGo, now),
+		newLog("cart", "cart-1", models.PlatformGo, now),
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	agents := func(result *models.ServiceResult) string {
+		var agents []string
+		for _, service := range result.Services {
+			agents = append(agents, service.AgentID)
+		}
+		return strings.Join(agents, ",")
+	}
+
+	tests := []struct {
+		name   string
+		filter models.ServiceFilter
+		want   string
+		total  int
+	}{
+		{"all, most recently seen first", models.ServiceFilter{}, "cart-1,checkout-1,check-in-1,checkout-2", 4},
+		{"name prefix", models.ServiceFilter{NamePrefix: "check", Sort: models.ServiceSortName}, "check-in-1,checkout-1,checkout-2", 3},
+		{"prefix is not a pattern", models.ServiceFilter{NamePrefix: "check_"}, "check-in-1", 1},
+		{"platform", models.ServiceFilter{Platform: models.PlatformSwift}, "check-in-1", 1},
+		{"active since", models.ServiceFilter{ActiveSince: now.Add(-90 * time.Minute)}, "cart-1,checkout-1,check-in-1", 3},
+		{"log count", models.ServiceFilter{Sort: models.ServiceSortLogCount, Limit: 2}, "cart-1,checkout-2", 4},
+		{"page", models.ServiceFilter{Sort: models.ServiceSortName, Limit: 2, Offset: 1}, "check-in-1,checkout-1", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.GetServices(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to get services: %v", err)
+			}
+			if agents(result) != tt.want || result.TotalCount != tt.total || result.HasMore != (tt.filter.Offset+len(result.Services) < tt.total) {
+				t.Errorf("Expected %s of %d services, got %s of %d (has more: %v)", tt.want, tt.total, agents(result), result.TotalCount, result.HasMore)
+			}
+		})
+	}
+
+	// Platforms are counted over all matching services, not only the page
+	result, err := storage.GetServices(ctx, models.ServiceFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to get services: %v", err)
+	}
+	if goCount := result.Platforms[models.PlatformGo]; goCount.Services != 3 || goCount.Logs != 6 || result.Platforms[models.PlatformSwift].Services != 1 {
+		t.Errorf("Unexpected platform counts %+v", result.Platforms)
+	}
+}
+
 func TestSQLiteStorage_HealthCheck(t *testing.T) {
 	storage, err := NewSQLiteStorage(":memory:")
 	if err != nil {
@@ -375,6 +501,64 @@ func TestSQLiteStorage_HealthCheck(t *testing.T) {
 	}
 }
 
+func TestSQLiteStorage_QueryByReceivedAt(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// A device with a clock two days behind, received just now
+	skewedLog := models.LogEntry{
+		ID:          uuid.New().String(),
+		Timestamp:   now.Add(-48 * time.Hour),
+		ReceivedAt:  now,
+		ClockSkewed: true,
+		Level:       models.LogLevelInfo,
+		Message:     "Skewed message",
+		ServiceName: "mobile-app",
+		AgentID:     "device-1",
+		Platform:    models.PlatformSwift,
+	}
+
+	if err := storage.Store(ctx, []models.LogEntry{skewedLog}); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	// Not found when filtering on the client timestamp
+	result, err := storage.Query(ctx, models.LogFilter{
+		StartTime: now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if len(result.Logs) != 0 {
+		t.Errorf("Expected 0 logs by timestamp, got %d", len(result.Logs))
+	}
+
+	// Found when filtering on the receive time
+	result, err = storage.Query(ctx, models.LogFilter{
+		StartTime: now.Add(-time.Hour),
+		TimeField: models.TimeFieldReceivedAt,
+	})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if len(result.Logs) != 1 {
+		t.Fatalf("Expected 1 log by received_at, got %d", len(result.Logs))
+	}
+
+	if !result.Logs[0].ClockSkewed {
+		t.Error("Expected clock_skewed flag to be persisted")
+	}
+	if !result.Logs[0].ReceivedAt.Equal(now) {
+		t.Errorf("Expected received_at %v, got %v", now, result.Logs[0].ReceivedAt)
+	}
+}
+
 func TestSQLiteStorage_InvalidData(t *testing.T) {
 	storage, err := NewSQLiteStorage(":memory:")
 	if err != nil {
@@ -427,4 +611,452 @@ func TestSQLiteStorage_Migration(t *testing.T) {
 	if health.Status != "healthy" {
 		t.Errorf("Expected healthy status after migration, got %s", health.Status)
 	}
+
+	version, err := storage.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get schema version: %v", err)
+	}
+	if version < 11 {
+		t.Errorf("Expected all migrations to be applied, got schema version %d", version)
+	}
+}
+
+func TestSQLiteStorage_CustomPlatform(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	// Platforms are validated at ingestion, storage accepts any platform
+	logs := []models.LogEntry{
+		{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Test message",
+			ServiceName: "python-service",
+			AgentID:     "test-agent",
+			Platform:    models.Platform("python"),
+		},
+	}
+
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store log with custom platform: %v", err)
+	}
+
+	result, err := storage.Query(ctx, models.LogFilter{Platform: "python"})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if len(result.Logs) != 1 {
+		t.Errorf("Expected 1 log, got %d", len(result.Logs))
+	}
+}
+
+func TestSQLiteStorage_ServiceRegistry(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	registration, err := storage.RegisterService(ctx, models.ServiceRegistration{
+		ServiceName: "service-1",
+		OwnerTeam:   "team-a",
+		RunbookURL:  "https://example.com/runbook",
+	})
+	if err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	createdAt := registration.CreatedAt
+
+	// Re-registering updates the metadata but keeps the creation time
+	registration, err = storage.RegisterService(ctx, models.ServiceRegistration{
+		ServiceName: "service-1",
+		OwnerTeam:   "team-b",
+		Environment: "staging",
+	})
+	if err != nil {
+		t.Fatalf("Failed to update service: %v", err)
+	}
+	if registration.OwnerTeam != "team-b" || registration.Environment != "staging" || registration.RunbookURL != "" {
+		t.Errorf("Unexpected registration after update: %+v", registration)
+	}
+	if !registration.CreatedAt.Equal(createdAt) {
+		t.Errorf("Expected created_at %v to be preserved, got %v", createdAt, registration.CreatedAt)
+	}
+
+	logs := []models.LogEntry{
+		{ID: uuid.New().String(), Timestamp: time.Now(), Level: models.LogLevelError, Message: "failure", ServiceName: "service-1", AgentID: "agent-1", Platform: models.PlatformGo},
+		{ID: uuid.New().String(), Timestamp: time.Now(), Level: models.LogLevelInfo, Message: "ok", ServiceName: "service-2", AgentID: "agent-1", Platform: models.PlatformGo},
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.GetServices(ctx, models.ServiceFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get services: %v", err)
+	}
+	services := result.Services
+
+	for _, service := range services {
+		switch service.ServiceName {
+		case "service-1":
+			if service.Registration == nil || service.Registration.OwnerTeam != "team-b" {
+				t.Errorf("Expected service-1 to be owned by team-b, got %+v", service.Registration)
+			}
+		case "service-2":
+			if service.Registration != nil {
+				t.Errorf("Expected service-2 to be unregistered, got %+v", service.Registration)
+			}
+		}
+	}
+
+	deleted, err := storage.DeleteServiceRegistration(ctx, "service-1")
+	if err != nil || !deleted {
+		t.Fatalf("Expected registration to be deleted, got %v, %v", deleted, err)
+	}
+
+	registration, err = storage.GetServiceRegistration(ctx, "service-1")
+	if err != nil {
+		t.Fatalf("Failed to get registration: %v", err)
+	}
+	if registration != nil {
+		t.Errorf("Expected no registration after delete, got %+v", registration)
+	}
+}
+
+func TestSQLiteStorage_QueryByTags(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	newLog := func(tags ...string) models.LogEntry {
+		return models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Tagged message",
+			ServiceName: "test-service",
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+			Tags:        tags,
+		}
+	}
+
+	logs := []models.LogEntry{
+		newLog("checkout", "payments"),
+		newLog("checkout"),
+		newLog("auth"),
+		newLog(),
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.LogFilter
+		expected int
+	}{
+		{"tags any", models.LogFilter{TagsAny: []string{"payments", "auth"}}, 2},
+		{"tags all", models.LogFilter{TagsAll: []string{"checkout", "payments"}}, 1},
+		{"tags all with duplicates", models.LogFilter{TagsAll: []string{"checkout", "checkout"}}, 2},
+		{"tags any and all", models.LogFilter{TagsAny: []string{"checkout", "auth"}, TagsAll: []string{"payments"}}, 1},
+		{"unknown tag", models.LogFilter{TagsAny: []string{"missing"}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := storage.Query(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("Failed to query logs: %v", err)
+			}
+			if result.TotalCount != tt.expected {
+				t.Errorf("Expected %d logs, got %d", tt.expected, result.TotalCount)
+			}
+		})
+	}
+
+	result, err := storage.GetByIDs(ctx, []string{logs[0].ID})
+	if err != nil {
+		t.Fatalf("Failed to get log: %v", err)
+	}
+	if len(result) != 1 || len(result[0].Tags) != 2 {
+		t.Errorf("Expected tags to round trip, got %+v", result)
+	}
+}
+
+func TestSQLiteStorage_QueryByRequestID(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+	storage.SetIntegrityHashing(true, "")
+
+	ctx := context.Background()
+
+	newLog := func(requestID string) models.LogEntry {
+		return models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     "Handled request",
+			ServiceName: "test-service",
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+			RequestID:   requestID,
+		}
+	}
+
+	logs := []models.LogEntry{newLog("req-1"), newLog("req-1"), newLog("req-2"), newLog("")}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.Query(ctx, models.LogFilter{RequestID: "req-1"})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 2 {
+		t.Errorf("Expected 2 logs of the request, got %d", result.TotalCount)
+	}
+	for _, log := range result.Logs {
+		if log.RequestID != "req-1" {
+			t.Errorf("Expected request ID to round trip, got %q", log.RequestID)
+		}
+	}
+
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != len(logs) || report.Mismatched != 0 {
+		t.Errorf("Expected entries with and without a request ID to verify, got %+v", report)
+	}
+}
+
+func TestSQLiteStorage_QueryByTemplateID(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+	storage.SetIntegrityHashing(true, "")
+
+	ctx := context.Background()
+
+	newLog := func(message string) models.LogEntry {
+		return models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       models.LogLevelInfo,
+			Message:     message,
+			ServiceName: "test-service",
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+			TemplateID:  models.MessageTemplateID(message),
+		}
+	}
+
+	logs := []models.LogEntry{newLog("Order 1 placed"), newLog("Order 22 placed"), newLog("Cache warmed")}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.Query(ctx, models.LogFilter{TemplateID: logs[0].TemplateID})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 2 {
+		t.Errorf("Expected 2 logs of the template, got %d", result.TotalCount)
+	}
+	for _, log := range result.Logs {
+		if log.TemplateID != logs[0].TemplateID {
+			t.Errorf("Expected template ID to round trip, got %q", log.TemplateID)
+		}
+	}
+
+	// Template IDs are derived from the message and not covered by the content hash
+	report, err := storage.VerifyIntegrity(ctx, IntegrityVerifyOptions{})
+	if err != nil {
+		t.Fatalf("Failed to verify integrity: %v", err)
+	}
+	if report.Checked != len(logs) || report.Mismatched != 0 {
+		t.Errorf("Expected entries with a template ID to verify, got %+v", report)
+	}
+}
+
+func TestSQLiteStorage_QueryByMinLevel(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	var logs []models.LogEntry
+	for _, level := range []models.LogLevel{models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn, models.LogLevelError, models.LogLevelFatal} {
+		logs = append(logs, models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   time.Now(),
+			Level:       level,
+			Message:     "Test message",
+			ServiceName: "test-service",
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+		})
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	result, err := storage.Query(ctx, models.LogFilter{MinLevel: models.LogLevelWarn})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 3 {
+		t.Errorf("Expected 3 logs of WARN and above, got %d", result.TotalCount)
+	}
+	for _, log := range result.Logs {
+		if log.Level.Severity() < models.LogLevelWarn.Severity() {
+			t.Errorf("Expected no %s logs", log.Level)
+		}
+	}
+
+	// Combined with a level, both apply
+	result, err = storage.Query(ctx, models.LogFilter{Level: models.LogLevelInfo, MinLevel: models.LogLevelWarn})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 0 {
+		t.Errorf("Expected no INFO logs of WARN and above, got %d", result.TotalCount)
+	}
+}
+
+func TestSQLiteStorage_QueryStream(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	var logs []models.LogEntry
+	for i := 0; i < 5; i++ {
+		service := "service-a"
+		if i%2 == 1 {
+			service = "service-b"
+		}
+		logs = append(logs, models.LogEntry{
+			ID:          uuid.New().String(),
+			Timestamp:   now.Add(time.Duration(i) * time.Second),
+			Level:       models.LogLevelInfo,
+			Message:     "Streamed message",
+			ServiceName: service,
+			AgentID:     "test-agent",
+			Platform:    models.PlatformGo,
+		})
+	}
+	if err := storage.Store(ctx, logs); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+
+	it, err := storage.QueryStream(ctx, models.LogFilter{ServiceName: "service-a"})
+	if err != nil {
+		t.Fatalf("Failed to stream logs: %v", err)
+	}
+	defer it.Close()
+
+	var streamed []models.LogEntry
+	for it.Next() {
+		streamed = append(streamed, it.Entry())
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Iteration failed: %v", err)
+	}
+
+	expected := []string{logs[0].ID, logs[2].ID, logs[4].ID}
+	if len(streamed) != len(expected) {
+		t.Fatalf("Expected %d streamed logs, got %d", len(expected), len(streamed))
+	}
+	for i, id := range expected {
+		if streamed[i].ID != id {
+			t.Errorf("Expected log %s at position %d (oldest first), got %s", id, i, streamed[i].ID)
+		}
+	}
+
+	// Limit and offset are honored when set
+	limited, err := storage.QueryStream(ctx, models.LogFilter{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("Failed to stream logs: %v", err)
+	}
+	defer limited.Close()
+
+	count := 0
+	for limited.Next() {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 streamed logs with limit, got %d", count)
+	}
+}
+
+func TestSQLiteStorage_InMemoryConcurrentAccess(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer storage.Close()
+
+	ctx := context.Background()
+
+	// Concurrent callers must all see the same in-memory database
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- storage.Store(ctx, []models.LogEntry{{
+				ID:          uuid.New().String(),
+				Timestamp:   time.Now(),
+				Level:       models.LogLevelInfo,
+				Message:     "Concurrent message",
+				ServiceName: "test-service",
+				AgentID:     "test-agent",
+				Platform:    models.PlatformGo,
+			}})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Failed to store logs: %v", err)
+		}
+	}
+
+	result, err := storage.Query(ctx, models.LogFilter{Limit: 100})
+	if err != nil {
+		t.Fatalf("Failed to query logs: %v", err)
+	}
+	if result.TotalCount != 10 {
+		t.Errorf("Expected 10 logs, got %d", result.TotalCount)
+	}
 }
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// symbolFileKey identifies a symbol file in memory storage
+type symbolFileKey struct {
+	serviceName string
+	version     string
+	name        string
+}
+
+// PutSymbolFile stores a symbol file, replacing the one with the same service, version and name
+func (s *SQLiteStorage) PutSymbolFile(ctx context.Context, file models.SymbolFile) (*models.SymbolFile, error) {
+	file.Size = len(file.Content)
+	file.UploadedAt = time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO symbol_files (service_name, version, name, kind, size, content, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, file.ServiceName, file.Version, file.Name, string(file.Kind), file.Size, file.Content, file.UploadedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store symbol file %s: %w", file.Name, err)
+	}
+
+	return &file, nil
+}
+
+// GetSymbolFile returns a symbol file with its content, or nil if it does not exist
+func (s *SQLiteStorage) GetSymbolFile(ctx context.Context, serviceName, version, name string) (*models.SymbolFile, error) {
+	file := &models.SymbolFile{ServiceName: serviceName, Version: version, Name: name}
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT kind, size, content, uploaded_at FROM symbol_files
+		WHERE service_name = ? AND version = ? AND name = ?
+	`, serviceName, version, name).Scan(&file.Kind, &file.Size, &file.Content, &file.UploadedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get symbol file %s: %w", name, err)
+	}
+
+	return file, nil
+}
+
+// ListSymbolFiles returns the symbol files of a service without their content, limited to one
+// version unless version is empty, ordered by version and name
+func (s *SQLiteStorage) ListSymbolFiles(ctx context.Context, serviceName, version string) ([]models.SymbolFile, error) {
+	query := "SELECT service_name, version, name, kind, size, uploaded_at FROM symbol_files WHERE service_name = ?"
+	args := []interface{}{serviceName}
+	if version != "" {
+		query += " AND version = ?"
+		args = append(args, version)
+	}
+	query += " ORDER BY version, name"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbol files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make([]models.SymbolFile, 0)
+	for rows.Next() {
+		var file models.SymbolFile
+		if err := rows.Scan(&file.ServiceName, &file.Version, &file.Name, &file.Kind, &file.Size, &file.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan symbol file: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return files, nil
+}
+
+// DeleteSymbolFile removes a symbol file and reports whether it existed
+func (s *SQLiteStorage) DeleteSymbolFile(ctx context.Context, serviceName, version, name string) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM symbol_files WHERE service_name = ? AND version = ? AND name = ?",
+		serviceName, version, name,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete symbol file %s: %w", name, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// PutSymbolFile stores a symbol file, replacing the one with the same service, version and name
+func (s *MemoryStorage) PutSymbolFile(ctx context.Context, file models.SymbolFile) (*models.SymbolFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file.Size = len(file.Content)
+	file.UploadedAt = time.Now().UTC()
+	s.symbolFiles[symbolFileKey{file.ServiceName, file.Version, file.Name}] = file
+	return &file, nil
+}
+
+// GetSymbolFile returns a symbol file with its content, or nil if it does not exist
+func (s *MemoryStorage) GetSymbolFile(ctx context.Context, serviceName, version, name string) (*models.SymbolFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, ok := s.symbolFiles[symbolFileKey{serviceName, version, name}]
+	if !ok {
+		return nil, nil
+	}
+	return &file, nil
+}
+
+// ListSymbolFiles returns the symbol files of a service without their content, limited to one
+// version unless version is empty, ordered by version and name
+func (s *MemoryStorage) ListSymbolFiles(ctx context.Context, serviceName, version string) ([]models.SymbolFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make([]models.SymbolFile, 0)
+	for key, file := range s.symbolFiles {
+		if key.serviceName != serviceName || (version != "" && key.version != version) {
+			continue
+		}
+		file.Content = nil
+		files = append(files, file)
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Version != files[j].Version {
+			return files[i].Version < files[j].Version
+		}
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// DeleteSymbolFile removes a symbol file and reports whether it existed
+func (s *MemoryStorage) DeleteSymbolFile(ctx context.Context, serviceName, version, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := symbolFileKey{serviceName, version, name}
+	if _, ok := s.symbolFiles[key]; !ok {
+		return false, nil
+	}
+	delete(s.symbolFiles, key)
+	return true, nil
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestSymbolFileStores(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "symbols.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]SymbolFileStore{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			uploads := []models.SymbolFile{
+				{ServiceName: "mobile-app", Version: "2.0.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap, Content: []byte(`{"version":3}`)},
+				{ServiceName: "mobile-app", Version: "1.9.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap, Content: []byte(`{"version":3}`)},
+				{ServiceName: "mobile-app", Version: "2.0.0", Name: "index.android.bundle", Kind: models.SymbolFileKindSourceMap, Content: []byte(`{}`)},
+				{ServiceName: "web-app", Version: "2.0.0", Name: "main.js", Kind: models.SymbolFileKindSourceMap, Content: []byte(`{}`)},
+			}
+			for _, upload := range uploads {
+				stored, err := store.PutSymbolFile(ctx, upload)
+				if err != nil {
+					t.Fatalf("Failed to store symbol file: %v", err)
+				}
+				if stored.Size != len(upload.Content) || stored.UploadedAt.IsZero() {
+					t.Errorf("Expected size and upload time to be set, got %+v", stored)
+				}
+			}
+
+			// Uploading the same name again replaces the file
+			replacement := []byte(`{"version":3,"sources":["App.tsx"]}`)
+			if _, err := store.PutSymbolFile(ctx, models.SymbolFile{ServiceName: "mobile-app", Version: "2.0.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap, Content: replacement}); err != nil {
+				t.Fatalf("Failed to replace symbol file: %v", err)
+			}
+
+			file, err := store.GetSymbolFile(ctx, "mobile-app", "2.0.0", "main.jsbundle")
+			if err != nil {
+				t.Fatalf("Failed to get symbol file: %v", err)
+			}
+			if file == nil || string(file.Content) != string(replacement) || file.Kind != models.SymbolFileKindSourceMap {
+				t.Errorf("Expected the replaced content, got %+v", file)
+			}
+
+			files, err := store.ListSymbolFiles(ctx, "mobile-app", "")
+			if err != nil {
+				t.Fatalf("Failed to list symbol files: %v", err)
+			}
+			if len(files) != 3 || files[0].Version != "1.9.0" || files[1].Name != "index.android.bundle" || files[1].Content != nil {
+				t.Errorf("Expected 3 files ordered by version and name without content, got %+v", files)
+			}
+			if files, _ := store.ListSymbolFiles(ctx, "mobile-app", "2.0.0"); len(files) != 2 {
+				t.Errorf("Expected 2 files for version 2.0.0, got %d", len(files))
+			}
+
+			deleted, err := store.DeleteSymbolFile(ctx, "mobile-app", "2.0.0", "main.jsbundle")
+			if err != nil || !deleted {
+				t.Fatalf("Expected the symbol file to be deleted, got %v, %v", deleted, err)
+			}
+			if deleted, _ := store.DeleteSymbolFile(ctx, "mobile-app", "2.0.0", "main.jsbundle"); deleted {
+				t.Error("Expected deleting a missing symbol file to report false")
+			}
+			if file, _ := store.GetSymbolFile(ctx, "mobile-app", "2.0.0", "main.jsbundle"); file != nil {
+				t.Errorf("Expected nil for a deleted symbol file, got %+v", file)
+			}
+		})
+	}
+}
+
+func TestMemoryStorage_SnapshotSymbolFiles(t *testing.T) {
+	ctx := context.Background()
+	config := MemoryConfig{SnapshotPath: filepath.Join(t.TempDir(), "snapshot.json")}
+
+	storage, err := NewMemoryStorageWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to create memory storage: %v", err)
+	}
+	content := []byte(`{"version":3,"mappings":"AAAA"}`)
+	if _, err := storage.PutSymbolFile(ctx, models.SymbolFile{ServiceName: "mobile-app", Version: "2.0.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap, Content: content}); err != nil {
+		t.Fatalf("Failed to store symbol file: %v", err)
+	}
+	if err := storage.Close(); err != nil {
+		t.Fatalf("Failed to close memory storage: %v", err)
+	}
+
+	restored, err := NewMemoryStorageWithConfig(config)
+	if err != nil {
+		t.Fatalf("Failed to restore memory storage: %v", err)
+	}
+	defer restored.Close()
+
+	file, err := restored.GetSymbolFile(ctx, "mobile-app", "2.0.0", "main.jsbundle")
+	if err != nil || file == nil || string(file.Content) != string(content) {
+		t.Errorf("Expected the symbol file to be restored with its content, got %+v, %v", file, err)
+	}
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// GetSyncState returns the sync state of an agent, with LastSequence 0 if it never synced
+func (s *SQLiteStorage) GetSyncState(ctx context.Context, agentID string) (*models.SyncState, error) {
+	state := &models.SyncState{AgentID: agentID}
+
+	err := s.db.QueryRowContext(ctx,
+		"SELECT last_sequence, updated_at FROM sync_states WHERE agent_id = ?", agentID,
+	).Scan(&state.LastSequence, &state.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get sync state of agent %s: %w", agentID, err)
+	}
+
+	return state, nil
+}
+
+// AdvanceSyncState records the highest sequence accepted from an agent, never moving it backwards
+func (s *SQLiteStorage) AdvanceSyncState(ctx context.Context, agentID string, lastSequence int64) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO sync_states (agent_id, last_sequence, updated_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(agent_id) DO UPDATE SET
+			last_sequence = MAX(sync_states.last_sequence, excluded.last_sequence),
+			updated_at = excluded.updated_at
+	`, agentID, lastSequence, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to advance sync state of agent %s: %w", agentID, err)
+	}
+
+	return nil
+}
+
+// GetSyncState returns the sync state of an agent, with LastSequence 0 if it never synced
+func (s *MemoryStorage) GetSyncState(ctx context.Context, agentID string) (*models.SyncState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	state, ok := s.syncStates[agentID]
+	if !ok {
+		return &models.SyncState{AgentID: agentID}, nil
+	}
+	return &state, nil
+}
+
+// AdvanceSyncState records the highest sequence accepted from an agent, never moving it backwards
+func (s *MemoryStorage) AdvanceSyncState(ctx context.Context, agentID string, lastSequence int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.syncStates[agentID]
+	state.AgentID = agentID
+	if lastSequence > state.LastSequence {
+		state.LastSequence = lastSequence
+	}
+	state.UpdatedAt = time.Now().UTC()
+	s.syncStates[agentID] = state
+	return nil
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncStateStores(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "sync.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]SyncStateStore{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			state, err := store.GetSyncState(ctx, "device-1")
+			if err != nil {
+				t.Fatalf("Failed to get sync state: %v", err)
+			}
+			if state.AgentID != "device-1" || state.LastSequence != 0 {
+				t.Errorf("Expected an empty state for a new agent, got %+v", state)
+			}
+
+			for _, sequence := range []int64{5, 12, 7} {
+				if err := store.AdvanceSyncState(ctx, "device-1", sequence); err != nil {
+					t.Fatalf("Failed to advance sync state: %v", err)
+				}
+			}
+
+			// The sequence never moves backwards
+			state, _ = store.GetSyncState(ctx, "device-1")
+			if state.LastSequence != 12 || state.UpdatedAt.IsZero() {
+				t.Errorf("Expected last sequence 12, got %+v", state)
+			}
+
+			other, _ := store.GetSyncState(ctx, "device-2")
+			if other.LastSequence != 0 {
+				t.Errorf("Expected agents to be tracked separately, got %d", other.LastSequence)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// DefaultUsageLimit is the number of usage records returned when the filter sets no limit
+const DefaultUsageLimit = 100
+
+// usageKey identifies a usage record in memory storage
+type usageKey struct {
+	day         string
+	apiKeyID    string
+	serviceName string
+}
+
+// RecordUsage adds the entry and byte counts of the records to the stored ones
+func (s *SQLiteStorage) RecordUsage(ctx context.Context, records []models.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO ingestion_usage (day, api_key_id, service_name, entries, bytes)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(day, api_key_id, service_name) DO UPDATE SET
+			entries = ingestion_usage.entries + excluded.entries,
+			bytes = ingestion_usage.bytes + excluded.bytes
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare usage statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		if _, err := stmt.ExecContext(ctx, record.Day, record.APIKeyID, record.ServiceName, record.Entries, record.Bytes); err != nil {
+			return fmt.Errorf("failed to record usage of %s: %w", record.ServiceName, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit usage: %w", err)
+	}
+	return nil
+}
+
+// QueryUsage returns the usage matching the filter, largest volume first, and for daily
+// records most recent day first
+func (s *SQLiteStorage) QueryUsage(ctx context.Context, filter models.UsageFilter) ([]models.UsageRecord, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.StartDay != "" {
+		conditions = append(conditions, "day >= ?")
+		args = append(args, filter.StartDay)
+	}
+	if filter.EndDay != "" {
+		conditions = append(conditions, "day <= ?")
+		args = append(args, filter.EndDay)
+	}
+	if filter.APIKeyID != "" {
+		conditions = append(conditions, "api_key_id = ?")
+		args = append(args, filter.APIKeyID)
+	}
+	if filter.ServiceName != "" {
+		conditions = append(conditions, "service_name = ?")
+		args = append(args, filter.ServiceName)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	day, groupBy, orderBy := "''", "api_key_id, service_name", "SUM(bytes) DESC"
+	if filter.Daily {
+		day, groupBy, orderBy = "day", "day, api_key_id, service_name", "day DESC, SUM(bytes) DESC"
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultUsageLimit
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, api_key_id, service_name, SUM(entries), SUM(bytes)
+		FROM ingestion_usage %s
+		GROUP BY %s
+		ORDER BY %s, api_key_id, service_name
+		LIMIT ?
+	`, day, whereClause, groupBy, orderBy)
+
+	rows, err := s.reader().QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]models.UsageRecord, 0)
+	for rows.Next() {
+		var record models.UsageRecord
+		if err := rows.Scan(&record.Day, &record.APIKeyID, &record.ServiceName, &record.Entries, &record.Bytes); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+
+	return records, nil
+}
+
+// RecordUsage adds the entry and byte counts of the records to the stored ones
+func (s *MemoryStorage) RecordUsage(ctx context.Context, records []models.UsageRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		key := usageKey{record.Day, record.APIKeyID, record.ServiceName}
+		stored := s.usage[key]
+		stored.Day, stored.APIKeyID, stored.ServiceName = record.Day, record.APIKeyID, record.ServiceName
+		stored.Entries += record.Entries
+		stored.Bytes += record.Bytes
+		s.usage[key] = stored
+	}
+	return nil
+}
+
+// QueryUsage returns the usage matching the filter, largest volume first, and for daily
+// records most recent day first
+func (s *MemoryStorage) QueryUsage(ctx context.Context, filter models.UsageFilter) ([]models.UsageRecord, error) {
+	s.mu.RLock()
+	summed := make(map[usageKey]models.UsageRecord)
+	for key, record := range s.usage {
+		if (filter.StartDay != "" && key.day < filter.StartDay) ||
+			(filter.EndDay != "" && key.day > filter.EndDay) ||
+			(filter.APIKeyID != "" && key.apiKeyID != filter.APIKeyID) ||
+			(filter.ServiceName != "" && key.serviceName != filter.ServiceName) {
+			continue
+		}
+		if !filter.Daily {
+			key.day, record.Day = "", ""
+		}
+		sum := summed[key]
+		record.Entries += sum.Entries
+		record.Bytes += sum.Bytes
+		summed[key] = record
+	}
+	s.mu.RUnlock()
+
+	records := make([]models.UsageRecord, 0, len(summed))
+	for _, record := range summed {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		switch {
+		case a.Day != b.Day:
+			return a.Day > b.Day
+		case a.Bytes != b.Bytes:
+			return a.Bytes > b.Bytes
+		case a.APIKeyID != b.APIKeyID:
+			return a.APIKeyID < b.APIKeyID
+		default:
+			return a.ServiceName < b.ServiceName
+		}
+	})
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultUsageLimit
+	}
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestUsageStores(t *testing.T) {
+	sqliteStorage, err := NewSQLiteStorage(filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("Failed to create SQLite storage: %v", err)
+	}
+	defer sqliteStorage.Close()
+
+	memoryStorage := NewMemoryStorage()
+	defer memoryStorage.Close()
+
+	stores := map[string]UsageStore{
+		"sqlite": sqliteStorage,
+		"memory": memoryStorage,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			batches := [][]models.UsageRecord{
+				{
+					{Day: "2024-03-01", APIKeyID: "key-a", ServiceName: "checkout", Entries: 10, Bytes: 1000},
+					{Day: "2024-03-01", APIKeyID: "key-b", ServiceName: "search", Entries: 5, Bytes: 4000},
+				},
+				{
+					{Day: "2024-03-01", APIKeyID: "key-a", ServiceName: "checkout", Entries: 2, Bytes: 200},
+					{Day: "2024-03-02", APIKeyID: "key-a", ServiceName: "checkout", Entries: 30, Bytes: 3000},
+				},
+			}
+			for _, batch := range batches {
+				if err := store.RecordUsage(ctx, batch); err != nil {
+					t.Fatalf("Failed to record usage: %v", err)
+				}
+			}
+
+			// Counts of the same day, key and service add up
+			daily, err := store.QueryUsage(ctx, models.UsageFilter{Daily: true, StartDay: "2024-03-01", EndDay: "2024-03-01"})
+			if err != nil {
+				t.Fatalf("Failed to query usage: %v", err)
+			}
+			if len(daily) != 2 || daily[0].APIKeyID != "key-b" || daily[1].Entries != 12 || daily[1].Bytes != 1200 {
+				t.Errorf("Unexpected daily usage: %+v", daily)
+			}
+
+			// Summed over the days, the checkout key is the noisiest producer
+			totals, err := store.QueryUsage(ctx, models.UsageFilter{})
+			if err != nil {
+				t.Fatalf("Failed to query usage: %v", err)
+			}
+			if len(totals) != 2 {
+				t.Fatalf("Expected 2 records, got %+v", totals)
+			}
+			if totals[0].APIKeyID != "key-a" || totals[0].Day != "" || totals[0].Entries != 42 || totals[0].Bytes != 4200 {
+				t.Errorf("Unexpected total usage: %+v", totals[0])
+			}
+
+			filtered, err := store.QueryUsage(ctx, models.UsageFilter{ServiceName: "search", Limit: 1})
+			if err != nil {
+				t.Fatalf("Failed to query usage: %v", err)
+			}
+			if len(filtered) != 1 || filtered[0].Bytes != 4000 {
+				t.Errorf("Expected only the search usage, got %+v", filtered)
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// Defaults of WALArchivePolicy
+const (
+	DefaultWALArchiveInterval         = 10 * time.Second
+	DefaultWALArchiveSnapshotInterval = 24 * time.Hour
+)
+
+// walCheckpointFrames is the size of the WAL, in frames, above which the archiver checkpoints
+// it, matching the automatic checkpoints of SQLite
+const walCheckpointFrames = 1000
+
+// WAL file layout, see https://www.sqlite.org/fileformat.html#the_write_ahead_log
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	walMagic           = 0x377f0682 // The lowest bit selects big-endian checksums
+)
+
+// WALArchivePolicy configures a WALArchiver
+type WALArchivePolicy struct {
+	Store            ObjectStore
+	Interval         time.Duration // How often committed WAL frames are shipped, 0 uses the default
+	SnapshotInterval time.Duration // How often a new generation starts with a snapshot, 0 uses the default
+	Retention        time.Duration // Generations superseded longer ago than this are deleted, 0 keeps all
+}
+
+// WALArchiver continuously archives a SQLite database for point-in-time recovery with
+// RestoreArchive. The archive is made of generations: each starts with a snapshot of the
+// database file, followed by segments of the write-ahead log holding the transactions
+// committed since.
+//
+// The archiver keeps a read transaction open, so that SQLite cannot checkpoint and restart
+// the WAL before its frames are shipped. It checkpoints the WAL itself, with the write lock
+// held while it reads the last frames.
+type WALArchiver struct {
+	storage *SQLiteStorage
+	policy  WALArchivePolicy
+	now     func() time.Time
+
+	reader *sql.Conn // Holds the read transaction, nil until the first run
+
+	generation string // Empty until the first snapshot
+	snapshotAt time.Time
+	walIndex   int       // Counts the restarts of the WAL within the generation
+	header     walHeader // Of the WAL being shipped, zero salts before a WAL was seen
+	offset     int64     // Position in the WAL file of the first frame not shipped
+	checksum   [2]uint32 // Running checksum of the frames before offset
+
+	pending []archiveObject // Read but not uploaded yet, in order
+}
+
+// archiveObject is a snapshot or WAL segment waiting to be uploaded
+type archiveObject struct {
+	key  string
+	data []byte
+}
+
+// NewWALArchiver creates an archiver, failing if the storage is not a SQLite database file
+func NewWALArchiver(storage LogStorage, policy WALArchivePolicy) (*WALArchiver, error) {
+	sqliteStorage, ok := storage.(*SQLiteStorage)
+	if !ok || sqliteStorage.path == "" {
+		return nil, fmt.Errorf("WAL archiving requires a SQLite database file")
+	}
+	if policy.Store == nil {
+		return nil, fmt.Errorf("WAL archiving requires an object store")
+	}
+
+	if policy.Interval <= 0 {
+		policy.Interval = DefaultWALArchiveInterval
+	}
+	if policy.SnapshotInterval <= 0 {
+		policy.SnapshotInterval = DefaultWALArchiveSnapshotInterval
+	}
+
+	return &WALArchiver{storage: sqliteStorage, policy: policy, now: time.Now}, nil
+}
+
+// Run archives the database until ctx is done, then ships the last committed frames
+func (a *WALArchiver) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Archive(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("WAL archive: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			_, err := a.readWAL()
+			if err == nil {
+				err = a.upload(shutdownCtx)
+			}
+			if err != nil {
+				log.Printf("WAL archive: failed to ship the last frames: %v", err)
+			}
+			if len(a.pending) > 0 {
+				log.Printf("WAL archive: %d objects were not uploaded", len(a.pending))
+			}
+			a.endRead()
+			if a.reader != nil {
+				a.reader.Close()
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Archive ships the frames committed since the last run, and starts a new generation when
+// the snapshot interval has passed. Objects that fail to upload are retried on the next run.
+func (a *WALArchiver) Archive(ctx context.Context) error {
+	if a.reader == nil {
+		reader, err := a.storage.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		a.reader = reader
+		if err := a.beginRead(ctx); err != nil {
+			return err
+		}
+	}
+
+	newGeneration := false
+	if a.generation == "" || a.now().Sub(a.snapshotAt) >= a.policy.SnapshotInterval {
+		if err := a.snapshot(ctx); err != nil {
+			return err
+		}
+		newGeneration = true
+	} else {
+		frames, err := a.readWAL()
+		if err != nil {
+			return err
+		}
+		if frames >= walCheckpointFrames {
+			if _, err := a.checkpoint(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := a.upload(ctx); err != nil {
+		return err
+	}
+	if newGeneration && a.policy.Retention > 0 {
+		return a.prune(ctx)
+	}
+	return nil
+}
+
+// beginRead starts the read transaction of the archiver. Reading a row is needed for SQLite
+// to take the read lock.
+func (a *WALArchiver) beginRead(ctx context.Context) error {
+	_, err := a.reader.ExecContext(ctx, "BEGIN")
+	if err == nil {
+		var count int
+		if err = a.reader.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master").Scan(&count); err != nil {
+			a.endRead()
+		}
+	}
+	if err != nil {
+		// Without the read transaction the WAL may be restarted before its frames are shipped,
+		// so archiving continues with a new generation
+		a.reader.Close()
+		a.reader = nil
+		a.generation = ""
+		return fmt.Errorf("failed to begin read transaction: %w", err)
+	}
+	return nil
+}
+
+// endRead ends the read transaction of the archiver, if there is one
+func (a *WALArchiver) endRead() {
+	if a.reader != nil {
+		a.reader.ExecContext(context.Background(), "ROLLBACK")
+	}
+}
+
+// checkpoint ships the remaining frames and checkpoints the WAL while holding the write lock,
+// so that no transaction commits between the two. The read transaction is renewed, which lets
+// the next writer restart the WAL. It reports whether all frames were checkpointed, which
+// leaves the database file unchanged until the read transaction ends.
+func (a *WALArchiver) checkpoint(ctx context.Context) (bool, error) {
+	writer, err := a.storage.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer writer.Close()
+	if _, err := writer.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		return false, fmt.Errorf("failed to lock the database: %w", err)
+	}
+	defer writer.ExecContext(context.Background(), "ROLLBACK")
+
+	if _, err := a.readWAL(); err != nil {
+		return false, err
+	}
+
+	a.endRead()
+	var busy, logFrames, checkpointed int
+	err = a.reader.QueryRowContext(ctx, "PRAGMA wal_checkpoint(PASSIVE)").Scan(&busy, &logFrames, &checkpointed)
+	if beginErr := a.beginRead(ctx); beginErr != nil {
+		return false, beginErr
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return busy == 0 && checkpointed == logFrames, nil
+}
+
+// snapshot starts a new generation with a copy of the database file, taken after all frames
+// were checkpointed. The generation ships the current WAL again from its first frame: the
+// snapshot already holds these frames, so applying them again is harmless, and the restore
+// never needs frames from before the generation.
+func (a *WALArchiver) snapshot(ctx context.Context) error {
+	complete, err := a.checkpoint(ctx)
+	if err != nil {
+		return err
+	}
+	if !complete {
+		return fmt.Errorf("open transactions kept the WAL from being checkpointed, the snapshot is retried on the next run")
+	}
+
+	data, err := os.ReadFile(a.storage.path)
+	if err != nil {
+		return fmt.Errorf("failed to read database file: %w", err)
+	}
+
+	now := a.now()
+	a.generation = fmt.Sprintf("%016x", now.UnixNano())
+	a.snapshotAt = now
+	a.walIndex = 0
+	a.offset = walHeaderSize
+	a.checksum = a.header.checksum
+	a.pending = append(a.pending, archiveObject{
+		key:  snapshotKey(a.generation),
+		data: zstdEncoder.EncodeAll(data, nil),
+	})
+	return nil
+}
+
+// readWAL queues the frames committed since the last read as a segment of the current
+// generation, and returns the number of frames in the WAL
+func (a *WALArchiver) readWAL() (int, error) {
+	file, err := os.Open(a.storage.path + "-wal")
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	headerData := make([]byte, walHeaderSize)
+	if _, err := io.ReadFull(file, headerData); err != nil {
+		// The WAL is empty until the first transaction after a checkpoint
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	header, err := parseWALHeader(headerData)
+	if err != nil {
+		return 0, err
+	}
+
+	// New salts mean the WAL was restarted after a checkpoint, so its frames start over
+	if header.salt != a.header.salt {
+		a.header = header
+		a.walIndex++
+		a.offset = walHeaderSize
+		a.checksum = header.checksum
+	}
+
+	frames, err := io.ReadAll(io.NewSectionReader(file, a.offset, math.MaxInt64-a.offset))
+	if err != nil {
+		return 0, err
+	}
+	end, checksum := scanWALFrames(header, frames, a.checksum)
+	if end > 0 {
+		if a.generation != "" {
+			segment := append(headerData, frames[:end]...)
+			a.pending = append(a.pending, archiveObject{
+				key:  walSegmentKey(a.generation, a.walIndex, a.offset, a.now()),
+				data: zstdEncoder.EncodeAll(segment, nil),
+			})
+		}
+		a.offset += int64(end)
+		a.checksum = checksum
+	}
+	return int(a.offset-walHeaderSize) / (walFrameHeaderSize + header.pageSize), nil
+}
+
+// upload uploads the pending objects in order, stopping at the first failure
+func (a *WALArchiver) upload(ctx context.Context) error {
+	for len(a.pending) > 0 {
+		object := a.pending[0]
+		if err := a.policy.Store.Put(ctx, object.key, object.data); err != nil {
+			return fmt.Errorf("failed to upload %s, %d objects pending: %w", object.key, len(a.pending), err)
+		}
+		a.pending = a.pending[1:]
+	}
+	return nil
+}
+
+// prune deletes the generations superseded by a newer generation longer ago than the retention
+func (a *WALArchiver) prune(ctx context.Context) error {
+	generations, err := ListArchiveGenerations(ctx, a.policy.Store)
+	if err != nil {
+		return err
+	}
+
+	cutoff := a.now().Add(-a.policy.Retention)
+	for i := 0; i+1 < len(generations); i++ {
+		if generations[i+1].SnapshotAt.After(cutoff) {
+			break
+		}
+		keys, err := a.policy.Store.List(ctx, generationPrefix(generations[i].Name))
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := a.policy.Store.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete %s: %w", key, err)
+			}
+		}
+		log.Printf("WAL archive: deleted generation %s", generations[i].Name)
+	}
+	return nil
+}
+
+// walHeader is the header of a WAL file. Frames belong to the WAL while their salts match.
+type walHeader struct {
+	pageSize  int
+	salt      [2]uint32
+	checksum  [2]uint32
+	bigEndian bool
+}
+
+// parseWALHeader parses and verifies a WAL header
+func parseWALHeader(data []byte) (walHeader, error) {
+	if len(data) < walHeaderSize {
+		return walHeader{}, fmt.Errorf("WAL header is truncated")
+	}
+	magic := binary.BigEndian.Uint32(data)
+	if magic&^1 != walMagic {
+		return walHeader{}, fmt.Errorf("invalid WAL header magic %08x", magic)
+	}
+
+	header := walHeader{
+		pageSize:  int(binary.BigEndian.Uint32(data[8:])),
+		salt:      [2]uint32{binary.BigEndian.Uint32(data[16:]), binary.BigEndian.Uint32(data[20:])},
+		bigEndian: magic&1 == 1,
+	}
+	header.checksum = walChecksum(header.bigEndian, [2]uint32{}, data[:24])
+	if header.checksum != [2]uint32{binary.BigEndian.Uint32(data[24:]), binary.BigEndian.Uint32(data[28:])} {
+		return walHeader{}, fmt.Errorf("WAL header checksum mismatch")
+	}
+	return header, nil
+}
+
+// walChecksum continues the running checksum of a WAL over data
+func walChecksum(bigEndian bool, checksum [2]uint32, data []byte) [2]uint32 {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(data); i += 8 {
+		checksum[0] += order.Uint32(data[i:]) + checksum[1]
+		checksum[1] += order.Uint32(data[i+4:]) + checksum[0]
+	}
+	return checksum
+}
+
+// scanWALFrames returns the length of the frames up to the last commit frame, and the running
+// checksum there, given the running checksum before the first frame. Frames of uncommitted
+// transactions, frames being written and frames left over from before the WAL was restarted
+// are not included.
+func scanWALFrames(header walHeader, frames []byte, checksum [2]uint32) (int, [2]uint32) {
+	frameSize := walFrameHeaderSize + header.pageSize
+	end, endChecksum := 0, checksum
+	for offset := 0; offset+frameSize <= len(frames); offset += frameSize {
+		frame := frames[offset : offset+frameSize]
+		if binary.BigEndian.Uint32(frame[8:]) != header.salt[0] || binary.BigEndian.Uint32(frame[12:]) != header.salt[1] {
+			break
+		}
+		checksum = walChecksum(header.bigEndian, checksum, frame[:8])
+		checksum = walChecksum(header.bigEndian, checksum, frame[walFrameHeaderSize:])
+		if checksum != [2]uint32{binary.BigEndian.Uint32(frame[16:]), binary.BigEndian.Uint32(frame[20:])} {
+			break
+		}
+		// Commit frames hold the size of the database after the transaction
+		if binary.BigEndian.Uint32(frame[4:]) != 0 {
+			end, endChecksum = offset+frameSize, checksum
+		}
+	}
+	return end, endChecksum
+}
+
+// databasePath returns the file of a SQLite connection string, empty for in-memory databases
+func databasePath(connectionString string) string {
+	if isInMemory(connectionString) {
+		return ""
+	}
+	path := strings.TrimPrefix(connectionString, "file:")
+	path, _, _ = strings.Cut(path, "?")
+	return path
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func storeArchiveTestLogs(t *testing.T, storage *SQLiteStorage, batch string, count int) {
+	entries := make([]models.LogEntry, count)
+	for i := range entries {
+		entries[i] = newReplicaTestLog(fmt.Sprintf("%s entry %d", batch, i), time.Now())
+	}
+	if err := storage.Store(context.Background(), entries); err != nil {
+		t.Fatalf("Failed to store logs: %v", err)
+	}
+}
+
+func countRestoredLogs(t *testing.T, path string) int {
+	restored, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+	result, err := restored.Query(context.Background(), models.LogFilter{Limit: 1})
+	if err != nil {
+		t.Fatalf("Failed to query restored database: %v", err)
+	}
+	return result.TotalCount
+}
+
+func TestWALArchiver_PointInTimeRestore(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	storage, err := NewSQLiteStorage(filepath.Join(dir, "logs.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	store, err := NewDirObjectStore(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("Failed to create object store: %v", err)
+	}
+	archiver, err := NewWALArchiver(storage, WALArchivePolicy{Store: store})
+	if err != nil {
+		t.Fatalf("Failed to create archiver: %v", err)
+	}
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	archiver.now = func() time.Time { return clock }
+	archive := func() {
+		t.Helper()
+		clock = clock.Add(time.Minute)
+		if err := archiver.Archive(ctx); err != nil {
+			t.Fatalf("Failed to archive: %v", err)
+		}
+	}
+
+	// The first run takes the snapshot, later runs ship the WAL
+	storeArchiveTestLogs(t, storage, "snapshot", 10)
+	archive()
+	storeArchiveTestLogs(t, storage, "first", 5)
+	archive()
+
+	// Frames shipped before a checkpoint and from the restarted WAL are both restored
+	if _, err := archiver.checkpoint(ctx); err != nil {
+		t.Fatalf("Failed to checkpoint: %v", err)
+	}
+	storeArchiveTestLogs(t, storage, "second", 5)
+	archive()
+	beforeLast := clock
+	storeArchiveTestLogs(t, storage, "third", 5)
+	archive()
+	if archiver.walIndex < 2 {
+		t.Errorf("Expected the WAL to be restarted after the checkpoint, at index %d", archiver.walIndex)
+	}
+
+	generations, err := ListArchiveGenerations(ctx, store)
+	if err != nil || len(generations) != 1 || generations[0].Segments == 0 {
+		t.Fatalf("Expected one generation with segments, got %+v (%v)", generations, err)
+	}
+
+	latest := filepath.Join(dir, "latest.db")
+	result, err := RestoreArchive(ctx, store, latest, time.Time{})
+	if err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if count := countRestoredLogs(t, latest); count != 25 {
+		t.Errorf("Expected all 25 entries to be restored, got %d", count)
+	}
+	if !result.RestoredTo.Equal(clock) {
+		t.Errorf("Expected the restore to reach %v, got %v", clock, result.RestoredTo)
+	}
+
+	pointInTime := filepath.Join(dir, "point-in-time.db")
+	if _, err := RestoreArchive(ctx, store, pointInTime, beforeLast); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if count := countRestoredLogs(t, pointInTime); count != 20 {
+		t.Errorf("Expected the 20 entries archived by %v, got %d", beforeLast, count)
+	}
+
+	if _, err := RestoreArchive(ctx, store, latest, time.Time{}); err == nil {
+		t.Error("Expected restoring over an existing file to fail")
+	}
+	if _, err := RestoreArchive(ctx, store, filepath.Join(dir, "early.db"), clock.Add(-time.Hour)); err == nil {
+		t.Error("Expected restoring before the first snapshot to fail")
+	}
+}
+
+func TestWALArchiver_NewGenerationPrunesOld(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	storage, err := NewSQLiteStorage(filepath.Join(dir, "logs.db"))
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	store, err := NewDirObjectStore(filepath.Join(dir, "archive"))
+	if err != nil {
+		t.Fatalf("Failed to create object store: %v", err)
+	}
+	archiver, err := NewWALArchiver(storage, WALArchivePolicy{Store: store, SnapshotInterval: time.Hour, Retention: 90 * time.Minute})
+	if err != nil {
+		t.Fatalf("Failed to create archiver: %v", err)
+	}
+	clock := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	archiver.now = func() time.Time { return clock }
+
+	for i := 0; i < 4; i++ {
+		storeArchiveTestLogs(t, storage, fmt.Sprintf("hour %d", i), 3)
+		if err := archiver.Archive(ctx); err != nil {
+			t.Fatalf("Failed to archive: %v", err)
+		}
+		clock = clock.Add(time.Hour)
+	}
+
+	// Only the first generation was superseded more than 90 minutes ago
+	generations, err := ListArchiveGenerations(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(generations) != 3 || !generations[0].SnapshotAt.Equal(time.Date(2024, 5, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the 3 newest generations to be kept, got %+v", generations)
+	}
+
+	restored := filepath.Join(dir, "restored.db")
+	if _, err := RestoreArchive(ctx, store, restored, time.Time{}); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if count := countRestoredLogs(t, restored); count != 12 {
+		t.Errorf("Expected all 12 entries to be restored, got %d", count)
+	}
+}
+
+func TestNewWALArchiver_InMemoryDatabase(t *testing.T) {
+	storage, err := NewSQLiteStorage(":memory:")
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer storage.Close()
+
+	store, _ := NewDirObjectStore(t.TempDir())
+	if _, err := NewWALArchiver(storage, WALArchivePolicy{Store: store}); err == nil {
+		t.Error("Expected archiving an in-memory database to fail")
+	}
+}
+
+func TestS3ObjectStore(t *testing.T) {
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			http.Error(w, "unsigned request", http.StatusForbidden)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, "/backups/")
+		switch {
+		case r.Method == http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[key] = string(data)
+		case r.Method == http.MethodGet && r.URL.Path == "/backups":
+			prefix := r.URL.Query().Get("prefix")
+			fmt.Fprint(w, "<ListBucketResult>")
+			for name := range objects {
+				if strings.HasPrefix(name, prefix) {
+					fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", name)
+				}
+			}
+			fmt.Fprint(w, "<IsTruncated>false</IsTruncated></ListBucketResult>")
+		case r.Method == http.MethodGet:
+			data, ok := objects[key]
+			if !ok {
+				http.Error(w, "NoSuchKey", http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, data)
+		case r.Method == http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store, err := OpenObjectStore("s3://backups/logs", S3Config{
+		Region:          "eu-west-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Failed to open object store: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "generations/1/snapshot.db.zst", []byte("snapshot")); err != nil {
+		t.Fatalf("Failed to put object: %v", err)
+	}
+	if _, ok := objects["logs/generations/1/snapshot.db.zst"]; !ok {
+		t.Fatalf("Expected the object under the prefix, got %v", objects)
+	}
+
+	keys, err := store.List(ctx, "generations/")
+	if err != nil || len(keys) != 1 || keys[0] != "generations/1/snapshot.db.zst" {
+		t.Errorf("Expected the key without the prefix, got %v (%v)", keys, err)
+	}
+	if data, err := store.Get(ctx, "generations/1/snapshot.db.zst"); err != nil || string(data) != "snapshot" {
+		t.Errorf("Expected the object contents, got %q (%v)", data, err)
+	}
+	if err := store.Delete(ctx, "generations/1/snapshot.db.zst"); err != nil {
+		t.Fatalf("Failed to delete object: %v", err)
+	}
+	if _, err := store.Get(ctx, "generations/1/snapshot.db.zst"); err != ErrObjectNotFound {
+		t.Errorf("Expected a deleted object to be missing, got %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Keys of WAL archive objects. Generations, WAL indexes and offsets are zero-padded, so that
+// keys sort in the order objects were written.
+const (
+	archiveGenerationsPrefix = "generations/"
+	archiveSnapshotName      = "snapshot.db.zst"
+	archiveWALPrefix         = "wal/"
+	archiveWALSuffix         = ".wal.zst"
+)
+
+// ArchiveGeneration describes a generation of a WAL archive
+type ArchiveGeneration struct {
+	Name          string
+	SnapshotAt    time.Time
+	Segments      int
+	LastSegmentAt time.Time // Zero for generations without segments
+}
+
+// RestoreResult describes the database rebuilt by RestoreArchive
+type RestoreResult struct {
+	Generation string
+	Segments   int       // WAL segments applied to the snapshot
+	RestoredTo time.Time // When the last applied segment was shipped, or the snapshot taken
+}
+
+// walSegment is a WAL segment object, the header of a WAL followed by frames of it
+type walSegment struct {
+	key      string
+	walIndex int
+	offset   int64 // Of the first frame in the WAL file
+	shipped  time.Time
+}
+
+// generationPrefix returns the prefix of the keys of a generation
+func generationPrefix(generation string) string {
+	return archiveGenerationsPrefix + generation + "/"
+}
+
+// snapshotKey returns the key of the snapshot of a generation
+func snapshotKey(generation string) string {
+	return generationPrefix(generation) + archiveSnapshotName
+}
+
+// walSegmentKey returns the key of a WAL segment
+func walSegmentKey(generation string, walIndex int, offset int64, shipped time.Time) string {
+	return fmt.Sprintf("%s%s%08d-%016x-%019d%s", generationPrefix(generation), archiveWALPrefix, walIndex, offset, shipped.UnixNano(), archiveWALSuffix)
+}
+
+// parseWALSegmentKey parses the name of a WAL segment, the part of its key after the WAL prefix
+func parseWALSegmentKey(key, name string) (walSegment, bool) {
+	parts := strings.Split(strings.TrimSuffix(name, archiveWALSuffix), "-")
+	if len(parts) != 3 || !strings.HasSuffix(name, archiveWALSuffix) {
+		return walSegment{}, false
+	}
+	walIndex, err1 := strconv.Atoi(parts[0])
+	offset, err2 := strconv.ParseInt(parts[1], 16, 64)
+	shipped, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return walSegment{}, false
+	}
+	return walSegment{key: key, walIndex: walIndex, offset: offset, shipped: time.Unix(0, shipped)}, true
+}
+
+// parseGenerationTime returns when the snapshot of a generation was taken
+func parseGenerationTime(generation string) (time.Time, bool) {
+	nanos, err := strconv.ParseInt(generation, 16, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// archiveContents returns the generations with a snapshot, oldest first, and their segments
+func archiveContents(ctx context.Context, store ObjectStore) ([]ArchiveGeneration, map[string][]walSegment, error) {
+	keys, err := store.List(ctx, archiveGenerationsPrefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list archive: %w", err)
+	}
+
+	snapshots := make(map[string]bool)
+	segments := make(map[string][]walSegment)
+	for _, key := range keys {
+		generation, name, ok := strings.Cut(strings.TrimPrefix(key, archiveGenerationsPrefix), "/")
+		if !ok {
+			continue
+		}
+		if name == archiveSnapshotName {
+			snapshots[generation] = true
+		} else if segment, ok := parseWALSegmentKey(key, strings.TrimPrefix(name, archiveWALPrefix)); ok {
+			segments[generation] = append(segments[generation], segment)
+		}
+	}
+
+	var generations []ArchiveGeneration
+	for name := range snapshots {
+		snapshotAt, ok := parseGenerationTime(name)
+		if !ok {
+			continue
+		}
+		generation := ArchiveGeneration{Name: name, SnapshotAt: snapshotAt, Segments: len(segments[name])}
+		if n := len(segments[name]); n > 0 {
+			generation.LastSegmentAt = segments[name][n-1].shipped
+		}
+		generations = append(generations, generation)
+	}
+	sort.Slice(generations, func(i, j int) bool {
+		return generations[i].Name < generations[j].Name
+	})
+	return generations, segments, nil
+}
+
+// ListArchiveGenerations returns the generations of a WAL archive, oldest first
+func ListArchiveGenerations(ctx context.Context, store ObjectStore) ([]ArchiveGeneration, error) {
+	generations, _, err := archiveContents(ctx, store)
+	return generations, err
+}
+
+// RestoreArchive rebuilds a SQLite database from a WAL archive into a new file at path. The
+// database is restored as of until, from the newest generation whose snapshot was taken by
+// then and the WAL segments shipped by then; zero restores the newest state in the archive.
+func RestoreArchive(ctx context.Context, store ObjectStore, path string, until time.Time) (*RestoreResult, error) {
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("%s already exists, restore into a new file", path)
+	}
+
+	generations, segments, err := archiveContents(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	var generation *ArchiveGeneration
+	for i := range generations {
+		if until.IsZero() || !generations[i].SnapshotAt.After(until) {
+			generation = &generations[i]
+		}
+	}
+	if generation == nil {
+		return nil, fmt.Errorf("archive has no snapshot taken by %s", until.Format(time.RFC3339))
+	}
+
+	snapshot, err := store.Get(ctx, snapshotKey(generation.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	data, err := zstdDecoder.DecodeAll(snapshot, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+
+	result := &RestoreResult{Generation: generation.Name, RestoredTo: generation.SnapshotAt}
+	if err := applyWALSegments(ctx, store, path, segments[generation.Name], until, result); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	if err := checkRestoredDatabase(path); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyWALSegments applies the segments shipped by until to the database at path, one WAL at a
+// time. The frames of a WAL are reassembled into a WAL file next to the database, which SQLite
+// recovers when the database is opened and then checkpoints.
+func applyWALSegments(ctx context.Context, store ObjectStore, path string, segments []walSegment, until time.Time, result *RestoreResult) error {
+	var (
+		wal      []byte
+		walIndex = -1
+	)
+	for _, segment := range segments {
+		if !until.IsZero() && segment.shipped.After(until) {
+			break
+		}
+
+		compressed, err := store.Get(ctx, segment.key)
+		if err != nil {
+			return fmt.Errorf("failed to download %s: %w", segment.key, err)
+		}
+		data, err := zstdDecoder.DecodeAll(compressed, nil)
+		if err != nil || len(data) < walHeaderSize {
+			return fmt.Errorf("invalid WAL segment %s", segment.key)
+		}
+
+		if segment.walIndex != walIndex {
+			if err := applyWAL(path, wal); err != nil {
+				return err
+			}
+			if segment.offset != walHeaderSize {
+				return fmt.Errorf("archive is missing the start of WAL %d of generation %s", segment.walIndex, result.Generation)
+			}
+			wal = append([]byte(nil), data[:walHeaderSize]...)
+			walIndex = segment.walIndex
+		}
+		if segment.offset != int64(len(wal)) || !bytes.Equal(data[:walHeaderSize], wal[:walHeaderSize]) {
+			return fmt.Errorf("archive is missing frames of WAL %d before %s", walIndex, segment.key)
+		}
+
+		wal = append(wal, data[walHeaderSize:]...)
+		result.Segments++
+		result.RestoredTo = segment.shipped
+	}
+	return applyWAL(path, wal)
+}
+
+// applyWAL checkpoints the frames of a WAL file into the database at path
+func applyWAL(path string, wal []byte) error {
+	if len(wal) == 0 {
+		return nil
+	}
+	os.Remove(path + "-shm")
+	if err := os.WriteFile(path+"-wal", wal, 0600); err != nil {
+		return err
+	}
+
+	db, err := sql.Open(sqliteDriver, sqliteDSN(path))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Closing the last connection removes the WAL file once it is checkpointed
+	var busy, logFrames, checkpointed int
+	if err := db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logFrames, &checkpointed); err != nil {
+		return fmt.Errorf("failed to apply WAL: %w", err)
+	}
+	if busy != 0 {
+		return errors.New("failed to apply WAL: database is busy")
+	}
+	return nil
+}
+
+// checkRestoredDatabase verifies the integrity of a restored database
+func checkRestoredDatabase(path string) error {
+	db, err := sql.Open(sqliteDriver, sqliteDSN(path))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var status string
+	if err := db.QueryRow("PRAGMA quick_check").Scan(&status); err != nil {
+		return fmt.Errorf("failed to check restored database: %w", err)
+	}
+	if status != "ok" {
+		return fmt.Errorf("restored database is corrupt: %s", status)
+	}
+	return nil
+}
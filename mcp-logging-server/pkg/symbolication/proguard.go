@@ -0,0 +1,190 @@
+package symbolication
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// javaFramePattern matches a Java or Kotlin stack frame, e.g. "at a.b.c(SourceFile:12)"
+	javaFramePattern = regexp.MustCompile(`^(\s*at )([\w$.]+)\.([\w$<>-]+)\(([^:)]*)(?::(\d+))?\)(.*)$`)
+
+	// javaExceptionPattern matches the exception class at the start of a stack trace or cause
+	javaExceptionPattern = regexp.MustCompile(`^(\s*(?:Caused by: )?)([\w$]+(?:\.[\w$]+)*)(:.*)?$`)
+
+	// memberRangePattern matches the line range prefix of a method mapping, e.g. "12:15:"
+	memberRangePattern = regexp.MustCompile(`^(\d+):(\d+):`)
+
+	// originalRangePattern matches the original line range suffix of an R8 method mapping
+	originalRangePattern = regexp.MustCompile(`:(\d+)(?::(\d+))?$`)
+)
+
+// ProGuardMapping is a parsed ProGuard or R8 mapping file of an obfuscated build
+type ProGuardMapping struct {
+	classes map[string]*proguardClass // By obfuscated name
+}
+
+// proguardClass is the mapping of one obfuscated class
+type proguardClass struct {
+	original   string
+	sourceFile string
+	methods    map[string][]proguardMethod // By obfuscated name
+}
+
+// proguardMethod maps an obfuscated method, and for R8 inlined frames, a range of its lines
+type proguardMethod struct {
+	original      string
+	startLine     int // Obfuscated line range, 0 if the mapping has none
+	endLine       int
+	originalStart int // Original first line, 0 if the line numbers were kept
+}
+
+// ParseProGuardMapping parses a ProGuard or R8 mapping file
+func ParseProGuardMapping(data []byte) (*ProGuardMapping, error) {
+	mapping := &ProGuardMapping{classes: make(map[string]*proguardClass)}
+	var current *proguardClass
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			// R8 records the source file of a class in a JSON comment after the class
+			if current != nil && strings.Contains(trimmed, `"sourceFile"`) {
+				var meta struct {
+					ID       string `json:"id"`
+					FileName string `json:"fileName"`
+				}
+				if json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))), &meta) == nil && meta.ID == "sourceFile" {
+					current.sourceFile = meta.FileName
+				}
+			}
+		case line[0] != ' ' && line[0] != '\t':
+			original, obfuscated, ok := strings.Cut(strings.TrimSuffix(trimmed, ":"), " -> ")
+			if !ok || !strings.HasSuffix(trimmed, ":") {
+				return nil, fmt.Errorf("invalid class mapping in line %d", lineNumber)
+			}
+			current = &proguardClass{original: original, methods: make(map[string][]proguardMethod)}
+			mapping.classes[obfuscated] = current
+		default:
+			if current == nil {
+				return nil, fmt.Errorf("member mapping outside of a class in line %d", lineNumber)
+			}
+			member, obfuscated, ok := strings.Cut(trimmed, " -> ")
+			if !ok {
+				return nil, fmt.Errorf("invalid member mapping in line %d", lineNumber)
+			}
+			// Fields have no parameter list and do not appear in stack traces
+			if strings.Contains(member, "(") {
+				current.methods[obfuscated] = append(current.methods[obfuscated], parseProGuardMethod(member))
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read mapping: %w", err)
+	}
+	if len(mapping.classes) == 0 {
+		return nil, fmt.Errorf("mapping contains no classes")
+	}
+	return mapping, nil
+}
+
+// parseProGuardMethod parses the original side of a method mapping,
+// e.g. "12:15:void load(java.lang.String):40:43"
+func parseProGuardMethod(member string) proguardMethod {
+	var method proguardMethod
+
+	if match := memberRangePattern.FindStringSubmatch(member); match != nil {
+		method.startLine, _ = strconv.Atoi(match[1])
+		method.endLine, _ = strconv.Atoi(match[2])
+		member = member[len(match[0]):]
+	}
+
+	closing := strings.LastIndex(member, ")")
+	if match := originalRangePattern.FindStringSubmatch(member[closing+1:]); match != nil {
+		method.originalStart, _ = strconv.Atoi(match[1])
+	}
+
+	// Drop the return type and parameters, keeping the name
+	signature := member[:strings.Index(member, "(")]
+	method.original = signature[strings.LastIndex(signature, " ")+1:]
+	return method
+}
+
+// ClassName returns the original name of an obfuscated class
+func (m *ProGuardMapping) ClassName(obfuscated string) (string, bool) {
+	class, ok := m.classes[obfuscated]
+	if !ok {
+		return "", false
+	}
+	return class.original, true
+}
+
+// rewriteJavaLine replaces the obfuscated class, method and line of a stack frame, or the
+// exception class of a stack trace header, with the original names
+func (m *ProGuardMapping) rewriteJavaLine(line string) string {
+	if match := javaFramePattern.FindStringSubmatch(line); match != nil {
+		class, ok := m.classes[match[2]]
+		if !ok {
+			return line
+		}
+
+		lineNumber, _ := strconv.Atoi(match[5])
+		methodName := match[3]
+		if method, ok := class.findMethod(methodName, lineNumber); ok {
+			methodName = method.original
+			if method.originalStart > 0 && lineNumber > 0 {
+				lineNumber = method.originalStart + lineNumber - method.startLine
+			}
+		}
+
+		// Inlined methods are mapped with the class they came from, whose source file is unknown
+		className, location := class.original, match[4]
+		if i := strings.LastIndex(methodName, "."); i >= 0 {
+			className, methodName = methodName[:i], methodName[i+1:]
+		} else if class.sourceFile != "" {
+			location = class.sourceFile
+		}
+		if match[5] != "" {
+			location += ":" + strconv.Itoa(lineNumber)
+		}
+		return fmt.Sprintf("%s%s.%s(%s)%s", match[1], className, methodName, location, match[6])
+	}
+
+	if match := javaExceptionPattern.FindStringSubmatch(line); match != nil {
+		if original, ok := m.ClassName(match[2]); ok {
+			return match[1] + original + match[3]
+		}
+	}
+	return line
+}
+
+// findMethod returns the mapping of an obfuscated method, the one covering the line if the
+// mapping has line ranges
+func (c *proguardClass) findMethod(name string, line int) (proguardMethod, bool) {
+	candidates := c.methods[name]
+	if len(candidates) == 0 {
+		return proguardMethod{}, false
+	}
+
+	if line > 0 {
+		for _, method := range candidates {
+			if method.startLine > 0 && line >= method.startLine && line <= method.endLine {
+				return method, true
+			}
+		}
+	}
+	return candidates[0], true
+}
@@ -0,0 +1,60 @@
+package symbolication
+
+import (
+	"testing"
+)
+
+const testProGuardMapping = `# compiler: R8
+com.example.checkout.CartViewModel -> a.b.c:
+# {"id":"sourceFile","fileName":"CartViewModel.kt"}
+    java.util.List items -> a
+    1:4:void addItem(com.example.checkout.Item):25:28 -> a
+    5:9:void checkout():40:44 -> b
+    10:10:void com.example.checkout.PriceCalculator.total():12:12 -> b
+    void clear() -> c
+com.example.checkout.CheckoutException -> a.b.d:
+`
+
+func TestProGuardMapping_RewriteJavaLine(t *testing.T) {
+	mapping, err := ParseProGuardMapping([]byte(testProGuardMapping))
+	if err != nil {
+		t.Fatalf("Failed to parse mapping: %v", err)
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "Caused by: a.b.d: Cart is empty", want: "Caused by: com.example.checkout.CheckoutException: Cart is empty"},
+		{line: "a.b.d", want: "com.example.checkout.CheckoutException"},
+		{line: "\tat a.b.c.a(SourceFile:2)", want: "\tat com.example.checkout.CartViewModel.addItem(CartViewModel.kt:26)"},
+		{line: "\tat a.b.c.b(SourceFile:7)", want: "\tat com.example.checkout.CartViewModel.checkout(CartViewModel.kt:42)"},
+		{line: "\tat a.b.c.b(SourceFile:10)", want: "\tat com.example.checkout.PriceCalculator.total(SourceFile:12)"},
+		{line: "\tat a.b.c.c(Unknown Source)", want: "\tat com.example.checkout.CartViewModel.clear(CartViewModel.kt)"},
+		{line: "\tat android.os.Handler.dispatchMessage(Handler.java:106)", want: "\tat android.os.Handler.dispatchMessage(Handler.java:106)"},
+		{line: "\t... 12 more", want: "\t... 12 more"},
+	}
+
+	for _, tt := range tests {
+		if got := mapping.rewriteJavaLine(tt.line); got != tt.want {
+			t.Errorf("rewriteJavaLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseProGuardMapping_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "# nothing here\n",
+		"member first":   "    void run() -> a\n",
+		"invalid class":  "com.example.Cart a.b:\n",
+		"invalid member": "com.example.Cart -> a.b:\n    void run()\n",
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseProGuardMapping([]byte(data)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
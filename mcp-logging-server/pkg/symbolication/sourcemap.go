@@ -0,0 +1,219 @@
+package symbolication
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// base64Values maps the characters of the base64 VLQ alphabet to their values, -1 for others
+var base64Values = func() [256]int {
+	var values [256]int
+	for i := range values {
+		values[i] = -1
+	}
+	for i, char := range "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/" {
+		values[char] = i
+	}
+	return values
+}()
+
+// jsLocationPattern matches the file:line:column locations of JavaScript stack frames in the
+// V8 and Hermes ("at fn (file:1:2)") as well as the JavaScriptCore and Firefox ("fn@file:1:2") formats
+var jsLocationPattern = regexp.MustCompile(`([^\s()@]+):(\d+):(\d+)`)
+
+// Position is an original source position resolved through a source map
+type Position struct {
+	Source string
+	Line   int // 1-based
+	Column int // 1-based
+	Name   string
+}
+
+// SourceMap is a parsed source map v3 of a JavaScript bundle
+type SourceMap struct {
+	sources []string
+	names   []string
+	lines   [][]mapping // Segments of each generated line, by generated column
+}
+
+// mapping is one decoded segment of a source map
+type mapping struct {
+	generatedColumn int
+	source          int // -1 for segments without an original position
+	line            int
+	column          int
+	name            int // -1 for segments without a name
+}
+
+// ParseSourceMap parses a source map v3. Indexed source maps with sections are not supported.
+func ParseSourceMap(data []byte) (*SourceMap, error) {
+	var raw struct {
+		Version    int               `json:"version"`
+		SourceRoot string            `json:"sourceRoot"`
+		Sources    []string          `json:"sources"`
+		Names      []string          `json:"names"`
+		Mappings   string            `json:"mappings"`
+		Sections   []json.RawMessage `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+	if len(raw.Sections) > 0 {
+		return nil, fmt.Errorf("indexed source maps are not supported")
+	}
+
+	sourceMap := &SourceMap{
+		sources: raw.Sources,
+		names:   raw.Names,
+	}
+	if raw.SourceRoot != "" {
+		root := strings.TrimSuffix(raw.SourceRoot, "/") + "/"
+		for i, source := range sourceMap.sources {
+			sourceMap.sources[i] = root + source
+		}
+	}
+
+	// Apart from the generated column, the fields are relative to the previous segment of the map
+	var source, line, column, name int
+	for _, encodedLine := range strings.Split(raw.Mappings, ";") {
+		var segments []mapping
+		generatedColumn := 0
+
+		for _, encoded := range strings.Split(encodedLine, ",") {
+			if encoded == "" {
+				continue
+			}
+			fields, err := decodeVLQ(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid mappings in line %d: %w", len(sourceMap.lines)+1, err)
+			}
+
+			generatedColumn += fields[0]
+			segment := mapping{generatedColumn: generatedColumn, source: -1, name: -1}
+			switch len(fields) {
+			case 1:
+			case 4, 5:
+				source += fields[1]
+				line += fields[2]
+				column += fields[3]
+				if source < 0 || source >= len(sourceMap.sources) {
+					return nil, fmt.Errorf("invalid mappings in line %d: source %d out of range", len(sourceMap.lines)+1, source)
+				}
+				segment.source, segment.line, segment.column = source, line, column
+				if len(fields) == 5 {
+					name += fields[4]
+					if name >= 0 && name < len(sourceMap.names) {
+						segment.name = name
+					}
+				}
+			default:
+				return nil, fmt.Errorf("invalid mappings in line %d: segment with %d fields", len(sourceMap.lines)+1, len(fields))
+			}
+			segments = append(segments, segment)
+		}
+
+		sort.SliceStable(segments, func(i, j int) bool {
+			return segments[i].generatedColumn < segments[j].generatedColumn
+		})
+		sourceMap.lines = append(sourceMap.lines, segments)
+	}
+
+	return sourceMap, nil
+}
+
+// Lookup resolves a 1-based generated line and column to the original position
+func (m *SourceMap) Lookup(line, column int) (Position, bool) {
+	if line < 1 || line > len(m.lines) {
+		return Position{}, false
+	}
+
+	// The segment covering the column is the last one starting at or before it
+	segments := m.lines[line-1]
+	i := sort.Search(len(segments), func(i int) bool {
+		return segments[i].generatedColumn > column-1
+	})
+	if i == 0 || segments[i-1].source < 0 {
+		return Position{}, false
+	}
+
+	segment := segments[i-1]
+	position := Position{
+		Source: m.sources[segment.source],
+		Line:   segment.line + 1,
+		Column: segment.column + 1,
+	}
+	if segment.name >= 0 {
+		position.Name = m.names[segment.name]
+	}
+	return position, true
+}
+
+// decodeVLQ decodes the base64 VLQ fields of a source map segment
+func decodeVLQ(encoded string) ([]int, error) {
+	var fields []int
+	value, shift := 0, 0
+
+	for i := 0; i < len(encoded); i++ {
+		digit := base64Values[encoded[i]]
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid character %q", encoded[i])
+		}
+
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			if shift > 30 {
+				return nil, fmt.Errorf("value out of range")
+			}
+			continue
+		}
+
+		// The lowest bit is the sign
+		if value&1 != 0 {
+			fields = append(fields, -(value >> 1))
+		} else {
+			fields = append(fields, value>>1)
+		}
+		value, shift = 0, 0
+	}
+
+	if shift != 0 {
+		return nil, fmt.Errorf("truncated value")
+	}
+	return fields, nil
+}
+
+// bundleName returns the file name of a bundle location, without directories, query or fragment
+func bundleName(location string) string {
+	if i := strings.IndexAny(location, "?#"); i >= 0 {
+		location = location[:i]
+	}
+	return location[strings.LastIndex(location, "/")+1:]
+}
+
+// rewriteJavaScriptLine replaces the bundle locations in a stack trace line with the original
+// positions, using the source map of the bundle returned by sourceMaps
+func rewriteJavaScriptLine(line string, sourceMaps func(bundle string) *SourceMap) string {
+	return jsLocationPattern.ReplaceAllStringFunc(line, func(location string) string {
+		parts := jsLocationPattern.FindStringSubmatch(location)
+		sourceMap := sourceMaps(bundleName(parts[1]))
+		if sourceMap == nil {
+			return location
+		}
+
+		generatedLine, _ := strconv.Atoi(parts[2])
+		generatedColumn, _ := strconv.Atoi(parts[3])
+		position, ok := sourceMap.Lookup(generatedLine, generatedColumn)
+		if !ok {
+			return location
+		}
+		return fmt.Sprintf("%s:%d:%d", position.Source, position.Line, position.Column)
+	})
+}
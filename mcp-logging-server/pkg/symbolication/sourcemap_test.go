@@ -0,0 +1,136 @@
+package symbolication
+
+import (
+	"testing"
+)
+
+// testSourceMap maps main.jsbundle, whose first line holds cart.tsx and whose second line holds
+// checkout.tsx:
+//
+//	line 1, column 1  -> src/cart.tsx:1:1
+//	line 1, column 6  -> src/cart.tsx:1:6 (addItem)
+//	line 1, column 8  -> src/cart.tsx:2:8
+//	line 2, column 1  -> src/checkout.tsx:3:8 (pay)
+const testSourceMap = `{
+	"version": 3,
+	"file": "main.jsbundle",
+	"sourceRoot": "src",
+	"sources": ["cart.tsx", "checkout.tsx"],
+	"names": ["addItem", "pay"],
+	"mappings": "AAAA,KAAKA,EACE;ACCAC"
+}`
+
+func TestDecodeVLQ(t *testing.T) {
+	tests := []struct {
+		encoded string
+		want    []int
+	}{
+		{encoded: "A", want: []int{0}},
+		{encoded: "C", want: []int{1}},
+		{encoded: "D", want: []int{-1}},
+		{encoded: "gB", want: []int{16}},
+		{encoded: "2H", want: []int{123}},
+		{encoded: "AAgBC", want: []int{0, 0, 16, 1}},
+	}
+
+	for _, tt := range tests {
+		got, err := decodeVLQ(tt.encoded)
+		if err != nil {
+			t.Errorf("decodeVLQ(%q) failed: %v", tt.encoded, err)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("decodeVLQ(%q) = %v, want %v", tt.encoded, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("decodeVLQ(%q) = %v, want %v", tt.encoded, got, tt.want)
+				break
+			}
+		}
+	}
+
+	for _, invalid := range []string{"g", "A!"} {
+		if _, err := decodeVLQ(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestSourceMap_Lookup(t *testing.T) {
+	sourceMap, err := ParseSourceMap([]byte(testSourceMap))
+	if err != nil {
+		t.Fatalf("Failed to parse source map: %v", err)
+	}
+
+	tests := []struct {
+		line, column int
+		want         Position
+		found        bool
+	}{
+		{line: 1, column: 1, want: Position{Source: "src/cart.tsx", Line: 1, Column: 1}, found: true},
+		{line: 1, column: 5, want: Position{Source: "src/cart.tsx", Line: 1, Column: 1}, found: true},
+		{line: 1, column: 6, want: Position{Source: "src/cart.tsx", Line: 1, Column: 6, Name: "addItem"}, found: true},
+		{line: 1, column: 500, want: Position{Source: "src/cart.tsx", Line: 2, Column: 8}, found: true},
+		{line: 2, column: 1, want: Position{Source: "src/checkout.tsx", Line: 3, Column: 8, Name: "pay"}, found: true},
+		{line: 3, column: 1, found: false},
+		{line: 0, column: 1, found: false},
+	}
+
+	for _, tt := range tests {
+		got, found := sourceMap.Lookup(tt.line, tt.column)
+		if found != tt.found || got != tt.want {
+			t.Errorf("Lookup(%d, %d) = %+v, %v, want %+v, %v", tt.line, tt.column, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestParseSourceMap_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":       `{"version": 3`,
+		"version 2":      `{"version": 2, "sources": [], "mappings": ""}`,
+		"sections":       `{"version": 3, "sections": [{"offset": {"line": 0, "column": 0}, "map": {}}]}`,
+		"unknown source": `{"version": 3, "sources": ["a.js"], "mappings": "ACAA"}`,
+		"bad segment":    `{"version": 3, "sources": ["a.js"], "mappings": "AA"}`,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseSourceMap([]byte(data)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestRewriteJavaScriptLine(t *testing.T) {
+	sourceMap, err := ParseSourceMap([]byte(testSourceMap))
+	if err != nil {
+		t.Fatalf("Failed to parse source map: %v", err)
+	}
+	sourceMaps := func(bundle string) *SourceMap {
+		if bundle == "main.jsbundle" {
+			return sourceMap
+		}
+		return nil
+	}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{line: "    at c (http://localhost:8081/main.jsbundle?platform=ios&dev=true:1:6)", want: "    at c (src/cart.tsx:1:6)"},
+		{line: "    at main.jsbundle:2:1", want: "    at src/checkout.tsx:3:8"},
+		{line: "    at c (address at main.jsbundle:1:9)", want: "    at c (address at src/cart.tsx:2:8)"},
+		{line: "c@file:///var/app/main.jsbundle:1:1", want: "c@src/cart.tsx:1:1"},
+		{line: "    at vendor (vendor.js:1:1)", want: "    at vendor (vendor.js:1:1)"},
+		{line: "    at c (main.jsbundle:9:1)", want: "    at c (main.jsbundle:9:1)"},
+	}
+
+	for _, tt := range tests {
+		if got := rewriteJavaScriptLine(tt.line, sourceMaps); got != tt.want {
+			t.Errorf("rewriteJavaScriptLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package symbolication rewrites minified and obfuscated stack traces using the source maps and
+// mapping files uploaded for the service version that reported them.
+package symbolication
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+// DefaultCacheSize is the number of parsed symbol files kept in memory
+const DefaultCacheSize = 32
+
+// Parse parses a symbol file of the given kind, returning a *SourceMap or a *ProGuardMapping
+func Parse(kind models.SymbolFileKind, content []byte) (interface{}, error) {
+	switch kind {
+	case models.SymbolFileKindSourceMap:
+		return ParseSourceMap(content)
+	case models.SymbolFileKindProGuard:
+		return ParseProGuardMapping(content)
+	default:
+		return nil, fmt.Errorf("unsupported symbol file kind %q", kind)
+	}
+}
+
+// Symbolicator symbolicates the stack traces of log entries with the symbol files in a store.
+// Parsed files are cached, keyed by their upload time so that replaced files are picked up.
+type Symbolicator struct {
+	store     storage.SymbolFileStore
+	cacheSize int
+
+	mutex sync.Mutex
+	cache map[string]*cachedSymbolFile
+	uses  uint64
+}
+
+// cachedSymbolFile is a parsed symbol file with the counter value of its last use
+type cachedSymbolFile struct {
+	parsed  interface{}
+	lastUse uint64
+}
+
+// NewSymbolicator creates a symbolicator caching up to DefaultCacheSize parsed files
+func NewSymbolicator(store storage.SymbolFileStore) *Symbolicator {
+	return NewSymbolicatorWithCacheSize(store, DefaultCacheSize)
+}
+
+// ForStorage returns a symbolicator for the symbol files of a log storage, or nil if the storage
+// does not keep symbol files
+func ForStorage(logStorage storage.LogStorage) *Symbolicator {
+	store, ok := logStorage.(storage.SymbolFileStore)
+	if !ok {
+		return nil
+	}
+	return NewSymbolicator(store)
+}
+
+// NewSymbolicatorWithCacheSize creates a symbolicator caching up to cacheSize parsed files
+func NewSymbolicatorWithCacheSize(store storage.SymbolFileStore, cacheSize int) *Symbolicator {
+	if cacheSize <= 0 {
+		cacheSize = DefaultCacheSize
+	}
+	return &Symbolicator{
+		store:     store,
+		cacheSize: cacheSize,
+		cache:     make(map[string]*cachedSymbolFile),
+	}
+}
+
+// SymbolicateEntry rewrites the stack trace of a log entry with the symbol files of its service
+// and release version and reports whether it changed. Locations the files do not cover are kept,
+// and rewriting an already symbolicated stack trace leaves it unchanged.
+func (s *Symbolicator) SymbolicateEntry(ctx context.Context, entry *models.LogEntry) (bool, error) {
+	version := entry.ReleaseVersion()
+	if entry.StackTrace == "" || version == "" {
+		return false, nil
+	}
+
+	files, err := s.store.ListSymbolFiles(ctx, entry.ServiceName, version)
+	if err != nil {
+		return false, err
+	}
+	if len(files) == 0 {
+		return false, nil
+	}
+
+	sourceMaps := make(map[string]*SourceMap)
+	var mappings []*ProGuardMapping
+	for _, file := range files {
+		parsed, err := s.load(ctx, file)
+		if err != nil {
+			return false, err
+		}
+		switch parsed := parsed.(type) {
+		case *SourceMap:
+			sourceMaps[file.Name] = parsed
+		case *ProGuardMapping:
+			mappings = append(mappings, parsed)
+		}
+	}
+
+	// Source maps may be uploaded under the bundle name or with a .map suffix
+	findSourceMap := func(bundle string) *SourceMap {
+		if sourceMap, ok := sourceMaps[bundle]; ok {
+			return sourceMap
+		}
+		return sourceMaps[bundle+".map"]
+	}
+
+	lines := strings.Split(entry.StackTrace, "\n")
+	for i, line := range lines {
+		if len(sourceMaps) > 0 {
+			line = rewriteJavaScriptLine(line, findSourceMap)
+		}
+		for _, mapping := range mappings {
+			if rewritten := mapping.rewriteJavaLine(line); rewritten != line {
+				line = rewritten
+				break
+			}
+		}
+		lines[i] = line
+	}
+
+	symbolicated := strings.Join(lines, "\n")
+	if symbolicated == entry.StackTrace {
+		return false, nil
+	}
+	entry.StackTrace = symbolicated
+	return true, nil
+}
+
+// load returns a parsed symbol file from the cache, reading and parsing it on a miss
+func (s *Symbolicator) load(ctx context.Context, file models.SymbolFile) (interface{}, error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", file.ServiceName, file.Version, file.Name, file.UploadedAt.UnixNano())
+
+	s.mutex.Lock()
+	if cached, ok := s.cache[key]; ok {
+		s.uses++
+		cached.lastUse = s.uses
+		s.mutex.Unlock()
+		return cached.parsed, nil
+	}
+	s.mutex.Unlock()
+
+	stored, err := s.store.GetSymbolFile(ctx, file.ServiceName, file.Version, file.Name)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("symbol file %s of %s %s was deleted", file.Name, file.ServiceName, file.Version)
+	}
+	parsed, err := Parse(stored.Kind, stored.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse symbol file %s of %s %s: %w", file.Name, file.ServiceName, file.Version, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Evict the least recently used file
+	if len(s.cache) >= s.cacheSize {
+		var oldestKey string
+		var oldestUse uint64
+		for cachedKey, cached := range s.cache {
+			if oldestKey == "" || cached.lastUse < oldestUse {
+				oldestKey, oldestUse = cachedKey, cached.lastUse
+			}
+		}
+		delete(s.cache, oldestKey)
+	}
+
+	s.uses++
+	s.cache[key] = &cachedSymbolFile{parsed: parsed, lastUse: s.uses}
+	return parsed, nil
+}
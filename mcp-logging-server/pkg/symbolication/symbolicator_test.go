@@ -0,0 +1,128 @@
+package symbolication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+	"github.com/kerlexov/mcp-logging-server/pkg/storage"
+)
+
+func TestSymbolicator_SymbolicateEntry(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	uploads := []models.SymbolFile{
+		{ServiceName: "mobile-app", Version: "2.0.0", Name: "main.jsbundle.map", Kind: models.SymbolFileKindSourceMap, Content: []byte(testSourceMap)},
+		{ServiceName: "android-app", Version: "2.0.0 (118)", Name: "mapping.txt", Kind: models.SymbolFileKindProGuard, Content: []byte(testProGuardMapping)},
+	}
+	for _, upload := range uploads {
+		if _, err := store.PutSymbolFile(ctx, upload); err != nil {
+			t.Fatalf("Failed to store symbol file: %v", err)
+		}
+	}
+
+	symbolicator := NewSymbolicator(store)
+
+	tests := []struct {
+		name  string
+		entry models.LogEntry
+		want  string
+	}{
+		{
+			name: "source map by device app version",
+			entry: models.LogEntry{
+				ServiceName: "mobile-app",
+				DeviceInfo:  &models.DeviceInfo{Platform: "ios", AppVersion: "2.0.0"},
+				StackTrace:  "TypeError: undefined is not an object\n    at c (main.jsbundle:1:6)\n    at main.jsbundle:2:1",
+			},
+			want: "TypeError: undefined is not an object\n    at c (src/cart.tsx:1:6)\n    at src/checkout.tsx:3:8",
+		},
+		{
+			name: "proguard mapping by version metadata",
+			entry: models.LogEntry{
+				ServiceName: "android-app",
+				Metadata:    map[string]interface{}{"version": "2.0.0 (118)"},
+				StackTrace:  "a.b.d: Cart is empty\n\tat a.b.c.b(SourceFile:7)",
+			},
+			want: "com.example.checkout.CheckoutException: Cart is empty\n\tat com.example.checkout.CartViewModel.checkout(CartViewModel.kt:42)",
+		},
+		{
+			name: "other version",
+			entry: models.LogEntry{
+				ServiceName: "mobile-app",
+				DeviceInfo:  &models.DeviceInfo{Platform: "ios", AppVersion: "1.9.0"},
+				StackTrace:  "    at c (main.jsbundle:1:6)",
+			},
+			want: "    at c (main.jsbundle:1:6)",
+		},
+		{
+			name: "no version",
+			entry: models.LogEntry{
+				ServiceName: "mobile-app",
+				StackTrace:  "    at c (main.jsbundle:1:6)",
+			},
+			want: "    at c (main.jsbundle:1:6)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := tt.entry
+			changed, err := symbolicator.SymbolicateEntry(ctx, &entry)
+			if err != nil {
+				t.Fatalf("Failed to symbolicate: %v", err)
+			}
+			if entry.StackTrace != tt.want || changed != (tt.want != tt.entry.StackTrace) {
+				t.Errorf("Expected %q (changed %v), got %q (changed %v)", tt.want, tt.want != tt.entry.StackTrace, entry.StackTrace, changed)
+			}
+
+			// Symbolicating again, e.g. on read, leaves the stack trace as it is
+			if changed, _ := symbolicator.SymbolicateEntry(ctx, &entry); changed {
+				t.Errorf("Expected a symbolicated stack trace to stay unchanged, got %q", entry.StackTrace)
+			}
+		})
+	}
+}
+
+func TestSymbolicator_ReplacedAndInvalidFiles(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	defer store.Close()
+
+	symbolicator := NewSymbolicatorWithCacheSize(store, 1)
+	entry := func() *models.LogEntry {
+		return &models.LogEntry{
+			ServiceName: "mobile-app",
+			DeviceInfo:  &models.DeviceInfo{Platform: "ios", AppVersion: "2.0.0"},
+			StackTrace:  "    at c (main.jsbundle:2:1)",
+		}
+	}
+	upload := func(content string) {
+		t.Helper()
+		if _, err := store.PutSymbolFile(ctx, models.SymbolFile{ServiceName: "mobile-app", Version: "2.0.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap, Content: []byte(content)}); err != nil {
+			t.Fatalf("Failed to store symbol file: %v", err)
+		}
+	}
+
+	upload(testSourceMap)
+	first := entry()
+	if _, err := symbolicator.SymbolicateEntry(ctx, first); err != nil || first.StackTrace != "    at c (src/checkout.tsx:3:8)" {
+		t.Fatalf("Unexpected stack trace %q: %v", first.StackTrace, err)
+	}
+
+	// A replaced file is parsed again instead of served from the cache
+	upload(`{"version": 3, "sources": ["bundle.ts"], "names": [], "mappings": ";AAAA"}`)
+	second := entry()
+	if _, err := symbolicator.SymbolicateEntry(ctx, second); err != nil || second.StackTrace != "    at c (bundle.ts:1:1)" {
+		t.Errorf("Expected the replaced source map to be used, got %q: %v", second.StackTrace, err)
+	}
+
+	// A broken file fails without touching the stack trace
+	upload(`{"version": 3, "sources": [], "mappings": "A!"}`)
+	third := entry()
+	if _, err := symbolicator.SymbolicateEntry(ctx, third); err == nil || third.StackTrace != "    at c (main.jsbundle:2:1)" {
+		t.Errorf("Expected an error and an unchanged stack trace, got %q: %v", third.StackTrace, err)
+	}
+}
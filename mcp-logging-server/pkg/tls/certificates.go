@@ -0,0 +1,45 @@
+package tls
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// CertificateStore holds the server certificate when it does not come from files, so that it
+// can be replaced while the server runs, e.g. when it is reloaded from a secrets manager.
+// New connections use the latest certificate, established ones keep theirs.
+type CertificateStore struct {
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+}
+
+// NewCertificateStore creates an empty certificate store
+func NewCertificateStore() *CertificateStore {
+	return &CertificateStore{}
+}
+
+// SetKeyPair parses a PEM encoded certificate chain and private key and serves them from now on
+func (s *CertificateStore) SetKeyPair(certPEM, keyPEM []byte) error {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.certificate = &cert
+	return nil
+}
+
+// GetCertificate returns the current certificate, for use as tls.Config.GetCertificate
+func (s *CertificateStore) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.certificate == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return s.certificate, nil
+}
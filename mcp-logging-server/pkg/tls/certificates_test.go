@@ -0,0 +1,103 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// generateKeyPair returns a PEM encoded self-signed certificate and key for the common name
+func generateKeyPair(t *testing.T, commonName string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+func TestCertificateStore_SetKeyPair(t *testing.T) {
+	store := NewCertificateStore()
+	if _, err := store.GetCertificate(nil); err == nil {
+		t.Error("Expected error before a certificate is loaded")
+	}
+
+	for _, commonName := range []string{"first.example.com", "second.example.com"} {
+		certPEM, keyPEM := generateKeyPair(t, commonName)
+		if err := store.SetKeyPair(certPEM, keyPEM); err != nil {
+			t.Fatalf("Failed to set key pair: %v", err)
+		}
+
+		cert, err := store.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("Failed to get certificate: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("Failed to parse certificate: %v", err)
+		}
+		if leaf.Subject.CommonName != commonName {
+			t.Errorf("Expected certificate for %s, got %s", commonName, leaf.Subject.CommonName)
+		}
+	}
+}
+
+func TestCertificateStore_InvalidKeyPairKeepsCertificate(t *testing.T) {
+	store := NewCertificateStore()
+	certPEM, keyPEM := generateKeyPair(t, "example.com")
+	if err := store.SetKeyPair(certPEM, keyPEM); err != nil {
+		t.Fatalf("Failed to set key pair: %v", err)
+	}
+
+	_, otherKeyPEM := generateKeyPair(t, "other.example.com")
+	if err := store.SetKeyPair(certPEM, otherKeyPEM); err == nil {
+		t.Error("Expected error for a key that does not match the certificate")
+	}
+	if _, err := store.GetCertificate(nil); err != nil {
+		t.Errorf("Expected the previous certificate to be kept: %v", err)
+	}
+}
+
+func TestGetTLSConfig_CertificateStore(t *testing.T) {
+	config := DefaultTLSConfig()
+	config.Enabled = true
+	config.CertFile = ""
+	config.KeyFile = ""
+	config.Certificates = NewCertificateStore()
+
+	if err := config.ValidateConfig(); err != nil {
+		t.Fatalf("Config with a certificate store should be valid: %v", err)
+	}
+
+	tlsConf, err := config.GetTLSConfig()
+	if err != nil {
+		t.Fatalf("Failed to get TLS config: %v", err)
+	}
+	if tlsConf.GetCertificate == nil || len(tlsConf.Certificates) != 0 {
+		t.Error("Expected the certificate to be served from the store")
+	}
+}
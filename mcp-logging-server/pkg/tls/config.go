@@ -13,6 +13,9 @@ type TLSConfig struct {
 	KeyFile    string `yaml:"key_file" json:"key_file"`
 	MinVersion string `yaml:"min_version" json:"min_version"`
 	CipherSuites []string `yaml:"cipher_suites" json:"cipher_suites"`
+
+	// Certificates serves the certificate from the store instead of CertFile and KeyFile
+	Certificates *CertificateStore `yaml:"-" json:"-"`
 }
 
 // DefaultTLSConfig returns default TLS configuration
@@ -62,21 +65,6 @@ func (c *TLSConfig) GetTLSConfig() (*tls.Config, error) {
 		return nil, nil
 	}
 	
-	// Validate certificate files exist
-	if _, err := os.Stat(c.CertFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("certificate file not found: %s", c.CertFile)
-	}
-	
-	if _, err := os.Stat(c.KeyFile); os.IsNotExist(err) {
-		return nil, fmt.Errorf("key file not found: %s", c.KeyFile)
-	}
-	
-	// Load certificate
-	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load certificate: %w", err)
-	}
-	
 	// Parse minimum TLS version
 	minVersion, err := c.parseMinVersion()
 	if err != nil {
@@ -90,7 +78,6 @@ func (c *TLSConfig) GetTLSConfig() (*tls.Config, error) {
 	}
 	
 	tlsConfig := &tls.Config{
-		Certificates: []tls.Certificate{cert},
 		MinVersion:   minVersion,
 		CipherSuites: cipherSuites,
 		// Security best practices
@@ -103,6 +90,27 @@ func (c *TLSConfig) GetTLSConfig() (*tls.Config, error) {
 		},
 	}
 	
+	if c.Certificates != nil {
+		tlsConfig.GetCertificate = c.Certificates.GetCertificate
+		return tlsConfig, nil
+	}
+	
+	// Validate certificate files exist
+	if _, err := os.Stat(c.CertFile); os.IsNotExist(err) {
+		return nil, fmt.Errorf("certificate file not found: %s", c.CertFile)
+	}
+	
+	if _, err := os.Stat(c.KeyFile); os.IsNotExist(err) {
+		return nil, fmt.Errorf("key file not found: %s", c.KeyFile)
+	}
+	
+	// Load certificate
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	tlsConfig.Certificates = []tls.Certificate{cert}
+	
 	return tlsConfig, nil
 }
 
@@ -153,11 +161,11 @@ func (c *TLSConfig) ValidateConfig() error {
 		return nil
 	}
 	
-	if c.CertFile == "" {
+	if c.CertFile == "" && c.Certificates == nil {
 		return fmt.Errorf("certificate file path is required when TLS is enabled")
 	}
 	
-	if c.KeyFile == "" {
+	if c.KeyFile == "" && c.Certificates == nil {
 		return fmt.Errorf("key file path is required when TLS is enabled")
 	}
 	
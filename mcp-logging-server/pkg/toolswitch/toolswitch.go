@@ -0,0 +1,166 @@
+// Package toolswitch holds which MCP tools are enabled. Tools are disabled by the configuration,
+// such as tools changing stored data in production, and switched at runtime by operators at the
+// ingestion server's /admin/tools, while the MCP server lists and serves only the enabled tools
+// and tells connected clients when they change.
+package toolswitch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTool is returned when switching a tool the MCP server does not have
+var ErrUnknownTool = errors.New("unknown tool")
+
+// State is whether a tool is enabled, with why and since when it is disabled
+type State struct {
+	Name    string    `json:"name"`
+	Enabled bool      `json:"enabled"`
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since,omitempty"` // When the tool was disabled, zero while it is enabled
+}
+
+// Switch holds the tools shared by the ingestion and MCP servers. The zero value is not usable,
+// create one with New.
+type Switch struct {
+	mutex     sync.RWMutex
+	known     map[string]bool  // Tools registered by the MCP server
+	disabled  map[string]State // Disabled tools, which need not be registered yet
+	listeners map[int]func()
+	nextID    int
+}
+
+// New creates a switch with the named tools disabled
+func New(disabled []string) *Switch {
+	s := &Switch{
+		known:     make(map[string]bool),
+		disabled:  make(map[string]State),
+		listeners: make(map[int]func()),
+	}
+	now := time.Now().UTC()
+	for _, name := range disabled {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		s.disabled[name] = State{Name: name, Reason: "disabled by configuration", Since: now}
+	}
+	return s
+}
+
+// Register records the tools the MCP server serves, only registered tools can be switched
+func (s *Switch) Register(names ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, name := range names {
+		s.known[name] = true
+	}
+}
+
+// Unregistered returns the disabled tools that were never registered, sorted, which are
+// misspelled in the configuration
+func (s *Switch) Unregistered() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var names []string
+	for name := range s.disabled {
+		if !s.known[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled reports whether a tool may be listed and called
+func (s *Switch) Enabled(name string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, disabled := s.disabled[name]
+	return !disabled
+}
+
+// Get returns the state of a tool
+func (s *Switch) Get(name string) State {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if state, disabled := s.disabled[name]; disabled {
+		return state
+	}
+	return State{Name: name, Enabled: true}
+}
+
+// Tools returns the states of the registered tools, sorted by name
+func (s *Switch) Tools() []State {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	states := make([]State, 0, len(s.known))
+	for name := range s.known {
+		state, disabled := s.disabled[name]
+		if !disabled {
+			state = State{Name: name, Enabled: true}
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Set enables or disables a registered tool and returns its new state, or ErrUnknownTool for
+// other tools. Listeners are called when the tool changed; disabling a disabled tool again only
+// updates the reason.
+func (s *Switch) Set(name string, enabled bool, reason string) (State, error) {
+	s.mutex.Lock()
+	if !s.known[name] {
+		s.mutex.Unlock()
+		return State{}, fmt.Errorf("%w %q", ErrUnknownTool, name)
+	}
+
+	previous, wasDisabled := s.disabled[name]
+	state := State{Name: name, Enabled: true}
+	switch {
+	case enabled:
+		delete(s.disabled, name)
+	case wasDisabled:
+		state = previous
+		state.Reason = reason
+		s.disabled[name] = state
+	default:
+		state = State{Name: name, Reason: reason, Since: time.Now().UTC()}
+		s.disabled[name] = state
+	}
+	changed := enabled == wasDisabled
+	listeners := make([]func(), 0, len(s.listeners))
+	for _, listener := range s.listeners {
+		listeners = append(listeners, listener)
+	}
+	s.mutex.Unlock()
+
+	// Called without the lock, so that listeners may read the switch
+	if changed {
+		for _, listener := range listeners {
+			listener()
+		}
+	}
+	return state, nil
+}
+
+// Subscribe calls listener after each change of a tool until the returned function is called
+func (s *Switch) Subscribe(listener func()) func() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.listeners[id] = listener
+	return func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		delete(s.listeners, id)
+	}
+}
@@ -0,0 +1,59 @@
+package toolswitch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSwitch(t *testing.T) {
+	s := New([]string{"annotate_log", "delete_logs"})
+	s.Register("query_logs", "annotate_log")
+
+	if s.Enabled("annotate_log") || !s.Enabled("query_logs") {
+		t.Fatal("Expected the configured tools disabled and the others enabled")
+	}
+	if unregistered := s.Unregistered(); !reflect.DeepEqual(unregistered, []string{"delete_logs"}) {
+		t.Errorf("Expected delete_logs unregistered, got %v", unregistered)
+	}
+
+	changes := 0
+	unsubscribe := s.Subscribe(func() { changes++ })
+
+	disabled, err := s.Set("query_logs", false, "too expensive")
+	if err != nil {
+		t.Fatalf("Failed to disable a tool: %v", err)
+	}
+	if disabled.Enabled || disabled.Reason != "too expensive" || disabled.Since.IsZero() || s.Enabled("query_logs") {
+		t.Errorf("Expected query_logs disabled, got %+v", disabled)
+	}
+
+	// Disabling again keeps the time it was disabled and is no change
+	if state, _ := s.Set("query_logs", false, "incident"); !state.Since.Equal(disabled.Since) || state.Reason != "incident" {
+		t.Errorf("Expected the reason updated and the time kept, got %+v", state)
+	}
+	if changes != 1 {
+		t.Errorf("Expected 1 change, got %d", changes)
+	}
+
+	if state, _ := s.Set("annotate_log", true, ""); !state.Enabled || !state.Since.IsZero() || !s.Enabled("annotate_log") {
+		t.Errorf("Expected annotate_log enabled, got %+v", state)
+	}
+	if changes != 2 {
+		t.Errorf("Expected 2 changes, got %d", changes)
+	}
+
+	if _, err := s.Set("delete_logs", true, ""); err == nil {
+		t.Error("Expected an unregistered tool to be rejected")
+	}
+
+	tools := s.Tools()
+	if len(tools) != 2 || tools[0].Name != "annotate_log" || !tools[0].Enabled || tools[1].Name != "query_logs" || tools[1].Enabled {
+		t.Errorf("Expected annotate_log enabled and query_logs disabled, got %+v", tools)
+	}
+
+	unsubscribe()
+	s.Set("query_logs", true, "")
+	if changes != 2 {
+		t.Errorf("Expected no changes after unsubscribing, got %d", changes)
+	}
+}
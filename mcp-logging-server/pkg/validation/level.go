@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// DefaultLevelAliases returns the built-in mapping of common level names,
+// including numeric syslog severities, to canonical log levels
+func DefaultLevelAliases() map[string]models.LogLevel {
+	return map[string]models.LogLevel{
+		"trace":       models.LogLevelDebug,
+		"verbose":     models.LogLevelDebug,
+		"dbg":         models.LogLevelDebug,
+		"information": models.LogLevelInfo,
+		"notice":      models.LogLevelInfo,
+		"warning":     models.LogLevelWarn,
+		"err":         models.LogLevelError,
+		"critical":    models.LogLevelFatal,
+		"crit":        models.LogLevelFatal,
+		"alert":       models.LogLevelFatal,
+		"emerg":       models.LogLevelFatal,
+		"emergency":   models.LogLevelFatal,
+		"panic":       models.LogLevelFatal,
+		"0":           models.LogLevelFatal, // syslog emergency
+		"1":           models.LogLevelFatal, // syslog alert
+		"2":           models.LogLevelFatal, // syslog critical
+		"3":           models.LogLevelError, // syslog error
+		"4":           models.LogLevelWarn,  // syslog warning
+		"5":           models.LogLevelInfo,  // syslog notice
+		"6":           models.LogLevelInfo,  // syslog informational
+		"7":           models.LogLevelDebug, // syslog debug
+	}
+}
+
+// LevelNormalizer maps level aliases to the canonical log level enum
+type LevelNormalizer struct {
+	aliases map[string]models.LogLevel
+}
+
+// NewLevelNormalizer creates a level normalizer from the default aliases
+// extended (and overridden) by the given custom aliases
+func NewLevelNormalizer(customAliases map[string]string) (*LevelNormalizer, error) {
+	aliases := DefaultLevelAliases()
+
+	for alias, level := range customAliases {
+		canonical := models.LogLevel(strings.ToUpper(strings.TrimSpace(level)))
+		if !isCanonicalLevel(canonical) {
+			return nil, fmt.Errorf("invalid target level %q for alias %q", level, alias)
+		}
+		aliases[strings.ToLower(strings.TrimSpace(alias))] = canonical
+	}
+
+	return &LevelNormalizer{aliases: aliases}, nil
+}
+
+// Normalize returns the canonical level for the given level or alias.
+// Unknown levels are returned unchanged so validation can reject them.
+func (n *LevelNormalizer) Normalize(level models.LogLevel) models.LogLevel {
+	trimmed := strings.TrimSpace(string(level))
+
+	if canonical := models.LogLevel(strings.ToUpper(trimmed)); isCanonicalLevel(canonical) {
+		return canonical
+	}
+
+	if canonical, exists := n.aliases[strings.ToLower(trimmed)]; exists {
+		return canonical
+	}
+
+	return level
+}
+
+// isCanonicalLevel checks if a level is one of the canonical log levels
+func isCanonicalLevel(level models.LogLevel) bool {
+	switch level {
+	case models.LogLevelDebug, models.LogLevelInfo, models.LogLevelWarn, models.LogLevelError, models.LogLevelFatal:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+func TestLevelNormalizer_Normalize(t *testing.T) {
+	normalizer, err := NewLevelNormalizer(map[string]string{"Severe": "fatal"})
+	if err != nil {
+		t.Fatalf("Failed to create normalizer: %v", err)
+	}
+
+	tests := []struct {
+		input    models.LogLevel
+		expected models.LogLevel
+	}{
+		{"INFO", models.LogLevelInfo},
+		{"info", models.LogLevelInfo},
+		{" warn ", models.LogLevelWarn},
+		{"warning", models.LogLevelWarn},
+		{"ERR", models.LogLevelError},
+		{"critical", models.LogLevelFatal},
+		{"trace", models.LogLevelDebug},
+		{"3", models.LogLevelError},
+		{"7", models.LogLevelDebug},
+		{"severe", models.LogLevelFatal},
+		{"bogus", "bogus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.input), func(t *testing.T) {
+			if got := normalizer.Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewLevelNormalizer_InvalidTarget(t *testing.T) {
+	if _, err := NewLevelNormalizer(map[string]string{"loud": "SHOUT"}); err == nil {
+		t.Error("Expected error for invalid target level")
+	}
+}
+
+func TestLevelNormalizer_NumericJSONLevel(t *testing.T) {
+	normalizer, _ := NewLevelNormalizer(nil)
+
+	var entry models.LogEntry
+	if err := json.Unmarshal([]byte(`{"level": 4}`), &entry); err != nil {
+		t.Fatalf("Failed to unmarshal numeric level: %v", err)
+	}
+
+	if got := normalizer.Normalize(entry.Level); got != models.LogLevelWarn {
+		t.Errorf("Expected WARN, got %q", got)
+	}
+}
@@ -0,0 +1,227 @@
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kerlexov/mcp-logging-server/pkg/models"
+)
+
+// Names of the configurable rules, reported in validation errors and metrics. Errors of the
+// struct tags of log entries are reported with the name of the tag, such as required or uuid4.
+const (
+	RuleMessageLength    = "message_length"
+	RuleRequiredFields   = "required_fields"
+	RulePlatform         = "platform"
+	RuleTimestampFuture  = "timestamp_future"
+	RuleTimestampPast    = "timestamp_past"
+	RuleMetadataKeys     = "metadata_keys"
+	RuleStackTraceLength = "stack_trace_length"
+)
+
+// Default limits of the rules
+const (
+	DefaultMaxMessageLength    = 10000
+	DefaultMaxFutureSkew       = 5 * time.Minute
+	DefaultMaxAge              = 365 * 24 * time.Hour
+	DefaultMaxMetadataKeys     = 50
+	DefaultMaxStackTraceLength = 50000
+)
+
+// Rules configures the rules applied to log entries on top of their struct tags. Zero limits
+// take the defaults.
+type Rules struct {
+	MaxMessageLength    int           // Characters
+	RequiredFields      []string      // Optional fields entries must set, such as device_info or metadata.user_id
+	MaxFutureSkew       time.Duration // How far timestamps may be ahead of the server clock
+	MaxAge              time.Duration // How far timestamps may be behind the server clock
+	MaxMetadataKeys     int
+	MaxStackTraceLength int         // Characters
+	FieldRules          []FieldRule // Custom validators of single fields
+	Disabled            []string    // Names of rules and field rules that are not applied
+}
+
+// FieldRule is a custom validator of a field. It only applies to entries that set the field;
+// list the field in RequiredFields to require it as well.
+type FieldRule struct {
+	Name      string   // Reported in validation errors and metrics
+	Field     string   // Field name, such as agent_id or metadata.order_id. Each tag is validated on its own.
+	Pattern   string   // Regular expression the value must match
+	MaxLength int      // Characters, zero for no limit
+	OneOf     []string // Accepted values, empty for any
+}
+
+// fieldRule is a FieldRule with its pattern compiled
+type fieldRule struct {
+	FieldRule
+	pattern *regexp.Regexp
+	oneOf   map[string]bool
+}
+
+// entryFields returns the values of the fields rules may refer to, and whether the entry sets
+// the field. Metadata keys are looked up separately.
+var entryFields = map[string]func(entry *models.LogEntry) ([]string, bool){
+	"message":      func(e *models.LogEntry) ([]string, bool) { return single(e.Message) },
+	"level":        func(e *models.LogEntry) ([]string, bool) { return single(string(e.Level)) },
+	"service_name": func(e *models.LogEntry) ([]string, bool) { return single(e.ServiceName) },
+	"agent_id":     func(e *models.LogEntry) ([]string, bool) { return single(e.AgentID) },
+	"platform":     func(e *models.LogEntry) ([]string, bool) { return single(string(e.Platform)) },
+	"request_id":   func(e *models.LogEntry) ([]string, bool) { return single(e.RequestID) },
+	"stack_trace":  func(e *models.LogEntry) ([]string, bool) { return single(e.StackTrace) },
+	"tags":         func(e *models.LogEntry) ([]string, bool) { return e.Tags, len(e.Tags) > 0 },
+	"metadata":     func(e *models.LogEntry) ([]string, bool) { return nil, len(e.Metadata) > 0 },
+	"crash":        func(e *models.LogEntry) ([]string, bool) { return nil, e.Crash != nil },
+	"device_info":  func(e *models.LogEntry) ([]string, bool) { return nil, e.DeviceInfo != nil },
+	"device_info.version": func(e *models.LogEntry) ([]string, bool) {
+		if e.DeviceInfo == nil {
+			return nil, false
+		}
+		return single(e.DeviceInfo.Version)
+	},
+	"device_info.model": func(e *models.LogEntry) ([]string, bool) {
+		if e.DeviceInfo == nil {
+			return nil, false
+		}
+		return single(e.DeviceInfo.Model)
+	},
+	"device_info.app_version": func(e *models.LogEntry) ([]string, bool) {
+		if e.DeviceInfo == nil {
+			return nil, false
+		}
+		return single(e.DeviceInfo.AppVersion)
+	},
+	"source_location": func(e *models.LogEntry) ([]string, bool) { return nil, e.SourceLocation != nil },
+	"source_location.file": func(e *models.LogEntry) ([]string, bool) {
+		if e.SourceLocation == nil {
+			return nil, false
+		}
+		return single(e.SourceLocation.File)
+	},
+	"source_location.function": func(e *models.LogEntry) ([]string, bool) {
+		if e.SourceLocation == nil {
+			return nil, false
+		}
+		return single(e.SourceLocation.Function)
+	},
+}
+
+// metadataPrefix selects a metadata key in field names
+const metadataPrefix = "metadata."
+
+// single returns a string field value, which is set if it is not empty
+func single(value string) ([]string, bool) {
+	return []string{value}, value != ""
+}
+
+// fieldValues returns the values of a field of the entry and whether the entry sets it.
+// Metadata values that are not strings are formatted.
+func fieldValues(entry *models.LogEntry, field string) ([]string, bool) {
+	if key, ok := strings.CutPrefix(field, metadataPrefix); ok {
+		value, exists := entry.Metadata[key]
+		if !exists || value == nil {
+			return nil, false
+		}
+		if text, ok := value.(string); ok {
+			return []string{text}, true
+		}
+		return []string{fmt.Sprintf("%v", value)}, true
+	}
+	if values, ok := entryFields[field]; ok {
+		return values(entry)
+	}
+	return nil, false
+}
+
+// knownField reports whether rules can refer to a field
+func knownField(field string) bool {
+	if key, ok := strings.CutPrefix(field, metadataPrefix); ok {
+		return key != ""
+	}
+	_, ok := entryFields[field]
+	return ok
+}
+
+// withDefaults returns the rules with zero limits replaced by the defaults
+func (r Rules) withDefaults() Rules {
+	if r.MaxMessageLength == 0 {
+		r.MaxMessageLength = DefaultMaxMessageLength
+	}
+	if r.MaxFutureSkew == 0 {
+		r.MaxFutureSkew = DefaultMaxFutureSkew
+	}
+	if r.MaxAge == 0 {
+		r.MaxAge = DefaultMaxAge
+	}
+	if r.MaxMetadataKeys == 0 {
+		r.MaxMetadataKeys = DefaultMaxMetadataKeys
+	}
+	if r.MaxStackTraceLength == 0 {
+		r.MaxStackTraceLength = DefaultMaxStackTraceLength
+	}
+	return r
+}
+
+// compileFieldRules checks the field rules and compiles their patterns
+func compileFieldRules(rules []FieldRule) ([]fieldRule, error) {
+	compiled := make([]fieldRule, 0, len(rules))
+	names := make(map[string]bool)
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("field rule for %q has no name", rule.Field)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicate field rule %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if !knownField(rule.Field) {
+			return nil, fmt.Errorf("field rule %q: unknown field %q", rule.Name, rule.Field)
+		}
+
+		fr := fieldRule{FieldRule: rule}
+		if rule.Pattern != "" {
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("field rule %q: invalid pattern: %w", rule.Name, err)
+			}
+			fr.pattern = pattern
+		}
+		if len(rule.OneOf) > 0 {
+			fr.oneOf = make(map[string]bool, len(rule.OneOf))
+			for _, value := range rule.OneOf {
+				fr.oneOf[value] = true
+			}
+		}
+		compiled = append(compiled, fr)
+	}
+	return compiled, nil
+}
+
+// check returns the error message of a field value that breaks the rule, empty if it passes
+func (r *fieldRule) check(value string) string {
+	if r.MaxLength > 0 && len([]rune(value)) > r.MaxLength {
+		return fmt.Sprintf("%s cannot exceed %d characters", r.Field, r.MaxLength)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(value) {
+		return fmt.Sprintf("%s must match %s", r.Field, r.Pattern)
+	}
+	if r.oneOf != nil && !r.oneOf[value] {
+		return fmt.Sprintf("%s must be one of: %s", r.Field, strings.Join(r.OneOf, " "))
+	}
+	return ""
+}
+
+// describeDuration formats a rule limit in the largest whole unit among days, hours and minutes
+func describeDuration(d time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case d >= day && d%day == 0:
+		return fmt.Sprintf("%d days", d/day)
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	case d >= time.Minute && d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	return d.String()
+}
@@ -3,6 +3,7 @@ package validation
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,22 +13,105 @@ import (
 
 // LogValidator provides comprehensive validation for log entries
 type LogValidator struct {
-	validator *validator.Validate
+	validator  *validator.Validate
+	platforms  map[models.Platform]bool
+	rules      Rules
+	fieldRules []fieldRule
+	disabled   map[string]bool
 }
 
-// NewLogValidator creates a new log validator
+// NewLogValidator creates a new log validator accepting the default platforms
 func NewLogValidator() *LogValidator {
+	return NewLogValidatorWithPlatforms(nil)
+}
+
+// NewLogValidatorWithPlatforms creates a new log validator accepting the given platforms.
+// An empty list falls back to models.DefaultPlatforms.
+func NewLogValidatorWithPlatforms(platforms []string) *LogValidator {
+	lv, _ := NewLogValidatorWithRules(platforms, Rules{})
+	return lv
+}
+
+// NewLogValidatorWithRules creates a new log validator accepting the given platforms and
+// applying the given rules. It fails if the rules refer to unknown fields or rules, or if a
+// pattern does not compile.
+func NewLogValidatorWithRules(platforms []string, rules Rules) (*LogValidator, error) {
 	v := validator.New()
 
+	allowed := make(map[models.Platform]bool)
+	for _, platform := range platforms {
+		if platform = strings.TrimSpace(platform); platform != "" {
+			allowed[models.Platform(platform)] = true
+		}
+	}
+	if len(allowed) == 0 {
+		for _, platform := range models.DefaultPlatforms() {
+			allowed[platform] = true
+		}
+	}
+
+	for _, field := range rules.RequiredFields {
+		if !knownField(field) {
+			return nil, fmt.Errorf("unknown required field %q", field)
+		}
+	}
+	fieldRules, err := compileFieldRules(rules.FieldRules)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make(map[string]bool)
+	for _, name := range rules.Disabled {
+		if name = strings.TrimSpace(name); name != "" {
+			disabled[name] = true
+		}
+	}
+	for name := range disabled {
+		if !knownRule(name, fieldRules) {
+			return nil, fmt.Errorf("cannot disable unknown rule %q", name)
+		}
+	}
+
 	// Register custom validators
 	v.RegisterValidation("service_name", validateServiceName)
 	v.RegisterValidation("agent_id", validateAgentID)
 	v.RegisterValidation("log_message", validateLogMessage)
 	v.RegisterValidation("metadata_size", validateMetadataSize)
+	v.RegisterValidation("platform", func(fl validator.FieldLevel) bool {
+		return disabled[RulePlatform] || allowed[models.Platform(fl.Field().String())]
+	})
 
 	return &LogValidator{
-		validator: v,
+		validator:  v,
+		platforms:  allowed,
+		rules:      rules.withDefaults(),
+		fieldRules: fieldRules,
+		disabled:   disabled,
+	}, nil
+}
+
+// knownRule reports whether a rule can be disabled by name
+func knownRule(name string, fieldRules []fieldRule) bool {
+	switch name {
+	case RuleMessageLength, RuleRequiredFields, RulePlatform, RuleTimestampFuture, RuleTimestampPast, RuleMetadataKeys, RuleStackTraceLength:
+		return true
 	}
+	for _, rule := range fieldRules {
+		if rule.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Platforms returns the sorted list of accepted platforms
+func (lv *LogValidator) Platforms() []string {
+	platforms := make([]string, 0, len(lv.platforms))
+	for platform := range lv.platforms {
+		platforms = append(platforms, string(platform))
+	}
+	sort.Strings(platforms)
+	return platforms
 }
 
 // ValidateLogEntry validates a single log entry with detailed error reporting
@@ -38,23 +122,153 @@ func (lv *LogValidator) ValidateLogEntry(entry *models.LogEntry) *ValidationResu
 	}
 
 	// Basic struct validation
-	if err := lv.validator.Struct(entry); err != nil {
+	result.Errors = append(result.Errors, lv.structErrors(entry)...)
+
+	// Custom business logic validation
+	lv.validateBusinessRules(entry, result)
+
+	result.IsValid = len(result.Errors) == 0
+	return result
+}
+
+// ValidateServiceRegistration validates a service registration
+func (lv *LogValidator) ValidateServiceRegistration(registration *models.ServiceRegistration) *ValidationResult {
+	errors := lv.structErrors(registration)
+
+	return &ValidationResult{
+		IsValid: len(errors) == 0,
+		Errors:  errors,
+	}
+}
+
+// ValidateLegalHold validates a legal hold
+func (lv *LogValidator) ValidateLegalHold(hold *models.LegalHold) *ValidationResult {
+	errors := lv.structErrors(hold)
+
+	if !hold.StartTime.IsZero() && !hold.EndTime.IsZero() && hold.EndTime.Before(hold.StartTime) {
+		errors = append(errors, ValidationError{
+			Field:   "EndTime",
+			Value:   hold.EndTime.Format(time.RFC3339),
+			Message: "EndTime must not be before StartTime",
+		})
+	}
+
+	return &ValidationResult{
+		IsValid: len(errors) == 0,
+		Errors:  errors,
+	}
+}
+
+// ValidateAnnotation validates an operator annotation of a log entry
+func (lv *LogValidator) ValidateAnnotation(annotation *models.LogAnnotation) *ValidationResult {
+	errors := lv.structErrors(annotation)
+
+	if strings.TrimSpace(annotation.Text) == "" && len(errors) == 0 {
+		errors = append(errors, ValidationError{
+			Field:   "Text",
+			Value:   annotation.Text,
+			Message: "Text must not be blank",
+		})
+	}
+
+	return &ValidationResult{
+		IsValid: len(errors) == 0,
+		Errors:  errors,
+	}
+}
+
+// ValidateIncident validates a new incident and its filter
+func (lv *LogValidator) ValidateIncident(incident *models.Incident) *ValidationResult {
+	errors := lv.structErrors(incident)
+	errors = append(errors, filterErrors(incident.Filter)...)
+
+	return &ValidationResult{
+		IsValid: len(errors) == 0,
+		Errors:  errors,
+	}
+}
+
+// ValidateIncidentAttachment validates evidence attached to an incident
+func (lv *LogValidator) ValidateIncidentAttachment(attachment *models.IncidentAttachment) *ValidationResult {
+	errors := lv.structErrors(attachment)
+	errors = append(errors, filterErrors(attachment.Filter)...)
+
+	if len(attachment.LogIDs) == 0 && attachment.Filter == nil {
+		errors = append(errors, ValidationError{
+			Field:   "LogIDs",
+			Message: "LogIDs or Filter is required",
+		})
+	}
+
+	return &ValidationResult{
+		IsValid: len(errors) == 0,
+		Errors:  errors,
+	}
+}
+
+// filterErrors checks the level, time field and time range of a stored filter, nil is valid
+func filterErrors(filter *models.LogFilter) []ValidationError {
+	if filter == nil {
+		return nil
+	}
+
+	var errors []ValidationError
+	if filter.Level != "" && !isCanonicalLevel(filter.Level) {
+		errors = append(errors, ValidationError{
+			Field:   "Filter.Level",
+			Value:   string(filter.Level),
+			Message: "Level must be one of DEBUG, INFO, WARN, ERROR, FATAL",
+		})
+	}
+	if filter.TimeField != "" && filter.TimeField != models.TimeFieldTimestamp && filter.TimeField != models.TimeFieldReceivedAt {
+		errors = append(errors, ValidationError{
+			Field:   "Filter.TimeField",
+			Value:   string(filter.TimeField),
+			Message: "TimeField must be timestamp or received_at",
+		})
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && filter.EndTime.Before(filter.StartTime) {
+		errors = append(errors, ValidationError{
+			Field:   "Filter.EndTime",
+			Value:   filter.EndTime.Format(time.RFC3339),
+			Message: "EndTime must not be before StartTime",
+		})
+	}
+	return errors
+}
+
+// ValidateSymbolFile validates an uploaded source map or mapping file
+func (lv *LogValidator) ValidateSymbolFile(file *models.SymbolFile) *ValidationResult {
+	errors := lv.structErrors(file)
+
+	return &ValidationResult{
+		IsValid: len(errors) == 0,
+		Errors:  errors,
+	}
+}
+
+// structErrors runs struct tag validation and converts the failures to validation errors
+func (lv *LogValidator) structErrors(value interface{}) []ValidationError {
+	errors := make([]ValidationError, 0)
+
+	if err := lv.validator.Struct(value); err != nil {
 		if validationErrors, ok := err.(validator.ValidationErrors); ok {
 			for _, fieldError := range validationErrors {
-				result.Errors = append(result.Errors, ValidationError{
+				message := getValidationMessage(fieldError)
+				if fieldError.Tag() == "platform" {
+					message = fmt.Sprintf("%s must be one of: %s", fieldError.Field(), strings.Join(lv.Platforms(), " "))
+				}
+				errors = append(errors, ValidationError{
 					Field:   fieldError.Field(),
 					Value:   fmt.Sprintf("%v", fieldError.Value()),
-					Message: getValidationMessage(fieldError),
+					Message: message,
+					Rule:    fieldError.Tag(),
 				})
 			}
 		}
 	}
 
-	// Custom business logic validation
-	lv.validateBusinessRules(entry, result)
-
-	result.IsValid = len(result.Errors) == 0
-	return result
+	return errors
 }
 
 // ValidateLogBatch validates a batch of log entries
@@ -95,6 +309,7 @@ type ValidationError struct {
 	Field   string `json:"field"`
 	Value   string `json:"value"`
 	Message string `json:"message"`
+	Rule    string `json:"rule,omitempty"` // Name of the rule or struct tag that failed
 }
 
 // BatchValidationResult represents the result of validating a batch of log entries
@@ -113,42 +328,70 @@ type InvalidEntry struct {
 	Errors []ValidationError `json:"errors"`
 }
 
-// validateBusinessRules applies custom business logic validation
+// validateBusinessRules applies the configurable rules
 func (lv *LogValidator) validateBusinessRules(entry *models.LogEntry, result *ValidationResult) {
-	// Validate timestamp is not too far in the future
-	if entry.Timestamp.After(time.Now().Add(5 * time.Minute)) {
+	rules := lv.rules
+	fail := func(rule, field, value, message string) {
 		result.Errors = append(result.Errors, ValidationError{
-			Field:   "timestamp",
-			Value:   entry.Timestamp.String(),
-			Message: "Timestamp cannot be more than 5 minutes in the future",
+			Field:   field,
+			Value:   value,
+			Message: message,
+			Rule:    rule,
 		})
 	}
 
-	// Validate timestamp is not too old (more than 1 year)
-	if entry.Timestamp.Before(time.Now().Add(-365 * 24 * time.Hour)) {
-		result.Errors = append(result.Errors, ValidationError{
-			Field:   "timestamp",
-			Value:   entry.Timestamp.String(),
-			Message: "Timestamp cannot be more than 1 year in the past",
-		})
+	if !lv.disabled[RuleMessageLength] {
+		if length := len([]rune(entry.Message)); length > rules.MaxMessageLength {
+			fail(RuleMessageLength, "message", fmt.Sprintf("%d characters", length),
+				fmt.Sprintf("Message cannot exceed %d characters", rules.MaxMessageLength))
+		}
 	}
 
-	// Validate metadata size
-	if entry.Metadata != nil && len(entry.Metadata) > 50 {
-		result.Errors = append(result.Errors, ValidationError{
-			Field:   "metadata",
-			Value:   fmt.Sprintf("%d keys", len(entry.Metadata)),
-			Message: "Metadata cannot have more than 50 keys",
-		})
+	if !lv.disabled[RuleRequiredFields] {
+		for _, field := range rules.RequiredFields {
+			if _, set := fieldValues(entry, field); !set {
+				fail(RuleRequiredFields, field, "", fmt.Sprintf("%s is required", field))
+			}
+		}
 	}
 
-	// Validate stack trace size
-	if len(entry.StackTrace) > 50000 {
-		result.Errors = append(result.Errors, ValidationError{
-			Field:   "stack_trace",
-			Value:   fmt.Sprintf("%d characters", len(entry.StackTrace)),
-			Message: "Stack trace cannot exceed 50,000 characters",
-		})
+	// Entries flagged as clock skewed are accepted as-is, their receive time is authoritative
+	if !entry.ClockSkewed {
+		now := time.Now()
+		if !lv.disabled[RuleTimestampFuture] && entry.Timestamp.After(now.Add(rules.MaxFutureSkew)) {
+			fail(RuleTimestampFuture, "timestamp", entry.Timestamp.String(),
+				fmt.Sprintf("Timestamp cannot be more than %s in the future", describeDuration(rules.MaxFutureSkew)))
+		}
+		if !lv.disabled[RuleTimestampPast] && entry.Timestamp.Before(now.Add(-rules.MaxAge)) {
+			fail(RuleTimestampPast, "timestamp", entry.Timestamp.String(),
+				fmt.Sprintf("Timestamp cannot be more than %s in the past", describeDuration(rules.MaxAge)))
+		}
+	}
+
+	if !lv.disabled[RuleMetadataKeys] && len(entry.Metadata) > rules.MaxMetadataKeys {
+		fail(RuleMetadataKeys, "metadata", fmt.Sprintf("%d keys", len(entry.Metadata)),
+			fmt.Sprintf("Metadata cannot have more than %d keys", rules.MaxMetadataKeys))
+	}
+
+	if !lv.disabled[RuleStackTraceLength] && len(entry.StackTrace) > rules.MaxStackTraceLength {
+		fail(RuleStackTraceLength, "stack_trace", fmt.Sprintf("%d characters", len(entry.StackTrace)),
+			fmt.Sprintf("Stack trace cannot exceed %d characters", rules.MaxStackTraceLength))
+	}
+
+	for i := range lv.fieldRules {
+		rule := &lv.fieldRules[i]
+		if lv.disabled[rule.Name] {
+			continue
+		}
+		values, set := fieldValues(entry, rule.Field)
+		if !set {
+			continue
+		}
+		for _, value := range values {
+			if message := rule.check(value); message != "" {
+				fail(rule.Name, rule.Field, value, message)
+			}
+		}
 	}
 }
 
@@ -185,6 +428,8 @@ func getValidationMessage(fe validator.FieldError) string {
 		return fmt.Sprintf("%s is required", fe.Field())
 	case "uuid4":
 		return fmt.Sprintf("%s must be a valid UUID v4", fe.Field())
+	case "url":
+		return fmt.Sprintf("%s must be a valid URL", fe.Field())
 	case "oneof":
 		return fmt.Sprintf("%s must be one of: %s", fe.Field(), fe.Param())
 	case "max":
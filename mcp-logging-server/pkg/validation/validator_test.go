@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -269,3 +270,160 @@ func TestCustomValidators(t *testing.T) {
 		})
 	}
 }
+
+func TestLogValidator_ConfiguredPlatforms(t *testing.T) {
+	entry := createValidLogEntry()
+	entry.Platform = "python"
+
+	if result := NewLogValidator().ValidateLogEntry(&entry); result.IsValid {
+		t.Error("Expected python platform to be rejected by default")
+	}
+
+	validator := NewLogValidatorWithPlatforms([]string{"go", " python ", "rust"})
+	if result := validator.ValidateLogEntry(&entry); !result.IsValid {
+		t.Errorf("Expected python platform to be accepted, got errors: %v", result.Errors)
+	}
+
+	entry.Platform = models.PlatformSwift
+	result := validator.ValidateLogEntry(&entry)
+	if result.IsValid {
+		t.Fatal("Expected swift platform to be rejected")
+	}
+	if expected := "Platform must be one of: go python rust"; result.Errors[0].Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, result.Errors[0].Message)
+	}
+}
+
+func TestLogValidator_Rules(t *testing.T) {
+	validator, err := NewLogValidatorWithRules(nil, Rules{
+		MaxMessageLength: 10,
+		RequiredFields:   []string{"device_info", "metadata.user_id"},
+		MaxAge:           time.Hour,
+		FieldRules: []FieldRule{
+			{Name: "order_id", Field: "metadata.order_id", Pattern: `^ORD-[0-9]+$`},
+			{Name: "known_tags", Field: "tags", OneOf: []string{"checkout", "login"}},
+		},
+		Disabled: []string{"platform", " timestamp_past "},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	rules := func(result *ValidationResult) []string {
+		names := make([]string, 0, len(result.Errors))
+		for _, validationError := range result.Errors {
+			names = append(names, validationError.Rule)
+		}
+		return names
+	}
+
+	entry := createValidLogEntry()
+	entry.Message = "Message longer than ten characters"
+	entry.Platform = "python"
+	entry.Timestamp = time.Now().Add(-48 * time.Hour)
+	entry.Metadata = map[string]interface{}{"order_id": "42"}
+	entry.Tags = []string{"checkout", "signup"}
+
+	result := validator.ValidateLogEntry(&entry)
+	expected := []string{RuleMessageLength, RuleRequiredFields, RuleRequiredFields, "order_id", "known_tags"}
+	if got := rules(result); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected rules %v to fail, got %v: %v", expected, got, result.Errors)
+	}
+	if result.Errors[4].Value != "signup" {
+		t.Errorf("Expected each tag to be validated, got %+v", result.Errors[4])
+	}
+
+	entry.Message = "Short"
+	entry.DeviceInfo = &models.DeviceInfo{Platform: "linux"}
+	entry.Metadata = map[string]interface{}{"order_id": "ORD-42", "user_id": 7}
+	entry.Tags = []string{"login"}
+	if result := validator.ValidateLogEntry(&entry); !result.IsValid {
+		t.Errorf("Expected entry to pass the rules, got %v", result.Errors)
+	}
+
+	// Struct tag failures are reported with the tag
+	entry.ID = "invalid"
+	if got := rules(validator.ValidateLogEntry(&entry)); len(got) != 1 || got[0] != "uuid4" {
+		t.Errorf("Expected the uuid4 tag to fail, got %v", got)
+	}
+}
+
+func TestLogValidator_DefaultRules(t *testing.T) {
+	entry := createValidLogEntry()
+	entry.Message = strings.Repeat("a", DefaultMaxMessageLength+1)
+	entry.Timestamp = time.Now().Add(-2 * DefaultMaxAge)
+
+	result := NewLogValidator().ValidateLogEntry(&entry)
+	if len(result.Errors) != 2 || result.Errors[0].Rule != RuleMessageLength || result.Errors[1].Rule != RuleTimestampPast {
+		t.Fatalf("Expected the default limits to apply, got %v", result.Errors)
+	}
+	if expected := "Timestamp cannot be more than 365 days in the past"; result.Errors[1].Message != expected {
+		t.Errorf("Expected message %q, got %q", expected, result.Errors[1].Message)
+	}
+}
+
+func TestNewLogValidatorWithRules_Invalid(t *testing.T) {
+	for name, rules := range map[string]Rules{
+		"unknown required field": {RequiredFields: []string{"colour"}},
+		"unknown disabled rule":  {Disabled: []string{"no_such_rule"}},
+		"unnamed field rule":     {FieldRules: []FieldRule{{Field: "agent_id"}}},
+		"duplicate field rule":   {FieldRules: []FieldRule{{Name: "a", Field: "agent_id"}, {Name: "a", Field: "message"}}},
+		"unknown field":          {FieldRules: []FieldRule{{Name: "a", Field: "metadata."}}},
+		"invalid pattern":        {FieldRules: []FieldRule{{Name: "a", Field: "agent_id", Pattern: "("}}},
+	} {
+		if _, err := NewLogValidatorWithRules(nil, rules); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLogValidator_ValidateLegalHold(t *testing.T) {
+	validator := NewLogValidator()
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		hold    models.LegalHold
+		isValid bool
+	}{
+		{name: "service and range", hold: models.LegalHold{ServiceName: "orders-service", StartTime: now.Add(-time.Hour), EndTime: now, Reason: "Audit"}, isValid: true},
+		{name: "all services", hold: models.LegalHold{Reason: "Audit"}, isValid: true},
+		{name: "missing reason", hold: models.LegalHold{ServiceName: "orders-service"}, isValid: false},
+		{name: "invalid service name", hold: models.LegalHold{ServiceName: "orders service", Reason: "Audit"}, isValid: false},
+		{name: "end before start", hold: models.LegalHold{StartTime: now, EndTime: now.Add(-time.Hour), Reason: "Audit"}, isValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validator.ValidateLegalHold(&tt.hold)
+			if result.IsValid != tt.isValid {
+				t.Errorf("Expected valid=%v, got %v with errors %v", tt.isValid, result.IsValid, result.Errors)
+			}
+		})
+	}
+}
+
+func TestLogValidator_ValidateSymbolFile(t *testing.T) {
+	validator := NewLogValidator()
+
+	tests := []struct {
+		name    string
+		file    models.SymbolFile
+		isValid bool
+	}{
+		{name: "source map", file: models.SymbolFile{ServiceName: "mobile-app", Version: "2.4.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap}, isValid: true},
+		{name: "proguard mapping", file: models.SymbolFile{ServiceName: "android-app", Version: "2.4.0 (118)", Name: "mapping.txt", Kind: models.SymbolFileKindProGuard}, isValid: true},
+		{name: "unknown kind", file: models.SymbolFile{ServiceName: "mobile-app", Version: "2.4.0", Name: "app.dsym", Kind: "dsym"}, isValid: false},
+		{name: "missing version", file: models.SymbolFile{ServiceName: "mobile-app", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap}, isValid: false},
+		{name: "invalid service name", file: models.SymbolFile{ServiceName: "mobile app", Version: "2.4.0", Name: "main.jsbundle", Kind: models.SymbolFileKindSourceMap}, isValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validator.ValidateSymbolFile(&tt.file)
+			if result.IsValid != tt.isValid {
+				t.Errorf("Expected valid=%v, got %v with errors %v", tt.isValid, result.IsValid, result.Errors)
+			}
+		})
+	}
+}